@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"time"
@@ -10,10 +9,9 @@ import (
 	"github.com/rtrwnet/saas-backend/internal/delivery/http/router"
 	"github.com/rtrwnet/saas-backend/internal/infrastructure/cache"
 	"github.com/rtrwnet/saas-backend/internal/infrastructure/database"
-	"github.com/rtrwnet/saas-backend/internal/repository/postgres"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
 	"github.com/rtrwnet/saas-backend/pkg/config"
 	"github.com/rtrwnet/saas-backend/pkg/logger"
-	"gorm.io/gorm"
 
 	_ "github.com/rtrwnet/saas-backend/docs/swagger" // Import generated docs
 )
@@ -82,7 +80,7 @@ func main() {
 		Config: cfg,
 	}
 
-	r := router.SetupRouter(routerCfg)
+	r, services := router.SetupRouter(routerCfg)
 
 	// Log startup info
 	logger.Info("Database: connected")
@@ -95,7 +93,10 @@ func main() {
 	logger.Info("RADIUS: Using FreeRADIUS server (external container)")
 
 	// Start hotspot background jobs
-	startHotspotBackgroundJobs(db)
+	startHotspotBackgroundJobs(services)
+
+	// Start billing background jobs
+	startBillingBackgroundJobs(cfg, services)
 
 	// Start server
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
@@ -108,26 +109,44 @@ func main() {
 }
 
 // startHotspotBackgroundJobs starts background jobs for hotspot management
-func startHotspotBackgroundJobs(db *gorm.DB) {
-	// Initialize repositories
-	voucherRepo := postgres.NewHotspotVoucherRepository(db)
-
-	// Start voucher status updater (every 5 minutes)
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-
-		logger.Info("Voucher status updater started (interval: 5m)")
-
-		for range ticker.C {
-			ctx := context.Background()
-			if err := voucherRepo.UpdateExpiredVouchers(ctx); err != nil {
-				logger.Error("Voucher status updater error: %v", err)
-			} else {
-				logger.Info("Voucher status updated successfully")
-			}
-		}
-	}()
+func startHotspotBackgroundJobs(services *router.Services) {
+	// Start voucher expiration checker (every 5 minutes)
+	usecase.StartExpirationChecker(services.HotspotSession, 5*time.Minute)
 
 	logger.Info("Hotspot background jobs started successfully")
 }
+
+// startBillingBackgroundJobs starts background jobs for customer billing on
+// the services the router built
+func startBillingBackgroundJobs(cfg *config.Config, services *router.Services) {
+	usecase.StartInvoiceGenerator(services.InvoiceGenerator, cfg.Billing.InvoiceInterval)
+
+	// Plan changes scheduled for the next cycle are applied on its first day
+	usecase.StartPlanChangeJob(services.PlanChange, cfg.Billing.PlanChangeInterval)
+
+	// Speed boosts end on their own once EndDate passes
+	usecase.StartSpeedBoostJob(services.SpeedBoost, cfg.Billing.SpeedBoostInterval)
+
+	// Prepaid customers are emailed before their service lapses
+	usecase.StartPrepaidJob(services.Prepaid, cfg.Billing.PrepaidInterval)
+
+	usecase.StartAutoSuspendJob(services.AutoSuspend, cfg.Billing.AutoSuspendInterval)
+
+	usecase.StartLateFeeJob(services.LateFee, cfg.Billing.LateFeeInterval)
+
+	// Tenant subscriptions are renewed and chased on the platform's billing policy
+	usecase.StartSubscriptionRenewalJob(services.SubscriptionRenewal, cfg.Billing.RenewalInterval)
+
+	// Customers are reminded and notified of their invoices once a day, over
+	// WhatsApp for tenants that enabled it and by email
+	usecase.StartNotificationJob(services.NotificationDispatch, cfg.Billing.NotificationInterval)
+
+	// Tenant bots push events to linked operator chats and answer their
+	// commands, long-polling Telegram unless a webhook URL is set
+	usecase.StartTelegramBotJob(services.TelegramBot, cfg.Telegram.PushInterval, cfg.Telegram.WebhookURL == "")
+
+	// Tenant webhooks get new events and failed deliveries retried
+	usecase.StartWebhookJob(services.Webhook, cfg.Webhook.Interval)
+
+	logger.Info("Billing background jobs started successfully")
+}
@@ -134,12 +134,30 @@ type CustomerDetailResponse struct {
 	DueDate          int                   `json:"due_date"`
 	MonthlyFee       float64               `json:"monthly_fee"`
 	Notes            string                `json:"notes"`
+	AutoSuspendExempt      bool            `json:"auto_suspend_exempt"`
+	AutoSuspendExemptUntil *time.Time      `json:"auto_suspend_exempt_until,omitempty"`
+	AutoSuspendedAt        *time.Time      `json:"auto_suspended_at,omitempty"`
+	ServiceUntil     *time.Time            `json:"service_until,omitempty"` // prepaid billing only
 	PaymentHistory   []PaymentHistory      `json:"payment_history"`
+	CreditBalance    float64               `json:"credit_balance"`
+	Statement        []LedgerEntry         `json:"statement"` // latest balance movements, newest first
 	Statistics       CustomerStatistics    `json:"statistics"`
 	CreatedAt        time.Time             `json:"created_at"`
 	UpdatedAt        time.Time             `json:"updated_at"`
 }
 
+// LedgerEntry is a movement of a customer's credit balance
+type LedgerEntry struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`   // credit, debit
+	Source       string    `json:"source"` // overpayment, invoice, manual
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	PaymentID    *string   `json:"payment_id,omitempty"`
+	Reason       string    `json:"reason"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 type ServicePlanInfo struct {
 	ID            string  `json:"id"`
 	Name          string  `json:"name"`
@@ -150,7 +168,12 @@ type ServicePlanInfo struct {
 
 type PaymentHistory struct {
 	ID            string     `json:"id"`
-	Amount        float64    `json:"amount"`
+	InvoiceNumber string     `json:"invoice_number,omitempty"`
+	Amount        float64    `json:"amount"` // invoice amount without late fee
+	LateFee       float64    `json:"late_fee"`
+	LateFeeWaived bool       `json:"late_fee_waived"`
+	TotalAmount   float64    `json:"total_amount"` // amount plus the late fee still owed
+	PaidAmount    float64    `json:"paid_amount"`
 	PaymentDate   *time.Time `json:"payment_date,omitempty"`
 	DueDate       time.Time  `json:"due_date"`
 	Status        string     `json:"status"`
@@ -218,6 +241,10 @@ type UpdateCustomerRequest struct {
 	MonthlyFee    float64 `json:"monthly_fee" binding:"omitempty,min=0"`
 	Status        string  `json:"status" binding:"omitempty,oneof=pending_activation active suspended inactive terminated"`
 	Notes         string  `json:"notes"`
+
+	// Auto-suspend exemption; empty exempt_until clears a temporary exemption
+	AutoSuspendExempt      *bool   `json:"auto_suspend_exempt"`
+	AutoSuspendExemptUntil *string `json:"auto_suspend_exempt_until"` // YYYY-MM-DD
 }
 
 // Payment List Response
@@ -230,10 +257,15 @@ type PaymentListResponse struct {
 
 type PaymentSummary struct {
 	ID            string     `json:"id"`
+	InvoiceNumber string     `json:"invoice_number,omitempty"`
 	CustomerID    string     `json:"customer_id"`
 	CustomerName  string     `json:"customer_name"`
 	CustomerCode  string     `json:"customer_code"`
-	Amount        float64    `json:"amount"`
+	Amount        float64    `json:"amount"` // invoice amount without late fee
+	LateFee       float64    `json:"late_fee"`
+	LateFeeWaived bool       `json:"late_fee_waived"`
+	TotalAmount   float64    `json:"total_amount"` // amount plus the late fee still owed
+	PaidAmount    float64    `json:"paid_amount"`
 	PaymentDate   *time.Time `json:"payment_date,omitempty"`
 	DueDate       time.Time  `json:"due_date"`
 	Status        string     `json:"status"`
@@ -243,13 +275,146 @@ type PaymentSummary struct {
 
 // Record Payment Request
 type RecordPaymentRequest struct {
-	CustomerID    string  `json:"customer_id" binding:"required"`
-	Amount        float64 `json:"amount" binding:"required,min=0"`
-	PaymentDate   string  `json:"payment_date"` // Optional for invoice (empty = pending invoice)
-	PaymentMethod string  `json:"payment_method"` // Optional for invoice
+	CustomerID    string               `json:"customer_id" binding:"required"`
+	Amount        float64              `json:"amount" binding:"omitempty,min=0"` // Used as a single line when items is empty
+	Items         []InvoiceItemRequest `json:"items" binding:"omitempty,dive"`
+	PaymentDate   string               `json:"payment_date"` // Optional for invoice (empty = pending invoice)
+	PaymentMethod string               `json:"payment_method"` // Optional for invoice
+	Notes         string               `json:"notes"`
+}
+
+// Invoice line item; late fees are added by the billing job
+type InvoiceItemRequest struct {
+	Type        string  `json:"type" binding:"required,oneof=monthly_fee installation addon discount other"`
+	Description string  `json:"description" binding:"required"`
+	Quantity    float64 `json:"quantity" binding:"omitempty,min=0"` // defaults to 1
+	UnitPrice   float64 `json:"unit_price" binding:"min=0"`         // discounts are entered as a positive price
+}
+
+// Allocate Payment Request
+type AllocatePaymentRequest struct {
+	Amount        float64 `json:"amount" binding:"required,gt=0"`
+	PaymentMethod string  `json:"payment_method" binding:"required"`
+	PaymentDate   string  `json:"payment_date"` // Optional, defaults to now
+	Reference     string  `json:"reference"`
 	Notes         string  `json:"notes"`
 }
 
+// Issue Credit Note Request
+type IssueCreditNoteRequest struct {
+	Amount       float64 `json:"amount" binding:"required,gt=0"`
+	Reason       string  `json:"reason" binding:"required"`
+	RefundMethod string  `json:"refund_method"` // cash, transfer or midtrans; needed when the credit exceeds the balance
+}
+
+// Adjust Balance Request
+type AdjustBalanceRequest struct {
+	Type   string  `json:"type" binding:"required,oneof=credit debit"`
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+// Mark Payment Paid Request
+type MarkPaymentPaidRequest struct {
+	PaymentDate   string `json:"payment_date"` // Optional, defaults to now
+	PaymentMethod string `json:"payment_method" binding:"required"`
+	Notes         string `json:"notes"`
+}
+
+// Waive Late Fee Request
+type WaiveLateFeeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// Change Plan Request; monthly_fee defaults to the new plan's price
+type ChangePlanRequest struct {
+	ServicePlanID string  `json:"service_plan_id" binding:"required"`
+	Effective     string  `json:"effective" binding:"omitempty,oneof=immediate next_cycle"`
+	MonthlyFee    float64 `json:"monthly_fee" binding:"omitempty,min=0"`
+	Reason        string  `json:"reason"`
+}
+
+// Speed Boost Request; price defaults to the plan difference for the days
+type SpeedBoostRequest struct {
+	CustomerID   string   `json:"customer_id" binding:"required"`
+	BoostPlanID  string   `json:"boost_plan_id" binding:"required"`
+	DurationDays int      `json:"duration_days" binding:"required,min=1,max=30"`
+	Price        *float64 `json:"price" binding:"omitempty,min=0"`
+	Notes        string   `json:"notes"`
+}
+
+// Speed Boost Link Request; the link lets the customer request boosts
+type SpeedBoostLinkRequest struct {
+	CustomerID string `json:"customer_id" binding:"required"`
+}
+
+// Customer Speed Boost Request, sent by the customer who proves their
+// identity with the token of the link an operator sent them
+type CustomerSpeedBoostRequest struct {
+	Token        string `json:"token" binding:"required"`
+	BoostPlanID  string `json:"boost_plan_id" binding:"required"`
+	DurationDays int    `json:"duration_days" binding:"required,min=1,max=30"`
+	Notes        string `json:"notes"`
+}
+
+// Approve Speed Boost Request; without start_date the boost starts now
+type ApproveSpeedBoostRequest struct {
+	StartDate string   `json:"start_date"` // RFC3339 or YYYY-MM-DD
+	Price     *float64 `json:"price" binding:"omitempty,min=0"`
+}
+
+// Reject Speed Boost Request
+type RejectSpeedBoostRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// Send WhatsApp Message Request, an operator's message to a customer
+type SendWhatsAppRequest struct {
+	Message string `json:"message" binding:"required,max=4096"`
+}
+
+// Pay Invoice Request, sent by the customer from the invoice payment page
+type PayInvoiceRequest struct {
+	PaymentMethod string `json:"payment_method" binding:"required,oneof=bca_va bni_va bri_va permata_va mandiri_bill gopay qris"`
+}
+
+// Invoice Payment Page Response: what a customer sees before paying online
+type InvoicePaymentPage struct {
+	InvoiceNumber        string                   `json:"invoice_number"`
+	CompanyName          string                   `json:"company_name"`
+	CustomerName         string                   `json:"customer_name"`
+	TotalAmount          float64                  `json:"total_amount"`
+	PaidAmount           float64                  `json:"paid_amount"`
+	Balance              float64                  `json:"balance"`
+	DueDate              time.Time                `json:"due_date"`
+	Status               string                   `json:"status"`
+	OnlinePaymentEnabled bool                     `json:"online_payment_enabled"`
+	PaymentMethods       []map[string]interface{} `json:"payment_methods,omitempty"`
+	PendingCharge        *InvoiceChargeResponse   `json:"pending_charge,omitempty"`
+}
+
+// Invoice Charge Response: payment instructions of a Midtrans charge
+type InvoiceChargeResponse struct {
+	OrderID         string     `json:"order_id"`
+	PaymentMethod   string     `json:"payment_method"`
+	PaymentType     string     `json:"payment_type"`
+	Status          string     `json:"status"`
+	Amount          float64    `json:"amount"`
+	ExpiryTime      string     `json:"expiry_time,omitempty"`
+	VANumbers       []VANumber `json:"va_numbers,omitempty"`
+	PermataVANumber string     `json:"permata_va_number,omitempty"`
+	BillerCode      string     `json:"biller_code,omitempty"`
+	BillKey         string     `json:"bill_key,omitempty"`
+	QRString        string     `json:"qr_string,omitempty"`
+	QRCodeURL       string     `json:"qr_code_url,omitempty"`
+	DeeplinkURL     string     `json:"deeplink_url,omitempty"`
+}
+
+type VANumber struct {
+	Bank     string `json:"bank"`
+	VANumber string `json:"va_number"`
+}
+
 // Service Plan List Response
 type ServicePlanListResponse struct {
 	Plans   []ServicePlanSummary `json:"plans"`
@@ -71,10 +71,17 @@ type TenantSettingsResponse struct {
 	SendPaymentConfirmation bool `json:"send_payment_confirmation"`
 	SendSuspensionWarning bool  `json:"send_suspension_warning"`
 	WarningDaysBeforeSuspension int `json:"warning_days_before_suspension"`
+	SendInvoiceNotification bool `json:"send_invoice_notification"`
+	SendTicketNotification bool `json:"send_ticket_notification"`
+	NotificationLanguage string `json:"notification_language"`
 	
 	// Integrations
 	WhatsappEnabled     bool    `json:"whatsapp_enabled"`
 	TelegramEnabled     bool    `json:"telegram_enabled"`
+	MidtransEnabled     bool    `json:"midtrans_enabled"`
+	MidtransConfigured  bool    `json:"midtrans_configured"` // server key is set; the key itself is never returned
+	MidtransClientKey   string  `json:"midtrans_client_key,omitempty"`
+	MidtransIsProduction bool   `json:"midtrans_is_production"`
 }
 
 type UpdateTenantSettingsRequest struct {
@@ -111,6 +118,9 @@ type UpdateTenantSettingsRequest struct {
 	SendPaymentConfirmation *bool `json:"send_payment_confirmation"`
 	SendSuspensionWarning *bool `json:"send_suspension_warning"`
 	WarningDaysBeforeSuspension *int `json:"warning_days_before_suspension" binding:"omitempty,min=1,max=14"`
+	SendInvoiceNotification *bool `json:"send_invoice_notification"`
+	SendTicketNotification *bool `json:"send_ticket_notification"`
+	NotificationLanguage string `json:"notification_language" binding:"omitempty,oneof=id en"`
 }
 
 type UpdateIntegrationSettingsRequest struct {
@@ -118,6 +128,47 @@ type UpdateIntegrationSettingsRequest struct {
 	WhatsappAPIKey   string `json:"whatsapp_api_key"`
 	TelegramEnabled  *bool  `json:"telegram_enabled"`
 	TelegramBotToken string `json:"telegram_bot_token"`
+	// Midtrans account for online payment of customer invoices
+	MidtransEnabled      *bool  `json:"midtrans_enabled"`
+	MidtransServerKey    string `json:"midtrans_server_key"`
+	MidtransClientKey    string `json:"midtrans_client_key"`
+	MidtransIsProduction *bool  `json:"midtrans_is_production"`
+}
+
+// ==================== NOTIFICATION TEMPLATES ====================
+
+// SaveNotificationTemplateRequest is a tenant's template; the event, channel
+// and language are in the URL
+type SaveNotificationTemplateRequest struct {
+	Subject string `json:"subject" binding:"max=255"`
+	Body    string `json:"body" binding:"required,max=10000"`
+}
+
+// PreviewNotificationTemplateRequest renders a template with sample data. An
+// empty body previews the template the tenant sends now.
+type PreviewNotificationTemplateRequest struct {
+	Event    string `json:"event" binding:"required"`
+	Channel  string `json:"channel" binding:"required"`
+	Language string `json:"language" binding:"required,oneof=id en"`
+	Subject  string `json:"subject" binding:"max=255"`
+	Body     string `json:"body" binding:"max=10000"`
+}
+
+// ==================== WEBHOOKS ====================
+
+// CreateWebhookRequest registers an endpoint of the tenant for the events
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=500"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"required,min=1"`
+}
+
+// UpdateWebhookRequest changes the fields that are set
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,max=500"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Events      []string `json:"events" binding:"omitempty,min=1"`
+	IsActive    *bool    `json:"is_active"`
 }
 
 // ==================== PROFILE ====================
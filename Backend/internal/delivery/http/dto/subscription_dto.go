@@ -77,6 +77,7 @@ type PlanLimitsResponse struct {
 	PlanSlug     string       `json:"plan_slug"`
 	IsTrial      bool         `json:"is_trial"`
 	TrialEndsAt  string       `json:"trial_ends_at,omitempty"`
+	ReadOnly     bool         `json:"read_only"` // trial ended, only reads are allowed
 	Limits       PlanLimits   `json:"limits"`
 	Features     PlanFeatures `json:"features"`
 	Usage        PlanUsage    `json:"usage"`
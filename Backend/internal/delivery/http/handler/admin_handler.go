@@ -1,21 +1,25 @@
 package handler
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
 	"github.com/rtrwnet/saas-backend/internal/usecase"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
 	"github.com/rtrwnet/saas-backend/pkg/response"
 )
 
 type AdminHandler struct {
-	adminService usecase.AdminService
+	adminService        usecase.AdminService
+	webhookEventService usecase.WebhookEventService
 }
 
-func NewAdminHandler(adminService usecase.AdminService) *AdminHandler {
+func NewAdminHandler(adminService usecase.AdminService, webhookEventService usecase.WebhookEventService) *AdminHandler {
 	return &AdminHandler{
-		adminService: adminService,
+		adminService:        adminService,
+		webhookEventService: webhookEventService,
 	}
 }
 
@@ -758,3 +762,128 @@ func (h *AdminHandler) ReconcilePayment(c *gin.Context) {
 
 	response.OK(c, "Payment reconciliation completed", resp)
 }
+
+// RefundPayment handles refunding all or part of a paid payment
+func (h *AdminHandler) RefundPayment(c *gin.Context) {
+	orderID := c.Param("order_id")
+	if orderID == "" {
+		response.BadRequest(c, "VAL_2003", "Order ID is required", nil)
+		return
+	}
+
+	var req usecase.RefundPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "VAL_2001", "Invalid request body", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	adminID := c.GetString("admin_id")
+	resp, err := h.adminService.RefundPayment(c.Request.Context(), orderID, &req, adminID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Failed to refund payment")
+		}
+		return
+	}
+
+	// Create audit log
+	adminName := c.GetString("admin_name")
+	details := fmt.Sprintf("Refunded %.2f via %s: %s", resp.Refund.Amount, resp.Refund.Method, req.Reason)
+	h.adminService.CreateAuditLog(c.Request.Context(), adminID, adminName, "REFUND", "payment", orderID, details, c.ClientIP())
+
+	response.OK(c, "Payment refunded successfully", resp)
+}
+
+// CancelPayment handles cancelling a pending payment
+func (h *AdminHandler) CancelPayment(c *gin.Context) {
+	orderID := c.Param("order_id")
+	if orderID == "" {
+		response.BadRequest(c, "VAL_2003", "Order ID is required", nil)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "VAL_2001", "Reason is required", nil)
+		return
+	}
+
+	adminID := c.GetString("admin_id")
+	tx, err := h.adminService.CancelPayment(c.Request.Context(), orderID, req.Reason, adminID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Failed to cancel payment")
+		}
+		return
+	}
+
+	// Create audit log
+	adminName := c.GetString("admin_name")
+	h.adminService.CreateAuditLog(c.Request.Context(), adminID, adminName, "CANCEL", "payment", orderID, req.Reason, c.ClientIP())
+
+	response.OK(c, "Payment cancelled successfully", tx)
+}
+
+// ListWebhookEvents handles listing inbound payment webhooks
+func (h *AdminHandler) ListWebhookEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "10"))
+
+	resp, err := h.webhookEventService.ListEvents(c.Request.Context(), repository.WebhookEventFilter{
+		Source:   c.Query("source"),
+		Status:   c.Query("status"),
+		TenantID: c.Query("tenant_id"),
+		Search:   c.Query("search"),
+		Page:     page,
+		PerPage:  perPage,
+	})
+	if err != nil {
+		response.InternalServerError(c, "SRV_9001", "Failed to list webhook events")
+		return
+	}
+
+	response.OK(c, "Webhook events retrieved successfully", resp)
+}
+
+// GetWebhookEvent handles getting a single webhook event with its raw body
+func (h *AdminHandler) GetWebhookEvent(c *gin.Context) {
+	event, err := h.webhookEventService.GetEvent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Failed to get webhook event")
+		}
+		return
+	}
+
+	response.OK(c, "Webhook event retrieved successfully", event)
+}
+
+// ReplayWebhookEvent handles processing a failed webhook event again
+func (h *AdminHandler) ReplayWebhookEvent(c *gin.Context) {
+	id := c.Param("id")
+	event, err := h.webhookEventService.Replay(c.Request.Context(), id)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Failed to replay webhook event")
+		}
+		return
+	}
+
+	adminID := c.GetString("admin_id")
+	adminName := c.GetString("admin_name")
+	h.adminService.CreateAuditLog(c.Request.Context(), adminID, adminName, "REPLAY", "webhook_event", id, "Webhook replay "+event.Status, c.ClientIP())
+
+	response.OK(c, "Webhook event replayed", event)
+}
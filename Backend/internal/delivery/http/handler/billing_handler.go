@@ -10,12 +10,14 @@ import (
 )
 
 type BillingHandler struct {
-	billingService usecase.BillingService
+	billingService  usecase.BillingService
+	documentService usecase.DocumentService
 }
 
-func NewBillingHandler(billingService usecase.BillingService) *BillingHandler {
+func NewBillingHandler(billingService usecase.BillingService, documentService usecase.DocumentService) *BillingHandler {
 	return &BillingHandler{
-		billingService: billingService,
+		billingService:  billingService,
+		documentService: documentService,
 	}
 }
 
@@ -296,3 +298,35 @@ func (h *BillingHandler) GetPendingOrder(c *gin.Context) {
 
 	response.OK(c, "Pending order found", orderResp)
 }
+
+// DownloadInvoicePDF godoc
+// @Summary      Download subscription invoice PDF
+// @Description  Render the invoice of a subscription payment listed on the billing dashboard as PDF
+// @Tags         Billing
+// @Produce      application/pdf
+// @Security     BearerAuth
+// @Security     TenantID
+// @Param        id   path      string  true  "Payment transaction ID"
+// @Success      200  {file}    file    "Invoice PDF"
+// @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Invoice not found"
+// @Router       /billing/invoices/{id}/pdf [get]
+func (h *BillingHandler) DownloadInvoicePDF(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.Unauthorized(c, "AUTH_1002", "Unauthorized access")
+		return
+	}
+
+	doc, err := h.documentService.RenderTransactionPDF(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Internal server error")
+		}
+		return
+	}
+
+	sendPDF(c, doc)
+}
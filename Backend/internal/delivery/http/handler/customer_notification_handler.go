@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/middleware"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+)
+
+type CustomerNotificationHandler struct {
+	dispatchService usecase.NotificationDispatchService
+}
+
+func NewCustomerNotificationHandler(dispatchService usecase.NotificationDispatchService) *CustomerNotificationHandler {
+	return &CustomerNotificationHandler{
+		dispatchService: dispatchService,
+	}
+}
+
+// customerNotificationError writes a service error
+func customerNotificationError(c *gin.Context, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		response.ErrorFromAppError(c, appErr)
+		return
+	}
+	response.InternalServerError(c, "SRV_9001", "Internal server error")
+}
+
+// ListCustomerNotifications handles the notification delivery log of a customer
+func (h *CustomerNotificationHandler) ListCustomerNotifications(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	notifications, err := h.dispatchService.ListCustomerNotifications(c.Request.Context(), tenantID, c.Param("id"), limit)
+	if err != nil {
+		customerNotificationError(c, err)
+		return
+	}
+
+	response.OK(c, "Customer notifications retrieved successfully", notifications)
+}
+
+// DispatchNotifications handles sending the tenant's due customer
+// notifications now instead of waiting for the daily run
+func (h *CustomerNotificationHandler) DispatchNotifications(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	result, err := h.dispatchService.DispatchTenant(c.Request.Context(), tenantID, time.Now())
+	if err != nil {
+		customerNotificationError(c, err)
+		return
+	}
+
+	response.OK(c, "Customer notifications dispatched", result)
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
 	"github.com/rtrwnet/saas-backend/internal/middleware"
@@ -11,12 +13,23 @@ import (
 )
 
 type DashboardHandler struct {
-	dashboardService usecase.DashboardService
+	dashboardService   usecase.DashboardService
+	autoSuspendService usecase.AutoSuspendService
+	lateFeeService     usecase.LateFeeService
+	planChangeService  usecase.PlanChangeService
 }
 
-func NewDashboardHandler(dashboardService usecase.DashboardService) *DashboardHandler {
+func NewDashboardHandler(
+	dashboardService usecase.DashboardService,
+	autoSuspendService usecase.AutoSuspendService,
+	lateFeeService usecase.LateFeeService,
+	planChangeService usecase.PlanChangeService,
+) *DashboardHandler {
 	return &DashboardHandler{
-		dashboardService: dashboardService,
+		dashboardService:   dashboardService,
+		autoSuspendService: autoSuspendService,
+		lateFeeService:     lateFeeService,
+		planChangeService:  planChangeService,
 	}
 }
 
@@ -247,6 +260,167 @@ func (h *DashboardHandler) RecordPayment(c *gin.Context) {
 	response.Created(c, "Payment recorded successfully", nil)
 }
 
+// MarkPaymentPaid handles settling an invoice. Customers suspended by the
+// billing job are reactivated when nothing else is overdue.
+func (h *DashboardHandler) MarkPaymentPaid(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.MarkPaymentPaidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	payment, err := h.dashboardService.MarkPaymentPaid(c.Request.Context(), tenantID, c.Param("id"), &req)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Internal server error")
+		}
+		return
+	}
+
+	response.OK(c, "Payment marked as paid", payment)
+}
+
+// WaiveLateFee handles waiving the late fee of an unpaid invoice
+func (h *DashboardHandler) WaiveLateFee(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.WaiveLateFeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	payment, err := h.lateFeeService.WaiveLateFee(c.Request.Context(), tenantID, c.Param("id"), userID, req.Reason)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Internal server error")
+		}
+		return
+	}
+
+	response.OK(c, "Late fee waived", payment)
+}
+
+// ListBillingActions handles the automatic billing action log of a customer
+func (h *DashboardHandler) ListBillingActions(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	actions, err := h.autoSuspendService.ListActions(c.Request.Context(), tenantID, c.Param("id"), limit)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Internal server error")
+		}
+		return
+	}
+
+	response.OK(c, "Billing actions retrieved successfully", actions)
+}
+
+// ChangeCustomerPlan handles moving a customer to another service plan,
+// now with proration or from their next billing cycle
+func (h *DashboardHandler) ChangeCustomerPlan(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	changeReq := &usecase.PlanChangeRequest{
+		ServicePlanID: req.ServicePlanID,
+		Effective:     req.Effective,
+		MonthlyFee:    req.MonthlyFee,
+		Reason:        req.Reason,
+	}
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		changeReq.CreatedBy = &userID
+	}
+
+	change, err := h.planChangeService.ChangePlan(c.Request.Context(), tenantID, c.Param("id"), changeReq)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Internal server error")
+		}
+		return
+	}
+
+	response.OK(c, "Plan change saved", change)
+}
+
+// CancelPlanChange handles cancelling a scheduled plan change
+func (h *DashboardHandler) CancelPlanChange(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	if err := h.planChangeService.CancelPlanChange(c.Request.Context(), tenantID, c.Param("id"), c.Param("change_id")); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Internal server error")
+		}
+		return
+	}
+
+	response.OK(c, "Plan change cancelled", nil)
+}
+
+// ListPlanHistory handles the service plan history of a customer
+func (h *DashboardHandler) ListPlanHistory(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	changes, err := h.planChangeService.ListPlanHistory(c.Request.Context(), tenantID, c.Param("id"), limit)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			response.ErrorFromAppError(c, appErr)
+		} else {
+			response.InternalServerError(c, "SRV_9001", "Internal server error")
+		}
+		return
+	}
+
+	response.OK(c, "Plan history retrieved successfully", changes)
+}
+
 // ListServicePlans handles service plan list request
 func (h *DashboardHandler) ListServicePlans(c *gin.Context) {
 	tenantID, err := middleware.GetTenantIDFromContext(c)
@@ -219,13 +219,14 @@ func (h *DeviceHandler) TestMikrotikConnection(c *gin.Context) {
 
 // SyncMikrotikQueues godoc
 // @Summary Sync Mikrotik queues
-// @Description Synchronize queues with Mikrotik device
+// @Description Reconcile simple queues on the Mikrotik device with active customers. Only the needed creates, updates and removes are applied; with dry_run=true the plan is returned without touching the router.
 // @Tags devices
 // @Accept json
 // @Produce json
 // @Param X-Tenant-ID header string true "Tenant ID"
 // @Param id path string true "Device ID"
-// @Success 200 {object} response.Response
+// @Param dry_run query bool false "Only compute the plan"
+// @Success 200 {object} response.Response{data=usecase.QueueSyncResult}
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
@@ -233,13 +234,45 @@ func (h *DeviceHandler) TestMikrotikConnection(c *gin.Context) {
 func (h *DeviceHandler) SyncMikrotikQueues(c *gin.Context) {
 	tenantID := c.GetString("tenant_id")
 	deviceID := c.Param("id")
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
 
-	err := h.deviceService.SyncMikrotikQueues(c.Request.Context(), tenantID, deviceID)
+	result, err := h.deviceService.SyncMikrotikQueues(c.Request.Context(), tenantID, deviceID, dryRun)
 	if err != nil {
 		response.SimpleError(c, http.StatusInternalServerError, "Failed to sync queues", err.Error())
 		return
 	}
 
-	response.Success(c, http.StatusOK, "Queues synchronized successfully", nil)
+	message := "Queues synchronized successfully"
+	if dryRun {
+		message = "Queue sync plan generated"
+	}
+	response.Success(c, http.StatusOK, message, result)
 }
 
+// ListQueueSyncReports godoc
+// @Summary List queue sync reports
+// @Description Get the most recent queue sync runs for a device
+// @Tags devices
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Device ID"
+// @Param limit query int false "Number of reports" default(20)
+// @Success 200 {object} response.Response{data=[]entity.QueueSyncReport}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /devices/{id}/sync-reports [get]
+func (h *DeviceHandler) ListQueueSyncReports(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	deviceID := c.Param("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	reports, err := h.deviceService.ListQueueSyncReports(c.Request.Context(), tenantID, deviceID, limit)
+	if err != nil {
+		response.SimpleError(c, http.StatusInternalServerError, "Failed to list sync reports", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Sync reports retrieved successfully", reports)
+}
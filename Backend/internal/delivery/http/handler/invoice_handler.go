@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/middleware"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+)
+
+type InvoiceHandler struct {
+	invoiceGenerator usecase.InvoiceGeneratorService
+	invoiceService   usecase.InvoiceService
+	documentService  usecase.DocumentService
+}
+
+func NewInvoiceHandler(
+	invoiceGenerator usecase.InvoiceGeneratorService,
+	invoiceService usecase.InvoiceService,
+	documentService usecase.DocumentService,
+) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceGenerator: invoiceGenerator,
+		invoiceService:   invoiceService,
+		documentService:  documentService,
+	}
+}
+
+// invoiceError writes a service error with the status of the AppError
+func invoiceError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Status != 0 {
+		status = appErr.Status
+	}
+	response.SimpleError(c, status, message, err.Error())
+}
+
+// GenerateInvoices godoc
+// @Summary Generate recurring invoices
+// @Description Run the recurring invoice generator for the tenant now. Customers that already have an invoice for the period are skipped; with dry_run=true the invoices are only planned.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param dry_run query bool false "Only compute the plan"
+// @Param date query string false "Run as of this date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} response.Response{data=usecase.InvoiceRunResult}
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /payments/generate [post]
+func (h *InvoiceHandler) GenerateInvoices(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	asOf := time.Now()
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			response.SimpleError(c, http.StatusBadRequest, "Invalid date", "date must be in YYYY-MM-DD format")
+			return
+		}
+		asOf = parsed
+	}
+
+	result, err := h.invoiceGenerator.GenerateInvoices(c.Request.Context(), tenantID, asOf, entity.InvoiceRunTriggerManual, dryRun)
+	if err != nil {
+		response.SimpleError(c, http.StatusInternalServerError, "Failed to generate invoices", err.Error())
+		return
+	}
+
+	message := "Invoices generated successfully"
+	if dryRun {
+		message = "Invoice plan generated"
+	}
+	response.Success(c, http.StatusOK, message, result)
+}
+
+// ListInvoiceRuns godoc
+// @Summary List invoice runs
+// @Description Get the most recent recurring invoice generator runs
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param limit query int false "Number of runs" default(20)
+// @Success 200 {object} response.Response{data=[]entity.InvoiceRun}
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /payments/invoice-runs [get]
+func (h *InvoiceHandler) ListInvoiceRuns(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	runs, err := h.invoiceGenerator.ListInvoiceRuns(c.Request.Context(), tenantID, limit)
+	if err != nil {
+		response.SimpleError(c, http.StatusInternalServerError, "Failed to list invoice runs", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invoice runs retrieved successfully", runs)
+}
+
+// GetInvoice godoc
+// @Summary Get invoice
+// @Description Get a customer invoice with its number, line items, tax and allocated payments
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Payment ID"
+// @Success 200 {object} response.Response{data=entity.Payment}
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /payments/{id} [get]
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+
+	invoice, err := h.invoiceService.GetInvoice(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		invoiceError(c, "Failed to get invoice", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invoice retrieved successfully", invoice)
+}
+
+// DownloadInvoicePDF godoc
+// @Summary Download invoice or receipt PDF
+// @Description Render a customer invoice, or the receipt of the payments allocated to it, as PDF with the tenant's company details. Without type, paid invoices are rendered as receipts.
+// @Tags payments
+// @Produce application/pdf
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Payment ID"
+// @Param type query string false "invoice or receipt"
+// @Success 200 {file} file
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /payments/{id}/pdf [get]
+func (h *InvoiceHandler) DownloadInvoicePDF(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+
+	doc, err := h.documentService.RenderPaymentPDF(c.Request.Context(), tenantID, c.Param("id"), c.Query("type"))
+	if err != nil {
+		invoiceError(c, "Failed to render invoice", err)
+		return
+	}
+
+	sendPDF(c, doc)
+}
+
+// sendPDF writes a rendered document as a download
+func sendPDF(c *gin.Context, doc *usecase.RenderedDocument) {
+	c.Header("Content-Disposition", "attachment; filename="+doc.FileName)
+	c.Data(http.StatusOK, "application/pdf", doc.Content)
+}
+
+// AllocatePayment godoc
+// @Summary Allocate a payment to an invoice
+// @Description Record money received against an invoice. Partial payments leave the invoice partially_paid; it is paid once its balance is covered. Any amount above the balance is added to the customer's credit balance.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Payment ID"
+// @Param request body dto.AllocatePaymentRequest true "Allocation"
+// @Success 200 {object} response.Response{data=entity.Payment}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /payments/{id}/allocations [post]
+func (h *InvoiceHandler) AllocatePayment(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+
+	var req dto.AllocatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.SimpleError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	allocation := &entity.PaymentAllocation{
+		Amount:        req.Amount,
+		PaymentMethod: req.PaymentMethod,
+		Reference:     req.Reference,
+		Notes:         req.Notes,
+	}
+	if req.PaymentDate != "" {
+		paidAt, err := time.Parse(time.RFC3339, req.PaymentDate)
+		if err != nil {
+			paidAt, err = time.ParseInLocation("2006-01-02", req.PaymentDate, time.Local)
+		}
+		if err != nil {
+			response.SimpleError(c, http.StatusBadRequest, "Invalid payment_date", "payment_date must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		allocation.PaidAt = paidAt
+	}
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		allocation.CreatedBy = &userID
+	}
+
+	invoice, err := h.invoiceService.AllocatePayment(c.Request.Context(), tenantID, c.Param("id"), allocation)
+	if err != nil {
+		invoiceError(c, "Failed to allocate payment", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Payment allocated successfully", invoice)
+}
+
+// IssueCreditNote godoc
+// @Summary Issue a credit note for an invoice
+// @Description Credit an invoice. The credit lowers the balance; any part above the balance is refunded to the customer with refund_method (cash, transfer, or midtrans to refund the customer's Midtrans payment).
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Payment ID"
+// @Param request body dto.IssueCreditNoteRequest true "Credit note"
+// @Success 200 {object} response.Response{data=entity.Payment}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 502 {object} response.Response
+// @Security BearerAuth
+// @Router /payments/{id}/credit-notes [post]
+func (h *InvoiceHandler) IssueCreditNote(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+
+	var req dto.IssueCreditNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.SimpleError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	note := &entity.CreditNote{
+		Amount:       req.Amount,
+		Reason:       req.Reason,
+		RefundMethod: req.RefundMethod,
+	}
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		note.CreatedBy = &userID
+	}
+
+	invoice, err := h.invoiceService.IssueCreditNote(c.Request.Context(), tenantID, c.Param("id"), note)
+	if err != nil {
+		invoiceError(c, "Failed to issue credit note", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Credit note issued successfully", invoice)
+}
+
+// GetCustomerBalance godoc
+// @Summary Get a customer's credit balance
+// @Description Credit balance of a customer and its ledger, newest first. Overpayments and manual credits add to the balance; the balance is applied to new invoices.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Customer ID"
+// @Param limit query int false "Number of ledger entries" default(50)
+// @Success 200 {object} response.Response{data=usecase.CustomerStatement}
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /customers/{id}/balance [get]
+func (h *InvoiceHandler) GetCustomerBalance(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	statement, err := h.invoiceService.GetCustomerStatement(c.Request.Context(), tenantID, c.Param("id"), limit)
+	if err != nil {
+		invoiceError(c, "Failed to get customer balance", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Customer balance retrieved successfully", statement)
+}
+
+// AdjustCustomerBalance godoc
+// @Summary Adjust a customer's credit balance
+// @Description Record a manual credit or debit with a reason. A debit cannot exceed the balance.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Customer ID"
+// @Param request body dto.AdjustBalanceRequest true "Adjustment"
+// @Success 201 {object} response.Response{data=entity.CustomerLedgerEntry}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /customers/{id}/balance-adjustments [post]
+func (h *InvoiceHandler) AdjustCustomerBalance(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+
+	var req dto.AdjustBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.SimpleError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	entry := &entity.CustomerLedgerEntry{
+		CustomerID: c.Param("id"),
+		Type:       req.Type,
+		Amount:     req.Amount,
+		Reason:     req.Reason,
+	}
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		entry.CreatedBy = &userID
+	}
+
+	entry, err := h.invoiceService.AdjustCustomerBalance(c.Request.Context(), tenantID, entry)
+	if err != nil {
+		invoiceError(c, "Failed to adjust customer balance", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Customer balance adjusted successfully", entry)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+)
+
+type InvoicePaymentHandler struct {
+	invoicePaymentService usecase.InvoicePaymentService
+	webhookEventService   usecase.WebhookEventService
+}
+
+func NewInvoicePaymentHandler(invoicePaymentService usecase.InvoicePaymentService, webhookEventService usecase.WebhookEventService) *InvoicePaymentHandler {
+	return &InvoicePaymentHandler{
+		invoicePaymentService: invoicePaymentService,
+		webhookEventService:   webhookEventService,
+	}
+}
+
+func respondInvoicePaymentError(c *gin.Context, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		response.ErrorFromAppError(c, appErr)
+	} else {
+		response.InternalServerError(c, "SRV_9001", "Internal server error")
+	}
+}
+
+// GetPaymentPage godoc
+// @Summary      Get invoice payment page
+// @Description  Invoice summary and online payment methods for the customer payment link
+// @Tags         Public
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Invoice (payment) ID"
+// @Success      200  {object}  response.SuccessResponse{data=dto.InvoicePaymentPage}  "Invoice retrieved"
+// @Failure      404  {object}  response.ErrorResponse  "Invoice not found"
+// @Router       /public/invoices/{id} [get]
+func (h *InvoicePaymentHandler) GetPaymentPage(c *gin.Context) {
+	page, err := h.invoicePaymentService.GetPaymentPage(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondInvoicePaymentError(c, err)
+		return
+	}
+
+	response.OK(c, "Invoice retrieved", page)
+}
+
+// PayInvoice godoc
+// @Summary      Pay invoice online
+// @Description  Create a Midtrans charge (virtual account, GoPay or QRIS) for the invoice balance using the tenant's Midtrans account
+// @Tags         Public
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                 true  "Invoice (payment) ID"
+// @Param        request  body      dto.PayInvoiceRequest  true  "Payment method"
+// @Success      200      {object}  response.SuccessResponse{data=dto.InvoiceChargeResponse}  "Payment created"
+// @Failure      400      {object}  response.ErrorResponse  "Invoice paid or online payment disabled"
+// @Failure      404      {object}  response.ErrorResponse  "Invoice not found"
+// @Router       /public/invoices/{id}/pay [post]
+func (h *InvoicePaymentHandler) PayInvoice(c *gin.Context) {
+	var req dto.PayInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "VAL_2001", "Invalid request body", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	charge, err := h.invoicePaymentService.CreateCharge(c.Request.Context(), c.Param("id"), req.PaymentMethod)
+	if err != nil {
+		respondInvoicePaymentError(c, err)
+		return
+	}
+
+	response.OK(c, "Payment created", charge)
+}
+
+// MidtransWebhook godoc
+// @Summary      Tenant Midtrans notification webhook
+// @Description  Handle Midtrans notifications for invoice payments made through a tenant's Midtrans account. Set this URL as the notification URL in the tenant's Midtrans dashboard.
+// @Tags         Public
+// @Accept       json
+// @Produce      json
+// @Param        tenant_id  path      string  true  "Tenant ID"
+// @Success      200        {object}  response.SuccessResponse  "Notification processed"
+// @Failure      400        {object}  response.ErrorResponse  "Invalid notification data"
+// @Failure      401        {object}  response.ErrorResponse  "Invalid signature"
+// @Router       /webhooks/midtrans/tenants/{tenant_id} [post]
+func (h *InvoicePaymentHandler) MidtransWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil || len(body) == 0 {
+		response.BadRequest(c, "VAL_2001", "Invalid notification data", nil)
+		return
+	}
+
+	err = h.webhookEventService.Receive(c.Request.Context(), entity.WebhookSourceTenantMidtrans, c.Param("tenant_id"), c.Request.Header, body)
+	if err != nil {
+		respondInvoicePaymentError(c, err)
+		return
+	}
+
+	response.OK(c, "Notification processed", map[string]interface{}{
+		"status": "ok",
+	})
+}
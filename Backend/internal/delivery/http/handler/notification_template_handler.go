@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/middleware"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+	"github.com/rtrwnet/saas-backend/pkg/validator"
+)
+
+type NotificationTemplateHandler struct {
+	templateService usecase.NotificationTemplateService
+}
+
+func NewNotificationTemplateHandler(templateService usecase.NotificationTemplateService) *NotificationTemplateHandler {
+	return &NotificationTemplateHandler{
+		templateService: templateService,
+	}
+}
+
+// templateError writes a service error
+func templateError(c *gin.Context, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		response.ErrorFromAppError(c, appErr)
+		return
+	}
+	response.InternalServerError(c, "SRV_9001", "Internal server error")
+}
+
+// ListEvents handles the events with templates, their channels and variables
+func (h *NotificationTemplateHandler) ListEvents(c *gin.Context) {
+	response.OK(c, "Notification events retrieved successfully", h.templateService.ListEvents())
+}
+
+// ListTemplates handles the tenant's templates, optionally of one language
+func (h *NotificationTemplateHandler) ListTemplates(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	templates, err := h.templateService.ListTemplates(c.Request.Context(), tenantID, c.Query("language"))
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+
+	response.OK(c, "Notification templates retrieved successfully", templates)
+}
+
+// GetTemplate handles the tenant's template of an event, channel and language
+func (h *NotificationTemplateHandler) GetTemplate(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	template, err := h.templateService.GetTemplate(c.Request.Context(), tenantID, c.Param("event"), c.Param("channel"), c.Param("language"))
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+
+	response.OK(c, "Notification template retrieved successfully", template)
+}
+
+// SaveTemplate handles the tenant customizing a template
+func (h *NotificationTemplateHandler) SaveTemplate(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.SaveNotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	template, err := h.templateService.SaveTemplate(c.Request.Context(), tenantID, userID, &usecase.NotificationTemplateRequest{
+		Event:    c.Param("event"),
+		Channel:  c.Param("channel"),
+		Language: c.Param("language"),
+		Subject:  req.Subject,
+		Body:     req.Body,
+	})
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+
+	response.OK(c, "Notification template saved", template)
+}
+
+// ResetTemplate handles the tenant going back to the built-in template
+func (h *NotificationTemplateHandler) ResetTemplate(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	if err := h.templateService.ResetTemplate(c.Request.Context(), tenantID, c.Param("event"), c.Param("channel"), c.Param("language")); err != nil {
+		templateError(c, err)
+		return
+	}
+
+	response.OK(c, "Notification template reset to default", nil)
+}
+
+// Preview handles rendering a template with sample data
+func (h *NotificationTemplateHandler) Preview(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.PreviewNotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	rendered, err := h.templateService.Preview(c.Request.Context(), tenantID, &usecase.NotificationTemplateRequest{
+		Event:    req.Event,
+		Channel:  req.Channel,
+		Language: req.Language,
+		Subject:  req.Subject,
+		Body:     req.Body,
+	})
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+
+	response.OK(c, "Notification template rendered", rendered)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
 	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
 	"github.com/rtrwnet/saas-backend/pkg/response"
 )
 
@@ -174,7 +175,7 @@ func (h *SettingsHandler) UpdateTenantSettings(c *gin.Context) {
 
 // UpdateIntegrationSettings godoc
 // @Summary Update integration settings
-// @Description Update WhatsApp/Telegram integration settings (admin only)
+// @Description Update WhatsApp/Telegram integration settings and the Midtrans account used for online payment of customer invoices (admin only)
 // @Tags settings
 // @Accept json
 // @Produce json
@@ -198,7 +199,11 @@ func (h *SettingsHandler) UpdateIntegrationSettings(c *gin.Context) {
 
 	err := h.settingsService.UpdateIntegrationSettings(c.Request.Context(), tenantID, &req)
 	if err != nil {
-		response.SimpleError(c, http.StatusInternalServerError, "Failed to update integration settings", err.Error())
+		status := http.StatusInternalServerError
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Status != 0 {
+			status = appErr.Status
+		}
+		response.SimpleError(c, status, "Failed to update integration settings", err.Error())
 		return
 	}
 
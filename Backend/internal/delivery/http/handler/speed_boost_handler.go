@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/internal/middleware"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+	"github.com/rtrwnet/saas-backend/pkg/validator"
+)
+
+type SpeedBoostHandler struct {
+	speedBoostService usecase.SpeedBoostService
+}
+
+func NewSpeedBoostHandler(speedBoostService usecase.SpeedBoostService) *SpeedBoostHandler {
+	return &SpeedBoostHandler{
+		speedBoostService: speedBoostService,
+	}
+}
+
+// speedBoostError writes a service error
+func speedBoostError(c *gin.Context, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		response.ErrorFromAppError(c, appErr)
+		return
+	}
+	response.InternalServerError(c, "SRV_9001", "Internal server error")
+}
+
+// ListSpeedBoosts handles listing speed boosts, filtered by status and customer
+func (h *SpeedBoostHandler) ListSpeedBoosts(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	boosts, err := h.speedBoostService.ListBoosts(c.Request.Context(), tenantID, repository.SpeedBoostFilter{
+		Status:     c.Query("status"),
+		CustomerID: c.Query("customer_id"),
+		Limit:      limit,
+	})
+	if err != nil {
+		speedBoostError(c, err)
+		return
+	}
+
+	response.OK(c, "Speed boosts retrieved successfully", boosts)
+}
+
+// GetSpeedBoost handles getting a speed boost
+func (h *SpeedBoostHandler) GetSpeedBoost(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	boost, err := h.speedBoostService.GetBoost(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		speedBoostError(c, err)
+		return
+	}
+
+	response.OK(c, "Speed boost retrieved successfully", boost)
+}
+
+// RequestSpeedBoost handles an operator filing a speed boost for a customer
+func (h *SpeedBoostHandler) RequestSpeedBoost(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.SpeedBoostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	boostReq := &usecase.SpeedBoostRequest{
+		BoostPlanID:  req.BoostPlanID,
+		DurationDays: req.DurationDays,
+		Price:        req.Price,
+		Notes:        req.Notes,
+	}
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		boostReq.CreatedBy = &userID
+	}
+
+	boost, err := h.speedBoostService.RequestBoost(c.Request.Context(), tenantID, req.CustomerID, boostReq)
+	if err != nil {
+		speedBoostError(c, err)
+		return
+	}
+
+	response.Created(c, "Speed boost requested", boost)
+}
+
+// CreateCustomerLink handles signing the link a customer requests speed
+// boosts with
+func (h *SpeedBoostHandler) CreateCustomerLink(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.SpeedBoostLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	link, err := h.speedBoostService.CreateCustomerLink(c.Request.Context(), tenantID, req.CustomerID)
+	if err != nil {
+		speedBoostError(c, err)
+		return
+	}
+
+	response.Created(c, "Speed boost link created", link)
+}
+
+// RequestCustomerSpeedBoost handles a customer requesting a speed boost from
+// the public API with the token of their link. The boost waits for an
+// operator's approval.
+func (h *SpeedBoostHandler) RequestCustomerSpeedBoost(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.CustomerSpeedBoostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	boost, err := h.speedBoostService.RequestCustomerBoost(c.Request.Context(), tenantID, req.Token, &usecase.SpeedBoostRequest{
+		BoostPlanID:  req.BoostPlanID,
+		DurationDays: req.DurationDays,
+		Notes:        req.Notes,
+	})
+	if err != nil {
+		speedBoostError(c, err)
+		return
+	}
+
+	response.Created(c, "Speed boost requested, waiting for approval", gin.H{
+		"id":            boost.ID,
+		"status":        boost.Status,
+		"duration_days": boost.DurationDays,
+		"price":         boost.Price,
+	})
+}
+
+// ApproveSpeedBoost handles approving a pending speed boost
+func (h *SpeedBoostHandler) ApproveSpeedBoost(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.ApproveSpeedBoostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	approval := &usecase.SpeedBoostApproval{Price: req.Price}
+	if req.StartDate != "" {
+		start, err := time.Parse(time.RFC3339, req.StartDate)
+		if err != nil {
+			start, err = time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		}
+		if err != nil {
+			response.BadRequest(c, "VAL_2001", "Validation failed", map[string]interface{}{
+				"start_date": "start_date must be RFC3339 or YYYY-MM-DD",
+			})
+			return
+		}
+		approval.StartDate = &start
+	}
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		approval.ReviewedBy = &userID
+	}
+
+	boost, err := h.speedBoostService.ApproveBoost(c.Request.Context(), tenantID, c.Param("id"), approval)
+	if err != nil {
+		speedBoostError(c, err)
+		return
+	}
+
+	response.OK(c, "Speed boost approved", boost)
+}
+
+// RejectSpeedBoost handles rejecting a pending speed boost
+func (h *SpeedBoostHandler) RejectSpeedBoost(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.RejectSpeedBoostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	var reviewedBy *string
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		reviewedBy = &userID
+	}
+
+	boost, err := h.speedBoostService.RejectBoost(c.Request.Context(), tenantID, c.Param("id"), req.Reason, reviewedBy)
+	if err != nil {
+		speedBoostError(c, err)
+		return
+	}
+
+	response.OK(c, "Speed boost rejected", boost)
+}
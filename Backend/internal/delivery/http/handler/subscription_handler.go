@@ -3,6 +3,7 @@ package handler
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/rtrwnet/saas-backend/internal/usecase"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
 	"github.com/rtrwnet/saas-backend/pkg/response"
@@ -10,11 +11,13 @@ import (
 
 type SubscriptionHandler struct {
 	subscriptionService usecase.SubscriptionService
+	webhookEventService usecase.WebhookEventService
 }
 
-func NewSubscriptionHandler(subscriptionService usecase.SubscriptionService) *SubscriptionHandler {
+func NewSubscriptionHandler(subscriptionService usecase.SubscriptionService, webhookEventService usecase.WebhookEventService) *SubscriptionHandler {
 	return &SubscriptionHandler{
 		subscriptionService: subscriptionService,
+		webhookEventService: webhookEventService,
 	}
 }
 
@@ -108,7 +111,7 @@ func (h *SubscriptionHandler) SignUp(c *gin.Context) {
 
 // PaymentWebhook godoc
 // @Summary      Payment gateway webhook
-// @Description  Handle payment gateway callbacks for subscription payments. Processes payment status updates and activates subscriptions.
+// @Description  Handle payment gateway callbacks for subscription payments. Processes payment status updates and activates subscriptions. Each order and status is applied once.
 // @Tags         Public
 // @Accept       json
 // @Produce      json
@@ -118,90 +121,49 @@ func (h *SubscriptionHandler) SignUp(c *gin.Context) {
 // @Failure      500      {object}  response.ErrorResponse  "Internal server error"
 // @Router       /webhooks/payment [post]
 func (h *SubscriptionHandler) PaymentWebhook(c *gin.Context) {
-	var req dto.PaymentWebhookRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "VAL_2001", "Validation failed", map[string]interface{}{
-			"error": "Invalid webhook request data",
-		})
-		return
-	}
-
-	// TODO: Verify webhook signature from payment gateway
-
-	err := h.subscriptionService.ProcessPayment(
-		c.Request.Context(),
-		req.OrderID,
-		req.Status,
-		req.PaymentMethod,
-		req.GatewayTransactionID,
-	)
-
-	if err != nil {
-		if appErr, ok := err.(*errors.AppError); ok {
-			response.ErrorFromAppError(c, appErr)
-		} else {
-			response.InternalServerError(c, "SRV_9001", "Internal server error")
-		}
-		return
-	}
-
-	response.OK(c, "Payment processed successfully", map[string]interface{}{
-		"status": "ok",
-	})
+	h.receiveWebhook(c, entity.WebhookSourcePayment)
 }
 
 // MidtransWebhook godoc
 // @Summary      Midtrans payment notification webhook
-// @Description  Handle Midtrans payment notification callbacks
+// @Description  Handle Midtrans payment notification callbacks. The notification signature is verified with the server key.
 // @Tags         Public
 // @Accept       json
 // @Produce      json
 // @Success      200      {object}  response.SuccessResponse  "Notification processed"
 // @Failure      400      {object}  response.ErrorResponse  "Invalid notification data"
+// @Failure      401      {object}  response.ErrorResponse  "Invalid signature"
 // @Failure      500      {object}  response.ErrorResponse  "Internal server error"
 // @Router       /webhooks/midtrans [post]
 func (h *SubscriptionHandler) MidtransWebhook(c *gin.Context) {
-	var notification map[string]interface{}
-	if err := c.ShouldBindJSON(&notification); err != nil {
-		response.BadRequest(c, "VAL_2001", "Invalid notification data", nil)
-		return
-	}
+	h.receiveWebhook(c, entity.WebhookSourceMidtrans)
+}
 
-	orderID, _ := notification["order_id"].(string)
-	transactionStatus, _ := notification["transaction_status"].(string)
-	fraudStatus, _ := notification["fraud_status"].(string)
-	paymentType, _ := notification["payment_type"].(string)
-	transactionID, _ := notification["transaction_id"].(string)
+// XenditWebhook godoc
+// @Summary      Xendit invoice callback webhook
+// @Description  Handle Xendit invoice callbacks. The x-callback-token header is verified with the configured callback token.
+// @Tags         Public
+// @Accept       json
+// @Produce      json
+// @Success      200      {object}  response.SuccessResponse  "Notification processed"
+// @Failure      400      {object}  response.ErrorResponse  "Invalid notification data"
+// @Failure      401      {object}  response.ErrorResponse  "Invalid signature"
+// @Failure      500      {object}  response.ErrorResponse  "Internal server error"
+// @Router       /webhooks/xendit [post]
+func (h *SubscriptionHandler) XenditWebhook(c *gin.Context) {
+	h.receiveWebhook(c, entity.WebhookSourceXendit)
+}
 
-	// Determine payment status
-	var status string
-	switch transactionStatus {
-	case "capture":
-		if fraudStatus == "accept" {
-			status = "paid"
-		} else {
-			status = "pending"
-		}
-	case "settlement":
-		status = "paid"
-	case "pending":
-		status = "pending"
-	case "deny", "cancel", "expire":
-		status = "failed"
-	case "refund":
-		status = "refunded"
-	default:
-		status = transactionStatus
+// receiveWebhook passes the raw webhook to the webhook event log, which
+// verifies and processes it
+func (h *SubscriptionHandler) receiveWebhook(c *gin.Context, source string) {
+	body, err := c.GetRawData()
+	if err != nil || len(body) == 0 {
+		response.BadRequest(c, "VAL_2001", "Invalid notification data", nil)
+		return
 	}
 
-	err := h.subscriptionService.ProcessPayment(
-		c.Request.Context(),
-		orderID,
-		status,
-		paymentType,
-		transactionID,
-	)
-
+	err = h.webhookEventService.Receive(c.Request.Context(), source, "", c.Request.Header, body)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
 			response.ErrorFromAppError(c, appErr)
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/middleware"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+)
+
+type TelegramHandler struct {
+	telegramService usecase.TelegramBotService
+}
+
+func NewTelegramHandler(telegramService usecase.TelegramBotService) *TelegramHandler {
+	return &TelegramHandler{
+		telegramService: telegramService,
+	}
+}
+
+// telegramError writes a service error
+func telegramError(c *gin.Context, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		response.ErrorFromAppError(c, appErr)
+		return
+	}
+	response.InternalServerError(c, "SRV_9001", "Internal server error")
+}
+
+// CreateLinkCode handles a user asking for a code to link their Telegram chat
+func (h *TelegramHandler) CreateLinkCode(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	link, err := h.telegramService.CreateLinkCode(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		telegramError(c, err)
+		return
+	}
+
+	response.Created(c, "Telegram link code created", link)
+}
+
+// ListChats handles the tenant's linked Telegram chats
+func (h *TelegramHandler) ListChats(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	chats, err := h.telegramService.ListChats(c.Request.Context(), tenantID)
+	if err != nil {
+		telegramError(c, err)
+		return
+	}
+
+	response.OK(c, "Telegram chats retrieved successfully", chats)
+}
+
+// UnlinkChat handles removing a linked Telegram chat
+func (h *TelegramHandler) UnlinkChat(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	if err := h.telegramService.UnlinkChat(c.Request.Context(), tenantID, c.Param("id")); err != nil {
+		telegramError(c, err)
+		return
+	}
+
+	response.OK(c, "Telegram chat unlinked", nil)
+}
+
+// Webhook handles updates Telegram posts for the tenant's bot
+func (h *TelegramHandler) Webhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil || len(body) == 0 {
+		response.BadRequest(c, "VAL_2001", "Invalid update data", nil)
+		return
+	}
+
+	secret := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+	if err := h.telegramService.HandleWebhook(c.Request.Context(), c.Param("tenant_id"), secret, body); err != nil {
+		telegramError(c, err)
+		return
+	}
+
+	response.OK(c, "Update processed", map[string]interface{}{
+		"status": "ok",
+	})
+}
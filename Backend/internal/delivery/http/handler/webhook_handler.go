@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/middleware"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+	"github.com/rtrwnet/saas-backend/pkg/validator"
+)
+
+type WebhookHandler struct {
+	webhookService usecase.WebhookService
+}
+
+func NewWebhookHandler(webhookService usecase.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// webhookError writes a service error
+func webhookError(c *gin.Context, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		response.ErrorFromAppError(c, appErr)
+		return
+	}
+	response.InternalServerError(c, "SRV_9001", "Internal server error")
+}
+
+// ListEvents handles the events webhooks can subscribe to
+func (h *WebhookHandler) ListEvents(c *gin.Context) {
+	response.OK(c, "Webhook events retrieved successfully", h.webhookService.ListEvents())
+}
+
+// ListWebhooks handles the tenant's webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context(), tenantID)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhooks retrieved successfully", webhooks)
+}
+
+// GetWebhook handles a webhook of the tenant
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook retrieved successfully", webhook)
+}
+
+// CreateWebhook handles registering a webhook. The response holds the
+// signing secret, which is not shown again.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.Created(c, "Webhook created", webhook)
+}
+
+// UpdateWebhook handles changing a webhook
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), tenantID, c.Param("id"), &req)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook updated", webhook)
+}
+
+// DeleteWebhook handles removing a webhook with its deliveries
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), tenantID, c.Param("id")); err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook deleted", nil)
+}
+
+// RotateSecret handles replacing the signing secret of a webhook
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	webhook, err := h.webhookService.RotateSecret(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook secret rotated", webhook)
+}
+
+// Ping handles sending a test event to a webhook
+func (h *WebhookHandler) Ping(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	delivery, err := h.webhookService.Ping(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook ping sent", delivery)
+}
+
+// ListDeliveries handles the latest deliveries of a webhook, optionally
+// filtered by ?status=
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), tenantID, c.Param("id"), c.Query("status"), limit)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook deliveries retrieved successfully", deliveries)
+}
+
+// GetDelivery handles a webhook delivery with its payload and last response
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	delivery, err := h.webhookService.GetDelivery(c.Request.Context(), tenantID, c.Param("delivery_id"))
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook delivery retrieved successfully", delivery)
+}
+
+// Redeliver handles sending a delivery's payload again
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), tenantID, c.Param("delivery_id"))
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+
+	response.Created(c, "Webhook redelivery sent", delivery)
+}
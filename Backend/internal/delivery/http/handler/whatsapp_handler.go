@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/middleware"
+	"github.com/rtrwnet/saas-backend/internal/usecase"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/response"
+	"github.com/rtrwnet/saas-backend/pkg/validator"
+)
+
+type WhatsAppHandler struct {
+	whatsAppService usecase.WhatsAppService
+}
+
+func NewWhatsAppHandler(whatsAppService usecase.WhatsAppService) *WhatsAppHandler {
+	return &WhatsAppHandler{
+		whatsAppService: whatsAppService,
+	}
+}
+
+// whatsAppError writes a service error
+func whatsAppError(c *gin.Context, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		response.ErrorFromAppError(c, appErr)
+		return
+	}
+	response.InternalServerError(c, "SRV_9001", "Internal server error")
+}
+
+// SendCustomerMessage handles an operator sending a WhatsApp message to a customer
+func (h *WhatsAppHandler) SendCustomerMessage(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	var req dto.SendWhatsAppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrors := validator.ParseValidationErrors(err, req)
+		response.BadRequest(c, "VAL_2001", "Validation failed", validationErrors.ToMap())
+		return
+	}
+
+	var sentBy *string
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		sentBy = &userID
+	}
+
+	message, err := h.whatsAppService.SendToCustomer(c.Request.Context(), tenantID, c.Param("id"), req.Message, sentBy)
+	if err != nil {
+		whatsAppError(c, err)
+		return
+	}
+
+	response.Created(c, "WhatsApp message sent", message)
+}
+
+// ListCustomerMessages handles the WhatsApp messages sent to a customer
+func (h *WhatsAppHandler) ListCustomerMessages(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	messages, err := h.whatsAppService.ListMessages(c.Request.Context(), tenantID, c.Param("id"), limit)
+	if err != nil {
+		whatsAppError(c, err)
+		return
+	}
+
+	response.OK(c, "WhatsApp messages retrieved successfully", messages)
+}
+
+// GetStatusWebhook handles the callback URL the tenant sets for delivery
+// statuses in their WhatsApp gateway dashboard
+func (h *WhatsAppHandler) GetStatusWebhook(c *gin.Context) {
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		response.ErrorFromAppError(c, err.(*errors.AppError))
+		return
+	}
+
+	token := h.whatsAppService.StatusWebhookToken(tenantID)
+	if token == "" {
+		response.InternalServerError(c, "SRV_9002", "WhatsApp status webhook not configured")
+		return
+	}
+
+	response.OK(c, "WhatsApp status webhook retrieved successfully", map[string]interface{}{
+		"path": fmt.Sprintf("/api/v1/webhooks/whatsapp/tenants/%s?token=%s", tenantID, token),
+	})
+}
+
+// StatusWebhook handles delivery status callbacks of the tenant's WhatsApp
+// gateway. The gateway cannot sign them, so the callback URL carries the
+// tenant's token in ?token=.
+func (h *WhatsAppHandler) StatusWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil || len(body) == 0 {
+		response.BadRequest(c, "VAL_2001", "Invalid status data", nil)
+		return
+	}
+
+	if err := h.whatsAppService.HandleStatus(c.Request.Context(), c.Param("tenant_id"), c.Query("token"), body); err != nil {
+		whatsAppError(c, err)
+		return
+	}
+
+	response.OK(c, "Status processed", map[string]interface{}{
+		"status": "ok",
+	})
+}
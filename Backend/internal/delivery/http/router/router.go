@@ -9,6 +9,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"github.com/rtrwnet/saas-backend/internal/delivery/http/handler"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/rtrwnet/saas-backend/internal/infrastructure/cache"
 	"github.com/rtrwnet/saas-backend/internal/middleware"
 	"github.com/rtrwnet/saas-backend/internal/repository/postgres"
@@ -17,8 +18,13 @@ import (
 	"github.com/rtrwnet/saas-backend/pkg/email"
 	"github.com/rtrwnet/saas-backend/pkg/logger"
 	"github.com/rtrwnet/saas-backend/pkg/payment"
+	"github.com/rtrwnet/saas-backend/pkg/radius"
+	"github.com/rtrwnet/saas-backend/pkg/routeros"
 	"github.com/rtrwnet/saas-backend/pkg/storage"
 	"github.com/rtrwnet/saas-backend/pkg/websocket"
+	"github.com/rtrwnet/saas-backend/pkg/telegram"
+	"github.com/rtrwnet/saas-backend/pkg/webhook"
+	"github.com/rtrwnet/saas-backend/pkg/whatsapp"
 	"gorm.io/gorm"
 
 	swaggerFiles "github.com/swaggo/files"
@@ -31,7 +37,23 @@ type RouterConfig struct {
 	Config    *config.Config
 }
 
-func SetupRouter(cfg *RouterConfig) *gin.Engine {
+// Services are the services the background jobs run. They are the ones the
+// handlers use, so requests and jobs share one service graph.
+type Services struct {
+	InvoiceGenerator     usecase.InvoiceGeneratorService
+	PlanChange           usecase.PlanChangeService
+	SubscriptionRenewal  usecase.SubscriptionRenewalService
+	SpeedBoost           usecase.SpeedBoostService
+	Prepaid              usecase.PrepaidService
+	AutoSuspend          usecase.AutoSuspendService
+	LateFee              usecase.LateFeeService
+	NotificationDispatch usecase.NotificationDispatchService
+	TelegramBot          usecase.TelegramBotService
+	Webhook              usecase.WebhookService
+	HotspotSession       usecase.HotspotSessionService
+}
+
+func SetupRouter(cfg *RouterConfig) (*gin.Engine, *Services) {
 	router := gin.Default()
 
 	// Apply global middleware
@@ -58,7 +80,22 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 	ticketRepo := postgres.NewTicketRepository(cfg.DB)
 	infraRepo := postgres.NewInfrastructureRepository(cfg.DB)
 	deviceRepo := postgres.NewDeviceRepository(cfg.DB)
+	queueSyncRepo := postgres.NewQueueSyncRepository(cfg.DB)
 	settingsRepo := postgres.NewSettingsRepository(cfg.DB)
+	invoiceRunRepo := postgres.NewInvoiceRunRepository(cfg.DB)
+	invoiceRepo := postgres.NewInvoiceRepository(cfg.DB)
+	autoSuspendRepo := postgres.NewAutoSuspendRepository(cfg.DB)
+	lateFeeRepo := postgres.NewLateFeeRepository(cfg.DB)
+	prepaidRepo := postgres.NewPrepaidRepository(cfg.DB)
+	planChangeRepo := postgres.NewPlanChangeRepository(cfg.DB)
+	speedBoostRepo := postgres.NewSpeedBoostRepository(cfg.DB)
+	customerNotificationRepo := postgres.NewCustomerNotificationRepository(cfg.DB)
+	whatsAppRepo := postgres.NewWhatsAppRepository(cfg.DB)
+	telegramRepo := postgres.NewTelegramRepository(cfg.DB)
+	notificationTemplateRepo := postgres.NewNotificationTemplateRepository(cfg.DB)
+	webhookRepo := postgres.NewWebhookRepository(cfg.DB)
+	invoicePaymentOrderRepo := postgres.NewInvoicePaymentOrderRepository(cfg.DB)
+	webhookEventRepo := postgres.NewWebhookEventRepository(cfg.DB)
 	chatRepo := postgres.NewChatRepository(cfg.DB)
 
 	// Admin repositories
@@ -82,35 +119,32 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 	// Initialize services
 	authService := usecase.NewAuthService(userRepo, tenantRepo, &cfg.Config.JWT, cfg.Cache)
 	tenantService := usecase.NewTenantService(tenantRepo)
-	subscriptionService := usecase.NewSubscriptionService(planRepo, tenantRepo, userRepo, subscriptionRepo, transactionRepo)
-	dashboardService := usecase.NewDashboardService(cfg.DB, customerRepo, paymentRepo, servicePlanRepo, tenantRepo, userRepo, subscriptionRepo, planRepo)
-	billingService := usecase.NewBillingService(tenantRepo, subscriptionRepo, planRepo, transactionRepo)
-	ticketService := usecase.NewTicketService(ticketRepo, customerRepo)
-	infraService := usecase.NewInfrastructureService(infraRepo)
-	mikrotikService := usecase.NewMikrotikService()
-	deviceService := usecase.NewDeviceService(deviceRepo, mikrotikService)
-	settingsService := usecase.NewSettingsService(settingsRepo, userRepo)
-	radiusService := usecase.NewRadiusService(cfg.DB)
-	vpnService := usecase.NewVPNService(cfg.DB)
-
-	// FreeRADIUS sync service
-	freeradiusSync := usecase.NewFreeRADIUSSyncService(cfg.DB)
+	// Payment gateways for platform payments, only those with credentials are registered
+	paymentGateways := payment.NewGateways(cfg.Config.Payment.Gateway)
+	if cfg.Config.Midtrans.ServerKey != "" {
+		paymentGateways.Register(payment.NewMidtransClient(&payment.MidtransConfig{
+			ServerKey:    cfg.Config.Midtrans.ServerKey,
+			ClientKey:    cfg.Config.Midtrans.ClientKey,
+			IsProduction: cfg.Config.Midtrans.IsProduction,
+		}))
+	}
+	if cfg.Config.Xendit.SecretKey != "" {
+		paymentGateways.Register(payment.NewXenditClient(&payment.XenditConfig{
+			SecretKey:     cfg.Config.Xendit.SecretKey,
+			CallbackToken: cfg.Config.Xendit.CallbackToken,
+		}))
+	}
 
-	// Hotspot services
-	hotspotPackageService := usecase.NewHotspotPackageService(hotspotPackageRepo)
-	hotspotVoucherService := usecase.NewHotspotVoucherService(hotspotVoucherRepo, hotspotPackageRepo, freeradiusSync)
-	captivePortalService := usecase.NewCaptivePortalService(captivePortalRepo, hotspotVoucherRepo, hotspotPackageRepo)
-	
-	// Note: hotspotSessionService requires RADIUS server which is now handled by FreeRADIUS
-	hotspotSessionService := usecase.NewHotspotSessionService(hotspotVoucherRepo, hotspotPackageRepo, nil)
-
-	// Initialize Midtrans client
-	midtransConfig := &payment.MidtransConfig{
-		ServerKey:    cfg.Config.Midtrans.ServerKey,
-		ClientKey:    cfg.Config.Midtrans.ClientKey,
-		IsProduction: cfg.Config.Midtrans.IsProduction,
+	// Notification service
+	var notificationService usecase.NotificationService
+	if cfg.Cache != nil {
+		notificationService = usecase.NewNotificationService(cfg.DB, cfg.Cache.Client())
+	} else {
+		notificationService = usecase.NewNotificationService(cfg.DB, nil)
 	}
-	midtransClient := payment.NewMidtransClient(midtransConfig)
+
+	// Customer and staff messages are rendered from the tenant's templates
+	notificationTemplateService := usecase.NewNotificationTemplateService(notificationTemplateRepo, settingsRepo)
 
 	// Initialize Email service (optional - nil if not configured)
 	var emailService *email.Service
@@ -132,19 +166,108 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		})
 	}
 
+	subscriptionService := usecase.NewSubscriptionServiceWithNotification(planRepo, tenantRepo, userRepo, subscriptionRepo, transactionRepo, paymentGateways, notificationService, notificationTemplateService)
+	// Tenant subscriptions are renewed and chased on the platform's billing policy
+	subscriptionRenewalService := usecase.NewSubscriptionRenewalService(
+		subscriptionRepo,
+		transactionRepo,
+		postgres.NewSubscriptionDunningRepository(cfg.DB),
+		notificationService,
+		notificationTemplateService,
+		usecase.RenewalPolicy{
+			LeadDays:        cfg.Config.Billing.RenewalLeadDays,
+			ReminderDays:    cfg.Config.Billing.RenewalReminderDays,
+			GracePeriodDays: cfg.Config.Billing.GracePeriodDays,
+			SuspensionDays:  cfg.Config.Billing.SuspensionDays,
+		},
+	)
+	coaService := usecase.NewRadiusCoAService(cfg.DB, radius.NewClient(cfg.Config.Radius.CoATimeout, cfg.Config.Radius.CoARetries), cfg.Config.Radius.CoAPort)
+	invoiceService := usecase.NewInvoiceService(invoiceRepo, settingsRepo)
+	planChangeService := usecase.NewPlanChangeService(planChangeRepo, invoiceRepo, settingsRepo, tenantRepo, usecase.NewFreeRADIUSSyncService(cfg.DB), coaService)
+	dashboardService := usecase.NewDashboardService(cfg.DB, customerRepo, paymentRepo, servicePlanRepo, tenantRepo, userRepo, subscriptionRepo, planRepo, coaService, invoiceService, planChangeService)
+	speedBoostService := usecase.NewSpeedBoostService(speedBoostRepo, invoiceRepo, settingsRepo, usecase.NewFreeRADIUSSyncService(cfg.DB), coaService,
+		cfg.Config.Billing.SpeedBoostLinkSecret, cfg.Config.Billing.SpeedBoostPageURL)
+	// Prepaid customers get paid periods added to their RADIUS expiry and are
+	// emailed before it lapses
+	var customerMailer usecase.CustomerMailer
+	if emailService != nil {
+		customerMailer = emailService
+	}
+	prepaidService := usecase.NewPrepaidService(prepaidRepo, settingsRepo, tenantRepo, usecase.NewFreeRADIUSSyncService(cfg.DB), customerMailer, notificationTemplateService)
+	autoSuspendService := usecase.NewAutoSuspendService(autoSuspendRepo, settingsRepo, tenantRepo, customerRepo, dashboardService, prepaidService)
+	// Paid invoices extend prepaid service and reactivate suspended customers
+	invoiceService.SetPaymentReactivator(autoSuspendService)
+	invoicePaymentService := usecase.NewInvoicePaymentService(invoiceRepo, invoicePaymentOrderRepo, settingsRepo, invoiceService, autoSuspendService)
+	webhookEventService := usecase.NewWebhookEventService(webhookEventRepo, map[string]usecase.WebhookProcessor{
+		entity.WebhookSourceMidtrans:       usecase.NewGatewayWebhookProcessor(payment.GatewayMidtrans, subscriptionService),
+		entity.WebhookSourceXendit:         usecase.NewGatewayWebhookProcessor(payment.GatewayXendit, subscriptionService),
+		entity.WebhookSourcePayment:        usecase.NewPaymentWebhookProcessor(subscriptionService),
+		entity.WebhookSourceTenantMidtrans: usecase.NewInvoiceWebhookProcessor(invoicePaymentService),
+	})
+	lateFeeService := usecase.NewLateFeeService(lateFeeRepo, paymentRepo, settingsRepo, tenantRepo, autoSuspendService)
+	billingService := usecase.NewBillingService(tenantRepo, subscriptionRepo, planRepo, transactionRepo)
+	documentService := usecase.NewDocumentService(invoiceRepo, settingsRepo, tenantRepo, planRepo, transactionRepo, usecase.PlatformInfo{
+		Name:    cfg.Config.Billing.PlatformName,
+		Address: cfg.Config.Billing.PlatformAddress,
+		Email:   cfg.Config.Billing.PlatformEmail,
+	})
+	ticketService := usecase.NewTicketService(ticketRepo, customerRepo, notificationService, notificationTemplateService)
+	infraService := usecase.NewInfrastructureService(infraRepo)
+	mikrotikPool := routeros.NewPool(routeros.PoolConfig{
+		MaxConnsPerRouter: cfg.Config.Mikrotik.MaxConnsPerRouter,
+		IdleTimeout:       cfg.Config.Mikrotik.IdleTimeout,
+		DialRetries:       cfg.Config.Mikrotik.DialRetries,
+		FailureThreshold:  cfg.Config.Mikrotik.FailureThreshold,
+		Cooldown:          cfg.Config.Mikrotik.CircuitCooldown,
+	})
+	mikrotikService := usecase.NewMikrotikService(mikrotikPool)
+	deviceService := usecase.NewDeviceService(deviceRepo, queueSyncRepo, mikrotikService)
+	invoiceGenerator := usecase.NewInvoiceGeneratorService(invoiceRunRepo, settingsRepo, tenantRepo)
+	settingsService := usecase.NewSettingsService(settingsRepo, userRepo)
+	radiusService := usecase.NewRadiusService(cfg.DB)
+	vpnService := usecase.NewVPNService(cfg.DB)
+
+	// FreeRADIUS sync service
+	freeradiusSync := usecase.NewFreeRADIUSSyncService(cfg.DB)
+
+	// Hotspot services
+	hotspotPackageService := usecase.NewHotspotPackageService(hotspotPackageRepo)
+	hotspotVoucherService := usecase.NewHotspotVoucherService(hotspotVoucherRepo, hotspotPackageRepo, freeradiusSync, notificationTemplateService)
+	captivePortalService := usecase.NewCaptivePortalService(captivePortalRepo, hotspotVoucherRepo, hotspotPackageRepo)
+	
+	// Hotspot sessions are read from the FreeRADIUS accounting table
+	hotspotSessionService := usecase.NewHotspotSessionService(hotspotVoucherRepo, hotspotPackageRepo, usecase.NewRadacctHotspotServer(cfg.DB, coaService))
+
+	// WhatsApp messages go through the tenant's own gateway device
+	whatsAppService := usecase.NewWhatsAppService(whatsAppRepo, settingsRepo,
+		whatsapp.NewProvider(cfg.Config.WhatsApp.Provider, cfg.Config.WhatsApp.APIURL), cfg.Config.WhatsApp.RatePerMinute, cfg.Config.WhatsApp.WebhookSecret)
+
+	// Operators link their chats to the tenant's Telegram bot
+	telegramBotService := usecase.NewTelegramBotService(telegramRepo, settingsRepo, tenantRepo,
+		telegram.NewClient(&telegram.Config{BaseURL: cfg.Config.Telegram.APIURL}), invoiceService, dashboardService, dashboardService,
+		notificationTemplateService, cfg.Config.Telegram.WebhookURL, cfg.Config.Telegram.OverdueHour)
+
+	// Tenant webhooks receive signed events of the tenant
+	webhookService := usecase.NewWebhookService(webhookRepo, subscriptionRepo, planRepo,
+		webhook.NewClient(&webhook.Config{
+			Timeout:      cfg.Config.Webhook.Timeout,
+			AllowPrivate: cfg.Config.Webhook.AllowPrivate,
+			UserAgent:    "RTRWNet-Webhook/1.0",
+		}))
+
+	// Customer billing notifications go out over every configured channel
+	customerChannels := []usecase.CustomerChannel{usecase.NewWhatsAppChannel(whatsAppService)}
+	if emailService != nil {
+		customerChannels = append(customerChannels, usecase.NewEmailChannel(emailService))
+	}
+	notificationDispatchService := usecase.NewNotificationDispatchService(customerNotificationRepo, settingsRepo, tenantRepo,
+		notificationTemplateService, cfg.Config.Billing.PaymentPageURL, customerChannels...)
+
 	// OTP service
-	otpService := usecase.NewOTPService(otpRepo, userRepo, emailService)
+	otpService := usecase.NewOTPService(otpRepo, userRepo, emailService, notificationTemplateService)
 	
 	// Payment service
-	paymentService := usecase.NewPaymentService(transactionRepo, tenantRepo, subscriptionRepo, planRepo, userRepo, midtransClient)
-
-	// Notification service
-	var notificationService usecase.NotificationService
-	if cfg.Cache != nil {
-		notificationService = usecase.NewNotificationService(cfg.DB, cfg.Cache.Client())
-	} else {
-		notificationService = usecase.NewNotificationService(cfg.DB, nil)
-	}
+	paymentService := usecase.NewPaymentService(transactionRepo, tenantRepo, subscriptionRepo, planRepo, userRepo, paymentGateways)
 
 	// Support ticket service (for user dashboard) - with notification
 	supportTicketService := usecase.NewSupportTicketServiceWithNotification(supportTicketRepo, notificationService)
@@ -159,23 +282,33 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		tenantRepo,
 		userRepo,
 		transactionRepo,
-		midtransClient,
+		subscriptionRepo,
+		paymentGateways,
 		cfg.Config.JWT.Secret,
 		notificationService,
+		notificationTemplateService,
 	)
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
 	tenantHandler := handler.NewTenantHandler(tenantService)
-	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
-	dashboardHandler := handler.NewDashboardHandler(dashboardService)
-	billingHandler := handler.NewBillingHandler(billingService)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService, webhookEventService)
+	dashboardHandler := handler.NewDashboardHandler(dashboardService, autoSuspendService, lateFeeService, planChangeService)
+	billingHandler := handler.NewBillingHandler(billingService, documentService)
 	paymentHandler := handler.NewPaymentHandler(paymentService)
 	ticketHandler := handler.NewTicketHandler(ticketService)
 	infraHandler := handler.NewInfrastructureHandler(infraService)
 	deviceHandler := handler.NewDeviceHandler(deviceService)
+	invoiceHandler := handler.NewInvoiceHandler(invoiceGenerator, invoiceService, documentService)
+	speedBoostHandler := handler.NewSpeedBoostHandler(speedBoostService)
+	customerNotificationHandler := handler.NewCustomerNotificationHandler(notificationDispatchService)
+	whatsAppHandler := handler.NewWhatsAppHandler(whatsAppService)
+	telegramHandler := handler.NewTelegramHandler(telegramBotService)
+	notificationTemplateHandler := handler.NewNotificationTemplateHandler(notificationTemplateService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	invoicePaymentHandler := handler.NewInvoicePaymentHandler(invoicePaymentService, webhookEventService)
 	settingsHandler := handler.NewSettingsHandler(settingsService)
-	adminHandler := handler.NewAdminHandler(adminService)
+	adminHandler := handler.NewAdminHandler(adminService, webhookEventService)
 	otpHandler := handler.NewOTPHandler(otpService)
 	supportTicketHandler := handler.NewSupportTicketHandler(supportTicketService)
 	notificationHandler := handler.NewNotificationHandler(notificationService)
@@ -251,9 +384,18 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			public.POST("/payments", subscriptionHandler.CreatePayment)
 			public.GET("/payments/:order_id/status", subscriptionHandler.GetPaymentStatus)
 
+			// Customer invoice payment (tenant's Midtrans account)
+			public.GET("/invoices/:id", invoicePaymentHandler.GetPaymentPage)
+			public.POST("/invoices/:id/pay", invoicePaymentHandler.PayInvoice)
+
 			// Captive portal public routes
 			public.GET("/hotspot/portal/:tenant_id", captivePortalHandler.GetPortalPage)
 			public.POST("/hotspot/login", captivePortalHandler.AuthenticateUser)
+
+			// Speed boost requested by the customer through their signed link,
+			// tenant from X-Tenant-ID
+			public.POST("/speed-boosts", middleware.PerIPRateLimiter(redisClient, 5, time.Minute), tenantMiddleware.ExtractTenant(),
+				planLimitMiddleware.CheckFeature("speed_boost"), speedBoostHandler.RequestCustomerSpeedBoost)
 		}
 
 		// Webhook routes
@@ -261,6 +403,10 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		{
 			webhooks.POST("/payment", subscriptionHandler.PaymentWebhook)
 			webhooks.POST("/midtrans", subscriptionHandler.MidtransWebhook)
+			webhooks.POST("/xendit", subscriptionHandler.XenditWebhook)
+			webhooks.POST("/midtrans/tenants/:tenant_id", invoicePaymentHandler.MidtransWebhook)
+			webhooks.POST("/whatsapp/tenants/:tenant_id", whatsAppHandler.StatusWebhook)
+			webhooks.POST("/telegram/:tenant_id", telegramHandler.Webhook)
 		}
 
 		// Auth routes
@@ -344,6 +490,13 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 				adminProtected.GET("/payments/stats", adminHandler.GetPaymentStats)
 				adminProtected.GET("/payments/:id", adminHandler.GetPaymentTransaction)
 				adminProtected.POST("/payments/:order_id/reconcile", adminHandler.ReconcilePayment)
+				adminProtected.POST("/payments/:order_id/refund", adminHandler.RefundPayment)
+				adminProtected.POST("/payments/:order_id/cancel", adminHandler.CancelPayment)
+
+				// Inbound payment webhooks
+				adminProtected.GET("/webhook-events", adminHandler.ListWebhookEvents)
+				adminProtected.GET("/webhook-events/:id", adminHandler.GetWebhookEvent)
+				adminProtected.POST("/webhook-events/:id/replay", adminHandler.ReplayWebhookEvent)
 
 				// Admin notifications
 				adminProtected.GET("/notifications", notificationHandler.GetAdminNotifications)
@@ -414,6 +567,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			// Customer management (with feature and limit check)
 			customers := protected.Group("/customers")
 			customers.Use(planLimitMiddleware.CheckFeature("customer_management"))
+			customers.Use(planLimitMiddleware.RequireWritable())
 			{
 				customers.GET("", dashboardHandler.ListCustomers)
 				customers.GET("/:id", dashboardHandler.GetCustomerDetail)
@@ -425,6 +579,15 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 				customers.POST("/:id/activate", dashboardHandler.ActivateCustomer)
 				customers.POST("/:id/suspend", dashboardHandler.SuspendCustomer)
 				customers.POST("/:id/terminate", dashboardHandler.TerminateCustomer)
+				customers.GET("/:id/billing-actions", dashboardHandler.ListBillingActions)
+				customers.POST("/:id/plan-change", dashboardHandler.ChangeCustomerPlan)
+				customers.DELETE("/:id/plan-change/:change_id", dashboardHandler.CancelPlanChange)
+				customers.GET("/:id/plan-history", dashboardHandler.ListPlanHistory)
+				customers.GET("/:id/balance", invoiceHandler.GetCustomerBalance)
+				customers.POST("/:id/balance-adjustments", invoiceHandler.AdjustCustomerBalance)
+				customers.GET("/:id/notifications", customerNotificationHandler.ListCustomerNotifications)
+				customers.GET("/:id/whatsapp", whatsAppHandler.ListCustomerMessages)
+				customers.POST("/:id/whatsapp", whatsAppHandler.SendCustomerMessage)
 				
 				// Customer hotspot management
 				customers.POST("/:id/hotspot/enable", customerHotspotHandler.EnableHotspot)
@@ -441,13 +604,37 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			// Payment management (with feature check)
 			payments := protected.Group("/payments")
 			payments.Use(planLimitMiddleware.CheckFeature("billing_management"))
+			payments.Use(planLimitMiddleware.RequireWritable())
 			{
 				payments.GET("", dashboardHandler.ListPayments)
 				payments.POST("", dashboardHandler.RecordPayment)
+				payments.POST("/:id/pay", dashboardHandler.MarkPaymentPaid)
+				payments.POST("/:id/waive-late-fee", dashboardHandler.WaiveLateFee)
+				payments.POST("/generate", invoiceHandler.GenerateInvoices)
+				payments.GET("/invoice-runs", invoiceHandler.ListInvoiceRuns)
+				payments.POST("/notifications/dispatch", customerNotificationHandler.DispatchNotifications)
+				payments.GET("/:id", invoiceHandler.GetInvoice)
+				payments.GET("/:id/pdf", invoiceHandler.DownloadInvoicePDF)
+				payments.POST("/:id/allocations", invoiceHandler.AllocatePayment)
+				payments.POST("/:id/credit-notes", invoiceHandler.IssueCreditNote)
+			}
+
+			// Speed boost (temporary plan upgrade)
+			speedBoosts := protected.Group("/speed-boosts")
+			speedBoosts.Use(planLimitMiddleware.CheckFeature("speed_boost"))
+			speedBoosts.Use(planLimitMiddleware.RequireWritable())
+			{
+				speedBoosts.GET("", speedBoostHandler.ListSpeedBoosts)
+				speedBoosts.POST("", speedBoostHandler.RequestSpeedBoost)
+				speedBoosts.POST("/links", speedBoostHandler.CreateCustomerLink)
+				speedBoosts.GET("/:id", speedBoostHandler.GetSpeedBoost)
+				speedBoosts.POST("/:id/approve", speedBoostHandler.ApproveSpeedBoost)
+				speedBoosts.POST("/:id/reject", speedBoostHandler.RejectSpeedBoost)
 			}
 
 			// Service plan management
 			servicePlans := protected.Group("/service-plans")
+			servicePlans.Use(planLimitMiddleware.RequireWritable())
 			{
 				servicePlans.GET("", dashboardHandler.ListServicePlans)
 				servicePlans.POST("", dashboardHandler.CreateServicePlan)
@@ -466,6 +653,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			
 			// Ticket management (internal customer tickets)
 			tickets := protected.Group("/tickets")
+			tickets.Use(planLimitMiddleware.RequireWritable())
 			{
 				tickets.GET("", ticketHandler.ListTickets)
 				tickets.POST("", ticketHandler.CreateTicket)
@@ -489,6 +677,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			// Infrastructure management (requires network_monitoring feature)
 			infra := protected.Group("/infrastructure")
 			infra.Use(planLimitMiddleware.CheckFeature("network_monitoring"))
+			infra.Use(planLimitMiddleware.RequireWritable())
 			{
 				// OLT routes
 				infra.GET("/olts", infraHandler.ListOLTs)
@@ -515,6 +704,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			// Device management (requires device_management feature)
 			devices := protected.Group("/devices")
 			devices.Use(planLimitMiddleware.CheckFeature("device_management"))
+			devices.Use(planLimitMiddleware.RequireWritable())
 			{
 				devices.GET("", deviceHandler.ListDevices)
 				devices.POST("", planLimitMiddleware.CheckResourceLimit("devices", func(ctx context.Context, tenantID string) (int, error) {
@@ -525,11 +715,13 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 				devices.DELETE("/:id", deviceHandler.DeleteDevice)
 				devices.POST("/:id/test-connection", planLimitMiddleware.CheckFeature("mikrotik_integration"), deviceHandler.TestMikrotikConnection)
 				devices.POST("/:id/sync-queues", planLimitMiddleware.CheckFeature("mikrotik_integration"), deviceHandler.SyncMikrotikQueues)
+				devices.GET("/:id/sync-reports", planLimitMiddleware.CheckFeature("mikrotik_integration"), deviceHandler.ListQueueSyncReports)
 			}
 
 			// RADIUS management (requires mikrotik_integration feature)
 			radius := protected.Group("/radius")
 			radius.Use(planLimitMiddleware.CheckFeature("mikrotik_integration"))
+			radius.Use(planLimitMiddleware.RequireWritable())
 			{
 				// NAS management
 				radius.GET("/nas", radiusHandler.ListNAS)
@@ -572,6 +764,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			// VPN management (for connecting MikroTik to VPS)
 			vpn := protected.Group("/vpn")
 			vpn.Use(planLimitMiddleware.CheckFeature("mikrotik_integration"))
+			vpn.Use(planLimitMiddleware.RequireWritable())
 			{
 				// Generate MikroTik script with VPN + RADIUS config
 				vpn.GET("/mikrotik-script/:id", vpnHandler.GenerateMikroTikScript)
@@ -591,6 +784,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			// ============================================
 			hotspot := protected.Group("/hotspot")
 			hotspot.Use(planLimitMiddleware.CheckFeature("hotspot_management"))
+			hotspot.Use(planLimitMiddleware.RequireWritable())
 			{
 				// Package management
 				hotspot.GET("/packages", hotspotPackageHandler.ListPackages)
@@ -620,6 +814,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 			protected.PUT("/billing/subscription", billingHandler.UpdateSubscription)
 			protected.POST("/billing/order", billingHandler.CreateOrder)
 			protected.GET("/billing/pending-order", billingHandler.GetPendingOrder)
+			protected.GET("/billing/invoices/:id/pdf", billingHandler.DownloadInvoicePDF)
 			protected.PUT("/billing/settings", billingHandler.UpdateTenantSettings)
 			protected.POST("/billing/cancel", billingHandler.CancelSubscription)
 			protected.PUT("/billing/payment-method", billingHandler.UpdatePaymentMethod)
@@ -633,10 +828,40 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 				settings.GET("/tenant", settingsHandler.GetTenantSettings)
 				settings.PUT("/tenant", settingsHandler.UpdateTenantSettings)
 				settings.PUT("/integrations", settingsHandler.UpdateIntegrationSettings)
+				settings.GET("/whatsapp/webhook", whatsAppHandler.GetStatusWebhook)
+				settings.POST("/telegram/link", telegramHandler.CreateLinkCode)
+				settings.GET("/telegram/chats", telegramHandler.ListChats)
+				settings.DELETE("/telegram/chats/:id", telegramHandler.UnlinkChat)
+				settings.GET("/notification-templates", notificationTemplateHandler.ListTemplates)
+				settings.GET("/notification-templates/events", notificationTemplateHandler.ListEvents)
+				settings.POST("/notification-templates/preview", notificationTemplateHandler.Preview)
+				settings.GET("/notification-templates/:event/:channel/:language", notificationTemplateHandler.GetTemplate)
+				settings.PUT("/notification-templates/:event/:channel/:language", notificationTemplateHandler.SaveTemplate)
+				settings.DELETE("/notification-templates/:event/:channel/:language", notificationTemplateHandler.ResetTemplate)
 				settings.PUT("/profile", settingsHandler.UpdateProfile)
 				settings.PUT("/password", settingsHandler.ChangePassword)
 			}
 
+			// Tenant webhooks (requires webhook_support feature)
+			tenantWebhooks := protected.Group("/settings/webhooks")
+			tenantWebhooks.Use(planLimitMiddleware.CheckFeature("webhook_support"))
+			tenantWebhooks.Use(planLimitMiddleware.RequireWritable())
+			{
+				tenantWebhooks.GET("", webhookHandler.ListWebhooks)
+				tenantWebhooks.GET("/events", webhookHandler.ListEvents)
+				tenantWebhooks.POST("", planLimitMiddleware.CheckResourceLimit("webhooks", func(ctx context.Context, tenantID string) (int, error) {
+					return webhookRepo.CountByTenant(ctx, tenantID)
+				}), webhookHandler.CreateWebhook)
+				tenantWebhooks.GET("/:id", webhookHandler.GetWebhook)
+				tenantWebhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+				tenantWebhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+				tenantWebhooks.POST("/:id/rotate-secret", webhookHandler.RotateSecret)
+				tenantWebhooks.POST("/:id/ping", webhookHandler.Ping)
+				tenantWebhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+				tenantWebhooks.GET("/:id/deliveries/:delivery_id", webhookHandler.GetDelivery)
+				tenantWebhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+			}
+
 			// Upload routes (avatar)
 			upload := protected.Group("/upload")
 			{
@@ -658,5 +883,17 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		}
 	}
 
-	return router
+	return router, &Services{
+		InvoiceGenerator:     invoiceGenerator,
+		PlanChange:           planChangeService,
+		SubscriptionRenewal:  subscriptionRenewalService,
+		SpeedBoost:           speedBoostService,
+		Prepaid:              prepaidService,
+		AutoSuspend:          autoSuspendService,
+		LateFee:              lateFeeService,
+		NotificationDispatch: notificationDispatchService,
+		TelegramBot:          telegramBotService,
+		Webhook:              webhookService,
+		HotspotSession:       hotspotSessionService,
+	}
 }
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BillingActionLog records an automatic action the billing jobs took on a
+// customer
+type BillingActionLog struct {
+	ID         string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID   string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID string    `gorm:"type:uuid;not null;index" json:"customer_id"`
+	PaymentID  *string   `gorm:"type:uuid" json:"payment_id,omitempty"`
+	Action     string    `gorm:"not null" json:"action"` // auto_suspend, auto_reactivate, late_fee, late_fee_waived, service_extend, expiry_reminder
+	Reason     string    `gorm:"type:text" json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (l *BillingActionLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+	return nil
+}
+
+const (
+	BillingActionAutoSuspend    = "auto_suspend"
+	BillingActionAutoReactivate = "auto_reactivate"
+	BillingActionLateFee        = "late_fee"
+	BillingActionLateFeeWaived  = "late_fee_waived"
+	BillingActionServiceExtend  = "service_extend"
+	BillingActionExpiryReminder = "expiry_reminder"
+)
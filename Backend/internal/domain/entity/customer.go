@@ -59,7 +59,21 @@ type Customer struct {
 	InstallationDate time.Time  `json:"installation_date"`
 	DueDate          int        `gorm:"not null;default:15" json:"due_date"` // day of month
 	MonthlyFee       float64    `gorm:"not null" json:"monthly_fee"`
+	CreditBalance    float64    `gorm:"default:0" json:"credit_balance"` // unused credit, see CustomerLedgerEntry
 	Notes            string     `gorm:"type:text" json:"notes"`
+
+	// Prepaid billing: service runs until ServiceUntil and each paid invoice
+	// extends it by the billed period
+	ServiceUntil     *time.Time `gorm:"column:service_until" json:"service_until,omitempty"`
+	ExpiryRemindedAt *time.Time `gorm:"column:expiry_reminded_at" json:"expiry_reminded_at,omitempty"`
+
+	// Auto-suspend exemption and marker for suspensions made by the billing job
+	AutoSuspendExempt      bool       `gorm:"column:auto_suspend_exempt;default:false" json:"auto_suspend_exempt"`
+	AutoSuspendExemptUntil *time.Time `gorm:"column:auto_suspend_exempt_until" json:"auto_suspend_exempt_until,omitempty"`
+	AutoSuspendedAt        *time.Time `gorm:"column:auto_suspended_at" json:"auto_suspended_at,omitempty"`
+	// Last time the customer was suspended, by hand or by the billing job
+	SuspendedAt *time.Time `gorm:"column:suspended_at" json:"suspended_at,omitempty"`
+
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 	DeletedAt        *time.Time `gorm:"index" json:"deleted_at,omitempty"`
@@ -79,3 +93,12 @@ func (c *Customer) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// IsAutoSuspendExempt reports whether the billing job must leave the customer
+// connected: permanently, or until AutoSuspendExemptUntil has passed
+func (c *Customer) IsAutoSuspendExempt(now time.Time) bool {
+	if c.AutoSuspendExempt {
+		return true
+	}
+	return c.AutoSuspendExemptUntil != nil && now.Before(*c.AutoSuspendExemptUntil)
+}
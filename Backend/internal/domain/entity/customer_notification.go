@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CustomerNotification is one billing message to a customer on one channel.
+// DedupKey names the message across dispatcher runs so each one is sent
+// once; failed sends are retried up to a few attempts.
+type CustomerNotification struct {
+	ID         string     `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID   string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID string     `gorm:"type:uuid;not null;index" json:"customer_id"`
+	PaymentID  *string    `gorm:"type:uuid" json:"payment_id,omitempty"`
+	Event      string     `gorm:"size:30;not null" json:"event"`   // one of the customer NotificationEvent constants
+	Channel    string     `gorm:"size:20;not null" json:"channel"` // email, whatsapp
+	Recipient  string     `gorm:"not null" json:"recipient"`
+	Subject    string     `json:"subject"`
+	Body       string     `gorm:"type:text" json:"body"`
+	Status     string     `gorm:"size:20;not null" json:"status"` // sending, sent, failed
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	Attempts   int        `gorm:"not null;default:1" json:"attempts"`
+	DedupKey   string     `gorm:"size:150;not null;uniqueIndex" json:"-"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (n *CustomerNotification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Customer notification events
+const (
+	NotificationEventPaymentReminder     = "payment_reminder"
+	NotificationEventOverdueNotice       = "overdue_notice"
+	NotificationEventPaymentConfirmation = "payment_confirmation"
+	NotificationEventSuspensionWarning   = "suspension_warning"
+	NotificationEventInvoiceCreated      = "invoice_created"
+	NotificationEventSuspended           = "suspended"
+	NotificationEventTicketResolved      = "ticket_resolved"
+	NotificationEventServiceExpiring     = "service_expiring" // prepaid service ends soon
+	NotificationEventVoucherSold         = "voucher_sold"     // hotspot voucher handed to a buyer
+)
+
+// Customer notification channels
+const (
+	NotificationChannelEmail    = "email"
+	NotificationChannelWhatsApp = "whatsapp"
+	NotificationChannelTelegram = "telegram" // operator chats of the tenant's bot
+	NotificationChannelInApp    = "in_app"   // notifications of the tenant's users in the dashboard
+)
+
+// Customer notification delivery statuses
+const (
+	NotificationDeliverySending = "sending"
+	NotificationDeliverySent    = "sent"
+	NotificationDeliveryFailed  = "failed"
+)
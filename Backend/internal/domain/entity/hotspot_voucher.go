@@ -22,6 +22,10 @@ type HotspotVoucher struct {
 	CreatedAt       time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt       time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 
+	// Message is the voucher_sold text to hand to the buyer, with the
+	// password in clear. It is only set on freshly generated vouchers.
+	Message string `gorm:"-" json:"message,omitempty"`
+
 	// Relations
 	Tenant      *Tenant         `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
 	Package     *HotspotPackage `gorm:"foreignKey:PackageID" json:"package,omitempty"`
@@ -0,0 +1,192 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvoiceItem is one line of a customer invoice. Customer invoices are
+// stored as Payment rows; discount lines have a negative Amount.
+type InvoiceItem struct {
+	ID          string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID    string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	PaymentID   string    `gorm:"type:uuid;not null;index" json:"payment_id"`
+	Type        string    `gorm:"not null" json:"type"` // monthly_fee, installation, addon, late_fee, discount, proration, other
+	Description string    `gorm:"type:text;not null" json:"description"`
+	Quantity    float64   `gorm:"not null;default:1" json:"quantity"`
+	UnitPrice   float64   `gorm:"not null" json:"unit_price"`
+	Amount      float64   `gorm:"not null" json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (i *InvoiceItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
+
+const (
+	InvoiceItemMonthlyFee   = "monthly_fee"
+	InvoiceItemInstallation = "installation"
+	InvoiceItemAddon        = "addon"
+	InvoiceItemLateFee      = "late_fee" // kept in Payment.LateFee, not part of Amount
+	InvoiceItemDiscount     = "discount"
+	InvoiceItemProration    = "proration" // plan change settlement, negative when credited
+	InvoiceItemOther        = "other"
+)
+
+// PaymentAllocation is money received from the customer and allocated
+// against an invoice. An invoice is paid once its allocations cover Total.
+type PaymentAllocation struct {
+	ID            string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID      string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID    string    `gorm:"type:uuid;not null;index" json:"customer_id"`
+	PaymentID     string    `gorm:"type:uuid;not null;index" json:"payment_id"`
+	Amount        float64   `gorm:"not null" json:"amount"`
+	PaymentMethod string    `json:"payment_method"` // transfer, cash, e-wallet
+	PaidAt        time.Time `gorm:"not null" json:"paid_at"`
+	Reference     string    `json:"reference,omitempty"`
+	Notes         string    `gorm:"type:text" json:"notes,omitempty"`
+	CreatedBy     *string   `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (a *PaymentAllocation) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// CreditNote reduces what a customer owes on an invoice. On an invoice that
+// was already paid, RefundAmount of the credit is paid back to the customer.
+type CreditNote struct {
+	ID               string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID         string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID       string    `gorm:"type:uuid;not null;index" json:"customer_id"`
+	PaymentID        string    `gorm:"type:uuid;not null;index" json:"payment_id"`
+	CreditNoteNumber string    `gorm:"size:50;not null" json:"credit_note_number"` // unique per tenant, see FormatCreditNoteNumber
+	Amount           float64   `gorm:"not null" json:"amount"`
+	RefundAmount     float64   `gorm:"default:0" json:"refund_amount"`
+	RefundMethod     string    `json:"refund_method,omitempty"` // cash, transfer, midtrans
+	RefundReference  string    `json:"refund_reference,omitempty"`
+	Reason           string    `gorm:"type:text;not null" json:"reason"`
+	Status           string    `gorm:"size:20;not null;default:issued" json:"status"`
+	CreatedBy        *string   `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (c *CreditNote) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+const (
+	CreditNoteRefundCash     = "cash"
+	CreditNoteRefundTransfer = "transfer"
+	CreditNoteRefundMidtrans = "midtrans" // back through the tenant's Midtrans account
+
+	// A note refunded through the gateway is pending until the gateway
+	// answers; only issued notes change the invoice
+	CreditNoteStatusPending = "pending"
+	CreditNoteStatusIssued  = "issued"
+	CreditNoteStatusFailed  = "failed"
+)
+
+// CustomerLedgerEntry is a movement of a customer's prepaid balance, kept in
+// Customer.CreditBalance. Overpayments and manual credits add to it; debits
+// take from it, including the credit applied to new invoices.
+type CustomerLedgerEntry struct {
+	ID           string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID     string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID   string    `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Type         string    `gorm:"size:10;not null" json:"type"`   // credit, debit
+	Source       string    `gorm:"size:20;not null" json:"source"` // overpayment, invoice, manual, plan_change
+	Amount       float64   `gorm:"not null" json:"amount"`
+	BalanceAfter float64   `gorm:"not null" json:"balance_after"`
+	PaymentID    *string   `gorm:"type:uuid" json:"payment_id,omitempty"` // invoice that was overpaid or paid from the balance
+	Reason       string    `gorm:"type:text" json:"reason"`
+	CreatedBy    *string   `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (e *CustomerLedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+const (
+	LedgerEntryCredit = "credit"
+	LedgerEntryDebit  = "debit"
+
+	LedgerSourceOverpayment = "overpayment"
+	LedgerSourceInvoice     = "invoice" // credit applied to an invoice
+	LedgerSourceManual      = "manual"
+	LedgerSourcePlanChange  = "plan_change" // prorated credit of a downgrade
+
+	// PaymentMethodCreditBalance marks allocations paid from the customer's balance
+	PaymentMethodCreditBalance = "credit_balance"
+	// PaymentMethodLateFeeWaiver marks invoices settled by waiving their late fee
+	PaymentMethodLateFeeWaiver = "late_fee_waiver"
+)
+
+// InvoiceSequence holds the last invoice and credit note numbers issued by a tenant
+type InvoiceSequence struct {
+	TenantID             string    `gorm:"primaryKey;type:uuid" json:"tenant_id"`
+	LastNumber           int64     `gorm:"not null;default:0" json:"last_number"`
+	LastCreditNoteNumber int64     `gorm:"not null;default:0" json:"last_credit_note_number"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// FormatInvoiceNumber renders the n-th invoice number of a tenant, e.g. INV-000042
+func FormatInvoiceNumber(prefix string, n int64) string {
+	if prefix == "" {
+		prefix = "INV"
+	}
+	return fmt.Sprintf("%s-%06d", prefix, n)
+}
+
+// FormatCreditNoteNumber renders the n-th credit note number of a tenant, e.g. CN-000007
+func FormatCreditNoteNumber(n int64) string {
+	return fmt.Sprintf("CN-%06d", n)
+}
+
+// InvoicePaymentOrder is an online charge created on the tenant's Midtrans
+// account for a customer invoice. OrderID is unique per tenant.
+type InvoicePaymentOrder struct {
+	ID                   string     `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID             string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID           string     `gorm:"type:uuid;not null" json:"customer_id"`
+	PaymentID            string     `gorm:"type:uuid;not null;index" json:"payment_id"`
+	OrderID              string     `gorm:"not null" json:"order_id"`
+	Amount               float64    `gorm:"not null" json:"amount"`
+	PaymentMethod        string     `gorm:"not null" json:"payment_method"` // bca_va, bni_va, bri_va, permata_va, mandiri_bill, gopay, qris
+	Status               string     `gorm:"not null;default:'pending'" json:"status"`
+	GatewayTransactionID string     `json:"gateway_transaction_id,omitempty"`
+	GatewayResponse      string     `gorm:"type:jsonb" json:"-"`
+	ExpiredAt            *time.Time `json:"expired_at,omitempty"`
+	PaidAt               *time.Time `json:"paid_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+func (o *InvoicePaymentOrder) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = uuid.New().String()
+	}
+	return nil
+}
+
+const (
+	InvoicePaymentOrderPending = "pending"
+	InvoicePaymentOrderPaid    = "paid"
+	InvoicePaymentOrderFailed  = "failed"
+)
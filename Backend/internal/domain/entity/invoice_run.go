@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvoiceRun records one run of the recurring invoice generator for a tenant
+type InvoiceRun struct {
+	ID            string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID      string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	RunDate       time.Time `gorm:"type:date;not null" json:"run_date"`
+	TriggeredBy   string    `gorm:"not null" json:"triggered_by"` // scheduled, manual
+	DryRun        bool      `gorm:"not null;default:false" json:"dry_run"`
+	Status        string    `gorm:"not null" json:"status"` // planned, success, partial, failed
+	BillingType   string    `gorm:"not null" json:"billing_type"`
+	CustomerCount int       `json:"customer_count"`
+	CreatedCount  int       `json:"created_count"`
+	SkippedCount  int       `json:"skipped_count"`
+	NotDueCount   int       `json:"not_due_count"` // outside the generation window, not listed in Invoices
+	FailedCount   int       `json:"failed_count"`
+	TotalAmount   float64   `json:"total_amount"`
+	Invoices      string    `gorm:"type:jsonb" json:"invoices"` // JSON array of created/skipped/failed invoices
+	ErrorMessage  string    `gorm:"type:text" json:"error_message,omitempty"`
+	StartedAt     time.Time `gorm:"not null" json:"started_at"`
+	FinishedAt    time.Time `gorm:"not null" json:"finished_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (r *InvoiceRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	if r.Invoices == "" {
+		r.Invoices = "[]"
+	}
+	return nil
+}
+
+const (
+	InvoiceRunTriggerScheduled = "scheduled"
+	InvoiceRunTriggerManual    = "manual"
+)
+
+const (
+	InvoiceRunStatusPlanned = "planned"
+	InvoiceRunStatusSuccess = "success"
+	InvoiceRunStatusPartial = "partial"
+	InvoiceRunStatusFailed  = "failed"
+)
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationTemplate is a tenant's own text for the message of an event on
+// one channel in one language. Messages without one use the built-in text.
+// Subject is the email subject or the in-app title; other channels only send
+// Body.
+type NotificationTemplate struct {
+	ID        string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID  string    `gorm:"type:uuid;not null;uniqueIndex:idx_notification_templates_key,priority:1" json:"tenant_id"`
+	Event     string    `gorm:"size:30;not null;uniqueIndex:idx_notification_templates_key,priority:2" json:"event"`
+	Channel   string    `gorm:"size:20;not null;uniqueIndex:idx_notification_templates_key,priority:3" json:"channel"`
+	Language  string    `gorm:"size:5;not null;uniqueIndex:idx_notification_templates_key,priority:4" json:"language"`
+	Subject   string    `gorm:"size:255" json:"subject,omitempty"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	UpdatedBy *string   `gorm:"type:uuid" json:"updated_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Customized is false for a built-in template the tenant has not changed
+	Customized bool `gorm:"-" json:"customized"`
+}
+
+func (NotificationTemplate) TableName() string {
+	return "notification_templates"
+}
+
+func (t *NotificationTemplate) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Staff notification events; customer events are the NotificationEvent
+// constants of CustomerNotification
+const (
+	NotificationEventTicketCreated = "ticket_created"
+)
+
+// Platform notification events. The platform sends these to tenants and
+// their users with built-in texts that tenants cannot change.
+const (
+	NotificationEventEmailOTP              = "email_otp"
+	NotificationEventSubscriptionActive    = "subscription_active"
+	NotificationEventSubscriptionRenewal   = "subscription_renewal"
+	NotificationEventSubscriptionExpiring  = "subscription_expiring"
+	NotificationEventSubscriptionExpired   = "subscription_expired"
+	NotificationEventSubscriptionPastDue   = "subscription_past_due"
+	NotificationEventSubscriptionSuspended = "subscription_suspended"
+	NotificationEventTrialExpiring         = "trial_expiring"
+	NotificationEventTrialConverted        = "trial_converted"
+	NotificationEventTrialReadOnly         = "trial_read_only"
+	NotificationEventPaymentRefunded       = "payment_refunded"
+	NotificationEventPaymentCancelled      = "payment_cancelled"
+)
+
+// Notification languages
+const (
+	NotificationLanguageIndonesian = "id"
+	NotificationLanguageEnglish    = "en"
+)
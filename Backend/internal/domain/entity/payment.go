@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,19 +9,39 @@ import (
 )
 
 type Payment struct {
-	ID            string     `gorm:"primaryKey;type:uuid" json:"id"`
-	TenantID      string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
-	CustomerID    string     `gorm:"type:uuid;not null;index" json:"customer_id"`
-	Amount        float64    `gorm:"not null" json:"amount"`
-	PaymentDate   *time.Time `json:"payment_date,omitempty"`
-	DueDate       time.Time  `gorm:"not null" json:"due_date"`
-	Status        string     `gorm:"not null;default:'pending'" json:"status"` // pending, paid, overdue
-	PaymentMethod string     `json:"payment_method"`                           // transfer, cash, e-wallet
-	Notes         string     `gorm:"type:text" json:"notes"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-	Tenant        *Tenant    `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
-	Customer      *Customer  `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
+	ID             string     `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID       string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID     string     `gorm:"type:uuid;not null;index" json:"customer_id"`
+	InvoiceNumber  *string    `gorm:"size:50" json:"invoice_number,omitempty"` // unique per tenant, see FormatInvoiceNumber
+	Subtotal       float64    `gorm:"default:0" json:"subtotal"`
+	DiscountAmount float64    `gorm:"default:0" json:"discount_amount"`
+	TaxPercentage  float64    `gorm:"default:0" json:"tax_percentage"`
+	TaxAmount      float64    `gorm:"default:0" json:"tax_amount"`
+	Amount         float64    `gorm:"not null" json:"amount"`           // Subtotal - DiscountAmount + TaxAmount, without late fee
+	PaidAmount     float64    `gorm:"default:0" json:"paid_amount"`     // sum of Allocations less refunds
+	CreditedAmount float64    `gorm:"default:0" json:"credited_amount"` // sum of CreditNotes
+	PaymentDate    *time.Time `json:"payment_date,omitempty"`
+	DueDate        time.Time  `gorm:"not null" json:"due_date"`
+	Status         string     `gorm:"not null;default:'pending'" json:"status"` // pending, partially_paid, paid, overdue
+	PaymentMethod  string     `json:"payment_method"`                           // transfer, cash, e-wallet
+	Notes          string     `gorm:"type:text" json:"notes"`
+	PeriodStart    *time.Time `gorm:"type:date" json:"period_start,omitempty"` // billing cycle covered by the invoice
+	PeriodEnd      *time.Time `gorm:"type:date" json:"period_end,omitempty"`
+	// Prepaid billing: when the paid invoice extended Customer.ServiceUntil
+	ServiceExtendedAt *time.Time `json:"service_extended_at,omitempty"`
+	// Late fee charged on top of Amount once the invoice is past the grace period
+	LateFee            float64             `gorm:"default:0" json:"late_fee"`
+	LateFeeAppliedAt   *time.Time          `json:"late_fee_applied_at,omitempty"`
+	LateFeeWaivedAt    *time.Time          `json:"late_fee_waived_at,omitempty"`
+	LateFeeWaivedBy    *string             `gorm:"type:uuid" json:"late_fee_waived_by,omitempty"`
+	LateFeeWaiveReason string              `gorm:"type:text" json:"late_fee_waive_reason,omitempty"`
+	CreatedAt          time.Time           `json:"created_at"`
+	UpdatedAt          time.Time           `json:"updated_at"`
+	Tenant             *Tenant             `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	Customer           *Customer           `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
+	Items              []InvoiceItem       `gorm:"foreignKey:PaymentID" json:"items,omitempty"`
+	Allocations        []PaymentAllocation `gorm:"foreignKey:PaymentID" json:"allocations,omitempty"`
+	CreditNotes        []CreditNote        `gorm:"foreignKey:PaymentID" json:"credit_notes,omitempty"`
 }
 
 func (p *Payment) BeforeCreate(tx *gorm.DB) error {
@@ -30,8 +51,32 @@ func (p *Payment) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// LateFeeDue returns the late fee the customer still owes, zero when waived
+func (p *Payment) LateFeeDue() float64 {
+	if p.LateFeeWaivedAt != nil {
+		return 0
+	}
+	return p.LateFee
+}
+
+// Total returns the invoice amount plus the outstanding late fee
+func (p *Payment) Total() float64 {
+	return p.Amount + p.LateFeeDue()
+}
+
+// Balance returns what the customer still has to pay on the invoice
+func (p *Payment) Balance() float64 {
+	return p.Total() - p.CreditedAmount - p.PaidAmount
+}
+
+// RoundMoney rounds to the cent, matching the DECIMAL(12,2) columns
+func RoundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
 const (
-	PaymentStatusPending = "pending"
-	PaymentStatusPaid    = "paid"
-	PaymentStatusOverdue = "overdue"
+	PaymentStatusPending       = "pending"
+	PaymentStatusPartiallyPaid = "partially_paid" // part of the balance has been paid
+	PaymentStatusPaid          = "paid"
+	PaymentStatusOverdue       = "overdue"
 )
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CustomerPlanChange is one entry of a customer's service plan history.
+// Immediate changes apply when they are made; next-cycle changes wait for
+// the first day of the customer's next billing cycle. A change applied
+// within a cycle is prorated against the cycle's invoice.
+type CustomerPlanChange struct {
+	ID             string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID       string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID     string    `gorm:"type:uuid;not null;index" json:"customer_id"`
+	FromPlanID     string    `gorm:"type:uuid;not null" json:"from_plan_id"`
+	ToPlanID       string    `gorm:"type:uuid;not null" json:"to_plan_id"`
+	FromMonthlyFee float64   `gorm:"not null" json:"from_monthly_fee"`
+	ToMonthlyFee   float64   `gorm:"not null" json:"to_monthly_fee"`
+	Effective      string    `gorm:"size:20;not null" json:"effective"` // immediate, next_cycle
+	EffectiveDate  time.Time `gorm:"type:date;not null" json:"effective_date"`
+	Status         string    `gorm:"size:20;not null;default:'scheduled'" json:"status"` // scheduled, applied, cancelled
+	// Net of the proration lines, negative when the customer was credited
+	ProrationAmount    float64      `gorm:"default:0" json:"proration_amount"`
+	ProrationPaymentID *string      `gorm:"type:uuid" json:"proration_payment_id,omitempty"` // invoice carrying the proration lines
+	Reason             string       `gorm:"type:text" json:"reason,omitempty"`
+	CreatedBy          *string      `gorm:"type:uuid" json:"created_by,omitempty"`
+	AppliedAt          *time.Time   `json:"applied_at,omitempty"`
+	CancelledAt        *time.Time   `json:"cancelled_at,omitempty"`
+	CreatedAt          time.Time    `json:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at"`
+	FromPlan           *ServicePlan `gorm:"foreignKey:FromPlanID" json:"from_plan,omitempty"`
+	ToPlan             *ServicePlan `gorm:"foreignKey:ToPlanID" json:"to_plan,omitempty"`
+}
+
+func (c *CustomerPlanChange) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+const (
+	PlanChangeImmediate = "immediate"
+	PlanChangeNextCycle = "next_cycle"
+
+	PlanChangeStatusScheduled = "scheduled"
+	PlanChangeStatusApplied   = "applied"
+	PlanChangeStatusCancelled = "cancelled"
+)
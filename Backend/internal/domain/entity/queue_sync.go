@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QueueSyncReport records one reconciliation run of Mikrotik simple queues
+type QueueSyncReport struct {
+	ID             string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID       string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	DeviceID       string    `gorm:"type:uuid;not null;index" json:"device_id"`
+	DryRun         bool      `gorm:"not null;default:false" json:"dry_run"`
+	Status         string    `gorm:"not null" json:"status"` // planned, success, partial, failed
+	CreatedCount   int       `json:"created_count"`
+	UpdatedCount   int       `json:"updated_count"`
+	RemovedCount   int       `json:"removed_count"`
+	UnchangedCount int       `json:"unchanged_count"`
+	SkippedCount   int       `json:"skipped_count"`
+	FailedCount    int       `json:"failed_count"`
+	Actions        string    `gorm:"type:jsonb" json:"actions"` // JSON array of queue changes
+	ErrorMessage   string    `gorm:"type:text" json:"error_message,omitempty"`
+	StartedAt      time.Time `gorm:"not null" json:"started_at"`
+	FinishedAt     time.Time `gorm:"not null" json:"finished_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (r *QueueSyncReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	if r.Actions == "" {
+		r.Actions = "[]"
+	}
+	return nil
+}
+
+const (
+	QueueSyncStatusPlanned = "planned"
+	QueueSyncStatusSuccess = "success"
+	QueueSyncStatusPartial = "partial"
+	QueueSyncStatusFailed  = "failed"
+)
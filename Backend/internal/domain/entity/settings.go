@@ -66,12 +66,20 @@ type TenantSettings struct {
 	SendPaymentConfirmation bool     `json:"send_payment_confirmation" gorm:"default:true"`
 	SendSuspensionWarning  bool      `json:"send_suspension_warning" gorm:"default:true"`
 	WarningDaysBeforeSuspension int  `json:"warning_days_before_suspension" gorm:"default:3"`
+	SendInvoiceNotification bool     `json:"send_invoice_notification" gorm:"default:false"`
+	SendTicketNotification bool      `json:"send_ticket_notification" gorm:"default:false"` // tells customers their ticket is resolved
+	NotificationLanguage   string    `json:"notification_language" gorm:"default:'id'"` // id or en
 	
 	// Integration Settings
 	WhatsappEnabled        bool      `json:"whatsapp_enabled" gorm:"default:false"`
 	WhatsappAPIKey         string    `json:"whatsapp_api_key"`
 	TelegramEnabled        bool      `json:"telegram_enabled" gorm:"default:false"`
 	TelegramBotToken       string    `json:"telegram_bot_token"`
+	// Midtrans account of the tenant, used for online payment of customer invoices
+	MidtransEnabled        bool      `json:"midtrans_enabled" gorm:"default:false"`
+	MidtransServerKey      string    `json:"-"`
+	MidtransClientKey      string    `json:"midtrans_client_key"`
+	MidtransIsProduction   bool      `json:"midtrans_is_production" gorm:"default:false"`
 	
 	CreatedAt              time.Time `json:"created_at"`
 	UpdatedAt              time.Time `json:"updated_at"`
@@ -84,3 +92,15 @@ func (UserSettings) TableName() string {
 func (TenantSettings) TableName() string {
 	return "tenant_settings"
 }
+
+// Billing settings values
+const (
+	BillingTypePrepaid  = "prepaid"
+	BillingTypePostpaid = "postpaid"
+
+	BillingDateTypeFixed   = "fixed"   // every customer is billed on BillingDay
+	BillingDateTypeRecycle = "recycle" // each customer is billed on their own DueDate
+
+	LateFeeTypeFixed      = "fixed"      // LateFee is an amount
+	LateFeeTypePercentage = "percentage" // LateFee is a percentage of the invoice amount
+)
@@ -21,6 +21,12 @@ type SpeedBoost struct {
 	EndDate         *time.Time   `json:"end_date,omitempty"`
 	Notes           string       `gorm:"type:text" json:"notes"`
 	RejectionReason string       `gorm:"type:text" json:"rejection_reason"`
+	RequestedBy     string       `gorm:"size:20;not null;default:'operator'" json:"requested_by"` // operator, customer
+	CreatedBy       *string      `gorm:"type:uuid" json:"created_by,omitempty"`
+	ReviewedBy      *string      `gorm:"type:uuid" json:"reviewed_by,omitempty"` // user who approved or rejected
+	ReviewedAt      *time.Time   `json:"reviewed_at,omitempty"`
+	PaymentID       *string      `gorm:"type:uuid" json:"payment_id,omitempty"` // invoice the price was charged on
+	ExpiredAt       *time.Time   `json:"expired_at,omitempty"`
 	CreatedAt       time.Time    `json:"created_at"`
 	UpdatedAt       time.Time    `json:"updated_at"`
 	Tenant          *Tenant      `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
@@ -43,3 +49,8 @@ const (
 	SpeedBoostStatusActive   = "active"
 	SpeedBoostStatusExpired  = "expired"
 )
+
+const (
+	SpeedBoostRequestedByOperator = "operator"
+	SpeedBoostRequestedByCustomer = "customer"
+)
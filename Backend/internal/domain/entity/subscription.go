@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,14 +38,35 @@ func (s *SubscriptionPlan) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// DefaultTrialDays is the trial length of plans without a trial_days setting
+const DefaultTrialDays = 7
+
+// Trial returns the parsed TrialConfig. Plans without one offer a
+// DefaultTrialDays trial that is not converted automatically.
+func (s *SubscriptionPlan) Trial() TrialConfig {
+	config := TrialConfig{TrialDays: DefaultTrialDays, TrialEnabled: true}
+	if s.TrialConfig != "" && s.TrialConfig != "{}" {
+		config = TrialConfig{}
+		if err := json.Unmarshal([]byte(s.TrialConfig), &config); err != nil {
+			config = TrialConfig{TrialEnabled: true}
+		}
+	}
+	if config.TrialDays <= 0 {
+		config.TrialDays = DefaultTrialDays
+	}
+	return config
+}
+
 type TenantSubscription struct {
 	ID              string            `gorm:"primaryKey;type:uuid" json:"id"`
 	TenantID        string            `gorm:"type:uuid;not null;index" json:"tenant_id"`
 	PlanID          string            `gorm:"type:uuid;not null" json:"plan_id"`
-	Status          string            `gorm:"not null;default:'pending'" json:"status"` // pending, active, suspended, cancelled, expired
+	Status          string            `gorm:"not null;default:'pending'" json:"status"` // pending, trial, read_only, active, past_due, suspended, cancelled, expired
 	StartDate       *time.Time        `json:"start_date,omitempty"`
 	EndDate         *time.Time        `json:"end_date,omitempty"`
 	NextBillingDate *time.Time        `json:"next_billing_date,omitempty"`
+	GraceUntil      *time.Time        `json:"grace_until,omitempty"`   // end of the grace period of a past_due subscription
+	TrialEndsAt     *time.Time        `json:"trial_ends_at,omitempty"` // set when the subscription started as a trial
 	PaymentMethod   string            `json:"payment_method"`
 	AutoRenew       bool              `gorm:"default:true" json:"auto_renew"`
 	CreatedAt       time.Time         `json:"created_at"`
@@ -64,11 +86,43 @@ const (
 	SubscriptionStatusPending   = "pending"
 	SubscriptionStatusActive    = "active"
 	SubscriptionStatusTrial     = "trial"
+	SubscriptionStatusReadOnly  = "read_only" // trial ended without conversion, data can be viewed but not changed
+	SubscriptionStatusPastDue   = "past_due"  // renewal unpaid, still usable until GraceUntil
 	SubscriptionStatusSuspended = "suspended"
 	SubscriptionStatusCancelled = "cancelled"
 	SubscriptionStatusExpired   = "expired"
 )
 
+// SubscriptionDunningStep records a renewal step taken for one billing date
+// of a subscription, so reminders and status changes happen once
+type SubscriptionDunningStep struct {
+	ID             string    `gorm:"primaryKey;type:uuid" json:"id"`
+	SubscriptionID string    `gorm:"type:uuid;not null" json:"subscription_id"`
+	TenantID       string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	BillingDate    time.Time `gorm:"not null" json:"billing_date"`
+	Step           string    `gorm:"size:30;not null" json:"step"` // see DunningStep*
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (d *SubscriptionDunningStep) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Dunning steps; reminders are recorded as "reminder_<days before>"
+const (
+	DunningStepRenewalOrder = "renewal_order"
+	DunningStepReminder     = "reminder"
+	DunningStepGrace        = "grace"
+	DunningStepSuspended    = "suspended"
+	DunningStepExpired      = "expired"
+
+	DunningStepTrialConverted = "trial_converted"
+	DunningStepTrialEnded     = "trial_ended"
+)
+
 type PaymentTransaction struct {
 	ID                   string              `gorm:"primaryKey;type:uuid" json:"id"`
 	TenantID             string              `gorm:"type:uuid;not null;index" json:"tenant_id"`
@@ -76,7 +130,8 @@ type PaymentTransaction struct {
 	PlanID               *string             `gorm:"type:uuid" json:"plan_id,omitempty"` // Plan to upgrade to after payment
 	OrderID              string              `gorm:"uniqueIndex;not null" json:"order_id"`
 	Amount               float64             `gorm:"not null" json:"amount"`
-	Status               string              `gorm:"not null;default:'pending'" json:"status"` // pending, paid, failed, refunded, expired
+	RefundedAmount       float64             `gorm:"default:0" json:"refunded_amount"`         // sum of Refunds
+	Status               string              `gorm:"not null;default:'pending'" json:"status"` // pending, paid, failed, refunded, expired, cancelled
 	PaymentMethod        string              `json:"payment_method"`
 	PaymentGateway       string              `json:"payment_gateway"` // midtrans, xendit, stripe, manual
 	GatewayTransactionID string              `json:"gateway_transaction_id"`
@@ -104,3 +159,36 @@ const (
 	TransactionStatusExpired   = "expired"
 	TransactionStatusCancelled = "cancelled"
 )
+
+// PaymentTransactionRefund is money returned to a tenant for a paid
+// subscription payment. A transaction becomes refunded once its refunds
+// cover Amount.
+type PaymentTransactionRefund struct {
+	ID              string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TransactionID   string    `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	TenantID        string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Amount          float64   `gorm:"not null" json:"amount"`
+	Reason          string    `gorm:"type:text" json:"reason"`
+	Method          string    `gorm:"not null" json:"method"` // gateway, manual
+	GatewayRefundID string    `json:"gateway_refund_id,omitempty"`
+	Status          string    `gorm:"not null" json:"status"`       // processing, pending, succeeded, failed
+	RefundedBy      string    `gorm:"type:uuid" json:"refunded_by"` // admin user
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (r *PaymentTransactionRefund) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+const (
+	RefundMethodGateway = "gateway"
+	RefundMethodManual  = "manual"
+
+	RefundStatusProcessing = "processing" // reserved while the gateway is asked
+	RefundStatusPending    = "pending"    // accepted by the gateway, not settled yet
+	RefundStatusSucceeded  = "succeeded"
+	RefundStatusFailed     = "failed" // refused by the gateway, nothing was refunded
+)
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TelegramBot keeps the state of a tenant's Telegram bot between runs: the
+// getUpdates offset, the webhook it registered and how far event pushes got
+type TelegramBot struct {
+	TenantID        string     `gorm:"primaryKey;type:uuid" json:"tenant_id"`
+	TokenHash       string     `gorm:"size:64" json:"-"` // detects a changed bot token
+	BotUsername     string     `gorm:"size:100" json:"bot_username"`
+	UpdateOffset    int64      `gorm:"not null;default:0" json:"-"`
+	WebhookURL      string     `gorm:"size:500" json:"webhook_url,omitempty"`
+	WebhookSecret   string     `gorm:"size:64" json:"-"`
+	EventsPushedAt  *time.Time `json:"events_pushed_at,omitempty"` // end of the last pushed event window
+	OverduePushedOn *time.Time `gorm:"type:date" json:"overdue_pushed_on,omitempty"`
+	LastError       string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (TelegramBot) TableName() string {
+	return "telegram_bots"
+}
+
+// TelegramChat links a user of the tenant to their Telegram chat with the
+// tenant's bot. The user asks for a link code in the dashboard and sends it
+// to the bot as "/start <code>"; the bot then pushes events to the chat and
+// answers commands with the permissions of the user's role.
+type TelegramChat struct {
+	ID                string     `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID          string     `gorm:"type:uuid;not null;uniqueIndex:idx_telegram_chat_user" json:"tenant_id"`
+	UserID            string     `gorm:"type:uuid;not null;uniqueIndex:idx_telegram_chat_user" json:"user_id"`
+	ChatID            *int64     `json:"chat_id,omitempty"`
+	Username          string     `gorm:"size:100" json:"username,omitempty"`
+	LinkCode          *string    `gorm:"size:20" json:"link_code,omitempty"`
+	LinkCodeExpiresAt *time.Time `json:"link_code_expires_at,omitempty"`
+	LinkedAt          *time.Time `json:"linked_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	User              *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (TelegramChat) TableName() string {
+	return "telegram_chats"
+}
+
+func (c *TelegramChat) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IsLinked reports whether the user finished linking a chat
+func (c *TelegramChat) IsLinked() bool {
+	return c.ChatID != nil
+}
@@ -0,0 +1,106 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TenantWebhook is an endpoint of the tenant that receives the events it is
+// subscribed to. Events that happened before EventsScannedAt have already
+// been turned into deliveries.
+type TenantWebhook struct {
+	ID              string    `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID        string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	URL             string    `gorm:"size:500;not null" json:"url"`
+	Description     string    `gorm:"size:255" json:"description"`
+	Events          string    `gorm:"type:jsonb;not null;default:'[]'" json:"-"` // JSON array of event names
+	Secret          string    `gorm:"size:100;not null" json:"-"`                // HMAC key of the signatures
+	IsActive        bool      `gorm:"not null;default:true" json:"is_active"`
+	EventsScannedAt time.Time `gorm:"not null" json:"-"`
+	CreatedBy       *string   `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (TenantWebhook) TableName() string {
+	return "tenant_webhooks"
+}
+
+func (w *TenantWebhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == "" {
+		w.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// EventList returns the events the webhook is subscribed to
+func (w *TenantWebhook) EventList() []string {
+	var events []string
+	if w.Events != "" {
+		json.Unmarshal([]byte(w.Events), &events)
+	}
+	return events
+}
+
+// SetEvents stores the events the webhook is subscribed to
+func (w *TenantWebhook) SetEvents(events []string) {
+	if events == nil {
+		events = []string{}
+	}
+	data, _ := json.Marshal(events)
+	w.Events = string(data)
+}
+
+// WebhookDelivery is one attempt sequence of sending an event to a webhook.
+// Failed attempts are retried with backoff until the delivery succeeds or
+// runs out of attempts.
+type WebhookDelivery struct {
+	ID             string     `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID       string     `gorm:"type:uuid;not null" json:"tenant_id"`
+	WebhookID      string     `gorm:"type:uuid;not null" json:"webhook_id"`
+	EventID        string     `gorm:"size:64;not null" json:"event_id"` // same for every webhook and redelivery of the event
+	Event          string     `gorm:"size:50;not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"` // JSON body as signed and sent
+	Status         string     `gorm:"size:20;not null;default:'pending'" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	ResponseBody   string     `gorm:"type:text" json:"response_body,omitempty"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	DurationMs     *int       `json:"duration_ms,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	RedeliveryOf   *string    `gorm:"type:uuid" json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // out of attempts
+)
+
+// Events sent to tenant webhooks
+const (
+	WebhookEventCustomerCreated   = "customer.created"
+	WebhookEventCustomerSuspended = "customer.suspended"
+	WebhookEventPaymentPaid       = "payment.paid"
+	WebhookEventVoucherActivated  = "voucher.activated"
+	WebhookEventDeviceDown        = "device.down"
+	WebhookEventPing              = "ping" // sent on request to test the endpoint
+)
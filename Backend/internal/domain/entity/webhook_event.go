@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookEvent is an inbound payment webhook as it was received, with the
+// outcome of processing it
+type WebhookEvent struct {
+	ID                string     `gorm:"primaryKey;type:uuid" json:"id"`
+	Source            string     `gorm:"not null;index" json:"source"` // midtrans, xendit, payment, tenant_midtrans
+	TenantID          *string    `json:"tenant_id,omitempty"`          // as given in the webhook URL
+	Headers           string     `gorm:"type:jsonb" json:"headers"`    // secrets are redacted
+	Body              string     `gorm:"type:text" json:"body"`
+	SignatureValid    *bool      `json:"signature_valid"` // nil when the source is not signed
+	OrderID           string     `gorm:"index" json:"order_id"`
+	TransactionStatus string     `json:"transaction_status"` // status as reported by the sender
+	PaymentStatus     string     `json:"payment_status"`     // normalized status
+	PaymentType       string     `json:"payment_type"`
+	TransactionID     string     `json:"transaction_id"`
+	Amount            float64    `json:"amount"`
+	Status            string     `gorm:"not null;default:'received'" json:"status"`
+	Error             string     `gorm:"type:text" json:"error,omitempty"`
+	IdempotencyKey    *string    `gorm:"uniqueIndex" json:"-"` // held while the event owns its (order_id, transaction_status)
+	Attempts          int        `gorm:"not null;default:0" json:"attempts"`
+	ProcessedAt       *time.Time `json:"processed_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (e *WebhookEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Webhook sources
+const (
+	WebhookSourceMidtrans       = "midtrans"
+	WebhookSourceXendit         = "xendit"
+	WebhookSourcePayment        = "payment"
+	WebhookSourceTenantMidtrans = "tenant_midtrans"
+)
+
+// Webhook event statuses
+const (
+	WebhookEventReceived  = "received"
+	WebhookEventProcessed = "processed"
+	WebhookEventFailed    = "failed"
+	WebhookEventDuplicate = "duplicate"
+	WebhookEventRejected  = "rejected"
+)
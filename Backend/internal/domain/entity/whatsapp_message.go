@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WhatsAppMessage is a WhatsApp message sent to a customer, by an operator or
+// by the notification dispatcher, with the delivery status the gateway reports
+type WhatsAppMessage struct {
+	ID                string     `gorm:"primaryKey;type:uuid" json:"id"`
+	TenantID          string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CustomerID        *string    `gorm:"type:uuid;index" json:"customer_id,omitempty"`
+	Phone             string     `gorm:"size:20;not null" json:"phone"`
+	Message           string     `gorm:"type:text;not null" json:"message"`
+	Source            string     `gorm:"size:20;not null" json:"source"`   // operator, notification
+	Provider          string     `gorm:"size:20;not null" json:"provider"` // fonnte, stub
+	ProviderMessageID string     `gorm:"size:100" json:"provider_message_id,omitempty"`
+	Status            string     `gorm:"size:20;not null" json:"status"` // queued, sent, delivered, read, failed
+	Error             string     `gorm:"type:text" json:"error,omitempty"`
+	SentBy            *string    `gorm:"type:uuid" json:"sent_by,omitempty"`
+	SentAt            *time.Time `json:"sent_at,omitempty"`
+	StatusAt          *time.Time `json:"status_at,omitempty"` // last delivery status update
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (WhatsAppMessage) TableName() string {
+	return "whatsapp_messages"
+}
+
+func (m *WhatsAppMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// WhatsApp message sources
+const (
+	WhatsAppSourceOperator     = "operator"
+	WhatsAppSourceNotification = "notification"
+)
@@ -60,6 +60,12 @@ type AdminDashboardStats struct {
 	GrowthRate          float64 `json:"growth_rate"`
 	NewTenantsThisMonth int64   `json:"new_tenants_this_month"`
 	ChurnRate           float64 `json:"churn_rate"`
+
+	// Trials that ended in the last 30 days, and how many of them were paid
+	TrialsEnded         int64   `json:"trials_ended"`
+	TrialsConverted     int64   `json:"trials_converted"`
+	TrialConversionRate float64 `json:"trial_conversion_rate"`
+	ReadOnlyTenants     int64   `json:"read_only_tenants"`
 }
 
 // RevenueData represents monthly revenue data
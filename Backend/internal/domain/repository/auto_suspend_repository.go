@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type AutoSuspendRepository interface {
+	// MarkOverdue flips pending and partially paid invoices due before dueBefore to overdue
+	MarkOverdue(ctx context.Context, tenantID string, dueBefore time.Time) (int64, error)
+	// FindSuspendCandidates returns unpaid invoices due before dueBefore that
+	// belong to active customers, oldest first, with Customer loaded
+	FindSuspendCandidates(ctx context.Context, tenantID string, dueBefore time.Time) ([]*entity.Payment, error)
+	// FindAutoSuspendedCustomers returns suspended customers whose suspension
+	// was made by the billing job
+	FindAutoSuspendedCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error)
+	// CountUnpaid counts the customer's unpaid invoices due before dueBefore
+	CountUnpaid(ctx context.Context, customerID string, dueBefore time.Time) (int64, error)
+	SetAutoSuspendedAt(ctx context.Context, customerID string, at *time.Time) error
+	CreateAction(ctx context.Context, action *entity.BillingActionLog) error
+	ListActions(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.BillingActionLog, error)
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type CustomerNotificationRepository interface {
+	// FindUnpaidInvoices returns the tenant's unpaid invoices due on or after
+	// from and before to, with Customer loaded
+	FindUnpaidInvoices(ctx context.Context, tenantID string, from, to time.Time) ([]*entity.Payment, error)
+	// FindPaidInvoices returns the tenant's invoices paid since the given
+	// time with Customer loaded
+	FindPaidInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error)
+	// FindIssuedInvoices returns the tenant's unpaid invoices created since
+	// the given time with Customer loaded
+	FindIssuedInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error)
+	// FindSuspendedInvoices returns the invoices the tenant's customers were
+	// automatically suspended for since the given time, with Customer loaded
+	FindSuspendedInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error)
+	// FindResolvedTickets returns the tenant's tickets resolved since the
+	// given time with Customer loaded
+	FindResolvedTickets(ctx context.Context, tenantID string, since time.Time) ([]*entity.Ticket, error)
+	// Claim records the notification as sending unless one with its DedupKey
+	// was sent already, is being sent, or failed maxAttempts times. It reports
+	// whether the caller should send it.
+	Claim(ctx context.Context, notification *entity.CustomerNotification, maxAttempts int) (bool, error)
+	// Finish stores the outcome of a claimed notification
+	Finish(ctx context.Context, notification *entity.CustomerNotification) error
+	// FindByCustomer returns the notifications of a customer of the tenant, newest first
+	FindByCustomer(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerNotification, error)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type InvoicePaymentOrderRepository interface {
+	Create(ctx context.Context, order *entity.InvoicePaymentOrder) error
+	FindByOrderID(ctx context.Context, tenantID, orderID string) (*entity.InvoicePaymentOrder, error)
+	// FindLatestByPayment returns the most recent order of an invoice, nil when there is none
+	FindLatestByPayment(ctx context.Context, paymentID string) (*entity.InvoicePaymentOrder, error)
+	// ApplyPaid moves a pending or failed order to paid and stores the
+	// allocation of its money in one transaction, returning the invoice after
+	// the allocation. It reports false when the order was already paid, so a
+	// repeated notification is only applied once. Money above the invoice
+	// balance, or all of it when the invoice is already paid, is credited to
+	// the customer. A nil allocation only marks the order paid.
+	ApplyPaid(ctx context.Context, id, gatewayTransactionID string, allocation *entity.PaymentAllocation) (*entity.Payment, bool, error)
+	// MarkFailed moves a pending order to failed
+	MarkFailed(ctx context.Context, id string) error
+}
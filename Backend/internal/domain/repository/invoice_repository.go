@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type InvoiceRepository interface {
+	// CreateInvoice assigns the tenant's next invoice number and stores the
+	// invoice with its items in one transaction. Credit in the customer's
+	// balance is applied to the new invoice.
+	CreateInvoice(ctx context.Context, payment *entity.Payment, prefix string) error
+	// FindInvoice returns the invoice with Customer, Items, Allocations and CreditNotes loaded
+	FindInvoice(ctx context.Context, id string) (*entity.Payment, error)
+	// AddAllocation records money received against the invoice and marks it
+	// partially_paid, or paid once the balance is covered. The part of the
+	// amount above the balance is credited to the customer's balance and
+	// allocation.Amount is lowered to what was applied.
+	AddAllocation(ctx context.Context, allocation *entity.PaymentAllocation) (*entity.Payment, error)
+	// AddCreditNote assigns the tenant's next credit note number and stores
+	// the note as issued. Its Amount lowers the invoice balance and its
+	// RefundAmount the paid amount; notes leaving a negative balance fail,
+	// counting the notes still pending.
+	AddCreditNote(ctx context.Context, note *entity.CreditNote) (*entity.Payment, error)
+	// ReserveCreditNote checks and numbers a note like AddCreditNote but
+	// stores it as pending without changing the invoice, so its refund can be
+	// made at the gateway outside any transaction
+	ReserveCreditNote(ctx context.Context, note *entity.CreditNote) error
+	// CompleteCreditNote issues a pending note with its RefundReference and
+	// applies it to the invoice. Payments made in the meantime that the
+	// credit leaves unneeded become customer credit.
+	CompleteCreditNote(ctx context.Context, note *entity.CreditNote) (*entity.Payment, error)
+	// FailCreditNote marks a pending note failed
+	FailCreditNote(ctx context.Context, note *entity.CreditNote) error
+	// AddLedgerEntry moves the balance of a customer of the tenant and sets
+	// entry.BalanceAfter. Debits above the balance fail.
+	AddLedgerEntry(ctx context.Context, entry *entity.CustomerLedgerEntry) error
+	// FindLedgerEntries returns the latest balance movements of a customer, newest first
+	FindLedgerEntries(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerLedgerEntry, error)
+	// SumLedger returns a customer's balance over all of their ledger
+	// entries, credits less debits
+	SumLedger(ctx context.Context, tenantID, customerID string) (float64, error)
+	// FindPeriodInvoice returns the customer's invoice for the billing period
+	// starting on periodStart with its Items, or nil when there is none
+	FindPeriodInvoice(ctx context.Context, customerID string, periodStart time.Time) (*entity.Payment, error)
+	// AddInvoiceItems stores items on an unpaid invoice together with the
+	// invoice's recomputed totals; it reports false when the invoice was paid
+	// in the meantime
+	AddInvoiceItems(ctx context.Context, payment *entity.Payment, items []entity.InvoiceItem) (bool, error)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type InvoiceRunRepository interface {
+	// FindBillableCustomers returns active customers of the tenant
+	FindBillableCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error)
+	// FindPeriodInvoices returns generated invoices whose period starts on or after since
+	FindPeriodInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error)
+	// FindAppliedPlanChanges returns applied plan changes of the tenant that
+	// took effect on or after since, oldest first
+	FindAppliedPlanChanges(ctx context.Context, tenantID string, since time.Time) ([]*entity.CustomerPlanChange, error)
+	// CreateInvoice numbers and inserts the invoice with its items unless the
+	// customer already has one for the same period; created is false in that case
+	CreateInvoice(ctx context.Context, payment *entity.Payment, prefix string) (created bool, err error)
+	CreateRun(ctx context.Context, run *entity.InvoiceRun) error
+	ListRuns(ctx context.Context, tenantID string, limit int) ([]*entity.InvoiceRun, error)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type LateFeeRepository interface {
+	// FindLateFeeCandidates returns unpaid invoices due before dueBefore that
+	// have not been charged a late fee yet
+	FindLateFeeCandidates(ctx context.Context, tenantID string, dueBefore time.Time) ([]*entity.Payment, error)
+	// ApplyLateFee charges the late fee item on its invoice unless the invoice
+	// was charged or paid in the meantime; it reports whether the fee was applied
+	ApplyLateFee(ctx context.Context, item *entity.InvoiceItem, at time.Time) (bool, error)
+	// WaiveLateFee waives a charged fee of an unpaid invoice that is not
+	// waived yet and returns the invoice; it reports whether the fee was
+	// waived. An invoice whose payments cover it without the fee is marked
+	// paid, crediting what was paid towards the fee to the customer.
+	WaiveLateFee(ctx context.Context, paymentID string, waivedBy *string, reason string, at time.Time) (*entity.Payment, bool, error)
+	CreateAction(ctx context.Context, action *entity.BillingActionLog) error
+}
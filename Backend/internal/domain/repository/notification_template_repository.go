@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type NotificationTemplateRepository interface {
+	// Find returns the tenant's template of an event for a channel and language
+	Find(ctx context.Context, tenantID, event, channel, language string) (*entity.NotificationTemplate, error)
+	// FindByTenant returns every template the tenant customized
+	FindByTenant(ctx context.Context, tenantID string) ([]*entity.NotificationTemplate, error)
+	// Save creates the template or replaces the tenant's one with the same
+	// event, channel and language
+	Save(ctx context.Context, template *entity.NotificationTemplate) error
+	// Delete removes the tenant's template, returning ErrNotFound when there is none
+	Delete(ctx context.Context, tenantID, event, channel, language string) error
+}
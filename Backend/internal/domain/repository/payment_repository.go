@@ -14,7 +14,12 @@ type PaymentRepository interface {
 	Update(ctx context.Context, payment *entity.Payment) error
 	Delete(ctx context.Context, id string) error
 	CountByStatus(ctx context.Context, tenantID, status string) (int, error)
-	SumByStatus(ctx context.Context, tenantID, status string) (float64, error)
+	// SumPaidAmount sums the money received on the tenant's invoices, less
+	// refunds, whatever their status
+	SumPaidAmount(ctx context.Context, tenantID string) (float64, error)
+	// SumBalanceByStatus sums what customers still owe on invoices with one
+	// of the statuses
+	SumBalanceByStatus(ctx context.Context, tenantID string, statuses ...string) (float64, error)
 	SumByMonth(ctx context.Context, tenantID string, month, year int) (float64, error)
 	GetRecentPayments(ctx context.Context, tenantID string, limit int) ([]*entity.Payment, error)
 	GetMonthlyRevenue(ctx context.Context, tenantID string, months int) ([]map[string]interface{}, error)
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type PlanChangeRepository interface {
+	// FindCustomer returns the tenant's customer with ServicePlan loaded
+	FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error)
+	// FindPlan returns a service plan of the tenant
+	FindPlan(ctx context.Context, tenantID, planID string) (*entity.ServicePlan, error)
+	Create(ctx context.Context, change *entity.CustomerPlanChange) error
+	// ApplyChange moves the customer and the profile of their RADIUS users to
+	// the new plan and fee and marks the change applied, unless it is no
+	// longer scheduled; it reports whether the change was applied
+	ApplyChange(ctx context.Context, change *entity.CustomerPlanChange, at time.Time) (bool, error)
+	// SaveProration stores how an applied change was billed
+	SaveProration(ctx context.Context, change *entity.CustomerPlanChange) error
+	// CancelScheduled cancels the scheduled changes of a customer of the
+	// tenant; with a changeID only that change. It returns how many were cancelled.
+	CancelScheduled(ctx context.Context, tenantID, customerID, changeID string, at time.Time) (int64, error)
+	// FindHistory returns the plan changes of a customer, newest first
+	FindHistory(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerPlanChange, error)
+	// FindDueChanges returns scheduled changes of the tenant effective on or before day
+	FindDueChanges(ctx context.Context, tenantID string, day time.Time) ([]*entity.CustomerPlanChange, error)
+	FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type PrepaidRepository interface {
+	// ExtendService moves the customer's ServiceUntil and the ExpireDate of
+	// their RADIUS users to until(current), where current is the ServiceUntil
+	// before the change. It marks the invoice as handled and returns nil when
+	// the invoice already extended the service.
+	ExtendService(ctx context.Context, payment *entity.Payment, at time.Time, until func(current *time.Time) time.Time) (*time.Time, error)
+	// FindUnextendedPayments returns invoices paid since paidSince that have
+	// not extended their customer's service
+	FindUnextendedPayments(ctx context.Context, tenantID string, paidSince time.Time) ([]*entity.Payment, error)
+	// FindExpiringCustomers returns active customers whose service ends
+	// between from and to and who have not been reminded since their last
+	// extension, with ServicePlan loaded
+	FindExpiringCustomers(ctx context.Context, tenantID string, from, to time.Time) ([]*entity.Customer, error)
+	MarkExpiryReminded(ctx context.Context, customerID string, at time.Time) error
+	FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error)
+	CreateAction(ctx context.Context, action *entity.BillingActionLog) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type QueueSyncRepository interface {
+	// FindQueueCustomers returns active customers with their service plan
+	FindQueueCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error)
+	FindAdvancedSettings(ctx context.Context, planIDs []string) ([]*entity.ServicePlanAdvancedSettings, error)
+	CreateReport(ctx context.Context, report *entity.QueueSyncReport) error
+	ListReports(ctx context.Context, deviceID string, limit int) ([]*entity.QueueSyncReport, error)
+}
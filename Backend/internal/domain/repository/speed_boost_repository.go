@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+// SpeedBoostFilter narrows ListBoosts; empty fields match everything
+type SpeedBoostFilter struct {
+	Status     string
+	CustomerID string
+	Limit      int
+}
+
+type SpeedBoostRepository interface {
+	// FindCustomer returns the tenant's customer with ServicePlan loaded
+	FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error)
+	// FindPlan returns a service plan of the tenant
+	FindPlan(ctx context.Context, tenantID, planID string) (*entity.ServicePlan, error)
+	Create(ctx context.Context, boost *entity.SpeedBoost) error
+	// FindByID returns a boost of the tenant with Customer, CurrentPlan and BoostPlan loaded
+	FindByID(ctx context.Context, tenantID, id string) (*entity.SpeedBoost, error)
+	// ListBoosts returns the tenant's boosts, newest request first
+	ListBoosts(ctx context.Context, tenantID string, filter SpeedBoostFilter) ([]*entity.SpeedBoost, error)
+	// HasOpenBoost reports whether the customer has a boost that is pending,
+	// approved or active
+	HasOpenBoost(ctx context.Context, customerID string) (bool, error)
+	// Transition stores the boost's status, dates, review and payment fields
+	// unless its status is no longer from; it reports whether it was stored
+	Transition(ctx context.Context, boost *entity.SpeedBoost, from string) (bool, error)
+	// FindDueBoosts returns approved boosts of every tenant starting by now
+	// and active boosts ending by now, with BoostPlan loaded
+	FindDueBoosts(ctx context.Context, now time.Time) ([]*entity.SpeedBoost, error)
+	FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 )
@@ -25,16 +26,38 @@ type TenantSubscriptionRepository interface {
 	FindByTenantID(ctx context.Context, tenantID string) (*entity.TenantSubscription, error)
 	FindActiveByTenantID(ctx context.Context, tenantID string) (*entity.TenantSubscription, error)
 	FindExpiringIn(ctx context.Context, days int) ([]*entity.TenantSubscription, error)
+	// FindForDunning returns trial, active, past_due and suspended
+	// subscriptions whose next billing date is on or before billedBefore
+	FindForDunning(ctx context.Context, billedBefore time.Time) ([]*entity.TenantSubscription, error)
 	Update(ctx context.Context, subscription *entity.TenantSubscription) error
 	Delete(ctx context.Context, id string) error
 }
 
+type SubscriptionDunningRepository interface {
+	// ClaimStep records a dunning step and reports false when the step was
+	// already taken for the subscription and billing date
+	ClaimStep(ctx context.Context, step *entity.SubscriptionDunningStep) (bool, error)
+}
+
 type PaymentTransactionRepository interface {
 	Create(ctx context.Context, transaction *entity.PaymentTransaction) error
 	FindByID(ctx context.Context, id string) (*entity.PaymentTransaction, error)
 	FindByOrderID(ctx context.Context, orderID string) (*entity.PaymentTransaction, error)
 	FindByTenantID(ctx context.Context, tenantID string) ([]*entity.PaymentTransaction, error)
 	Update(ctx context.Context, transaction *entity.PaymentTransaction) error
+	// AddRefund records a refund of a paid transaction and marks it refunded
+	// once its refunds cover Amount. Refunds above the unrefunded amount fail,
+	// counting the refunds still processing.
+	AddRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error)
+	// ReserveRefund checks a refund like AddRefund and stores it as
+	// processing without changing the transaction, so it can be made at the
+	// gateway outside any transaction
+	ReserveRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) error
+	// CompleteRefund stores the gateway's answer to a processing refund and
+	// applies it to the transaction
+	CompleteRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error)
+	// FailRefund marks a processing refund failed
+	FailRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) error
 	// Admin methods
 	FindAll(ctx context.Context, page, perPage int, status, tenantID, search string) ([]*entity.PaymentTransaction, int64, error)
 	GetStats(ctx context.Context) (*PaymentStats, error)
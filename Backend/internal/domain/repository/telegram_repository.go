@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type TelegramRepository interface {
+	// FindBot returns the tenant's bot state
+	FindBot(ctx context.Context, tenantID string) (*entity.TelegramBot, error)
+	// SaveBot creates or updates the tenant's bot state
+	SaveBot(ctx context.Context, bot *entity.TelegramBot) error
+	// SetUpdateOffset moves the bot's getUpdates offset forward
+	SetUpdateOffset(ctx context.Context, tenantID string, offset int64) error
+	// SetEventsPushed records the end of the last pushed event window and the
+	// day the overdue list was last pushed
+	SetEventsPushed(ctx context.Context, tenantID string, until time.Time, overduePushedOn *time.Time) error
+
+	// FindChatByUser returns the chat row of a user of the tenant, linked or not
+	FindChatByUser(ctx context.Context, tenantID, userID string) (*entity.TelegramChat, error)
+	// FindChatByLinkCode returns the tenant's chat row waiting for a link code
+	FindChatByLinkCode(ctx context.Context, tenantID, code string) (*entity.TelegramChat, error)
+	// FindChatByChatID returns the tenant's chat linked to a Telegram chat, with its User
+	FindChatByChatID(ctx context.Context, tenantID string, chatID int64) (*entity.TelegramChat, error)
+	// FindLinkedChats returns the tenant's linked chats of active users, with their User
+	FindLinkedChats(ctx context.Context, tenantID string) ([]*entity.TelegramChat, error)
+	SaveChat(ctx context.Context, chat *entity.TelegramChat) error
+	DeleteChat(ctx context.Context, tenantID, id string) error
+
+	// SearchCustomers returns the tenant's customers whose name, code or phone matches
+	SearchCustomers(ctx context.Context, tenantID, query string, limit int) ([]*entity.Customer, error)
+	// FindCustomerByCode returns a customer of the tenant with their ServicePlan
+	FindCustomerByCode(ctx context.Context, tenantID, code string) (*entity.Customer, error)
+	// FindCustomers returns the tenant's customers with the given IDs
+	FindCustomers(ctx context.Context, tenantID string, ids []string) ([]*entity.Customer, error)
+	// FindUnpaidInvoices returns a customer's unpaid invoices, oldest due first
+	FindUnpaidInvoices(ctx context.Context, tenantID, customerID string) ([]*entity.Payment, error)
+
+	// FindNewTickets returns the tickets opened in [since, until), with their Customer
+	FindNewTickets(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Ticket, error)
+	// FindAllocations returns the payments received in [since, until)
+	FindAllocations(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.PaymentAllocation, error)
+	// FindDownRouters returns the tenant's routers, not in maintenance, whose
+	// last connection attempt in [since, until) failed
+	FindDownRouters(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Device, error)
+	// FindOverdueInvoices returns up to limit unpaid invoices due before the
+	// given day, oldest first with their Customer, and how many there are
+	FindOverdueInvoices(ctx context.Context, tenantID string, before time.Time, limit int) ([]*entity.Payment, int64, error)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+// WebhookEventFilter narrows a webhook event listing
+type WebhookEventFilter struct {
+	Source   string
+	Status   string
+	TenantID string
+	Search   string // order or transaction ID
+	Page     int
+	PerPage  int
+}
+
+type WebhookEventRepository interface {
+	Create(ctx context.Context, event *entity.WebhookEvent) error
+	Update(ctx context.Context, event *entity.WebhookEvent) error
+	FindByID(ctx context.Context, id string) (*entity.WebhookEvent, error)
+	List(ctx context.Context, filter WebhookEventFilter) ([]*entity.WebhookEvent, int64, error)
+	// ClaimIdempotencyKey gives the key to an event and reports false when
+	// another event already holds it
+	ClaimIdempotencyKey(ctx context.Context, id, key string) (bool, error)
+}
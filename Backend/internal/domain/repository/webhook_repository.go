@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entity.TenantWebhook) error
+	Update(ctx context.Context, webhook *entity.TenantWebhook) error
+	// Delete removes a webhook of the tenant with its deliveries
+	Delete(ctx context.Context, tenantID, id string) error
+	// FindByID returns a webhook of the tenant
+	FindByID(ctx context.Context, tenantID, id string) (*entity.TenantWebhook, error)
+	// FindByTenant returns the tenant's webhooks, oldest first
+	FindByTenant(ctx context.Context, tenantID string) ([]*entity.TenantWebhook, error)
+	// CountByTenant returns how many webhooks the tenant has, active or not
+	CountByTenant(ctx context.Context, tenantID string) (int, error)
+	// FindActive returns the active webhooks of all tenants
+	FindActive(ctx context.Context) ([]*entity.TenantWebhook, error)
+	// SetEventsScanned records the end of the last scanned event window
+	SetEventsScanned(ctx context.Context, id string, until time.Time) error
+
+	// CreateDeliveries stores new deliveries, skipping events that already
+	// have a first delivery to the webhook
+	CreateDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+	// CreateDelivery stores a single delivery, e.g. a redelivery
+	CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// FindDelivery returns a delivery of the tenant
+	FindDelivery(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error)
+	// FindDeliveries returns the deliveries of a webhook of the tenant, newest
+	// first, optionally only those with the status
+	FindDeliveries(ctx context.Context, tenantID, webhookID, status string, limit int) ([]*entity.WebhookDelivery, error)
+	// FindDueDeliveries returns up to limit pending deliveries to active
+	// webhooks whose next attempt is due, oldest first
+	FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error)
+	// ClaimDelivery takes a pending delivery for an attempt by counting the
+	// attempt and moving its next attempt to leaseUntil. It returns false
+	// when another run changed the delivery first.
+	ClaimDelivery(ctx context.Context, delivery *entity.WebhookDelivery, leaseUntil time.Time) (bool, error)
+	// FinishDelivery saves the outcome of an attempt
+	FinishDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+
+	// FindNewCustomers returns the customers created in [since, until)
+	FindNewCustomers(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Customer, error)
+	// FindSuspendedCustomers returns the customers still suspended who were
+	// suspended in [since, until)
+	FindSuspendedCustomers(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Customer, error)
+	// FindPaidInvoices returns the invoices paid in full in [since, until),
+	// with their Customer
+	FindPaidInvoices(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Payment, error)
+	// FindActivatedVouchers returns the hotspot vouchers first used in
+	// [since, until), with their Package
+	FindActivatedVouchers(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.HotspotVoucher, error)
+	// FindDownRouters returns the tenant's routers, not in maintenance, whose
+	// last connection attempt in [since, until) failed
+	FindDownRouters(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Device, error)
+}
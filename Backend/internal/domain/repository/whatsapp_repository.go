@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+type WhatsAppRepository interface {
+	// FindCustomer returns a customer of the tenant
+	FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error)
+	// CountSince returns how many messages the tenant sent since the given time
+	CountSince(ctx context.Context, tenantID string, since time.Time) (int64, error)
+	Create(ctx context.Context, message *entity.WhatsAppMessage) error
+	Update(ctx context.Context, message *entity.WhatsAppMessage) error
+	// FindByProviderMessageID returns the tenant's message with the gateway's message ID
+	FindByProviderMessageID(ctx context.Context, tenantID, provider, providerMessageID string) (*entity.WhatsAppMessage, error)
+	// FindByCustomer returns the messages sent to a customer of the tenant, newest first
+	FindByCustomer(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.WhatsAppMessage, error)
+}
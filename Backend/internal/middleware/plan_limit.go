@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
@@ -17,6 +18,7 @@ type PlanLimits struct {
 	PlanName     string              `json:"plan_name"`
 	PlanSlug     string              `json:"plan_slug"`
 	IsTrial      bool                `json:"is_trial"`
+	ReadOnly     bool                `json:"read_only"` // trial ended, only reads are allowed
 	MaxCustomers int                 `json:"max_customers"`
 	MaxUsers     int                 `json:"max_users"`
 	Limits       entity.PlanLimits   `json:"limits"`
@@ -80,6 +82,7 @@ func (m *PlanLimitMiddleware) GetPlanLimits(ctx context.Context, tenantID string
 		PlanName:     plan.Name,
 		PlanSlug:     plan.Slug,
 		IsTrial:      plan.IsTrial || subscription.Status == entity.SubscriptionStatusTrial,
+		ReadOnly:     subscription.Status == entity.SubscriptionStatusReadOnly,
 		MaxCustomers: limits.MaxCustomers,
 		MaxUsers:     limits.MaxUsers,
 		Limits:       limits,
@@ -229,6 +232,45 @@ func (m *PlanLimitMiddleware) CheckFeature(featureName string) gin.HandlerFunc {
 	}
 }
 
+// RequireWritable rejects changes while the tenant's trial has ended without
+// a paid plan. Reads are still allowed.
+func (m *PlanLimitMiddleware) RequireWritable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		tenantID, err := GetTenantIDFromContext(c)
+		if err != nil {
+			response.ErrorFromAppError(c, err.(*errors.AppError))
+			c.Abort()
+			return
+		}
+
+		limits, err := m.GetPlanLimits(c.Request.Context(), tenantID)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok {
+				response.ErrorFromAppError(c, appErr)
+			} else {
+				response.InternalServerError(c, "SRV_9001", "Failed to get plan limits")
+			}
+			c.Abort()
+			return
+		}
+
+		if limits.ReadOnly {
+			response.ErrorFromAppError(c, errors.ErrReadOnlyMode)
+			c.Abort()
+			return
+		}
+
+		c.Set("plan_limits", limits)
+		c.Next()
+	}
+}
+
 // isFeatureEnabled checks if a feature is enabled
 func (m *PlanLimitMiddleware) isFeatureEnabled(features entity.PlanFeatures, featureName string) bool {
 	switch featureName {
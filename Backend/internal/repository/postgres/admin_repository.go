@@ -472,7 +472,7 @@ func (r *AdminTenantRepository) GetDashboardStats(ctx context.Context) (*reposit
 	var revenue float64
 	r.db.WithContext(ctx).Model(&entity.PaymentTransaction{}).
 		Where("status = 'paid' AND created_at >= ?", time.Now().AddDate(0, -1, 0)).
-		Select("COALESCE(SUM(amount), 0)").
+		Select("COALESCE(SUM(" + transactionNetSQL + "), 0)").
 		Scan(&revenue)
 	stats.MonthlyRevenue = revenue
 
@@ -505,6 +505,22 @@ func (r *AdminTenantRepository) GetDashboardStats(ctx context.Context) (*reposit
 		stats.ChurnRate = float64(churnedTenants) / float64(stats.ActiveTenants) * 100
 	}
 
+	// Trial conversion: trials that ended in the last 30 days and were paid for
+	trialsEnded := r.db.WithContext(ctx).Model(&entity.TenantSubscription{}).
+		Where("trial_ends_at >= ? AND trial_ends_at < ?", time.Now().AddDate(0, 0, -30), time.Now())
+	trialsEnded.Session(&gorm.Session{}).Count(&stats.TrialsEnded)
+	trialsEnded.Session(&gorm.Session{}).
+		Where("EXISTS (SELECT 1 FROM payment_transactions pt WHERE pt.subscription_id = tenant_subscriptions.id AND pt.status = ?)", entity.TransactionStatusPaid).
+		Count(&stats.TrialsConverted)
+
+	if stats.TrialsEnded > 0 {
+		stats.TrialConversionRate = float64(stats.TrialsConverted) / float64(stats.TrialsEnded) * 100
+	}
+
+	r.db.WithContext(ctx).Model(&entity.TenantSubscription{}).
+		Where("status = ?", entity.SubscriptionStatusReadOnly).
+		Count(&stats.ReadOnlyTenants)
+
 	return stats, nil
 }
 
@@ -520,7 +536,7 @@ func (r *AdminTenantRepository) GetRevenueData(ctx context.Context, months int)
 		var revenue float64
 		r.db.WithContext(ctx).Model(&entity.PaymentTransaction{}).
 			Where("status = 'paid' AND created_at >= ? AND created_at < ?", startDate, endDate).
-			Select("COALESCE(SUM(amount), 0)").
+			Select("COALESCE(SUM(" + transactionNetSQL + "), 0)").
 			Scan(&revenue)
 
 		var tenants int64
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type autoSuspendRepository struct {
+	db *gorm.DB
+}
+
+func NewAutoSuspendRepository(db *gorm.DB) repository.AutoSuspendRepository {
+	return &autoSuspendRepository{db: db}
+}
+
+var unpaidPaymentStatuses = []string{entity.PaymentStatusPending, entity.PaymentStatusPartiallyPaid, entity.PaymentStatusOverdue}
+
+func (r *autoSuspendRepository) MarkOverdue(ctx context.Context, tenantID string, dueBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entity.Payment{}).
+		Where("tenant_id = ? AND status IN ? AND due_date < ?", tenantID, []string{entity.PaymentStatusPending, entity.PaymentStatusPartiallyPaid}, dueBefore).
+		Update("status", entity.PaymentStatusOverdue)
+	return result.RowsAffected, result.Error
+}
+
+func (r *autoSuspendRepository) FindSuspendCandidates(ctx context.Context, tenantID string, dueBefore time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Joins("Customer").
+		Where("payments.tenant_id = ? AND payments.status IN ? AND payments.due_date < ?", tenantID, unpaidPaymentStatuses, dueBefore).
+		Where(`"Customer".status = ? AND "Customer".deleted_at IS NULL`, entity.CustomerStatusActive).
+		Order("payments.due_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *autoSuspendRepository) FindAutoSuspendedCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status = ? AND auto_suspended_at IS NOT NULL AND deleted_at IS NULL", tenantID, entity.CustomerStatusSuspended).
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *autoSuspendRepository) CountUnpaid(ctx context.Context, customerID string, dueBefore time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.Payment{}).
+		Where("customer_id = ? AND status IN ? AND due_date < ?", customerID, unpaidPaymentStatuses, dueBefore).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *autoSuspendRepository) SetAutoSuspendedAt(ctx context.Context, customerID string, at *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.Customer{}).
+		Where("id = ?", customerID).
+		Update("auto_suspended_at", at).Error
+}
+
+func (r *autoSuspendRepository) CreateAction(ctx context.Context, action *entity.BillingActionLog) error {
+	return r.db.WithContext(ctx).Create(action).Error
+}
+
+func (r *autoSuspendRepository) ListActions(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.BillingActionLog, error) {
+	var actions []*entity.BillingActionLog
+	query := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&actions).Error
+	return actions, err
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type customerNotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomerNotificationRepository(db *gorm.DB) repository.CustomerNotificationRepository {
+	return &customerNotificationRepository{db: db}
+}
+
+func (r *customerNotificationRepository) FindUnpaidInvoices(ctx context.Context, tenantID string, from, to time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Where("tenant_id = ? AND status IN ? AND due_date >= ? AND due_date < ?", tenantID, unpaidPaymentStatuses, from, to).
+		Order("due_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *customerNotificationRepository) FindPaidInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Where("tenant_id = ? AND status = ? AND payment_date >= ?", tenantID, entity.PaymentStatusPaid, since).
+		Order("payment_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *customerNotificationRepository) FindIssuedInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Where("tenant_id = ? AND status IN ? AND created_at >= ?", tenantID, unpaidPaymentStatuses, since).
+		Order("created_at ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *customerNotificationRepository) FindSuspendedInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	suspensions := r.db.Model(&entity.BillingActionLog{}).
+		Select("payment_id").
+		Where("tenant_id = ? AND action = ? AND payment_id IS NOT NULL AND created_at >= ?", tenantID, entity.BillingActionAutoSuspend, since)
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Where("tenant_id = ? AND id IN (?)", tenantID, suspensions).
+		Order("due_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *customerNotificationRepository) FindResolvedTickets(ctx context.Context, tenantID string, since time.Time) ([]*entity.Ticket, error) {
+	var tickets []*entity.Ticket
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Where("tenant_id = ? AND status = ? AND resolved_at >= ?", tenantID, entity.TicketStatusResolved, since).
+		Order("resolved_at ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+func (r *customerNotificationRepository) Claim(ctx context.Context, notification *entity.CustomerNotification, maxAttempts int) (bool, error) {
+	notification.Status = entity.NotificationDeliverySending
+	notification.Attempts = 1
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "dedup_key"}},
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{
+					SQL:  "customer_notifications.status = ? AND customer_notifications.attempts < ?",
+					Vars: []interface{}{entity.NotificationDeliveryFailed, maxAttempts},
+				},
+			}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"status":     entity.NotificationDeliverySending,
+				"recipient":  notification.Recipient,
+				"attempts":   gorm.Expr("customer_notifications.attempts + 1"),
+				"updated_at": time.Now(),
+			}),
+		}).
+		Create(notification)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *customerNotificationRepository) Finish(ctx context.Context, notification *entity.CustomerNotification) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.CustomerNotification{}).
+		Where("dedup_key = ?", notification.DedupKey).
+		Updates(map[string]interface{}{
+			"status":  notification.Status,
+			"subject": notification.Subject,
+			"body":    notification.Body,
+			"error":   notification.Error,
+			"sent_at": notification.SentAt,
+		}).Error
+}
+
+func (r *customerNotificationRepository) FindByCustomer(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerNotification, error) {
+	var notifications []*entity.CustomerNotification
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&notifications).Error
+	return notifications, err
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type invoicePaymentOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoicePaymentOrderRepository(db *gorm.DB) repository.InvoicePaymentOrderRepository {
+	return &invoicePaymentOrderRepository{db: db}
+}
+
+func (r *invoicePaymentOrderRepository) Create(ctx context.Context, order *entity.InvoicePaymentOrder) error {
+	return r.db.WithContext(ctx).Create(order).Error
+}
+
+func (r *invoicePaymentOrderRepository) FindByOrderID(ctx context.Context, tenantID, orderID string) (*entity.InvoicePaymentOrder, error) {
+	var order entity.InvoicePaymentOrder
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND order_id = ?", tenantID, orderID).
+		First(&order).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *invoicePaymentOrderRepository) FindLatestByPayment(ctx context.Context, paymentID string) (*entity.InvoicePaymentOrder, error) {
+	var order entity.InvoicePaymentOrder
+	err := r.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("created_at DESC").
+		First(&order).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *invoicePaymentOrderRepository) ApplyPaid(ctx context.Context, id, gatewayTransactionID string, allocation *entity.PaymentAllocation) (*entity.Payment, bool, error) {
+	var payment *entity.Payment
+	applied := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.InvoicePaymentOrder{}).
+			Where("id = ? AND status <> ?", id, entity.InvoicePaymentOrderPaid).
+			Updates(map[string]interface{}{
+				"status":                 entity.InvoicePaymentOrderPaid,
+				"gateway_transaction_id": gatewayTransactionID,
+				"paid_at":                time.Now(),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		applied = true
+		if allocation == nil {
+			return nil
+		}
+
+		var err error
+		payment, err = lockInvoice(tx, allocation.PaymentID)
+		if err != nil {
+			return err
+		}
+		// An invoice paid some other way while the charge was open leaves
+		// the whole charge as customer credit
+		if payment.Status == entity.PaymentStatusPaid {
+			return creditOverpayment(tx, payment, allocation.Amount, allocation.CreatedBy)
+		}
+		return allocateToInvoice(tx, payment, allocation)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return payment, applied, nil
+}
+
+func (r *invoicePaymentOrderRepository) MarkFailed(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.InvoicePaymentOrder{}).
+		Where("id = ? AND status = ?", id, entity.InvoicePaymentOrderPending).
+		Update("status", entity.InvoicePaymentOrderFailed).Error
+}
@@ -0,0 +1,448 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) repository.InvoiceRepository {
+	return &invoiceRepository{db: db}
+}
+
+// nextInvoiceNumber increments the tenant's invoice sequence. The row stays
+// locked until the transaction ends, so numbers are issued without gaps.
+func nextInvoiceNumber(tx *gorm.DB, tenantID string) (int64, error) {
+	var n int64
+	err := tx.Raw(`INSERT INTO invoice_sequences (tenant_id, last_number, updated_at) VALUES (?, 1, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET last_number = invoice_sequences.last_number + 1, updated_at = NOW()
+		RETURNING last_number`, tenantID).Scan(&n).Error
+	return n, err
+}
+
+// nextCreditNoteNumber increments the tenant's credit note sequence, which
+// shares the row of the invoice sequence
+func nextCreditNoteNumber(tx *gorm.DB, tenantID string) (int64, error) {
+	var n int64
+	err := tx.Raw(`INSERT INTO invoice_sequences (tenant_id, last_credit_note_number, updated_at) VALUES (?, 1, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET last_credit_note_number = invoice_sequences.last_credit_note_number + 1, updated_at = NOW()
+		RETURNING last_credit_note_number`, tenantID).Scan(&n).Error
+	return n, err
+}
+
+// createInvoiceItems stores the items of a freshly inserted invoice
+func createInvoiceItems(tx *gorm.DB, payment *entity.Payment) error {
+	if len(payment.Items) == 0 {
+		return nil
+	}
+	for i := range payment.Items {
+		payment.Items[i].PaymentID = payment.ID
+		payment.Items[i].TenantID = payment.TenantID
+	}
+	return tx.Create(&payment.Items).Error
+}
+
+func (r *invoiceRepository) CreateInvoice(ctx context.Context, payment *entity.Payment, prefix string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		n, err := nextInvoiceNumber(tx, payment.TenantID)
+		if err != nil {
+			return err
+		}
+		number := entity.FormatInvoiceNumber(prefix, n)
+		payment.InvoiceNumber = &number
+
+		if err := tx.Omit(clause.Associations).Create(payment).Error; err != nil {
+			return err
+		}
+		if err := createInvoiceItems(tx, payment); err != nil {
+			return err
+		}
+		return applyCustomerCredit(tx, payment)
+	})
+}
+
+func (r *invoiceRepository) FindInvoice(ctx context.Context, id string) (*entity.Payment, error) {
+	var payment entity.Payment
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Allocations", func(db *gorm.DB) *gorm.DB { return db.Order("paid_at ASC") }).
+		Preload("CreditNotes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(&payment, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// recordInvoicePayment adds a stored allocation to the invoice, which becomes
+// paid once its balance is covered and partially_paid until then
+func recordInvoicePayment(tx *gorm.DB, payment *entity.Payment, allocation *entity.PaymentAllocation) error {
+	payment.PaidAmount += allocation.Amount
+	updates := map[string]interface{}{"paid_amount": payment.PaidAmount}
+	// Half a cent of tolerance for DECIMAL rounding
+	if payment.Balance() < 0.005 {
+		paidAt := allocation.PaidAt
+		payment.Status = entity.PaymentStatusPaid
+		payment.PaymentDate = &paidAt
+		payment.PaymentMethod = allocation.PaymentMethod
+		updates["status"] = payment.Status
+		updates["payment_date"] = payment.PaymentDate
+		updates["payment_method"] = payment.PaymentMethod
+	} else if payment.Status == entity.PaymentStatusPending {
+		// Overdue invoices stay overdue until they are paid in full
+		payment.Status = entity.PaymentStatusPartiallyPaid
+		updates["status"] = payment.Status
+	}
+	return tx.Model(payment).Updates(updates).Error
+}
+
+func (r *invoiceRepository) AddAllocation(ctx context.Context, allocation *entity.PaymentAllocation) (*entity.Payment, error) {
+	var payment *entity.Payment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, err = addAllocation(tx, allocation)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// addAllocation stores an allocation against its invoice, crediting the part
+// above the balance to the customer, and returns the updated invoice
+func addAllocation(tx *gorm.DB, allocation *entity.PaymentAllocation) (*entity.Payment, error) {
+	payment, err := lockInvoice(tx, allocation.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status == entity.PaymentStatusPaid {
+		return nil, errors.NewValidationError("payment is already paid")
+	}
+	return payment, allocateToInvoice(tx, payment, allocation)
+}
+
+// lockInvoice loads an invoice and locks it so concurrent payments cannot both
+// cover the balance
+func lockInvoice(tx *gorm.DB, id string) (*entity.Payment, error) {
+	var payment entity.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// allocateToInvoice stores an allocation against a locked unpaid invoice. The
+// part above the balance becomes customer credit.
+func allocateToInvoice(tx *gorm.DB, payment *entity.Payment, allocation *entity.PaymentAllocation) error {
+	overpaid := entity.RoundMoney(allocation.Amount - payment.Balance())
+	if overpaid > 0 {
+		allocation.Amount = entity.RoundMoney(payment.Balance())
+	}
+
+	if err := tx.Create(allocation).Error; err != nil {
+		return err
+	}
+	if err := recordInvoicePayment(tx, payment, allocation); err != nil {
+		return err
+	}
+	if overpaid <= 0 {
+		return nil
+	}
+	return creditOverpayment(tx, payment, overpaid, allocation.CreatedBy)
+}
+
+// creditOverpayment adds money paid above an invoice's balance to the
+// customer's credit balance
+func creditOverpayment(tx *gorm.DB, payment *entity.Payment, amount float64, createdBy *string) error {
+	invoiceNumber := payment.ID
+	if payment.InvoiceNumber != nil {
+		invoiceNumber = *payment.InvoiceNumber
+	}
+	return addLedgerEntry(tx, &entity.CustomerLedgerEntry{
+		TenantID:   payment.TenantID,
+		CustomerID: payment.CustomerID,
+		Type:       entity.LedgerEntryCredit,
+		Source:     entity.LedgerSourceOverpayment,
+		Amount:     amount,
+		PaymentID:  &payment.ID,
+		Reason:     fmt.Sprintf("Overpayment of invoice %s", invoiceNumber),
+		CreatedBy:  createdBy,
+	})
+}
+
+// createCreditNote checks a note against its locked invoice and the notes
+// still pending on it, then numbers and stores it
+func createCreditNote(tx *gorm.DB, payment *entity.Payment, note *entity.CreditNote) error {
+	var pending struct {
+		Amount       float64
+		RefundAmount float64
+	}
+	if err := tx.Model(&entity.CreditNote{}).
+		Select("COALESCE(SUM(amount), 0) AS amount, COALESCE(SUM(refund_amount), 0) AS refund_amount").
+		Where("payment_id = ? AND status = ?", payment.ID, entity.CreditNoteStatusPending).
+		Scan(&pending).Error; err != nil {
+		return err
+	}
+
+	paid := payment.PaidAmount - pending.RefundAmount
+	if note.RefundAmount > paid+0.005 {
+		return errors.NewValidationError(fmt.Sprintf("refund exceeds the paid amount of %.2f", paid))
+	}
+	// The part of the credit that is not refunded settles the balance
+	balance := payment.Balance() - (pending.Amount - pending.RefundAmount)
+	if note.Amount-note.RefundAmount > balance+0.005 {
+		return errors.NewValidationError(fmt.Sprintf("credit exceeds the outstanding balance of %.2f, refund the difference", balance))
+	}
+
+	n, err := nextCreditNoteNumber(tx, payment.TenantID)
+	if err != nil {
+		return err
+	}
+	note.CreditNoteNumber = entity.FormatCreditNoteNumber(n)
+	return tx.Create(note).Error
+}
+
+// applyCreditNote lowers the locked invoice's balance by the note's credit
+// and its paid amount by the refund
+func applyCreditNote(tx *gorm.DB, payment *entity.Payment, note *entity.CreditNote) error {
+	payment.CreditedAmount += note.Amount
+	payment.PaidAmount -= note.RefundAmount
+	// Payments made while a refund was pending may no longer be needed
+	if overpaid := entity.RoundMoney(-payment.Balance()); overpaid > 0 {
+		payment.PaidAmount = entity.RoundMoney(payment.PaidAmount - overpaid)
+		if err := creditOverpayment(tx, payment, overpaid, note.CreatedBy); err != nil {
+			return err
+		}
+	}
+	updates := map[string]interface{}{
+		"credited_amount": payment.CreditedAmount,
+		"paid_amount":     payment.PaidAmount,
+	}
+	if payment.Status != entity.PaymentStatusPaid && payment.Balance() < 0.005 {
+		paidAt := time.Now()
+		payment.Status = entity.PaymentStatusPaid
+		payment.PaymentDate = &paidAt
+		payment.PaymentMethod = "credit_note"
+		updates["status"] = payment.Status
+		updates["payment_date"] = payment.PaymentDate
+		updates["payment_method"] = payment.PaymentMethod
+	}
+	return tx.Model(payment).Updates(updates).Error
+}
+
+func (r *invoiceRepository) AddCreditNote(ctx context.Context, note *entity.CreditNote) (*entity.Payment, error) {
+	var payment *entity.Payment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if payment, err = lockInvoice(tx, note.PaymentID); err != nil {
+			return err
+		}
+		note.Status = entity.CreditNoteStatusIssued
+		if err := createCreditNote(tx, payment, note); err != nil {
+			return err
+		}
+		return applyCreditNote(tx, payment, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (r *invoiceRepository) ReserveCreditNote(ctx context.Context, note *entity.CreditNote) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		payment, err := lockInvoice(tx, note.PaymentID)
+		if err != nil {
+			return err
+		}
+		note.Status = entity.CreditNoteStatusPending
+		return createCreditNote(tx, payment, note)
+	})
+}
+
+func (r *invoiceRepository) CompleteCreditNote(ctx context.Context, note *entity.CreditNote) (*entity.Payment, error) {
+	var payment *entity.Payment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if payment, err = lockInvoice(tx, note.PaymentID); err != nil {
+			return err
+		}
+		result := tx.Model(note).Where("status = ?", entity.CreditNoteStatusPending).Updates(map[string]interface{}{
+			"status":           entity.CreditNoteStatusIssued,
+			"refund_reference": note.RefundReference,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("credit note %s is not pending", note.ID)
+		}
+		note.Status = entity.CreditNoteStatusIssued
+		return applyCreditNote(tx, payment, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (r *invoiceRepository) FailCreditNote(ctx context.Context, note *entity.CreditNote) error {
+	note.Status = entity.CreditNoteStatusFailed
+	return r.db.WithContext(ctx).Model(note).
+		Where("status = ?", entity.CreditNoteStatusPending).
+		Update("status", entity.CreditNoteStatusFailed).Error
+}
+
+func (r *invoiceRepository) AddLedgerEntry(ctx context.Context, entry *entity.CustomerLedgerEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return addLedgerEntry(tx, entry)
+	})
+}
+
+func (r *invoiceRepository) FindLedgerEntries(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerLedgerEntry, error) {
+	var entries []*entity.CustomerLedgerEntry
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *invoiceRepository) SumLedger(ctx context.Context, tenantID, customerID string) (float64, error) {
+	var sum float64
+	err := r.db.WithContext(ctx).
+		Model(&entity.CustomerLedgerEntry{}).
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN amount ELSE -amount END), 0)", entity.LedgerEntryCredit).
+		Scan(&sum).Error
+	return sum, err
+}
+
+func (r *invoiceRepository) FindPeriodInvoice(ctx context.Context, customerID string, periodStart time.Time) (*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("customer_id = ? AND period_start = ?", customerID, periodStart).
+		Order("created_at ASC").
+		Limit(1).
+		Find(&payments).Error
+	if err != nil || len(payments) == 0 {
+		return nil, err
+	}
+	return payments[0], nil
+}
+
+func (r *invoiceRepository) AddInvoiceItems(ctx context.Context, payment *entity.Payment, items []entity.InvoiceItem) (bool, error) {
+	added := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Payment{}).
+			Where("id = ? AND status IN ?", payment.ID, unpaidPaymentStatuses).
+			Updates(map[string]interface{}{
+				"subtotal":        payment.Subtotal,
+				"discount_amount": payment.DiscountAmount,
+				"tax_percentage":  payment.TaxPercentage,
+				"tax_amount":      payment.TaxAmount,
+				"amount":          payment.Amount,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		added = true
+		return tx.Create(&items).Error
+	})
+	return added && err == nil, err
+}
+
+// addLedgerEntry moves a customer's balance. The customer row stays locked
+// until the transaction ends, so BalanceAfter follows the order of entries.
+func addLedgerEntry(tx *gorm.DB, entry *entity.CustomerLedgerEntry) error {
+	var customer entity.Customer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "credit_balance").
+		First(&customer, "id = ? AND tenant_id = ? AND deleted_at IS NULL", entry.CustomerID, entry.TenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		return errors.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	balance := customer.CreditBalance
+	switch entry.Type {
+	case entity.LedgerEntryCredit:
+		balance += entry.Amount
+	case entity.LedgerEntryDebit:
+		if entry.Amount > balance+0.005 {
+			return errors.NewValidationError(fmt.Sprintf("amount exceeds the customer balance of %.2f", balance))
+		}
+		balance -= entry.Amount
+	default:
+		return errors.NewValidationError("type must be credit or debit")
+	}
+	entry.BalanceAfter = entity.RoundMoney(balance)
+
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+	return tx.Model(&entity.Customer{}).Where("id = ?", customer.ID).Update("credit_balance", entry.BalanceAfter).Error
+}
+
+// applyCustomerCredit pays as much of a new invoice as the customer's
+// balance covers
+func applyCustomerCredit(tx *gorm.DB, payment *entity.Payment) error {
+	var customer entity.Customer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "credit_balance").
+		First(&customer, "id = ?", payment.CustomerID).Error
+	if err != nil {
+		return err
+	}
+	amount := entity.RoundMoney(math.Min(customer.CreditBalance, payment.Balance()))
+	if amount <= 0 {
+		return nil
+	}
+
+	if err := addLedgerEntry(tx, &entity.CustomerLedgerEntry{
+		TenantID:   payment.TenantID,
+		CustomerID: payment.CustomerID,
+		Type:       entity.LedgerEntryDebit,
+		Source:     entity.LedgerSourceInvoice,
+		Amount:     amount,
+		PaymentID:  &payment.ID,
+		Reason:     fmt.Sprintf("Applied to invoice %s", *payment.InvoiceNumber),
+	}); err != nil {
+		return err
+	}
+
+	allocation := &entity.PaymentAllocation{
+		TenantID:      payment.TenantID,
+		CustomerID:    payment.CustomerID,
+		PaymentID:     payment.ID,
+		Amount:        amount,
+		PaymentMethod: entity.PaymentMethodCreditBalance,
+		PaidAt:        time.Now(),
+		Notes:         "Paid from customer balance",
+	}
+	if err := tx.Create(allocation).Error; err != nil {
+		return err
+	}
+	payment.Allocations = append(payment.Allocations, *allocation)
+	return recordInvoicePayment(tx, payment, allocation)
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type invoiceRunRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRunRepository(db *gorm.DB) repository.InvoiceRunRepository {
+	return &invoiceRunRepository{db: db}
+}
+
+func (r *invoiceRunRepository) FindBillableCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status = ? AND deleted_at IS NULL", tenantID, entity.CustomerStatusActive).
+		Order("customer_code ASC").
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *invoiceRunRepository) FindPeriodInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Select("id, customer_id, period_start, period_end").
+		Where("tenant_id = ? AND period_start >= ?", tenantID, since).
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *invoiceRunRepository) FindAppliedPlanChanges(ctx context.Context, tenantID string, since time.Time) ([]*entity.CustomerPlanChange, error) {
+	var changes []*entity.CustomerPlanChange
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status = ? AND effective_date >= ?", tenantID, entity.PlanChangeStatusApplied, since).
+		Order("effective_date ASC, applied_at ASC").
+		Find(&changes).Error
+	return changes, err
+}
+
+// errInvoiceExists rolls back the invoice number of a skipped invoice
+var errInvoiceExists = errors.New("invoice already exists for the period")
+
+func (r *invoiceRunRepository) CreateInvoice(ctx context.Context, payment *entity.Payment, prefix string) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		n, err := nextInvoiceNumber(tx, payment.TenantID)
+		if err != nil {
+			return err
+		}
+		number := entity.FormatInvoiceNumber(prefix, n)
+		payment.InvoiceNumber = &number
+
+		// idx_payments_customer_period makes a concurrent run a no-op
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Omit(clause.Associations).
+			Create(payment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvoiceExists
+		}
+		if err := createInvoiceItems(tx, payment); err != nil {
+			return err
+		}
+		return applyCustomerCredit(tx, payment)
+	})
+	if err == errInvoiceExists {
+		payment.InvoiceNumber = nil
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *invoiceRunRepository) CreateRun(ctx context.Context, run *entity.InvoiceRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *invoiceRunRepository) ListRuns(ctx context.Context, tenantID string, limit int) ([]*entity.InvoiceRun, error) {
+	var runs []*entity.InvoiceRun
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type lateFeeRepository struct {
+	db *gorm.DB
+}
+
+func NewLateFeeRepository(db *gorm.DB) repository.LateFeeRepository {
+	return &lateFeeRepository{db: db}
+}
+
+func (r *lateFeeRepository) FindLateFeeCandidates(ctx context.Context, tenantID string, dueBefore time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status IN ? AND due_date < ? AND late_fee_applied_at IS NULL", tenantID, unpaidPaymentStatuses, dueBefore).
+		Order("due_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *lateFeeRepository) ApplyLateFee(ctx context.Context, item *entity.InvoiceItem, at time.Time) (bool, error) {
+	applied := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Payment{}).
+			Where("id = ? AND status IN ? AND late_fee_applied_at IS NULL", item.PaymentID, unpaidPaymentStatuses).
+			Updates(map[string]interface{}{
+				"late_fee":            item.Amount,
+				"late_fee_applied_at": at,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		applied = true
+		return tx.Create(item).Error
+	})
+	return applied && err == nil, err
+}
+
+func (r *lateFeeRepository) WaiveLateFee(ctx context.Context, paymentID string, waivedBy *string, reason string, at time.Time) (*entity.Payment, bool, error) {
+	var payment entity.Payment
+	waived := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status IN ? AND late_fee_applied_at IS NOT NULL AND late_fee_waived_at IS NULL", paymentID, unpaidPaymentStatuses).
+			First(&payment).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		payment.LateFeeWaivedAt = &at
+		payment.LateFeeWaivedBy = waivedBy
+		payment.LateFeeWaiveReason = reason
+		updates := map[string]interface{}{
+			"late_fee_waived_at":    at,
+			"late_fee_waived_by":    waivedBy,
+			"late_fee_waive_reason": reason,
+		}
+		// Without the fee the payments made so far may settle the invoice
+		if payment.Balance() < 0.005 {
+			// What was paid towards the fee becomes customer credit
+			if overpaid := entity.RoundMoney(-payment.Balance()); overpaid > 0 {
+				payment.PaidAmount = entity.RoundMoney(payment.PaidAmount - overpaid)
+				updates["paid_amount"] = payment.PaidAmount
+				invoiceNumber := payment.ID
+				if payment.InvoiceNumber != nil {
+					invoiceNumber = *payment.InvoiceNumber
+				}
+				if err := addLedgerEntry(tx, &entity.CustomerLedgerEntry{
+					TenantID:   payment.TenantID,
+					CustomerID: payment.CustomerID,
+					Type:       entity.LedgerEntryCredit,
+					Source:     entity.LedgerSourceOverpayment,
+					Amount:     overpaid,
+					PaymentID:  &payment.ID,
+					Reason:     fmt.Sprintf("Late fee waived on invoice %s", invoiceNumber),
+					CreatedBy:  waivedBy,
+				}); err != nil {
+					return err
+				}
+			}
+			payment.Status = entity.PaymentStatusPaid
+			payment.PaymentDate = &at
+			payment.PaymentMethod = entity.PaymentMethodLateFeeWaiver
+			updates["status"] = payment.Status
+			updates["payment_date"] = payment.PaymentDate
+			updates["payment_method"] = payment.PaymentMethod
+		}
+		if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+			return err
+		}
+		waived = true
+		return nil
+	})
+	if err != nil || !waived {
+		return nil, false, err
+	}
+	return &payment, true, nil
+}
+
+func (r *lateFeeRepository) CreateAction(ctx context.Context, action *entity.BillingActionLog) error {
+	return r.db.WithContext(ctx).Create(action).Error
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type notificationTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationTemplateRepository(db *gorm.DB) repository.NotificationTemplateRepository {
+	return &notificationTemplateRepository{db: db}
+}
+
+func (r *notificationTemplateRepository) Find(ctx context.Context, tenantID, event, channel, language string) (*entity.NotificationTemplate, error) {
+	var template entity.NotificationTemplate
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND event = ? AND channel = ? AND language = ?", tenantID, event, channel, language).
+		First(&template).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	template.Customized = true
+	return &template, nil
+}
+
+func (r *notificationTemplateRepository) FindByTenant(ctx context.Context, tenantID string) ([]*entity.NotificationTemplate, error) {
+	var templates []*entity.NotificationTemplate
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("event, channel, language").
+		Find(&templates).Error
+	for _, template := range templates {
+		template.Customized = true
+	}
+	return templates, err
+}
+
+func (r *notificationTemplateRepository) Save(ctx context.Context, template *entity.NotificationTemplate) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "tenant_id"}, {Name: "event"}, {Name: "channel"}, {Name: "language"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"subject":    template.Subject,
+				"body":       template.Body,
+				"updated_by": template.UpdatedBy,
+				"updated_at": time.Now(),
+			}),
+		}).
+		Create(template).Error
+}
+
+func (r *notificationTemplateRepository) Delete(ctx context.Context, tenantID, event, channel, language string) error {
+	result := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND event = ? AND channel = ? AND language = ?", tenantID, event, channel, language).
+		Delete(&entity.NotificationTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
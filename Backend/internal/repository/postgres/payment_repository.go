@@ -14,6 +14,12 @@ type paymentRepository struct {
 	db *gorm.DB
 }
 
+// paymentTotalSQL is the invoice amount plus the late fee unless it was waived
+const paymentTotalSQL = "amount + CASE WHEN late_fee_waived_at IS NULL THEN late_fee ELSE 0 END"
+
+// paymentBalanceSQL is what the customer still has to pay, see Payment.Balance
+const paymentBalanceSQL = "(" + paymentTotalSQL + ") - credited_amount - paid_amount"
+
 func NewPaymentRepository(db *gorm.DB) repository.PaymentRepository {
 	return &paymentRepository{db: db}
 }
@@ -122,12 +128,22 @@ func (r *paymentRepository) CountByStatus(ctx context.Context, tenantID, status
 	return int(count), err
 }
 
-func (r *paymentRepository) SumByStatus(ctx context.Context, tenantID, status string) (float64, error) {
+func (r *paymentRepository) SumPaidAmount(ctx context.Context, tenantID string) (float64, error) {
 	var sum float64
 	err := r.db.WithContext(ctx).
 		Model(&entity.Payment{}).
-		Where("tenant_id = ? AND status = ?", tenantID, status).
-		Select("COALESCE(SUM(amount), 0)").
+		Where("tenant_id = ?", tenantID).
+		Select("COALESCE(SUM(paid_amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+func (r *paymentRepository) SumBalanceByStatus(ctx context.Context, tenantID string, statuses ...string) (float64, error) {
+	var sum float64
+	err := r.db.WithContext(ctx).
+		Model(&entity.Payment{}).
+		Where("tenant_id = ? AND status IN ?", tenantID, statuses).
+		Select("COALESCE(SUM(" + paymentBalanceSQL + "), 0)").
 		Scan(&sum).Error
 	return sum, err
 }
@@ -137,11 +153,12 @@ func (r *paymentRepository) SumByMonth(ctx context.Context, tenantID string, mon
 	endDate := startDate.AddDate(0, 1, 0)
 
 	var sum float64
+	// paid_amount leaves out credit notes and refunds
 	err := r.db.WithContext(ctx).
 		Model(&entity.Payment{}).
 		Where("tenant_id = ? AND status = ? AND payment_date >= ? AND payment_date < ?",
 			tenantID, entity.PaymentStatusPaid, startDate, endDate).
-		Select("COALESCE(SUM(amount), 0)").
+		Select("COALESCE(SUM(paid_amount), 0)").
 		Scan(&sum).Error
 	return sum, err
 }
@@ -8,12 +8,17 @@ import (
 	"github.com/rtrwnet/saas-backend/internal/domain/repository"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type paymentTransactionRepository struct {
 	db *gorm.DB
 }
 
+// transactionNetSQL is what the platform kept of a transaction after refunds.
+// Fully refunded transactions are no longer paid.
+const transactionNetSQL = "amount - refunded_amount"
+
 func NewPaymentTransactionRepository(db *gorm.DB) repository.PaymentTransactionRepository {
 	return &paymentTransactionRepository{db: db}
 }
@@ -61,7 +66,7 @@ func (r *paymentTransactionRepository) FindByOrderID(ctx context.Context, orderI
 	
 	// Use GORM with explicit select to ensure all columns including plan_id are fetched
 	if err := r.db.WithContext(ctx).
-		Select("id, tenant_id, subscription_id, plan_id, order_id, amount, status, payment_method, payment_gateway, gateway_transaction_id, gateway_response, paid_at, expired_at, created_at, updated_at").
+		Select("id, tenant_id, subscription_id, plan_id, order_id, amount, refunded_amount, status, payment_method, payment_gateway, gateway_transaction_id, gateway_response, paid_at, expired_at, created_at, updated_at").
 		Where("order_id = ?", orderID).
 		First(&transaction).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -104,6 +109,100 @@ func (r *paymentTransactionRepository) Update(ctx context.Context, transaction *
 	return nil
 }
 
+// lockRefundable locks a paid transaction so concurrent refunds cannot
+// exceed its amount, counting the refunds still processing at the gateway
+func lockRefundable(tx *gorm.DB, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error) {
+	var transaction entity.PaymentTransaction
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, "id = ?", refund.TransactionID).Error; err != nil {
+		return nil, err
+	}
+	if transaction.Status != entity.TransactionStatusPaid {
+		return nil, errors.NewValidationError("only paid transactions can be refunded")
+	}
+	var processing float64
+	if err := tx.Model(&entity.PaymentTransactionRefund{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("transaction_id = ? AND status = ?", transaction.ID, entity.RefundStatusProcessing).
+		Scan(&processing).Error; err != nil {
+		return nil, err
+	}
+	remaining := transaction.Amount - transaction.RefundedAmount - processing
+	if refund.Amount > remaining+0.005 {
+		return nil, errors.NewValidationError(fmt.Sprintf("amount exceeds the refundable amount of %.2f", remaining))
+	}
+	return &transaction, nil
+}
+
+// applyRefund adds a refund to the locked transaction
+func applyRefund(tx *gorm.DB, transaction *entity.PaymentTransaction, refund *entity.PaymentTransactionRefund) error {
+	transaction.RefundedAmount += refund.Amount
+	updates := map[string]interface{}{"refunded_amount": transaction.RefundedAmount}
+	if transaction.Amount-transaction.RefundedAmount < 0.005 {
+		transaction.Status = entity.TransactionStatusRefunded
+		updates["status"] = transaction.Status
+	}
+	return tx.Model(transaction).Updates(updates).Error
+}
+
+func (r *paymentTransactionRepository) AddRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error) {
+	var transaction *entity.PaymentTransaction
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if transaction, err = lockRefundable(tx, refund); err != nil {
+			return err
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		return applyRefund(tx, transaction, refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+func (r *paymentTransactionRepository) ReserveRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockRefundable(tx, refund); err != nil {
+			return err
+		}
+		refund.Status = entity.RefundStatusProcessing
+		return tx.Create(refund).Error
+	})
+}
+
+func (r *paymentTransactionRepository) CompleteRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error) {
+	var transaction entity.PaymentTransaction
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, "id = ?", refund.TransactionID).Error; err != nil {
+			return err
+		}
+		result := tx.Model(refund).Where("status = ?", entity.RefundStatusProcessing).Updates(map[string]interface{}{
+			"status":            refund.Status,
+			"gateway_refund_id": refund.GatewayRefundID,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("refund %s is not processing", refund.ID)
+		}
+		return applyRefund(tx, &transaction, refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *paymentTransactionRepository) FailRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) error {
+	refund.Status = entity.RefundStatusFailed
+	return r.db.WithContext(ctx).Model(refund).
+		Where("status = ?", entity.RefundStatusProcessing).
+		Update("status", entity.RefundStatusFailed).Error
+}
+
 func (r *paymentTransactionRepository) FindAll(ctx context.Context, page, perPage int, status, tenantID, search string) ([]*entity.PaymentTransaction, int64, error) {
 	var transactions []*entity.PaymentTransaction
 	var total int64
@@ -148,11 +247,11 @@ func (r *paymentTransactionRepository) GetStats(ctx context.Context) (*repositor
 		return nil, fmt.Errorf("failed to count total transactions: %w", err)
 	}
 
-	// Get total revenue (sum of paid transactions)
+	// Get total revenue (sum of paid transactions less their refunds)
 	var totalRevenue float64
 	if err := r.db.WithContext(ctx).Model(&entity.PaymentTransaction{}).
 		Where("status = ?", "paid").
-		Select("COALESCE(SUM(amount), 0)").
+		Select("COALESCE(SUM(" + transactionNetSQL + "), 0)").
 		Scan(&totalRevenue).Error; err != nil {
 		return nil, fmt.Errorf("failed to sum revenue: %w", err)
 	}
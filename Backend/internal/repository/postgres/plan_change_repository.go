@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type planChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewPlanChangeRepository(db *gorm.DB) repository.PlanChangeRepository {
+	return &planChangeRepository{db: db}
+}
+
+func (r *planChangeRepository) FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error) {
+	var customer entity.Customer
+	err := r.db.WithContext(ctx).
+		Preload("ServicePlan").
+		First(&customer, "id = ? AND tenant_id = ? AND deleted_at IS NULL", customerID, tenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (r *planChangeRepository) FindPlan(ctx context.Context, tenantID, planID string) (*entity.ServicePlan, error) {
+	var plan entity.ServicePlan
+	err := r.db.WithContext(ctx).First(&plan, "id = ? AND tenant_id = ?", planID, tenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *planChangeRepository) Create(ctx context.Context, change *entity.CustomerPlanChange) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(change).Error
+}
+
+func (r *planChangeRepository) ApplyChange(ctx context.Context, change *entity.CustomerPlanChange, at time.Time) (bool, error) {
+	applied := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.CustomerPlanChange{}).
+			Where("id = ? AND status = ?", change.ID, entity.PlanChangeStatusScheduled).
+			Updates(map[string]interface{}{
+				"status":     entity.PlanChangeStatusApplied,
+				"applied_at": at,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := tx.Model(&entity.Customer{}).Where("id = ?", change.CustomerID).Updates(map[string]interface{}{
+			"service_plan_id": change.ToPlanID,
+			"monthly_fee":     change.ToMonthlyFee,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.RadiusUser{}).
+			Where("customer_id = ?", change.CustomerID).
+			Update("profile_name", tx.Model(&entity.ServicePlan{}).Select("name").Where("id = ?", change.ToPlanID)).Error; err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if applied {
+		change.Status = entity.PlanChangeStatusApplied
+		change.AppliedAt = &at
+	}
+	return applied, nil
+}
+
+func (r *planChangeRepository) SaveProration(ctx context.Context, change *entity.CustomerPlanChange) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.CustomerPlanChange{}).
+		Where("id = ?", change.ID).
+		Updates(map[string]interface{}{
+			"proration_amount":     change.ProrationAmount,
+			"proration_payment_id": change.ProrationPaymentID,
+		}).Error
+}
+
+func (r *planChangeRepository) CancelScheduled(ctx context.Context, tenantID, customerID, changeID string, at time.Time) (int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&entity.CustomerPlanChange{}).
+		Where("tenant_id = ? AND customer_id = ? AND status = ?", tenantID, customerID, entity.PlanChangeStatusScheduled)
+	if changeID != "" {
+		query = query.Where("id = ?", changeID)
+	}
+	result := query.Updates(map[string]interface{}{
+		"status":       entity.PlanChangeStatusCancelled,
+		"cancelled_at": at,
+	})
+	return result.RowsAffected, result.Error
+}
+
+func (r *planChangeRepository) FindHistory(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerPlanChange, error) {
+	var changes []*entity.CustomerPlanChange
+	err := r.db.WithContext(ctx).
+		Preload("FromPlan").
+		Preload("ToPlan").
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&changes).Error
+	return changes, err
+}
+
+func (r *planChangeRepository) FindDueChanges(ctx context.Context, tenantID string, day time.Time) ([]*entity.CustomerPlanChange, error) {
+	var changes []*entity.CustomerPlanChange
+	err := r.db.WithContext(ctx).
+		Preload("FromPlan").
+		Preload("ToPlan").
+		Where("tenant_id = ? AND status = ? AND effective_date <= ?", tenantID, entity.PlanChangeStatusScheduled, day).
+		Order("effective_date ASC, created_at ASC").
+		Find(&changes).Error
+	return changes, err
+}
+
+func (r *planChangeRepository) FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error) {
+	var users []*entity.RadiusUser
+	err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).Find(&users).Error
+	return users, err
+}
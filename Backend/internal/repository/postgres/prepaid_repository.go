@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type prepaidRepository struct {
+	db *gorm.DB
+}
+
+func NewPrepaidRepository(db *gorm.DB) repository.PrepaidRepository {
+	return &prepaidRepository{db: db}
+}
+
+func (r *prepaidRepository) ExtendService(ctx context.Context, payment *entity.Payment, at time.Time, until func(current *time.Time) time.Time) (*time.Time, error) {
+	var serviceUntil *time.Time
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Payment{}).
+			Where("id = ? AND status = ? AND service_extended_at IS NULL", payment.ID, entity.PaymentStatusPaid).
+			Update("service_extended_at", at)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		// Lock the customer so two paid invoices extend one after the other
+		var customer entity.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "service_until").
+			First(&customer, "id = ?", payment.CustomerID).Error; err != nil {
+			return err
+		}
+
+		next := until(customer.ServiceUntil)
+		serviceUntil = &next
+		if err := tx.Model(&entity.Customer{}).Where("id = ?", customer.ID).Updates(map[string]interface{}{
+			"service_until":      next,
+			"expiry_reminded_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.RadiusUser{}).
+			Where("customer_id = ?", customer.ID).
+			Update("expire_date", next).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return serviceUntil, nil
+}
+
+func (r *prepaidRepository) FindUnextendedPayments(ctx context.Context, tenantID string, paidSince time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status = ? AND payment_date >= ? AND service_extended_at IS NULL", tenantID, entity.PaymentStatusPaid, paidSince).
+		Order("payment_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *prepaidRepository) FindExpiringCustomers(ctx context.Context, tenantID string, from, to time.Time) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	err := r.db.WithContext(ctx).
+		Preload("ServicePlan").
+		Where("tenant_id = ? AND status = ? AND service_until > ? AND service_until <= ? AND expiry_reminded_at IS NULL AND deleted_at IS NULL",
+			tenantID, entity.CustomerStatusActive, from, to).
+		Order("service_until ASC").
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *prepaidRepository) MarkExpiryReminded(ctx context.Context, customerID string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.Customer{}).
+		Where("id = ?", customerID).
+		Update("expiry_reminded_at", at).Error
+}
+
+func (r *prepaidRepository) FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error) {
+	var users []*entity.RadiusUser
+	err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).Find(&users).Error
+	return users, err
+}
+
+func (r *prepaidRepository) CreateAction(ctx context.Context, action *entity.BillingActionLog) error {
+	return r.db.WithContext(ctx).Create(action).Error
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type queueSyncRepository struct {
+	db *gorm.DB
+}
+
+func NewQueueSyncRepository(db *gorm.DB) repository.QueueSyncRepository {
+	return &queueSyncRepository{db: db}
+}
+
+func (r *queueSyncRepository) FindQueueCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	err := r.db.WithContext(ctx).
+		Preload("ServicePlan").
+		Where("tenant_id = ? AND status = ? AND deleted_at IS NULL", tenantID, entity.CustomerStatusActive).
+		Order("customer_code ASC").
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *queueSyncRepository) FindAdvancedSettings(ctx context.Context, planIDs []string) ([]*entity.ServicePlanAdvancedSettings, error) {
+	var settings []*entity.ServicePlanAdvancedSettings
+	if len(planIDs) == 0 {
+		return settings, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("service_plan_id IN ?", planIDs).
+		Find(&settings).Error
+	return settings, err
+}
+
+func (r *queueSyncRepository) CreateReport(ctx context.Context, report *entity.QueueSyncReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+func (r *queueSyncRepository) ListReports(ctx context.Context, deviceID string, limit int) ([]*entity.QueueSyncReport, error) {
+	var reports []*entity.QueueSyncReport
+	err := r.db.WithContext(ctx).
+		Where("device_id = ?", deviceID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&reports).Error
+	return reports, err
+}
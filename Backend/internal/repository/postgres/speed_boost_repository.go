@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type speedBoostRepository struct {
+	db *gorm.DB
+}
+
+func NewSpeedBoostRepository(db *gorm.DB) repository.SpeedBoostRepository {
+	return &speedBoostRepository{db: db}
+}
+
+func (r *speedBoostRepository) FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error) {
+	return r.findCustomer(ctx, "id = ? AND tenant_id = ?", customerID, tenantID)
+}
+
+func (r *speedBoostRepository) findCustomer(ctx context.Context, query string, args ...interface{}) (*entity.Customer, error) {
+	var customer entity.Customer
+	err := r.db.WithContext(ctx).
+		Preload("ServicePlan").
+		Where(query, args...).
+		Where("deleted_at IS NULL").
+		First(&customer).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (r *speedBoostRepository) FindPlan(ctx context.Context, tenantID, planID string) (*entity.ServicePlan, error) {
+	var plan entity.ServicePlan
+	err := r.db.WithContext(ctx).First(&plan, "id = ? AND tenant_id = ?", planID, tenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *speedBoostRepository) Create(ctx context.Context, boost *entity.SpeedBoost) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(boost).Error
+}
+
+func (r *speedBoostRepository) FindByID(ctx context.Context, tenantID, id string) (*entity.SpeedBoost, error) {
+	var boost entity.SpeedBoost
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Preload("CurrentPlan").
+		Preload("BoostPlan").
+		First(&boost, "id = ? AND tenant_id = ?", id, tenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &boost, nil
+}
+
+func (r *speedBoostRepository) ListBoosts(ctx context.Context, tenantID string, filter repository.SpeedBoostFilter) ([]*entity.SpeedBoost, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Customer").
+		Preload("CurrentPlan").
+		Preload("BoostPlan").
+		Where("tenant_id = ?", tenantID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.CustomerID != "" {
+		query = query.Where("customer_id = ?", filter.CustomerID)
+	}
+
+	var boosts []*entity.SpeedBoost
+	err := query.Order("request_date DESC").Limit(filter.Limit).Find(&boosts).Error
+	return boosts, err
+}
+
+func (r *speedBoostRepository) HasOpenBoost(ctx context.Context, customerID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.SpeedBoost{}).
+		Where("customer_id = ? AND status IN ?", customerID, []string{
+			entity.SpeedBoostStatusPending,
+			entity.SpeedBoostStatusApproved,
+			entity.SpeedBoostStatusActive,
+		}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *speedBoostRepository) Transition(ctx context.Context, boost *entity.SpeedBoost, from string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entity.SpeedBoost{}).
+		Where("id = ? AND status = ?", boost.ID, from).
+		Updates(map[string]interface{}{
+			"status":           boost.Status,
+			"price":            boost.Price,
+			"start_date":       boost.StartDate,
+			"end_date":         boost.EndDate,
+			"rejection_reason": boost.RejectionReason,
+			"reviewed_by":      boost.ReviewedBy,
+			"reviewed_at":      boost.ReviewedAt,
+			"payment_id":       boost.PaymentID,
+			"expired_at":       boost.ExpiredAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *speedBoostRepository) FindDueBoosts(ctx context.Context, now time.Time) ([]*entity.SpeedBoost, error) {
+	var boosts []*entity.SpeedBoost
+	err := r.db.WithContext(ctx).
+		Preload("BoostPlan").
+		Where("(status = ? AND (start_date IS NULL OR start_date <= ?)) OR (status = ? AND end_date <= ?)",
+			entity.SpeedBoostStatusApproved, now, entity.SpeedBoostStatusActive, now).
+		Order("created_at ASC").
+		Find(&boosts).Error
+	return boosts, err
+}
+
+func (r *speedBoostRepository) FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error) {
+	var users []*entity.RadiusUser
+	err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type subscriptionDunningRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionDunningRepository(db *gorm.DB) repository.SubscriptionDunningRepository {
+	return &subscriptionDunningRepository{db: db}
+}
+
+func (r *subscriptionDunningRepository) ClaimStep(ctx context.Context, step *entity.SubscriptionDunningStep) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(step)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record dunning step: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
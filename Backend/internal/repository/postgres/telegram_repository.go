@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type telegramRepository struct {
+	db *gorm.DB
+}
+
+func NewTelegramRepository(db *gorm.DB) repository.TelegramRepository {
+	return &telegramRepository{db: db}
+}
+
+func (r *telegramRepository) FindBot(ctx context.Context, tenantID string) (*entity.TelegramBot, error) {
+	var bot entity.TelegramBot
+	err := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID).First(&bot).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+func (r *telegramRepository) SaveBot(ctx context.Context, bot *entity.TelegramBot) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(bot).Error
+}
+
+func (r *telegramRepository) SetUpdateOffset(ctx context.Context, tenantID string, offset int64) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.TelegramBot{}).
+		Where("tenant_id = ?", tenantID).
+		Update("update_offset", gorm.Expr("GREATEST(update_offset, ?)", offset)).Error
+}
+
+func (r *telegramRepository) SetEventsPushed(ctx context.Context, tenantID string, until time.Time, overduePushedOn *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.TelegramBot{}).
+		Where("tenant_id = ?", tenantID).
+		Updates(map[string]interface{}{
+			"events_pushed_at":  until,
+			"overdue_pushed_on": overduePushedOn,
+		}).Error
+}
+
+func (r *telegramRepository) findChat(ctx context.Context, query string, args ...interface{}) (*entity.TelegramChat, error) {
+	var chat entity.TelegramChat
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where(query, args...).
+		First(&chat).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+func (r *telegramRepository) FindChatByUser(ctx context.Context, tenantID, userID string) (*entity.TelegramChat, error) {
+	return r.findChat(ctx, "tenant_id = ? AND user_id = ?", tenantID, userID)
+}
+
+func (r *telegramRepository) FindChatByLinkCode(ctx context.Context, tenantID, code string) (*entity.TelegramChat, error) {
+	return r.findChat(ctx, "tenant_id = ? AND link_code = ?", tenantID, code)
+}
+
+func (r *telegramRepository) FindChatByChatID(ctx context.Context, tenantID string, chatID int64) (*entity.TelegramChat, error) {
+	return r.findChat(ctx, "tenant_id = ? AND chat_id = ?", tenantID, chatID)
+}
+
+func (r *telegramRepository) FindLinkedChats(ctx context.Context, tenantID string) ([]*entity.TelegramChat, error) {
+	var chats []*entity.TelegramChat
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Joins("JOIN users ON users.id = telegram_chats.user_id").
+		Where("telegram_chats.tenant_id = ? AND telegram_chats.chat_id IS NOT NULL AND users.is_active = ?", tenantID, true).
+		Order("telegram_chats.linked_at ASC").
+		Find(&chats).Error
+	return chats, err
+}
+
+func (r *telegramRepository) SaveChat(ctx context.Context, chat *entity.TelegramChat) error {
+	return r.db.WithContext(ctx).Save(chat).Error
+}
+
+func (r *telegramRepository) DeleteChat(ctx context.Context, tenantID, id string) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Delete(&entity.TelegramChat{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *telegramRepository) SearchCustomers(ctx context.Context, tenantID, query string, limit int) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	pattern := "%" + query + "%"
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND deleted_at IS NULL", tenantID).
+		Where("name ILIKE ? OR customer_code ILIKE ? OR phone ILIKE ?", pattern, pattern, pattern).
+		Order("name ASC").
+		Limit(limit).
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *telegramRepository) FindCustomerByCode(ctx context.Context, tenantID, code string) (*entity.Customer, error) {
+	var customer entity.Customer
+	err := r.db.WithContext(ctx).
+		Preload("ServicePlan").
+		Where("tenant_id = ? AND UPPER(customer_code) = UPPER(?) AND deleted_at IS NULL", tenantID, code).
+		First(&customer).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (r *telegramRepository) FindCustomers(ctx context.Context, tenantID string, ids []string) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	if len(ids) == 0 {
+		return customers, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id IN ?", tenantID, ids).
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *telegramRepository) FindUnpaidInvoices(ctx context.Context, tenantID, customerID string) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND customer_id = ? AND status IN ?", tenantID, customerID, unpaidPaymentStatuses).
+		Order("due_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *telegramRepository) FindNewTickets(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Ticket, error) {
+	var tickets []*entity.Ticket
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Where("tenant_id = ? AND created_at >= ? AND created_at < ?", tenantID, since, until).
+		Order("created_at ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+func (r *telegramRepository) FindAllocations(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.PaymentAllocation, error) {
+	var allocations []*entity.PaymentAllocation
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND created_at >= ? AND created_at < ?", tenantID, since, until).
+		Order("created_at ASC").
+		Find(&allocations).Error
+	return allocations, err
+}
+
+func (r *telegramRepository) FindDownRouters(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Device, error) {
+	var devices []*entity.Device
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND mikrotik_api_enabled = ? AND status <> ?", tenantID, true, entity.DeviceStatusMaintenance).
+		Where("connection_status IN ? AND last_connected_at >= ? AND last_connected_at < ?",
+			[]string{entity.ConnectionStatusError, entity.ConnectionStatusDisconnected}, since, until).
+		Order("device_name ASC").
+		Find(&devices).Error
+	return devices, err
+}
+
+func (r *telegramRepository) FindOverdueInvoices(ctx context.Context, tenantID string, before time.Time, limit int) ([]*entity.Payment, int64, error) {
+	overdue := func() *gorm.DB {
+		return r.db.WithContext(ctx).
+			Model(&entity.Payment{}).
+			Where("tenant_id = ? AND status IN ? AND due_date < ?", tenantID, unpaidPaymentStatuses, before)
+	}
+
+	var total int64
+	if err := overdue().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var payments []*entity.Payment
+	err := overdue().
+		Preload("Customer").
+		Order("due_date ASC").
+		Limit(limit).
+		Find(&payments).Error
+	return payments, total, err
+}
@@ -67,6 +67,18 @@ func (r *tenantSubscriptionRepository) FindExpiringIn(ctx context.Context, days
 	return subscriptions, nil
 }
 
+func (r *tenantSubscriptionRepository) FindForDunning(ctx context.Context, billedBefore time.Time) ([]*entity.TenantSubscription, error) {
+	var subscriptions []*entity.TenantSubscription
+	if err := r.db.WithContext(ctx).
+		Preload("Plan").
+		Where("status IN ?", []string{entity.SubscriptionStatusTrial, entity.SubscriptionStatusActive, entity.SubscriptionStatusPastDue, entity.SubscriptionStatusSuspended}).
+		Where("COALESCE(next_billing_date, end_date) <= ?", billedBefore).
+		Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to find subscriptions for dunning: %w", err)
+	}
+	return subscriptions, nil
+}
+
 func (r *tenantSubscriptionRepository) Update(ctx context.Context, subscription *entity.TenantSubscription) error {
 	fmt.Printf("[DEBUG] Updating subscription: id=%s, plan_id=%s, status=%s\n", 
 		subscription.ID, subscription.PlanID, subscription.Status)
@@ -90,7 +102,7 @@ func (r *tenantSubscriptionRepository) FindActiveByTenantID(ctx context.Context,
 	var subscription entity.TenantSubscription
 	err := r.db.WithContext(ctx).
 		Where("tenant_id = ?", tenantID).
-		Where("status IN ?", []string{entity.SubscriptionStatusActive, entity.SubscriptionStatusTrial}).
+		Where("status IN ?", []string{entity.SubscriptionStatusActive, entity.SubscriptionStatusTrial, entity.SubscriptionStatusPastDue, entity.SubscriptionStatusReadOnly}).
 		Preload("Plan").
 		First(&subscription).Error
 	if err != nil {
@@ -109,8 +121,17 @@ func (r *tenantSubscriptionRepository) FindActiveByTenantID(ctx context.Context,
 	fmt.Printf("[DEBUG] FindActiveByTenantID: tenant=%s, subscription_id=%s, plan_id=%s, status=%s\n",
 		tenantID, subscription.ID, subscription.PlanID, subscription.Status)
 	
-	// Check if trial/subscription has expired based on end_date
-	if subscription.EndDate != nil && time.Now().After(*subscription.EndDate) {
+	// Check if subscription has expired based on end_date. Trials and unpaid
+	// auto-renewing subscriptions are moved on by the dunning job instead.
+	deadline := subscription.EndDate
+	switch {
+	case subscription.Status == entity.SubscriptionStatusTrial,
+		subscription.Status == entity.SubscriptionStatusReadOnly,
+		subscription.Status == entity.SubscriptionStatusPastDue,
+		subscription.Status == entity.SubscriptionStatusActive && subscription.AutoRenew:
+		deadline = nil
+	}
+	if deadline != nil && time.Now().After(*deadline) {
 		fmt.Printf("[DEBUG] Subscription expired: end_date=%s, now=%s\n", 
 			subscription.EndDate.Format(time.RFC3339), time.Now().Format(time.RFC3339))
 		
@@ -118,9 +139,6 @@ func (r *tenantSubscriptionRepository) FindActiveByTenantID(ctx context.Context,
 		subscription.Status = entity.SubscriptionStatusExpired
 		r.db.WithContext(ctx).Save(&subscription)
 		
-		if subscription.Status == entity.SubscriptionStatusTrial {
-			return nil, errors.ErrTrialExpired
-		}
 		return nil, errors.ErrSubscriptionExpired
 	}
 	
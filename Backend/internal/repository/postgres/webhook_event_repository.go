@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type webhookEventRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookEventRepository(db *gorm.DB) repository.WebhookEventRepository {
+	return &webhookEventRepository{db: db}
+}
+
+func (r *webhookEventRepository) Create(ctx context.Context, event *entity.WebhookEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *webhookEventRepository) Update(ctx context.Context, event *entity.WebhookEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+func (r *webhookEventRepository) FindByID(ctx context.Context, id string) (*entity.WebhookEvent, error) {
+	var event entity.WebhookEvent
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&event).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *webhookEventRepository) List(ctx context.Context, filter repository.WebhookEventFilter) ([]*entity.WebhookEvent, int64, error) {
+	var events []*entity.WebhookEvent
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.WebhookEvent{})
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.TenantID != "" {
+		query = query.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.Search != "" {
+		query = query.Where("order_id ILIKE ? OR transaction_id ILIKE ?", "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook events: %w", err)
+	}
+
+	offset := (filter.Page - 1) * filter.PerPage
+	if err := query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(filter.PerPage).
+		Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find webhook events: %w", err)
+	}
+
+	return events, total, nil
+}
+
+func (r *webhookEventRepository) ClaimIdempotencyKey(ctx context.Context, id, key string) (bool, error) {
+	err := r.db.WithContext(ctx).
+		Model(&entity.WebhookEvent{}).
+		Where("id = ?", id).
+		Update("idempotency_key", key).Error
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) repository.WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *entity.TenantWebhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepository) Update(ctx context.Context, webhook *entity.TenantWebhook) error {
+	// events_scanned_at belongs to the webhook job
+	return r.db.WithContext(ctx).
+		Model(webhook).
+		Select("url", "description", "events", "secret", "is_active", "updated_at").
+		Updates(webhook).Error
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, tenantID, id string) error {
+	result := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id = ?", tenantID, id).
+		Delete(&entity.TenantWebhook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepository) FindByID(ctx context.Context, tenantID, id string) (*entity.TenantWebhook, error) {
+	var webhook entity.TenantWebhook
+	err := r.db.WithContext(ctx).Where("tenant_id = ? AND id = ?", tenantID, id).First(&webhook).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) FindByTenant(ctx context.Context, tenantID string) ([]*entity.TenantWebhook, error) {
+	var webhooks []*entity.TenantWebhook
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at ASC").
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) CountByTenant(ctx context.Context, tenantID string) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.TenantWebhook{}).
+		Where("tenant_id = ?", tenantID).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (r *webhookRepository) FindActive(ctx context.Context) ([]*entity.TenantWebhook, error) {
+	var webhooks []*entity.TenantWebhook
+	err := r.db.WithContext(ctx).
+		Where("is_active = ?", true).
+		Order("tenant_id ASC, created_at ASC").
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) SetEventsScanned(ctx context.Context, id string, until time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.TenantWebhook{}).
+		Where("id = ?", id).
+		Update("events_scanned_at", until).Error
+}
+
+func (r *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "webhook_id"}, {Name: "event_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "redelivery_of IS NULL"}}},
+			DoNothing:   true,
+		}).
+		Create(deliveries).Error
+}
+
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookRepository) FindDelivery(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("tenant_id = ? AND id = ?", tenantID, id).First(&delivery).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) FindDeliveries(ctx context.Context, tenantID, webhookID, status string, limit int) ([]*entity.WebhookDelivery, error) {
+	var deliveries []*entity.WebhookDelivery
+	query := r.db.WithContext(ctx).Where("tenant_id = ? AND webhook_id = ?", tenantID, webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	var deliveries []*entity.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", entity.WebhookDeliveryPending, now).
+		Where("webhook_id IN (?)", r.db.Model(&entity.TenantWebhook{}).Select("id").Where("is_active = ?", true)).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) ClaimDelivery(ctx context.Context, delivery *entity.WebhookDelivery, leaseUntil time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, entity.WebhookDeliveryPending, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+			"updated_at":      time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	delivery.Attempts++
+	delivery.NextAttemptAt = &leaseUntil
+	return true, nil
+}
+
+func (r *webhookRepository) FinishDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	return r.db.WithContext(ctx).
+		Model(delivery).
+		Select("status", "next_attempt_at", "response_status", "response_body", "error", "duration_ms", "delivered_at", "updated_at").
+		Updates(delivery).Error
+}
+
+func (r *webhookRepository) FindNewCustomers(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND created_at >= ? AND created_at < ? AND deleted_at IS NULL", tenantID, since, until).
+		Order("created_at ASC").
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *webhookRepository) FindSuspendedCustomers(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Customer, error) {
+	var customers []*entity.Customer
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status = ? AND deleted_at IS NULL", tenantID, entity.CustomerStatusSuspended).
+		Where("suspended_at >= ? AND suspended_at < ?", since, until).
+		Order("suspended_at ASC").
+		Find(&customers).Error
+	return customers, err
+}
+
+func (r *webhookRepository) FindPaidInvoices(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Preload("Customer").
+		Where("tenant_id = ? AND status = ? AND payment_date >= ? AND payment_date < ?", tenantID, entity.PaymentStatusPaid, since, until).
+		Order("payment_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *webhookRepository) FindActivatedVouchers(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.HotspotVoucher, error) {
+	var vouchers []*entity.HotspotVoucher
+	err := r.db.WithContext(ctx).
+		Preload("Package").
+		Where("tenant_id = ? AND activated_at >= ? AND activated_at < ?", tenantID, since, until).
+		Order("activated_at ASC").
+		Find(&vouchers).Error
+	return vouchers, err
+}
+
+func (r *webhookRepository) FindDownRouters(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Device, error) {
+	var devices []*entity.Device
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND mikrotik_api_enabled = ? AND status <> ?", tenantID, true, entity.DeviceStatusMaintenance).
+		Where("connection_status IN ? AND last_connected_at >= ? AND last_connected_at < ?",
+			[]string{entity.ConnectionStatusError, entity.ConnectionStatusDisconnected}, since, until).
+		Order("last_connected_at ASC").
+		Find(&devices).Error
+	return devices, err
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type whatsAppRepository struct {
+	db *gorm.DB
+}
+
+func NewWhatsAppRepository(db *gorm.DB) repository.WhatsAppRepository {
+	return &whatsAppRepository{db: db}
+}
+
+func (r *whatsAppRepository) FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error) {
+	var customer entity.Customer
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ? AND deleted_at IS NULL", customerID, tenantID).
+		First(&customer).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (r *whatsAppRepository) CountSince(ctx context.Context, tenantID string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.WhatsAppMessage{}).
+		Where("tenant_id = ? AND created_at >= ?", tenantID, since).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *whatsAppRepository) Create(ctx context.Context, message *entity.WhatsAppMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+func (r *whatsAppRepository) Update(ctx context.Context, message *entity.WhatsAppMessage) error {
+	return r.db.WithContext(ctx).Save(message).Error
+}
+
+func (r *whatsAppRepository) FindByProviderMessageID(ctx context.Context, tenantID, provider, providerMessageID string) (*entity.WhatsAppMessage, error) {
+	var message entity.WhatsAppMessage
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND provider = ? AND provider_message_id = ?", tenantID, provider, providerMessageID).
+		Order("created_at DESC").
+		First(&message).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *whatsAppRepository) FindByCustomer(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.WhatsAppMessage, error) {
+	var messages []*entity.WhatsAppMessage
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
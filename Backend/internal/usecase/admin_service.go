@@ -6,9 +6,11 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/rtrwnet/saas-backend/internal/domain/repository"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
 	"github.com/rtrwnet/saas-backend/pkg/payment"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -63,6 +65,8 @@ type AdminService interface {
 	GetPaymentTransaction(ctx context.Context, id string) (*entity.PaymentTransaction, error)
 	GetPaymentStats(ctx context.Context) (*repository.PaymentStats, error)
 	ReconcilePayment(ctx context.Context, orderID string) (*PaymentReconcileResponse, error)
+	RefundPayment(ctx context.Context, orderID string, req *RefundPaymentRequest, adminID string) (*PaymentRefundResponse, error)
+	CancelPayment(ctx context.Context, orderID, reason, adminID string) (*entity.PaymentTransaction, error)
 }
 
 // Response types
@@ -121,6 +125,7 @@ type PaymentTransactionDetail struct {
 	PlanID               *string    `json:"plan_id,omitempty"`
 	OrderID              string     `json:"order_id"`
 	Amount               float64    `json:"amount"`
+	RefundedAmount       float64    `json:"refunded_amount"`
 	Status               string     `json:"status"`
 	PaymentMethod        string     `json:"payment_method"`
 	PaymentGateway       string     `json:"payment_gateway"`
@@ -140,6 +145,20 @@ type PaymentReconcileResponse struct {
 	UpdatedStatus  string `json:"updated_status,omitempty"`
 }
 
+// RefundPaymentRequest refunds a paid transaction. An Amount of 0 refunds
+// everything not yet refunded; Manual records a refund made outside the gateway.
+type RefundPaymentRequest struct {
+	Amount float64 `json:"amount" binding:"gte=0"`
+	Reason string  `json:"reason" binding:"required"`
+	Manual bool    `json:"manual"`
+}
+
+type PaymentRefundResponse struct {
+	Transaction  *entity.PaymentTransaction        `json:"transaction"`
+	Refund       *entity.PaymentTransactionRefund `json:"refund"`
+	Subscription *entity.TenantSubscription       `json:"subscription,omitempty"`
+}
+
 // Request types
 type CreateTenantRequest struct {
 	Name          string `json:"name" binding:"required"`
@@ -223,9 +242,11 @@ type AdminServiceImpl struct {
 	tenantRepo             repository.TenantRepository
 	userRepo               repository.UserRepository
 	paymentTransactionRepo repository.PaymentTransactionRepository
-	midtransClient         *payment.MidtransClient
+	subscriptionRepo       repository.TenantSubscriptionRepository
+	gateways               *payment.Gateways
 	jwtSecret              string
 	notificationService    NotificationService
+	templates              NotificationTemplateService
 }
 
 func NewAdminService(
@@ -237,7 +258,8 @@ func NewAdminService(
 	tenantRepo repository.TenantRepository,
 	userRepo repository.UserRepository,
 	paymentTransactionRepo repository.PaymentTransactionRepository,
-	midtransClient *payment.MidtransClient,
+	subscriptionRepo repository.TenantSubscriptionRepository,
+	gateways *payment.Gateways,
 	jwtSecret string,
 ) AdminService {
 	return &AdminServiceImpl{
@@ -249,7 +271,8 @@ func NewAdminService(
 		tenantRepo:             tenantRepo,
 		userRepo:               userRepo,
 		paymentTransactionRepo: paymentTransactionRepo,
-		midtransClient:         midtransClient,
+		subscriptionRepo:       subscriptionRepo,
+		gateways:               gateways,
 		jwtSecret:              jwtSecret,
 	}
 }
@@ -264,9 +287,11 @@ func NewAdminServiceWithNotification(
 	tenantRepo repository.TenantRepository,
 	userRepo repository.UserRepository,
 	paymentTransactionRepo repository.PaymentTransactionRepository,
-	midtransClient *payment.MidtransClient,
+	subscriptionRepo repository.TenantSubscriptionRepository,
+	gateways *payment.Gateways,
 	jwtSecret string,
 	notificationService NotificationService,
+	templates NotificationTemplateService,
 ) AdminService {
 	return &AdminServiceImpl{
 		adminUserRepo:          adminUserRepo,
@@ -277,9 +302,11 @@ func NewAdminServiceWithNotification(
 		tenantRepo:             tenantRepo,
 		userRepo:               userRepo,
 		paymentTransactionRepo: paymentTransactionRepo,
-		midtransClient:         midtransClient,
+		subscriptionRepo:       subscriptionRepo,
+		gateways:               gateways,
 		jwtSecret:              jwtSecret,
 		notificationService:    notificationService,
+		templates:              templates,
 	}
 }
 
@@ -1001,6 +1028,7 @@ func (s *AdminServiceImpl) ListPaymentTransactions(ctx context.Context, page, pe
 			PlanID:               tx.PlanID,
 			OrderID:              tx.OrderID,
 			Amount:               tx.Amount,
+			RefundedAmount:       tx.RefundedAmount,
 			Status:               tx.Status,
 			PaymentMethod:        tx.PaymentMethod,
 			PaymentGateway:       tx.PaymentGateway,
@@ -1035,19 +1063,20 @@ func (s *AdminServiceImpl) ReconcilePayment(ctx context.Context, orderID string)
 		return nil, errors.NewNotFoundError("Transaction not found")
 	}
 
-	// Check if midtrans client is available
-	if s.midtransClient == nil {
+	// Check if the transaction's gateway is available
+	gateway := s.gateways.Get(tx.PaymentGateway)
+	if gateway == nil {
 		return &PaymentReconcileResponse{
 			OrderID:       orderID,
 			LocalStatus:   tx.Status,
 			GatewayStatus: "unknown",
 			IsMatched:     false,
-			Message:       "Midtrans client not configured",
+			Message:       "Payment gateway " + tx.PaymentGateway + " not configured",
 		}, nil
 	}
 
-	// Get status from Midtrans
-	gatewayResp, err := s.midtransClient.GetTransactionStatus(orderID)
+	// Get status from the gateway
+	gatewayResp, err := gateway.GetStatus(ctx, orderID)
 	if err != nil {
 		return &PaymentReconcileResponse{
 			OrderID:       orderID,
@@ -1058,21 +1087,21 @@ func (s *AdminServiceImpl) ReconcilePayment(ctx context.Context, orderID string)
 		}, nil
 	}
 
-	gatewayStatus := gatewayResp.TransactionStatus
+	gatewayStatus := gatewayResp.GatewayStatus
 	transactionID := gatewayResp.TransactionID
 
-	// Map Midtrans status to local status
+	// Map gateway status to local status
 	var mappedStatus string
-	switch gatewayStatus {
-	case "capture", "settlement":
+	switch gatewayResp.Status {
+	case payment.StatusPaid:
 		mappedStatus = entity.TransactionStatusPaid
-	case "pending":
+	case payment.StatusPending:
 		mappedStatus = entity.TransactionStatusPending
-	case "deny", "cancel":
+	case payment.StatusFailed:
 		mappedStatus = entity.TransactionStatusFailed
-	case "expire":
+	case payment.StatusExpired:
 		mappedStatus = entity.TransactionStatusExpired
-	case "refund", "partial_refund":
+	case payment.StatusRefunded:
 		mappedStatus = entity.TransactionStatusRefunded
 	default:
 		mappedStatus = tx.Status
@@ -1106,3 +1135,183 @@ func (s *AdminServiceImpl) ReconcilePayment(ctx context.Context, orderID string)
 
 	return response, nil
 }
+
+func (s *AdminServiceImpl) RefundPayment(ctx context.Context, orderID string, req *RefundPaymentRequest, adminID string) (*PaymentRefundResponse, error) {
+	tx, err := s.paymentTransactionRepo.FindByOrderID(ctx, orderID)
+	if err != nil || tx == nil {
+		return nil, errors.NewNotFoundError("Transaction not found")
+	}
+	if tx.Status != entity.TransactionStatusPaid {
+		return nil, errors.NewValidationError("Only paid transactions can be refunded")
+	}
+
+	remaining := tx.Amount - tx.RefundedAmount
+	amount := req.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining+0.005 {
+		return nil, errors.NewWithDetails("VAL_2001", "Refund amount exceeds the refundable amount", 400, map[string]interface{}{
+			"refundable_amount": remaining,
+		})
+	}
+
+	refund := &entity.PaymentTransactionRefund{
+		ID:            uuid.New().String(),
+		TransactionID: tx.ID,
+		TenantID:      tx.TenantID,
+		Amount:        amount,
+		Reason:        req.Reason,
+		Method:        entity.RefundMethodManual,
+		Status:        entity.RefundStatusSucceeded,
+		RefundedBy:    adminID,
+	}
+
+	if req.Manual {
+		tx, err = s.paymentTransactionRepo.AddRefund(ctx, refund)
+	} else {
+		tx, err = s.refundAtGateway(ctx, tx, refund)
+	}
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewDatabaseError("record refund", err)
+	}
+
+	result := &PaymentRefundResponse{Transaction: tx, Refund: refund}
+	result.Subscription = s.shortenSubscription(ctx, tx, amount)
+
+	s.notifyTenant(ctx, tx.TenantID, entity.NotificationEventPaymentRefunded,
+		TemplateVars{"order_id": orderID, "amount": amount, "reason": req.Reason},
+		map[string]interface{}{"order_id": orderID, "amount": amount, "refund_id": refund.ID})
+
+	return result, nil
+}
+
+// refundAtGateway reserves the refund so concurrent refunds cannot exceed the
+// transaction's amount, asks the gateway without holding the transaction lock
+// and then records its answer, or marks the refund failed when refused
+func (s *AdminServiceImpl) refundAtGateway(ctx context.Context, tx *entity.PaymentTransaction, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error) {
+	gateway := s.gateways.Get(tx.PaymentGateway)
+	if gateway == nil {
+		return nil, errors.NewValidationError("Payment gateway " + tx.PaymentGateway + " not configured, record a manual refund instead")
+	}
+	refund.Method = entity.RefundMethodGateway
+	if err := s.paymentTransactionRepo.ReserveRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	result, err := gateway.Refund(ctx, &payment.RefundParams{
+		OrderID:   tx.OrderID,
+		RefundKey: refund.ID,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+	})
+	if err != nil {
+		logger.Error("Gateway refund failed for order %s: %v", tx.OrderID, err)
+		if failErr := s.paymentTransactionRepo.FailRefund(ctx, refund); failErr != nil {
+			logger.Error("Failed to mark refund %s failed: %v", refund.ID, failErr)
+		}
+		return nil, errors.NewWithDetails("PAY_5002", "Gateway refused the refund", 502, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	refund.GatewayRefundID = result.RefundID
+	refund.Status = entity.RefundStatusSucceeded
+	if result.Status == payment.StatusPending {
+		refund.Status = entity.RefundStatusPending
+	}
+	updated, err := s.paymentTransactionRepo.CompleteRefund(ctx, refund)
+	if err != nil {
+		// The gateway has already refunded, so the record must be fixed by hand
+		logger.Error("Gateway refund %s for order %s was made but the refund is still processing: %v", refund.GatewayRefundID, tx.OrderID, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// shortenSubscription takes back the part of the period the refund paid for.
+// A paid transaction bought one month from PaidAt, so the cut is that month
+// scaled by the refunded share of the amount.
+func (s *AdminServiceImpl) shortenSubscription(ctx context.Context, tx *entity.PaymentTransaction, amount float64) *entity.TenantSubscription {
+	if tx.SubscriptionID == nil || tx.PaidAt == nil || tx.Amount <= 0 {
+		return nil
+	}
+	subscription, err := s.subscriptionRepo.FindByID(ctx, *tx.SubscriptionID)
+	if err != nil || subscription == nil || subscription.EndDate == nil {
+		return nil
+	}
+
+	period := tx.PaidAt.AddDate(0, 1, 0).Sub(*tx.PaidAt)
+	cut := time.Duration(float64(period) * amount / tx.Amount)
+	endDate := subscription.EndDate.Add(-cut)
+	subscription.EndDate = &endDate
+	subscription.NextBillingDate = &endDate
+	if !endDate.After(time.Now()) {
+		if tx.Status == entity.TransactionStatusRefunded {
+			subscription.Status = entity.SubscriptionStatusCancelled
+		} else {
+			subscription.Status = entity.SubscriptionStatusExpired
+		}
+	}
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		logger.Error("Failed to adjust subscription %s after refund: %v", subscription.ID, err)
+		return nil
+	}
+	return subscription
+}
+
+func (s *AdminServiceImpl) CancelPayment(ctx context.Context, orderID, reason, adminID string) (*entity.PaymentTransaction, error) {
+	tx, err := s.paymentTransactionRepo.FindByOrderID(ctx, orderID)
+	if err != nil || tx == nil {
+		return nil, errors.NewNotFoundError("Transaction not found")
+	}
+	if tx.Status != entity.TransactionStatusPending {
+		return nil, errors.NewValidationError("Only pending transactions can be cancelled")
+	}
+
+	if gateway := s.gateways.Get(tx.PaymentGateway); gateway != nil {
+		charge, err := gateway.Cancel(ctx, orderID)
+		switch {
+		case err == payment.ErrTransactionNotFound:
+			// No payment was started at the gateway
+		case err != nil:
+			return nil, errors.NewWithDetails("PAY_5002", "Failed to cancel payment at gateway", 502, map[string]interface{}{
+				"error": err.Error(),
+			})
+		case charge.Status == payment.StatusPaid:
+			return nil, errors.NewValidationError("Payment was already completed, reconcile it instead")
+		}
+	}
+
+	tx.Status = entity.TransactionStatusCancelled
+	if err := s.paymentTransactionRepo.Update(ctx, tx); err != nil {
+		return nil, errors.NewDatabaseError("cancel transaction", err)
+	}
+	logger.Info("Payment cancelled: order=%s, admin=%s, reason=%s", orderID, adminID, reason)
+
+	s.notifyTenant(ctx, tx.TenantID, entity.NotificationEventPaymentCancelled,
+		TemplateVars{"order_id": orderID, "amount": tx.Amount, "reason": reason},
+		map[string]interface{}{"order_id": orderID})
+
+	return tx, nil
+}
+
+// notifyTenant sends the payment notification of a platform event to every
+// user of a tenant
+func (s *AdminServiceImpl) notifyTenant(ctx context.Context, tenantID, event string, vars TemplateVars, data map[string]interface{}) {
+	if s.notificationService == nil || s.templates == nil {
+		return
+	}
+	notification, err := newPlatformNotification(ctx, s.templates, tenantID, event, entity.NotificationTypePayment, vars, data)
+	if err != nil {
+		logger.Error("Failed to render %s notification for tenant %s: %v", event, tenantID, err)
+		return
+	}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		logger.Error("Failed to notify tenant %s: %v", tenantID, err)
+	}
+}
@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/payment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTenantSubscriptionRepository only implements FindByID, FindForDunning and Update
+type MockTenantSubscriptionRepository struct {
+	repository.TenantSubscriptionRepository
+	mock.Mock
+}
+
+func (m *MockTenantSubscriptionRepository) FindByID(ctx context.Context, id string) (*entity.TenantSubscription, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TenantSubscription), args.Error(1)
+}
+
+func (m *MockTenantSubscriptionRepository) FindActiveByTenantID(ctx context.Context, tenantID string) (*entity.TenantSubscription, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TenantSubscription), args.Error(1)
+}
+
+func (m *MockTenantSubscriptionRepository) FindForDunning(ctx context.Context, billedBefore time.Time) ([]*entity.TenantSubscription, error) {
+	args := m.Called(ctx, billedBefore)
+	return args.Get(0).([]*entity.TenantSubscription), args.Error(1)
+}
+
+func (m *MockTenantSubscriptionRepository) Update(ctx context.Context, subscription *entity.TenantSubscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func TestAdminService_RefundPayment(t *testing.T) {
+	ctx := context.Background()
+	subscriptionID := "sub-1"
+	paidAt := time.Now().AddDate(0, 0, -10)
+	paidTransaction := func() *entity.PaymentTransaction {
+		return &entity.PaymentTransaction{
+			ID: "tx-1", TenantID: "tenant-1", SubscriptionID: &subscriptionID, OrderID: "ORD-1",
+			Amount: 300000, Status: entity.TransactionStatusPaid, PaymentGateway: payment.GatewayMidtrans, PaidAt: &paidAt,
+		}
+	}
+	subscription := func() *entity.TenantSubscription {
+		endDate := paidAt.AddDate(0, 1, 0)
+		return &entity.TenantSubscription{ID: subscriptionID, Status: entity.SubscriptionStatusActive, EndDate: &endDate}
+	}
+
+	t.Run("Partial Refund Shortens Subscription", func(t *testing.T) {
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockGateway := new(MockPaymentGateway)
+		service := NewAdminService(nil, nil, nil, nil, nil, nil, nil, mockTransactionRepo, mockSubscriptionRepo,
+			payment.NewGateways(payment.GatewayMidtrans, mockGateway), "secret")
+
+		refunded := paidTransaction()
+		refunded.RefundedAmount = 150000
+		mockTransactionRepo.On("FindByOrderID", ctx, "ORD-1").Return(paidTransaction(), nil)
+		mockGateway.On("Refund", ctx, mock.MatchedBy(func(p *payment.RefundParams) bool {
+			return p.OrderID == "ORD-1" && p.Amount == 150000 && p.RefundKey != ""
+		})).Return(&payment.Refund{RefundID: "rf-1", Status: payment.StatusRefunded}, nil)
+		mockTransactionRepo.On("ReserveRefund", ctx, mock.Anything).Return(nil)
+		mockTransactionRepo.On("CompleteRefund", ctx, mock.MatchedBy(func(r *entity.PaymentTransactionRefund) bool {
+			return r.GatewayRefundID == "rf-1" && r.Status == entity.RefundStatusSucceeded
+		})).Return(refunded, nil)
+		mockSubscriptionRepo.On("FindByID", ctx, subscriptionID).Return(subscription(), nil)
+		mockSubscriptionRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		resp, err := service.RefundPayment(ctx, "ORD-1", &RefundPaymentRequest{Amount: 150000, Reason: "Double charge"}, "admin-1")
+		require.NoError(t, err)
+		assert.Equal(t, entity.RefundMethodGateway, resp.Refund.Method)
+		assert.Equal(t, "rf-1", resp.Refund.GatewayRefundID)
+		assert.Equal(t, "admin-1", resp.Refund.RefundedBy)
+
+		// Half the amount takes back half of the month from the end date
+		period := paidAt.AddDate(0, 1, 0).Sub(paidAt)
+		assert.WithinDuration(t, paidAt.AddDate(0, 1, 0).Add(-period/2), *resp.Subscription.EndDate, time.Second)
+		assert.Equal(t, entity.SubscriptionStatusActive, resp.Subscription.Status)
+	})
+
+	t.Run("Full Refund Cancels Subscription", func(t *testing.T) {
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockGateway := new(MockPaymentGateway)
+		service := NewAdminService(nil, nil, nil, nil, nil, nil, nil, mockTransactionRepo, mockSubscriptionRepo,
+			payment.NewGateways(payment.GatewayMidtrans, mockGateway), "secret")
+
+		refunded := paidTransaction()
+		refunded.RefundedAmount = 300000
+		refunded.Status = entity.TransactionStatusRefunded
+		mockTransactionRepo.On("FindByOrderID", ctx, "ORD-1").Return(paidTransaction(), nil)
+		mockTransactionRepo.On("AddRefund", ctx, mock.MatchedBy(func(r *entity.PaymentTransactionRefund) bool {
+			return r.Amount == 300000 && r.Method == entity.RefundMethodManual
+		})).Return(refunded, nil)
+		mockSubscriptionRepo.On("FindByID", ctx, subscriptionID).Return(subscription(), nil)
+		mockSubscriptionRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		resp, err := service.RefundPayment(ctx, "ORD-1", &RefundPaymentRequest{Reason: "Paid by transfer", Manual: true}, "admin-1")
+		require.NoError(t, err)
+		assert.Equal(t, entity.SubscriptionStatusCancelled, resp.Subscription.Status)
+		mockGateway.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Refund Above Remaining Amount", func(t *testing.T) {
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockGateway := new(MockPaymentGateway)
+		service := NewAdminService(nil, nil, nil, nil, nil, nil, nil, mockTransactionRepo, mockSubscriptionRepo,
+			payment.NewGateways(payment.GatewayMidtrans, mockGateway), "secret")
+
+		tx := paidTransaction()
+		tx.RefundedAmount = 250000
+		mockTransactionRepo.On("FindByOrderID", ctx, "ORD-1").Return(tx, nil)
+
+		_, err := service.RefundPayment(ctx, "ORD-1", &RefundPaymentRequest{Amount: 100000, Reason: "x"}, "admin-1")
+		require.Error(t, err)
+		assert.Equal(t, 400, err.(*errors.AppError).Status)
+		mockGateway.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Gateway Failure Marks Refund Failed", func(t *testing.T) {
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockGateway := new(MockPaymentGateway)
+		service := NewAdminService(nil, nil, nil, nil, nil, nil, nil, mockTransactionRepo, mockSubscriptionRepo,
+			payment.NewGateways(payment.GatewayMidtrans, mockGateway), "secret")
+
+		mockTransactionRepo.On("FindByOrderID", ctx, "ORD-1").Return(paidTransaction(), nil)
+		mockTransactionRepo.On("ReserveRefund", ctx, mock.Anything).Return(nil)
+		mockTransactionRepo.On("FailRefund", ctx, mock.Anything).Return(nil)
+		mockGateway.On("Refund", ctx, mock.Anything).Return(nil, assert.AnError)
+
+		_, err := service.RefundPayment(ctx, "ORD-1", &RefundPaymentRequest{Reason: "x"}, "admin-1")
+		require.Error(t, err)
+		assert.Equal(t, 502, err.(*errors.AppError).Status)
+		mockTransactionRepo.AssertCalled(t, "FailRefund", ctx, mock.Anything)
+		mockTransactionRepo.AssertNotCalled(t, "CompleteRefund", mock.Anything, mock.Anything)
+		mockSubscriptionRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminService_CancelPayment(t *testing.T) {
+	ctx := context.Background()
+	pending := func() *entity.PaymentTransaction {
+		return &entity.PaymentTransaction{ID: "tx-1", OrderID: "ORD-1", Status: entity.TransactionStatusPending, PaymentGateway: payment.GatewayMidtrans}
+	}
+
+	t.Run("Cancels At Gateway", func(t *testing.T) {
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockGateway := new(MockPaymentGateway)
+		service := NewAdminService(nil, nil, nil, nil, nil, nil, nil, mockTransactionRepo, mockSubscriptionRepo,
+			payment.NewGateways(payment.GatewayMidtrans, mockGateway), "secret")
+
+		mockTransactionRepo.On("FindByOrderID", ctx, "ORD-1").Return(pending(), nil)
+		mockGateway.On("Cancel", ctx, "ORD-1").Return(&payment.Charge{Status: payment.StatusFailed}, nil)
+		mockTransactionRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		tx, err := service.CancelPayment(ctx, "ORD-1", "Customer request", "admin-1")
+		require.NoError(t, err)
+		assert.Equal(t, entity.TransactionStatusCancelled, tx.Status)
+	})
+
+	t.Run("Paid At Gateway Is Not Cancelled", func(t *testing.T) {
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockGateway := new(MockPaymentGateway)
+		service := NewAdminService(nil, nil, nil, nil, nil, nil, nil, mockTransactionRepo, mockSubscriptionRepo,
+			payment.NewGateways(payment.GatewayMidtrans, mockGateway), "secret")
+
+		mockTransactionRepo.On("FindByOrderID", ctx, "ORD-1").Return(pending(), nil)
+		mockGateway.On("Cancel", ctx, "ORD-1").Return(&payment.Charge{Status: payment.StatusPaid}, nil)
+
+		_, err := service.CancelPayment(ctx, "ORD-1", "Customer request", "admin-1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reconcile")
+		mockTransactionRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
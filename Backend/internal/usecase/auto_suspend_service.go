@@ -0,0 +1,304 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// AutoSuspendService isolates customers with overdue invoices and brings them
+// back once they pay, following the tenant's AutoSuspend* settings
+type AutoSuspendService interface {
+	// EnforceTenant marks invoices overdue after the grace period, suspends
+	// customers past the auto-suspend threshold and reactivates auto-suspended
+	// customers that have settled
+	EnforceTenant(ctx context.Context, tenantID string, asOf time.Time) (*AutoSuspendResult, error)
+	// EnforceAllTenants runs EnforceTenant for every active tenant
+	EnforceAllTenants(ctx context.Context, asOf time.Time) error
+	// HandlePaymentPaid extends the service of prepaid customers and
+	// reactivates the customer of a paid invoice when the billing job
+	// suspended them and nothing else is past the threshold
+	HandlePaymentPaid(ctx context.Context, payment *entity.Payment) error
+	ListActions(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.BillingActionLog, error)
+}
+
+// CustomerStatusChanger is the customer lifecycle used by the billing jobs,
+// implemented by DashboardService
+type CustomerStatusChanger interface {
+	ActivateCustomer(ctx context.Context, tenantID, customerID string) error
+	SuspendCustomer(ctx context.Context, tenantID, customerID, reason string) error
+}
+
+// AutoSuspendResult summarizes one EnforceTenant run
+type AutoSuspendResult struct {
+	TenantID      string `json:"tenant_id"`
+	MarkedOverdue int64  `json:"marked_overdue"`
+	Suspended     int    `json:"suspended"`
+	Reactivated   int    `json:"reactivated"`
+	Exempt        int    `json:"exempt"`
+	Failed        int    `json:"failed"`
+}
+
+type autoSuspendService struct {
+	autoSuspendRepo repository.AutoSuspendRepository
+	settingsRepo    repository.SettingsRepository
+	tenantRepo      repository.TenantRepository
+	customerRepo    repository.CustomerRepository
+	customers       CustomerStatusChanger
+	prepaid         PaymentReactivator
+}
+
+func NewAutoSuspendService(
+	autoSuspendRepo repository.AutoSuspendRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+	customerRepo repository.CustomerRepository,
+	customers CustomerStatusChanger,
+	prepaid PaymentReactivator,
+) AutoSuspendService {
+	return &autoSuspendService{
+		autoSuspendRepo: autoSuspendRepo,
+		settingsRepo:    settingsRepo,
+		tenantRepo:      tenantRepo,
+		customerRepo:    customerRepo,
+		customers:       customers,
+		prepaid:         prepaid,
+	}
+}
+
+// overdueThreshold returns the due date before which unpaid invoices are
+// overdue: GracePeriodDays after the due date
+func overdueThreshold(settings *entity.TenantSettings, today time.Time) time.Time {
+	return today.AddDate(0, 0, -settings.GracePeriodDays)
+}
+
+// suspendAfterDays returns the days after the due date an unpaid invoice gets
+// the customer suspended: AutoSuspendDays, never inside the grace period
+func suspendAfterDays(settings *entity.TenantSettings) int {
+	days := settings.AutoSuspendDays
+	if days < settings.GracePeriodDays {
+		days = settings.GracePeriodDays
+	}
+	return days
+}
+
+// suspendThreshold returns the due date before which unpaid invoices get the
+// customer suspended
+func suspendThreshold(settings *entity.TenantSettings, today time.Time) time.Time {
+	return today.AddDate(0, 0, -suspendAfterDays(settings))
+}
+
+func (s *autoSuspendService) loadSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		return defaultTenantSettings(tenantID), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	return settings, nil
+}
+
+func (s *autoSuspendService) EnforceTenant(ctx context.Context, tenantID string, asOf time.Time) (*AutoSuspendResult, error) {
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := dateOf(asOf)
+	result := &AutoSuspendResult{TenantID: tenantID}
+
+	result.MarkedOverdue, err = s.autoSuspendRepo.MarkOverdue(ctx, tenantID, overdueThreshold(settings, today))
+	if err != nil {
+		return nil, errors.NewDatabaseError("mark overdue invoices", err)
+	}
+
+	threshold := suspendThreshold(settings, today)
+
+	if settings.AutoSuspendEnabled {
+		candidates, err := s.autoSuspendRepo.FindSuspendCandidates(ctx, tenantID, threshold)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find overdue customers", err)
+		}
+
+		seen := make(map[string]bool)
+		for _, payment := range candidates {
+			// Candidates are ordered by due date, so the first one is the oldest
+			if seen[payment.CustomerID] || payment.Customer == nil {
+				continue
+			}
+			seen[payment.CustomerID] = true
+
+			if payment.Customer.IsAutoSuspendExempt(asOf) {
+				result.Exempt++
+				continue
+			}
+			if err := s.suspend(ctx, payment, today); err != nil {
+				logger.Error("Auto-suspend: failed to suspend customer %s: %v", payment.CustomerID, err)
+				result.Failed++
+				continue
+			}
+			result.Suspended++
+		}
+	}
+
+	// Customers who paid through a path that did not call HandlePaymentPaid
+	if settings.AutoReactivateOnPayment {
+		suspended, err := s.autoSuspendRepo.FindAutoSuspendedCustomers(ctx, tenantID)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find auto-suspended customers", err)
+		}
+		for _, customer := range suspended {
+			reactivated, err := s.reactivateIfSettled(ctx, customer, threshold, nil)
+			if err != nil {
+				logger.Error("Auto-suspend: failed to reactivate customer %s: %v", customer.ID, err)
+				result.Failed++
+				continue
+			}
+			if reactivated {
+				result.Reactivated++
+			}
+		}
+	}
+
+	logger.Info("Auto-suspend for tenant %s: overdue=%d suspended=%d reactivated=%d exempt=%d failed=%d",
+		tenantID, result.MarkedOverdue, result.Suspended, result.Reactivated, result.Exempt, result.Failed)
+	return result, nil
+}
+
+// suspend isolates the customer through the regular SuspendCustomer path and
+// marks the suspension as automatic
+func (s *autoSuspendService) suspend(ctx context.Context, payment *entity.Payment, today time.Time) error {
+	daysOverdue := int(today.Sub(dateOf(payment.DueDate)).Hours() / 24)
+	reason := fmt.Sprintf("Auto-suspended: invoice due %s is %d days overdue",
+		payment.DueDate.Format("02/01/2006"), daysOverdue)
+
+	if err := s.customers.SuspendCustomer(ctx, payment.TenantID, payment.CustomerID, reason); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.autoSuspendRepo.SetAutoSuspendedAt(ctx, payment.CustomerID, &now); err != nil {
+		return err
+	}
+
+	paymentID := payment.ID
+	s.logAction(ctx, &entity.BillingActionLog{
+		TenantID:   payment.TenantID,
+		CustomerID: payment.CustomerID,
+		PaymentID:  &paymentID,
+		Action:     entity.BillingActionAutoSuspend,
+		Reason:     reason,
+	})
+	return nil
+}
+
+// reactivateIfSettled activates an auto-suspended customer when none of their
+// invoices is past the suspend threshold any more
+func (s *autoSuspendService) reactivateIfSettled(ctx context.Context, customer *entity.Customer, threshold time.Time, paymentID *string) (bool, error) {
+	if customer.Status != entity.CustomerStatusSuspended || customer.AutoSuspendedAt == nil {
+		return false, nil
+	}
+
+	unpaid, err := s.autoSuspendRepo.CountUnpaid(ctx, customer.ID, threshold)
+	if err != nil {
+		return false, err
+	}
+	if unpaid > 0 {
+		return false, nil
+	}
+
+	if err := s.customers.ActivateCustomer(ctx, customer.TenantID, customer.ID); err != nil {
+		return false, err
+	}
+
+	s.logAction(ctx, &entity.BillingActionLog{
+		TenantID:   customer.TenantID,
+		CustomerID: customer.ID,
+		PaymentID:  paymentID,
+		Action:     entity.BillingActionAutoReactivate,
+		Reason:     "Overdue invoices settled",
+	})
+	return true, nil
+}
+
+func (s *autoSuspendService) logAction(ctx context.Context, action *entity.BillingActionLog) {
+	if err := s.autoSuspendRepo.CreateAction(ctx, action); err != nil {
+		logger.Error("Failed to log billing action %s for customer %s: %v", action.Action, action.CustomerID, err)
+	}
+	logger.Info("Billing action %s for customer %s: %s", action.Action, action.CustomerID, action.Reason)
+}
+
+func (s *autoSuspendService) HandlePaymentPaid(ctx context.Context, payment *entity.Payment) error {
+	if s.prepaid != nil {
+		if err := s.prepaid.HandlePaymentPaid(ctx, payment); err != nil {
+			logger.Error("Prepaid service extension after payment %s failed: %v", payment.ID, err)
+		}
+	}
+
+	settings, err := s.loadSettings(ctx, payment.TenantID)
+	if err != nil {
+		return err
+	}
+	if !settings.AutoReactivateOnPayment {
+		return nil
+	}
+
+	customer, err := s.customerRepo.FindByID(ctx, payment.CustomerID)
+	if err != nil || customer == nil {
+		return errors.NewNotFoundError("customer not found")
+	}
+
+	paymentID := payment.ID
+	_, err = s.reactivateIfSettled(ctx, customer, suspendThreshold(settings, dateOf(time.Now())), &paymentID)
+	return err
+}
+
+func (s *autoSuspendService) EnforceAllTenants(ctx context.Context, asOf time.Time) error {
+	tenants, err := s.tenantRepo.FindAll(ctx)
+	if err != nil {
+		return errors.NewDatabaseError("load tenants", err)
+	}
+
+	for _, tenant := range tenants {
+		if !tenant.IsActive {
+			continue
+		}
+		if _, err := s.EnforceTenant(ctx, tenant.ID, asOf); err != nil {
+			logger.Error("Auto-suspend for tenant %s failed: %v", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *autoSuspendService) ListActions(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.BillingActionLog, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	actions, err := s.autoSuspendRepo.ListActions(ctx, tenantID, customerID, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list billing actions", err)
+	}
+	return actions, nil
+}
+
+// StartAutoSuspendJob runs EnforceAllTenants now and then on every interval
+func StartAutoSuspendJob(service AutoSuspendService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.EnforceAllTenants(context.Background(), time.Now()); err != nil {
+				logger.Error("Auto-suspend job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Auto-suspend job started (interval: %s)", interval)
+}
@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAutoSuspendRepository struct {
+	mock.Mock
+}
+
+func (m *MockAutoSuspendRepository) MarkOverdue(ctx context.Context, tenantID string, dueBefore time.Time) (int64, error) {
+	args := m.Called(ctx, tenantID, dueBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAutoSuspendRepository) FindSuspendCandidates(ctx context.Context, tenantID string, dueBefore time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, dueBefore)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockAutoSuspendRepository) FindAutoSuspendedCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]*entity.Customer), args.Error(1)
+}
+
+func (m *MockAutoSuspendRepository) CountUnpaid(ctx context.Context, customerID string, dueBefore time.Time) (int64, error) {
+	args := m.Called(ctx, customerID, dueBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAutoSuspendRepository) SetAutoSuspendedAt(ctx context.Context, customerID string, at *time.Time) error {
+	args := m.Called(ctx, customerID, at)
+	return args.Error(0)
+}
+
+func (m *MockAutoSuspendRepository) CreateAction(ctx context.Context, action *entity.BillingActionLog) error {
+	args := m.Called(ctx, action)
+	return args.Error(0)
+}
+
+func (m *MockAutoSuspendRepository) ListActions(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.BillingActionLog, error) {
+	args := m.Called(ctx, tenantID, customerID, limit)
+	return args.Get(0).([]*entity.BillingActionLog), args.Error(1)
+}
+
+type MockCustomerStatusChanger struct {
+	mock.Mock
+}
+
+func (m *MockCustomerStatusChanger) ActivateCustomer(ctx context.Context, tenantID, customerID string) error {
+	args := m.Called(ctx, tenantID, customerID)
+	return args.Error(0)
+}
+
+func (m *MockCustomerStatusChanger) SuspendCustomer(ctx context.Context, tenantID, customerID, reason string) error {
+	args := m.Called(ctx, tenantID, customerID, reason)
+	return args.Error(0)
+}
+
+func TestSuspendThreshold(t *testing.T) {
+	today := billingDate(2025, 3, 20)
+
+	settings := &entity.TenantSettings{GracePeriodDays: 7, AutoSuspendDays: 14}
+	assert.Equal(t, billingDate(2025, 3, 13), overdueThreshold(settings, today))
+	assert.Equal(t, billingDate(2025, 3, 6), suspendThreshold(settings, today))
+
+	// Never suspend inside the grace period
+	settings = &entity.TenantSettings{GracePeriodDays: 10, AutoSuspendDays: 3}
+	assert.Equal(t, billingDate(2025, 3, 10), suspendThreshold(settings, today))
+}
+
+func TestAutoSuspendService_EnforceTenant(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	asOf := time.Date(2025, 3, 20, 1, 0, 0, 0, time.UTC)
+	settings := &entity.TenantSettings{
+		GracePeriodDays:         7,
+		AutoSuspendEnabled:      true,
+		AutoSuspendDays:         14,
+		AutoReactivateOnPayment: true,
+	}
+
+	until := asOf.AddDate(0, 0, 5)
+	budi := &entity.Customer{ID: "c1", TenantID: tenantID, Status: entity.CustomerStatusActive}
+	siti := &entity.Customer{ID: "c2", TenantID: tenantID, Status: entity.CustomerStatusActive, AutoSuspendExemptUntil: &until}
+	candidates := []*entity.Payment{
+		{ID: "p1", TenantID: tenantID, CustomerID: "c1", DueDate: billingDate(2025, 2, 1), Customer: budi},
+		{ID: "p2", TenantID: tenantID, CustomerID: "c1", DueDate: billingDate(2025, 3, 1), Customer: budi},
+		{ID: "p3", TenantID: tenantID, CustomerID: "c2", DueDate: billingDate(2025, 2, 15), Customer: siti},
+	}
+
+	suspendedAt := asOf.AddDate(0, 0, -10)
+	paidUp := &entity.Customer{ID: "c3", TenantID: tenantID, Status: entity.CustomerStatusSuspended, AutoSuspendedAt: &suspendedAt}
+
+	repo := new(MockAutoSuspendRepository)
+	settingsRepo := new(MockSettingsRepository)
+	customers := new(MockCustomerStatusChanger)
+	service := NewAutoSuspendService(repo, settingsRepo, nil, nil, customers, nil)
+
+	threshold := billingDate(2025, 3, 6)
+	settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+	repo.On("MarkOverdue", ctx, tenantID, billingDate(2025, 3, 13)).Return(int64(4), nil)
+	repo.On("FindSuspendCandidates", ctx, tenantID, threshold).Return(candidates, nil)
+	customers.On("SuspendCustomer", ctx, tenantID, "c1", "Auto-suspended: invoice due 01/02/2025 is 47 days overdue").Return(nil)
+	repo.On("SetAutoSuspendedAt", ctx, "c1", mock.Anything).Return(nil)
+	repo.On("FindAutoSuspendedCustomers", ctx, tenantID).Return([]*entity.Customer{paidUp}, nil)
+	repo.On("CountUnpaid", ctx, "c3", threshold).Return(int64(0), nil)
+	customers.On("ActivateCustomer", ctx, tenantID, "c3").Return(nil)
+	repo.On("CreateAction", ctx, mock.AnythingOfType("*entity.BillingActionLog")).Return(nil)
+
+	result, err := service.EnforceTenant(ctx, tenantID, asOf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), result.MarkedOverdue)
+	assert.Equal(t, 1, result.Suspended)
+	assert.Equal(t, 1, result.Exempt)
+	assert.Equal(t, 1, result.Reactivated)
+	customers.AssertNumberOfCalls(t, "SuspendCustomer", 1)
+
+	suspendLog := repo.Calls[3].Arguments.Get(1).(*entity.BillingActionLog)
+	assert.Equal(t, entity.BillingActionAutoSuspend, suspendLog.Action)
+	assert.Equal(t, "p1", *suspendLog.PaymentID)
+	repo.AssertExpectations(t)
+}
+
+func TestAutoSuspendService_HandlePaymentPaid(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	settings := &entity.TenantSettings{GracePeriodDays: 7, AutoSuspendDays: 14, AutoReactivateOnPayment: true}
+	payment := &entity.Payment{ID: "p1", TenantID: tenantID, CustomerID: "c1"}
+	suspendedAt := time.Now().AddDate(0, 0, -3)
+
+	t.Run("Reactivates Auto Suspended Customer", func(t *testing.T) {
+		repo := new(MockAutoSuspendRepository)
+		settingsRepo := new(MockSettingsRepository)
+		customerRepo := new(MockCustomerRepository)
+		customers := new(MockCustomerStatusChanger)
+		service := NewAutoSuspendService(repo, settingsRepo, nil, customerRepo, customers, nil)
+
+		customer := &entity.Customer{ID: "c1", TenantID: tenantID, Status: entity.CustomerStatusSuspended, AutoSuspendedAt: &suspendedAt}
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		customerRepo.On("FindByID", ctx, "c1").Return(customer, nil)
+		repo.On("CountUnpaid", ctx, "c1", mock.Anything).Return(int64(0), nil)
+		customers.On("ActivateCustomer", ctx, tenantID, "c1").Return(nil)
+		repo.On("CreateAction", ctx, mock.MatchedBy(func(a *entity.BillingActionLog) bool {
+			return a.Action == entity.BillingActionAutoReactivate && *a.PaymentID == "p1"
+		})).Return(nil)
+
+		assert.NoError(t, service.HandlePaymentPaid(ctx, payment))
+		customers.AssertExpectations(t)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Still Overdue", func(t *testing.T) {
+		repo := new(MockAutoSuspendRepository)
+		settingsRepo := new(MockSettingsRepository)
+		customerRepo := new(MockCustomerRepository)
+		customers := new(MockCustomerStatusChanger)
+		service := NewAutoSuspendService(repo, settingsRepo, nil, customerRepo, customers, nil)
+
+		customer := &entity.Customer{ID: "c1", TenantID: tenantID, Status: entity.CustomerStatusSuspended, AutoSuspendedAt: &suspendedAt}
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		customerRepo.On("FindByID", ctx, "c1").Return(customer, nil)
+		repo.On("CountUnpaid", ctx, "c1", mock.Anything).Return(int64(1), nil)
+
+		assert.NoError(t, service.HandlePaymentPaid(ctx, payment))
+		customers.AssertNotCalled(t, "ActivateCustomer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Manual Suspension Is Kept", func(t *testing.T) {
+		repo := new(MockAutoSuspendRepository)
+		settingsRepo := new(MockSettingsRepository)
+		customerRepo := new(MockCustomerRepository)
+		customers := new(MockCustomerStatusChanger)
+		service := NewAutoSuspendService(repo, settingsRepo, nil, customerRepo, customers, nil)
+
+		customer := &entity.Customer{ID: "c1", TenantID: tenantID, Status: entity.CustomerStatusSuspended}
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		customerRepo.On("FindByID", ctx, "c1").Return(customer, nil)
+
+		assert.NoError(t, service.HandlePaymentPaid(ctx, payment))
+		repo.AssertNotCalled(t, "CountUnpaid", mock.Anything, mock.Anything, mock.Anything)
+		customers.AssertNotCalled(t, "ActivateCustomer", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
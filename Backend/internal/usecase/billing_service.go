@@ -168,8 +168,9 @@ func (s *billingService) UpdateSubscription(ctx context.Context, tenantID string
 		return nil, errors.New("INVALID_PLAN", "Invalid plan selected", 400)
 	}
 
-	// If same plan, just update settings
-	if currentPlan.ID == newPlan.ID {
+	// If same plan, just update settings. A read-only tenant orders its
+	// plan again to leave read-only mode.
+	if currentPlan.ID == newPlan.ID && subscription.Status != entity.SubscriptionStatusReadOnly {
 		if req.PaymentMethod != "" {
 			subscription.PaymentMethod = req.PaymentMethod
 		}
@@ -191,7 +192,7 @@ func (s *billingService) UpdateSubscription(ctx context.Context, tenantID string
 	isDowngrade := newPlan.Price < currentPlan.Price
 
 	// If upgrading from trial, create order for payment
-	if subscription.Status == entity.SubscriptionStatusTrial {
+	if subscription.Status == entity.SubscriptionStatusTrial || subscription.Status == entity.SubscriptionStatusReadOnly {
 		// Create order for payment
 		orderID := generateBillingOrderID()
 		expiredAt := time.Now().Add(24 * time.Hour)
@@ -617,21 +618,15 @@ func (s *billingService) buildInvoices(transactions []*entity.PaymentTransaction
 			status = "pending"
 		}
 
-		// Use order_id as invoice number
-		invoiceNo := tx.OrderID
-		if invoiceNo == "" {
-			invoiceNo = "INV-" + tx.ID[:8]
-		}
-
 		invoice := dto.InvoiceInfo{
 			ID:          tx.ID,
-			InvoiceNo:   invoiceNo,
+			InvoiceNo:   transactionInvoiceNumber(tx),
 			Amount:      tx.Amount,
 			IssuedDate:  &tx.CreatedAt,
 			DueDate:     tx.ExpiredAt,
 			PaidDate:    tx.PaidAt,
 			Status:      status,
-			DownloadURL: "", // Will be implemented later
+			DownloadURL: "/api/v1/billing/invoices/" + tx.ID + "/pdf",
 		}
 
 		invoices = append(invoices, invoice)
@@ -29,6 +29,7 @@ type DashboardService interface {
 	// Payment Management
 	ListPayments(ctx context.Context, tenantID string, params dto.PaymentQueryParams) (*dto.PaymentListResponse, error)
 	RecordPayment(ctx context.Context, tenantID string, req *dto.RecordPaymentRequest) error
+	MarkPaymentPaid(ctx context.Context, tenantID, paymentID string, req *dto.MarkPaymentPaidRequest) (*entity.Payment, error)
 	
 	// Service Plan Management
 	ListServicePlans(ctx context.Context, tenantID string) (*dto.ServicePlanListResponse, error)
@@ -46,14 +47,17 @@ type DashboardService interface {
 }
 
 type dashboardService struct {
-	db                 *gorm.DB
-	customerRepo       repository.CustomerRepository
-	paymentRepo        repository.PaymentRepository
-	servicePlanRepo    repository.ServicePlanRepository
-	tenantRepo         repository.TenantRepository
-	userRepo           repository.UserRepository
-	subscriptionRepo   repository.TenantSubscriptionRepository
-	subPlanRepo        repository.SubscriptionPlanRepository
+	db                *gorm.DB
+	customerRepo      repository.CustomerRepository
+	paymentRepo       repository.PaymentRepository
+	servicePlanRepo   repository.ServicePlanRepository
+	tenantRepo        repository.TenantRepository
+	userRepo          repository.UserRepository
+	subscriptionRepo  repository.TenantSubscriptionRepository
+	subPlanRepo       repository.SubscriptionPlanRepository
+	coaService        RadiusCoAService
+	invoiceService    InvoiceService
+	planChangeService PlanChangeService
 }
 
 func NewDashboardService(
@@ -65,16 +69,22 @@ func NewDashboardService(
 	userRepo repository.UserRepository,
 	subscriptionRepo repository.TenantSubscriptionRepository,
 	subPlanRepo repository.SubscriptionPlanRepository,
+	coaService RadiusCoAService,
+	invoiceService InvoiceService,
+	planChangeService PlanChangeService,
 ) DashboardService {
 	return &dashboardService{
-		db:               db,
-		customerRepo:     customerRepo,
-		paymentRepo:      paymentRepo,
-		servicePlanRepo:  servicePlanRepo,
-		tenantRepo:       tenantRepo,
-		userRepo:         userRepo,
-		subscriptionRepo: subscriptionRepo,
-		subPlanRepo:      subPlanRepo,
+		db:                db,
+		customerRepo:      customerRepo,
+		paymentRepo:       paymentRepo,
+		servicePlanRepo:   servicePlanRepo,
+		tenantRepo:        tenantRepo,
+		userRepo:          userRepo,
+		subscriptionRepo:  subscriptionRepo,
+		subPlanRepo:       subPlanRepo,
+		coaService:        coaService,
+		invoiceService:    invoiceService,
+		planChangeService: planChangeService,
 	}
 }
 
@@ -86,6 +96,8 @@ func (s *dashboardService) GetOverview(ctx context.Context, tenantID string) (*d
 	newCustomersMonth, _ := s.customerRepo.CountNewCustomersThisMonth(ctx, tenantID)
 	
 	pendingPayments, _ := s.paymentRepo.CountByStatus(ctx, tenantID, entity.PaymentStatusPending)
+	partiallyPaidPayments, _ := s.paymentRepo.CountByStatus(ctx, tenantID, entity.PaymentStatusPartiallyPaid)
+	pendingPayments += partiallyPaidPayments
 	overduePayments, _ := s.paymentRepo.CountByStatus(ctx, tenantID, entity.PaymentStatusOverdue)
 	
 	// Get revenue info
@@ -93,9 +105,11 @@ func (s *dashboardService) GetOverview(ctx context.Context, tenantID string) (*d
 	thisMonth, _ := s.paymentRepo.SumByMonth(ctx, tenantID, int(now.Month()), now.Year())
 	lastMonth, _ := s.paymentRepo.SumByMonth(ctx, tenantID, int(now.AddDate(0, -1, 0).Month()), now.AddDate(0, -1, 0).Year())
 	
-	collected, _ := s.paymentRepo.SumByStatus(ctx, tenantID, entity.PaymentStatusPaid)
-	pending, _ := s.paymentRepo.SumByStatus(ctx, tenantID, entity.PaymentStatusPending)
-	overdue, _ := s.paymentRepo.SumByStatus(ctx, tenantID, entity.PaymentStatusOverdue)
+	// Partly paid invoices count their payments as collected and the rest as
+	// pending or overdue
+	collected, _ := s.paymentRepo.SumPaidAmount(ctx, tenantID)
+	pending, _ := s.paymentRepo.SumBalanceByStatus(ctx, tenantID, entity.PaymentStatusPending, entity.PaymentStatusPartiallyPaid)
+	overdue, _ := s.paymentRepo.SumBalanceByStatus(ctx, tenantID, entity.PaymentStatusOverdue)
 	
 	growth := float64(0)
 	if lastMonth > 0 {
@@ -297,16 +311,22 @@ func (s *dashboardService) GetCustomerDetail(ctx context.Context, tenantID, cust
 		switch p.Status {
 		case entity.PaymentStatusPaid:
 			paidPayments++
-			totalPaid += p.Amount
-		case entity.PaymentStatusPending:
+			totalPaid += p.Total()
+		case entity.PaymentStatusPending, entity.PaymentStatusPartiallyPaid:
 			pendingPayments++
-			totalPending += p.Amount
+			totalPending += p.Balance()
 		case entity.PaymentStatusOverdue:
 			overduePayments++
-			totalPending += p.Amount
+			totalPending += p.Balance()
 		}
 	}
 	
+	statement, err := s.invoiceService.GetCustomerStatement(ctx, tenantID, customerID, 20)
+	if err != nil {
+		logger.Error("Failed to load statement for customer %s: %v", customerID, err)
+		statement = &CustomerStatement{}
+	}
+	
 	onTimeRate := float64(0)
 	if totalPayments > 0 {
 		onTimeRate = (float64(paidPayments) / float64(totalPayments)) * 100
@@ -336,7 +356,13 @@ func (s *dashboardService) GetCustomerDetail(ctx context.Context, tenantID, cust
 		DueDate:          customer.DueDate,
 		MonthlyFee:       customer.MonthlyFee,
 		Notes:            customer.Notes,
+		AutoSuspendExempt:      customer.AutoSuspendExempt,
+		AutoSuspendExemptUntil: customer.AutoSuspendExemptUntil,
+		AutoSuspendedAt:        customer.AutoSuspendedAt,
+		ServiceUntil:     customer.ServiceUntil,
 		PaymentHistory:   s.buildPaymentHistory(payments),
+		CreditBalance:    customer.CreditBalance,
+		Statement:        buildStatement(statement.Entries),
 		Statistics: dto.CustomerStatistics{
 			TotalPayments:   totalPayments,
 			PaidPayments:    paidPayments,
@@ -351,6 +377,23 @@ func (s *dashboardService) GetCustomerDetail(ctx context.Context, tenantID, cust
 	}, nil
 }
 
+func buildStatement(entries []*entity.CustomerLedgerEntry) []dto.LedgerEntry {
+	statement := make([]dto.LedgerEntry, 0, len(entries))
+	for _, e := range entries {
+		statement = append(statement, dto.LedgerEntry{
+			ID:           e.ID,
+			Type:         e.Type,
+			Source:       e.Source,
+			Amount:       e.Amount,
+			BalanceAfter: e.BalanceAfter,
+			PaymentID:    e.PaymentID,
+			Reason:       e.Reason,
+			CreatedAt:    e.CreatedAt,
+		})
+	}
+	return statement
+}
+
 func (s *dashboardService) CreateCustomer(ctx context.Context, tenantID string, req *dto.CreateCustomerRequest) (*dto.CustomerDetailResponse, error) {
 	// Generate customer code
 	customerCode, err := s.customerRepo.GenerateCustomerCode(ctx, tenantID)
@@ -449,8 +492,18 @@ func (s *dashboardService) UpdateCustomer(ctx context.Context, tenantID, custome
 	customer.Latitude = req.Latitude
 	customer.Longitude = req.Longitude
 	if req.ServicePlanID != "" && req.ServicePlanID != oldServicePlanID {
-		customer.ServicePlanID = req.ServicePlanID
-		needsRadiusSync = true
+		// Plan changes are prorated and pushed to RADIUS by PlanChangeService
+		change, err := s.planChangeService.ChangePlan(ctx, tenantID, customerID, &PlanChangeRequest{
+			ServicePlanID: req.ServicePlanID,
+			Effective:     entity.PlanChangeImmediate,
+			MonthlyFee:    req.MonthlyFee,
+		})
+		if err != nil {
+			return err
+		}
+		customer.ServicePlanID = change.ToPlanID
+		customer.ServicePlan = change.ToPlan
+		customer.MonthlyFee = change.ToMonthlyFee
 	}
 	if req.ServiceType != "" {
 		customer.ServiceType = req.ServiceType
@@ -473,15 +526,38 @@ func (s *dashboardService) UpdateCustomer(ctx context.Context, tenantID, custome
 		customer.Status = req.Status
 	}
 	customer.Notes = req.Notes
+	if req.AutoSuspendExempt != nil {
+		customer.AutoSuspendExempt = *req.AutoSuspendExempt
+	}
+	if req.AutoSuspendExemptUntil != nil {
+		if *req.AutoSuspendExemptUntil == "" {
+			customer.AutoSuspendExemptUntil = nil
+		} else {
+			until, err := time.ParseInLocation("2006-01-02", *req.AutoSuspendExemptUntil, time.Local)
+			if err != nil {
+				return errors.NewValidationError("auto_suspend_exempt_until must be in YYYY-MM-DD format")
+			}
+			// Exempt through the end of that day
+			until = until.AddDate(0, 0, 1)
+			customer.AutoSuspendExemptUntil = &until
+		}
+	}
 	
 	if err := s.customerRepo.Update(ctx, customer); err != nil {
 		logger.Error("Failed to update customer: %v", err)
 		return errors.ErrInternalServer
 	}
 
-	// Sync RADIUS user if PPPoE settings or service plan changed
+	// Sync RADIUS user if PPPoE settings changed
 	if needsRadiusSync && customer.ServiceType == entity.ServiceTypePPPoE && customer.PPPoEUsername != "" {
 		s.syncRadiusUserForCustomer(ctx, tenantID, customer, oldPPPoEUsername)
+
+		// Kick the old username's sessions so the customer reconnects with the new one
+		if oldPPPoEUsername != "" && oldPPPoEUsername != customer.PPPoEUsername {
+			if err := s.coaService.DisconnectUser(ctx, tenantID, oldPPPoEUsername); err != nil {
+				logger.Warn("Failed to disconnect old PPPoE session %s: %v", oldPPPoEUsername, err)
+			}
+		}
 	}
 	
 	logger.Info("Customer updated: %s (%s)", customer.Name, customer.ID)
@@ -605,12 +681,8 @@ func (s *dashboardService) RecordPayment(ctx context.Context, tenantID string, r
 		return errors.ErrUnauthorized
 	}
 	
-	// Determine status and payment date
+	// If payment_date is provided, the invoice is paid right away
 	var paymentDate *time.Time
-	status := entity.PaymentStatusPending // Default: invoice (pending)
-	paymentMethod := req.PaymentMethod
-	
-	// If payment_date is provided, it's a paid payment
 	if req.PaymentDate != "" {
 		parsed, err := time.Parse(time.RFC3339, req.PaymentDate)
 		if err != nil {
@@ -619,38 +691,113 @@ func (s *dashboardService) RecordPayment(ctx context.Context, tenantID string, r
 		}
 		if err == nil {
 			paymentDate = &parsed
-			status = entity.PaymentStatusPaid
 		}
 	}
 	
-	// Calculate due date (current date + invoice_due_days or default 14 days)
-	dueDate := time.Now().AddDate(0, 0, 14)
+	// Without items the amount becomes a single line
+	items := make([]entity.InvoiceItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, entity.InvoiceItem{
+			Type:        item.Type,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+		})
+	}
+	if len(items) == 0 {
+		if req.Amount <= 0 {
+			return errors.NewValidationError("amount or items is required")
+		}
+		description := req.Notes
+		if description == "" {
+			description = "Invoice for " + customer.Name
+		}
+		items = append(items, entity.InvoiceItem{
+			Type:        entity.InvoiceItemOther,
+			Description: description,
+			Quantity:    1,
+			UnitPrice:   req.Amount,
+		})
+	}
 	
-	// Create payment/invoice
 	payment := &entity.Payment{
-		TenantID:      tenantID,
-		CustomerID:    req.CustomerID,
-		Amount:        req.Amount,
-		PaymentDate:   paymentDate,
-		DueDate:       dueDate,
-		Status:        status,
-		PaymentMethod: paymentMethod,
-		Notes:         req.Notes,
+		TenantID:   tenantID,
+		CustomerID: req.CustomerID,
+		Status:     entity.PaymentStatusPending,
+		Notes:      req.Notes,
+		Items:      items,
 	}
 	
-	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+	if err := s.invoiceService.CreateInvoice(ctx, payment); err != nil {
 		logger.Error("Failed to record payment: %v", err)
-		return errors.ErrInternalServer
+		return err
 	}
 	
-	if status == entity.PaymentStatusPaid {
-		logger.Info("Payment recorded: %s - %.2f", customer.Name, req.Amount)
-	} else {
-		logger.Info("Invoice created: %s - %.2f", customer.Name, req.Amount)
+	// Nothing is left to allocate when the customer's credit covered the invoice
+	if paymentDate == nil || payment.Status == entity.PaymentStatusPaid {
+		logger.Info("Invoice created: %s - %.2f", customer.Name, payment.Amount)
+		return nil
 	}
+	
+	allocation := &entity.PaymentAllocation{
+		Amount:        payment.Balance(),
+		PaymentMethod: req.PaymentMethod,
+		PaidAt:        *paymentDate,
+		Notes:         req.Notes,
+	}
+	if _, err := s.invoiceService.AllocatePayment(ctx, tenantID, payment.ID, allocation); err != nil {
+		logger.Error("Failed to record payment: %v", err)
+		return err
+	}
+	
+	logger.Info("Payment recorded: %s - %.2f", customer.Name, payment.Amount)
 	return nil
 }
 
+// MarkPaymentPaid settles a pending or overdue invoice by allocating its
+// outstanding balance
+func (s *dashboardService) MarkPaymentPaid(ctx context.Context, tenantID, paymentID string, req *dto.MarkPaymentPaidRequest) (*entity.Payment, error) {
+	payment, err := s.paymentRepo.FindByID(ctx, paymentID)
+	if err != nil || payment == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	if payment.TenantID != tenantID {
+		return nil, errors.ErrUnauthorized
+	}
+
+	if payment.Status == entity.PaymentStatusPaid {
+		return nil, errors.NewValidationError("payment is already paid")
+	}
+
+	paymentDate := time.Now()
+	if req.PaymentDate != "" {
+		parsed, err := time.Parse(time.RFC3339, req.PaymentDate)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", req.PaymentDate)
+		}
+		if err != nil {
+			return nil, errors.NewValidationError("payment_date must be RFC3339 or YYYY-MM-DD")
+		}
+		paymentDate = parsed
+	}
+
+	allocation := &entity.PaymentAllocation{
+		Amount:        payment.Balance(),
+		PaymentMethod: req.PaymentMethod,
+		PaidAt:        paymentDate,
+		Notes:         req.Notes,
+	}
+	paid, err := s.invoiceService.AllocatePayment(ctx, tenantID, payment.ID, allocation)
+	if err != nil {
+		logger.Error("Failed to mark payment paid: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Payment marked paid: %s - %.2f", paid.ID, paid.Total())
+	return paid, nil
+}
+
 func (s *dashboardService) ListServicePlans(ctx context.Context, tenantID string) (*dto.ServicePlanListResponse, error) {
 	plans, err := s.servicePlanRepo.FindByTenantID(ctx, tenantID)
 	if err != nil {
@@ -884,7 +1031,12 @@ func (s *dashboardService) buildPaymentHistory(payments []*entity.Payment) []dto
 	for _, p := range payments {
 		result = append(result, dto.PaymentHistory{
 			ID:            p.ID,
+			InvoiceNumber: invoiceNumberOf(p),
 			Amount:        p.Amount,
+			LateFee:       p.LateFee,
+			LateFeeWaived: p.LateFeeWaivedAt != nil,
+			TotalAmount:   p.Total(),
+			PaidAmount:    p.PaidAmount,
 			PaymentDate:   p.PaymentDate,
 			DueDate:       p.DueDate,
 			Status:        p.Status,
@@ -912,10 +1064,15 @@ func (s *dashboardService) buildPaymentSummaries(payments []*entity.Payment) []d
 		
 		result = append(result, dto.PaymentSummary{
 			ID:            p.ID,
+			InvoiceNumber: invoiceNumberOf(p),
 			CustomerID:    p.CustomerID,
 			CustomerName:  customerName,
 			CustomerCode:  customerCode,
 			Amount:        p.Amount,
+			LateFee:       p.LateFee,
+			LateFeeWaived: p.LateFeeWaivedAt != nil,
+			TotalAmount:   p.Total(),
+			PaidAmount:    p.PaidAmount,
 			PaymentDate:   p.PaymentDate,
 			DueDate:       p.DueDate,
 			Status:        p.Status,
@@ -1044,9 +1201,12 @@ func (s *dashboardService) GetPlanLimits(ctx context.Context, tenantID string) (
 	}
 
 	// Parse trial config
-	var trialConfig dto.TrialConfig
-	if plan.TrialConfig != "" {
-		json.Unmarshal([]byte(plan.TrialConfig), &trialConfig)
+	trial := plan.Trial()
+	trialConfig := dto.TrialConfig{
+		TrialDays:      trial.TrialDays,
+		TrialEnabled:   trial.TrialEnabled,
+		RequirePayment: trial.RequirePayment,
+		AutoConvert:    trial.AutoConvert,
 	}
 
 	// Get current usage
@@ -1057,6 +1217,8 @@ func (s *dashboardService) GetPlanLimits(ctx context.Context, tenantID string) (
 	trialEndsAt := ""
 	if subscription.Status == entity.SubscriptionStatusTrial && subscription.EndDate != nil {
 		trialEndsAt = subscription.EndDate.Format("2006-01-02T15:04:05Z07:00")
+	} else if subscription.Status == entity.SubscriptionStatusReadOnly && subscription.TrialEndsAt != nil {
+		trialEndsAt = subscription.TrialEndsAt.Format("2006-01-02T15:04:05Z07:00")
 	}
 
 	return &dto.PlanLimitsResponse{
@@ -1065,6 +1227,7 @@ func (s *dashboardService) GetPlanLimits(ctx context.Context, tenantID string) (
 		PlanSlug:    plan.Slug,
 		IsTrial:     plan.IsTrial || subscription.Status == entity.SubscriptionStatusTrial,
 		TrialEndsAt: trialEndsAt,
+		ReadOnly:    subscription.Status == entity.SubscriptionStatusReadOnly,
 		Limits:      limits,
 		Features:    features,
 		Usage: dto.PlanUsage{
@@ -1142,6 +1305,7 @@ func (s *dashboardService) ActivateCustomer(ctx context.Context, tenantID, custo
 
 	// Update customer status to active
 	customer.Status = entity.CustomerStatusActive
+	customer.AutoSuspendedAt = nil
 	if customer.InstallationDate.IsZero() {
 		customer.InstallationDate = time.Now()
 	}
@@ -1185,6 +1349,11 @@ func (s *dashboardService) SuspendCustomer(ctx context.Context, tenantID, custom
 	// Update customer status to suspended
 	customer.Status = entity.CustomerStatusSuspended
 	customer.Notes = reason
+	// A manual suspension is never lifted by auto-reactivation; the billing
+	// job sets this again after calling SuspendCustomer
+	customer.AutoSuspendedAt = nil
+	now := time.Now()
+	customer.SuspendedAt = &now
 
 	if err := s.customerRepo.Update(ctx, customer); err != nil {
 		logger.Error("Failed to suspend customer: %v", err)
@@ -1207,10 +1376,26 @@ func (s *dashboardService) SuspendCustomer(ctx context.Context, tenantID, custom
 		}
 	}
 
+	// Kick online sessions so the customer re-authenticates and is rejected
+	s.disconnectCustomerSessions(ctx, tenantID, customer)
+
 	logger.Info("Customer suspended: %s (%s) - Reason: %s", customer.Name, customer.ID, reason)
 	return nil
 }
 
+// disconnectCustomerSessions sends Disconnect-Request for the customer's
+// PPPoE and hotspot sessions
+func (s *dashboardService) disconnectCustomerSessions(ctx context.Context, tenantID string, customer *entity.Customer) {
+	for _, username := range []string{customer.PPPoEUsername, customer.HotspotUsername} {
+		if username == "" {
+			continue
+		}
+		if err := s.coaService.DisconnectUser(ctx, tenantID, username); err != nil {
+			logger.Warn("Failed to disconnect sessions of %s: %v", username, err)
+		}
+	}
+}
+
 // TerminateCustomer terminates a customer and their RADIUS user
 func (s *dashboardService) TerminateCustomer(ctx context.Context, tenantID, customerID, reason string) error {
 	customer, err := s.customerRepo.FindByID(ctx, customerID)
@@ -1245,6 +1430,15 @@ func (s *dashboardService) TerminateCustomer(ctx context.Context, tenantID, cust
 		}
 	}
 
+	s.disconnectCustomerSessions(ctx, tenantID, customer)
+
 	logger.Info("Customer terminated: %s (%s) - Reason: %s", customer.Name, customer.ID, reason)
 	return nil
 }
+
+func invoiceNumberOf(p *entity.Payment) string {
+	if p.InvoiceNumber == nil {
+		return ""
+	}
+	return *p.InvoiceNumber
+}
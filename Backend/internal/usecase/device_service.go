@@ -2,10 +2,13 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/rtrwnet/saas-backend/internal/domain/repository"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
 )
 
 type DeviceService interface {
@@ -15,18 +18,21 @@ type DeviceService interface {
 	ListDevices(ctx context.Context, tenantID string, page, perPage int, filters map[string]interface{}) ([]*entity.Device, int, error)
 	DeleteDevice(ctx context.Context, tenantID, deviceID string) error
 	TestMikrotikConnection(ctx context.Context, tenantID, deviceID string) (bool, error)
-	SyncMikrotikQueues(ctx context.Context, tenantID, deviceID string) error
+	SyncMikrotikQueues(ctx context.Context, tenantID, deviceID string, dryRun bool) (*QueueSyncResult, error)
+	ListQueueSyncReports(ctx context.Context, tenantID, deviceID string, limit int) ([]*entity.QueueSyncReport, error)
 }
 
 type deviceService struct {
 	deviceRepo     repository.DeviceRepository
+	queueSyncRepo  repository.QueueSyncRepository
 	mikrotikSvc    MikrotikService
 }
 
-func NewDeviceService(deviceRepo repository.DeviceRepository, mikrotikSvc MikrotikService) DeviceService {
+func NewDeviceService(deviceRepo repository.DeviceRepository, queueSyncRepo repository.QueueSyncRepository, mikrotikSvc MikrotikService) DeviceService {
 	return &deviceService{
-		deviceRepo:  deviceRepo,
-		mikrotikSvc: mikrotikSvc,
+		deviceRepo:    deviceRepo,
+		queueSyncRepo: queueSyncRepo,
+		mikrotikSvc:   mikrotikSvc,
 	}
 }
 
@@ -251,7 +257,13 @@ func (s *deviceService) DeleteDevice(ctx context.Context, tenantID, deviceID str
 		return errors.NewUnauthorizedError("device does not belong to this tenant")
 	}
 
-	return s.deviceRepo.Delete(ctx, deviceID)
+	if err := s.deviceRepo.Delete(ctx, deviceID); err != nil {
+		return err
+	}
+
+	// Drop pooled API sessions for the removed router
+	s.mikrotikSvc.ForgetDevice(deviceID)
+	return nil
 }
 
 func (s *deviceService) TestMikrotikConnection(ctx context.Context, tenantID, deviceID string) (bool, error) {
@@ -268,9 +280,7 @@ func (s *deviceService) TestMikrotikConnection(ctx context.Context, tenantID, de
 	}
 
 	// Test connection
-	success, err := s.mikrotikSvc.TestConnection(ctx, device.IPAddress, device.MikrotikPort, 
-		device.MikrotikUsername, device.MikrotikPasswordEncrypted)
-	
+	success, err := s.mikrotikSvc.TestConnection(ctx, device)
 	if err != nil {
 		_ = s.deviceRepo.UpdateConnectionStatus(ctx, deviceID, entity.ConnectionStatusError)
 		return false, err
@@ -285,34 +295,146 @@ func (s *deviceService) TestMikrotikConnection(ctx context.Context, tenantID, de
 	return success, nil
 }
 
-func (s *deviceService) SyncMikrotikQueues(ctx context.Context, tenantID, deviceID string) error {
+// SyncMikrotikQueues reconciles the router's simple queues with the active
+// customers of the tenant. With dryRun the plan is computed and recorded but
+// nothing is changed on the router. Every run is persisted as a report.
+func (s *deviceService) SyncMikrotikQueues(ctx context.Context, tenantID, deviceID string, dryRun bool) (*QueueSyncResult, error) {
 	device, err := s.deviceRepo.GetByID(ctx, deviceID)
 	if err != nil {
-		return errors.NewNotFoundError("device not found")
+		return nil, errors.NewNotFoundError("device not found")
 	}
 	if device.TenantID != tenantID {
-		return errors.NewUnauthorizedError("device does not belong to this tenant")
+		return nil, errors.NewUnauthorizedError("device does not belong to this tenant")
 	}
 
 	if !device.MikrotikAPIEnabled {
-		return errors.NewValidationError("Mikrotik API is not enabled for this device")
+		return nil, errors.NewValidationError("Mikrotik API is not enabled for this device")
 	}
 
-	// Connect to Mikrotik
-	if err := s.mikrotikSvc.Connect(ctx, device.IPAddress, device.MikrotikPort,
-		device.MikrotikUsername, device.MikrotikPasswordEncrypted); err != nil {
-		return err
+	report := &entity.QueueSyncReport{
+		TenantID:  tenantID,
+		DeviceID:  deviceID,
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
 	}
-	defer s.mikrotikSvc.Disconnect()
 
-	// Get queue list
-	queues, err := s.mikrotikSvc.GetQueueList(ctx)
+	// Build the desired state from active customers and their plans
+	customers, err := s.queueSyncRepo.FindQueueCustomers(ctx, tenantID)
 	if err != nil {
-		return err
+		return nil, errors.NewDatabaseError("load queue customers", err)
 	}
 
-	// TODO: Sync queues with customer service plans
-	_ = queues
+	planIDs := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, c := range customers {
+		if c.ServicePlanID != "" && !seen[c.ServicePlanID] {
+			seen[c.ServicePlanID] = true
+			planIDs = append(planIDs, c.ServicePlanID)
+		}
+	}
+	advSettings, err := s.queueSyncRepo.FindAdvancedSettings(ctx, planIDs)
+	if err != nil {
+		return nil, errors.NewDatabaseError("load service plan settings", err)
+	}
+	settingsByPlan := make(map[string]*entity.ServicePlanAdvancedSettings, len(advSettings))
+	for _, adv := range advSettings {
+		settingsByPlan[adv.ServicePlanID] = adv
+	}
 
-	return nil
+	desired, skipped := desiredQueues(customers, settingsByPlan)
+
+	// Read the actual state from the router
+	actual, err := s.mikrotikSvc.GetQueueList(ctx, device)
+	if err != nil {
+		_ = s.deviceRepo.UpdateConnectionStatus(ctx, deviceID, entity.ConnectionStatusError)
+		report.Status = entity.QueueSyncStatusFailed
+		report.ErrorMessage = err.Error()
+		s.saveQueueSyncReport(ctx, report, skipped)
+		return nil, err
+	}
+
+	actions, unchanged := planQueueSync(desired, actual)
+	report.UnchangedCount = unchanged
+	report.SkippedCount = len(skipped)
+
+	if dryRun {
+		report.Status = entity.QueueSyncStatusPlanned
+	} else {
+		s.applyQueueSync(ctx, device, actions)
+		report.Status = entity.QueueSyncStatusSuccess
+	}
+
+	for _, action := range actions {
+		if action.Error != "" {
+			report.FailedCount++
+			continue
+		}
+		switch action.Action {
+		case QueueActionCreate:
+			report.CreatedCount++
+		case QueueActionUpdate:
+			report.UpdatedCount++
+		case QueueActionRemove:
+			report.RemovedCount++
+		}
+	}
+	if report.FailedCount > 0 {
+		report.Status = entity.QueueSyncStatusPartial
+		if report.FailedCount == len(actions) {
+			report.Status = entity.QueueSyncStatusFailed
+		}
+	}
+
+	actions = append(actions, skipped...)
+	s.saveQueueSyncReport(ctx, report, actions)
+
+	return &QueueSyncResult{Report: report, Actions: actions}, nil
+}
+
+// applyQueueSync executes planned actions, recording per-action errors so a
+// single bad queue does not abort the rest of the run
+func (s *deviceService) applyQueueSync(ctx context.Context, device *entity.Device, actions []QueueSyncAction) {
+	for i := range actions {
+		var err error
+		switch actions[i].Action {
+		case QueueActionCreate:
+			err = s.mikrotikSvc.AddQueue(ctx, device, actions[i].queue)
+		case QueueActionUpdate:
+			err = s.mikrotikSvc.SetQueue(ctx, device, actions[i].queue)
+		case QueueActionRemove:
+			err = s.mikrotikSvc.RemoveQueue(ctx, device, actions[i].queue.ID)
+		}
+		if err != nil {
+			actions[i].Error = err.Error()
+			logger.Error("Queue sync: %s %s on device %s failed: %v", actions[i].Action, actions[i].QueueName, device.ID, err)
+		}
+	}
+}
+
+func (s *deviceService) saveQueueSyncReport(ctx context.Context, report *entity.QueueSyncReport, actions []QueueSyncAction) {
+	report.FinishedAt = time.Now()
+	if actions == nil {
+		actions = []QueueSyncAction{}
+	}
+	if data, err := json.Marshal(actions); err == nil {
+		report.Actions = string(data)
+	}
+	if err := s.queueSyncRepo.CreateReport(ctx, report); err != nil {
+		logger.Error("Queue sync: failed to save report for device %s: %v", report.DeviceID, err)
+	}
+}
+
+func (s *deviceService) ListQueueSyncReports(ctx context.Context, tenantID, deviceID string, limit int) ([]*entity.QueueSyncReport, error) {
+	device, err := s.deviceRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("device not found")
+	}
+	if device.TenantID != tenantID {
+		return nil, errors.NewUnauthorizedError("device does not belong to this tenant")
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.queueSyncRepo.ListReports(ctx, deviceID, limit)
 }
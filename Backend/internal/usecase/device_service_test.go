@@ -7,6 +7,7 @@ import (
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockDeviceRepository struct {
@@ -67,49 +68,87 @@ func (m *MockDeviceRepository) GetDefaultMikrotik(ctx context.Context, tenantID
 	return args.Get(0).(*entity.Device), args.Error(1)
 }
 
+func (m *MockDeviceRepository) CountByTenantID(ctx context.Context, tenantID string) (int, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Int(0), args.Error(1)
+}
+
 type MockMikrotikService struct {
 	mock.Mock
 }
 
-func (m *MockMikrotikService) Connect(ctx context.Context, host, port, username, password string) error {
-	args := m.Called(ctx, host, port, username, password)
+func (m *MockMikrotikService) CreateQueue(ctx context.Context, device *entity.Device, queueName, targetIP string, maxUpload, maxDownload int) error {
+	args := m.Called(ctx, device, queueName, targetIP, maxUpload, maxDownload)
 	return args.Error(0)
 }
 
-func (m *MockMikrotikService) Disconnect() error {
-	args := m.Called()
+func (m *MockMikrotikService) UpdateQueue(ctx context.Context, device *entity.Device, queueName string, maxUpload, maxDownload int) error {
+	args := m.Called(ctx, device, queueName, maxUpload, maxDownload)
 	return args.Error(0)
 }
 
-func (m *MockMikrotikService) CreateQueue(ctx context.Context, queueName, targetIP string, maxUpload, maxDownload int) error {
-	args := m.Called(ctx, queueName, targetIP, maxUpload, maxDownload)
+func (m *MockMikrotikService) DeleteQueue(ctx context.Context, device *entity.Device, queueName string) error {
+	args := m.Called(ctx, device, queueName)
 	return args.Error(0)
 }
 
-func (m *MockMikrotikService) UpdateQueue(ctx context.Context, queueName string, maxUpload, maxDownload int) error {
-	args := m.Called(ctx, queueName, maxUpload, maxDownload)
+func (m *MockMikrotikService) GetQueueList(ctx context.Context, device *entity.Device) ([]MikrotikQueue, error) {
+	args := m.Called(ctx, device)
+	return args.Get(0).([]MikrotikQueue), args.Error(1)
+}
+
+func (m *MockMikrotikService) AddQueue(ctx context.Context, device *entity.Device, queue MikrotikQueue) error {
+	args := m.Called(ctx, device, queue)
 	return args.Error(0)
 }
 
-func (m *MockMikrotikService) DeleteQueue(ctx context.Context, queueName string) error {
-	args := m.Called(ctx, queueName)
+func (m *MockMikrotikService) SetQueue(ctx context.Context, device *entity.Device, queue MikrotikQueue) error {
+	args := m.Called(ctx, device, queue)
 	return args.Error(0)
 }
 
-func (m *MockMikrotikService) GetQueueList(ctx context.Context) ([]MikrotikQueue, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]MikrotikQueue), args.Error(1)
+func (m *MockMikrotikService) RemoveQueue(ctx context.Context, device *entity.Device, queueID string) error {
+	args := m.Called(ctx, device, queueID)
+	return args.Error(0)
 }
 
-func (m *MockMikrotikService) TestConnection(ctx context.Context, host, port, username, password string) (bool, error) {
-	args := m.Called(ctx, host, port, username, password)
+func (m *MockMikrotikService) TestConnection(ctx context.Context, device *entity.Device) (bool, error) {
+	args := m.Called(ctx, device)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockMikrotikService) ForgetDevice(deviceID string) {
+	m.Called(deviceID)
+}
+
+type MockQueueSyncRepository struct {
+	mock.Mock
+}
+
+func (m *MockQueueSyncRepository) FindQueueCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]*entity.Customer), args.Error(1)
+}
+
+func (m *MockQueueSyncRepository) FindAdvancedSettings(ctx context.Context, planIDs []string) ([]*entity.ServicePlanAdvancedSettings, error) {
+	args := m.Called(ctx, planIDs)
+	return args.Get(0).([]*entity.ServicePlanAdvancedSettings), args.Error(1)
+}
+
+func (m *MockQueueSyncRepository) CreateReport(ctx context.Context, report *entity.QueueSyncReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockQueueSyncRepository) ListReports(ctx context.Context, deviceID string, limit int) ([]*entity.QueueSyncReport, error) {
+	args := m.Called(ctx, deviceID, limit)
+	return args.Get(0).([]*entity.QueueSyncReport), args.Error(1)
+}
+
 func TestDeviceService_CreateDevice(t *testing.T) {
 	mockDeviceRepo := new(MockDeviceRepository)
 	mockMikrotikSvc := new(MockMikrotikService)
-	service := NewDeviceService(mockDeviceRepo, mockMikrotikSvc)
+	service := NewDeviceService(mockDeviceRepo, nil, mockMikrotikSvc)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -189,7 +228,7 @@ func TestDeviceService_CreateDevice(t *testing.T) {
 func TestDeviceService_UpdateDevice(t *testing.T) {
 	mockDeviceRepo := new(MockDeviceRepository)
 	mockMikrotikSvc := new(MockMikrotikService)
-	service := NewDeviceService(mockDeviceRepo, mockMikrotikSvc)
+	service := NewDeviceService(mockDeviceRepo, nil, mockMikrotikSvc)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -248,7 +287,7 @@ func TestDeviceService_UpdateDevice(t *testing.T) {
 func TestDeviceService_TestMikrotikConnection(t *testing.T) {
 	mockDeviceRepo := new(MockDeviceRepository)
 	mockMikrotikSvc := new(MockMikrotikService)
-	service := NewDeviceService(mockDeviceRepo, mockMikrotikSvc)
+	service := NewDeviceService(mockDeviceRepo, nil, mockMikrotikSvc)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -266,8 +305,7 @@ func TestDeviceService_TestMikrotikConnection(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		mockDeviceRepo.On("GetByID", ctx, deviceID).Return(device, nil).Once()
-		mockMikrotikSvc.On("TestConnection", ctx, device.IPAddress, device.MikrotikPort, 
-			device.MikrotikUsername, device.MikrotikPasswordEncrypted).Return(true, nil).Once()
+		mockMikrotikSvc.On("TestConnection", ctx, device).Return(true, nil).Once()
 		mockDeviceRepo.On("UpdateConnectionStatus", ctx, deviceID, entity.ConnectionStatusConnected).Return(nil).Once()
 
 		success, err := service.TestMikrotikConnection(ctx, tenantID, deviceID)
@@ -296,8 +334,7 @@ func TestDeviceService_TestMikrotikConnection(t *testing.T) {
 
 	t.Run("Connection Failed", func(t *testing.T) {
 		mockDeviceRepo.On("GetByID", ctx, deviceID).Return(device, nil).Once()
-		mockMikrotikSvc.On("TestConnection", ctx, device.IPAddress, device.MikrotikPort,
-			device.MikrotikUsername, device.MikrotikPasswordEncrypted).Return(false, assert.AnError).Once()
+		mockMikrotikSvc.On("TestConnection", ctx, device).Return(false, assert.AnError).Once()
 		mockDeviceRepo.On("UpdateConnectionStatus", ctx, deviceID, entity.ConnectionStatusError).Return(nil).Once()
 
 		success, err := service.TestMikrotikConnection(ctx, tenantID, deviceID)
@@ -312,7 +349,7 @@ func TestDeviceService_TestMikrotikConnection(t *testing.T) {
 func TestDeviceService_ListDevices(t *testing.T) {
 	mockDeviceRepo := new(MockDeviceRepository)
 	mockMikrotikSvc := new(MockMikrotikService)
-	service := NewDeviceService(mockDeviceRepo, mockMikrotikSvc)
+	service := NewDeviceService(mockDeviceRepo, nil, mockMikrotikSvc)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -352,7 +389,7 @@ func TestDeviceService_ListDevices(t *testing.T) {
 func TestDeviceService_DeleteDevice(t *testing.T) {
 	mockDeviceRepo := new(MockDeviceRepository)
 	mockMikrotikSvc := new(MockMikrotikService)
-	service := NewDeviceService(mockDeviceRepo, mockMikrotikSvc)
+	service := NewDeviceService(mockDeviceRepo, nil, mockMikrotikSvc)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -366,11 +403,13 @@ func TestDeviceService_DeleteDevice(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		mockDeviceRepo.On("GetByID", ctx, deviceID).Return(device, nil).Once()
 		mockDeviceRepo.On("Delete", ctx, deviceID).Return(nil).Once()
+		mockMikrotikSvc.On("ForgetDevice", deviceID).Once()
 
 		err := service.DeleteDevice(ctx, tenantID, deviceID)
 
 		assert.NoError(t, err)
 		mockDeviceRepo.AssertExpectations(t)
+		mockMikrotikSvc.AssertExpectations(t)
 	})
 
 	t.Run("Wrong Tenant", func(t *testing.T) {
@@ -386,3 +425,94 @@ func TestDeviceService_DeleteDevice(t *testing.T) {
 		mockDeviceRepo.AssertExpectations(t)
 	})
 }
+
+func TestDeviceService_SyncMikrotikQueues(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-123"
+	deviceID := "device-123"
+
+	device := &entity.Device{
+		ID:                 deviceID,
+		TenantID:           tenantID,
+		MikrotikAPIEnabled: true,
+	}
+	plan := &entity.ServicePlan{ID: "plan-1", SpeedUpload: 5, SpeedDownload: 10}
+	customers := []*entity.Customer{
+		{ID: "cust-1", CustomerCode: "CUST001", ServiceType: entity.ServiceTypeDHCP, IPAddress: "10.0.0.2", ServicePlanID: plan.ID, ServicePlan: plan},
+		{ID: "cust-2", CustomerCode: "CUST002", ServiceType: entity.ServiceTypeStatic, StaticIP: "10.0.0.3", ServicePlanID: plan.ID, ServicePlan: plan},
+	}
+	actual := []MikrotikQueue{
+		// Up to date
+		{ID: "*1", Name: "CUST001", Target: "10.0.0.2/32", MaxUpload: 5000000, MaxDownload: 10000000, Priority: 8, Comment: "rtrwnet:customer:cust-1"},
+		// Customer no longer active
+		{ID: "*2", Name: "CUST009", Target: "10.0.0.9/32", MaxUpload: 1000000, MaxDownload: 1000000, Priority: 8, Comment: "rtrwnet:customer:cust-9"},
+		// Operator queue, never touched
+		{ID: "*3", Name: "uplink", Target: "10.0.0.0/24", MaxUpload: 100000000, MaxDownload: 100000000},
+	}
+
+	setup := func() (*MockDeviceRepository, *MockQueueSyncRepository, *MockMikrotikService, DeviceService) {
+		mockDeviceRepo := new(MockDeviceRepository)
+		mockQueueSyncRepo := new(MockQueueSyncRepository)
+		mockMikrotikSvc := new(MockMikrotikService)
+
+		mockDeviceRepo.On("GetByID", ctx, deviceID).Return(device, nil).Once()
+		mockQueueSyncRepo.On("FindQueueCustomers", ctx, tenantID).Return(customers, nil).Once()
+		mockQueueSyncRepo.On("FindAdvancedSettings", ctx, []string{"plan-1"}).Return([]*entity.ServicePlanAdvancedSettings{}, nil).Once()
+		mockMikrotikSvc.On("GetQueueList", ctx, device).Return(actual, nil).Once()
+
+		return mockDeviceRepo, mockQueueSyncRepo, mockMikrotikSvc, NewDeviceService(mockDeviceRepo, mockQueueSyncRepo, mockMikrotikSvc)
+	}
+
+	t.Run("Dry Run", func(t *testing.T) {
+		_, mockQueueSyncRepo, mockMikrotikSvc, service := setup()
+		mockQueueSyncRepo.On("CreateReport", ctx, mock.AnythingOfType("*entity.QueueSyncReport")).Return(nil).Once()
+
+		result, err := service.SyncMikrotikQueues(ctx, tenantID, deviceID, true)
+
+		require.NoError(t, err)
+		assert.Equal(t, entity.QueueSyncStatusPlanned, result.Report.Status)
+		assert.Equal(t, 1, result.Report.CreatedCount)
+		assert.Equal(t, 1, result.Report.RemovedCount)
+		assert.Equal(t, 1, result.Report.UnchangedCount)
+		require.Len(t, result.Actions, 2)
+		assert.Equal(t, QueueActionCreate, result.Actions[0].Action)
+		assert.Equal(t, "CUST002", result.Actions[0].QueueName)
+		assert.Equal(t, QueueActionRemove, result.Actions[1].Action)
+		assert.Equal(t, "CUST009", result.Actions[1].QueueName)
+		mockMikrotikSvc.AssertNotCalled(t, "AddQueue", mock.Anything, mock.Anything, mock.Anything)
+		mockMikrotikSvc.AssertNotCalled(t, "RemoveQueue", mock.Anything, mock.Anything, mock.Anything)
+		mockQueueSyncRepo.AssertExpectations(t)
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		_, mockQueueSyncRepo, mockMikrotikSvc, service := setup()
+		mockMikrotikSvc.On("AddQueue", ctx, device, mock.MatchedBy(func(q MikrotikQueue) bool {
+			return q.Name == "CUST002" && q.Target == "10.0.0.3/32" && q.MaxDownload == 10000000
+		})).Return(nil).Once()
+		mockMikrotikSvc.On("RemoveQueue", ctx, device, "*2").Return(assert.AnError).Once()
+		mockQueueSyncRepo.On("CreateReport", ctx, mock.MatchedBy(func(r *entity.QueueSyncReport) bool {
+			return !r.DryRun && r.Status == entity.QueueSyncStatusPartial && r.FailedCount == 1
+		})).Return(nil).Once()
+
+		result, err := service.SyncMikrotikQueues(ctx, tenantID, deviceID, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, entity.QueueSyncStatusPartial, result.Report.Status)
+		assert.Equal(t, 1, result.Report.CreatedCount)
+		assert.Equal(t, 0, result.Report.RemovedCount)
+		assert.NotEmpty(t, result.Actions[1].Error)
+		mockMikrotikSvc.AssertExpectations(t)
+		mockQueueSyncRepo.AssertExpectations(t)
+	})
+
+	t.Run("API Not Enabled", func(t *testing.T) {
+		mockDeviceRepo := new(MockDeviceRepository)
+		service := NewDeviceService(mockDeviceRepo, nil, new(MockMikrotikService))
+		mockDeviceRepo.On("GetByID", ctx, deviceID).Return(&entity.Device{ID: deviceID, TenantID: tenantID}, nil).Once()
+
+		_, err := service.SyncMikrotikQueues(ctx, tenantID, deviceID, true)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not enabled")
+	})
+}
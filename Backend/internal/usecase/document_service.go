@@ -0,0 +1,390 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/pdf"
+	"github.com/rtrwnet/saas-backend/pkg/webhook"
+)
+
+// Document types of a customer invoice PDF
+const (
+	DocumentTypeInvoice = "invoice"
+	DocumentTypeReceipt = "receipt"
+)
+
+const (
+	documentDateFormat = "02/01/2006"
+	logoFetchTimeout   = 5 * time.Second
+	logoMaxBytes       = 2 << 20
+)
+
+// PlatformInfo identifies the SaaS operator issuing subscription invoices
+type PlatformInfo struct {
+	Name    string
+	Address string
+	Email   string
+}
+
+// RenderedDocument is a generated file ready to be downloaded
+type RenderedDocument struct {
+	FileName string
+	Content  []byte
+}
+
+// DocumentService renders invoices and receipts as PDF. Customer invoices
+// carry the tenant's company details; subscription invoices the platform's.
+type DocumentService interface {
+	// RenderPaymentPDF renders a customer invoice, or with docType receipt the
+	// payments allocated to it. An empty docType picks receipt for paid invoices.
+	RenderPaymentPDF(ctx context.Context, tenantID, paymentID, docType string) (*RenderedDocument, error)
+	// RenderTransactionPDF renders the invoice of a subscription payment
+	RenderTransactionPDF(ctx context.Context, tenantID, transactionID string) (*RenderedDocument, error)
+}
+
+type documentService struct {
+	invoiceRepo     repository.InvoiceRepository
+	settingsRepo    repository.SettingsRepository
+	tenantRepo      repository.TenantRepository
+	planRepo        repository.SubscriptionPlanRepository
+	transactionRepo repository.PaymentTransactionRepository
+	platform        PlatformInfo
+	fetchLogo       func(ctx context.Context, url string) (image.Image, error)
+}
+
+func NewDocumentService(
+	invoiceRepo repository.InvoiceRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+	planRepo repository.SubscriptionPlanRepository,
+	transactionRepo repository.PaymentTransactionRepository,
+	platform PlatformInfo,
+) DocumentService {
+	return &documentService{
+		invoiceRepo:     invoiceRepo,
+		settingsRepo:    settingsRepo,
+		tenantRepo:      tenantRepo,
+		planRepo:        planRepo,
+		transactionRepo: transactionRepo,
+		platform:        platform,
+		fetchLogo:       newLogoFetcher(webhook.NewRestrictedHTTPClient(logoFetchTimeout, false)),
+	}
+}
+
+// newLogoFetcher returns a function that downloads and decodes the company
+// logo stored as a public URL. The URL is the tenant's, so the client must
+// not reach the internal network.
+func newLogoFetcher(client *http.Client) func(ctx context.Context, url string) (image.Image, error) {
+	return func(ctx context.Context, url string) (image.Image, error) {
+		return fetchLogo(ctx, client, url)
+	}
+}
+
+func fetchLogo(ctx context.Context, client *http.Client, url string) (image.Image, error) {
+	ctx, cancel := context.WithTimeout(ctx, logoFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported logo URL scheme %q", req.URL.Scheme)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, logoMaxBytes))
+	return img, err
+}
+
+func (s *documentService) RenderPaymentPDF(ctx context.Context, tenantID, paymentID, docType string) (*RenderedDocument, error) {
+	payment, err := s.invoiceRepo.FindInvoice(ctx, paymentID)
+	if err != nil || payment == nil {
+		return nil, errors.ErrNotFound
+	}
+	if payment.TenantID != tenantID {
+		return nil, errors.ErrUnauthorized
+	}
+
+	if docType == "" {
+		docType = DocumentTypeInvoice
+		if payment.Status == entity.PaymentStatusPaid {
+			docType = DocumentTypeReceipt
+		}
+	}
+
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		settings = defaultTenantSettings(tenantID)
+	} else if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+
+	var doc *pdf.Invoice
+	switch docType {
+	case DocumentTypeInvoice:
+		doc = customerInvoiceDocument(payment)
+	case DocumentTypeReceipt:
+		if payment.PaidAmount <= 0 {
+			return nil, errors.NewValidationError("invoice has no payments to issue a receipt for")
+		}
+		doc = customerReceiptDocument(payment)
+	default:
+		return nil, errors.NewValidationError("type must be invoice or receipt")
+	}
+
+	doc.Issuer = pdf.Party{
+		Name:  settings.CompanyName,
+		Lines: nonEmpty(settings.CompanyAddress, settings.CompanyPhone, settings.CompanyEmail),
+	}
+	if doc.Issuer.Name == "" {
+		if tenant, err := s.tenantRepo.FindByID(ctx, tenantID); err == nil && tenant != nil {
+			doc.Issuer.Name = tenant.Name
+		}
+	}
+	doc.Footer = settings.InvoiceFooter
+	if settings.CompanyLogo != "" {
+		logo, err := s.fetchLogo(ctx, settings.CompanyLogo)
+		if err != nil {
+			logger.Warn("Document: skipping logo of tenant %s: %v", tenantID, err)
+		} else {
+			doc.Logo = logo
+		}
+	}
+
+	content, err := pdf.RenderInvoice(doc)
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+
+	number := invoiceNumberOf(payment)
+	if number == "" {
+		number = payment.ID
+	}
+	return &RenderedDocument{
+		FileName: fmt.Sprintf("%s-%s.pdf", docType, number),
+		Content:  content,
+	}, nil
+}
+
+func customerBillTo(payment *entity.Payment) pdf.Party {
+	if payment.Customer == nil {
+		return pdf.Party{}
+	}
+	c := payment.Customer
+	return pdf.Party{Name: c.Name, Lines: nonEmpty(c.CustomerCode, c.Address, c.Phone, c.Email)}
+}
+
+func customerInvoiceDocument(payment *entity.Payment) *pdf.Invoice {
+	doc := &pdf.Invoice{
+		Title:  "INVOICE",
+		Number: invoiceNumberOf(payment),
+		BillTo: customerBillTo(payment),
+		Details: []pdf.Detail{
+			{Label: "Invoice Date", Value: payment.CreatedAt.Format(documentDateFormat)},
+			{Label: "Due Date", Value: payment.DueDate.Format(documentDateFormat)},
+		},
+		Notes: payment.Notes,
+	}
+	if payment.PeriodStart != nil && payment.PeriodEnd != nil {
+		doc.Details = append(doc.Details, pdf.Detail{
+			Label: "Period",
+			Value: payment.PeriodStart.Format(documentDateFormat) + " - " + payment.PeriodEnd.Format(documentDateFormat),
+		})
+	}
+	doc.Details = append(doc.Details, pdf.Detail{Label: "Status", Value: strings.ToUpper(payment.Status)})
+
+	for _, item := range payment.Items {
+		// A waived late fee is no longer owed
+		if item.Type == entity.InvoiceItemLateFee && payment.LateFeeWaivedAt != nil {
+			continue
+		}
+		doc.Lines = append(doc.Lines, pdf.InvoiceLine{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      item.Amount,
+		})
+	}
+
+	doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Subtotal", Amount: payment.Subtotal})
+	if payment.DiscountAmount > 0 {
+		doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Discount", Amount: -payment.DiscountAmount})
+	}
+	if payment.TaxAmount > 0 {
+		doc.Totals = append(doc.Totals, pdf.InvoiceTotal{
+			Label:  fmt.Sprintf("Tax (%g%%)", payment.TaxPercentage),
+			Amount: payment.TaxAmount,
+		})
+	}
+	if fee := payment.LateFeeDue(); fee > 0 {
+		doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Late Fee", Amount: fee})
+	}
+	doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Total", Amount: payment.Total(), Bold: true})
+	if payment.CreditedAmount > 0 {
+		doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Credit Notes", Amount: -payment.CreditedAmount})
+	}
+	if payment.PaidAmount > 0 || payment.CreditedAmount > 0 {
+		doc.Totals = append(doc.Totals,
+			pdf.InvoiceTotal{Label: "Paid", Amount: payment.PaidAmount},
+			pdf.InvoiceTotal{Label: "Balance Due", Amount: payment.Balance(), Bold: true},
+		)
+	}
+
+	switch payment.Status {
+	case entity.PaymentStatusPaid:
+		doc.Stamp = "PAID"
+	case entity.PaymentStatusOverdue:
+		doc.Stamp = "OVERDUE"
+	}
+	return doc
+}
+
+func customerReceiptDocument(payment *entity.Payment) *pdf.Invoice {
+	doc := &pdf.Invoice{
+		Title:  "RECEIPT",
+		Number: invoiceNumberOf(payment),
+		BillTo: customerBillTo(payment),
+		Details: []pdf.Detail{
+			{Label: "Invoice Date", Value: payment.CreatedAt.Format(documentDateFormat)},
+			{Label: "Invoice Total", Value: pdf.FormatRupiah(payment.Total())},
+		},
+	}
+	if payment.PaymentDate != nil {
+		doc.Details = append(doc.Details, pdf.Detail{Label: "Paid On", Value: payment.PaymentDate.Format(documentDateFormat)})
+	}
+
+	for _, allocation := range payment.Allocations {
+		description := fmt.Sprintf("Payment %s via %s", allocation.PaidAt.Format(documentDateFormat), allocation.PaymentMethod)
+		if allocation.Reference != "" {
+			description += " (ref. " + allocation.Reference + ")"
+		}
+		doc.Lines = append(doc.Lines, pdf.InvoiceLine{
+			Description: description,
+			Quantity:    1,
+			UnitPrice:   allocation.Amount,
+			Amount:      allocation.Amount,
+		})
+	}
+
+	for _, note := range payment.CreditNotes {
+		if note.RefundAmount <= 0 {
+			continue
+		}
+		doc.Lines = append(doc.Lines, pdf.InvoiceLine{
+			Description: fmt.Sprintf("Refund %s via %s (%s)", note.CreatedAt.Format(documentDateFormat), note.RefundMethod, note.CreditNoteNumber),
+			Quantity:    1,
+			UnitPrice:   -note.RefundAmount,
+			Amount:      -note.RefundAmount,
+		})
+	}
+
+	doc.Totals = []pdf.InvoiceTotal{{Label: "Invoice Total", Amount: payment.Total()}}
+	if payment.CreditedAmount > 0 {
+		doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Credit Notes", Amount: -payment.CreditedAmount})
+	}
+	doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Amount Received", Amount: payment.PaidAmount, Bold: true})
+	if balance := payment.Balance(); balance > 0 {
+		doc.Totals = append(doc.Totals, pdf.InvoiceTotal{Label: "Balance Due", Amount: balance})
+		doc.Stamp = "PARTIALLY PAID"
+	} else {
+		doc.Stamp = "PAID"
+	}
+	return doc
+}
+
+func (s *documentService) RenderTransactionPDF(ctx context.Context, tenantID, transactionID string) (*RenderedDocument, error) {
+	tx, err := s.transactionRepo.FindByID(ctx, transactionID)
+	if err != nil || tx == nil {
+		return nil, errors.ErrNotFound
+	}
+	if tx.TenantID != tenantID {
+		return nil, errors.ErrUnauthorized
+	}
+
+	tenant, err := s.tenantRepo.FindByID(ctx, tenantID)
+	if err != nil || tenant == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	description := "Subscription payment"
+	if tx.PlanID != nil {
+		if plan, err := s.planRepo.FindByID(ctx, *tx.PlanID); err == nil && plan != nil {
+			description = "Subscription - " + plan.Name
+		}
+	}
+
+	number := transactionInvoiceNumber(tx)
+	doc := &pdf.Invoice{
+		Title:  "INVOICE",
+		Number: number,
+		Issuer: pdf.Party{Name: s.platform.Name, Lines: nonEmpty(s.platform.Address, s.platform.Email)},
+		BillTo: pdf.Party{Name: tenant.Name, Lines: nonEmpty(tenant.Email)},
+		Details: []pdf.Detail{
+			{Label: "Invoice Date", Value: tx.CreatedAt.Format(documentDateFormat)},
+		},
+		Lines:  []pdf.InvoiceLine{{Description: description, Quantity: 1, UnitPrice: tx.Amount, Amount: tx.Amount}},
+		Totals: []pdf.InvoiceTotal{{Label: "Total", Amount: tx.Amount, Bold: true}},
+	}
+	if tx.ExpiredAt != nil {
+		doc.Details = append(doc.Details, pdf.Detail{Label: "Due Date", Value: tx.ExpiredAt.Format(documentDateFormat)})
+	}
+	if tx.PaidAt != nil {
+		doc.Details = append(doc.Details, pdf.Detail{Label: "Paid On", Value: tx.PaidAt.Format(documentDateFormat)})
+	}
+	if tx.PaymentMethod != "" {
+		doc.Details = append(doc.Details, pdf.Detail{Label: "Payment Method", Value: tx.PaymentMethod})
+	}
+	doc.Details = append(doc.Details, pdf.Detail{Label: "Status", Value: strings.ToUpper(tx.Status)})
+	if tx.Status == entity.TransactionStatusPaid {
+		doc.Stamp = "PAID"
+	}
+
+	content, err := pdf.RenderInvoice(doc)
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	return &RenderedDocument{FileName: "invoice-" + number + ".pdf", Content: content}, nil
+}
+
+// transactionInvoiceNumber is the invoice number shown for a subscription
+// payment: its order ID, or a short form of its ID
+func transactionInvoiceNumber(tx *entity.PaymentTransaction) string {
+	if tx.OrderID != "" {
+		return tx.OrderID
+	}
+	if len(tx.ID) > 8 {
+		return "INV-" + tx.ID[:8]
+	}
+	return "INV-" + tx.ID
+}
+
+func nonEmpty(values ...string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,301 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTenantRepository only implements FindByID and FindAll
+type MockTenantRepository struct {
+	repository.TenantRepository
+	mock.Mock
+}
+
+func (m *MockTenantRepository) FindByID(ctx context.Context, id string) (*entity.Tenant, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) FindAll(ctx context.Context) ([]*entity.Tenant, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.Tenant), args.Error(1)
+}
+
+// MockPaymentTransactionRepository only implements the lookups, Create, Update and AddRefund
+type MockPaymentTransactionRepository struct {
+	repository.PaymentTransactionRepository
+	mock.Mock
+}
+
+func (m *MockPaymentTransactionRepository) FindByID(ctx context.Context, id string) (*entity.PaymentTransaction, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) FindByOrderID(ctx context.Context, orderID string) (*entity.PaymentTransaction, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) FindByTenantID(ctx context.Context, tenantID string) ([]*entity.PaymentTransaction, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]*entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) Create(ctx context.Context, transaction *entity.PaymentTransaction) error {
+	args := m.Called(ctx, transaction)
+	return args.Error(0)
+}
+
+func (m *MockPaymentTransactionRepository) Update(ctx context.Context, transaction *entity.PaymentTransaction) error {
+	args := m.Called(ctx, transaction)
+	return args.Error(0)
+}
+
+func (m *MockPaymentTransactionRepository) AddRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error) {
+	args := m.Called(ctx, refund)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) ReserveRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) error {
+	args := m.Called(ctx, refund)
+	return args.Error(0)
+}
+
+func (m *MockPaymentTransactionRepository) CompleteRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) (*entity.PaymentTransaction, error) {
+	args := m.Called(ctx, refund)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) FailRefund(ctx context.Context, refund *entity.PaymentTransactionRefund) error {
+	args := m.Called(ctx, refund)
+	return args.Error(0)
+}
+
+func documentInvoice() *entity.Payment {
+	number := "NET-000012"
+	return &entity.Payment{
+		ID:             "p1",
+		TenantID:       "tenant-1",
+		CustomerID:     "c1",
+		InvoiceNumber:  &number,
+		Subtotal:       200000,
+		DiscountAmount: 20000,
+		TaxPercentage:  11,
+		TaxAmount:      19800,
+		Amount:         199800,
+		LateFee:        10000,
+		DueDate:        billingDate(2025, 3, 10),
+		Status:         entity.PaymentStatusOverdue,
+		CreatedAt:      billingDate(2025, 3, 1),
+		Customer:       &entity.Customer{Name: "Budi Santoso", CustomerCode: "CUST-001", Address: "Jl. Melati 5"},
+		Items: []entity.InvoiceItem{
+			{Type: entity.InvoiceItemMonthlyFee, Description: "Paket 20 Mbps", Quantity: 1, UnitPrice: 200000, Amount: 200000},
+			{Type: entity.InvoiceItemDiscount, Description: "Promo", Quantity: 1, UnitPrice: -20000, Amount: -20000},
+			{Type: entity.InvoiceItemLateFee, Description: "Late fee", Quantity: 1, UnitPrice: 10000, Amount: 10000},
+		},
+	}
+}
+
+func TestCustomerInvoiceDocument(t *testing.T) {
+	payment := documentInvoice()
+
+	doc := customerInvoiceDocument(payment)
+	assert.Equal(t, "NET-000012", doc.Number)
+	assert.Equal(t, "Budi Santoso", doc.BillTo.Name)
+	assert.Len(t, doc.Lines, 3)
+	assert.Equal(t, "OVERDUE", doc.Stamp)
+
+	labels := make([]string, 0, len(doc.Totals))
+	for _, total := range doc.Totals {
+		labels = append(labels, total.Label)
+	}
+	assert.Equal(t, []string{"Subtotal", "Discount", "Tax (11%)", "Late Fee", "Total"}, labels)
+	assert.Equal(t, 209800.0, doc.Totals[4].Amount)
+
+	t.Run("Waived Late Fee Is Left Out", func(t *testing.T) {
+		waivedAt := time.Now()
+		payment.LateFeeWaivedAt = &waivedAt
+
+		doc := customerInvoiceDocument(payment)
+		assert.Len(t, doc.Lines, 2)
+		assert.Equal(t, 199800.0, doc.Totals[len(doc.Totals)-1].Amount)
+	})
+}
+
+func TestCustomerReceiptDocument(t *testing.T) {
+	payment := documentInvoice()
+	payment.PaidAmount = 100000
+	payment.Allocations = []entity.PaymentAllocation{
+		{Amount: 100000, PaymentMethod: "transfer", Reference: "BCA-991", PaidAt: billingDate(2025, 3, 5)},
+	}
+
+	doc := customerReceiptDocument(payment)
+	assert.Equal(t, "RECEIPT", doc.Title)
+	require.Len(t, doc.Lines, 1)
+	assert.Equal(t, "Payment 05/03/2025 via transfer (ref. BCA-991)", doc.Lines[0].Description)
+	assert.Equal(t, "PARTIALLY PAID", doc.Stamp)
+	assert.Equal(t, 109800.0, doc.Totals[len(doc.Totals)-1].Amount)
+}
+
+func TestDocumentService_RenderPaymentPDF(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	settings := &entity.TenantSettings{
+		CompanyName:    "Net Desa",
+		CompanyAddress: "Jl. Merdeka 10",
+		CompanyLogo:    "https://cdn.example.com/logo.png",
+		InvoiceFooter:  "Terima kasih",
+	}
+
+	newService := func(payment *entity.Payment) (*documentService, *MockInvoiceRepository) {
+		repo := new(MockInvoiceRepository)
+		settingsRepo := new(MockSettingsRepository)
+		repo.On("FindInvoice", ctx, "p1").Return(payment, nil)
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+
+		service := NewDocumentService(repo, settingsRepo, nil, nil, nil, PlatformInfo{}).(*documentService)
+		service.fetchLogo = func(ctx context.Context, url string) (image.Image, error) {
+			return image.NewRGBA(image.Rect(0, 0, 10, 10)), nil
+		}
+		return service, repo
+	}
+
+	t.Run("Renders Invoice With Company Details", func(t *testing.T) {
+		service, _ := newService(documentInvoice())
+
+		doc, err := service.RenderPaymentPDF(ctx, tenantID, "p1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "invoice-NET-000012.pdf", doc.FileName)
+		assert.Contains(t, string(doc.Content), "(Net Desa) Tj")
+		assert.Contains(t, string(doc.Content), "(Terima kasih) Tj")
+		assert.Contains(t, string(doc.Content), "/Im1 Do")
+	})
+
+	t.Run("Defaults To Receipt When Paid", func(t *testing.T) {
+		payment := documentInvoice()
+		payment.Status = entity.PaymentStatusPaid
+		payment.PaidAmount = payment.Total()
+		payment.Allocations = []entity.PaymentAllocation{{Amount: payment.Total(), PaymentMethod: "cash", PaidAt: time.Now()}}
+		service, _ := newService(payment)
+
+		doc, err := service.RenderPaymentPDF(ctx, tenantID, "p1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "receipt-NET-000012.pdf", doc.FileName)
+		assert.Contains(t, string(doc.Content), "(RECEIPT) Tj")
+	})
+
+	t.Run("Skips Logo That Cannot Be Fetched", func(t *testing.T) {
+		service, _ := newService(documentInvoice())
+		service.fetchLogo = func(ctx context.Context, url string) (image.Image, error) {
+			return nil, fmt.Errorf("timeout")
+		}
+
+		doc, err := service.RenderPaymentPDF(ctx, tenantID, "p1", DocumentTypeInvoice)
+		require.NoError(t, err)
+		assert.NotContains(t, string(doc.Content), "/Im1 Do")
+	})
+
+	t.Run("Receipt Needs Payments", func(t *testing.T) {
+		service, _ := newService(documentInvoice())
+
+		_, err := service.RenderPaymentPDF(ctx, tenantID, "p1", DocumentTypeReceipt)
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects Unknown Type", func(t *testing.T) {
+		service, _ := newService(documentInvoice())
+
+		_, err := service.RenderPaymentPDF(ctx, tenantID, "p1", "quote")
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects Other Tenant", func(t *testing.T) {
+		service, _ := newService(documentInvoice())
+
+		_, err := service.RenderPaymentPDF(ctx, "tenant-2", "p1", "")
+		assert.Equal(t, errors.ErrUnauthorized, err)
+	})
+}
+
+func TestFetchLogo(t *testing.T) {
+	ctx := context.Background()
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logo.png" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+			return
+		}
+		redirected = true
+	}))
+	defer server.Close()
+
+	t.Run("Refuses Private Address", func(t *testing.T) {
+		_, err := newLogoFetcher(webhook.NewRestrictedHTTPClient(time.Second, false))(ctx, server.URL+"/logo.png")
+		assert.ErrorIs(t, err, webhook.ErrPrivateAddress)
+	})
+
+	t.Run("Does Not Follow Redirects", func(t *testing.T) {
+		_, err := newLogoFetcher(webhook.NewRestrictedHTTPClient(time.Second, true))(ctx, server.URL+"/logo.png")
+		assert.Error(t, err)
+		assert.False(t, redirected)
+	})
+
+	t.Run("Refuses Other Schemes", func(t *testing.T) {
+		_, err := newLogoFetcher(webhook.NewRestrictedHTTPClient(time.Second, true))(ctx, "file:///etc/passwd")
+		assert.Error(t, err)
+	})
+}
+
+func TestDocumentService_RenderTransactionPDF(t *testing.T) {
+	ctx := context.Background()
+	paidAt := billingDate(2025, 3, 2)
+
+	tenantRepo := new(MockTenantRepository)
+	transactionRepo := new(MockPaymentTransactionRepository)
+	service := NewDocumentService(nil, nil, tenantRepo, nil, transactionRepo, PlatformInfo{Name: "RTRWNet", Email: "billing@rtrwnet.com"})
+
+	transactionRepo.On("FindByID", ctx, "tx-1").Return(&entity.PaymentTransaction{
+		ID: "tx-1", TenantID: "tenant-1", OrderID: "ORDER-123", Amount: 299000,
+		Status: entity.TransactionStatusPaid, PaidAt: &paidAt, CreatedAt: billingDate(2025, 3, 1),
+	}, nil)
+	tenantRepo.On("FindByID", ctx, "tenant-1").Return(&entity.Tenant{ID: "tenant-1", Name: "Net Desa", Email: "owner@netdesa.id"}, nil)
+
+	doc, err := service.RenderTransactionPDF(ctx, "tenant-1", "tx-1")
+	require.NoError(t, err)
+	assert.Equal(t, "invoice-ORDER-123.pdf", doc.FileName)
+	assert.Contains(t, string(doc.Content), "(RTRWNet) Tj")
+	assert.Contains(t, string(doc.Content), "(Subscription payment) Tj")
+	assert.Contains(t, string(doc.Content), "(PAID) Tj")
+
+	_, err = service.RenderTransactionPDF(ctx, "tenant-2", "tx-1")
+	assert.Equal(t, errors.ErrUnauthorized, err)
+}
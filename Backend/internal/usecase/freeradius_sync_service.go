@@ -56,9 +56,9 @@ func (s *FreeRADIUSSyncService) SyncRadiusUser(user *entity.RadiusUser) error {
 		return fmt.Errorf("failed to insert password to radcheck: %w", err)
 	}
 
-	// Check expiration
-	if user.ExpireDate != nil && time.Now().After(*user.ExpireDate) {
-		// User expired, add Expiration attribute to reject
+	// FreeRADIUS rejects the user from ExpireDate on and limits sessions
+	// started before it to the time left
+	if user.ExpireDate != nil {
 		if err := tx.Exec(`
 			INSERT INTO radcheck (username, attribute, op, value, is_active, tenant_id)
 			VALUES (?, 'Expiration', ':=', ?, true, ?)
@@ -84,14 +84,26 @@ func (s *FreeRADIUSSyncService) SyncRadiusUser(user *entity.RadiusUser) error {
 		var customer entity.Customer
 		if err := tx.Preload("ServicePlan").Where("id = ?", *user.CustomerID).First(&customer).Error; err == nil {
 			if customer.ServicePlan != nil {
+				// A running speed boost shapes the user at the boost plan's
+				// speed until it ends
+				ratePlan := customer.ServicePlan
+				var boosts []entity.SpeedBoost
+				if err := tx.Preload("BoostPlan").
+					Where("customer_id = ? AND status = ? AND end_date > ?", customer.ID, entity.SpeedBoostStatusActive, time.Now()).
+					Order("end_date DESC").
+					Limit(1).
+					Find(&boosts).Error; err == nil && len(boosts) > 0 && boosts[0].BoostPlan != nil {
+					ratePlan = boosts[0].BoostPlan
+				}
+
 				// Add MikroTik rate limit
-				downloadKbps := customer.ServicePlan.SpeedDownload * 1000
-				uploadKbps := customer.ServicePlan.SpeedUpload * 1000
+				downloadKbps := ratePlan.SpeedDownload * 1000
+				uploadKbps := ratePlan.SpeedUpload * 1000
 				rateLimit := fmt.Sprintf("%dk/%dk", uploadKbps, downloadKbps)
 
 				// Check for burst settings
 				var advSettings entity.ServicePlanAdvancedSettings
-				if err := tx.Where("service_plan_id = ?", customer.ServicePlan.ID).First(&advSettings).Error; err == nil {
+				if err := tx.Where("service_plan_id = ?", ratePlan.ID).First(&advSettings).Error; err == nil {
 					if advSettings.BurstEnabled && advSettings.BurstLimit > 0 {
 						burstKbps := advSettings.BurstLimit * 1000
 						thresholdKbps := advSettings.BurstThreshold * 1000
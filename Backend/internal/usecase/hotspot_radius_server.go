@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// radacctHotspotServer implements RadiusServerInterface on top of the
+// FreeRADIUS accounting table. Sessions are matched to the tenant through
+// hotspot_vouchers because FreeRADIUS does not fill radacct.tenant_id.
+type radacctHotspotServer struct {
+	db         *gorm.DB
+	coaService RadiusCoAService
+}
+
+// hotspotSessionRow is an open radacct session joined with its voucher
+type hotspotSessionRow struct {
+	AcctSessionID    string     `gorm:"column:acctsessionid"`
+	Username         string     `gorm:"column:username"`
+	FramedIPAddress  string     `gorm:"column:framedipaddress"`
+	CallingStationID string     `gorm:"column:callingstationid"`
+	NASIPAddress     string     `gorm:"column:nasipaddress"`
+	AcctStartTime    *time.Time `gorm:"column:acctstarttime"`
+	InputOctets      int64      `gorm:"column:acctinputoctets"`
+	OutputOctets     int64      `gorm:"column:acctoutputoctets"`
+	PackageName      string     `gorm:"column:package_name"`
+	ExpiresAt        *time.Time `gorm:"column:expires_at"`
+}
+
+// expiredVoucherSession is an open session of a voucher past its expiry
+type expiredVoucherSession struct {
+	TenantID      string `gorm:"column:tenant_id"`
+	Username      string `gorm:"column:username"`
+	AcctSessionID string `gorm:"column:acctsessionid"`
+}
+
+// NewRadacctHotspotServer creates a RadiusServerInterface backed by radacct
+func NewRadacctHotspotServer(db *gorm.DB, coaService RadiusCoAService) RadiusServerInterface {
+	return &radacctHotspotServer{
+		db:         db,
+		coaService: coaService,
+	}
+}
+
+func (s *radacctHotspotServer) GetHotspotActiveSessions(ctx context.Context, tenantID string) ([]*entity.HotspotSession, error) {
+	var rows []hotspotSessionRow
+	err := s.sessionQuery(ctx, tenantID).
+		Order("a.acctstarttime DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, errors.NewDatabaseError("get hotspot sessions", err)
+	}
+
+	now := time.Now()
+	sessions := make([]*entity.HotspotSession, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, row.toSession(now))
+	}
+	return sessions, nil
+}
+
+func (s *radacctHotspotServer) DisconnectHotspotSession(ctx context.Context, tenantID, sessionID string) error {
+	var row hotspotSessionRow
+	result := s.sessionQuery(ctx, tenantID).
+		Where("a.acctsessionid = ?", sessionID).
+		Limit(1).
+		Scan(&row)
+	if result.Error != nil {
+		return errors.NewDatabaseError("get hotspot session", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("session not found")
+	}
+
+	return s.coaService.DisconnectSession(ctx, tenantID, row.Username, row.AcctSessionID)
+}
+
+// CheckExpiredHotspotSessions removes expired vouchers from radcheck/radreply
+// so they cannot log in again, then disconnects their open sessions. Vouchers
+// already flipped to expired are included so a NAS that was unreachable on the
+// previous run is retried.
+func (s *radacctHotspotServer) CheckExpiredHotspotSessions(ctx context.Context) error {
+	now := time.Now()
+	statuses := []string{entity.VoucherStatusActive, entity.VoucherStatusExpired}
+
+	for _, table := range []string{"radcheck", "radreply"} {
+		err := s.db.WithContext(ctx).Exec(fmt.Sprintf(`
+			DELETE FROM %s r
+			USING hotspot_vouchers v
+			WHERE r.username = v.voucher_code
+			  AND r.tenant_id = v.tenant_id
+			  AND v.expires_at IS NOT NULL AND v.expires_at < ?
+			  AND v.status IN ?
+		`, table), now, statuses).Error
+		if err != nil {
+			return fmt.Errorf("failed to revoke expired vouchers from %s: %w", table, err)
+		}
+	}
+
+	var expired []expiredVoucherSession
+	err := s.db.WithContext(ctx).
+		Table("radacct AS a").
+		Select("v.tenant_id, a.username, a.acctsessionid").
+		Joins("JOIN hotspot_vouchers v ON v.voucher_code = a.username").
+		Where("a.acctstoptime IS NULL").
+		Where("v.expires_at IS NOT NULL AND v.expires_at < ?", now).
+		Where("v.status IN ?", statuses).
+		// Voucher codes are unique per tenant only; never kick a valid voucher
+		// of another tenant that happens to share the code
+		Where(`NOT EXISTS (
+			SELECT 1 FROM hotspot_vouchers o
+			WHERE o.voucher_code = a.username AND o.status = ?
+			  AND (o.expires_at IS NULL OR o.expires_at >= ?))`, entity.VoucherStatusActive, now).
+		Scan(&expired).Error
+	if err != nil {
+		return fmt.Errorf("failed to load expired hotspot sessions: %w", err)
+	}
+
+	disconnected := 0
+	for _, sess := range expired {
+		if err := s.coaService.DisconnectSession(ctx, sess.TenantID, sess.Username, sess.AcctSessionID); err != nil {
+			logger.Warn("Failed to disconnect expired voucher %s (session %s): %v", sess.Username, sess.AcctSessionID, err)
+			continue
+		}
+		disconnected++
+	}
+
+	if len(expired) > 0 {
+		logger.Info("Expired hotspot sessions: %d found, %d disconnected", len(expired), disconnected)
+	}
+	return nil
+}
+
+// sessionQuery selects the tenant's open voucher sessions
+func (s *radacctHotspotServer) sessionQuery(ctx context.Context, tenantID string) *gorm.DB {
+	return s.db.WithContext(ctx).
+		Table("radacct AS a").
+		Select(`a.acctsessionid, a.username,
+			COALESCE(host(a.framedipaddress), '') AS framedipaddress,
+			a.callingstationid,
+			host(a.nasipaddress) AS nasipaddress,
+			a.acctstarttime,
+			COALESCE(a.acctinputoctets, 0) AS acctinputoctets,
+			COALESCE(a.acctoutputoctets, 0) AS acctoutputoctets,
+			COALESCE(p.name, '') AS package_name,
+			v.expires_at`).
+		Joins("JOIN hotspot_vouchers v ON v.voucher_code = a.username AND v.tenant_id = ?", tenantID).
+		Joins("LEFT JOIN hotspot_packages p ON p.id = v.package_id").
+		Where("a.acctstoptime IS NULL")
+}
+
+func (r hotspotSessionRow) toSession(now time.Time) *entity.HotspotSession {
+	session := &entity.HotspotSession{
+		SessionID:    r.AcctSessionID,
+		Username:     r.Username,
+		IPAddress:    r.FramedIPAddress,
+		MACAddress:   r.CallingStationID,
+		NASIPAddress: r.NASIPAddress,
+		// Acct-Input-Octets is what the NAS received from the user
+		UploadBytes:   r.InputOctets,
+		DownloadBytes: r.OutputOctets,
+		PackageName:   r.PackageName,
+		Status:        entity.SessionStatusActive,
+	}
+	if r.AcctStartTime != nil {
+		session.StartTime = *r.AcctStartTime
+		session.Duration = int(now.Sub(*r.AcctStartTime).Seconds())
+	}
+	if r.ExpiresAt != nil && now.After(*r.ExpiresAt) {
+		session.Status = entity.SessionStatusExpired
+	}
+	return session
+}
@@ -26,8 +26,8 @@ type hotspotSessionService struct {
 
 // RadiusServerInterface defines the interface for RADIUS server operations
 type RadiusServerInterface interface {
-	GetHotspotActiveSessions(tenantID string) []*entity.HotspotSession
-	DisconnectHotspotSession(sessionID string) error
+	GetHotspotActiveSessions(ctx context.Context, tenantID string) ([]*entity.HotspotSession, error)
+	DisconnectHotspotSession(ctx context.Context, tenantID, sessionID string) error
 	CheckExpiredHotspotSessions(ctx context.Context) error
 }
 
@@ -49,7 +49,10 @@ func (s *hotspotSessionService) GetActiveSessions(ctx context.Context, tenantID
 		return []*entity.HotspotSession{}, nil
 	}
 
-	sessions := s.radiusServer.GetHotspotActiveSessions(tenantID)
+	sessions, err := s.radiusServer.GetHotspotActiveSessions(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active sessions: %w", err)
+	}
 	return sessions, nil
 }
 
@@ -59,7 +62,10 @@ func (s *hotspotSessionService) DisconnectSession(ctx context.Context, tenantID,
 	}
 
 	// Verify session belongs to tenant
-	sessions := s.radiusServer.GetHotspotActiveSessions(tenantID)
+	sessions, err := s.radiusServer.GetHotspotActiveSessions(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to get active sessions: %w", err)
+	}
 	found := false
 	for _, session := range sessions {
 		if session.SessionID == sessionID {
@@ -72,7 +78,7 @@ func (s *hotspotSessionService) DisconnectSession(ctx context.Context, tenantID,
 		return errors.NewNotFoundError("session not found or does not belong to this tenant")
 	}
 
-	if err := s.radiusServer.DisconnectHotspotSession(sessionID); err != nil {
+	if err := s.radiusServer.DisconnectHotspotSession(ctx, tenantID, sessionID); err != nil {
 		return fmt.Errorf("failed to disconnect session: %w", err)
 	}
 
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRadiusServer struct {
+	mock.Mock
+}
+
+func (m *MockRadiusServer) GetHotspotActiveSessions(ctx context.Context, tenantID string) ([]*entity.HotspotSession, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.HotspotSession), args.Error(1)
+}
+
+func (m *MockRadiusServer) DisconnectHotspotSession(ctx context.Context, tenantID, sessionID string) error {
+	args := m.Called(ctx, tenantID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockRadiusServer) CheckExpiredHotspotSessions(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// MockExpiringVoucherRepository only implements what CheckExpiredSessions uses
+type MockExpiringVoucherRepository struct {
+	repository.HotspotVoucherRepository
+	mock.Mock
+}
+
+func (m *MockExpiringVoucherRepository) UpdateExpiredVouchers(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func TestHotspotSessionService_DisconnectSession(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	sessions := []*entity.HotspotSession{{SessionID: "81a00004", Username: "VC1234"}}
+
+	t.Run("Success", func(t *testing.T) {
+		radiusServer := new(MockRadiusServer)
+		service := NewHotspotSessionService(nil, nil, radiusServer)
+
+		radiusServer.On("GetHotspotActiveSessions", ctx, tenantID).Return(sessions, nil)
+		radiusServer.On("DisconnectHotspotSession", ctx, tenantID, "81a00004").Return(nil)
+
+		err := service.DisconnectSession(ctx, tenantID, "81a00004")
+
+		assert.NoError(t, err)
+		radiusServer.AssertExpectations(t)
+	})
+
+	t.Run("Session Of Another Tenant", func(t *testing.T) {
+		radiusServer := new(MockRadiusServer)
+		service := NewHotspotSessionService(nil, nil, radiusServer)
+
+		radiusServer.On("GetHotspotActiveSessions", ctx, tenantID).Return(sessions, nil)
+
+		err := service.DisconnectSession(ctx, tenantID, "81a00099")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "session not found")
+		radiusServer.AssertNotCalled(t, "DisconnectHotspotSession", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHotspotSessionService_CheckExpiredSessions(t *testing.T) {
+	ctx := context.Background()
+	radiusServer := new(MockRadiusServer)
+	voucherRepo := new(MockExpiringVoucherRepository)
+	service := NewHotspotSessionService(voucherRepo, nil, radiusServer)
+
+	radiusServer.On("CheckExpiredHotspotSessions", ctx).Return(nil)
+	voucherRepo.On("UpdateExpiredVouchers", ctx).Return(nil)
+
+	err := service.CheckExpiredSessions(ctx)
+
+	assert.NoError(t, err)
+	radiusServer.AssertExpectations(t)
+	voucherRepo.AssertExpectations(t)
+}
+
+func TestHotspotSessionRow_ToSession(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	start := now.Add(-90 * time.Minute)
+	expires := now.Add(-time.Minute)
+
+	row := hotspotSessionRow{
+		AcctSessionID:    "81a00004",
+		Username:         "VC1234",
+		FramedIPAddress:  "10.5.50.12",
+		CallingStationID: "AA:BB:CC:DD:EE:FF",
+		NASIPAddress:     "192.168.88.1",
+		AcctStartTime:    &start,
+		InputOctets:      1000,
+		OutputOctets:     5000,
+		PackageName:      "Paket 1 Jam",
+	}
+
+	session := row.toSession(now)
+	assert.Equal(t, "81a00004", session.SessionID)
+	assert.Equal(t, 5400, session.Duration)
+	assert.Equal(t, int64(1000), session.UploadBytes)
+	assert.Equal(t, int64(5000), session.DownloadBytes)
+	assert.Equal(t, "Paket 1 Jam", session.PackageName)
+	assert.Equal(t, entity.SessionStatusActive, session.Status)
+
+	row.ExpiresAt = &expires
+	assert.Equal(t, entity.SessionStatusExpired, row.toSession(now).Status)
+}
@@ -5,12 +5,14 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/rtrwnet/saas-backend/internal/domain/repository"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -28,6 +30,7 @@ type hotspotVoucherService struct {
 	voucherRepo    repository.HotspotVoucherRepository
 	packageRepo    repository.HotspotPackageRepository
 	freeradiusSync *FreeRADIUSSyncService
+	templates      NotificationTemplateService
 }
 
 // NewHotspotVoucherService creates a new instance of hotspot voucher service
@@ -35,11 +38,13 @@ func NewHotspotVoucherService(
 	voucherRepo repository.HotspotVoucherRepository,
 	packageRepo repository.HotspotPackageRepository,
 	freeradiusSync *FreeRADIUSSyncService,
+	templates NotificationTemplateService,
 ) HotspotVoucherService {
 	return &hotspotVoucherService{
 		voucherRepo:    voucherRepo,
 		packageRepo:    packageRepo,
 		freeradiusSync: freeradiusSync,
+		templates:      templates,
 	}
 }
 
@@ -92,9 +97,11 @@ func (s *hotspotVoucherService) GenerateVouchers(ctx context.Context, tenantID s
 
 	// Generate vouchers
 	vouchers := make([]*entity.HotspotVoucher, req.Quantity)
+	passwords := make([]string, req.Quantity)
 	for i := 0; i < req.Quantity; i++ {
 		code := generateVoucherCode(req.Prefix)
 		password := generateVoucherPassword()
+		passwords[i] = password
 		hashedPassword, err := hashPassword(password)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password: %w", err)
@@ -115,13 +122,38 @@ func (s *hotspotVoucherService) GenerateVouchers(ctx context.Context, tenantID s
 	}
 
 	// Load package info for response
-	for _, v := range vouchers {
+	for i, v := range vouchers {
 		v.Package = pkg
+		v.Message = s.soldMessage(ctx, tenantID, v, passwords[i])
 	}
 
 	return vouchers, nil
 }
 
+// soldMessage renders the tenant's voucher_sold WhatsApp text of a voucher.
+// The voucher is returned without one when rendering fails.
+func (s *hotspotVoucherService) soldMessage(ctx context.Context, tenantID string, voucher *entity.HotspotVoucher, password string) string {
+	if s.templates == nil {
+		return ""
+	}
+	vars := TemplateVars{
+		"voucher_code":     voucher.VoucherCode,
+		"voucher_password": password,
+		"package_name":     voucher.Package.Name,
+		"duration":         strconv.Itoa(voucher.Package.Duration),
+		"duration_type":    voucher.Package.DurationType,
+	}
+	if voucher.Package.Price > 0 {
+		vars["price"] = float64(voucher.Package.Price)
+	}
+	msg, err := s.templates.RenderTenant(ctx, tenantID, entity.NotificationEventVoucherSold, entity.NotificationChannelWhatsApp, vars)
+	if err != nil {
+		logger.Error("Failed to render voucher_sold message for voucher %s: %v", voucher.VoucherCode, err)
+		return ""
+	}
+	return msg.Body
+}
+
 func (s *hotspotVoucherService) ListVouchers(ctx context.Context, tenantID string, filters map[string]interface{}, page, perPage int) ([]*entity.HotspotVoucher, int, error) {
 	if page < 1 {
 		page = 1
@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// InvoiceGeneratorService creates the recurring monthly invoices of
+// customers according to the tenant's billing settings
+type InvoiceGeneratorService interface {
+	// GenerateInvoices runs the generator for one tenant as of the given day.
+	// With dryRun the invoices are planned and recorded but not created.
+	GenerateInvoices(ctx context.Context, tenantID string, asOf time.Time, triggeredBy string, dryRun bool) (*InvoiceRunResult, error)
+	// GenerateAllTenants runs the scheduled generator for every active tenant
+	GenerateAllTenants(ctx context.Context, asOf time.Time) error
+	ListInvoiceRuns(ctx context.Context, tenantID string, limit int) ([]*entity.InvoiceRun, error)
+}
+
+type invoiceGeneratorService struct {
+	invoiceRunRepo repository.InvoiceRunRepository
+	settingsRepo   repository.SettingsRepository
+	tenantRepo     repository.TenantRepository
+}
+
+func NewInvoiceGeneratorService(
+	invoiceRunRepo repository.InvoiceRunRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+) InvoiceGeneratorService {
+	return &invoiceGeneratorService{
+		invoiceRunRepo: invoiceRunRepo,
+		settingsRepo:   settingsRepo,
+		tenantRepo:     tenantRepo,
+	}
+}
+
+func (s *invoiceGeneratorService) GenerateInvoices(ctx context.Context, tenantID string, asOf time.Time, triggeredBy string, dryRun bool) (*InvoiceRunResult, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		settings = defaultTenantSettings(tenantID)
+	} else if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+
+	today := dateOf(asOf)
+	run := &entity.InvoiceRun{
+		TenantID:    tenantID,
+		RunDate:     today,
+		TriggeredBy: triggeredBy,
+		DryRun:      dryRun,
+		BillingType: settings.BillingType,
+		StartedAt:   time.Now(),
+	}
+
+	customers, err := s.invoiceRunRepo.FindBillableCustomers(ctx, tenantID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("load billable customers", err)
+	}
+	run.CustomerCount = len(customers)
+
+	// Periods considered today start at most maxCatchUpPeriods cycles back
+	since := today.AddDate(0, -maxCatchUpPeriods-1, 0)
+	existing, err := s.invoiceRunRepo.FindPeriodInvoices(ctx, tenantID, since)
+	if err != nil {
+		return nil, errors.NewDatabaseError("load existing invoices", err)
+	}
+	invoiced := make(map[string]bool, len(existing))
+	lastInvoiced := make(map[string]*time.Time)
+	for _, p := range existing {
+		if p.PeriodStart == nil {
+			continue
+		}
+		invoiced[periodKey(p.CustomerID, *p.PeriodStart)] = true
+		if last := lastInvoiced[p.CustomerID]; last == nil || p.PeriodStart.After(*last) {
+			lastInvoiced[p.CustomerID] = p.PeriodStart
+		}
+	}
+
+	// Customers who changed plan after a period ended are billed it at the old fee
+	changes, err := s.invoiceRunRepo.FindAppliedPlanChanges(ctx, tenantID, since)
+	if err != nil {
+		return nil, errors.NewDatabaseError("load plan changes", err)
+	}
+	planChanges := make(map[string][]*entity.CustomerPlanChange)
+	for _, change := range changes {
+		planChanges[change.CustomerID] = append(planChanges[change.CustomerID], change)
+	}
+
+	items := make([]InvoiceRunItem, 0)
+	for _, customer := range customers {
+		for _, period := range billingPeriodsDue(settings, customer, today, lastInvoiced[customer.ID]) {
+			item := planInvoice(periodCustomer(customer, planChanges[customer.ID], period), period, today, invoiced)
+			if item.Action == InvoiceActionNotDue {
+				run.NotDueCount++
+				continue
+			}
+			if item.Action == InvoiceActionCreate {
+				payment := newPeriodInvoice(settings, tenantID, &item)
+				item.Amount = payment.Amount
+				if !dryRun {
+					s.createInvoice(ctx, payment, settings.InvoicePrefix, &item)
+				}
+			}
+			items = append(items, item)
+		}
+	}
+
+	for _, item := range items {
+		switch {
+		case item.Error != "":
+			run.FailedCount++
+		case item.Action == InvoiceActionCreate:
+			run.CreatedCount++
+			run.TotalAmount += item.Amount
+		default:
+			run.SkippedCount++
+		}
+	}
+
+	run.Status = entity.InvoiceRunStatusSuccess
+	if dryRun {
+		run.Status = entity.InvoiceRunStatusPlanned
+	} else if run.FailedCount > 0 {
+		run.Status = entity.InvoiceRunStatusPartial
+		if run.CreatedCount == 0 {
+			run.Status = entity.InvoiceRunStatusFailed
+		}
+	}
+
+	// Scheduled runs that had nothing to do are not worth a row every day
+	if triggeredBy != entity.InvoiceRunTriggerScheduled || dryRun || run.CreatedCount+run.FailedCount > 0 {
+		s.saveInvoiceRun(ctx, run, items)
+	}
+
+	logger.Info("Invoice run for tenant %s (%s): created=%d skipped=%d not_due=%d failed=%d total=%.2f",
+		tenantID, run.Status, run.CreatedCount, run.SkippedCount, run.NotDueCount, run.FailedCount, run.TotalAmount)
+
+	return &InvoiceRunResult{Run: run, Invoices: items}, nil
+}
+
+// periodCustomer returns the customer as billed for the period: with the fee
+// they paid before the first plan change that took effect after the period
+// ended. changes are oldest first.
+func periodCustomer(customer *entity.Customer, changes []*entity.CustomerPlanChange, period billingPeriod) *entity.Customer {
+	periodEnd := period.End.Format(periodDateFormat)
+	for _, change := range changes {
+		if change.EffectiveDate.Format(periodDateFormat) > periodEnd {
+			billed := *customer
+			billed.MonthlyFee = change.FromMonthlyFee
+			return &billed
+		}
+	}
+	return customer
+}
+
+// newPeriodInvoice builds the invoice of a planned item with the monthly fee
+// as its only line
+func newPeriodInvoice(settings *entity.TenantSettings, tenantID string, item *InvoiceRunItem) *entity.Payment {
+	periodStart := item.period.Start
+	periodEnd := item.period.End
+	payment := &entity.Payment{
+		TenantID:    tenantID,
+		CustomerID:  item.CustomerID,
+		DueDate:     item.period.DueDate,
+		Status:      entity.PaymentStatusPending,
+		Notes:       periodNotes(item.period),
+		PeriodStart: &periodStart,
+		PeriodEnd:   &periodEnd,
+		Items: []entity.InvoiceItem{{
+			Type:        entity.InvoiceItemMonthlyFee,
+			Description: periodNotes(item.period),
+			Quantity:    1,
+			UnitPrice:   item.Amount,
+		}},
+	}
+	applyInvoiceTotals(payment, settings)
+	return payment
+}
+
+// createInvoice stores the planned invoice, turning it into a skip when a
+// concurrent run already created the same period
+func (s *invoiceGeneratorService) createInvoice(ctx context.Context, payment *entity.Payment, prefix string, item *InvoiceRunItem) {
+	created, err := s.invoiceRunRepo.CreateInvoice(ctx, payment, prefix)
+	if err != nil {
+		item.Error = err.Error()
+		logger.Error("Invoice run: failed to create invoice for customer %s: %v", item.CustomerID, err)
+		return
+	}
+	if !created {
+		item.Action = InvoiceActionSkip
+		item.Reason = "already invoiced for this period"
+		return
+	}
+	item.PaymentID = payment.ID
+}
+
+func (s *invoiceGeneratorService) saveInvoiceRun(ctx context.Context, run *entity.InvoiceRun, items []InvoiceRunItem) {
+	run.FinishedAt = time.Now()
+	if data, err := json.Marshal(items); err == nil {
+		run.Invoices = string(data)
+	}
+	if err := s.invoiceRunRepo.CreateRun(ctx, run); err != nil {
+		logger.Error("Failed to save invoice run for tenant %s: %v", run.TenantID, err)
+	}
+}
+
+func (s *invoiceGeneratorService) GenerateAllTenants(ctx context.Context, asOf time.Time) error {
+	tenants, err := s.tenantRepo.FindAll(ctx)
+	if err != nil {
+		return errors.NewDatabaseError("load tenants", err)
+	}
+
+	for _, tenant := range tenants {
+		if !tenant.IsActive {
+			continue
+		}
+		if _, err := s.GenerateInvoices(ctx, tenant.ID, asOf, entity.InvoiceRunTriggerScheduled, false); err != nil {
+			logger.Error("Invoice run for tenant %s failed: %v", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *invoiceGeneratorService) ListInvoiceRuns(ctx context.Context, tenantID string, limit int) ([]*entity.InvoiceRun, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	runs, err := s.invoiceRunRepo.ListRuns(ctx, tenantID, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list invoice runs", err)
+	}
+	return runs, nil
+}
+
+// StartInvoiceGenerator runs the generator for all tenants now and then on
+// every interval. Runs are idempotent so a restart never double-bills.
+func StartInvoiceGenerator(service InvoiceGeneratorService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.GenerateAllTenants(context.Background(), time.Now()); err != nil {
+				logger.Error("Invoice generator error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Invoice generator started (interval: %s)", interval)
+}
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInvoiceRunRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceRunRepository) FindBillableCustomers(ctx context.Context, tenantID string) ([]*entity.Customer, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]*entity.Customer), args.Error(1)
+}
+
+func (m *MockInvoiceRunRepository) FindPeriodInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, since)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockInvoiceRunRepository) FindAppliedPlanChanges(ctx context.Context, tenantID string, since time.Time) ([]*entity.CustomerPlanChange, error) {
+	args := m.Called(ctx, tenantID, since)
+	return args.Get(0).([]*entity.CustomerPlanChange), args.Error(1)
+}
+
+func (m *MockInvoiceRunRepository) CreateInvoice(ctx context.Context, payment *entity.Payment, prefix string) (bool, error) {
+	args := m.Called(ctx, payment, prefix)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockInvoiceRunRepository) CreateRun(ctx context.Context, run *entity.InvoiceRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockInvoiceRunRepository) ListRuns(ctx context.Context, tenantID string, limit int) ([]*entity.InvoiceRun, error) {
+	args := m.Called(ctx, tenantID, limit)
+	return args.Get(0).([]*entity.InvoiceRun), args.Error(1)
+}
+
+type MockSettingsRepository struct {
+	mock.Mock
+}
+
+func (m *MockSettingsRepository) GetUserSettings(ctx context.Context, tenantID, userID string) (*entity.UserSettings, error) {
+	args := m.Called(ctx, tenantID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.UserSettings), args.Error(1)
+}
+
+func (m *MockSettingsRepository) CreateUserSettings(ctx context.Context, settings *entity.UserSettings) error {
+	args := m.Called(ctx, settings)
+	return args.Error(0)
+}
+
+func (m *MockSettingsRepository) UpdateUserSettings(ctx context.Context, settings *entity.UserSettings) error {
+	args := m.Called(ctx, settings)
+	return args.Error(0)
+}
+
+func (m *MockSettingsRepository) GetTenantSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TenantSettings), args.Error(1)
+}
+
+func (m *MockSettingsRepository) CreateTenantSettings(ctx context.Context, settings *entity.TenantSettings) error {
+	args := m.Called(ctx, settings)
+	return args.Error(0)
+}
+
+func (m *MockSettingsRepository) UpdateTenantSettings(ctx context.Context, settings *entity.TenantSettings) error {
+	args := m.Called(ctx, settings)
+	return args.Error(0)
+}
+
+func TestInvoiceGeneratorService_GenerateInvoices(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	asOf := time.Date(2025, 3, 5, 8, 0, 0, 0, time.UTC)
+	settings := &entity.TenantSettings{BillingType: "postpaid", BillingDateType: "fixed", BillingDay: 1, InvoiceDueDays: 14}
+
+	customers := []*entity.Customer{
+		{ID: "c1", CustomerCode: "CUST-001", Name: "Budi", MonthlyFee: 150000},
+		{ID: "c2", CustomerCode: "CUST-002", Name: "Siti", MonthlyFee: 200000},
+		{ID: "c3", CustomerCode: "CUST-003", Name: "Andi", MonthlyFee: 100000},
+	}
+	periodStart := billingDate(2025, 2, 1)
+	existing := []*entity.Payment{{CustomerID: "c2", PeriodStart: &periodStart}}
+
+	t.Run("Creates Missing Invoices", func(t *testing.T) {
+		repo := new(MockInvoiceRunRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewInvoiceGeneratorService(repo, settingsRepo, nil)
+
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindBillableCustomers", ctx, tenantID).Return(customers, nil)
+		repo.On("FindPeriodInvoices", ctx, tenantID, mock.Anything).Return(existing, nil)
+		repo.On("FindAppliedPlanChanges", ctx, tenantID, mock.Anything).Return([]*entity.CustomerPlanChange{}, nil)
+		repo.On("CreateInvoice", ctx, mock.MatchedBy(func(p *entity.Payment) bool { return p.CustomerID == "c1" }), mock.Anything).Return(true, nil)
+		repo.On("CreateInvoice", ctx, mock.MatchedBy(func(p *entity.Payment) bool { return p.CustomerID == "c3" }), mock.Anything).Return(false, fmt.Errorf("connection reset"))
+		repo.On("CreateRun", ctx, mock.AnythingOfType("*entity.InvoiceRun")).Return(nil)
+
+		result, err := service.GenerateInvoices(ctx, tenantID, asOf, entity.InvoiceRunTriggerScheduled, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.InvoiceRunStatusPartial, result.Run.Status)
+		assert.Equal(t, 1, result.Run.CreatedCount)
+		assert.Equal(t, 1, result.Run.SkippedCount)
+		assert.Equal(t, 1, result.Run.FailedCount)
+		assert.Equal(t, 150000.0, result.Run.TotalAmount)
+
+		created := repo.Calls[3].Arguments.Get(1).(*entity.Payment)
+		assert.Equal(t, entity.PaymentStatusPending, created.Status)
+		assert.Equal(t, billingDate(2025, 3, 15), created.DueDate)
+		assert.Equal(t, billingDate(2025, 2, 28), *created.PeriodEnd)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Dry Run Creates Nothing", func(t *testing.T) {
+		repo := new(MockInvoiceRunRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewInvoiceGeneratorService(repo, settingsRepo, nil)
+
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindBillableCustomers", ctx, tenantID).Return(customers, nil)
+		repo.On("FindPeriodInvoices", ctx, tenantID, mock.Anything).Return(existing, nil)
+		repo.On("FindAppliedPlanChanges", ctx, tenantID, mock.Anything).Return([]*entity.CustomerPlanChange{}, nil)
+		repo.On("CreateRun", ctx, mock.AnythingOfType("*entity.InvoiceRun")).Return(nil)
+
+		result, err := service.GenerateInvoices(ctx, tenantID, asOf, entity.InvoiceRunTriggerManual, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.InvoiceRunStatusPlanned, result.Run.Status)
+		assert.Equal(t, 2, result.Run.CreatedCount)
+		assert.Equal(t, 250000.0, result.Run.TotalAmount)
+		repo.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Scheduled Run With Nothing To Do Is Not Saved", func(t *testing.T) {
+		repo := new(MockInvoiceRunRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewInvoiceGeneratorService(repo, settingsRepo, nil)
+
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(nil, errors.ErrNotFound)
+		repo.On("FindBillableCustomers", ctx, tenantID).Return(customers[1:2], nil)
+		repo.On("FindPeriodInvoices", ctx, tenantID, mock.Anything).Return(existing, nil)
+		repo.On("FindAppliedPlanChanges", ctx, tenantID, mock.Anything).Return([]*entity.CustomerPlanChange{}, nil)
+
+		result, err := service.GenerateInvoices(ctx, tenantID, asOf, entity.InvoiceRunTriggerScheduled, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.InvoiceRunStatusSuccess, result.Run.Status)
+		assert.Equal(t, 1, result.Run.SkippedCount)
+		repo.AssertNotCalled(t, "CreateRun", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Bills Every Period Missed Since The Last Invoice", func(t *testing.T) {
+		repo := new(MockInvoiceRunRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewInvoiceGeneratorService(repo, settingsRepo, nil)
+
+		lastStart := billingDate(2024, 12, 1)
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindBillableCustomers", ctx, tenantID).Return(customers[:1], nil)
+		repo.On("FindPeriodInvoices", ctx, tenantID, mock.Anything).Return([]*entity.Payment{{CustomerID: "c1", PeriodStart: &lastStart}}, nil)
+		repo.On("FindAppliedPlanChanges", ctx, tenantID, mock.Anything).Return([]*entity.CustomerPlanChange{}, nil)
+		repo.On("CreateInvoice", ctx, mock.Anything, mock.Anything).Return(true, nil)
+		repo.On("CreateRun", ctx, mock.AnythingOfType("*entity.InvoiceRun")).Return(nil)
+
+		result, err := service.GenerateInvoices(ctx, tenantID, asOf, entity.InvoiceRunTriggerScheduled, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Run.CreatedCount)
+		assert.Equal(t, "2025-01-01", result.Invoices[0].PeriodStart)
+		assert.Equal(t, "2025-02-15", result.Invoices[0].DueDate)
+		assert.Equal(t, "2025-02-01", result.Invoices[1].PeriodStart)
+		repo.AssertNumberOfCalls(t, "CreateInvoice", 2)
+	})
+
+	t.Run("Period Is Billed At The Fee Before A Later Plan Change", func(t *testing.T) {
+		repo := new(MockInvoiceRunRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewInvoiceGeneratorService(repo, settingsRepo, nil)
+
+		changes := []*entity.CustomerPlanChange{
+			{CustomerID: "c1", FromMonthlyFee: 120000, EffectiveDate: billingDate(2025, 2, 10)},
+			{CustomerID: "c1", FromMonthlyFee: 130000, EffectiveDate: billingDate(2025, 3, 1)},
+		}
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindBillableCustomers", ctx, tenantID).Return(customers[:1], nil)
+		repo.On("FindPeriodInvoices", ctx, tenantID, mock.Anything).Return(existing, nil)
+		repo.On("FindAppliedPlanChanges", ctx, tenantID, mock.Anything).Return(changes, nil)
+		repo.On("CreateRun", ctx, mock.AnythingOfType("*entity.InvoiceRun")).Return(nil)
+
+		result, err := service.GenerateInvoices(ctx, tenantID, asOf, entity.InvoiceRunTriggerManual, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 130000.0, result.Run.TotalAmount)
+	})
+}
@@ -0,0 +1,355 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/payment"
+)
+
+// InvoicePaymentService lets customers pay their invoices online through the
+// tenant's own Midtrans account. Midtrans notifications allocate the payment
+// to the invoice, which reactivates the customer once it is paid.
+type InvoicePaymentService interface {
+	// GetPaymentPage returns what a customer needs to pay an invoice online
+	GetPaymentPage(ctx context.Context, paymentID string) (*dto.InvoicePaymentPage, error)
+	// CreateCharge charges the invoice balance with the chosen payment method.
+	// A pending charge with the same method and amount is returned again.
+	CreateCharge(ctx context.Context, paymentID, paymentMethod string) (*dto.InvoiceChargeResponse, error)
+	// ParseNotification verifies a Midtrans notification sent to the tenant's
+	// webhook. It returns payment.ErrInvalidSignature when it is not authentic.
+	ParseNotification(ctx context.Context, tenantID string, header http.Header, body []byte) (*payment.Notification, error)
+	// ApplyNotification applies a verified notification to its charge and invoice
+	ApplyNotification(ctx context.Context, tenantID string, notification *payment.Notification) error
+}
+
+// PaymentReactivator restores service once an invoice is paid
+type PaymentReactivator interface {
+	HandlePaymentPaid(ctx context.Context, payment *entity.Payment) error
+}
+
+// Payment methods customers can choose for invoices
+var invoicePaymentMethods = map[string]bool{
+	"bca_va": true, "bni_va": true, "bri_va": true, "permata_va": true,
+	"mandiri_bill": true, "gopay": true, "qris": true,
+}
+
+type invoicePaymentService struct {
+	invoiceRepo    repository.InvoiceRepository
+	orderRepo      repository.InvoicePaymentOrderRepository
+	settingsRepo   repository.SettingsRepository
+	invoiceService InvoiceService
+	reactivator    PaymentReactivator
+	newGateway     func(settings *entity.TenantSettings) payment.PaymentGateway
+}
+
+func NewInvoicePaymentService(
+	invoiceRepo repository.InvoiceRepository,
+	orderRepo repository.InvoicePaymentOrderRepository,
+	settingsRepo repository.SettingsRepository,
+	invoiceService InvoiceService,
+	reactivator PaymentReactivator,
+) InvoicePaymentService {
+	return &invoicePaymentService{
+		invoiceRepo:    invoiceRepo,
+		orderRepo:      orderRepo,
+		settingsRepo:   settingsRepo,
+		invoiceService: invoiceService,
+		reactivator:    reactivator,
+		newGateway:     newTenantMidtransClient,
+	}
+}
+
+// newTenantMidtransClient builds a Midtrans client with the tenant's credentials
+func newTenantMidtransClient(settings *entity.TenantSettings) payment.PaymentGateway {
+	return payment.NewMidtransClient(&payment.MidtransConfig{
+		ServerKey:    settings.MidtransServerKey,
+		ClientKey:    settings.MidtransClientKey,
+		IsProduction: settings.MidtransIsProduction,
+	})
+}
+
+func onlinePaymentEnabled(settings *entity.TenantSettings) bool {
+	return settings.MidtransEnabled && settings.MidtransServerKey != ""
+}
+
+func (s *invoicePaymentService) loadSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		return defaultTenantSettings(tenantID), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	return settings, nil
+}
+
+func (s *invoicePaymentService) GetPaymentPage(ctx context.Context, paymentID string) (*dto.InvoicePaymentPage, error) {
+	invoice, err := s.invoiceRepo.FindInvoice(ctx, paymentID)
+	if err != nil || invoice == nil {
+		return nil, errors.ErrNotFound
+	}
+	settings, err := s.loadSettings(ctx, invoice.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.InvoicePaymentPage{
+		InvoiceNumber:        invoiceNumberOf(invoice),
+		CompanyName:          settings.CompanyName,
+		TotalAmount:          invoice.Total(),
+		PaidAmount:           invoice.PaidAmount,
+		Balance:              invoice.Balance(),
+		DueDate:              invoice.DueDate,
+		Status:               invoice.Status,
+		OnlinePaymentEnabled: onlinePaymentEnabled(settings) && invoice.Status != entity.PaymentStatusPaid,
+	}
+	if invoice.Customer != nil {
+		page.CustomerName = invoice.Customer.Name
+	}
+	if !page.OnlinePaymentEnabled {
+		return page, nil
+	}
+
+	for _, method := range payment.AvailablePaymentMethods() {
+		if id, _ := method["id"].(string); invoicePaymentMethods[id] {
+			page.PaymentMethods = append(page.PaymentMethods, method)
+		}
+	}
+	order, err := s.orderRepo.FindLatestByPayment(ctx, invoice.ID)
+	if err == nil && reusableOrder(order, "", chargeAmount(invoice)) {
+		page.PendingCharge = chargeResponseOf(order)
+	}
+	return page, nil
+}
+
+// chargeAmount is the invoice balance in whole rupiah, as Midtrans requires.
+// Cents are rounded up so the charge settles the invoice.
+func chargeAmount(invoice *entity.Payment) float64 {
+	return math.Ceil(entity.RoundMoney(invoice.Balance()))
+}
+
+// reusableOrder reports whether order is still a valid pending charge for
+// amount, with the given method unless method is empty
+func reusableOrder(order *entity.InvoicePaymentOrder, method string, amount float64) bool {
+	if order == nil || order.Status != entity.InvoicePaymentOrderPending || order.Amount != amount {
+		return false
+	}
+	if method != "" && order.PaymentMethod != method {
+		return false
+	}
+	return order.ExpiredAt == nil || order.ExpiredAt.After(time.Now())
+}
+
+func (s *invoicePaymentService) CreateCharge(ctx context.Context, paymentID, paymentMethod string) (*dto.InvoiceChargeResponse, error) {
+	if !invoicePaymentMethods[paymentMethod] {
+		return nil, errors.New("INVALID_PAYMENT_METHOD", "Invalid payment method", 400)
+	}
+
+	invoice, err := s.invoiceRepo.FindInvoice(ctx, paymentID)
+	if err != nil || invoice == nil {
+		return nil, errors.ErrNotFound
+	}
+	amount := chargeAmount(invoice)
+	if invoice.Status == entity.PaymentStatusPaid || amount <= 0 {
+		return nil, errors.New("ALREADY_PAID", "Invoice already paid", 400)
+	}
+
+	settings, err := s.loadSettings(ctx, invoice.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !onlinePaymentEnabled(settings) {
+		return nil, errors.New("ONLINE_PAYMENT_DISABLED", "Online payment is not enabled for this invoice", 400)
+	}
+
+	if order, err := s.orderRepo.FindLatestByPayment(ctx, invoice.ID); err == nil && reusableOrder(order, paymentMethod, amount) {
+		logger.Info("Returning pending charge %s for invoice %s", order.OrderID, invoice.ID)
+		return chargeResponseOf(order), nil
+	}
+
+	// Midtrans needs a new order ID for every charge, so the invoice number
+	// is suffixed with the time
+	number := invoiceNumberOf(invoice)
+	if number == "" {
+		number = invoice.ID[:8]
+	}
+	orderID := fmt.Sprintf("%s-%d", number, time.Now().Unix())
+
+	var customer *payment.CustomerDetails
+	if invoice.Customer != nil {
+		customer = &payment.CustomerDetails{
+			FirstName: invoice.Customer.Name,
+			Email:     invoice.Customer.Email,
+			Phone:     invoice.Customer.Phone,
+		}
+	}
+
+	charge, err := s.newGateway(settings).CreatePayment(ctx, &payment.ChargeParams{
+		OrderID:     orderID,
+		Amount:      amount,
+		Method:      paymentMethod,
+		Description: "Invoice " + number,
+		Customer:    customer,
+	})
+	if err != nil {
+		logger.Error("Failed to create Midtrans charge for invoice %s: %v", invoice.ID, err)
+		return nil, errors.New("PAYMENT_FAILED", fmt.Sprintf("Failed to create payment: %v", err), 500)
+	}
+
+	gatewayResponse, _ := json.Marshal(charge)
+	order := &entity.InvoicePaymentOrder{
+		TenantID:             invoice.TenantID,
+		CustomerID:           invoice.CustomerID,
+		PaymentID:            invoice.ID,
+		OrderID:              orderID,
+		Amount:               amount,
+		PaymentMethod:        paymentMethod,
+		Status:               entity.InvoicePaymentOrderPending,
+		GatewayTransactionID: charge.TransactionID,
+		GatewayResponse:      string(gatewayResponse),
+	}
+	if charge.ExpiryTime != "" {
+		// Midtrans expiry time format: "2024-01-15 12:00:00" in WIB
+		if expiry, err := charge.ExpiresAt(); err == nil {
+			order.ExpiredAt = &expiry
+		}
+	}
+	if err := s.orderRepo.Create(ctx, order); err != nil {
+		return nil, errors.NewDatabaseError("create payment order", err)
+	}
+
+	logger.Info("Midtrans charge %s created for invoice %s: %.0f via %s", orderID, invoice.ID, amount, paymentMethod)
+	return chargeResponseOf(order), nil
+}
+
+// chargeResponseOf reads the payment instructions of an order from the
+// stored gateway charge
+func chargeResponseOf(order *entity.InvoicePaymentOrder) *dto.InvoiceChargeResponse {
+	resp := &dto.InvoiceChargeResponse{
+		OrderID:       order.OrderID,
+		PaymentMethod: order.PaymentMethod,
+		Status:        order.Status,
+		Amount:        order.Amount,
+	}
+
+	var charge payment.Charge
+	if err := json.Unmarshal([]byte(order.GatewayResponse), &charge); err != nil {
+		return resp
+	}
+	resp.PaymentType = charge.PaymentType
+	resp.ExpiryTime = charge.ExpiryTime
+	resp.PermataVANumber = charge.PermataVANumber
+	resp.BillerCode = charge.BillerCode
+	resp.BillKey = charge.BillKey
+	resp.QRString = charge.QRString
+	resp.QRCodeURL = charge.QRCodeURL
+	resp.DeeplinkURL = charge.DeeplinkURL
+	for _, va := range charge.VANumbers {
+		resp.VANumbers = append(resp.VANumbers, dto.VANumber{Bank: va.Bank, VANumber: va.VANumber})
+	}
+	return resp
+}
+
+func (s *invoicePaymentService) ParseNotification(ctx context.Context, tenantID string, header http.Header, body []byte) (*payment.Notification, error) {
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.MidtransServerKey == "" {
+		return nil, errors.ErrNotFound
+	}
+
+	notification, err := s.newGateway(settings).ParseNotification(header, body)
+	if err == payment.ErrInvalidSignature {
+		logger.Warn("Invalid Midtrans signature for tenant %s", tenantID)
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.NewValidationError("invalid notification data")
+	}
+	return notification, nil
+}
+
+func (s *invoicePaymentService) ApplyNotification(ctx context.Context, tenantID string, notification *payment.Notification) error {
+	order, err := s.orderRepo.FindByOrderID(ctx, tenantID, notification.OrderID)
+	if err != nil {
+		return errors.NewDatabaseError("find payment order", err)
+	}
+	if order == nil {
+		return errors.ErrNotFound
+	}
+
+	switch notification.Status {
+	case payment.StatusPaid:
+		return s.applyPaidOrder(ctx, order, notification)
+	case payment.StatusFailed, payment.StatusExpired:
+		if err := s.orderRepo.MarkFailed(ctx, order.ID); err != nil {
+			return errors.NewDatabaseError("update payment order", err)
+		}
+		logger.Info("Midtrans charge %s for invoice %s %s", order.OrderID, order.PaymentID, notification.GatewayStatus)
+	}
+	return nil
+}
+
+// applyPaidOrder allocates a settled order to its invoice exactly once. The
+// order is marked paid in the same transaction as the allocation, so a failed
+// allocation is applied again when the notification is retried.
+func (s *invoicePaymentService) applyPaidOrder(ctx context.Context, order *entity.InvoicePaymentOrder, notification *payment.Notification) error {
+	invoice, err := s.invoiceService.GetInvoice(ctx, order.TenantID, order.PaymentID)
+	if err != nil {
+		return err
+	}
+	// The whole charge is allocated; what the invoice no longer owes, for
+	// one paid at the counter while the charge was open, becomes credit
+	amount := entity.RoundMoney(order.Amount)
+	if amount > entity.RoundMoney(invoice.Balance()) {
+		logger.Warn("Midtrans charge %s paid %.2f but invoice %s only owed %.2f, crediting the rest", order.OrderID, order.Amount, invoice.ID, invoice.Balance())
+	}
+
+	var allocation *entity.PaymentAllocation
+	if amount > 0 {
+		allocation = &entity.PaymentAllocation{
+			TenantID:      order.TenantID,
+			CustomerID:    invoice.CustomerID,
+			PaymentID:     invoice.ID,
+			Amount:        amount,
+			PaymentMethod: "midtrans_" + notification.PaymentType,
+			PaidAt:        time.Now(),
+			Reference:     order.OrderID,
+			Notes:         "Midtrans transaction " + notification.TransactionID,
+		}
+	}
+
+	paid, applied, err := s.orderRepo.ApplyPaid(ctx, order.ID, notification.TransactionID, allocation)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return appErr
+		}
+		return errors.NewDatabaseError("apply payment order", err)
+	}
+	if !applied {
+		logger.Info("Midtrans charge %s already applied", order.OrderID)
+		return nil
+	}
+	if paid == nil {
+		return nil
+	}
+	logger.Info("Midtrans charge %s allocated %.2f to invoice %s (balance %.2f)", order.OrderID, allocation.Amount, paid.ID, paid.Balance())
+
+	if invoice.Status != entity.PaymentStatusPaid && paid.Status == entity.PaymentStatusPaid {
+		if err := s.reactivator.HandlePaymentPaid(ctx, paid); err != nil {
+			logger.Error("Auto-reactivation after payment %s failed: %v", paid.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/payment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockInvoicePaymentOrderRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvoicePaymentOrderRepository) Create(ctx context.Context, order *entity.InvoicePaymentOrder) error {
+	args := m.Called(ctx, order)
+	return args.Error(0)
+}
+
+func (m *MockInvoicePaymentOrderRepository) FindByOrderID(ctx context.Context, tenantID, orderID string) (*entity.InvoicePaymentOrder, error) {
+	args := m.Called(ctx, tenantID, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.InvoicePaymentOrder), args.Error(1)
+}
+
+func (m *MockInvoicePaymentOrderRepository) FindLatestByPayment(ctx context.Context, paymentID string) (*entity.InvoicePaymentOrder, error) {
+	args := m.Called(ctx, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.InvoicePaymentOrder), args.Error(1)
+}
+
+func (m *MockInvoicePaymentOrderRepository) ApplyPaid(ctx context.Context, id, gatewayTransactionID string, allocation *entity.PaymentAllocation) (*entity.Payment, bool, error) {
+	args := m.Called(ctx, id, gatewayTransactionID, allocation)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*entity.Payment), args.Bool(1), args.Error(2)
+}
+
+func (m *MockInvoicePaymentOrderRepository) MarkFailed(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+type MockPaymentGateway struct {
+	mock.Mock
+}
+
+func (m *MockPaymentGateway) Name() string {
+	return payment.GatewayMidtrans
+}
+
+func (m *MockPaymentGateway) CreatePayment(ctx context.Context, params *payment.ChargeParams) (*payment.Charge, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Charge), args.Error(1)
+}
+
+func (m *MockPaymentGateway) GetStatus(ctx context.Context, orderID string) (*payment.Charge, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Charge), args.Error(1)
+}
+
+func (m *MockPaymentGateway) Cancel(ctx context.Context, orderID string) (*payment.Charge, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Charge), args.Error(1)
+}
+
+func (m *MockPaymentGateway) Expire(ctx context.Context, orderID string) (*payment.Charge, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Charge), args.Error(1)
+}
+
+func (m *MockPaymentGateway) Refund(ctx context.Context, params *payment.RefundParams) (*payment.Refund, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Refund), args.Error(1)
+}
+
+func (m *MockPaymentGateway) ParseNotification(header http.Header, body []byte) (*payment.Notification, error) {
+	args := m.Called(header, body)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Notification), args.Error(1)
+}
+
+type MockPaymentReactivator struct {
+	mock.Mock
+}
+
+func (m *MockPaymentReactivator) HandlePaymentPaid(ctx context.Context, payment *entity.Payment) error {
+	args := m.Called(ctx, payment)
+	return args.Error(0)
+}
+
+func midtransSettings() *entity.TenantSettings {
+	return &entity.TenantSettings{CompanyName: "Net Desa", MidtransEnabled: true, MidtransServerKey: "SB-Mid-server-x"}
+}
+
+func TestInvoicePaymentService_CreateCharge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Charges Remaining Balance", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		invoice := documentInvoice()
+		// A balance with cents is rounded up so the charge settles it
+		invoice.PaidAmount = 100000.8
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+		mockOrderRepo.On("FindLatestByPayment", ctx, "p1").Return(nil, nil)
+		mockGateway.On("CreatePayment", ctx, mock.MatchedBy(func(params *payment.ChargeParams) bool {
+			return params.Method == "bca_va" && params.Amount == 109800 && len(params.OrderID) > len("NET-000012-")
+		})).Return(&payment.Charge{
+			TransactionID: "mid-1",
+			PaymentType:   "bank_transfer",
+			VANumbers:     []payment.VANumber{{Bank: "bca", VANumber: "12345678"}},
+			ExpiryTime:    "2025-03-11 10:00:00",
+		}, nil)
+		mockOrderRepo.On("Create", ctx, mock.Anything).Return(nil)
+
+		charge, err := service.CreateCharge(ctx, "p1", "bca_va")
+		require.NoError(t, err)
+		assert.Equal(t, 109800.0, charge.Amount)
+		assert.Equal(t, []dto.VANumber{{Bank: "bca", VANumber: "12345678"}}, charge.VANumbers)
+
+		order := mockOrderRepo.Calls[1].Arguments.Get(1).(*entity.InvoicePaymentOrder)
+		assert.Equal(t, "tenant-1", order.TenantID)
+		assert.Equal(t, "mid-1", order.GatewayTransactionID)
+		require.NotNil(t, order.ExpiredAt)
+		// Midtrans expiry times are WIB (UTC+7)
+		assert.True(t, time.Date(2025, 3, 11, 3, 0, 0, 0, time.UTC).Equal(*order.ExpiredAt))
+	})
+
+	t.Run("Reuses Pending Charge", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(documentInvoice(), nil)
+		mockOrderRepo.On("FindLatestByPayment", ctx, "p1").Return(&entity.InvoicePaymentOrder{
+			OrderID: "NET-000012-1", PaymentMethod: "qris", Amount: 209800,
+			Status: entity.InvoicePaymentOrderPending, GatewayResponse: `{"qr_string":"000201"}`,
+		}, nil)
+
+		charge, err := service.CreateCharge(ctx, "p1", "qris")
+		require.NoError(t, err)
+		assert.Equal(t, "NET-000012-1", charge.OrderID)
+		assert.Equal(t, "000201", charge.QRString)
+		mockGateway.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Disabled Without Server Key", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{MidtransEnabled: true}, nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(documentInvoice(), nil)
+
+		_, err := service.CreateCharge(ctx, "p1", "qris")
+		require.Error(t, err)
+		assert.Equal(t, "ONLINE_PAYMENT_DISABLED", err.(*errors.AppError).Code)
+	})
+
+	t.Run("Rejects Paid Invoice", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		invoice := documentInvoice()
+		invoice.Status = entity.PaymentStatusPaid
+		invoice.PaidAmount = invoice.Total()
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+
+		_, err := service.CreateCharge(ctx, "p1", "gopay")
+		assert.Error(t, err)
+	})
+}
+
+func TestInvoicePaymentService_Notifications(t *testing.T) {
+	ctx := context.Background()
+	order := &entity.InvoicePaymentOrder{
+		ID: "o1", TenantID: "tenant-1", PaymentID: "p1", OrderID: "NET-000012-1",
+		Amount: 209800, Status: entity.InvoicePaymentOrderPending,
+	}
+	body := []byte(`{"order_id":"NET-000012-1"}`)
+	notification := &payment.Notification{
+		Gateway: payment.GatewayMidtrans, OrderID: "NET-000012-1", TransactionID: "mid-1",
+		Status: payment.StatusPaid, GatewayStatus: "settlement", PaymentType: "qris", Amount: 209800,
+	}
+
+	t.Run("Allocates And Reactivates", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		paid := documentInvoice()
+		paid.Status = entity.PaymentStatusPaid
+		mockGateway.On("ParseNotification", mock.Anything, body).Return(notification, nil)
+		mockOrderRepo.On("FindByOrderID", ctx, "tenant-1", "NET-000012-1").Return(order, nil)
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(documentInvoice(), nil)
+		mockOrderRepo.On("ApplyPaid", ctx, "o1", "mid-1", mock.Anything).Return(paid, true, nil)
+		mockReactivator.On("HandlePaymentPaid", ctx, paid).Return(nil)
+
+		parsed, err := service.ParseNotification(ctx, "tenant-1", http.Header{}, body)
+		require.NoError(t, err)
+		require.NoError(t, service.ApplyNotification(ctx, "tenant-1", parsed))
+
+		allocation := mockOrderRepo.Calls[1].Arguments.Get(3).(*entity.PaymentAllocation)
+		assert.Equal(t, 209800.0, allocation.Amount)
+		assert.Equal(t, "p1", allocation.PaymentID)
+		assert.Equal(t, "c1", allocation.CustomerID)
+		assert.Equal(t, "midtrans_qris", allocation.PaymentMethod)
+		assert.Equal(t, "NET-000012-1", allocation.Reference)
+		mockReactivator.AssertExpectations(t)
+	})
+
+	t.Run("Duplicate Notification Is Ignored", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		mockOrderRepo.On("FindByOrderID", ctx, "tenant-1", "NET-000012-1").Return(order, nil)
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(documentInvoice(), nil)
+		mockOrderRepo.On("ApplyPaid", ctx, "o1", "mid-1", mock.Anything).Return(nil, false, nil)
+
+		require.NoError(t, service.ApplyNotification(ctx, "tenant-1", notification))
+		mockReactivator.AssertNotCalled(t, "HandlePaymentPaid", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed Allocation Leaves Order Unpaid", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		mockOrderRepo.On("FindByOrderID", ctx, "tenant-1", "NET-000012-1").Return(order, nil)
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(documentInvoice(), nil)
+		mockOrderRepo.On("ApplyPaid", ctx, "o1", "mid-1", mock.Anything).Return(nil, false, assert.AnError)
+
+		// The error makes the gateway retry the notification
+		assert.Error(t, service.ApplyNotification(ctx, "tenant-1", notification))
+		mockReactivator.AssertNotCalled(t, "HandlePaymentPaid", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Allocates Whole Charge Above Balance", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		invoice := documentInvoice()
+		invoice.PaidAmount = 200000
+		mockOrderRepo.On("FindByOrderID", ctx, "tenant-1", "NET-000012-1").Return(order, nil)
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+		mockOrderRepo.On("ApplyPaid", ctx, "o1", "mid-1", mock.Anything).Return(invoice, true, nil)
+
+		require.NoError(t, service.ApplyNotification(ctx, "tenant-1", notification))
+		allocation := mockOrderRepo.Calls[1].Arguments.Get(3).(*entity.PaymentAllocation)
+		assert.Equal(t, order.Amount, allocation.Amount)
+		mockReactivator.AssertNotCalled(t, "HandlePaymentPaid", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Paid Invoice Is Not Reactivated Again", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		invoice := documentInvoice()
+		invoice.PaidAmount = invoice.Total()
+		invoice.Status = entity.PaymentStatusPaid
+		mockOrderRepo.On("FindByOrderID", ctx, "tenant-1", "NET-000012-1").Return(order, nil)
+		mockInvoiceRepo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+		mockOrderRepo.On("ApplyPaid", ctx, "o1", "mid-1", mock.Anything).Return(invoice, true, nil)
+
+		require.NoError(t, service.ApplyNotification(ctx, "tenant-1", notification))
+		allocation := mockOrderRepo.Calls[1].Arguments.Get(3).(*entity.PaymentAllocation)
+		assert.Equal(t, order.Amount, allocation.Amount)
+		mockReactivator.AssertNotCalled(t, "HandlePaymentPaid", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Invalid Signature", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		mockGateway.On("ParseNotification", mock.Anything, body).Return(nil, payment.ErrInvalidSignature)
+
+		_, err := service.ParseNotification(ctx, "tenant-1", http.Header{}, body)
+		assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+	})
+
+	t.Run("Marks Expired Charge Failed", func(t *testing.T) {
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockOrderRepo := new(MockInvoicePaymentOrderRepository)
+		mockGateway := new(MockPaymentGateway)
+		mockReactivator := new(MockPaymentReactivator)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(midtransSettings(), nil)
+		service := NewInvoicePaymentService(mockInvoiceRepo, mockOrderRepo, mockSettingsRepo,
+			NewInvoiceService(mockInvoiceRepo, mockSettingsRepo), mockReactivator).(*invoicePaymentService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return mockGateway }
+
+		expired := *notification
+		expired.Status = payment.StatusExpired
+		mockOrderRepo.On("FindByOrderID", ctx, "tenant-1", "NET-000012-1").Return(order, nil)
+		mockOrderRepo.On("MarkFailed", ctx, "o1").Return(nil)
+
+		require.NoError(t, service.ApplyNotification(ctx, "tenant-1", &expired))
+		mockOrderRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+// Invoice run action types
+const (
+	InvoiceActionCreate = "create"
+	InvoiceActionSkip   = "skip"
+	InvoiceActionNotDue = "not_due"
+)
+
+const periodDateFormat = "2006-01-02"
+
+// maxCatchUpPeriods bounds the cycles one run bills a customer for, so a
+// customer whose last invoice is older than that is not sent a year of
+// invoices at once
+const maxCatchUpPeriods = 12
+
+// InvoiceRunItem is the generator's decision for one customer
+type InvoiceRunItem struct {
+	Action       string  `json:"action"`
+	CustomerID   string  `json:"customer_id"`
+	CustomerCode string  `json:"customer_code"`
+	CustomerName string  `json:"customer_name"`
+	PaymentID    string  `json:"payment_id,omitempty"`
+	Amount       float64 `json:"amount"`
+	PeriodStart  string  `json:"period_start"`
+	PeriodEnd    string  `json:"period_end"`
+	DueDate      string  `json:"due_date"`
+	Reason       string  `json:"reason,omitempty"`
+	Error        string  `json:"error,omitempty"`
+
+	period billingPeriod
+}
+
+// InvoiceRunResult is returned by GenerateInvoices
+type InvoiceRunResult struct {
+	Run      *entity.InvoiceRun `json:"run"`
+	Invoices []InvoiceRunItem   `json:"invoices"`
+}
+
+// billingPeriod is one billing cycle of a customer. Start and End are both
+// inclusive dates.
+type billingPeriod struct {
+	Start   time.Time
+	End     time.Time
+	IssueOn time.Time // first day the invoice may be generated
+	DueDate time.Time
+}
+
+// billingAnchorDay is the day of month a customer's cycle starts on: the
+// tenant's BillingDay for fixed billing, the customer's own DueDate for
+// recycle billing
+func billingAnchorDay(settings *entity.TenantSettings, customer *entity.Customer) int {
+	day := settings.BillingDay
+	if settings.BillingDateType == entity.BillingDateTypeRecycle {
+		day = customer.DueDate
+	}
+	if day < 1 {
+		day = 1
+	}
+	return day
+}
+
+// anchorDate returns the given day of the month, clamped to the month's last
+// day so a cycle on the 31st falls on the 28th/29th in February
+func anchorDate(year int, month time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, loc)
+}
+
+// shiftAnchor moves an anchor date by n months keeping the anchor day
+func shiftAnchor(date time.Time, months, day int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+	return anchorDate(first.Year(), first.Month(), day, date.Location())
+}
+
+// currentBillingPeriod returns the cycle the generator should invoice today.
+// Postpaid bills the cycle that ended most recently, due InvoiceDueDays after
+// it ended. Prepaid bills the next cycle in advance: the invoice is issued
+// GenerateInvoiceDaysBefore days before the cycle starts and is due on its
+// first day.
+func currentBillingPeriod(settings *entity.TenantSettings, customer *entity.Customer, today time.Time) billingPeriod {
+	today = dateOf(today)
+	day := billingAnchorDay(settings, customer)
+	anchor := anchorDate(today.Year(), today.Month(), day, today.Location())
+
+	if settings.BillingType == entity.BillingTypePrepaid {
+		if anchor.Before(today) {
+			anchor = shiftAnchor(anchor, 1, day)
+		}
+		return billingPeriodFrom(settings, anchor, day)
+	}
+
+	if anchor.After(today) {
+		anchor = shiftAnchor(anchor, -1, day)
+	}
+	return billingPeriodFrom(settings, shiftAnchor(anchor, -1, day), day)
+}
+
+// billingPeriodFrom returns the cycle starting on start, issued and due as
+// the tenant's billing type has it
+func billingPeriodFrom(settings *entity.TenantSettings, start time.Time, day int) billingPeriod {
+	next := shiftAnchor(start, 1, day)
+	period := billingPeriod{Start: start, End: next.AddDate(0, 0, -1)}
+	if settings.BillingType == entity.BillingTypePrepaid {
+		period.IssueOn = start.AddDate(0, 0, -settings.GenerateInvoiceDaysBefore)
+		period.DueDate = start
+	} else {
+		period.IssueOn = next
+		period.DueDate = next.AddDate(0, 0, settings.InvoiceDueDays)
+	}
+	return period
+}
+
+// billingPeriodsDue returns the cycles the generator should invoice today,
+// oldest first: every cycle after the last one invoiced up to the current
+// one, at most maxCatchUpPeriods. A customer without an invoice only gets
+// the current cycle. Cycles that were invoiced in the meantime are skipped
+// by planInvoice and by the invoice uniqueness guard.
+func billingPeriodsDue(settings *entity.TenantSettings, customer *entity.Customer, today time.Time, lastInvoiced *time.Time) []billingPeriod {
+	day := billingAnchorDay(settings, customer)
+	periods := []billingPeriod{currentBillingPeriod(settings, customer, today)}
+	if lastInvoiced == nil {
+		return periods
+	}
+	last := dateOf(*lastInvoiced)
+	for len(periods) < maxCatchUpPeriods {
+		previous := billingPeriodFrom(settings, shiftAnchor(periods[0].Start, -1, day), day)
+		if !previous.Start.After(last) {
+			break
+		}
+		periods = append([]billingPeriod{previous}, periods...)
+	}
+	return periods
+}
+
+// serviceCycle returns the customer's cycle the given day falls in. Only
+// Start and End are set.
+func serviceCycle(settings *entity.TenantSettings, customer *entity.Customer, day time.Time) billingPeriod {
+	day = dateOf(day)
+	anchorDay := billingAnchorDay(settings, customer)
+	start := anchorDate(day.Year(), day.Month(), anchorDay, day.Location())
+	if start.After(day) {
+		start = shiftAnchor(start, -1, anchorDay)
+	}
+	return billingPeriod{
+		Start: start,
+		End:   shiftAnchor(start, 1, anchorDay).AddDate(0, 0, -1),
+	}
+}
+
+// planInvoice decides whether the customer gets an invoice for the period
+// today. invoiced holds periodKey values of invoices that already exist.
+func planInvoice(customer *entity.Customer, period billingPeriod, today time.Time, invoiced map[string]bool) InvoiceRunItem {
+	item := InvoiceRunItem{
+		Action:       InvoiceActionCreate,
+		CustomerID:   customer.ID,
+		CustomerCode: customer.CustomerCode,
+		CustomerName: customer.Name,
+		Amount:       customer.MonthlyFee,
+		PeriodStart:  period.Start.Format(periodDateFormat),
+		PeriodEnd:    period.End.Format(periodDateFormat),
+		DueDate:      period.DueDate.Format(periodDateFormat),
+		period:       period,
+	}
+
+	switch {
+	case today.Before(period.IssueOn):
+		item.Action = InvoiceActionNotDue
+	case invoiced[periodKey(customer.ID, period.Start)]:
+		item.Action = InvoiceActionSkip
+		item.Reason = "already invoiced for this period"
+	case customer.MonthlyFee <= 0:
+		item.Action = InvoiceActionSkip
+		item.Reason = "customer has no monthly fee"
+	case !customer.InstallationDate.IsZero() && dateOf(customer.InstallationDate).After(period.Start):
+		item.Action = InvoiceActionSkip
+		item.Reason = "customer was installed after the period started"
+	}
+	return item
+}
+
+// dateOf drops the time of day
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func periodKey(customerID string, start time.Time) string {
+	return customerID + "|" + start.Format(periodDateFormat)
+}
+
+func periodNotes(period billingPeriod) string {
+	return fmt.Sprintf("Monthly invoice for %s - %s",
+		period.Start.Format("02/01/2006"), period.End.Format("02/01/2006"))
+}
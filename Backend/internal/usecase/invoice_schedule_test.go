@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func billingDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCurrentBillingPeriod(t *testing.T) {
+	customer := &entity.Customer{ID: "c1", DueDate: 20}
+
+	tests := []struct {
+		name     string
+		settings *entity.TenantSettings
+		today    time.Time
+		start    time.Time
+		end      time.Time
+		issueOn  time.Time
+		dueDate  time.Time
+	}{
+		{
+			name:     "Postpaid Fixed",
+			settings: &entity.TenantSettings{BillingType: "postpaid", BillingDateType: "fixed", BillingDay: 1, InvoiceDueDays: 14},
+			today:    billingDate(2025, 3, 5),
+			start:    billingDate(2025, 2, 1),
+			end:      billingDate(2025, 2, 28),
+			issueOn:  billingDate(2025, 3, 1),
+			dueDate:  billingDate(2025, 3, 15),
+		},
+		{
+			name:     "Postpaid Recycle Uses Customer Day",
+			settings: &entity.TenantSettings{BillingType: "postpaid", BillingDateType: "recycle", BillingDay: 1, InvoiceDueDays: 7},
+			today:    billingDate(2025, 3, 5),
+			start:    billingDate(2025, 1, 20),
+			end:      billingDate(2025, 2, 19),
+			issueOn:  billingDate(2025, 2, 20),
+			dueDate:  billingDate(2025, 2, 27),
+		},
+		{
+			name:     "Prepaid Next Cycle",
+			settings: &entity.TenantSettings{BillingType: "prepaid", BillingDateType: "fixed", BillingDay: 1, GenerateInvoiceDaysBefore: 7},
+			today:    billingDate(2025, 3, 5),
+			start:    billingDate(2025, 4, 1),
+			end:      billingDate(2025, 4, 30),
+			issueOn:  billingDate(2025, 3, 25),
+			dueDate:  billingDate(2025, 4, 1),
+		},
+		{
+			name:     "Prepaid Cycle Starting Today",
+			settings: &entity.TenantSettings{BillingType: "prepaid", BillingDateType: "fixed", BillingDay: 5, GenerateInvoiceDaysBefore: 3},
+			today:    billingDate(2025, 3, 5),
+			start:    billingDate(2025, 3, 5),
+			end:      billingDate(2025, 4, 4),
+			issueOn:  billingDate(2025, 3, 2),
+			dueDate:  billingDate(2025, 3, 5),
+		},
+		{
+			name:     "Day 31 Clamped To Month End",
+			settings: &entity.TenantSettings{BillingType: "postpaid", BillingDateType: "fixed", BillingDay: 31, InvoiceDueDays: 0},
+			today:    billingDate(2025, 3, 10),
+			start:    billingDate(2025, 1, 31),
+			end:      billingDate(2025, 2, 27),
+			issueOn:  billingDate(2025, 2, 28),
+			dueDate:  billingDate(2025, 2, 28),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period := currentBillingPeriod(tt.settings, customer, tt.today)
+			assert.Equal(t, tt.start, period.Start)
+			assert.Equal(t, tt.end, period.End)
+			assert.Equal(t, tt.issueOn, period.IssueOn)
+			assert.Equal(t, tt.dueDate, period.DueDate)
+		})
+	}
+}
+
+func TestBillingPeriodsDue(t *testing.T) {
+	customer := &entity.Customer{ID: "c1", DueDate: 20}
+	postpaid := &entity.TenantSettings{BillingType: "postpaid", BillingDateType: "fixed", BillingDay: 1, InvoiceDueDays: 14}
+	today := billingDate(2025, 3, 5)
+
+	t.Run("Without Invoices Only The Current Period", func(t *testing.T) {
+		periods := billingPeriodsDue(postpaid, customer, today, nil)
+		assert.Len(t, periods, 1)
+		assert.Equal(t, billingDate(2025, 2, 1), periods[0].Start)
+	})
+
+	t.Run("Every Period Since The Last Invoice", func(t *testing.T) {
+		last := billingDate(2024, 11, 1)
+		periods := billingPeriodsDue(postpaid, customer, today, &last)
+		assert.Len(t, periods, 3)
+		assert.Equal(t, billingDate(2024, 12, 1), periods[0].Start)
+		assert.Equal(t, billingDate(2024, 12, 31), periods[0].End)
+		assert.Equal(t, billingDate(2025, 1, 15), periods[0].DueDate)
+		assert.Equal(t, billingDate(2025, 1, 1), periods[1].Start)
+		assert.Equal(t, billingDate(2025, 2, 1), periods[2].Start)
+	})
+
+	t.Run("Current Period Already Invoiced", func(t *testing.T) {
+		last := billingDate(2025, 2, 1)
+		periods := billingPeriodsDue(postpaid, customer, today, &last)
+		assert.Len(t, periods, 1)
+	})
+
+	t.Run("Prepaid Catches Up To The Next Period", func(t *testing.T) {
+		prepaid := &entity.TenantSettings{BillingType: "prepaid", BillingDateType: "recycle", GenerateInvoiceDaysBefore: 7}
+		last := billingDate(2025, 1, 20)
+		periods := billingPeriodsDue(prepaid, customer, today, &last)
+		assert.Len(t, periods, 2)
+		assert.Equal(t, billingDate(2025, 2, 20), periods[0].Start)
+		assert.Equal(t, billingDate(2025, 2, 20), periods[0].DueDate)
+		assert.Equal(t, billingDate(2025, 3, 20), periods[1].Start)
+	})
+
+	t.Run("Catch Up Is Bounded", func(t *testing.T) {
+		last := billingDate(2020, 1, 1)
+		periods := billingPeriodsDue(postpaid, customer, today, &last)
+		assert.Len(t, periods, maxCatchUpPeriods)
+		assert.Equal(t, billingDate(2025, 2, 1), periods[len(periods)-1].Start)
+	})
+}
+
+func TestPlanInvoice(t *testing.T) {
+	settings := &entity.TenantSettings{BillingType: "postpaid", BillingDateType: "fixed", BillingDay: 1, InvoiceDueDays: 14}
+	today := billingDate(2025, 3, 5)
+	customer := &entity.Customer{ID: "c1", CustomerCode: "CUST-001", MonthlyFee: 150000, InstallationDate: billingDate(2024, 6, 10)}
+
+	t.Run("Create", func(t *testing.T) {
+		item := planInvoice(customer, currentBillingPeriod(settings, customer, today), today, map[string]bool{})
+		assert.Equal(t, InvoiceActionCreate, item.Action)
+		assert.Equal(t, 150000.0, item.Amount)
+		assert.Equal(t, "2025-02-01", item.PeriodStart)
+		assert.Equal(t, "2025-02-28", item.PeriodEnd)
+		assert.Equal(t, "2025-03-15", item.DueDate)
+	})
+
+	t.Run("Already Invoiced", func(t *testing.T) {
+		invoiced := map[string]bool{periodKey("c1", billingDate(2025, 2, 1)): true}
+		item := planInvoice(customer, currentBillingPeriod(settings, customer, today), today, invoiced)
+		assert.Equal(t, InvoiceActionSkip, item.Action)
+		assert.Contains(t, item.Reason, "already invoiced")
+	})
+
+	t.Run("No Monthly Fee", func(t *testing.T) {
+		free := *customer
+		free.MonthlyFee = 0
+		item := planInvoice(&free, currentBillingPeriod(settings, &free, today), today, map[string]bool{})
+		assert.Equal(t, InvoiceActionSkip, item.Action)
+	})
+
+	t.Run("Installed Mid Cycle", func(t *testing.T) {
+		recent := *customer
+		recent.InstallationDate = time.Date(2025, 2, 10, 9, 30, 0, 0, time.UTC)
+		item := planInvoice(&recent, currentBillingPeriod(settings, &recent, today), today, map[string]bool{})
+		assert.Equal(t, InvoiceActionSkip, item.Action)
+		assert.Contains(t, item.Reason, "installed after")
+	})
+
+	t.Run("Installed On Period Start", func(t *testing.T) {
+		onStart := *customer
+		onStart.InstallationDate = time.Date(2025, 2, 1, 15, 0, 0, 0, time.UTC)
+		item := planInvoice(&onStart, currentBillingPeriod(settings, &onStart, today), today, map[string]bool{})
+		assert.Equal(t, InvoiceActionCreate, item.Action)
+	})
+
+	t.Run("Prepaid Not Due Yet", func(t *testing.T) {
+		prepaid := &entity.TenantSettings{BillingType: "prepaid", BillingDateType: "fixed", BillingDay: 1, GenerateInvoiceDaysBefore: 7}
+		item := planInvoice(customer, currentBillingPeriod(prepaid, customer, today), today, map[string]bool{})
+		assert.Equal(t, InvoiceActionNotDue, item.Action)
+	})
+}
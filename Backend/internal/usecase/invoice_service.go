@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/payment"
+)
+
+// InvoiceService issues numbered customer invoices built from line items and
+// records the payments allocated against them. Invoices are Payment rows.
+type InvoiceService interface {
+	// CreateInvoice computes the totals of an invoice from its Items and the
+	// tenant's tax settings, numbers it and stores it
+	CreateInvoice(ctx context.Context, payment *entity.Payment) error
+	GetInvoice(ctx context.Context, tenantID, paymentID string) (*entity.Payment, error)
+	// AllocatePayment records money received against an invoice. The invoice
+	// becomes paid once its balance is covered.
+	AllocatePayment(ctx context.Context, tenantID, paymentID string, allocation *entity.PaymentAllocation) (*entity.Payment, error)
+	// IssueCreditNote credits an invoice. The part of the credit above the
+	// outstanding balance is refunded to the customer with note.RefundMethod;
+	// midtrans refunds go back through the tenant's Midtrans account.
+	IssueCreditNote(ctx context.Context, tenantID, paymentID string, note *entity.CreditNote) (*entity.Payment, error)
+	// AdjustCustomerBalance records a manual credit or debit to a customer's
+	// balance. Credit is applied to the customer's next invoices.
+	AdjustCustomerBalance(ctx context.Context, tenantID string, entry *entity.CustomerLedgerEntry) (*entity.CustomerLedgerEntry, error)
+	// GetCustomerStatement returns a customer's balance and latest ledger entries
+	GetCustomerStatement(ctx context.Context, tenantID, customerID string, limit int) (*CustomerStatement, error)
+	// SetPaymentReactivator sets the hook run once an invoice becomes paid,
+	// whichever path paid it. It is set after construction because the
+	// reactivator suspends and activates customers through services that
+	// depend on this one.
+	SetPaymentReactivator(reactivator PaymentReactivator)
+}
+
+// CustomerStatement is a customer's credit balance with its ledger, newest first
+type CustomerStatement struct {
+	CustomerID string                        `json:"customer_id"`
+	Balance    float64                       `json:"balance"`
+	Entries    []*entity.CustomerLedgerEntry `json:"entries"`
+}
+
+type invoiceService struct {
+	invoiceRepo  repository.InvoiceRepository
+	settingsRepo repository.SettingsRepository
+	newGateway   func(settings *entity.TenantSettings) payment.PaymentGateway
+	reactivator  PaymentReactivator
+}
+
+func NewInvoiceService(invoiceRepo repository.InvoiceRepository, settingsRepo repository.SettingsRepository) InvoiceService {
+	return &invoiceService{
+		invoiceRepo:  invoiceRepo,
+		settingsRepo: settingsRepo,
+		newGateway:   newTenantMidtransClient,
+	}
+}
+
+func (s *invoiceService) SetPaymentReactivator(reactivator PaymentReactivator) {
+	s.reactivator = reactivator
+}
+
+// invoicePaid runs the paid hook for an invoice that was not paid before
+func (s *invoiceService) invoicePaid(ctx context.Context, wasPaid bool, invoice *entity.Payment) {
+	if wasPaid || invoice.Status != entity.PaymentStatusPaid || s.reactivator == nil {
+		return
+	}
+	if err := s.reactivator.HandlePaymentPaid(ctx, invoice); err != nil {
+		logger.Error("Auto-reactivation after payment %s failed: %v", invoice.ID, err)
+	}
+}
+
+// applyInvoiceTotals computes item amounts and the invoice subtotal, discount,
+// tax and amount. Discounts reduce the taxed base; late fee items are kept in
+// Payment.LateFee and not part of Amount.
+func applyInvoiceTotals(payment *entity.Payment, settings *entity.TenantSettings) {
+	subtotal, discount := 0.0, 0.0
+	for i := range payment.Items {
+		item := &payment.Items[i]
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		if item.Type == entity.InvoiceItemDiscount {
+			item.UnitPrice = -math.Abs(item.UnitPrice)
+		}
+		item.Amount = entity.RoundMoney(item.Quantity * item.UnitPrice)
+
+		switch item.Type {
+		case entity.InvoiceItemDiscount:
+			discount -= item.Amount
+		case entity.InvoiceItemLateFee:
+			// tracked in Payment.LateFee
+		default:
+			subtotal += item.Amount
+		}
+	}
+	if discount > subtotal {
+		discount = subtotal
+	}
+
+	payment.Subtotal = entity.RoundMoney(subtotal)
+	payment.DiscountAmount = entity.RoundMoney(discount)
+	payment.TaxPercentage = 0
+	payment.TaxAmount = 0
+	if settings.TaxEnabled && settings.TaxPercentage > 0 {
+		payment.TaxPercentage = settings.TaxPercentage
+		payment.TaxAmount = entity.RoundMoney((subtotal - discount) * settings.TaxPercentage / 100)
+	}
+	payment.Amount = entity.RoundMoney(payment.Subtotal - payment.DiscountAmount + payment.TaxAmount)
+}
+
+// addInvoiceItems appends items to an unpaid invoice and stores them with the
+// recomputed totals. It returns the amount the items added and reports false
+// when they would leave nothing to pay or the invoice was paid in the
+// meantime.
+func addInvoiceItems(ctx context.Context, invoiceRepo repository.InvoiceRepository, settings *entity.TenantSettings, invoice *entity.Payment, items []entity.InvoiceItem) (float64, bool, error) {
+	updated := *invoice
+	updated.Items = append(append([]entity.InvoiceItem{}, invoice.Items...), items...)
+	applyInvoiceTotals(&updated, settings)
+	if updated.Balance() < 0.005 {
+		return 0, false, nil
+	}
+
+	added := updated.Items[len(invoice.Items):]
+	net := 0.0
+	for i := range added {
+		added[i].TenantID = invoice.TenantID
+		added[i].PaymentID = invoice.ID
+		net += added[i].Amount
+	}
+	ok, err := invoiceRepo.AddInvoiceItems(ctx, &updated, added)
+	if err != nil {
+		return 0, false, errors.NewDatabaseError("add items to invoice", err)
+	}
+	return entity.RoundMoney(net), ok, nil
+}
+
+func (s *invoiceService) loadSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		return defaultTenantSettings(tenantID), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	return settings, nil
+}
+
+func (s *invoiceService) CreateInvoice(ctx context.Context, payment *entity.Payment) error {
+	if len(payment.Items) == 0 {
+		return errors.NewValidationError("invoice needs at least one item")
+	}
+
+	settings, err := s.loadSettings(ctx, payment.TenantID)
+	if err != nil {
+		return err
+	}
+
+	applyInvoiceTotals(payment, settings)
+	if payment.DueDate.IsZero() {
+		payment.DueDate = time.Now().AddDate(0, 0, settings.InvoiceDueDays)
+	}
+	if payment.Status == "" {
+		payment.Status = entity.PaymentStatusPending
+	}
+
+	if err := s.invoiceRepo.CreateInvoice(ctx, payment, settings.InvoicePrefix); err != nil {
+		return errors.NewDatabaseError("create invoice", err)
+	}
+
+	logger.Info("Invoice %s created for customer %s: %.2f", *payment.InvoiceNumber, payment.CustomerID, payment.Amount)
+	// Customer credit may have paid the invoice in full
+	s.invoicePaid(ctx, false, payment)
+	return nil
+}
+
+func (s *invoiceService) GetInvoice(ctx context.Context, tenantID, paymentID string) (*entity.Payment, error) {
+	payment, err := s.invoiceRepo.FindInvoice(ctx, paymentID)
+	if err != nil || payment == nil {
+		return nil, errors.ErrNotFound
+	}
+	if payment.TenantID != tenantID {
+		return nil, errors.ErrUnauthorized
+	}
+	return payment, nil
+}
+
+func (s *invoiceService) AllocatePayment(ctx context.Context, tenantID, paymentID string, allocation *entity.PaymentAllocation) (*entity.Payment, error) {
+	invoice, err := s.GetInvoice(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if allocation.Amount <= 0 {
+		return nil, errors.NewValidationError("amount must be greater than zero")
+	}
+
+	allocation.TenantID = tenantID
+	allocation.CustomerID = invoice.CustomerID
+	allocation.PaymentID = invoice.ID
+	allocation.Amount = entity.RoundMoney(allocation.Amount)
+	if allocation.PaidAt.IsZero() {
+		allocation.PaidAt = time.Now()
+	}
+
+	payment, err := s.invoiceRepo.AddAllocation(ctx, allocation)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewDatabaseError("allocate payment", err)
+	}
+
+	logger.Info("Payment of %.2f allocated to invoice %s (balance %.2f)", allocation.Amount, payment.ID, payment.Balance())
+	s.invoicePaid(ctx, invoice.Status == entity.PaymentStatusPaid, payment)
+	return payment, nil
+}
+
+func (s *invoiceService) IssueCreditNote(ctx context.Context, tenantID, paymentID string, note *entity.CreditNote) (*entity.Payment, error) {
+	invoice, err := s.GetInvoice(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	note.Amount = entity.RoundMoney(note.Amount)
+	if note.Amount <= 0 {
+		return nil, errors.NewValidationError("amount must be greater than zero")
+	}
+	if note.Reason == "" {
+		return nil, errors.NewValidationError("reason is required")
+	}
+	if creditable := invoice.Total() - invoice.CreditedAmount; note.Amount > creditable+0.005 {
+		return nil, errors.NewValidationError(fmt.Sprintf("amount exceeds the creditable amount of %.2f", creditable))
+	}
+
+	note.TenantID = tenantID
+	note.CustomerID = invoice.CustomerID
+	note.PaymentID = invoice.ID
+	note.RefundAmount = entity.RoundMoney(math.Max(0, note.Amount-invoice.Balance()))
+	var refundAtGateway func(note *entity.CreditNote) error
+	if note.RefundAmount == 0 {
+		note.RefundMethod = ""
+	} else if refundAtGateway, err = s.refundCredit(ctx, invoice, note); err != nil {
+		return nil, err
+	}
+
+	var payment *entity.Payment
+	if refundAtGateway == nil {
+		payment, err = s.invoiceRepo.AddCreditNote(ctx, note)
+	} else {
+		payment, err = s.issueRefundedCreditNote(ctx, note, refundAtGateway)
+	}
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewDatabaseError("issue credit note", err)
+	}
+
+	logger.Info("Credit note %s of %.2f issued for invoice %s (refund %.2f)", note.CreditNoteNumber, note.Amount, payment.ID, note.RefundAmount)
+	s.invoicePaid(ctx, invoice.Status == entity.PaymentStatusPaid, payment)
+	return payment, nil
+}
+
+func (s *invoiceService) AdjustCustomerBalance(ctx context.Context, tenantID string, entry *entity.CustomerLedgerEntry) (*entity.CustomerLedgerEntry, error) {
+	entry.Amount = entity.RoundMoney(entry.Amount)
+	if entry.Amount <= 0 {
+		return nil, errors.NewValidationError("amount must be greater than zero")
+	}
+	if entry.Type != entity.LedgerEntryCredit && entry.Type != entity.LedgerEntryDebit {
+		return nil, errors.NewValidationError("type must be credit or debit")
+	}
+	if strings.TrimSpace(entry.Reason) == "" {
+		return nil, errors.NewValidationError("reason is required")
+	}
+
+	entry.TenantID = tenantID
+	entry.Source = entity.LedgerSourceManual
+	entry.PaymentID = nil
+	if err := s.invoiceRepo.AddLedgerEntry(ctx, entry); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewDatabaseError("adjust customer balance", err)
+	}
+
+	logger.Info("Manual %s of %.2f for customer %s (balance %.2f)", entry.Type, entry.Amount, entry.CustomerID, entry.BalanceAfter)
+	return entry, nil
+}
+
+func (s *invoiceService) GetCustomerStatement(ctx context.Context, tenantID, customerID string, limit int) (*CustomerStatement, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	entries, err := s.invoiceRepo.FindLedgerEntries(ctx, tenantID, customerID, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("find ledger entries", err)
+	}
+
+	// The balance covers the whole ledger, not only the entries listed
+	balance, err := s.invoiceRepo.SumLedger(ctx, tenantID, customerID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("sum ledger entries", err)
+	}
+	return &CustomerStatement{CustomerID: customerID, Balance: entity.RoundMoney(balance), Entries: entries}, nil
+}
+
+// issueRefundedCreditNote reserves the note, refunds it at the gateway
+// without holding the invoice lock and then issues it, or marks it failed
+// when the gateway refuses
+func (s *invoiceService) issueRefundedCreditNote(ctx context.Context, note *entity.CreditNote, refundAtGateway func(note *entity.CreditNote) error) (*entity.Payment, error) {
+	if err := s.invoiceRepo.ReserveCreditNote(ctx, note); err != nil {
+		return nil, err
+	}
+	if err := refundAtGateway(note); err != nil {
+		if failErr := s.invoiceRepo.FailCreditNote(ctx, note); failErr != nil {
+			logger.Error("Failed to mark credit note %s failed: %v", note.CreditNoteNumber, failErr)
+		}
+		return nil, err
+	}
+	payment, err := s.invoiceRepo.CompleteCreditNote(ctx, note)
+	if err != nil {
+		logger.Error("Midtrans refund %s for credit note %s was made but the note is still pending: %v", note.RefundReference, note.CreditNoteNumber, err)
+		return nil, err
+	}
+	return payment, nil
+}
+
+// refundCredit checks how the refunded part of a credit note is paid back.
+// Cash and transfer refunds are made by the tenant and only recorded; for
+// midtrans it returns the refund to run once the credit note is reserved.
+func (s *invoiceService) refundCredit(ctx context.Context, invoice *entity.Payment, note *entity.CreditNote) (func(note *entity.CreditNote) error, error) {
+	switch note.RefundMethod {
+	case entity.CreditNoteRefundCash, entity.CreditNoteRefundTransfer:
+		return nil, nil
+	case entity.CreditNoteRefundMidtrans:
+	case "":
+		return nil, errors.NewValidationError(fmt.Sprintf("refund_method is required to refund the %.2f already paid", note.RefundAmount))
+	default:
+		return nil, errors.NewValidationError("refund_method must be cash, transfer or midtrans")
+	}
+
+	// Refund through the Midtrans charge that paid enough of the invoice
+	var order string
+	for _, allocation := range invoice.Allocations {
+		if strings.HasPrefix(allocation.PaymentMethod, "midtrans_") && allocation.Amount >= note.RefundAmount {
+			order = allocation.Reference
+		}
+	}
+	if order == "" {
+		return nil, errors.NewValidationError("invoice has no Midtrans payment covering the refund")
+	}
+
+	settings, err := s.loadSettings(ctx, invoice.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !onlinePaymentEnabled(settings) {
+		return nil, errors.NewValidationError("online payment is not enabled for this tenant")
+	}
+
+	gateway := s.newGateway(settings)
+	return func(note *entity.CreditNote) error {
+		refund, err := gateway.Refund(ctx, &payment.RefundParams{
+			OrderID:   order,
+			RefundKey: note.ID,
+			Amount:    note.RefundAmount,
+			Reason:    note.Reason,
+		})
+		if err != nil {
+			logger.Error("Midtrans refund of order %s failed: %v", order, err)
+			return errors.NewWithDetails("PAY_5002", "Midtrans refused the refund", 502, map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		note.RefundReference = refund.RefundID
+		return nil
+	}, nil
+}
@@ -0,0 +1,437 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/payment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockInvoiceRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceRepository) CreateInvoice(ctx context.Context, payment *entity.Payment, prefix string) error {
+	args := m.Called(ctx, payment, prefix)
+	return args.Error(0)
+}
+
+func (m *MockInvoiceRepository) FindInvoice(ctx context.Context, id string) (*entity.Payment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) AddAllocation(ctx context.Context, allocation *entity.PaymentAllocation) (*entity.Payment, error) {
+	args := m.Called(ctx, allocation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) AddCreditNote(ctx context.Context, note *entity.CreditNote) (*entity.Payment, error) {
+	args := m.Called(ctx, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) ReserveCreditNote(ctx context.Context, note *entity.CreditNote) error {
+	args := m.Called(ctx, note)
+	return args.Error(0)
+}
+
+func (m *MockInvoiceRepository) CompleteCreditNote(ctx context.Context, note *entity.CreditNote) (*entity.Payment, error) {
+	args := m.Called(ctx, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) FailCreditNote(ctx context.Context, note *entity.CreditNote) error {
+	args := m.Called(ctx, note)
+	return args.Error(0)
+}
+
+func (m *MockInvoiceRepository) AddLedgerEntry(ctx context.Context, entry *entity.CustomerLedgerEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockInvoiceRepository) FindLedgerEntries(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerLedgerEntry, error) {
+	args := m.Called(ctx, tenantID, customerID, limit)
+	return args.Get(0).([]*entity.CustomerLedgerEntry), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) SumLedger(ctx context.Context, tenantID, customerID string) (float64, error) {
+	args := m.Called(ctx, tenantID, customerID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) FindPeriodInvoice(ctx context.Context, customerID string, periodStart time.Time) (*entity.Payment, error) {
+	args := m.Called(ctx, customerID, periodStart)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) AddInvoiceItems(ctx context.Context, payment *entity.Payment, items []entity.InvoiceItem) (bool, error) {
+	args := m.Called(ctx, payment, items)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestApplyInvoiceTotals(t *testing.T) {
+	payment := &entity.Payment{Items: []entity.InvoiceItem{
+		{Type: entity.InvoiceItemMonthlyFee, Description: "Paket 20 Mbps", UnitPrice: 200000},
+		{Type: entity.InvoiceItemAddon, Description: "Static IP", Quantity: 2, UnitPrice: 25000},
+		{Type: entity.InvoiceItemDiscount, Description: "Loyalty", UnitPrice: 50000},
+		{Type: entity.InvoiceItemLateFee, Description: "Late fee", UnitPrice: 10000},
+	}}
+
+	t.Run("Without Tax", func(t *testing.T) {
+		applyInvoiceTotals(payment, &entity.TenantSettings{})
+
+		assert.Equal(t, 250000.0, payment.Subtotal)
+		assert.Equal(t, 50000.0, payment.DiscountAmount)
+		assert.Equal(t, 0.0, payment.TaxAmount)
+		assert.Equal(t, 200000.0, payment.Amount)
+		assert.Equal(t, 1.0, payment.Items[0].Quantity)
+		assert.Equal(t, 50000.0, payment.Items[1].Amount)
+		assert.Equal(t, -50000.0, payment.Items[2].Amount)
+	})
+
+	t.Run("With Tax On Discounted Base", func(t *testing.T) {
+		applyInvoiceTotals(payment, &entity.TenantSettings{TaxEnabled: true, TaxPercentage: 11})
+
+		assert.Equal(t, 11.0, payment.TaxPercentage)
+		assert.Equal(t, 22000.0, payment.TaxAmount)
+		assert.Equal(t, 222000.0, payment.Amount)
+	})
+
+	t.Run("Discount Never Exceeds Subtotal", func(t *testing.T) {
+		free := &entity.Payment{Items: []entity.InvoiceItem{
+			{Type: entity.InvoiceItemInstallation, Description: "Installation", UnitPrice: 100000},
+			{Type: entity.InvoiceItemDiscount, Description: "Promo", UnitPrice: 150000},
+		}}
+		applyInvoiceTotals(free, &entity.TenantSettings{TaxEnabled: true, TaxPercentage: 11})
+
+		assert.Equal(t, 100000.0, free.DiscountAmount)
+		assert.Equal(t, 0.0, free.Amount)
+	})
+}
+
+func TestFormatInvoiceNumber(t *testing.T) {
+	assert.Equal(t, "INV-000042", entity.FormatInvoiceNumber("INV", 42))
+	assert.Equal(t, "NET-1234567", entity.FormatInvoiceNumber("NET", 1234567))
+	assert.Equal(t, "INV-000001", entity.FormatInvoiceNumber("", 1))
+}
+
+func TestInvoiceService_CreateInvoice(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+
+	repo := new(MockInvoiceRepository)
+	settingsRepo := new(MockSettingsRepository)
+	service := NewInvoiceService(repo, settingsRepo)
+
+	settings := &entity.TenantSettings{InvoicePrefix: "NET", InvoiceDueDays: 10, TaxEnabled: true, TaxPercentage: 11}
+	settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+	repo.On("CreateInvoice", ctx, mock.AnythingOfType("*entity.Payment"), "NET").Run(func(args mock.Arguments) {
+		number := "NET-000007"
+		args.Get(1).(*entity.Payment).InvoiceNumber = &number
+	}).Return(nil)
+
+	payment := &entity.Payment{
+		TenantID:   tenantID,
+		CustomerID: "c1",
+		Items:      []entity.InvoiceItem{{Type: entity.InvoiceItemMonthlyFee, Description: "Paket 10 Mbps", UnitPrice: 150000}},
+	}
+	require.NoError(t, service.CreateInvoice(ctx, payment))
+
+	assert.Equal(t, "NET-000007", *payment.InvoiceNumber)
+	assert.Equal(t, 166500.0, payment.Amount)
+	assert.Equal(t, entity.PaymentStatusPending, payment.Status)
+	assert.Equal(t, dateOf(time.Now().AddDate(0, 0, 10)), dateOf(payment.DueDate))
+
+	t.Run("Requires Items", func(t *testing.T) {
+		err := service.CreateInvoice(ctx, &entity.Payment{TenantID: tenantID, CustomerID: "c1"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Reactivates When Paid By Credit", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		reactivator := new(MockPaymentReactivator)
+		service := NewInvoiceService(repo, settingsRepo)
+		service.SetPaymentReactivator(reactivator)
+
+		repo.On("CreateInvoice", ctx, mock.AnythingOfType("*entity.Payment"), "NET").Run(func(args mock.Arguments) {
+			number := "NET-000008"
+			invoice := args.Get(1).(*entity.Payment)
+			invoice.InvoiceNumber = &number
+			invoice.PaidAmount = invoice.Amount
+			invoice.Status = entity.PaymentStatusPaid
+		}).Return(nil)
+		reactivator.On("HandlePaymentPaid", ctx, mock.AnythingOfType("*entity.Payment")).Return(nil)
+
+		require.NoError(t, service.CreateInvoice(ctx, &entity.Payment{
+			TenantID:   tenantID,
+			CustomerID: "c1",
+			Items:      []entity.InvoiceItem{{Type: entity.InvoiceItemMonthlyFee, Description: "Paket 10 Mbps", UnitPrice: 150000}},
+		}))
+		reactivator.AssertExpectations(t)
+	})
+}
+
+func TestInvoiceService_AllocatePayment(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	invoice := &entity.Payment{ID: "p1", TenantID: tenantID, CustomerID: "c1", Amount: 150000, Status: entity.PaymentStatusPending}
+
+	t.Run("Fills Allocation From Invoice", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+
+		paid := *invoice
+		paid.PaidAmount = 150000
+		paid.Status = entity.PaymentStatusPaid
+		repo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+		repo.On("AddAllocation", ctx, mock.MatchedBy(func(a *entity.PaymentAllocation) bool {
+			return a.TenantID == tenantID && a.CustomerID == "c1" && a.PaymentID == "p1" && !a.PaidAt.IsZero()
+		})).Return(&paid, nil)
+
+		result, err := service.AllocatePayment(ctx, tenantID, "p1", &entity.PaymentAllocation{Amount: 150000, PaymentMethod: "cash"})
+		require.NoError(t, err)
+		assert.Equal(t, entity.PaymentStatusPaid, result.Status)
+		assert.Equal(t, 0.0, result.Balance())
+	})
+
+	t.Run("Reactivates Only Once Paid", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		reactivator := new(MockPaymentReactivator)
+		service := NewInvoiceService(repo, nil)
+		service.SetPaymentReactivator(reactivator)
+
+		partial := *invoice
+		partial.PaidAmount = 50000
+		partial.Status = entity.PaymentStatusPartiallyPaid
+		paid := partial
+		paid.PaidAmount = 150000
+		paid.Status = entity.PaymentStatusPaid
+		repo.On("FindInvoice", ctx, "p1").Return(invoice, nil).Once()
+		repo.On("AddAllocation", ctx, mock.Anything).Return(&partial, nil).Once()
+		repo.On("FindInvoice", ctx, "p1").Return(&partial, nil).Once()
+		repo.On("AddAllocation", ctx, mock.Anything).Return(&paid, nil).Once()
+		reactivator.On("HandlePaymentPaid", ctx, &paid).Return(nil).Once()
+
+		_, err := service.AllocatePayment(ctx, tenantID, "p1", &entity.PaymentAllocation{Amount: 50000, PaymentMethod: "cash"})
+		require.NoError(t, err)
+		_, err = service.AllocatePayment(ctx, tenantID, "p1", &entity.PaymentAllocation{Amount: 100000, PaymentMethod: "cash"})
+		require.NoError(t, err)
+		reactivator.AssertExpectations(t)
+	})
+
+	t.Run("Passes Repository Error Through", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+
+		repo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+		repo.On("AddAllocation", ctx, mock.Anything).Return(nil, errors.NewValidationError("payment is already paid"))
+
+		_, err := service.AllocatePayment(ctx, tenantID, "p1", &entity.PaymentAllocation{Amount: 200000})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already paid")
+	})
+
+	t.Run("Rejects Other Tenant", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+
+		repo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+
+		_, err := service.AllocatePayment(ctx, "tenant-2", "p1", &entity.PaymentAllocation{Amount: 1000})
+		assert.Equal(t, errors.ErrUnauthorized, err)
+		repo.AssertNotCalled(t, "AddAllocation", mock.Anything, mock.Anything)
+	})
+}
+
+func TestInvoiceService_IssueCreditNote(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	paidInvoice := func() *entity.Payment {
+		return &entity.Payment{
+			ID: "p1", TenantID: tenantID, CustomerID: "c1", Amount: 150000, PaidAmount: 150000,
+			Status: entity.PaymentStatusPaid,
+			Allocations: []entity.PaymentAllocation{
+				{Amount: 150000, PaymentMethod: "midtrans_qris", Reference: "NET-000012-1"},
+			},
+		}
+	}
+
+	t.Run("Credit Within Balance Needs No Refund", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+
+		invoice := &entity.Payment{ID: "p1", TenantID: tenantID, CustomerID: "c1", Amount: 150000, PaidAmount: 50000}
+		repo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+		repo.On("AddCreditNote", ctx, mock.Anything).Return(invoice, nil)
+
+		note := &entity.CreditNote{Amount: 100000, Reason: "Gangguan 10 hari", RefundMethod: "cash"}
+		_, err := service.IssueCreditNote(ctx, tenantID, "p1", note)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, note.RefundAmount)
+		assert.Empty(t, note.RefundMethod)
+		assert.Equal(t, "c1", note.CustomerID)
+	})
+
+	t.Run("Paid Invoice Requires Refund Method", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+		repo.On("FindInvoice", ctx, "p1").Return(paidInvoice(), nil)
+
+		_, err := service.IssueCreditNote(ctx, tenantID, "p1", &entity.CreditNote{Amount: 50000, Reason: "Gangguan"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "refund_method")
+		repo.AssertNotCalled(t, "AddCreditNote", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Refunds Through Midtrans", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		settingsRepo := new(MockSettingsRepository)
+		gateway := new(MockPaymentGateway)
+		service := NewInvoiceService(repo, settingsRepo).(*invoiceService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return gateway }
+
+		repo.On("FindInvoice", ctx, "p1").Return(paidInvoice(), nil)
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(&entity.TenantSettings{
+			MidtransEnabled: true, MidtransServerKey: "SB-Mid-server-x",
+		}, nil)
+		gateway.On("Refund", ctx, mock.MatchedBy(func(p *payment.RefundParams) bool {
+			return p.OrderID == "NET-000012-1" && p.Amount == 50000 && p.RefundKey != ""
+		})).Return(&payment.Refund{RefundID: "rf-1", Status: payment.StatusRefunded}, nil)
+		repo.On("ReserveCreditNote", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*entity.CreditNote).ID = "cn-1"
+		}).Return(nil)
+		repo.On("CompleteCreditNote", ctx, mock.MatchedBy(func(n *entity.CreditNote) bool {
+			return n.RefundReference == "rf-1"
+		})).Return(paidInvoice(), nil)
+
+		note := &entity.CreditNote{Amount: 50000, Reason: "Gangguan", RefundMethod: entity.CreditNoteRefundMidtrans}
+		_, err := service.IssueCreditNote(ctx, tenantID, "p1", note)
+		require.NoError(t, err)
+		assert.Equal(t, 50000.0, note.RefundAmount)
+		assert.Equal(t, "rf-1", note.RefundReference)
+		repo.AssertNotCalled(t, "AddCreditNote", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Refused Midtrans Refund Fails The Note", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		settingsRepo := new(MockSettingsRepository)
+		gateway := new(MockPaymentGateway)
+		reactivator := new(MockPaymentReactivator)
+		service := NewInvoiceService(repo, settingsRepo).(*invoiceService)
+		service.newGateway = func(*entity.TenantSettings) payment.PaymentGateway { return gateway }
+		service.SetPaymentReactivator(reactivator)
+
+		repo.On("FindInvoice", ctx, "p1").Return(paidInvoice(), nil)
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(&entity.TenantSettings{
+			MidtransEnabled: true, MidtransServerKey: "SB-Mid-server-x",
+		}, nil)
+		gateway.On("Refund", ctx, mock.Anything).Return(nil, assert.AnError)
+		repo.On("ReserveCreditNote", ctx, mock.Anything).Return(nil)
+		repo.On("FailCreditNote", ctx, mock.Anything).Return(nil)
+
+		_, err := service.IssueCreditNote(ctx, tenantID, "p1", &entity.CreditNote{Amount: 50000, Reason: "Gangguan", RefundMethod: entity.CreditNoteRefundMidtrans})
+		require.Error(t, err)
+		assert.Equal(t, 502, err.(*errors.AppError).Status)
+		repo.AssertCalled(t, "FailCreditNote", ctx, mock.Anything)
+		repo.AssertNotCalled(t, "CompleteCreditNote", mock.Anything, mock.Anything)
+		reactivator.AssertNotCalled(t, "HandlePaymentPaid", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Credit Above Total", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+		repo.On("FindInvoice", ctx, "p1").Return(paidInvoice(), nil)
+
+		_, err := service.IssueCreditNote(ctx, tenantID, "p1", &entity.CreditNote{Amount: 200000, Reason: "x", RefundMethod: "cash"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "creditable amount")
+	})
+}
+
+func TestInvoiceService_AdjustCustomerBalance(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+
+	t.Run("Records Manual Credit", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+		repo.On("AddLedgerEntry", ctx, mock.MatchedBy(func(e *entity.CustomerLedgerEntry) bool {
+			return e.TenantID == tenantID && e.Source == entity.LedgerSourceManual && e.Amount == 25000.5
+		})).Return(nil)
+
+		entry, err := service.AdjustCustomerBalance(ctx, tenantID, &entity.CustomerLedgerEntry{
+			CustomerID: "c1", Type: entity.LedgerEntryCredit, Amount: 25000.499, Reason: "Kompensasi gangguan",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "c1", entry.CustomerID)
+	})
+
+	t.Run("Requires Reason And Type", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+
+		_, err := service.AdjustCustomerBalance(ctx, tenantID, &entity.CustomerLedgerEntry{CustomerID: "c1", Type: entity.LedgerEntryDebit, Amount: 1000})
+		assert.Contains(t, err.Error(), "reason")
+		_, err = service.AdjustCustomerBalance(ctx, tenantID, &entity.CustomerLedgerEntry{CustomerID: "c1", Type: "refund", Amount: 1000, Reason: "x"})
+		assert.Contains(t, err.Error(), "credit or debit")
+		repo.AssertNotCalled(t, "AddLedgerEntry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Passes Insufficient Balance Through", func(t *testing.T) {
+		repo := new(MockInvoiceRepository)
+		service := NewInvoiceService(repo, nil)
+		repo.On("AddLedgerEntry", ctx, mock.Anything).Return(errors.NewValidationError("amount exceeds the customer balance of 10000.00"))
+
+		_, err := service.AdjustCustomerBalance(ctx, tenantID, &entity.CustomerLedgerEntry{
+			CustomerID: "c1", Type: entity.LedgerEntryDebit, Amount: 20000, Reason: "Koreksi",
+		})
+		require.Error(t, err)
+		assert.Equal(t, 400, err.(*errors.AppError).Status)
+	})
+}
+
+func TestInvoiceService_GetCustomerStatement(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockInvoiceRepository)
+	service := NewInvoiceService(repo, nil)
+
+	repo.On("FindLedgerEntries", ctx, "tenant-1", "c1", 50).Return([]*entity.CustomerLedgerEntry{
+		{Type: entity.LedgerEntryDebit, Source: entity.LedgerSourceInvoice, Amount: 150000, BalanceAfter: 50000},
+		{Type: entity.LedgerEntryCredit, Source: entity.LedgerSourceOverpayment, Amount: 200000, BalanceAfter: 200000},
+	}, nil)
+	repo.On("FindLedgerEntries", ctx, "tenant-1", "c2", 50).Return([]*entity.CustomerLedgerEntry{}, nil)
+	repo.On("SumLedger", ctx, "tenant-1", "c1").Return(50000.0, nil)
+	repo.On("SumLedger", ctx, "tenant-1", "c2").Return(0.0, nil)
+
+	statement, err := service.GetCustomerStatement(ctx, "tenant-1", "c1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 50000.0, statement.Balance)
+	assert.Len(t, statement.Entries, 2)
+
+	statement, err = service.GetCustomerStatement(ctx, "tenant-1", "c2", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, statement.Balance)
+}
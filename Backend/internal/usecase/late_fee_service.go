@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// LateFeeService charges the tenant's LateFee on invoices that are still
+// unpaid after the grace period. The fee is stored next to the invoice
+// amount so both stay visible, and operators can waive it.
+type LateFeeService interface {
+	// ApplyLateFees charges the late fee once on every invoice of the tenant
+	// that is past the grace period
+	ApplyLateFees(ctx context.Context, tenantID string, asOf time.Time) (*LateFeeResult, error)
+	// ApplyAllTenants runs ApplyLateFees for every active tenant
+	ApplyAllTenants(ctx context.Context, asOf time.Time) error
+	WaiveLateFee(ctx context.Context, tenantID, paymentID, userID, reason string) (*entity.Payment, error)
+}
+
+// LateFeeResult summarizes one ApplyLateFees run
+type LateFeeResult struct {
+	TenantID    string  `json:"tenant_id"`
+	Applied     int     `json:"applied"`
+	TotalAmount float64 `json:"total_amount"`
+	Failed      int     `json:"failed"`
+}
+
+type lateFeeService struct {
+	lateFeeRepo  repository.LateFeeRepository
+	paymentRepo  repository.PaymentRepository
+	settingsRepo repository.SettingsRepository
+	tenantRepo   repository.TenantRepository
+	reactivator  PaymentReactivator
+}
+
+// NewLateFeeService creates the late fee service. The reactivator runs for
+// invoices a waiver settles, like for any other paid invoice.
+func NewLateFeeService(
+	lateFeeRepo repository.LateFeeRepository,
+	paymentRepo repository.PaymentRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+	reactivator PaymentReactivator,
+) LateFeeService {
+	return &lateFeeService{
+		lateFeeRepo:  lateFeeRepo,
+		paymentRepo:  paymentRepo,
+		settingsRepo: settingsRepo,
+		tenantRepo:   tenantRepo,
+		reactivator:  reactivator,
+	}
+}
+
+// lateFeeAmount returns the fee for an invoice of the given amount
+func lateFeeAmount(settings *entity.TenantSettings, amount float64) float64 {
+	fee := settings.LateFee
+	if settings.LateFeeType == entity.LateFeeTypePercentage {
+		fee = amount * settings.LateFee / 100
+	}
+	return entity.RoundMoney(fee)
+}
+
+func (s *lateFeeService) ApplyLateFees(ctx context.Context, tenantID string, asOf time.Time) (*LateFeeResult, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		settings = defaultTenantSettings(tenantID)
+	} else if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+
+	result := &LateFeeResult{TenantID: tenantID}
+	if settings.LateFee <= 0 {
+		return result, nil
+	}
+
+	candidates, err := s.lateFeeRepo.FindLateFeeCandidates(ctx, tenantID, overdueThreshold(settings, dateOf(asOf)))
+	if err != nil {
+		return nil, errors.NewDatabaseError("find overdue invoices", err)
+	}
+
+	for _, payment := range candidates {
+		fee := lateFeeAmount(settings, payment.Amount)
+		if fee <= 0 {
+			continue
+		}
+
+		item := &entity.InvoiceItem{
+			TenantID:    tenantID,
+			PaymentID:   payment.ID,
+			Type:        entity.InvoiceItemLateFee,
+			Description: "Late fee",
+			Quantity:    1,
+			UnitPrice:   fee,
+			Amount:      fee,
+		}
+		// The conditional update keeps the fee to one charge per invoice even
+		// when two runs overlap
+		applied, err := s.lateFeeRepo.ApplyLateFee(ctx, item, time.Now())
+		if err != nil {
+			logger.Error("Late fee: failed to charge invoice %s: %v", payment.ID, err)
+			result.Failed++
+			continue
+		}
+		if !applied {
+			continue
+		}
+
+		result.Applied++
+		result.TotalAmount += fee
+
+		paymentID := payment.ID
+		s.logAction(ctx, &entity.BillingActionLog{
+			TenantID:   tenantID,
+			CustomerID: payment.CustomerID,
+			PaymentID:  &paymentID,
+			Action:     entity.BillingActionLateFee,
+			Reason: fmt.Sprintf("Late fee %.2f charged on invoice due %s",
+				fee, payment.DueDate.Format("02/01/2006")),
+		})
+	}
+
+	logger.Info("Late fees for tenant %s: applied=%d total=%.2f failed=%d",
+		tenantID, result.Applied, result.TotalAmount, result.Failed)
+	return result, nil
+}
+
+func (s *lateFeeService) WaiveLateFee(ctx context.Context, tenantID, paymentID, userID, reason string) (*entity.Payment, error) {
+	payment, err := s.paymentRepo.FindByID(ctx, paymentID)
+	if err != nil || payment == nil {
+		return nil, errors.ErrNotFound
+	}
+	if payment.TenantID != tenantID {
+		return nil, errors.ErrUnauthorized
+	}
+	if payment.LateFeeAppliedAt == nil {
+		return nil, errors.NewValidationError("payment has no late fee")
+	}
+	if payment.LateFeeWaivedAt != nil {
+		return nil, errors.NewValidationError("late fee is already waived")
+	}
+	if payment.Status == entity.PaymentStatusPaid {
+		return nil, errors.NewValidationError("cannot waive the late fee of a paid invoice")
+	}
+
+	var waivedBy *string
+	if userID != "" {
+		waivedBy = &userID
+	}
+	// The waiver re-settles the invoice, which is paid when the payments
+	// made so far cover it without the fee
+	waivedPayment, waived, err := s.lateFeeRepo.WaiveLateFee(ctx, payment.ID, waivedBy, reason, time.Now())
+	if err != nil {
+		return nil, errors.NewDatabaseError("waive late fee", err)
+	}
+	if !waived {
+		return nil, errors.NewValidationError("late fee is already waived")
+	}
+	payment = waivedPayment
+
+	s.logAction(ctx, &entity.BillingActionLog{
+		TenantID:   tenantID,
+		CustomerID: payment.CustomerID,
+		PaymentID:  &paymentID,
+		Action:     entity.BillingActionLateFeeWaived,
+		Reason:     reason,
+	})
+	// A settled invoice reactivates the customer and extends prepaid service
+	if payment.Status == entity.PaymentStatusPaid && s.reactivator != nil {
+		if err := s.reactivator.HandlePaymentPaid(ctx, payment); err != nil {
+			logger.Error("Auto-reactivation after late fee waiver on payment %s failed: %v", payment.ID, err)
+		}
+	}
+	return payment, nil
+}
+
+func (s *lateFeeService) logAction(ctx context.Context, action *entity.BillingActionLog) {
+	if err := s.lateFeeRepo.CreateAction(ctx, action); err != nil {
+		logger.Error("Failed to log billing action %s for customer %s: %v", action.Action, action.CustomerID, err)
+	}
+	logger.Info("Billing action %s for customer %s: %s", action.Action, action.CustomerID, action.Reason)
+}
+
+func (s *lateFeeService) ApplyAllTenants(ctx context.Context, asOf time.Time) error {
+	tenants, err := s.tenantRepo.FindAll(ctx)
+	if err != nil {
+		return errors.NewDatabaseError("load tenants", err)
+	}
+
+	for _, tenant := range tenants {
+		if !tenant.IsActive {
+			continue
+		}
+		if _, err := s.ApplyLateFees(ctx, tenant.ID, asOf); err != nil {
+			logger.Error("Late fees for tenant %s failed: %v", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartLateFeeJob runs ApplyAllTenants now and then on every interval
+func StartLateFeeJob(service LateFeeService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.ApplyAllTenants(context.Background(), time.Now()); err != nil {
+				logger.Error("Late fee job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Late fee job started (interval: %s)", interval)
+}
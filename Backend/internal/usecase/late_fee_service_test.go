@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockLateFeeRepository struct {
+	mock.Mock
+}
+
+func (m *MockLateFeeRepository) FindLateFeeCandidates(ctx context.Context, tenantID string, dueBefore time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, dueBefore)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) ApplyLateFee(ctx context.Context, item *entity.InvoiceItem, at time.Time) (bool, error) {
+	args := m.Called(ctx, item, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) WaiveLateFee(ctx context.Context, paymentID string, waivedBy *string, reason string, at time.Time) (*entity.Payment, bool, error) {
+	args := m.Called(ctx, paymentID, waivedBy, reason, at)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*entity.Payment), args.Bool(1), args.Error(2)
+}
+
+func (m *MockLateFeeRepository) CreateAction(ctx context.Context, action *entity.BillingActionLog) error {
+	args := m.Called(ctx, action)
+	return args.Error(0)
+}
+
+// MockPaymentRepository only implements the lookups used by the billing
+// services; other methods panic through the nil embedded interface
+type MockPaymentRepository struct {
+	repository.PaymentRepository
+	mock.Mock
+}
+
+func (m *MockPaymentRepository) FindByID(ctx context.Context, id string) (*entity.Payment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func TestLateFeeAmount(t *testing.T) {
+	fixed := &entity.TenantSettings{LateFee: 25000, LateFeeType: entity.LateFeeTypeFixed}
+	assert.Equal(t, 25000.0, lateFeeAmount(fixed, 150000))
+
+	percentage := &entity.TenantSettings{LateFee: 2.5, LateFeeType: entity.LateFeeTypePercentage}
+	assert.Equal(t, 3750.0, lateFeeAmount(percentage, 150000))
+	assert.Equal(t, 3.33, lateFeeAmount(percentage, 133.3))
+}
+
+func TestPayment_Total(t *testing.T) {
+	payment := &entity.Payment{Amount: 150000, LateFee: 10000}
+	assert.Equal(t, 160000.0, payment.Total())
+
+	waivedAt := time.Now()
+	payment.LateFeeWaivedAt = &waivedAt
+	assert.Equal(t, 150000.0, payment.Total())
+	assert.Equal(t, 10000.0, payment.LateFee)
+}
+
+func TestLateFeeService_ApplyLateFees(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	asOf := time.Date(2025, 3, 20, 1, 0, 0, 0, time.UTC)
+
+	t.Run("Charges Once Per Invoice", func(t *testing.T) {
+		repo := new(MockLateFeeRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewLateFeeService(repo, nil, settingsRepo, nil, nil)
+
+		settings := &entity.TenantSettings{GracePeriodDays: 7, LateFee: 10, LateFeeType: entity.LateFeeTypePercentage}
+		candidates := []*entity.Payment{
+			{ID: "p1", TenantID: tenantID, CustomerID: "c1", Amount: 150000, DueDate: billingDate(2025, 3, 1)},
+			{ID: "p2", TenantID: tenantID, CustomerID: "c2", Amount: 200000, DueDate: billingDate(2025, 3, 5)},
+		}
+
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindLateFeeCandidates", ctx, tenantID, billingDate(2025, 3, 13)).Return(candidates, nil)
+		repo.On("ApplyLateFee", ctx, mock.MatchedBy(func(i *entity.InvoiceItem) bool {
+			return i.PaymentID == "p1" && i.Type == entity.InvoiceItemLateFee && i.Amount == 15000
+		}), mock.Anything).Return(true, nil)
+		// Charged by an overlapping run in the meantime
+		repo.On("ApplyLateFee", ctx, mock.MatchedBy(func(i *entity.InvoiceItem) bool {
+			return i.PaymentID == "p2" && i.Amount == 20000
+		}), mock.Anything).Return(false, nil)
+		repo.On("CreateAction", ctx, mock.MatchedBy(func(a *entity.BillingActionLog) bool {
+			return a.Action == entity.BillingActionLateFee && a.CustomerID == "c1" && *a.PaymentID == "p1"
+		})).Return(nil).Once()
+
+		result, err := service.ApplyLateFees(ctx, tenantID, asOf)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Applied)
+		assert.Equal(t, 15000.0, result.TotalAmount)
+		assert.Equal(t, 0, result.Failed)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Disabled Without Late Fee", func(t *testing.T) {
+		repo := new(MockLateFeeRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewLateFeeService(repo, nil, settingsRepo, nil, nil)
+
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(&entity.TenantSettings{GracePeriodDays: 7}, nil)
+
+		result, err := service.ApplyLateFees(ctx, tenantID, asOf)
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Applied)
+		repo.AssertNotCalled(t, "FindLateFeeCandidates", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLateFeeService_WaiveLateFee(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	appliedAt := time.Now().AddDate(0, 0, -2)
+
+	t.Run("Waives With Reason", func(t *testing.T) {
+		repo := new(MockLateFeeRepository)
+		paymentRepo := new(MockPaymentRepository)
+		reactivator := new(MockPaymentReactivator)
+		service := NewLateFeeService(repo, paymentRepo, nil, nil, reactivator)
+
+		payment := &entity.Payment{ID: "p1", TenantID: tenantID, CustomerID: "c1", Amount: 150000, LateFee: 15000,
+			LateFeeAppliedAt: &appliedAt, Status: entity.PaymentStatusOverdue}
+		userID := "user-1"
+		paymentRepo.On("FindByID", ctx, "p1").Return(payment, nil)
+		waivedAt := time.Now()
+		settled := *payment
+		settled.LateFeeWaivedAt = &waivedAt
+		repo.On("WaiveLateFee", ctx, "p1", &userID, "network outage", mock.Anything).Return(&settled, true, nil)
+		repo.On("CreateAction", ctx, mock.MatchedBy(func(a *entity.BillingActionLog) bool {
+			return a.Action == entity.BillingActionLateFeeWaived && a.Reason == "network outage"
+		})).Return(nil)
+
+		waived, err := service.WaiveLateFee(ctx, tenantID, "p1", userID, "network outage")
+		require.NoError(t, err)
+		assert.NotNil(t, waived.LateFeeWaivedAt)
+		assert.Equal(t, 150000.0, waived.Total())
+		repo.AssertExpectations(t)
+		reactivator.AssertNotCalled(t, "HandlePaymentPaid", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reactivates When The Waiver Settles The Invoice", func(t *testing.T) {
+		repo := new(MockLateFeeRepository)
+		paymentRepo := new(MockPaymentRepository)
+		reactivator := new(MockPaymentReactivator)
+		service := NewLateFeeService(repo, paymentRepo, nil, nil, reactivator)
+
+		payment := &entity.Payment{ID: "p1", TenantID: tenantID, CustomerID: "c1", Amount: 150000, PaidAmount: 150000, LateFee: 15000,
+			LateFeeAppliedAt: &appliedAt, Status: entity.PaymentStatusOverdue}
+		paymentRepo.On("FindByID", ctx, "p1").Return(payment, nil)
+		waivedAt := time.Now()
+		settled := *payment
+		settled.LateFeeWaivedAt = &waivedAt
+		settled.Status = entity.PaymentStatusPaid
+		settled.PaymentMethod = entity.PaymentMethodLateFeeWaiver
+		repo.On("WaiveLateFee", ctx, "p1", mock.Anything, "goodwill", mock.Anything).Return(&settled, true, nil)
+		repo.On("CreateAction", ctx, mock.Anything).Return(nil)
+		reactivator.On("HandlePaymentPaid", ctx, &settled).Return(nil)
+
+		waived, err := service.WaiveLateFee(ctx, tenantID, "p1", "user-1", "goodwill")
+		require.NoError(t, err)
+		assert.Equal(t, entity.PaymentStatusPaid, waived.Status)
+		reactivator.AssertExpectations(t)
+	})
+
+	t.Run("Rejects Paid Invoice", func(t *testing.T) {
+		repo := new(MockLateFeeRepository)
+		paymentRepo := new(MockPaymentRepository)
+		service := NewLateFeeService(repo, paymentRepo, nil, nil, nil)
+
+		payment := &entity.Payment{ID: "p1", TenantID: tenantID, LateFee: 15000, LateFeeAppliedAt: &appliedAt, Status: entity.PaymentStatusPaid}
+		paymentRepo.On("FindByID", ctx, "p1").Return(payment, nil)
+
+		_, err := service.WaiveLateFee(ctx, tenantID, "p1", "user-1", "goodwill")
+		assert.Error(t, err)
+		repo.AssertNotCalled(t, "WaiveLateFee", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Other Tenant", func(t *testing.T) {
+		paymentRepo := new(MockPaymentRepository)
+		service := NewLateFeeService(new(MockLateFeeRepository), paymentRepo, nil, nil, nil)
+
+		paymentRepo.On("FindByID", ctx, "p1").Return(&entity.Payment{ID: "p1", TenantID: "tenant-2"}, nil)
+
+		_, err := service.WaiveLateFee(ctx, tenantID, "p1", "user-1", "goodwill")
+		assert.Error(t, err)
+	})
+}
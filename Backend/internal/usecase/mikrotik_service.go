@@ -2,144 +2,325 @@ package usecase
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/routeros"
 )
 
+// MikrotikService talks to tenant routers through a shared per-device
+// connection pool. Every call borrows a session for the given device and
+// returns it afterwards, so concurrent operations on different routers never
+// share state.
 type MikrotikService interface {
-	Connect(ctx context.Context, host, port, username, password string) error
-	Disconnect() error
-	CreateQueue(ctx context.Context, queueName, targetIP string, maxUpload, maxDownload int) error
-	UpdateQueue(ctx context.Context, queueName string, maxUpload, maxDownload int) error
-	DeleteQueue(ctx context.Context, queueName string) error
-	GetQueueList(ctx context.Context) ([]MikrotikQueue, error)
-	TestConnection(ctx context.Context, host, port, username, password string) (bool, error)
+	CreateQueue(ctx context.Context, device *entity.Device, queueName, targetIP string, maxUpload, maxDownload int) error
+	UpdateQueue(ctx context.Context, device *entity.Device, queueName string, maxUpload, maxDownload int) error
+	DeleteQueue(ctx context.Context, device *entity.Device, queueName string) error
+	GetQueueList(ctx context.Context, device *entity.Device) ([]MikrotikQueue, error)
+	AddQueue(ctx context.Context, device *entity.Device, queue MikrotikQueue) error
+	SetQueue(ctx context.Context, device *entity.Device, queue MikrotikQueue) error
+	RemoveQueue(ctx context.Context, device *entity.Device, queueID string) error
+	TestConnection(ctx context.Context, device *entity.Device) (bool, error)
+	ForgetDevice(deviceID string)
 }
 
 type mikrotikService struct {
-	// Connection details would be stored here
-	connected bool
-	host      string
-	port      string
-	username  string
-	password  string
+	pool    *routeros.Pool
+	timeout time.Duration
 }
 
+// MikrotikQueue is a simple queue as seen on the router. Rates are in bits
+// per second; ID is the router's internal .id and is empty for new queues.
 type MikrotikQueue struct {
-	Name        string `json:"name"`
-	Target      string `json:"target"`
-	MaxUpload   int    `json:"max_upload"`
-	MaxDownload int    `json:"max_download"`
-	Disabled    bool   `json:"disabled"`
+	ID                     string `json:"id,omitempty"`
+	Name                   string `json:"name"`
+	Target                 string `json:"target"`
+	MaxUpload              int    `json:"max_upload"`
+	MaxDownload            int    `json:"max_download"`
+	BurstLimitUpload       int    `json:"burst_limit_upload,omitempty"`
+	BurstLimitDownload     int    `json:"burst_limit_download,omitempty"`
+	BurstThresholdUpload   int    `json:"burst_threshold_upload,omitempty"`
+	BurstThresholdDownload int    `json:"burst_threshold_download,omitempty"`
+	BurstTime              int    `json:"burst_time,omitempty"` // seconds
+	Priority               int    `json:"priority,omitempty"`   // 1 (highest) - 8
+	Parent                 string `json:"parent,omitempty"`
+	Comment                string `json:"comment,omitempty"`
+	Disabled               bool   `json:"disabled"`
 }
 
-func NewMikrotikService() MikrotikService {
+func NewMikrotikService(pool *routeros.Pool) MikrotikService {
 	return &mikrotikService{
-		connected: false,
-	}
-}
-
-func (s *mikrotikService) Connect(ctx context.Context, host, port, username, password string) error {
-	// TODO: Implement actual Mikrotik API connection using routeros library
-	// For now, this is a placeholder implementation
-	
-	s.host = host
-	s.port = port
-	s.username = username
-	s.password = password
-	s.connected = true
-	
-	return nil
-}
-
-func (s *mikrotikService) Disconnect() error {
-	if !s.connected {
-		return errors.NewValidationError("not connected to Mikrotik")
-	}
-	
-	s.connected = false
-	return nil
-}
-
-func (s *mikrotikService) CreateQueue(ctx context.Context, queueName, targetIP string, maxUpload, maxDownload int) error {
-	if !s.connected {
-		return errors.NewValidationError("not connected to Mikrotik")
-	}
-	
-	// TODO: Implement actual queue creation via Mikrotik API
-	// Example command: /queue simple add name=queueName target=targetIP max-limit=upload/download
-	
-	// Placeholder implementation
-	fmt.Printf("Creating queue: %s for %s (Upload: %d, Download: %d)\n", 
-		queueName, targetIP, maxUpload, maxDownload)
-	
-	return nil
-}
-
-func (s *mikrotikService) UpdateQueue(ctx context.Context, queueName string, maxUpload, maxDownload int) error {
-	if !s.connected {
-		return errors.NewValidationError("not connected to Mikrotik")
-	}
-	
-	// TODO: Implement actual queue update via Mikrotik API
-	// Example command: /queue simple set [find name=queueName] max-limit=upload/download
-	
-	// Placeholder implementation
-	fmt.Printf("Updating queue: %s (Upload: %d, Download: %d)\n", 
-		queueName, maxUpload, maxDownload)
-	
-	return nil
-}
-
-func (s *mikrotikService) DeleteQueue(ctx context.Context, queueName string) error {
-	if !s.connected {
-		return errors.NewValidationError("not connected to Mikrotik")
-	}
-	
-	// TODO: Implement actual queue deletion via Mikrotik API
-	// Example command: /queue simple remove [find name=queueName]
-	
-	// Placeholder implementation
-	fmt.Printf("Deleting queue: %s\n", queueName)
-	
-	return nil
-}
-
-func (s *mikrotikService) GetQueueList(ctx context.Context) ([]MikrotikQueue, error) {
-	if !s.connected {
-		return nil, errors.NewValidationError("not connected to Mikrotik")
-	}
-	
-	// TODO: Implement actual queue list retrieval via Mikrotik API
-	// Example command: /queue simple print
-	
-	// Placeholder implementation
-	queues := []MikrotikQueue{
-		{
-			Name:        "customer-001",
-			Target:      "192.168.1.100/32",
-			MaxUpload:   10000000,  // 10 Mbps
-			MaxDownload: 20000000,  // 20 Mbps
-			Disabled:    false,
-		},
-	}
-	
+		pool:    pool,
+		timeout: routeros.DefaultTimeout,
+	}
+}
+
+// routerConfig builds API connection settings for a device. Port 8729
+// (api-ssl) uses TLS; RouterOS ships api-ssl with a self-signed certificate
+// so verification is skipped.
+func (s *mikrotikService) routerConfig(device *entity.Device) *routeros.Config {
+	port := device.MikrotikPort
+	if port == "" {
+		port = routeros.DefaultPort
+	}
+
+	return &routeros.Config{
+		Address:   net.JoinHostPort(device.IPAddress, port),
+		Username:  device.MikrotikUsername,
+		Password:  device.MikrotikPasswordEncrypted,
+		UseTLS:    port == routeros.DefaultTLSPort,
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		Timeout:   s.timeout,
+	}
+}
+
+// withRouter borrows a pooled session for the device and runs fn on it
+func (s *mikrotikService) withRouter(ctx context.Context, device *entity.Device, fn func(conn *routeros.Conn) error) error {
+	conn, err := s.pool.Acquire(ctx, device.ID, s.routerConfig(device))
+	if err != nil {
+		return fmt.Errorf("failed to connect to Mikrotik %s: %w", device.IPAddress, err)
+	}
+	defer conn.Release()
+
+	return fn(conn)
+}
+
+func (s *mikrotikService) CreateQueue(ctx context.Context, device *entity.Device, queueName, targetIP string, maxUpload, maxDownload int) error {
+	return s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		_, err := conn.Run(ctx, "/queue/simple/add",
+			"=name="+queueName,
+			"=target="+targetIP,
+			"=max-limit="+formatMaxLimit(maxUpload, maxDownload),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create queue %s: %w", queueName, err)
+		}
+		return nil
+	})
+}
+
+func (s *mikrotikService) UpdateQueue(ctx context.Context, device *entity.Device, queueName string, maxUpload, maxDownload int) error {
+	return s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		id, err := findQueueID(ctx, conn, queueName)
+		if err != nil {
+			return err
+		}
+
+		_, err = conn.Run(ctx, "/queue/simple/set",
+			"=.id="+id,
+			"=max-limit="+formatMaxLimit(maxUpload, maxDownload),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update queue %s: %w", queueName, err)
+		}
+		return nil
+	})
+}
+
+func (s *mikrotikService) DeleteQueue(ctx context.Context, device *entity.Device, queueName string) error {
+	return s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		id, err := findQueueID(ctx, conn, queueName)
+		if err != nil {
+			return err
+		}
+
+		if _, err := conn.Run(ctx, "/queue/simple/remove", "=.id="+id); err != nil {
+			return fmt.Errorf("failed to delete queue %s: %w", queueName, err)
+		}
+		return nil
+	})
+}
+
+func (s *mikrotikService) GetQueueList(ctx context.Context, device *entity.Device) ([]MikrotikQueue, error) {
+	var queues []MikrotikQueue
+	err := s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		reply, err := conn.Run(ctx, "/queue/simple/print")
+		if err != nil {
+			return fmt.Errorf("failed to list queues: %w", err)
+		}
+
+		queues = make([]MikrotikQueue, 0, len(reply.Re))
+		for _, re := range reply.Re {
+			queues = append(queues, parseQueue(re.Attrs))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return queues, nil
 }
 
-func (s *mikrotikService) TestConnection(ctx context.Context, host, port, username, password string) (bool, error) {
-	// TODO: Implement actual connection test
-	// Try to connect and execute a simple command like /system identity print
-	
-	// Placeholder implementation with timeout
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (s *mikrotikService) AddQueue(ctx context.Context, device *entity.Device, queue MikrotikQueue) error {
+	return s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		args := append([]string{"=name=" + queue.Name, "=target=" + queue.Target}, queueAttrWords(queue)...)
+		if _, err := conn.Run(ctx, "/queue/simple/add", args...); err != nil {
+			return fmt.Errorf("failed to create queue %s: %w", queue.Name, err)
+		}
+		return nil
+	})
+}
+
+// SetQueue overwrites name, target and shaping attributes of the queue
+// identified by queue.ID
+func (s *mikrotikService) SetQueue(ctx context.Context, device *entity.Device, queue MikrotikQueue) error {
+	return s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		args := append([]string{"=.id=" + queue.ID, "=name=" + queue.Name, "=target=" + queue.Target}, queueAttrWords(queue)...)
+		if _, err := conn.Run(ctx, "/queue/simple/set", args...); err != nil {
+			return fmt.Errorf("failed to update queue %s: %w", queue.Name, err)
+		}
+		return nil
+	})
+}
+
+func (s *mikrotikService) RemoveQueue(ctx context.Context, device *entity.Device, queueID string) error {
+	return s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		if _, err := conn.Run(ctx, "/queue/simple/remove", "=.id="+queueID); err != nil {
+			return fmt.Errorf("failed to delete queue %s: %w", queueID, err)
+		}
+		return nil
+	})
+}
+
+// TestConnection is an explicit operator action, so it clears any open
+// circuit for the device and always attempts a fresh round trip.
+func (s *mikrotikService) TestConnection(ctx context.Context, device *entity.Device) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	
-	// Simulate connection test
-	time.Sleep(100 * time.Millisecond)
-	
-	// For now, always return success
+
+	s.pool.Evict(device.ID)
+
+	err := s.withRouter(ctx, device, func(conn *routeros.Conn) error {
+		if _, err := conn.Run(ctx, "/system/identity/print"); err != nil {
+			return fmt.Errorf("Mikrotik did not answer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
+
+// ForgetDevice drops pooled sessions for a device (e.g. after deletion)
+func (s *mikrotikService) ForgetDevice(deviceID string) {
+	s.pool.Evict(deviceID)
+}
+
+// findQueueID looks up the internal .id of a simple queue by name
+func findQueueID(ctx context.Context, conn *routeros.Conn, queueName string) (string, error) {
+	reply, err := conn.Run(ctx, "/queue/simple/print", "=.proplist=.id", "?name="+queueName)
+	if err != nil {
+		return "", fmt.Errorf("failed to find queue %s: %w", queueName, err)
+	}
+	if len(reply.Re) == 0 {
+		return "", errors.NewNotFoundError(fmt.Sprintf("queue %s not found on Mikrotik", queueName))
+	}
+	return reply.Re[0].Attrs[".id"], nil
+}
+
+// queueAttrWords renders the shaping attributes of a queue as API words
+func queueAttrWords(q MikrotikQueue) []string {
+	parent := q.Parent
+	if parent == "" {
+		parent = "none"
+	}
+	priority := q.Priority
+	if priority == 0 {
+		priority = 8
+	}
+
+	return []string{
+		"=max-limit=" + formatMaxLimit(q.MaxUpload, q.MaxDownload),
+		"=burst-limit=" + formatMaxLimit(q.BurstLimitUpload, q.BurstLimitDownload),
+		"=burst-threshold=" + formatMaxLimit(q.BurstThresholdUpload, q.BurstThresholdDownload),
+		fmt.Sprintf("=burst-time=%ds/%ds", q.BurstTime, q.BurstTime),
+		fmt.Sprintf("=priority=%d/%d", priority, priority),
+		"=parent=" + parent,
+		"=comment=" + q.Comment,
+		"=disabled=" + strconv.FormatBool(q.Disabled),
+	}
+}
+
+// parseQueue converts a /queue/simple/print row into a MikrotikQueue
+func parseQueue(attrs map[string]string) MikrotikQueue {
+	q := MikrotikQueue{
+		ID:       attrs[".id"],
+		Name:     attrs["name"],
+		Target:   attrs["target"],
+		Comment:  attrs["comment"],
+		Disabled: attrs["disabled"] == "true",
+	}
+	q.MaxUpload, q.MaxDownload = parseMaxLimit(attrs["max-limit"])
+	q.BurstLimitUpload, q.BurstLimitDownload = parseMaxLimit(attrs["burst-limit"])
+	q.BurstThresholdUpload, q.BurstThresholdDownload = parseMaxLimit(attrs["burst-threshold"])
+
+	if burstTime, _, ok := strings.Cut(attrs["burst-time"], "/"); ok {
+		q.BurstTime = parseSeconds(burstTime)
+	}
+	if priority, _, ok := strings.Cut(attrs["priority"], "/"); ok {
+		q.Priority, _ = strconv.Atoi(priority)
+	}
+	if parent := attrs["parent"]; parent != "none" {
+		q.Parent = parent
+	}
+	return q
+}
+
+// parseSeconds parses RouterOS durations such as "10s", "1m30s" or "0s"
+func parseSeconds(value string) int {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		n, _ := strconv.Atoi(strings.TrimSpace(value))
+		return n
+	}
+	return int(d.Seconds())
+}
+
+// formatMaxLimit builds a RouterOS max-limit value ("upload/download" in bps)
+func formatMaxLimit(maxUpload, maxDownload int) string {
+	return fmt.Sprintf("%d/%d", maxUpload, maxDownload)
+}
+
+// parseMaxLimit parses "upload/download" where each side may carry a k/M/G suffix
+func parseMaxLimit(value string) (int, int) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0
+	}
+	return parseRate(parts[0]), parseRate(parts[1])
+}
+
+func parseRate(value string) int {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	multiplier := 1
+	switch value[len(value)-1] {
+	case 'k', 'K':
+		multiplier = 1000
+	case 'M':
+		multiplier = 1000000
+	case 'G':
+		multiplier = 1000000000
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return n * multiplier
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/routeros"
+	"github.com/rtrwnet/saas-backend/pkg/routeros/routerostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMikrotikService(t *testing.T) MikrotikService {
+	pool := routeros.NewPool(routeros.PoolConfig{DialRetries: 0})
+	t.Cleanup(pool.Close)
+	return NewMikrotikService(pool)
+}
+
+func testRouterDevice(srv *routerostest.Server, password string) *entity.Device {
+	return &entity.Device{
+		ID:                        "device-123",
+		IPAddress:                 srv.Host(),
+		MikrotikPort:              srv.Port(),
+		MikrotikUsername:          "admin",
+		MikrotikPasswordEncrypted: password,
+		MikrotikAPIEnabled:        true,
+	}
+}
+
+func TestMikrotikService_TestConnection(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+
+	service := newTestMikrotikService(t)
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		ok, err := service.TestConnection(ctx, testRouterDevice(srv, "secret"))
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Invalid Credentials", func(t *testing.T) {
+		ok, err := service.TestConnection(ctx, testRouterDevice(srv, "wrong"))
+
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		device := testRouterDevice(srv, "secret")
+		device.IPAddress = "127.0.0.1"
+		device.MikrotikPort = "1"
+
+		ok, err := service.TestConnection(ctx, device)
+
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestMikrotikService_Queues(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+	srv.AddQueue(map[string]string{"name": "existing", "target": "10.0.0.2/32", "max-limit": "5M/10M"})
+
+	service := newTestMikrotikService(t)
+	device := testRouterDevice(srv, "secret")
+	ctx := context.Background()
+
+	t.Run("Create", func(t *testing.T) {
+		err := service.CreateQueue(ctx, device, "customer-001", "10.0.0.10/32", 10000000, 20000000)
+		require.NoError(t, err)
+
+		queues, err := service.GetQueueList(ctx, device)
+		require.NoError(t, err)
+		require.Len(t, queues, 2)
+		assert.Equal(t, MikrotikQueue{ID: "*1", Name: "existing", Target: "10.0.0.2/32", MaxUpload: 5000000, MaxDownload: 10000000}, queues[0])
+		assert.Equal(t, MikrotikQueue{ID: "*2", Name: "customer-001", Target: "10.0.0.10/32", MaxUpload: 10000000, MaxDownload: 20000000}, queues[1])
+	})
+
+	t.Run("Create Duplicate", func(t *testing.T) {
+		err := service.CreateQueue(ctx, device, "customer-001", "10.0.0.10/32", 1, 1)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "already have such name")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		require.NoError(t, service.UpdateQueue(ctx, device, "customer-001", 3000000, 6000000))
+
+		queues := srv.Queues()
+		assert.Equal(t, "3000000/6000000", queues[1]["max-limit"])
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, service.DeleteQueue(ctx, device, "existing"))
+
+		queues := srv.Queues()
+		require.Len(t, queues, 1)
+		assert.Equal(t, "customer-001", queues[0]["name"])
+	})
+
+	t.Run("Delete Missing", func(t *testing.T) {
+		err := service.DeleteQueue(ctx, device, "nope")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not found")
+	})
+
+	t.Run("Add Set Remove", func(t *testing.T) {
+		queue := MikrotikQueue{
+			Name:                   "CUST001",
+			Target:                 "10.0.0.20/32",
+			MaxUpload:              5000000,
+			MaxDownload:            10000000,
+			BurstLimitUpload:       20000000,
+			BurstLimitDownload:     20000000,
+			BurstThresholdUpload:   8000000,
+			BurstThresholdDownload: 8000000,
+			BurstTime:              10,
+			Priority:               4,
+			Parent:                 "total",
+			Comment:                "rtrwnet:customer:cust-1",
+		}
+		require.NoError(t, service.AddQueue(ctx, device, queue))
+
+		queues, err := service.GetQueueList(ctx, device)
+		require.NoError(t, err)
+		got := queues[len(queues)-1]
+		queue.ID = got.ID
+		assert.Equal(t, queue, got)
+
+		queue.MaxDownload = 20000000
+		queue.Parent = ""
+		require.NoError(t, service.SetQueue(ctx, device, queue))
+		raw := srv.Queues()
+		assert.Equal(t, "5000000/20000000", raw[len(raw)-1]["max-limit"])
+		assert.Equal(t, "none", raw[len(raw)-1]["parent"])
+
+		require.NoError(t, service.RemoveQueue(ctx, device, queue.ID))
+		assert.Len(t, srv.Queues(), len(raw)-1)
+	})
+
+	t.Run("Reuses Session", func(t *testing.T) {
+		assert.Equal(t, 1, srv.ConnectionCount())
+	})
+}
+
+func TestParseMaxLimit(t *testing.T) {
+	up, down := parseMaxLimit("512k/2M")
+	assert.Equal(t, 512000, up)
+	assert.Equal(t, 2000000, down)
+
+	up, down = parseMaxLimit("0/0")
+	assert.Equal(t, 0, up)
+	assert.Equal(t, 0, down)
+
+	up, down = parseMaxLimit("")
+	assert.Equal(t, 0, up)
+	assert.Equal(t, 0, down)
+}
@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// NotificationDispatchService sends customers the notifications switched on
+// in the tenant's settings: new invoices, payment reminders, overdue notices,
+// suspension warnings and notices, payment confirmations and resolved
+// tickets. Messages are rendered from the tenant's notification templates.
+// Every message is logged per customer and sent once per invoice or ticket,
+// event and channel however often the dispatcher runs.
+type NotificationDispatchService interface {
+	// DispatchTenant sends the tenant's due notifications over every channel
+	DispatchTenant(ctx context.Context, tenantID string, now time.Time) (*NotificationRunResult, error)
+	// DispatchAllTenants runs DispatchTenant for every active tenant
+	DispatchAllTenants(ctx context.Context, now time.Time) error
+	// ListCustomerNotifications returns the delivery log of a customer, newest first
+	ListCustomerNotifications(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerNotification, error)
+}
+
+// CustomerMessage is a notification rendered for a customer. Channels send
+// the variant they support.
+type CustomerMessage struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// CustomerChannel delivers customer messages over one channel
+type CustomerChannel interface {
+	// Name returns the channel, one of the NotificationChannel constants
+	Name() string
+	// Recipient returns the customer's address on the channel, or "" when the
+	// tenant has the channel off or the customer has no address on it
+	Recipient(settings *entity.TenantSettings, customer *entity.Customer) string
+	Send(ctx context.Context, settings *entity.TenantSettings, customer *entity.Customer, recipient string, msg *CustomerMessage) error
+}
+
+// NotificationRunResult summarizes one DispatchTenant run
+type NotificationRunResult struct {
+	TenantID string `json:"tenant_id"`
+	Sent     int    `json:"sent"`
+	Failed   int    `json:"failed"`
+}
+
+const (
+	// Overdue notices are sent for invoices that fell due up to this many days ago
+	overdueNoticeDays = 30
+	// New invoices, payments, suspensions and resolved tickets are notified
+	// this far back, so a daily run covers the previous day
+	notificationLookback = 48 * time.Hour
+	// A failed notification is retried on later runs up to this many attempts
+	notificationMaxAttempts = 3
+)
+
+// customerNotice is a message about one invoice or ticket of a customer
+type customerNotice struct {
+	customer  *entity.Customer
+	paymentID *string
+	subjectID string // ID of the invoice or ticket, part of the dedup key
+	vars      TemplateVars
+}
+
+type notificationDispatchService struct {
+	notificationRepo repository.CustomerNotificationRepository
+	settingsRepo     repository.SettingsRepository
+	tenantRepo       repository.TenantRepository
+	templates        NotificationTemplateService
+	paymentPageURL   string
+	channels         []CustomerChannel
+}
+
+// NewNotificationDispatchService creates the customer notification dispatcher.
+// With a paymentPageURL, invoice messages of tenants taking online payments
+// link to <paymentPageURL>/<invoice_id>. Nothing is sent when channels is
+// empty.
+func NewNotificationDispatchService(
+	notificationRepo repository.CustomerNotificationRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+	templates NotificationTemplateService,
+	paymentPageURL string,
+	channels ...CustomerChannel,
+) NotificationDispatchService {
+	return &notificationDispatchService{
+		notificationRepo: notificationRepo,
+		settingsRepo:     settingsRepo,
+		tenantRepo:       tenantRepo,
+		templates:        templates,
+		paymentPageURL:   strings.TrimRight(paymentPageURL, "/"),
+		channels:         channels,
+	}
+}
+
+func (s *notificationDispatchService) loadSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		return defaultTenantSettings(tenantID), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	return settings, nil
+}
+
+func (s *notificationDispatchService) DispatchTenant(ctx context.Context, tenantID string, now time.Time) (*NotificationRunResult, error) {
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NotificationRunResult{TenantID: tenantID}
+	if len(s.channels) == 0 {
+		return result, nil
+	}
+	today := dateOf(now)
+
+	if settings.SendInvoiceNotification {
+		invoices, err := s.notificationRepo.FindIssuedInvoices(ctx, tenantID, now.Add(-notificationLookback))
+		if err != nil {
+			return nil, errors.NewDatabaseError("find new invoices", err)
+		}
+		s.dispatch(ctx, settings, entity.NotificationEventInvoiceCreated, s.invoiceNotices(settings, invoices), result)
+	}
+
+	// Prepaid customers are reminded of their service expiry by PrepaidService
+	if settings.SendPaymentReminder && settings.BillingType != entity.BillingTypePrepaid {
+		invoices, err := s.notificationRepo.FindUnpaidInvoices(ctx, tenantID, today, today.AddDate(0, 0, settings.ReminderDaysBefore+1))
+		if err != nil {
+			return nil, errors.NewDatabaseError("find invoices to remind", err)
+		}
+		s.dispatch(ctx, settings, entity.NotificationEventPaymentReminder, s.invoiceNotices(settings, invoices), result)
+	}
+
+	if settings.SendOverdueNotice {
+		invoices, err := s.notificationRepo.FindUnpaidInvoices(ctx, tenantID, today.AddDate(0, 0, -overdueNoticeDays), today)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find overdue invoices", err)
+		}
+		s.dispatch(ctx, settings, entity.NotificationEventOverdueNotice, s.invoiceNotices(settings, invoices), result)
+	}
+
+	// Customers are warned when AutoSuspendService would suspend them within
+	// WarningDaysBeforeSuspension days, and told once it did
+	if settings.SendSuspensionWarning && settings.AutoSuspendEnabled {
+		if settings.WarningDaysBeforeSuspension > 0 {
+			from := suspendThreshold(settings, today)
+			to := suspendThreshold(settings, today.AddDate(0, 0, settings.WarningDaysBeforeSuspension))
+			invoices, err := s.notificationRepo.FindUnpaidInvoices(ctx, tenantID, from, to)
+			if err != nil {
+				return nil, errors.NewDatabaseError("find invoices to warn", err)
+			}
+			active := withCustomerStatus(invoices, entity.CustomerStatusActive)
+			notices := s.invoiceNotices(settings, active)
+			for i, notice := range notices {
+				// The first day the invoice is past suspendThreshold
+				suspendOn := dateOf(active[i].DueDate).AddDate(0, 0, suspendAfterDays(settings)+1)
+				if suspendOn.Before(today) {
+					suspendOn = today
+				}
+				notice.vars["suspend_date"] = suspendOn
+			}
+			s.dispatch(ctx, settings, entity.NotificationEventSuspensionWarning, notices, result)
+		}
+
+		invoices, err := s.notificationRepo.FindSuspendedInvoices(ctx, tenantID, now.Add(-notificationLookback))
+		if err != nil {
+			return nil, errors.NewDatabaseError("find suspended invoices", err)
+		}
+		s.dispatch(ctx, settings, entity.NotificationEventSuspended, s.invoiceNotices(settings, withCustomerStatus(invoices, entity.CustomerStatusSuspended)), result)
+	}
+
+	if settings.SendPaymentConfirmation {
+		invoices, err := s.notificationRepo.FindPaidInvoices(ctx, tenantID, now.Add(-notificationLookback))
+		if err != nil {
+			return nil, errors.NewDatabaseError("find paid invoices", err)
+		}
+		notices := s.invoiceNotices(settings, invoices)
+		for i, notice := range notices {
+			notice.vars["amount"] = invoices[i].PaidAmount
+			if invoices[i].PaymentDate != nil {
+				notice.vars["paid_date"] = *invoices[i].PaymentDate
+			}
+		}
+		s.dispatch(ctx, settings, entity.NotificationEventPaymentConfirmation, notices, result)
+	}
+
+	if settings.SendTicketNotification {
+		tickets, err := s.notificationRepo.FindResolvedTickets(ctx, tenantID, now.Add(-notificationLookback))
+		if err != nil {
+			return nil, errors.NewDatabaseError("find resolved tickets", err)
+		}
+		notices := make([]*customerNotice, 0, len(tickets))
+		for _, ticket := range tickets {
+			notices = append(notices, &customerNotice{
+				customer:  ticket.Customer,
+				subjectID: ticket.ID,
+				vars:      ticketTemplateVars(ticket),
+			})
+		}
+		s.dispatch(ctx, settings, entity.NotificationEventTicketResolved, notices, result)
+	}
+
+	logger.Info("Customer notifications for tenant %s: sent=%d failed=%d", tenantID, result.Sent, result.Failed)
+	return result, nil
+}
+
+// withCustomerStatus returns the invoices whose customer has the status
+func withCustomerStatus(invoices []*entity.Payment, status string) []*entity.Payment {
+	var kept []*entity.Payment
+	for _, invoice := range invoices {
+		if invoice.Customer != nil && invoice.Customer.Status == status {
+			kept = append(kept, invoice)
+		}
+	}
+	return kept
+}
+
+// invoiceNotices returns a notice with the invoice variables for each invoice
+func (s *notificationDispatchService) invoiceNotices(settings *entity.TenantSettings, invoices []*entity.Payment) []*customerNotice {
+	notices := make([]*customerNotice, 0, len(invoices))
+	for _, invoice := range invoices {
+		paymentID := invoice.ID
+		number := invoice.ID
+		if invoice.InvoiceNumber != nil {
+			number = *invoice.InvoiceNumber
+		}
+		link := ""
+		if s.paymentPageURL != "" && settings.MidtransEnabled {
+			link = s.paymentPageURL + "/" + invoice.ID
+		}
+		notices = append(notices, &customerNotice{
+			customer:  invoice.Customer,
+			paymentID: &paymentID,
+			subjectID: invoice.ID,
+			vars: TemplateVars{
+				"invoice_number": number,
+				"amount":         invoice.Balance(),
+				"due_date":       invoice.DueDate,
+				"payment_link":   link,
+			},
+		})
+	}
+	return notices
+}
+
+// dispatch sends the event for each notice over every channel the customer
+// can be reached on
+func (s *notificationDispatchService) dispatch(ctx context.Context, settings *entity.TenantSettings, event string, notices []*customerNotice, result *NotificationRunResult) {
+	for _, notice := range notices {
+		if notice.customer == nil {
+			continue
+		}
+		for name, value := range customerTemplateVars(notice.customer) {
+			notice.vars[name] = value
+		}
+		for _, channel := range s.channels {
+			recipient := channel.Recipient(settings, notice.customer)
+			if recipient == "" {
+				continue
+			}
+			sent, err := s.send(ctx, settings, channel, event, notice, recipient)
+			if err != nil {
+				logger.Error("Notification %s for %s over %s failed: %v", event, notice.subjectID, channel.Name(), err)
+				result.Failed++
+				continue
+			}
+			if sent {
+				result.Sent++
+			}
+		}
+	}
+}
+
+// send claims the notification in the delivery log, renders and sends it and
+// records the outcome. It reports false when the notification was sent before.
+func (s *notificationDispatchService) send(ctx context.Context, settings *entity.TenantSettings, channel CustomerChannel, event string, notice *customerNotice, recipient string) (bool, error) {
+	notification := &entity.CustomerNotification{
+		TenantID:   notice.customer.TenantID,
+		CustomerID: notice.customer.ID,
+		PaymentID:  notice.paymentID,
+		Event:      event,
+		Channel:    channel.Name(),
+		Recipient:  recipient,
+		DedupKey:   fmt.Sprintf("%s:%s:%s", event, notice.subjectID, channel.Name()),
+	}
+	claimed, err := s.notificationRepo.Claim(ctx, notification, notificationMaxAttempts)
+	if err != nil {
+		return false, errors.NewDatabaseError("claim notification", err)
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	msg, sendErr := s.message(ctx, settings, event, channel.Name(), notice.vars)
+	if sendErr == nil {
+		notification.Subject = msg.Subject
+		notification.Body = msg.Text
+		if notification.Body == "" {
+			notification.Body = msg.HTML
+		}
+		sendErr = channel.Send(ctx, settings, notice.customer, recipient, msg)
+	}
+	if sendErr != nil {
+		notification.Status = entity.NotificationDeliveryFailed
+		notification.Error = sendErr.Error()
+	} else {
+		sentAt := time.Now()
+		notification.Status = entity.NotificationDeliverySent
+		notification.SentAt = &sentAt
+	}
+	if err := s.notificationRepo.Finish(ctx, notification); err != nil {
+		logger.Error("Failed to record notification %s: %v", notification.DedupKey, err)
+	}
+	return sendErr == nil, sendErr
+}
+
+// message renders the event's template for the channel. Email gets the
+// subject and HTML body, the other channels the text.
+func (s *notificationDispatchService) message(ctx context.Context, settings *entity.TenantSettings, event, channel string, vars TemplateVars) (*CustomerMessage, error) {
+	rendered, err := s.templates.Render(ctx, settings, event, channel, vars)
+	if err != nil {
+		return nil, err
+	}
+	if channel == entity.NotificationChannelEmail {
+		return &CustomerMessage{Subject: rendered.Subject, HTML: rendered.Body}, nil
+	}
+	return &CustomerMessage{Subject: rendered.Subject, Text: rendered.Body}, nil
+}
+
+func (s *notificationDispatchService) DispatchAllTenants(ctx context.Context, now time.Time) error {
+	tenants, err := s.tenantRepo.FindAll(ctx)
+	if err != nil {
+		return errors.NewDatabaseError("load tenants", err)
+	}
+
+	for _, tenant := range tenants {
+		if !tenant.IsActive {
+			continue
+		}
+		if _, err := s.DispatchTenant(ctx, tenant.ID, now); err != nil {
+			logger.Error("Customer notifications for tenant %s failed: %v", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *notificationDispatchService) ListCustomerNotifications(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerNotification, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	notifications, err := s.notificationRepo.FindByCustomer(ctx, tenantID, customerID, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list customer notifications", err)
+	}
+	return notifications, nil
+}
+
+// StartNotificationJob runs DispatchAllTenants now and then on every interval
+func StartNotificationJob(service NotificationDispatchService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.DispatchAllTenants(context.Background(), time.Now()); err != nil {
+				logger.Error("Customer notification job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Customer notification job started (interval: %s)", interval)
+}
+
+type emailChannel struct {
+	mailer CustomerMailer
+}
+
+// NewEmailChannel returns the email channel for customer notifications
+func NewEmailChannel(mailer CustomerMailer) CustomerChannel {
+	return &emailChannel{mailer: mailer}
+}
+
+func (c *emailChannel) Name() string {
+	return entity.NotificationChannelEmail
+}
+
+func (c *emailChannel) Recipient(settings *entity.TenantSettings, customer *entity.Customer) string {
+	return customer.Email
+}
+
+func (c *emailChannel) Send(ctx context.Context, settings *entity.TenantSettings, customer *entity.Customer, recipient string, msg *CustomerMessage) error {
+	return c.mailer.SendHTML(recipient, msg.Subject, msg.HTML)
+}
@@ -0,0 +1,201 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCustomerNotificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockCustomerNotificationRepository) FindUnpaidInvoices(ctx context.Context, tenantID string, from, to time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, from, to)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockCustomerNotificationRepository) FindPaidInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, since)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockCustomerNotificationRepository) FindIssuedInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, since)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockCustomerNotificationRepository) FindSuspendedInvoices(ctx context.Context, tenantID string, since time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, since)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockCustomerNotificationRepository) FindResolvedTickets(ctx context.Context, tenantID string, since time.Time) ([]*entity.Ticket, error) {
+	args := m.Called(ctx, tenantID, since)
+	return args.Get(0).([]*entity.Ticket), args.Error(1)
+}
+
+func (m *MockCustomerNotificationRepository) Claim(ctx context.Context, notification *entity.CustomerNotification, maxAttempts int) (bool, error) {
+	args := m.Called(ctx, notification, maxAttempts)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCustomerNotificationRepository) Finish(ctx context.Context, notification *entity.CustomerNotification) error {
+	args := m.Called(ctx, notification)
+	return args.Error(0)
+}
+
+func (m *MockCustomerNotificationRepository) FindByCustomer(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerNotification, error) {
+	args := m.Called(ctx, tenantID, customerID, limit)
+	return args.Get(0).([]*entity.CustomerNotification), args.Error(1)
+}
+
+func dedupKey(key string) interface{} {
+	return mock.MatchedBy(func(n *entity.CustomerNotification) bool { return n.DedupKey == key })
+}
+
+func TestNotificationDispatchService_DispatchTenant(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	now := time.Date(2025, 3, 20, 8, 0, 0, 0, time.UTC)
+	settings := &entity.TenantSettings{
+		CompanyName:                 "Net Desa",
+		BillingType:                 entity.BillingTypePostpaid,
+		GracePeriodDays:             7,
+		AutoSuspendEnabled:          true,
+		AutoSuspendDays:             14,
+		SendPaymentReminder:         true,
+		ReminderDaysBefore:          3,
+		SendOverdueNotice:           true,
+		SendPaymentConfirmation:     true,
+		SendSuspensionWarning:       true,
+		WarningDaysBeforeSuspension: 3,
+	}
+
+	number := "INV-2025-03-0001"
+	budi := &entity.Customer{ID: "c1", TenantID: tenantID, Name: "Budi", CustomerCode: "CUST-1", Email: "budi@example.com", Status: entity.CustomerStatusActive}
+	siti := &entity.Customer{ID: "c2", TenantID: tenantID, Name: "Siti", CustomerCode: "CUST-2", Status: entity.CustomerStatusActive}
+	suspended := &entity.Customer{ID: "c3", TenantID: tenantID, Name: "Joko", Email: "joko@example.com", Status: entity.CustomerStatusSuspended}
+	upcoming := &entity.Payment{ID: "p1", TenantID: tenantID, CustomerID: "c1", InvoiceNumber: &number, Amount: 150000, DueDate: billingDate(2025, 3, 22), Customer: budi}
+	late := &entity.Payment{ID: "p2", TenantID: tenantID, CustomerID: "c2", Amount: 150000, DueDate: billingDate(2025, 3, 10), Customer: siti}
+	nearSuspension := &entity.Payment{ID: "p3", TenantID: tenantID, CustomerID: "c1", Amount: 150000, DueDate: billingDate(2025, 3, 7), Customer: budi}
+	alreadySuspended := &entity.Payment{ID: "p4", TenantID: tenantID, CustomerID: "c3", Amount: 150000, DueDate: billingDate(2025, 3, 7), Customer: suspended}
+	paid := &entity.Payment{ID: "p5", TenantID: tenantID, CustomerID: "c1", Amount: 150000, PaidAmount: 150000, Status: entity.PaymentStatusPaid, Customer: budi}
+
+	repo := new(MockCustomerNotificationRepository)
+	settingsRepo := new(MockSettingsRepository)
+	mailer := new(MockCustomerMailer)
+	service := NewNotificationDispatchService(repo, settingsRepo, nil, builtinTemplateService(), "", NewEmailChannel(mailer))
+
+	settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+	repo.On("FindUnpaidInvoices", ctx, tenantID, billingDate(2025, 3, 20), billingDate(2025, 3, 24)).Return([]*entity.Payment{upcoming}, nil)
+	repo.On("FindUnpaidInvoices", ctx, tenantID, billingDate(2025, 2, 18), billingDate(2025, 3, 20)).Return([]*entity.Payment{late}, nil)
+	repo.On("FindUnpaidInvoices", ctx, tenantID, billingDate(2025, 3, 6), billingDate(2025, 3, 9)).Return([]*entity.Payment{nearSuspension, alreadySuspended}, nil)
+	repo.On("FindSuspendedInvoices", ctx, tenantID, now.Add(-48*time.Hour)).Return([]*entity.Payment{}, nil)
+	repo.On("FindPaidInvoices", ctx, tenantID, now.Add(-48*time.Hour)).Return([]*entity.Payment{paid}, nil)
+
+	repo.On("Claim", ctx, dedupKey("payment_reminder:p1:email"), notificationMaxAttempts).Return(true, nil)
+	repo.On("Claim", ctx, dedupKey("suspension_warning:p3:email"), notificationMaxAttempts).Return(true, nil)
+	// Confirmed on an earlier run
+	repo.On("Claim", ctx, dedupKey("payment_confirmation:p5:email"), notificationMaxAttempts).Return(false, nil)
+	repo.On("Finish", ctx, mock.AnythingOfType("*entity.CustomerNotification")).Return(nil)
+
+	mailer.On("SendHTML", "budi@example.com", "Pengingat tagihan INV-2025-03-0001 jatuh tempo 22/03/2025", mock.Anything).Return(nil)
+	mailer.On("SendHTML", "budi@example.com", "Layanan internet Anda akan diisolir pada 22/03/2025", mock.Anything).Return(errors.New("smtp down"))
+
+	result, err := service.DispatchTenant(ctx, tenantID, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Sent)
+	assert.Equal(t, 1, result.Failed)
+	mailer.AssertNumberOfCalls(t, "SendHTML", 2)
+	repo.AssertNumberOfCalls(t, "Claim", 3)
+
+	reminder := repo.Calls[2].Arguments.Get(1).(*entity.CustomerNotification)
+	assert.Equal(t, entity.NotificationDeliverySent, reminder.Status)
+	assert.Equal(t, "c1", reminder.CustomerID)
+	assert.NotNil(t, reminder.SentAt)
+	assert.Contains(t, reminder.Body, "Rp 150.000")
+
+	warning := repo.Calls[6].Arguments.Get(1).(*entity.CustomerNotification)
+	assert.Equal(t, entity.NotificationDeliveryFailed, warning.Status)
+	assert.Equal(t, "smtp down", warning.Error)
+	assert.Nil(t, warning.SentAt)
+}
+
+func TestNotificationDispatchService_DispatchTenant_Disabled(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 20, 8, 0, 0, 0, time.UTC)
+
+	// Prepaid customers get expiry reminders instead of payment reminders
+	settings := &entity.TenantSettings{BillingType: entity.BillingTypePrepaid, SendPaymentReminder: true, ReminderDaysBefore: 3}
+
+	repo := new(MockCustomerNotificationRepository)
+	settingsRepo := new(MockSettingsRepository)
+	service := NewNotificationDispatchService(repo, settingsRepo, nil, builtinTemplateService(), "", NewEmailChannel(new(MockCustomerMailer)))
+	settingsRepo.On("GetTenantSettings", ctx, "tenant-1").Return(settings, nil)
+
+	result, err := service.DispatchTenant(ctx, "tenant-1", now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Sent)
+	repo.AssertNotCalled(t, "FindUnpaidInvoices", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "FindPaidInvoices", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNotificationDispatchService_DispatchTenant_Events(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	now := time.Date(2025, 3, 20, 8, 0, 0, 0, time.UTC)
+	since := now.Add(-48 * time.Hour)
+	settings := &entity.TenantSettings{
+		CompanyName:             "Net Desa",
+		BillingType:             entity.BillingTypePostpaid,
+		MidtransEnabled:         true,
+		AutoSuspendEnabled:      true,
+		SendSuspensionWarning:   true,
+		SendInvoiceNotification: true,
+		SendTicketNotification:  true,
+		NotificationLanguage:    entity.NotificationLanguageEnglish,
+	}
+
+	number := "INV-2025-03-0001"
+	budi := &entity.Customer{ID: "c1", TenantID: tenantID, Name: "Budi", CustomerCode: "CUST-1", Email: "budi@example.com", Status: entity.CustomerStatusSuspended}
+	issued := &entity.Payment{ID: "p1", TenantID: tenantID, CustomerID: "c1", InvoiceNumber: &number, Amount: 150000, DueDate: billingDate(2025, 4, 10), Customer: budi}
+	unpaid := &entity.Payment{ID: "p2", TenantID: tenantID, CustomerID: "c1", Amount: 150000, DueDate: billingDate(2025, 3, 1), Customer: budi}
+	ticket := &entity.Ticket{ID: "t1", TenantID: tenantID, TicketNumber: "TKT-1", Title: "No signal", Customer: budi}
+
+	repo := new(MockCustomerNotificationRepository)
+	settingsRepo := new(MockSettingsRepository)
+	mailer := new(MockCustomerMailer)
+	service := NewNotificationDispatchService(repo, settingsRepo, nil, builtinTemplateService(), "https://pay.example.com/", NewEmailChannel(mailer))
+
+	settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+	repo.On("FindIssuedInvoices", ctx, tenantID, since).Return([]*entity.Payment{issued}, nil)
+	repo.On("FindSuspendedInvoices", ctx, tenantID, since).Return([]*entity.Payment{unpaid}, nil)
+	repo.On("FindResolvedTickets", ctx, tenantID, since).Return([]*entity.Ticket{ticket}, nil)
+	repo.On("Claim", ctx, mock.AnythingOfType("*entity.CustomerNotification"), notificationMaxAttempts).Return(true, nil)
+	repo.On("Finish", ctx, mock.AnythingOfType("*entity.CustomerNotification")).Return(nil)
+	mailer.On("SendHTML", "budi@example.com", mock.Anything, mock.Anything).Return(nil)
+
+	result, err := service.DispatchTenant(ctx, tenantID, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Sent)
+	repo.AssertCalled(t, "Claim", ctx, dedupKey("invoice_created:p1:email"), notificationMaxAttempts)
+	repo.AssertCalled(t, "Claim", ctx, dedupKey("suspended:p2:email"), notificationMaxAttempts)
+	repo.AssertCalled(t, "Claim", ctx, dedupKey("ticket_resolved:t1:email"), notificationMaxAttempts)
+
+	mailer.AssertCalled(t, "SendHTML", "budi@example.com", "Invoice INV-2025-03-0001 for IDR 150,000", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "is due on 10 Apr 2025") && strings.Contains(body, "Pay online: https://pay.example.com/p1")
+	}))
+	mailer.AssertCalled(t, "SendHTML", "budi@example.com", "Your internet service has been suspended", mock.Anything)
+	mailer.AssertCalled(t, "SendHTML", "budi@example.com", "Ticket TKT-1 resolved", mock.Anything)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -293,33 +294,6 @@ func (s *notificationService) SubscribeNotifications(ctx context.Context, channe
 }
 
 // Helper function to create notification for specific events
-func CreatePaymentNotification(tenantID, userID, orderID, status string, amount float64) *entity.Notification {
-	title := "Pembayaran Berhasil"
-	message := fmt.Sprintf("Pembayaran sebesar Rp %.0f telah berhasil diproses.", amount)
-	notifType := entity.NotificationTypeSuccess
-
-	if status == "pending" {
-		title = "Menunggu Pembayaran"
-		message = fmt.Sprintf("Silakan selesaikan pembayaran sebesar Rp %.0f.", amount)
-		notifType = entity.NotificationTypePayment
-	} else if status == "failed" || status == "expired" {
-		title = "Pembayaran Gagal"
-		message = "Pembayaran Anda gagal atau kadaluarsa. Silakan coba lagi."
-		notifType = entity.NotificationTypeError
-	}
-
-	data, _ := json.Marshal(map[string]interface{}{"order_id": orderID, "amount": amount, "status": status})
-
-	return &entity.Notification{
-		TenantID: tenantID,
-		UserID:   &userID,
-		Type:     notifType,
-		Title:    title,
-		Message:  message,
-		Data:     string(data),
-	}
-}
-
 func CreateTicketNotification(tenantID string, userID *string, ticketID, subject, status string) *entity.Notification {
 	title := "Tiket Support Diperbarui"
 	message := fmt.Sprintf("Tiket '%s' telah diperbarui.", subject)
@@ -344,28 +318,45 @@ func CreateTicketNotification(tenantID string, userID *string, ticketID, subject
 	}
 }
 
-func CreateSubscriptionNotification(tenantID string, planName, status string, daysLeft int) *entity.Notification {
-	title := "Langganan Aktif"
-	message := fmt.Sprintf("Langganan %s Anda telah aktif.", planName)
-	notifType := entity.NotificationTypeSuccess
+// subscriptionNotifications maps a subscription status to the platform event
+// and notification type its tenant is told with
+var subscriptionNotifications = map[string]struct{ event, notifType string }{
+	entity.SubscriptionStatusActive:    {entity.NotificationEventSubscriptionActive, entity.NotificationTypeSuccess},
+	"renewal":                          {entity.NotificationEventSubscriptionRenewal, entity.NotificationTypePayment},
+	"expiring":                         {entity.NotificationEventSubscriptionExpiring, entity.NotificationTypeWarning},
+	entity.SubscriptionStatusExpired:   {entity.NotificationEventSubscriptionExpired, entity.NotificationTypeError},
+	entity.SubscriptionStatusPastDue:   {entity.NotificationEventSubscriptionPastDue, entity.NotificationTypeWarning},
+	entity.SubscriptionStatusSuspended: {entity.NotificationEventSubscriptionSuspended, entity.NotificationTypeError},
+	"trial_expiring":                   {entity.NotificationEventTrialExpiring, entity.NotificationTypeWarning},
+	"trial_converted":                  {entity.NotificationEventTrialConverted, entity.NotificationTypePayment},
+	entity.SubscriptionStatusReadOnly:  {entity.NotificationEventTrialReadOnly, entity.NotificationTypeError},
+}
 
-	if status == "expiring" {
-		title = "Langganan Akan Berakhir"
-		message = fmt.Sprintf("Langganan %s Anda akan berakhir dalam %d hari.", planName, daysLeft)
-		notifType = entity.NotificationTypeWarning
-	} else if status == "expired" {
-		title = "Langganan Berakhir"
-		message = fmt.Sprintf("Langganan %s Anda telah berakhir. Silakan perpanjang.", planName)
-		notifType = entity.NotificationTypeError
+// NewSubscriptionNotification renders the notification of a subscription
+// status for every user of the tenant
+func NewSubscriptionNotification(ctx context.Context, templates NotificationTemplateService, tenantID, planName, status string, daysLeft int) (*entity.Notification, error) {
+	kind, ok := subscriptionNotifications[status]
+	if !ok {
+		kind = subscriptionNotifications[entity.SubscriptionStatusActive]
 	}
+	vars := TemplateVars{"plan_name": planName, "days_left": strconv.Itoa(daysLeft)}
+	data := map[string]interface{}{"plan_name": planName, "status": status, "days_left": daysLeft}
+	return newPlatformNotification(ctx, templates, tenantID, kind.event, kind.notifType, vars, data)
+}
 
-	data, _ := json.Marshal(map[string]interface{}{"plan_name": planName, "status": status, "days_left": daysLeft})
-
+// newPlatformNotification renders the in-app text of a platform event as a
+// notification for every user of the tenant
+func newPlatformNotification(ctx context.Context, templates NotificationTemplateService, tenantID, event, notifType string, vars TemplateVars, data map[string]interface{}) (*entity.Notification, error) {
+	msg, err := templates.RenderPlatform(ctx, tenantID, event, entity.NotificationChannelInApp, vars)
+	if err != nil {
+		return nil, err
+	}
+	encoded, _ := json.Marshal(data)
 	return &entity.Notification{
 		TenantID: tenantID,
 		Type:     notifType,
-		Title:    title,
-		Message:  message,
-		Data:     string(data),
-	}
+		Title:    msg.Subject,
+		Message:  msg.Body,
+		Data:     string(encoded),
+	}, nil
 }
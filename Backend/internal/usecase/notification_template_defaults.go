@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"strings"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+// builtinTemplate is the built-in text of an event in one language. Email
+// bodies put each paragraph in a <p>, with line breaks as <br>; the other
+// channels send the paragraphs as plain text.
+type builtinTemplate struct {
+	subject    string
+	paragraphs []string
+}
+
+// customerLetter wraps the paragraphs of a customer message in a greeting
+// and the company's signature
+func customerLetter(language string, paragraphs ...string) []string {
+	if language == entity.NotificationLanguageEnglish {
+		return append(append([]string{"Dear {{.customer_name}} ({{.customer_code}}),"}, paragraphs...), "Thank you,\n{{.company_name}}")
+	}
+	return append(append([]string{"Yth. {{.customer_name}} ({{.customer_code}}),"}, paragraphs...), "Terima kasih,\n{{.company_name}}")
+}
+
+const (
+	paymentLinkID = "{{if .payment_link}}Bayar online: {{.payment_link}}{{end}}"
+	paymentLinkEN = "{{if .payment_link}}Pay online: {{.payment_link}}{{end}}"
+)
+
+// platformMessage is the built-in text of a one-line platform notification
+func platformMessage(subjectID, messageID, subjectEN, messageEN string) map[string]builtinTemplate {
+	return map[string]builtinTemplate{
+		entity.NotificationLanguageIndonesian: {subject: subjectID, paragraphs: []string{messageID}},
+		entity.NotificationLanguageEnglish:    {subject: subjectEN, paragraphs: []string{messageEN}},
+	}
+}
+
+// builtinTemplates holds the built-in texts by event and language
+var builtinTemplates = map[string]map[string]builtinTemplate{
+	entity.NotificationEventInvoiceCreated: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Tagihan {{.invoice_number}} sebesar {{.amount}}",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Tagihan {{.invoice_number}} sebesar {{.amount}} telah terbit dan jatuh tempo pada {{.due_date}}.",
+				paymentLinkID),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Invoice {{.invoice_number}} for {{.amount}}",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"Invoice {{.invoice_number}} for {{.amount}} has been issued and is due on {{.due_date}}.",
+				paymentLinkEN),
+		},
+	},
+	entity.NotificationEventPaymentReminder: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Pengingat tagihan {{.invoice_number}} jatuh tempo {{.due_date}}",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Tagihan {{.invoice_number}} sebesar {{.amount}} jatuh tempo pada {{.due_date}}. Mohon lakukan pembayaran sebelum tanggal tersebut.",
+				paymentLinkID),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Reminder: invoice {{.invoice_number}} is due on {{.due_date}}",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"Invoice {{.invoice_number}} for {{.amount}} is due on {{.due_date}}. Please pay before that date.",
+				paymentLinkEN),
+		},
+	},
+	entity.NotificationEventOverdueNotice: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Tagihan {{.invoice_number}} telah jatuh tempo",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Tagihan {{.invoice_number}} sebesar {{.amount}} telah jatuh tempo pada {{.due_date}} dan belum kami terima pembayarannya. Mohon segera lakukan pembayaran.",
+				paymentLinkID),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Invoice {{.invoice_number}} is overdue",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"Invoice {{.invoice_number}} for {{.amount}} was due on {{.due_date}} and we have not received your payment yet. Please pay as soon as possible.",
+				paymentLinkEN),
+		},
+	},
+	entity.NotificationEventSuspensionWarning: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Layanan internet Anda akan diisolir pada {{.suspend_date}}",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Tagihan {{.invoice_number}} sebesar {{.amount}} belum dibayar sejak {{.due_date}}. Layanan internet Anda akan diisolir pada {{.suspend_date}} jika tagihan belum dilunasi.",
+				paymentLinkID),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Your internet service will be suspended on {{.suspend_date}}",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"Invoice {{.invoice_number}} for {{.amount}} has been unpaid since {{.due_date}}. Your internet service will be suspended on {{.suspend_date}} unless the invoice is paid.",
+				paymentLinkEN),
+		},
+	},
+	entity.NotificationEventSuspended: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Layanan internet Anda diisolir",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Layanan internet Anda diisolir karena tagihan {{.invoice_number}} sebesar {{.amount}} yang jatuh tempo pada {{.due_date}} belum dibayar. Layanan akan aktif kembali setelah tagihan dilunasi.",
+				paymentLinkID),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Your internet service has been suspended",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"Your internet service has been suspended because invoice {{.invoice_number}} for {{.amount}}, due on {{.due_date}}, is unpaid. It will be restored once the invoice is paid.",
+				paymentLinkEN),
+		},
+	},
+	entity.NotificationEventPaymentConfirmation: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Pembayaran tagihan {{.invoice_number}} telah diterima",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Pembayaran tagihan {{.invoice_number}} sebesar {{.amount}} telah kami terima."),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Payment for invoice {{.invoice_number}} received",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"We have received your payment of {{.amount}} for invoice {{.invoice_number}}."),
+		},
+	},
+	entity.NotificationEventServiceExpiring: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Layanan internet Anda berakhir pada {{.expiry_date}}",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Masa aktif layanan internet Anda{{if .plan_name}} ({{.plan_name}}){{end}} berakhir pada {{.expiry_date}}.",
+				"Silakan lakukan pembayaran sebelum tanggal tersebut agar layanan tetap aktif."),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Your internet service ends on {{.expiry_date}}",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"Your internet service{{if .plan_name}} ({{.plan_name}}){{end}} ends on {{.expiry_date}}.",
+				"Please pay before then to keep it active."),
+		},
+	},
+	entity.NotificationEventTicketResolved: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Tiket {{.ticket_number}} telah diselesaikan",
+			paragraphs: customerLetter(entity.NotificationLanguageIndonesian,
+				"Laporan Anda \"{{.ticket_title}}\" ({{.ticket_number}}) telah kami selesaikan. Jika masih ada kendala, silakan hubungi kami{{if .company_phone}} di {{.company_phone}}{{end}}."),
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Ticket {{.ticket_number}} resolved",
+			paragraphs: customerLetter(entity.NotificationLanguageEnglish,
+				"Your report \"{{.ticket_title}}\" ({{.ticket_number}}) has been resolved. If the problem persists, please contact us{{if .company_phone}} at {{.company_phone}}{{end}}."),
+		},
+	},
+	entity.NotificationEventTicketCreated: {
+		entity.NotificationLanguageIndonesian: {
+			subject:    "Tiket baru {{.ticket_number}}",
+			paragraphs: []string{"Tiket baru {{.ticket_number}} ({{.priority}})\n{{.ticket_title}}{{if .customer_code}}\nPelanggan: {{.customer_code}} - {{.customer_name}}{{end}}"},
+		},
+		entity.NotificationLanguageEnglish: {
+			subject:    "New ticket {{.ticket_number}}",
+			paragraphs: []string{"New ticket {{.ticket_number}} ({{.priority}})\n{{.ticket_title}}{{if .customer_code}}\nCustomer: {{.customer_code}} - {{.customer_name}}{{end}}"},
+		},
+	},
+	entity.NotificationEventVoucherSold: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "Voucher internet {{.package_name}}",
+			paragraphs: []string{
+				"Voucher internet {{.package_name}} berlaku {{.duration}} {{if eq .duration_type \"days\"}}hari{{else}}jam{{end}} sejak pertama digunakan.",
+				"Kode: {{.voucher_code}}\nPassword: {{.voucher_password}}{{if .price}}\nHarga: {{.price}}{{end}}",
+				"Terima kasih,\n{{.company_name}}",
+			},
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "Internet voucher {{.package_name}}",
+			paragraphs: []string{
+				"Internet voucher {{.package_name}}, valid for {{.duration}} {{if eq .duration_type \"days\"}}days{{else}}hours{{end}} from first use.",
+				"Code: {{.voucher_code}}\nPassword: {{.voucher_password}}{{if .price}}\nPrice: {{.price}}{{end}}",
+				"Thank you,\n{{.company_name}}",
+			},
+		},
+	},
+
+	// Platform events
+	entity.NotificationEventEmailOTP: {
+		entity.NotificationLanguageIndonesian: {
+			subject: "{{if eq .purpose \"reset_password\"}}Kode Reset Password{{else if eq .purpose \"email_change\"}}Kode Verifikasi Perubahan Email{{else}}Kode Verifikasi Email{{end}}",
+			paragraphs: []string{
+				"Halo,",
+				"Anda menerima email ini karena ada permintaan {{if eq .purpose \"reset_password\"}}reset password{{else if eq .purpose \"email_change\"}}perubahan email{{else}}registrasi akun{{end}} pada platform RT/RW Net SaaS. Gunakan kode berikut untuk melanjutkan:",
+				"<strong>{{.otp_code}}</strong>",
+				"Kode ini akan kedaluwarsa dalam {{.expiry_minutes}} menit. Jika Anda tidak melakukan permintaan ini, abaikan email ini.",
+			},
+		},
+		entity.NotificationLanguageEnglish: {
+			subject: "{{if eq .purpose \"reset_password\"}}Password Reset Code{{else if eq .purpose \"email_change\"}}Email Change Verification Code{{else}}Email Verification Code{{end}}",
+			paragraphs: []string{
+				"Hello,",
+				"You are receiving this email because of a {{if eq .purpose \"reset_password\"}}password reset{{else if eq .purpose \"email_change\"}}email change{{else}}account registration{{end}} request on the RT/RW Net SaaS platform. Use the following code to continue:",
+				"<strong>{{.otp_code}}</strong>",
+				"The code expires in {{.expiry_minutes}} minutes. If you did not make this request, ignore this email.",
+			},
+		},
+	},
+	entity.NotificationEventSubscriptionActive: platformMessage(
+		"Langganan Aktif", "Langganan {{.plan_name}} Anda telah aktif.",
+		"Subscription Active", "Your {{.plan_name}} subscription is active."),
+	entity.NotificationEventSubscriptionRenewal: platformMessage(
+		"Tagihan Perpanjangan Langganan", "Tagihan perpanjangan langganan {{.plan_name}} telah dibuat dan jatuh tempo dalam {{.days_left}} hari.",
+		"Subscription Renewal Invoice", "The renewal invoice of your {{.plan_name}} subscription has been created and is due in {{.days_left}} days."),
+	entity.NotificationEventSubscriptionExpiring: platformMessage(
+		"Langganan Akan Berakhir", "Langganan {{.plan_name}} Anda akan berakhir dalam {{.days_left}} hari.",
+		"Subscription Ending Soon", "Your {{.plan_name}} subscription ends in {{.days_left}} days."),
+	entity.NotificationEventSubscriptionExpired: platformMessage(
+		"Langganan Berakhir", "Langganan {{.plan_name}} Anda telah berakhir. Silakan perpanjang.",
+		"Subscription Ended", "Your {{.plan_name}} subscription has ended. Please renew it."),
+	entity.NotificationEventSubscriptionPastDue: platformMessage(
+		"Pembayaran Langganan Terlambat", "Langganan {{.plan_name}} Anda belum dibayar. Layanan akan ditangguhkan dalam {{.days_left}} hari.",
+		"Subscription Payment Overdue", "Your {{.plan_name}} subscription is unpaid. The service will be suspended in {{.days_left}} days."),
+	entity.NotificationEventSubscriptionSuspended: platformMessage(
+		"Langganan Ditangguhkan", "Langganan {{.plan_name}} Anda ditangguhkan karena belum dibayar. Lakukan pembayaran untuk mengaktifkan kembali.",
+		"Subscription Suspended", "Your {{.plan_name}} subscription has been suspended because it is unpaid. Pay to reactivate it."),
+	entity.NotificationEventTrialExpiring: platformMessage(
+		"Masa Trial Akan Berakhir", "Masa trial {{.plan_name}} Anda akan berakhir dalam {{.days_left}} hari. Pilih paket berlangganan agar layanan tetap berjalan.",
+		"Trial Ending Soon", "Your {{.plan_name}} trial ends in {{.days_left}} days. Choose a subscription plan to keep the service running."),
+	entity.NotificationEventTrialConverted: platformMessage(
+		"Masa Trial Berakhir", "Masa trial Anda telah berakhir dan langganan dialihkan ke paket {{.plan_name}}. Bayar tagihan dalam {{.days_left}} hari agar layanan tidak ditangguhkan.",
+		"Trial Ended", "Your trial has ended and the subscription moved to the {{.plan_name}} plan. Pay the invoice within {{.days_left}} days to avoid suspension."),
+	entity.NotificationEventTrialReadOnly: platformMessage(
+		"Masa Trial Berakhir", "Masa trial {{.plan_name}} Anda telah berakhir. Akun Anda kini hanya dapat dilihat; pilih paket berlangganan untuk melanjutkan.",
+		"Trial Ended", "Your {{.plan_name}} trial has ended. Your account is now read-only; choose a subscription plan to continue."),
+	entity.NotificationEventPaymentRefunded: platformMessage(
+		"Pembayaran Dikembalikan", "Pembayaran {{.order_id}} sebesar {{.amount}} telah dikembalikan.{{if .reason}} Alasan: {{.reason}}{{end}}",
+		"Payment Refunded", "Payment {{.order_id}} of {{.amount}} has been refunded.{{if .reason}} Reason: {{.reason}}{{end}}"),
+	entity.NotificationEventPaymentCancelled: platformMessage(
+		"Pembayaran Dibatalkan", "Pembayaran {{.order_id}} telah dibatalkan.{{if .reason}} Alasan: {{.reason}}{{end}}",
+		"Payment Cancelled", "Payment {{.order_id}} has been cancelled.{{if .reason}} Reason: {{.reason}}{{end}}"),
+}
+
+// builtinNotificationTemplate returns the built-in template of an event for a
+// channel in a language
+func builtinNotificationTemplate(tenantID, event, channel, language string) *entity.NotificationTemplate {
+	builtin := builtinTemplates[event][language]
+	template := &entity.NotificationTemplate{
+		TenantID: tenantID,
+		Event:    event,
+		Channel:  channel,
+		Language: language,
+	}
+	if templateHasSubject(channel) {
+		template.Subject = builtin.subject
+	}
+
+	if channel != entity.NotificationChannelEmail {
+		template.Body = strings.Join(builtin.paragraphs, "\n\n")
+		return template
+	}
+	var b strings.Builder
+	for i, p := range builtin.paragraphs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("<p>" + strings.ReplaceAll(p, "\n", "<br>") + "</p>")
+	}
+	template.Body = b.String()
+	return template
+}
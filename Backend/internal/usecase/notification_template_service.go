@@ -0,0 +1,801 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/pdf"
+)
+
+// NotificationTemplateService renders the messages sent to customers and
+// staff from per-tenant templates. Every event has built-in Indonesian and
+// English texts that are sent until the tenant saves their own.
+//
+// Templates use Go template syntax, e.g. "Halo {{.customer_name}}" or
+// "{{if .payment_link}}Bayar: {{.payment_link}}{{end}}". Email bodies are
+// HTML with the variables escaped; the other channels are plain text.
+type NotificationTemplateService interface {
+	// ListEvents returns the events with their channels and variables
+	ListEvents() []*TemplateEvent
+	// ListTemplates returns the tenant's template of every event and channel
+	// in a language, or in both languages when language is empty
+	ListTemplates(ctx context.Context, tenantID, language string) ([]*entity.NotificationTemplate, error)
+	// GetTemplate returns the template the tenant sends for an event on a
+	// channel in a language
+	GetTemplate(ctx context.Context, tenantID, event, channel, language string) (*entity.NotificationTemplate, error)
+	// SaveTemplate validates and stores the tenant's own template
+	SaveTemplate(ctx context.Context, tenantID, userID string, req *NotificationTemplateRequest) (*entity.NotificationTemplate, error)
+	// ResetTemplate deletes the tenant's template so the built-in one is sent again
+	ResetTemplate(ctx context.Context, tenantID, event, channel, language string) error
+	// Preview renders a template with sample data. Without a body the
+	// tenant's current template is rendered.
+	Preview(ctx context.Context, tenantID string, req *NotificationTemplateRequest) (*RenderedTemplate, error)
+	// Render renders the tenant's template of an event for a channel in the
+	// language of the settings. The company variables come from the settings.
+	Render(ctx context.Context, settings *entity.TenantSettings, event, channel string, vars TemplateVars) (*RenderedTemplate, error)
+	// RenderTenant is Render for callers without the tenant's settings at hand
+	RenderTenant(ctx context.Context, tenantID, event, channel string, vars TemplateVars) (*RenderedTemplate, error)
+	// RenderPlatform renders the built-in text of a platform event in the
+	// tenant's language, or in Indonesian without a tenant
+	RenderPlatform(ctx context.Context, tenantID, event, channel string, vars TemplateVars) (*RenderedTemplate, error)
+}
+
+// Template variable types. Values are passed as a string for text and url, a
+// float64 for money and a time.Time for date and datetime, and are formatted
+// for the language of the template.
+const (
+	TemplateVarText     = "text"
+	TemplateVarMoney    = "money"
+	TemplateVarDate     = "date"
+	TemplateVarDateTime = "datetime"
+	TemplateVarURL      = "url"
+)
+
+// Template event audiences
+const (
+	TemplateAudienceCustomer = "customer"
+	TemplateAudienceStaff    = "staff"
+	TemplateAudiencePlatform = "platform" // sent by the platform, not editable by tenants
+)
+
+// TemplateVariable is a variable the templates of an event can use
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// TemplateEvent describes an event messages are sent for
+type TemplateEvent struct {
+	Event       string             `json:"event"`
+	Description string             `json:"description"`
+	Audience    string             `json:"audience"`
+	Channels    []string           `json:"channels"`
+	Variables   []TemplateVariable `json:"variables"`
+}
+
+// TemplateVars are the values of an event's variables by name. Variables
+// left out render empty.
+type TemplateVars map[string]interface{}
+
+// RenderedTemplate is a rendered message. Subject is only set for email and
+// in-app messages.
+type RenderedTemplate struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+// NotificationTemplateRequest is a template to save or preview
+type NotificationTemplateRequest struct {
+	Event    string
+	Channel  string
+	Language string
+	Subject  string
+	Body     string
+}
+
+const (
+	notificationTemplateMaxSubject = 255
+	notificationTemplateMaxBody    = 10000
+	// Rendering stops with an error past this size, e.g. for a runaway range
+	notificationTemplateMaxOutput = 64 << 10
+)
+
+var (
+	companyVars = []TemplateVariable{
+		{Name: "company_name", Type: TemplateVarText, Description: "Company name from the tenant settings"},
+		{Name: "company_phone", Type: TemplateVarText, Description: "Company phone from the tenant settings"},
+	}
+	customerVars = []TemplateVariable{
+		{Name: "customer_name", Type: TemplateVarText, Description: "Customer name"},
+		{Name: "customer_code", Type: TemplateVarText, Description: "Customer code"},
+	}
+	invoiceVars = []TemplateVariable{
+		{Name: "invoice_number", Type: TemplateVarText, Description: "Invoice number"},
+		{Name: "amount", Type: TemplateVarMoney, Description: "Amount still to pay"},
+		{Name: "due_date", Type: TemplateVarDate, Description: "Due date of the invoice"},
+		{Name: "payment_link", Type: TemplateVarURL, Description: "Online payment page of the invoice, empty when the tenant has no online payment"},
+	}
+	ticketVars = []TemplateVariable{
+		{Name: "ticket_number", Type: TemplateVarText, Description: "Ticket number"},
+		{Name: "ticket_title", Type: TemplateVarText, Description: "Ticket title"},
+	}
+	subscriptionVars = []TemplateVariable{
+		{Name: "plan_name", Type: TemplateVarText, Description: "Subscription plan of the tenant"},
+		{Name: "days_left", Type: TemplateVarText, Description: "Days until the next step of the subscription"},
+	}
+	subscriptionPaymentVars = []TemplateVariable{
+		{Name: "order_id", Type: TemplateVarText, Description: "Order ID of the subscription payment"},
+		{Name: "amount", Type: TemplateVarMoney, Description: "Amount of the payment"},
+		{Name: "reason", Type: TemplateVarText, Description: "Reason given by the platform admin"},
+	}
+)
+
+func templateVars(groups ...[]TemplateVariable) []TemplateVariable {
+	var vars []TemplateVariable
+	for _, group := range groups {
+		vars = append(vars, group...)
+	}
+	return vars
+}
+
+var (
+	customerTemplateChannels = []string{entity.NotificationChannelEmail, entity.NotificationChannelWhatsApp}
+
+	// templateEvents is the catalog of events with templates
+	templateEvents = []*TemplateEvent{
+		{
+			Event:       entity.NotificationEventInvoiceCreated,
+			Description: "A new invoice was issued to the customer",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    customerTemplateChannels,
+			Variables:   templateVars(customerVars, invoiceVars, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventPaymentReminder,
+			Description: "An invoice falls due in the next days",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    customerTemplateChannels,
+			Variables:   templateVars(customerVars, invoiceVars, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventOverdueNotice,
+			Description: "An invoice is past its due date",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    customerTemplateChannels,
+			Variables:   templateVars(customerVars, invoiceVars, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventSuspensionWarning,
+			Description: "The customer will be suspended for an unpaid invoice",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    customerTemplateChannels,
+			Variables: templateVars(customerVars, invoiceVars, []TemplateVariable{
+				{Name: "suspend_date", Type: TemplateVarDate, Description: "Day the service is suspended"},
+			}, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventSuspended,
+			Description: "The customer was suspended for an unpaid invoice",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    customerTemplateChannels,
+			Variables:   templateVars(customerVars, invoiceVars, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventPaymentConfirmation,
+			Description: "An invoice was paid",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    customerTemplateChannels,
+			Variables: templateVars(customerVars, []TemplateVariable{
+				{Name: "invoice_number", Type: TemplateVarText, Description: "Invoice number"},
+				{Name: "amount", Type: TemplateVarMoney, Description: "Amount paid"},
+				{Name: "paid_date", Type: TemplateVarDate, Description: "Day the invoice was paid"},
+			}, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventServiceExpiring,
+			Description: "The service of a prepaid customer ends in the next days",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    []string{entity.NotificationChannelEmail},
+			Variables: templateVars(customerVars, []TemplateVariable{
+				{Name: "plan_name", Type: TemplateVarText, Description: "Service plan of the customer"},
+				{Name: "expiry_date", Type: TemplateVarDateTime, Description: "End of the service"},
+			}, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventTicketResolved,
+			Description: "A ticket of the customer was resolved",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    customerTemplateChannels,
+			Variables:   templateVars(customerVars, ticketVars, companyVars),
+		},
+		{
+			Event:       entity.NotificationEventTicketCreated,
+			Description: "A new ticket was opened",
+			Audience:    TemplateAudienceStaff,
+			Channels:    []string{entity.NotificationChannelTelegram, entity.NotificationChannelInApp},
+			Variables: templateVars(ticketVars, []TemplateVariable{
+				{Name: "priority", Type: TemplateVarText, Description: "Ticket priority: low, medium, high or urgent"},
+			}, customerVars),
+		},
+		{
+			Event:       entity.NotificationEventVoucherSold,
+			Description: "A hotspot voucher was generated for a buyer",
+			Audience:    TemplateAudienceCustomer,
+			Channels:    []string{entity.NotificationChannelWhatsApp},
+			Variables: templateVars([]TemplateVariable{
+				{Name: "voucher_code", Type: TemplateVarText, Description: "Voucher code the buyer logs in with"},
+				{Name: "voucher_password", Type: TemplateVarText, Description: "Voucher password"},
+				{Name: "package_name", Type: TemplateVarText, Description: "Hotspot package of the voucher"},
+				{Name: "price", Type: TemplateVarMoney, Description: "Price of the package"},
+				{Name: "duration", Type: TemplateVarText, Description: "Validity of the voucher in duration_type units"},
+				{Name: "duration_type", Type: TemplateVarText, Description: "Unit of the duration: hours or days"},
+			}, companyVars),
+		},
+	}
+
+	// platformTemplateEvents is the catalog of platform events. They only
+	// have built-in texts.
+	platformTemplateEvents = []*TemplateEvent{
+		{
+			Event:       entity.NotificationEventEmailOTP,
+			Description: "A verification code was requested",
+			Audience:    TemplateAudiencePlatform,
+			Channels:    []string{entity.NotificationChannelEmail},
+			Variables: []TemplateVariable{
+				{Name: "otp_code", Type: TemplateVarText, Description: "Verification code"},
+				{Name: "purpose", Type: TemplateVarText, Description: "What the code is for: registration, reset_password or email_change"},
+				{Name: "expiry_minutes", Type: TemplateVarText, Description: "Minutes the code is valid"},
+			},
+		},
+		platformSubscriptionEvent(entity.NotificationEventSubscriptionActive, "The subscription is active again"),
+		platformSubscriptionEvent(entity.NotificationEventSubscriptionRenewal, "A renewal order was created"),
+		platformSubscriptionEvent(entity.NotificationEventSubscriptionExpiring, "The subscription ends in the next days"),
+		platformSubscriptionEvent(entity.NotificationEventSubscriptionExpired, "The subscription ended"),
+		platformSubscriptionEvent(entity.NotificationEventSubscriptionPastDue, "The renewal is unpaid after the billing date"),
+		platformSubscriptionEvent(entity.NotificationEventSubscriptionSuspended, "The subscription was suspended for an unpaid renewal"),
+		platformSubscriptionEvent(entity.NotificationEventTrialExpiring, "The trial ends in the next days"),
+		platformSubscriptionEvent(entity.NotificationEventTrialConverted, "The trial ended and moved to a paid plan"),
+		platformSubscriptionEvent(entity.NotificationEventTrialReadOnly, "The trial ended and the account became read-only"),
+		{
+			Event:       entity.NotificationEventPaymentRefunded,
+			Description: "A subscription payment was refunded",
+			Audience:    TemplateAudiencePlatform,
+			Channels:    []string{entity.NotificationChannelInApp},
+			Variables:   subscriptionPaymentVars,
+		},
+		{
+			Event:       entity.NotificationEventPaymentCancelled,
+			Description: "A pending subscription payment was cancelled",
+			Audience:    TemplateAudiencePlatform,
+			Channels:    []string{entity.NotificationChannelInApp},
+			Variables:   subscriptionPaymentVars,
+		},
+	}
+
+	notificationLanguages = []string{entity.NotificationLanguageIndonesian, entity.NotificationLanguageEnglish}
+
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// platformSubscriptionEvent is an in-app platform event about the tenant's
+// subscription
+func platformSubscriptionEvent(event, description string) *TemplateEvent {
+	return &TemplateEvent{
+		Event:       event,
+		Description: description,
+		Audience:    TemplateAudiencePlatform,
+		Channels:    []string{entity.NotificationChannelInApp},
+		Variables:   subscriptionVars,
+	}
+}
+
+type notificationTemplateService struct {
+	templateRepo repository.NotificationTemplateRepository
+	settingsRepo repository.SettingsRepository
+}
+
+// NewNotificationTemplateService creates the notification template service
+func NewNotificationTemplateService(
+	templateRepo repository.NotificationTemplateRepository,
+	settingsRepo repository.SettingsRepository,
+) NotificationTemplateService {
+	return &notificationTemplateService{
+		templateRepo: templateRepo,
+		settingsRepo: settingsRepo,
+	}
+}
+
+func (s *notificationTemplateService) loadSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		return defaultTenantSettings(tenantID), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	return settings, nil
+}
+
+// notificationLanguage returns the language the tenant's notifications are sent in
+func notificationLanguage(settings *entity.TenantSettings) string {
+	if settings.NotificationLanguage == entity.NotificationLanguageEnglish {
+		return entity.NotificationLanguageEnglish
+	}
+	return entity.NotificationLanguageIndonesian
+}
+
+// templateHasSubject reports whether messages on the channel have a subject
+func templateHasSubject(channel string) bool {
+	return channel == entity.NotificationChannelEmail || channel == entity.NotificationChannelInApp
+}
+
+// templateEvent returns the event after checking that it has templates for
+// the channel and language
+func templateEvent(event, channel, language string) (*TemplateEvent, error) {
+	var found *TemplateEvent
+	for _, e := range templateEvents {
+		if e.Event == event {
+			found = e
+			break
+		}
+	}
+	if found == nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("unknown notification event %q", event))
+	}
+	if !containsString(found.Channels, channel) {
+		return nil, errors.NewValidationError(fmt.Sprintf("event %s is not sent over channel %q", event, channel))
+	}
+	if !containsString(notificationLanguages, language) {
+		return nil, errors.NewValidationError(fmt.Sprintf("unsupported language %q, use id or en", language))
+	}
+	return found, nil
+}
+
+// platformTemplateEvent returns the platform event after checking that it is
+// sent over the channel
+func platformTemplateEvent(event, channel string) (*TemplateEvent, error) {
+	for _, e := range platformTemplateEvents {
+		if e.Event != event {
+			continue
+		}
+		if !containsString(e.Channels, channel) {
+			return nil, errors.NewValidationError(fmt.Sprintf("event %s is not sent over channel %q", event, channel))
+		}
+		return e, nil
+	}
+	return nil, errors.NewValidationError(fmt.Sprintf("unknown platform notification event %q", event))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *notificationTemplateService) ListEvents() []*TemplateEvent {
+	return templateEvents
+}
+
+func (s *notificationTemplateService) ListTemplates(ctx context.Context, tenantID, language string) ([]*entity.NotificationTemplate, error) {
+	languages := notificationLanguages
+	if language != "" {
+		if !containsString(notificationLanguages, language) {
+			return nil, errors.NewValidationError(fmt.Sprintf("unsupported language %q, use id or en", language))
+		}
+		languages = []string{language}
+	}
+
+	saved, err := s.templateRepo.FindByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list notification templates", err)
+	}
+	byKey := make(map[string]*entity.NotificationTemplate, len(saved))
+	for _, t := range saved {
+		byKey[t.Event+":"+t.Channel+":"+t.Language] = t
+	}
+
+	var templates []*entity.NotificationTemplate
+	for _, event := range templateEvents {
+		for _, channel := range event.Channels {
+			for _, lang := range languages {
+				if t, ok := byKey[event.Event+":"+channel+":"+lang]; ok {
+					templates = append(templates, t)
+					continue
+				}
+				templates = append(templates, builtinNotificationTemplate(tenantID, event.Event, channel, lang))
+			}
+		}
+	}
+	return templates, nil
+}
+
+func (s *notificationTemplateService) GetTemplate(ctx context.Context, tenantID, event, channel, language string) (*entity.NotificationTemplate, error) {
+	if _, err := templateEvent(event, channel, language); err != nil {
+		return nil, err
+	}
+	return s.current(ctx, tenantID, event, channel, language)
+}
+
+// current returns the tenant's own template or else the built-in one
+func (s *notificationTemplateService) current(ctx context.Context, tenantID, event, channel, language string) (*entity.NotificationTemplate, error) {
+	template, err := s.templateRepo.Find(ctx, tenantID, event, channel, language)
+	if err == errors.ErrNotFound {
+		return builtinNotificationTemplate(tenantID, event, channel, language), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load notification template", err)
+	}
+	return template, nil
+}
+
+func (s *notificationTemplateService) SaveTemplate(ctx context.Context, tenantID, userID string, req *NotificationTemplateRequest) (*entity.NotificationTemplate, error) {
+	event, err := templateEvent(req.Event, req.Channel, req.Language)
+	if err != nil {
+		return nil, err
+	}
+	subject := strings.TrimSpace(req.Subject)
+	if !templateHasSubject(req.Channel) {
+		subject = ""
+	} else if subject == "" {
+		return nil, errors.NewValidationError("subject is required for email and in-app templates")
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return nil, errors.NewValidationError("body is required")
+	}
+	if len(subject) > notificationTemplateMaxSubject {
+		return nil, errors.NewValidationError(fmt.Sprintf("subject must be at most %d characters", notificationTemplateMaxSubject))
+	}
+	if len(req.Body) > notificationTemplateMaxBody {
+		return nil, errors.NewValidationError(fmt.Sprintf("body must be at most %d characters", notificationTemplateMaxBody))
+	}
+
+	// A template that cannot render the sample data would fail on every send
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	vars := sampleTemplateVars(time.Now())
+	for name, value := range tenantTemplateVars(settings, req.Language) {
+		vars[name] = value
+	}
+	if _, err := renderNotificationTemplate(event, req.Channel, req.Language, subject, req.Body, vars); err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+
+	template := &entity.NotificationTemplate{
+		TenantID: tenantID,
+		Event:    req.Event,
+		Channel:  req.Channel,
+		Language: req.Language,
+		Subject:  subject,
+		Body:     req.Body,
+	}
+	if userID != "" {
+		template.UpdatedBy = &userID
+	}
+	if err := s.templateRepo.Save(ctx, template); err != nil {
+		return nil, errors.NewDatabaseError("save notification template", err)
+	}
+	return s.current(ctx, tenantID, req.Event, req.Channel, req.Language)
+}
+
+func (s *notificationTemplateService) ResetTemplate(ctx context.Context, tenantID, event, channel, language string) error {
+	if _, err := templateEvent(event, channel, language); err != nil {
+		return err
+	}
+	err := s.templateRepo.Delete(ctx, tenantID, event, channel, language)
+	if err == errors.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.NewDatabaseError("delete notification template", err)
+	}
+	return nil
+}
+
+func (s *notificationTemplateService) Preview(ctx context.Context, tenantID string, req *NotificationTemplateRequest) (*RenderedTemplate, error) {
+	event, err := templateEvent(req.Event, req.Channel, req.Language)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, body := req.Subject, req.Body
+	if strings.TrimSpace(body) == "" {
+		template, err := s.current(ctx, tenantID, req.Event, req.Channel, req.Language)
+		if err != nil {
+			return nil, err
+		}
+		subject, body = template.Subject, template.Body
+	}
+
+	vars := sampleTemplateVars(time.Now())
+	for name, value := range tenantTemplateVars(settings, req.Language) {
+		vars[name] = value
+	}
+	rendered, err := renderNotificationTemplate(event, req.Channel, req.Language, subject, body, vars)
+	if err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+	return rendered, nil
+}
+
+func (s *notificationTemplateService) Render(ctx context.Context, settings *entity.TenantSettings, event, channel string, vars TemplateVars) (*RenderedTemplate, error) {
+	language := notificationLanguage(settings)
+	def, err := templateEvent(event, channel, language)
+	if err != nil {
+		return nil, err
+	}
+	tenantID := settings.TenantID.String()
+	template, err := s.current(ctx, tenantID, event, channel, language)
+	if err != nil {
+		return nil, err
+	}
+
+	data := tenantTemplateVars(settings, language)
+	for name, value := range vars {
+		data[name] = value
+	}
+	rendered, err := renderNotificationTemplate(def, channel, language, template.Subject, template.Body, data)
+	if err != nil && template.Customized {
+		// Saved templates render the sample data, so this is unexpected; the
+		// message still goes out with the built-in text
+		logger.Error("Notification template %s/%s/%s of tenant %s failed, using the built-in one: %v", event, channel, language, tenantID, err)
+		builtin := builtinNotificationTemplate(tenantID, event, channel, language)
+		rendered, err = renderNotificationTemplate(def, channel, language, builtin.Subject, builtin.Body, data)
+	}
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Sprintf("render %s notification: %v", event, err))
+	}
+	return rendered, nil
+}
+
+func (s *notificationTemplateService) RenderTenant(ctx context.Context, tenantID, event, channel string, vars TemplateVars) (*RenderedTemplate, error) {
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Render(ctx, settings, event, channel, vars)
+}
+
+func (s *notificationTemplateService) RenderPlatform(ctx context.Context, tenantID, event, channel string, vars TemplateVars) (*RenderedTemplate, error) {
+	def, err := platformTemplateEvent(event, channel)
+	if err != nil {
+		return nil, err
+	}
+	language := entity.NotificationLanguageIndonesian
+	if tenantID != "" {
+		settings, err := s.loadSettings(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		language = notificationLanguage(settings)
+	}
+
+	builtin := builtinNotificationTemplate(tenantID, event, channel, language)
+	rendered, err := renderNotificationTemplate(def, channel, language, builtin.Subject, builtin.Body, vars)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Sprintf("render %s notification: %v", event, err))
+	}
+	return rendered, nil
+}
+
+// tenantTemplateVars returns the company variables of the tenant
+func tenantTemplateVars(settings *entity.TenantSettings, language string) TemplateVars {
+	company := settings.CompanyName
+	if company == "" {
+		company = "penyedia internet Anda"
+		if language == entity.NotificationLanguageEnglish {
+			company = "your internet provider"
+		}
+	}
+	return TemplateVars{
+		"company_name":  company,
+		"company_phone": settings.CompanyPhone,
+	}
+}
+
+// customerTemplateVars returns the customer variables
+func customerTemplateVars(customer *entity.Customer) TemplateVars {
+	return TemplateVars{
+		"customer_name": customer.Name,
+		"customer_code": customer.CustomerCode,
+	}
+}
+
+// ticketTemplateVars returns the ticket variables, with the customer's when
+// the ticket has Customer loaded
+func ticketTemplateVars(ticket *entity.Ticket) TemplateVars {
+	vars := TemplateVars{
+		"ticket_number": ticket.TicketNumber,
+		"ticket_title":  ticket.Title,
+		"priority":      ticket.Priority,
+	}
+	if ticket.Customer != nil {
+		for name, value := range customerTemplateVars(ticket.Customer) {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// sampleTemplateVars returns sample values of every variable for previews
+func sampleTemplateVars(now time.Time) TemplateVars {
+	today := dateOf(now)
+	return TemplateVars{
+		"customer_name":    "Budi Santoso",
+		"customer_code":    "CUST-0001",
+		"invoice_number":   "INV-" + today.Format("2006-01") + "-0001",
+		"amount":           150000.0,
+		"due_date":         today.AddDate(0, 0, 3),
+		"payment_link":     "https://example.com/pay/INV-" + today.Format("2006-01") + "-0001",
+		"suspend_date":     today.AddDate(0, 0, 10),
+		"paid_date":        today,
+		"plan_name":        "Paket 20 Mbps",
+		"expiry_date":      today.AddDate(0, 0, 3).Add(23*time.Hour + 59*time.Minute),
+		"ticket_number":    "TKT-" + today.Format("20060102") + "-001",
+		"ticket_title":     "Internet mati sejak pagi",
+		"priority":         entity.TicketPriorityHigh,
+		"voucher_code":     "VC-8K2M4P",
+		"voucher_password": "731904",
+		"package_name":     "Harian 5 Mbps",
+		"price":            5000.0,
+		"duration":         "24",
+		"duration_type":    "hours",
+		"otp_code":         "482913",
+		"purpose":          entity.OTPPurposeRegistration,
+		"expiry_minutes":   "10",
+		"days_left":        "3",
+		"order_id":         "ORD-1742457600",
+		"reason":           "Pembayaran ganda",
+	}
+}
+
+// renderNotificationTemplate executes a template with the event's variables
+// formatted for the language. Unknown variables are an error.
+func renderNotificationTemplate(event *TemplateEvent, channel, language, subject, body string, vars TemplateVars) (*RenderedTemplate, error) {
+	data, err := formatTemplateVars(event, language, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &RenderedTemplate{}
+	if templateHasSubject(channel) {
+		text, err := executeTextTemplate("subject", subject, data)
+		if err != nil {
+			return nil, err
+		}
+		// Subjects and titles are a single line
+		rendered.Subject = strings.Join(strings.Fields(text), " ")
+	}
+
+	if channel == entity.NotificationChannelEmail {
+		tmpl, err := htmltemplate.New("body").Option("missingkey=error").Parse(body)
+		if err != nil {
+			return nil, err
+		}
+		out := &limitedBuffer{max: notificationTemplateMaxOutput}
+		if err := tmpl.Execute(out, data); err != nil {
+			return nil, err
+		}
+		rendered.Body = strings.TrimSpace(out.String())
+		return rendered, nil
+	}
+
+	text, err := executeTextTemplate("body", body, data)
+	if err != nil {
+		return nil, err
+	}
+	// Conditional lines that rendered empty leave blank lines behind
+	rendered.Body = strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+	return rendered, nil
+}
+
+func executeTextTemplate(name, text string, data map[string]string) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	out := &limitedBuffer{max: notificationTemplateMaxOutput}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// formatTemplateVars formats the event's variables for the language. Every
+// variable of the event is set, empty when vars leaves it out, so templates
+// can test them with {{if}}.
+func formatTemplateVars(event *TemplateEvent, language string, vars TemplateVars) (map[string]string, error) {
+	data := make(map[string]string, len(event.Variables))
+	for _, v := range event.Variables {
+		value, ok := vars[v.Name]
+		if !ok || value == nil {
+			data[v.Name] = ""
+			continue
+		}
+		switch v.Type {
+		case TemplateVarMoney:
+			amount, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("variable %s must be a money amount, got %T", v.Name, value)
+			}
+			data[v.Name] = formatMoney(language, amount)
+		case TemplateVarDate, TemplateVarDateTime:
+			t, ok := value.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("variable %s must be a time, got %T", v.Name, value)
+			}
+			if !t.IsZero() {
+				data[v.Name] = formatTemplateTime(language, t, v.Type == TemplateVarDateTime)
+			} else {
+				data[v.Name] = ""
+			}
+		default:
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("variable %s must be text, got %T", v.Name, value)
+			}
+			data[v.Name] = text
+		}
+	}
+	return data, nil
+}
+
+// formatMoney formats an amount of rupiah, e.g. "Rp 150.000" in Indonesian
+// and "IDR 150,000" in English
+func formatMoney(language string, amount float64) string {
+	rupiah := pdf.FormatRupiah(amount)
+	if language != entity.NotificationLanguageEnglish {
+		return rupiah
+	}
+	sign := ""
+	if strings.HasPrefix(rupiah, "-") {
+		sign, rupiah = "-", rupiah[1:]
+	}
+	digits := strings.NewReplacer(".", ",", ",", ".").Replace(strings.TrimPrefix(rupiah, "Rp "))
+	return sign + "IDR " + digits
+}
+
+// formatTemplateTime formats a date as 22/03/2025 in Indonesian and as
+// 22 Mar 2025 in English, with the time after it for datetimes
+func formatTemplateTime(language string, t time.Time, withTime bool) string {
+	layout := "02/01/2006"
+	if language == entity.NotificationLanguageEnglish {
+		layout = "2 Jan 2006"
+	}
+	if withTime {
+		layout += " 15:04"
+	}
+	return t.Format(layout)
+}
+
+// limitedBuffer is a buffer that fails writes past max bytes
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("rendered message is longer than %d bytes", b.max)
+	}
+	return b.Buffer.Write(p)
+}
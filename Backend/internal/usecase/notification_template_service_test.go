@@ -0,0 +1,316 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNotificationTemplateRepository struct {
+	mock.Mock
+}
+
+func (m *MockNotificationTemplateRepository) Find(ctx context.Context, tenantID, event, channel, language string) (*entity.NotificationTemplate, error) {
+	args := m.Called(ctx, tenantID, event, channel, language)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.NotificationTemplate), args.Error(1)
+}
+
+func (m *MockNotificationTemplateRepository) FindByTenant(ctx context.Context, tenantID string) ([]*entity.NotificationTemplate, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]*entity.NotificationTemplate), args.Error(1)
+}
+
+func (m *MockNotificationTemplateRepository) Save(ctx context.Context, template *entity.NotificationTemplate) error {
+	args := m.Called(ctx, template)
+	return args.Error(0)
+}
+
+func (m *MockNotificationTemplateRepository) Delete(ctx context.Context, tenantID, event, channel, language string) error {
+	args := m.Called(ctx, tenantID, event, channel, language)
+	return args.Error(0)
+}
+
+// builtinTemplateService returns a template service for a tenant that has
+// customized no template
+func builtinTemplateService() NotificationTemplateService {
+	repo := new(MockNotificationTemplateRepository)
+	repo.On("Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.ErrNotFound)
+	return NewNotificationTemplateService(repo, nil)
+}
+
+func templateTenantSettings(language string) *entity.TenantSettings {
+	return &entity.TenantSettings{
+		TenantID:             uuid.MustParse("6a1f2c3d-0000-4000-8000-000000000001"),
+		CompanyName:          "Net Desa",
+		CompanyPhone:         "0812-1111-2222",
+		NotificationLanguage: language,
+	}
+}
+
+func TestNotificationTemplateService_Render(t *testing.T) {
+	ctx := context.Background()
+	vars := TemplateVars{
+		"customer_name":  "Budi <Admin>",
+		"customer_code":  "CUST-1",
+		"invoice_number": "INV-2025-03-0001",
+		"amount":         1500000.5,
+		"due_date":       billingDate(2025, 3, 22),
+		"payment_link":   "",
+	}
+
+	t.Run("Built In Indonesian Text", func(t *testing.T) {
+		msg, err := builtinTemplateService().Render(ctx, templateTenantSettings(""), entity.NotificationEventPaymentReminder, entity.NotificationChannelWhatsApp, vars)
+
+		require.NoError(t, err)
+		assert.Empty(t, msg.Subject)
+		// The empty payment link paragraph leaves no blank lines behind
+		assert.Equal(t, "Yth. Budi <Admin> (CUST-1),\n\n"+
+			"Tagihan INV-2025-03-0001 sebesar Rp 1.500.000,50 jatuh tempo pada 22/03/2025. Mohon lakukan pembayaran sebelum tanggal tersebut.\n\n"+
+			"Terima kasih,\nNet Desa", msg.Body)
+	})
+
+	t.Run("Built In English Email", func(t *testing.T) {
+		withLink := TemplateVars{"payment_link": "https://pay.example.com/p1"}
+		for name, value := range vars {
+			if name != "payment_link" {
+				withLink[name] = value
+			}
+		}
+		msg, err := builtinTemplateService().Render(ctx, templateTenantSettings("en"), entity.NotificationEventPaymentReminder, entity.NotificationChannelEmail, withLink)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Reminder: invoice INV-2025-03-0001 is due on 22 Mar 2025", msg.Subject)
+		assert.Contains(t, msg.Body, "<p>Dear Budi &lt;Admin&gt; (CUST-1),</p>")
+		assert.Contains(t, msg.Body, "for IDR 1,500,000.50 is due on 22 Mar 2025")
+		assert.Contains(t, msg.Body, "<p>Pay online: https://pay.example.com/p1</p>")
+		assert.Contains(t, msg.Body, "<p>Thank you,<br>Net Desa</p>")
+	})
+
+	t.Run("Tenant Template", func(t *testing.T) {
+		settings := templateTenantSettings("id")
+		repo := new(MockNotificationTemplateRepository)
+		repo.On("Find", ctx, settings.TenantID.String(), entity.NotificationEventPaymentReminder, entity.NotificationChannelWhatsApp, "id").
+			Return(&entity.NotificationTemplate{Body: "Halo {{.customer_name}}, tagihan {{.amount}} s/d {{.due_date}}. {{.company_phone}}", Customized: true}, nil)
+
+		msg, err := NewNotificationTemplateService(repo, nil).Render(ctx, settings, entity.NotificationEventPaymentReminder, entity.NotificationChannelWhatsApp, vars)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Halo Budi <Admin>, tagihan Rp 1.500.000,50 s/d 22/03/2025. 0812-1111-2222", msg.Body)
+	})
+
+	t.Run("Broken Tenant Template Falls Back To Built In", func(t *testing.T) {
+		settings := templateTenantSettings("id")
+		repo := new(MockNotificationTemplateRepository)
+		repo.On("Find", ctx, settings.TenantID.String(), entity.NotificationEventPaymentReminder, entity.NotificationChannelWhatsApp, "id").
+			Return(&entity.NotificationTemplate{Body: "{{.no_such_variable}}", Customized: true}, nil)
+
+		msg, err := NewNotificationTemplateService(repo, nil).Render(ctx, settings, entity.NotificationEventPaymentReminder, entity.NotificationChannelWhatsApp, vars)
+
+		require.NoError(t, err)
+		assert.Contains(t, msg.Body, "Tagihan INV-2025-03-0001")
+	})
+
+	t.Run("Voucher Sold", func(t *testing.T) {
+		msg, err := builtinTemplateService().Render(ctx, templateTenantSettings("id"), entity.NotificationEventVoucherSold, entity.NotificationChannelWhatsApp, TemplateVars{
+			"voucher_code": "VC-8K2M4P", "voucher_password": "731904", "package_name": "Mingguan",
+			"duration": "7", "duration_type": "days", "price": 25000.0,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Voucher internet Mingguan berlaku 7 hari sejak pertama digunakan.\n\n"+
+			"Kode: VC-8K2M4P\nPassword: 731904\nHarga: Rp 25.000\n\n"+
+			"Terima kasih,\nNet Desa", msg.Body)
+	})
+
+	t.Run("Wrong Variable Type", func(t *testing.T) {
+		_, err := builtinTemplateService().Render(ctx, templateTenantSettings("id"), entity.NotificationEventPaymentReminder, entity.NotificationChannelWhatsApp, TemplateVars{"amount": "150000"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Channel Without Template", func(t *testing.T) {
+		_, err := builtinTemplateService().Render(ctx, templateTenantSettings("id"), entity.NotificationEventServiceExpiring, entity.NotificationChannelTelegram, vars)
+		assert.Error(t, err)
+	})
+}
+
+func TestNotificationTemplateService_SaveTemplate(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+
+	tests := []struct {
+		name string
+		req  NotificationTemplateRequest
+	}{
+		{"Unknown Event", NotificationTemplateRequest{Event: "voucher_used", Channel: "whatsapp", Language: "id", Body: "Halo"}},
+		{"Platform Event", NotificationTemplateRequest{Event: entity.NotificationEventEmailOTP, Channel: "email", Language: "id", Subject: "Kode", Body: "<p>{{.otp_code}}</p>"}},
+		{"Unsupported Channel", NotificationTemplateRequest{Event: entity.NotificationEventServiceExpiring, Channel: "whatsapp", Language: "id", Body: "Halo"}},
+		{"Unsupported Language", NotificationTemplateRequest{Event: entity.NotificationEventPaymentReminder, Channel: "whatsapp", Language: "fr", Body: "Bonjour"}},
+		{"Email Without Subject", NotificationTemplateRequest{Event: entity.NotificationEventPaymentReminder, Channel: "email", Language: "id", Body: "<p>Halo</p>"}},
+		{"Unknown Variable", NotificationTemplateRequest{Event: entity.NotificationEventPaymentReminder, Channel: "whatsapp", Language: "id", Body: "Halo {{.nama}}"}},
+		{"Syntax Error", NotificationTemplateRequest{Event: entity.NotificationEventPaymentReminder, Channel: "whatsapp", Language: "id", Body: "Halo {{if .customer_name}}"}},
+		{"Runaway Output", NotificationTemplateRequest{Event: entity.NotificationEventPaymentReminder, Channel: "whatsapp", Language: "id", Body: "{{range 100000}}{{$.customer_name}}{{end}}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockNotificationTemplateRepository)
+			settingsRepo := new(MockSettingsRepository)
+			settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(templateTenantSettings("id"), nil)
+
+			_, err := NewNotificationTemplateService(repo, settingsRepo).SaveTemplate(ctx, tenantID, "user-1", &tt.req)
+
+			var appErr *errors.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, 400, appErr.Status)
+			repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("Saves", func(t *testing.T) {
+		repo := new(MockNotificationTemplateRepository)
+		settingsRepo := new(MockSettingsRepository)
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(templateTenantSettings("id"), nil)
+		repo.On("Save", ctx, mock.AnythingOfType("*entity.NotificationTemplate")).Return(nil)
+		saved := &entity.NotificationTemplate{TenantID: tenantID, Event: "payment_reminder", Channel: "whatsapp", Language: "id", Body: "Halo {{.customer_name}}", Customized: true}
+		repo.On("Find", ctx, tenantID, "payment_reminder", "whatsapp", "id").Return(saved, nil)
+
+		template, err := NewNotificationTemplateService(repo, settingsRepo).SaveTemplate(ctx, tenantID, "user-1", &NotificationTemplateRequest{
+			Event: "payment_reminder", Channel: "whatsapp", Language: "id", Subject: "ignored", Body: "Halo {{.customer_name}}",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, saved, template)
+		stored := repo.Calls[0].Arguments.Get(1).(*entity.NotificationTemplate)
+		assert.Empty(t, stored.Subject, "WhatsApp messages have no subject")
+		assert.Equal(t, "user-1", *stored.UpdatedBy)
+	})
+}
+
+func TestNotificationTemplateService_Preview(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	repo := new(MockNotificationTemplateRepository)
+	settingsRepo := new(MockSettingsRepository)
+	settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(templateTenantSettings("id"), nil)
+	repo.On("Find", ctx, tenantID, entity.NotificationEventTicketCreated, entity.NotificationChannelInApp, "en").Return(nil, errors.ErrNotFound)
+	service := NewNotificationTemplateService(repo, settingsRepo)
+
+	t.Run("Draft Template", func(t *testing.T) {
+		msg, err := service.Preview(ctx, tenantID, &NotificationTemplateRequest{
+			Event: entity.NotificationEventPaymentConfirmation, Channel: entity.NotificationChannelEmail, Language: "id",
+			Subject: "Terima kasih {{.customer_name}}", Body: "<p>{{.amount}} diterima oleh <b>{{.company_name}}</b></p>",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Terima kasih Budi Santoso", msg.Subject)
+		assert.Equal(t, "<p>Rp 150.000 diterima oleh <b>Net Desa</b></p>", msg.Body)
+	})
+
+	t.Run("Current Template", func(t *testing.T) {
+		msg, err := service.Preview(ctx, tenantID, &NotificationTemplateRequest{
+			Event: entity.NotificationEventTicketCreated, Channel: entity.NotificationChannelInApp, Language: "en",
+		})
+
+		require.NoError(t, err)
+		assert.Contains(t, msg.Subject, "New ticket TKT-")
+		assert.Contains(t, msg.Body, "Internet mati sejak pagi\nCustomer: CUST-0001 - Budi Santoso")
+	})
+
+	t.Run("Invalid Draft", func(t *testing.T) {
+		_, err := service.Preview(ctx, tenantID, &NotificationTemplateRequest{
+			Event: entity.NotificationEventPaymentConfirmation, Channel: entity.NotificationChannelWhatsApp, Language: "id",
+			Body: "{{.due_date}}",
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestNotificationTemplateService_ListTemplates(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockNotificationTemplateRepository)
+	own := &entity.NotificationTemplate{TenantID: "tenant-1", Event: "suspended", Channel: "whatsapp", Language: "en", Body: "Suspended", Customized: true}
+	repo.On("FindByTenant", ctx, "tenant-1").Return([]*entity.NotificationTemplate{own}, nil)
+
+	templates, err := NewNotificationTemplateService(repo, nil).ListTemplates(ctx, "tenant-1", "en")
+
+	require.NoError(t, err)
+	channels := 0
+	for _, event := range templateEvents {
+		channels += len(event.Channels)
+	}
+	require.Len(t, templates, channels)
+	for _, template := range templates {
+		assert.Equal(t, "en", template.Language)
+		assert.NotEmpty(t, template.Body, "%s/%s", template.Event, template.Channel)
+		assert.Equal(t, template == own, template.Customized)
+	}
+	assert.Contains(t, templates, own)
+}
+
+func TestBuiltinTemplates_RenderSampleData(t *testing.T) {
+	vars := sampleTemplateVars(time.Date(2025, 3, 20, 8, 0, 0, 0, time.UTC))
+	for name, value := range tenantTemplateVars(templateTenantSettings("id"), "id") {
+		vars[name] = value
+	}
+	for _, event := range append(append([]*TemplateEvent{}, templateEvents...), platformTemplateEvents...) {
+		require.Contains(t, builtinTemplates, event.Event)
+		for _, channel := range event.Channels {
+			for _, language := range notificationLanguages {
+				builtin := builtinNotificationTemplate("tenant-1", event.Event, channel, language)
+				msg, err := renderNotificationTemplate(event, channel, language, builtin.Subject, builtin.Body, vars)
+				require.NoError(t, err, "%s/%s/%s", event.Event, channel, language)
+				assert.NotEmpty(t, msg.Body)
+				assert.Equal(t, templateHasSubject(channel), msg.Subject != "", "%s/%s/%s", event.Event, channel, language)
+			}
+		}
+	}
+}
+
+func TestNotificationTemplateService_RenderPlatform(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("OTP Email Without Tenant", func(t *testing.T) {
+		msg, err := builtinTemplateService().RenderPlatform(ctx, "", entity.NotificationEventEmailOTP, entity.NotificationChannelEmail, TemplateVars{
+			"otp_code": "482913", "purpose": entity.OTPPurposeResetPassword, "expiry_minutes": "10",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Kode Reset Password", msg.Subject)
+		assert.Contains(t, msg.Body, "<p><strong>482913</strong></p>")
+		assert.Contains(t, msg.Body, "permintaan reset password")
+		assert.Contains(t, msg.Body, "10 menit")
+	})
+
+	t.Run("In Tenant Language", func(t *testing.T) {
+		settings := templateTenantSettings("en")
+		settingsRepo := new(MockSettingsRepository)
+		settingsRepo.On("GetTenantSettings", ctx, settings.TenantID.String()).Return(settings, nil)
+
+		msg, err := NewNotificationTemplateService(new(MockNotificationTemplateRepository), settingsRepo).RenderPlatform(ctx, settings.TenantID.String(),
+			entity.NotificationEventPaymentRefunded, entity.NotificationChannelInApp, TemplateVars{"order_id": "ORD-1", "amount": 300000.0, "reason": "Double payment"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Payment Refunded", msg.Subject)
+		assert.Equal(t, "Payment ORD-1 of IDR 300,000 has been refunded. Reason: Double payment", msg.Body)
+	})
+
+	t.Run("Tenant Event Is Not A Platform Event", func(t *testing.T) {
+		_, err := builtinTemplateService().RenderPlatform(ctx, "", entity.NotificationEventPaymentReminder, entity.NotificationChannelWhatsApp, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestFormatMoney(t *testing.T) {
+	assert.Equal(t, "Rp 150.000", formatMoney("id", 150000))
+	assert.Equal(t, "IDR 150,000", formatMoney("en", 150000))
+	assert.Equal(t, "-IDR 1,250.50", formatMoney("en", -1250.5))
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
@@ -24,6 +25,7 @@ type otpService struct {
 	otpRepo      repository.OTPRepository
 	userRepo     repository.UserRepository
 	emailService *email.Service
+	templates    NotificationTemplateService
 	otpExpiry    time.Duration
 }
 
@@ -31,11 +33,13 @@ func NewOTPService(
 	otpRepo repository.OTPRepository,
 	userRepo repository.UserRepository,
 	emailService *email.Service,
+	templates NotificationTemplateService,
 ) OTPService {
 	return &otpService{
 		otpRepo:      otpRepo,
 		userRepo:     userRepo,
 		emailService: emailService,
+		templates:    templates,
 		otpExpiry:    10 * time.Minute, // OTP valid for 10 minutes
 	}
 }
@@ -78,7 +82,7 @@ func (s *otpService) SendOTP(ctx context.Context, emailAddr, purpose string) err
 
 	// Send OTP via email
 	if s.emailService != nil {
-		if err := s.emailService.SendOTP(emailAddr, otp, purpose); err != nil {
+		if err := s.sendOTPEmail(ctx, emailAddr, otp, purpose); err != nil {
 			logger.Error("Failed to send OTP email: %v", err)
 			// For development, log the OTP
 			logger.Info("OTP for %s: %s (email sending failed)", emailAddr, otp)
@@ -94,6 +98,19 @@ func (s *otpService) SendOTP(ctx context.Context, emailAddr, purpose string) err
 	return nil
 }
 
+// sendOTPEmail emails the OTP with the platform's email_otp text
+func (s *otpService) sendOTPEmail(ctx context.Context, emailAddr, otp, purpose string) error {
+	msg, err := s.templates.RenderPlatform(ctx, "", entity.NotificationEventEmailOTP, entity.NotificationChannelEmail, TemplateVars{
+		"otp_code":       otp,
+		"purpose":        purpose,
+		"expiry_minutes": strconv.Itoa(int(s.otpExpiry / time.Minute)),
+	})
+	if err != nil {
+		return err
+	}
+	return s.emailService.SendHTML(emailAddr, msg.Subject, msg.Body)
+}
+
 // VerifyOTP verifies the OTP
 func (s *otpService) VerifyOTP(ctx context.Context, emailAddr, otp, purpose string) error {
 	// Find the latest OTP for this email and purpose
@@ -25,7 +25,7 @@ type paymentService struct {
 	subscriptionRepo repository.TenantSubscriptionRepository
 	subPlanRepo      repository.SubscriptionPlanRepository
 	userRepo         repository.UserRepository
-	midtransClient   *payment.MidtransClient
+	gateways         *payment.Gateways
 }
 
 func NewPaymentService(
@@ -34,7 +34,7 @@ func NewPaymentService(
 	subscriptionRepo repository.TenantSubscriptionRepository,
 	subPlanRepo repository.SubscriptionPlanRepository,
 	userRepo repository.UserRepository,
-	midtransClient *payment.MidtransClient,
+	gateways *payment.Gateways,
 ) PaymentService {
 	return &paymentService{
 		transactionRepo:  transactionRepo,
@@ -42,7 +42,7 @@ func NewPaymentService(
 		subscriptionRepo: subscriptionRepo,
 		subPlanRepo:      subPlanRepo,
 		userRepo:         userRepo,
-		midtransClient:   midtransClient,
+		gateways:         gateways,
 	}
 }
 
@@ -148,6 +148,14 @@ func (s *paymentService) GetInvoiceDetails(ctx context.Context, tenantID string,
 				}
 			}
 
+			// QR URL, deeplink and hosted payment page as stored by payment.Charge
+			for _, key := range []string{"qr_code_url", "deeplink_url", "checkout_url"} {
+				if url, ok := gatewayResponse[key].(string); ok && url != "" {
+					paymentInfo[key] = url
+					hasPayment = true
+				}
+			}
+
 			// Expiry time from gateway
 			if expiryTime, ok := gatewayResponse["expiry_time"].(string); ok && expiryTime != "" {
 				paymentInfo["expiry_time"] = expiryTime
@@ -187,161 +195,80 @@ func (s *paymentService) CreatePaymentToken(ctx context.Context, tenantID string
 		return nil, errors.ErrNotFound
 	}
 
-	// Check if payment already exists in Midtrans
-	existingStatus, err := s.midtransClient.GetTransactionStatus(orderID)
-	if err == nil && existingStatus != nil && existingStatus.TransactionStatus == "pending" {
-		logger.Info("Payment already exists in Midtrans: order=%s, status=%s, type=%s", 
-			orderID, existingStatus.TransactionStatus, existingStatus.PaymentType)
-		
+	gateway := s.gateways.Default()
+	if gateway == nil {
+		return nil, errors.New("PAYMENT_GATEWAY_NOT_CONFIGURED", "Payment gateway not configured", 500)
+	}
+
+	// Check if payment already exists at the gateway
+	var existing *payment.Charge
+	if transaction.PaymentGateway == gateway.Name() {
+		existing, err = gateway.GetStatus(ctx, orderID)
+	}
+	if err == nil && existing != nil && existing.Status == payment.StatusPending {
+		logger.Info("Payment already exists in %s: order=%s, status=%s, type=%s",
+			gateway.Name(), orderID, existing.GatewayStatus, existing.PaymentType)
+
 		// Check if user selected the same payment method
-		existingPaymentType := existingStatus.PaymentType
-		isSameMethod := false
-		
-		switch paymentMethod {
-		case "bca_va", "bni_va", "bri_va", "permata_va":
-			isSameMethod = existingPaymentType == "bank_transfer"
-		case "mandiri_bill":
-			isSameMethod = existingPaymentType == "echannel"
-		case "gopay":
-			isSameMethod = existingPaymentType == "gopay"
-		case "shopeepay":
-			isSameMethod = existingPaymentType == "shopeepay"
-		case "qris":
-			isSameMethod = existingPaymentType == "qris"
-		}
-		
-		if isSameMethod {
-			// Return existing payment data
-			result := map[string]interface{}{
-				"order_id":       orderID,
-				"transaction_id": existingStatus.TransactionID,
-				"status":         existingStatus.TransactionStatus,
-				"amount":         transaction.Amount,
-				"payment_type":   existingStatus.PaymentType,
-			}
-			
-			// Add VA numbers if available
-			if existingStatus.VANumbers != nil && len(existingStatus.VANumbers) > 0 {
-				result["va_numbers"] = existingStatus.VANumbers
-			}
-			if existingStatus.PermataVANumber != "" {
-				result["permata_va_number"] = existingStatus.PermataVANumber
-			}
-			if existingStatus.BillerCode != "" {
-				result["biller_code"] = existingStatus.BillerCode
-				result["bill_key"] = existingStatus.BillKey
-			}
-			// Add QR code for e-wallet
-			if existingStatus.Actions != nil {
-				for _, action := range existingStatus.Actions {
-					if action.Name == "generate-qr-code" {
-						result["qr_code_url"] = action.URL
-					}
-					if action.Name == "deeplink-redirect" {
-						result["deeplink_url"] = action.URL
-					}
-				}
-			}
-			
+		if transaction.PaymentMethod == paymentMethod || sameMidtransPaymentType(paymentMethod, existing.PaymentType) {
 			logger.Info("Returning existing payment: order=%s", orderID)
-			return result, nil
+			return chargeResult(orderID, transaction.Amount, existing), nil
 		}
-		
+
 		// Different payment method selected - need to use new order ID
 		// Midtrans doesn't allow reusing order_id after cancel
-		logger.Info("Different payment method selected: order=%s, old=%s, new=%s", 
-			orderID, existingPaymentType, paymentMethod)
-		
+		logger.Info("Different payment method selected: order=%s, old=%s, new=%s",
+			orderID, existing.PaymentType, paymentMethod)
+
 		// Add timestamp suffix to create unique order ID
 		orderID = fmt.Sprintf("%s-%d", orderID, time.Now().Unix())
 		logger.Info("Using new order ID: %s", orderID)
-		
-	} else if err == nil && existingStatus != nil {
+
+	} else if err == nil && existing != nil && (existing.Status == payment.StatusFailed || existing.Status == payment.StatusExpired) {
 		// If expired/failed, use new order ID
-		if existingStatus.TransactionStatus == "expire" || existingStatus.TransactionStatus == "cancel" || existingStatus.TransactionStatus == "deny" {
-			logger.Info("Previous payment expired/failed: order=%s, status=%s", orderID, existingStatus.TransactionStatus)
-			orderID = fmt.Sprintf("%s-%d", orderID, time.Now().Unix())
-			logger.Info("Using new order ID: %s", orderID)
-		}
+		logger.Info("Previous payment expired/failed: order=%s, status=%s", orderID, existing.GatewayStatus)
+		orderID = fmt.Sprintf("%s-%d", orderID, time.Now().Unix())
+		logger.Info("Using new order ID: %s", orderID)
 	}
 
-	// Create Midtrans charge request based on payment method
-	// Round amount to integer (Midtrans IDR doesn't accept decimals)
+	// Round amount to integer (IDR gateways don't accept decimals)
 	amount := int64(transaction.Amount)
-	
-	var chargeResp *payment.ChargeResponse
-	
-	// Parse payment method and create appropriate charge
-	switch paymentMethod {
-	case "bca_va":
-		chargeResp, err = s.midtransClient.ChargeBankTransfer(orderID, float64(amount), "bca", &payment.CustomerDetails{
-			FirstName: tenant.Name,
-			Email:     tenant.Email,
-		}, nil)
-	case "bni_va":
-		chargeResp, err = s.midtransClient.ChargeBankTransfer(orderID, float64(amount), "bni", &payment.CustomerDetails{
-			FirstName: tenant.Name,
-			Email:     tenant.Email,
-		}, nil)
-	case "bri_va":
-		chargeResp, err = s.midtransClient.ChargeBankTransfer(orderID, float64(amount), "bri", &payment.CustomerDetails{
-			FirstName: tenant.Name,
-			Email:     tenant.Email,
-		}, nil)
-	case "permata_va":
-		chargeResp, err = s.midtransClient.ChargeBankTransfer(orderID, float64(amount), "permata", &payment.CustomerDetails{
-			FirstName: tenant.Name,
-			Email:     tenant.Email,
-		}, nil)
-	case "mandiri_bill":
-		chargeResp, err = s.midtransClient.ChargeMandiriBill(orderID, float64(amount), &payment.CustomerDetails{
-			FirstName: tenant.Name,
-			Email:     tenant.Email,
-		}, nil)
-	case "gopay":
-		callbackURL := fmt.Sprintf("%s/payment/finish", s.midtransClient.GetClientKey())
-		chargeResp, err = s.midtransClient.ChargeGopay(orderID, float64(amount), &payment.CustomerDetails{
-			FirstName: tenant.Name,
-			Email:     tenant.Email,
-		}, nil, callbackURL)
-	case "shopeepay":
-		callbackURL := fmt.Sprintf("%s/payment/finish", s.midtransClient.GetClientKey())
-		chargeResp, err = s.midtransClient.ChargeShopeePay(orderID, float64(amount), &payment.CustomerDetails{
-			FirstName: tenant.Name,
-			Email:     tenant.Email,
-		}, nil, callbackURL)
-	case "qris":
-		chargeResp, err = s.midtransClient.ChargeQRIS(orderID, float64(amount), &payment.CustomerDetails{
+
+	logger.Info("Creating %s charge: order=%s, amount=%.0f, method=%s, tenant=%s",
+		gateway.Name(), orderID, float64(amount), paymentMethod, tenant.Name)
+
+	charge, err := gateway.CreatePayment(ctx, &payment.ChargeParams{
+		OrderID:     orderID,
+		Amount:      float64(amount),
+		Method:      paymentMethod,
+		Description: "Subscription Payment",
+		Customer: &payment.CustomerDetails{
 			FirstName: tenant.Name,
 			Email:     tenant.Email,
-		}, nil)
-	default:
+		},
+	})
+	if err == payment.ErrUnsupportedMethod {
 		return nil, errors.New("INVALID_PAYMENT_METHOD", "Invalid payment method", 400)
 	}
-
-	logger.Info("Creating Midtrans charge: order=%s, amount=%.0f, method=%s, tenant=%s", 
-		orderID, float64(amount), paymentMethod, tenant.Name)
 	if err != nil {
-		logger.Error("Failed to create Midtrans charge: %v", err)
+		logger.Error("Failed to create %s charge: %v", gateway.Name(), err)
 		return nil, errors.New("PAYMENT_FAILED", fmt.Sprintf("Failed to create payment: %v", err), 500)
 	}
-	
-	// Log response for debugging
-	respJSON, _ := json.Marshal(chargeResp)
-	logger.Info("Midtrans response: %s", string(respJSON))
 
-	logger.Info("Midtrans charge created: order=%s, transaction_id=%s, status=%s", 
-		orderID, chargeResp.TransactionID, chargeResp.TransactionStatus)
+	logger.Info("%s charge created: order=%s, transaction_id=%s, status=%s",
+		gateway.Name(), orderID, charge.TransactionID, charge.GatewayStatus)
 
 	// Update transaction with gateway response
-	gatewayResponseJSON, _ := json.Marshal(chargeResp)
+	gatewayResponseJSON, _ := json.Marshal(charge)
 	transaction.GatewayResponse = string(gatewayResponseJSON)
-	transaction.GatewayTransactionID = chargeResp.TransactionID
-
-	// Parse and set expiry time from Midtrans response
-	if chargeResp.ExpiryTime != "" {
-		// Midtrans expiry time format: "2024-01-15 12:00:00"
-		expiryTime, err := time.Parse("2006-01-02 15:04:05", chargeResp.ExpiryTime)
+	transaction.GatewayTransactionID = charge.TransactionID
+	transaction.PaymentGateway = gateway.Name()
+	transaction.PaymentMethod = paymentMethod
+
+	// Parse and set expiry time from the gateway response
+	if charge.ExpiryTime != "" {
+		// Expiry time format: "2024-01-15 12:00:00" in WIB
+		expiryTime, err := charge.ExpiresAt()
 		if err != nil {
 			logger.Error("Failed to parse expiry time: %v", err)
 		} else {
@@ -354,54 +281,74 @@ func (s *paymentService) CreatePaymentToken(ctx context.Context, tenantID string
 		logger.Error("Failed to update transaction: %v", err)
 	}
 
-	logger.Info("Payment token created: order=%s, transaction_id=%s", orderID, chargeResp.TransactionID)
+	logger.Info("Payment token created: order=%s, transaction_id=%s", orderID, charge.TransactionID)
+
+	return chargeResult(orderID, transaction.Amount, charge), nil
+}
+
+// sameMidtransPaymentType reports whether a Midtrans payment type belongs to
+// the payment method, for transactions charged before the method was stored
+func sameMidtransPaymentType(paymentMethod, paymentType string) bool {
+	switch paymentMethod {
+	case "bca_va", "bni_va", "bri_va", "permata_va":
+		return paymentType == "bank_transfer"
+	case "mandiri_bill":
+		return paymentType == "echannel"
+	case "gopay":
+		return paymentType == "gopay"
+	case "shopeepay":
+		return paymentType == "shopeepay"
+	case "qris":
+		return paymentType == "qris"
+	}
+	return false
+}
 
-	// Return payment data
+// chargeResult returns the payment data of a charge
+func chargeResult(orderID string, amount float64, charge *payment.Charge) map[string]interface{} {
 	result := map[string]interface{}{
 		"order_id":       orderID,
-		"transaction_id": chargeResp.TransactionID,
-		"status":         chargeResp.TransactionStatus,
-		"amount":         transaction.Amount,
-		"payment_type":   chargeResp.PaymentType,
+		"transaction_id": charge.TransactionID,
+		"status":         charge.GatewayStatus,
+		"amount":         amount,
+		"payment_type":   charge.PaymentType,
+		"gateway":        charge.Gateway,
 	}
 
 	// Add expiry time if available
-	if chargeResp.ExpiryTime != "" {
-		result["expiry_time"] = chargeResp.ExpiryTime
-	}
-
-	// Add payment-specific info
-	if chargeResp.Actions != nil && len(chargeResp.Actions) > 0 {
-		result["actions"] = chargeResp.Actions
+	if charge.ExpiryTime != "" {
+		result["expiry_time"] = charge.ExpiryTime
 	}
 
 	// For QR-based payments (GoPay, QRIS)
-	if chargeResp.Actions != nil {
-		for _, action := range chargeResp.Actions {
-			if action.Name == "generate-qr-code" {
-				result["qr_code_url"] = action.URL
-			}
-			if action.Name == "deeplink-redirect" {
-				result["deeplink_url"] = action.URL
-			}
-		}
+	if charge.QRCodeURL != "" {
+		result["qr_code_url"] = charge.QRCodeURL
+	}
+	if charge.DeeplinkURL != "" {
+		result["deeplink_url"] = charge.DeeplinkURL
+	}
+	if charge.QRString != "" {
+		result["qr_string"] = charge.QRString
 	}
 
 	// For VA-based payments
-	if chargeResp.VANumbers != nil && len(chargeResp.VANumbers) > 0 {
-		result["va_numbers"] = chargeResp.VANumbers
+	if len(charge.VANumbers) > 0 {
+		result["va_numbers"] = charge.VANumbers
 	}
-
-	if chargeResp.PermataVANumber != "" {
-		result["permata_va_number"] = chargeResp.PermataVANumber
+	if charge.PermataVANumber != "" {
+		result["permata_va_number"] = charge.PermataVANumber
+	}
+	if charge.BillerCode != "" {
+		result["biller_code"] = charge.BillerCode
+		result["bill_key"] = charge.BillKey
 	}
 
-	if chargeResp.BillerCode != "" {
-		result["biller_code"] = chargeResp.BillerCode
-		result["bill_key"] = chargeResp.BillKey
+	// Hosted payment page (Xendit)
+	if charge.CheckoutURL != "" {
+		result["checkout_url"] = charge.CheckoutURL
 	}
 
-	return result, nil
+	return result
 }
 
 func (s *paymentService) GetPaymentStatus(ctx context.Context, tenantID string, orderID string) (map[string]interface{}, error) {
@@ -419,11 +366,19 @@ func (s *paymentService) GetPaymentStatus(ctx context.Context, tenantID string,
 		return nil, errors.ErrUnauthorized
 	}
 
-	// Get status from Midtrans
-	statusResp, err := s.midtransClient.GetTransactionStatus(orderID)
+	// Get status from the gateway that charged the transaction
+	gateway := s.gateways.Get(transaction.PaymentGateway)
+	if gateway == nil {
+		return map[string]interface{}{
+			"order_id": orderID,
+			"status":   transaction.Status,
+			"amount":   transaction.Amount,
+		}, nil
+	}
+	statusResp, err := gateway.GetStatus(ctx, orderID)
 	if err != nil {
-		logger.Error("Failed to get Midtrans status: %v", err)
-		// Return local status if Midtrans fails
+		logger.Error("Failed to get %s status: %v", gateway.Name(), err)
+		// Return local status if the gateway fails
 		return map[string]interface{}{
 			"order_id": orderID,
 			"status":   transaction.Status,
@@ -431,26 +386,26 @@ func (s *paymentService) GetPaymentStatus(ctx context.Context, tenantID string,
 		}, nil
 	}
 
-	logger.Info("Midtrans status: order=%s, status=%s, payment_type=%s",
-		orderID, statusResp.TransactionStatus, statusResp.PaymentType)
+	logger.Info("%s status: order=%s, status=%s, payment_type=%s",
+		gateway.Name(), orderID, statusResp.GatewayStatus, statusResp.PaymentType)
 
 	// Update local transaction status
 	var newStatus string
-	switch statusResp.TransactionStatus {
-	case "capture", "settlement":
+	switch statusResp.Status {
+	case payment.StatusPaid:
 		newStatus = entity.TransactionStatusPaid
-	case "pending":
+	case payment.StatusPending:
 		newStatus = entity.TransactionStatusPending
-	case "deny", "cancel", "expire":
+	case payment.StatusFailed, payment.StatusExpired:
 		newStatus = entity.TransactionStatusFailed
 	default:
 		newStatus = transaction.Status
 	}
 
 	// Also check if local status is already paid (e.g., from webhook or manual update)
-	// This handles cases where Midtrans returns stale status
+	// This handles cases where the gateway returns stale status
 	if transaction.Status == entity.TransactionStatusPaid && newStatus != entity.TransactionStatusPaid {
-		logger.Info("Local status is paid but Midtrans says %s. Using local status.", statusResp.TransactionStatus)
+		logger.Info("Local status is paid but %s says %s. Using local status.", gateway.Name(), statusResp.GatewayStatus)
 		newStatus = entity.TransactionStatusPaid
 	}
 
@@ -509,6 +464,7 @@ func (s *paymentService) GetPaymentStatus(ctx context.Context, tenantID string,
 			subscription, err := s.subscriptionRepo.FindByID(ctx, *transaction.SubscriptionID)
 			if err == nil && subscription != nil {
 				logger.Info("Current subscription: plan_id=%s, status=%s", subscription.PlanID, subscription.Status)
+				renewal := transaction.PlanID == nil || *transaction.PlanID == "" || *transaction.PlanID == subscription.PlanID
 
 				// Update plan if transaction has a new plan ID (upgrade)
 				if transaction.PlanID != nil && *transaction.PlanID != "" {
@@ -521,13 +477,7 @@ func (s *paymentService) GetPaymentStatus(ctx context.Context, tenantID string,
 				}
 
 				// Activate/extend subscription
-				subscription.Status = entity.SubscriptionStatusActive
-				if subscription.StartDate == nil {
-					subscription.StartDate = &now
-				}
-				endDate := now.AddDate(0, 1, 0) // +1 month
-				subscription.EndDate = &endDate
-				subscription.NextBillingDate = &endDate
+				startSubscriptionPeriod(subscription, now, renewal)
 				subscription.PaymentMethod = statusResp.PaymentType
 
 				if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
@@ -580,10 +530,10 @@ func (s *paymentService) GetPaymentStatus(ctx context.Context, tenantID string,
 	return map[string]interface{}{
 		"order_id":         orderID,
 		"transaction_id":   statusResp.TransactionID,
-		"status":           statusResp.TransactionStatus,
+		"status":           statusResp.GatewayStatus,
 		"amount":           transaction.Amount,
 		"payment_type":     statusResp.PaymentType,
 		"transaction_time": statusResp.TransactionTime,
-		"fraud_status":     statusResp.FraudStatus,
+		"gateway":          gateway.Name(),
 	}, nil
 }
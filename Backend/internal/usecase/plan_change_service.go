@@ -0,0 +1,435 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// PlanChangeService moves customers between service plans. A change applied
+// within a billing cycle is prorated against the cycle's invoice, and the
+// new rate limit is pushed to FreeRADIUS and to open sessions on the NAS.
+type PlanChangeService interface {
+	// ChangePlan moves the customer to another plan now or from the first day
+	// of their next billing cycle. Earlier scheduled changes are cancelled.
+	ChangePlan(ctx context.Context, tenantID, customerID string, req *PlanChangeRequest) (*entity.CustomerPlanChange, error)
+	// CancelPlanChange cancels a change that is still scheduled
+	CancelPlanChange(ctx context.Context, tenantID, customerID, changeID string) error
+	// ListPlanHistory returns the customer's plan changes, newest first
+	ListPlanHistory(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerPlanChange, error)
+	// ApplyDueChanges applies the tenant's scheduled changes that took effect
+	// by now and returns how many were applied
+	ApplyDueChanges(ctx context.Context, tenantID string, now time.Time) (int, error)
+	// ApplyAllTenants runs ApplyDueChanges for every active tenant
+	ApplyAllTenants(ctx context.Context, now time.Time) error
+}
+
+// PlanChangeRequest describes a plan change
+type PlanChangeRequest struct {
+	ServicePlanID string
+	Effective     string  // immediate (default) or next_cycle
+	MonthlyFee    float64 // fee on the new plan, zero for the plan's price
+	Reason        string
+	CreatedBy     *string
+}
+
+type planChangeService struct {
+	planChangeRepo repository.PlanChangeRepository
+	invoiceRepo    repository.InvoiceRepository
+	settingsRepo   repository.SettingsRepository
+	tenantRepo     repository.TenantRepository
+	radiusSync     RadiusUserSyncer
+	coaService     RadiusCoAService
+}
+
+func NewPlanChangeService(
+	planChangeRepo repository.PlanChangeRepository,
+	invoiceRepo repository.InvoiceRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+	radiusSync RadiusUserSyncer,
+	coaService RadiusCoAService,
+) PlanChangeService {
+	return &planChangeService{
+		planChangeRepo: planChangeRepo,
+		invoiceRepo:    invoiceRepo,
+		settingsRepo:   settingsRepo,
+		tenantRepo:     tenantRepo,
+		radiusSync:     radiusSync,
+		coaService:     coaService,
+	}
+}
+
+// planRate is a plan's name and the monthly fee the customer pays on it
+type planRate struct {
+	Name string
+	Fee  float64
+}
+
+func newPlanRate(plan *entity.ServicePlan, fee float64) planRate {
+	name := "previous plan"
+	if plan != nil {
+		name = plan.Name
+	}
+	return planRate{Name: name, Fee: fee}
+}
+
+// prorationItems returns the lines settling a plan change effective on day
+// within cycle. A cycle billed at the old fee gets the days from the change
+// on credited at the old fee and charged at the new one. A cycle not billed
+// yet will be billed at the new fee, so the days before the change are
+// charged at the old fee and credited at the new one instead.
+func prorationItems(cycle billingPeriod, day time.Time, billed bool, from, to planRate) []entity.InvoiceItem {
+	start, end := dateOf(day), cycle.End
+	credit, charge := from, to
+	if !billed {
+		start, end = cycle.Start, dateOf(day).AddDate(0, 0, -1)
+		credit, charge = to, from
+	}
+	days := daysBetween(start, end) + 1
+	if days <= 0 {
+		return nil
+	}
+	total := daysBetween(cycle.Start, cycle.End) + 1
+	span := fmt.Sprintf("%s - %s (%d of %d days)", start.Format("02/01/2006"), end.Format("02/01/2006"), days, total)
+
+	creditAmount := entity.RoundMoney(credit.Fee * float64(days) / float64(total))
+	chargeAmount := entity.RoundMoney(charge.Fee * float64(days) / float64(total))
+	if creditAmount == chargeAmount {
+		return nil
+	}
+
+	items := make([]entity.InvoiceItem, 0, 2)
+	if creditAmount > 0 {
+		items = append(items, entity.InvoiceItem{
+			Type:        entity.InvoiceItemProration,
+			Description: fmt.Sprintf("Unused %s, %s", credit.Name, span),
+			Quantity:    1,
+			UnitPrice:   -creditAmount,
+		})
+	}
+	if chargeAmount > 0 {
+		items = append(items, entity.InvoiceItem{
+			Type:        entity.InvoiceItemProration,
+			Description: fmt.Sprintf("%s, %s", charge.Name, span),
+			Quantity:    1,
+			UnitPrice:   chargeAmount,
+		})
+	}
+	return items
+}
+
+// daysBetween counts the calendar days from start to end
+func daysBetween(start, end time.Time) int {
+	return int(math.Round(end.Sub(start).Hours() / 24))
+}
+
+func (s *planChangeService) loadSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		return defaultTenantSettings(tenantID), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	return settings, nil
+}
+
+func (s *planChangeService) ChangePlan(ctx context.Context, tenantID, customerID string, req *PlanChangeRequest) (*entity.CustomerPlanChange, error) {
+	effective := req.Effective
+	if effective == "" {
+		effective = entity.PlanChangeImmediate
+	}
+	if effective != entity.PlanChangeImmediate && effective != entity.PlanChangeNextCycle {
+		return nil, errors.NewValidationError("effective must be immediate or next_cycle")
+	}
+	if req.MonthlyFee < 0 {
+		return nil, errors.NewValidationError("monthly fee must not be negative")
+	}
+
+	customer, err := s.planChangeRepo.FindCustomer(ctx, tenantID, customerID)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewCustomerNotFoundError(customerID)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load customer", err)
+	}
+	plan, err := s.planChangeRepo.FindPlan(ctx, tenantID, req.ServicePlanID)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewServicePlanNotFoundError(req.ServicePlanID)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load service plan", err)
+	}
+	if !plan.IsActive {
+		return nil, errors.NewValidationError("service plan is not active")
+	}
+	if plan.ID == customer.ServicePlanID {
+		return nil, errors.NewValidationError("customer is already on this plan")
+	}
+
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	fee := req.MonthlyFee
+	if fee == 0 {
+		fee = plan.Price
+	}
+	change := &entity.CustomerPlanChange{
+		TenantID:       tenantID,
+		CustomerID:     customer.ID,
+		FromPlanID:     customer.ServicePlanID,
+		ToPlanID:       plan.ID,
+		FromMonthlyFee: customer.MonthlyFee,
+		ToMonthlyFee:   fee,
+		Effective:      effective,
+		EffectiveDate:  dateOf(now),
+		Status:         entity.PlanChangeStatusScheduled,
+		Reason:         req.Reason,
+		CreatedBy:      req.CreatedBy,
+	}
+	if effective == entity.PlanChangeNextCycle {
+		change.EffectiveDate = serviceCycle(settings, customer, now).End.AddDate(0, 0, 1)
+	}
+
+	if _, err := s.planChangeRepo.CancelScheduled(ctx, tenantID, customer.ID, "", now); err != nil {
+		return nil, errors.NewDatabaseError("cancel scheduled plan changes", err)
+	}
+	if err := s.planChangeRepo.Create(ctx, change); err != nil {
+		return nil, errors.NewDatabaseError("create plan change", err)
+	}
+	change.FromPlan = customer.ServicePlan
+	change.ToPlan = plan
+
+	if effective == entity.PlanChangeNextCycle {
+		logger.Info("Plan change of customer %s to %s scheduled for %s", customer.ID, plan.Name, change.EffectiveDate.Format("02/01/2006"))
+		return change, nil
+	}
+	if _, err := s.apply(ctx, settings, change, customer, now); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// apply moves the customer to the change's plan, bills the proration and
+// pushes the new rate limit. It reports false when the change was no longer
+// scheduled.
+func (s *planChangeService) apply(ctx context.Context, settings *entity.TenantSettings, change *entity.CustomerPlanChange, customer *entity.Customer, now time.Time) (bool, error) {
+	applied, err := s.planChangeRepo.ApplyChange(ctx, change, now)
+	if err != nil {
+		return false, errors.NewDatabaseError("apply plan change", err)
+	}
+	if !applied {
+		return false, nil
+	}
+
+	s.pushRateLimit(ctx, change)
+
+	if err := s.settle(ctx, settings, change, customer, now); err != nil {
+		// The customer is on the new plan already; the proration is left to the operator
+		logger.Error("Plan change %s of customer %s: failed to bill proration: %v", change.ID, customer.ID, err)
+		return true, errors.NewInternalError("plan changed but the proration could not be billed")
+	}
+
+	logger.Info("Customer %s moved to plan %s (proration %.2f)", customer.ID, change.ToPlanID, change.ProrationAmount)
+	return true, nil
+}
+
+// settle bills the change against the cycle it took effect in. The lines go
+// on the cycle's invoice while it is unpaid; otherwise on an invoice of their
+// own, or to the customer's balance when they add up to a credit.
+func (s *planChangeService) settle(ctx context.Context, settings *entity.TenantSettings, change *entity.CustomerPlanChange, customer *entity.Customer, now time.Time) error {
+	cycle := serviceCycle(settings, customer, change.EffectiveDate)
+	invoice, err := s.invoiceRepo.FindPeriodInvoice(ctx, customer.ID, cycle.Start)
+	if err != nil {
+		return errors.NewDatabaseError("load cycle invoice", err)
+	}
+	// Prepaid cycles are invoiced before they start, so one without an
+	// invoice is not billed at all
+	if invoice == nil && settings.BillingType == entity.BillingTypePrepaid {
+		return nil
+	}
+
+	from := newPlanRate(change.FromPlan, change.FromMonthlyFee)
+	to := newPlanRate(change.ToPlan, change.ToMonthlyFee)
+	items := prorationItems(cycle, change.EffectiveDate, invoice != nil, from, to)
+	if len(items) == 0 {
+		return nil
+	}
+
+	if invoice != nil && len(invoice.Items) > 0 && invoice.Status != entity.PaymentStatusPaid {
+		added, err := s.addToInvoice(ctx, settings, change, invoice, items)
+		if err != nil || added {
+			return err
+		}
+	}
+
+	payment := &entity.Payment{
+		TenantID:   change.TenantID,
+		CustomerID: change.CustomerID,
+		DueDate:    dateOf(now).AddDate(0, 0, settings.InvoiceDueDays),
+		Status:     entity.PaymentStatusPending,
+		Notes:      fmt.Sprintf("Plan change from %s to %s", from.Name, to.Name),
+		// The cycle is covered by its own invoice, so paying this one does
+		// not extend prepaid service
+		ServiceExtendedAt: &now,
+		Items:             items,
+	}
+	applyInvoiceTotals(payment, settings)
+	change.ProrationAmount = payment.Subtotal
+
+	switch {
+	case payment.Subtotal <= -0.005:
+		// applyInvoiceTotals only handles a positive subtotal, so the tax on
+		// a net credit is worked out here
+		credit := -payment.Subtotal
+		if settings.TaxEnabled && settings.TaxPercentage > 0 {
+			credit += credit * settings.TaxPercentage / 100
+		}
+		if err := s.invoiceRepo.AddLedgerEntry(ctx, &entity.CustomerLedgerEntry{
+			TenantID:   change.TenantID,
+			CustomerID: change.CustomerID,
+			Type:       entity.LedgerEntryCredit,
+			Source:     entity.LedgerSourcePlanChange,
+			Amount:     entity.RoundMoney(credit),
+			Reason:     payment.Notes,
+			CreatedBy:  change.CreatedBy,
+		}); err != nil {
+			return errors.NewDatabaseError("credit customer balance", err)
+		}
+	case payment.Amount >= 0.005:
+		if err := s.invoiceRepo.CreateInvoice(ctx, payment, settings.InvoicePrefix); err != nil {
+			return errors.NewDatabaseError("create proration invoice", err)
+		}
+		change.ProrationPaymentID = &payment.ID
+	}
+	return s.saveProration(ctx, change)
+}
+
+// addToInvoice adds the proration lines to the cycle's unpaid invoice. It
+// reports false when the lines would leave nothing to pay or the invoice was
+// paid in the meantime.
+func (s *planChangeService) addToInvoice(ctx context.Context, settings *entity.TenantSettings, change *entity.CustomerPlanChange, invoice *entity.Payment, items []entity.InvoiceItem) (bool, error) {
+	net, ok, err := addInvoiceItems(ctx, s.invoiceRepo, settings, invoice, items)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	change.ProrationAmount = net
+	change.ProrationPaymentID = &invoice.ID
+	return true, s.saveProration(ctx, change)
+}
+
+func (s *planChangeService) saveProration(ctx context.Context, change *entity.CustomerPlanChange) error {
+	if err := s.planChangeRepo.SaveProration(ctx, change); err != nil {
+		return errors.NewDatabaseError("save plan change proration", err)
+	}
+	return nil
+}
+
+// pushRateLimit rewrites the customer's RADIUS users with the new plan's
+// rate limit and sends it to their open sessions
+func (s *planChangeService) pushRateLimit(ctx context.Context, change *entity.CustomerPlanChange) {
+	users, err := s.planChangeRepo.FindRadiusUsers(ctx, change.CustomerID)
+	if err != nil {
+		logger.Error("Plan change: failed to load RADIUS users of customer %s: %v", change.CustomerID, err)
+		return
+	}
+	resyncRateLimit(ctx, s.radiusSync, s.coaService, change.TenantID, users)
+}
+
+func (s *planChangeService) CancelPlanChange(ctx context.Context, tenantID, customerID, changeID string) error {
+	cancelled, err := s.planChangeRepo.CancelScheduled(ctx, tenantID, customerID, changeID, time.Now())
+	if err != nil {
+		return errors.NewDatabaseError("cancel plan change", err)
+	}
+	if cancelled == 0 {
+		return errors.NewNotFoundError("Scheduled plan change not found")
+	}
+	return nil
+}
+
+func (s *planChangeService) ListPlanHistory(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerPlanChange, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	changes, err := s.planChangeRepo.FindHistory(ctx, tenantID, customerID, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("load plan history", err)
+	}
+	return changes, nil
+}
+
+func (s *planChangeService) ApplyDueChanges(ctx context.Context, tenantID string, now time.Time) (int, error) {
+	changes, err := s.planChangeRepo.FindDueChanges(ctx, tenantID, dateOf(now))
+	if err != nil {
+		return 0, errors.NewDatabaseError("find scheduled plan changes", err)
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, change := range changes {
+		customer, err := s.planChangeRepo.FindCustomer(ctx, tenantID, change.CustomerID)
+		if err != nil {
+			logger.Error("Plan change %s: failed to load customer %s: %v", change.ID, change.CustomerID, err)
+			continue
+		}
+		ok, err := s.apply(ctx, settings, change, customer, now)
+		if ok {
+			applied++
+		}
+		if err != nil {
+			logger.Error("Plan change %s failed: %v", change.ID, err)
+		}
+	}
+	logger.Info("Scheduled plan changes for tenant %s: applied=%d of %d", tenantID, applied, len(changes))
+	return applied, nil
+}
+
+func (s *planChangeService) ApplyAllTenants(ctx context.Context, now time.Time) error {
+	tenants, err := s.tenantRepo.FindAll(ctx)
+	if err != nil {
+		return errors.NewDatabaseError("load tenants", err)
+	}
+
+	for _, tenant := range tenants {
+		if !tenant.IsActive {
+			continue
+		}
+		if _, err := s.ApplyDueChanges(ctx, tenant.ID, now); err != nil {
+			logger.Error("Scheduled plan changes for tenant %s failed: %v", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartPlanChangeJob runs ApplyAllTenants now and then on every interval
+func StartPlanChangeJob(service PlanChangeService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.ApplyAllTenants(context.Background(), time.Now()); err != nil {
+				logger.Error("Plan change job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Plan change job started (interval: %s)", interval)
+}
@@ -0,0 +1,321 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPlanChangeRepository struct {
+	mock.Mock
+}
+
+func (m *MockPlanChangeRepository) FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error) {
+	args := m.Called(ctx, tenantID, customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Customer), args.Error(1)
+}
+
+func (m *MockPlanChangeRepository) FindPlan(ctx context.Context, tenantID, planID string) (*entity.ServicePlan, error) {
+	args := m.Called(ctx, tenantID, planID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.ServicePlan), args.Error(1)
+}
+
+func (m *MockPlanChangeRepository) Create(ctx context.Context, change *entity.CustomerPlanChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+func (m *MockPlanChangeRepository) ApplyChange(ctx context.Context, change *entity.CustomerPlanChange, at time.Time) (bool, error) {
+	args := m.Called(ctx, change, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPlanChangeRepository) SaveProration(ctx context.Context, change *entity.CustomerPlanChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+func (m *MockPlanChangeRepository) CancelScheduled(ctx context.Context, tenantID, customerID, changeID string, at time.Time) (int64, error) {
+	args := m.Called(ctx, tenantID, customerID, changeID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPlanChangeRepository) FindHistory(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.CustomerPlanChange, error) {
+	args := m.Called(ctx, tenantID, customerID, limit)
+	return args.Get(0).([]*entity.CustomerPlanChange), args.Error(1)
+}
+
+func (m *MockPlanChangeRepository) FindDueChanges(ctx context.Context, tenantID string, day time.Time) ([]*entity.CustomerPlanChange, error) {
+	args := m.Called(ctx, tenantID, day)
+	return args.Get(0).([]*entity.CustomerPlanChange), args.Error(1)
+}
+
+func (m *MockPlanChangeRepository) FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error) {
+	args := m.Called(ctx, customerID)
+	return args.Get(0).([]*entity.RadiusUser), args.Error(1)
+}
+
+type MockRadiusCoAService struct {
+	mock.Mock
+}
+
+func (m *MockRadiusCoAService) DisconnectUser(ctx context.Context, tenantID, username string) error {
+	args := m.Called(ctx, tenantID, username)
+	return args.Error(0)
+}
+
+func (m *MockRadiusCoAService) DisconnectSession(ctx context.Context, tenantID, username, acctSessionID string) error {
+	args := m.Called(ctx, tenantID, username, acctSessionID)
+	return args.Error(0)
+}
+
+func (m *MockRadiusCoAService) UpdateRateLimit(ctx context.Context, tenantID, username, rateLimit string) error {
+	args := m.Called(ctx, tenantID, username, rateLimit)
+	return args.Error(0)
+}
+
+func (m *MockRadiusCoAService) RefreshRateLimit(ctx context.Context, tenantID, username string) error {
+	args := m.Called(ctx, tenantID, username)
+	return args.Error(0)
+}
+
+func TestProrationItems(t *testing.T) {
+	april := billingPeriod{Start: billingDate(2025, 4, 1), End: billingDate(2025, 4, 30)}
+	basic := planRate{Name: "Basic 10 Mbps", Fee: 150000}
+	pro := planRate{Name: "Pro 30 Mbps", Fee: 300000}
+
+	t.Run("Billed Cycle Credits The Rest At The Old Fee", func(t *testing.T) {
+		items := prorationItems(april, billingDate(2025, 4, 16), true, basic, pro)
+		require.Len(t, items, 2)
+		assert.Equal(t, "Unused Basic 10 Mbps, 16/04/2025 - 30/04/2025 (15 of 30 days)", items[0].Description)
+		assert.Equal(t, -75000.0, items[0].UnitPrice)
+		assert.Equal(t, "Pro 30 Mbps, 16/04/2025 - 30/04/2025 (15 of 30 days)", items[1].Description)
+		assert.Equal(t, 150000.0, items[1].UnitPrice)
+		assert.Equal(t, entity.InvoiceItemProration, items[1].Type)
+	})
+
+	t.Run("Unbilled Cycle Charges The Days Before At The Old Fee", func(t *testing.T) {
+		items := prorationItems(april, billingDate(2025, 4, 11), false, basic, pro)
+		require.Len(t, items, 2)
+		assert.Equal(t, "Unused Pro 30 Mbps, 01/04/2025 - 10/04/2025 (10 of 30 days)", items[0].Description)
+		assert.Equal(t, -100000.0, items[0].UnitPrice)
+		assert.Equal(t, 50000.0, items[1].UnitPrice)
+	})
+
+	t.Run("Nothing To Settle", func(t *testing.T) {
+		assert.Empty(t, prorationItems(april, billingDate(2025, 4, 1), false, basic, pro))
+		assert.Empty(t, prorationItems(april, billingDate(2025, 4, 16), true, basic, planRate{Name: "Basic Plus", Fee: 150000}))
+	})
+}
+
+func TestPlanChangeService_ChangePlan(t *testing.T) {
+	ctx := context.Background()
+	settings := &entity.TenantSettings{BillingType: entity.BillingTypePostpaid, BillingDateType: entity.BillingDateTypeFixed, BillingDay: 1, InvoiceDueDays: 14}
+	basic := &entity.ServicePlan{ID: "plan-basic", Name: "Basic 10 Mbps", Price: 150000, IsActive: true}
+	pro := &entity.ServicePlan{ID: "plan-pro", Name: "Pro 30 Mbps", Price: 300000, IsActive: true}
+	customer := &entity.Customer{ID: "c1", TenantID: "tenant-1", ServicePlanID: basic.ID, ServicePlan: basic, MonthlyFee: 150000}
+
+	t.Run("Rejects The Current Plan", func(t *testing.T) {
+		mockRepo := new(MockPlanChangeRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(settings, nil)
+		service := NewPlanChangeService(mockRepo, mockInvoiceRepo, mockSettingsRepo, nil, mockRadiusSync, mockCoA)
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", basic.ID).Return(basic, nil)
+
+		_, err := service.ChangePlan(ctx, "tenant-1", "c1", &PlanChangeRequest{ServicePlanID: basic.ID})
+		assert.ErrorContains(t, err, "already on this plan")
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown Plan", func(t *testing.T) {
+		mockRepo := new(MockPlanChangeRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(settings, nil)
+		service := NewPlanChangeService(mockRepo, mockInvoiceRepo, mockSettingsRepo, nil, mockRadiusSync, mockCoA)
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", "plan-x").Return(nil, errors.ErrNotFound)
+
+		_, err := service.ChangePlan(ctx, "tenant-1", "c1", &PlanChangeRequest{ServicePlanID: "plan-x"})
+		assert.Equal(t, "PLAN_7001", err.(*errors.AppError).Code)
+	})
+
+	t.Run("Next Cycle Is Scheduled", func(t *testing.T) {
+		mockRepo := new(MockPlanChangeRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(settings, nil)
+		service := NewPlanChangeService(mockRepo, mockInvoiceRepo, mockSettingsRepo, nil, mockRadiusSync, mockCoA)
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", pro.ID).Return(pro, nil)
+		mockRepo.On("CancelScheduled", ctx, "tenant-1", "c1", "", mock.Anything).Return(int64(1), nil)
+		mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+
+		change, err := service.ChangePlan(ctx, "tenant-1", "c1", &PlanChangeRequest{ServicePlanID: pro.ID, Effective: entity.PlanChangeNextCycle})
+		require.NoError(t, err)
+		assert.Equal(t, entity.PlanChangeStatusScheduled, change.Status)
+		assert.Equal(t, 1, change.EffectiveDate.Day())
+		assert.True(t, change.EffectiveDate.After(time.Now()))
+		assert.Equal(t, 300000.0, change.ToMonthlyFee)
+		mockRepo.AssertNotCalled(t, "ApplyChange", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Immediate Change Prorates The Open Invoice And Pushes The Rate Limit", func(t *testing.T) {
+		mockRepo := new(MockPlanChangeRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(settings, nil)
+		service := NewPlanChangeService(mockRepo, mockInvoiceRepo, mockSettingsRepo, nil, mockRadiusSync, mockCoA)
+
+		invoice := &entity.Payment{
+			ID: "inv-1", TenantID: "tenant-1", CustomerID: "c1", Status: entity.PaymentStatusPending,
+			Items: []entity.InvoiceItem{{Type: entity.InvoiceItemMonthlyFee, Quantity: 1, UnitPrice: 150000}},
+		}
+		user := &entity.RadiusUser{Username: "budi", IsActive: true}
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", pro.ID).Return(pro, nil)
+		mockRepo.On("CancelScheduled", ctx, "tenant-1", "c1", "", mock.Anything).Return(int64(0), nil)
+		mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockRepo.On("ApplyChange", ctx, mock.Anything, mock.Anything).Return(true, nil)
+		mockRepo.On("FindRadiusUsers", ctx, "c1").Return([]*entity.RadiusUser{user}, nil)
+		mockRadiusSync.On("SyncRadiusUser", user).Return(nil)
+		mockCoA.On("RefreshRateLimit", ctx, "tenant-1", "budi").Return(nil)
+		mockInvoiceRepo.On("FindPeriodInvoice", ctx, "c1", mock.Anything).Return(invoice, nil)
+		mockInvoiceRepo.On("AddInvoiceItems", ctx, mock.Anything, mock.Anything).Return(true, nil)
+		mockRepo.On("SaveProration", ctx, mock.Anything).Return(nil)
+
+		change, err := service.ChangePlan(ctx, "tenant-1", "c1", &PlanChangeRequest{ServicePlanID: pro.ID, Reason: "Upgrade"})
+		require.NoError(t, err)
+		assert.Equal(t, entity.PlanChangeImmediate, change.Effective)
+		assert.Equal(t, "inv-1", *change.ProrationPaymentID)
+		assert.Greater(t, change.ProrationAmount, 0.0)
+		mockCoA.AssertExpectations(t)
+
+		updated := mockInvoiceRepo.Calls[1].Arguments.Get(1).(*entity.Payment)
+		items := mockInvoiceRepo.Calls[1].Arguments.Get(2).([]entity.InvoiceItem)
+		require.Len(t, items, 2)
+		assert.Equal(t, "inv-1", items[0].PaymentID)
+		assert.Equal(t, 150000+change.ProrationAmount, updated.Amount)
+		mockInvoiceRepo.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPlanChangeService_ApplyDueChanges(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 4, 16, 1, 0, 0, 0, time.UTC)
+	basic := &entity.ServicePlan{ID: "plan-basic", Name: "Basic 10 Mbps"}
+	pro := &entity.ServicePlan{ID: "plan-pro", Name: "Pro 30 Mbps"}
+	customer := &entity.Customer{ID: "c1", TenantID: "tenant-1", ServicePlanID: pro.ID}
+	newChange := func() *entity.CustomerPlanChange {
+		return &entity.CustomerPlanChange{
+			ID: "pc-1", TenantID: "tenant-1", CustomerID: "c1",
+			FromPlanID: basic.ID, FromPlan: basic, FromMonthlyFee: 150000,
+			ToPlanID: pro.ID, ToPlan: pro, ToMonthlyFee: 300000,
+			Effective: entity.PlanChangeNextCycle, EffectiveDate: billingDate(2025, 4, 16), Status: entity.PlanChangeStatusScheduled,
+		}
+	}
+
+	// expectDueChange has the repositories return change as due and applied,
+	// with invoice billing its cycle
+	expectDueChange := func(mockRepo *MockPlanChangeRepository, mockInvoiceRepo *MockInvoiceRepository, change *entity.CustomerPlanChange, invoice *entity.Payment) {
+		mockRepo.On("FindDueChanges", ctx, "tenant-1", billingDate(2025, 4, 16)).Return([]*entity.CustomerPlanChange{change}, nil)
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("ApplyChange", ctx, change, now).Return(true, nil)
+		mockRepo.On("FindRadiusUsers", ctx, "c1").Return([]*entity.RadiusUser{}, nil)
+		mockInvoiceRepo.On("FindPeriodInvoice", ctx, "c1", billingDate(2025, 4, 1)).Return(invoice, nil)
+		mockRepo.On("SaveProration", ctx, change).Return(nil)
+	}
+
+	t.Run("Upgrade In An Unbilled Postpaid Cycle Credits The Balance", func(t *testing.T) {
+		change := newChange()
+		mockRepo := new(MockPlanChangeRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{BillingType: entity.BillingTypePostpaid, BillingDay: 1}, nil)
+		service := NewPlanChangeService(mockRepo, mockInvoiceRepo, mockSettingsRepo, nil, new(MockRadiusUserSyncer), new(MockRadiusCoAService))
+		expectDueChange(mockRepo, mockInvoiceRepo, change, nil)
+
+		mockInvoiceRepo.On("AddLedgerEntry", ctx, mock.Anything).Return(nil)
+
+		applied, err := service.ApplyDueChanges(ctx, "tenant-1", now)
+		require.NoError(t, err)
+		assert.Equal(t, 1, applied)
+
+		// 1-15 April was used on Basic but will be billed at the Pro fee
+		entry := mockInvoiceRepo.Calls[1].Arguments.Get(1).(*entity.CustomerLedgerEntry)
+		assert.Equal(t, entity.LedgerEntryCredit, entry.Type)
+		assert.Equal(t, entity.LedgerSourcePlanChange, entry.Source)
+		assert.Equal(t, 75000.0, entry.Amount)
+		assert.Equal(t, "Plan change from Basic 10 Mbps to Pro 30 Mbps", entry.Reason)
+		assert.Equal(t, -75000.0, change.ProrationAmount)
+	})
+
+	t.Run("Paid Prepaid Cycle Gets A Separate Invoice For An Upgrade", func(t *testing.T) {
+		change := newChange()
+		paid := &entity.Payment{ID: "inv-1", Status: entity.PaymentStatusPaid, Items: []entity.InvoiceItem{{UnitPrice: 150000}}}
+		mockRepo := new(MockPlanChangeRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{BillingType: entity.BillingTypePrepaid, BillingDay: 1, InvoiceDueDays: 7, InvoicePrefix: "INV"}, nil)
+		service := NewPlanChangeService(mockRepo, mockInvoiceRepo, mockSettingsRepo, nil, new(MockRadiusUserSyncer), new(MockRadiusCoAService))
+		expectDueChange(mockRepo, mockInvoiceRepo, change, paid)
+
+		mockInvoiceRepo.On("CreateInvoice", ctx, mock.Anything, "INV").Return(nil)
+
+		_, err := service.ApplyDueChanges(ctx, "tenant-1", now)
+		require.NoError(t, err)
+
+		invoice := mockInvoiceRepo.Calls[1].Arguments.Get(1).(*entity.Payment)
+		assert.Equal(t, 75000.0, invoice.Amount)
+		assert.Equal(t, billingDate(2025, 4, 23), invoice.DueDate)
+		assert.NotNil(t, invoice.ServiceExtendedAt)
+		assert.Nil(t, invoice.PeriodStart)
+		assert.Equal(t, 75000.0, change.ProrationAmount)
+		mockInvoiceRepo.AssertNotCalled(t, "AddInvoiceItems", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Change Applied Elsewhere Is Skipped", func(t *testing.T) {
+		change := newChange()
+		mockRepo := new(MockPlanChangeRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{BillingType: entity.BillingTypePostpaid, BillingDay: 1}, nil)
+		service := NewPlanChangeService(mockRepo, mockInvoiceRepo, mockSettingsRepo, nil, mockRadiusSync, mockCoA)
+
+		mockRepo.On("FindDueChanges", ctx, "tenant-1", billingDate(2025, 4, 16)).Return([]*entity.CustomerPlanChange{change}, nil)
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("ApplyChange", ctx, change, now).Return(false, nil)
+
+		applied, err := service.ApplyDueChanges(ctx, "tenant-1", now)
+		require.NoError(t, err)
+		assert.Equal(t, 0, applied)
+		mockInvoiceRepo.AssertNotCalled(t, "FindPeriodInvoice", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
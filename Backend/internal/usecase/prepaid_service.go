@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// PrepaidService ties the service of customers of prepaid tenants to their
+// payments: each paid invoice extends Customer.ServiceUntil and the ExpireDate
+// of the customer's RADIUS users, so the NAS rejects them once it lapses
+type PrepaidService interface {
+	// HandlePaymentPaid extends the customer's service by the period of a
+	// paid invoice when the tenant bills prepaid. An invoice extends once.
+	HandlePaymentPaid(ctx context.Context, payment *entity.Payment) error
+	// RunTenant extends the service for paid invoices no payment path handled
+	// and reminds customers whose service ends within ReminderDaysBefore days
+	RunTenant(ctx context.Context, tenantID string, now time.Time) (*PrepaidRunResult, error)
+	// RunAllTenants runs RunTenant for every active tenant
+	RunAllTenants(ctx context.Context, now time.Time) error
+}
+
+// RadiusUserSyncer writes a RADIUS user to the FreeRADIUS tables,
+// implemented by FreeRADIUSSyncService
+type RadiusUserSyncer interface {
+	SyncRadiusUser(user *entity.RadiusUser) error
+}
+
+// CustomerMailer sends email to customers, implemented by email.Service
+type CustomerMailer interface {
+	SendHTML(to, subject, htmlBody string) error
+}
+
+// PrepaidRunResult summarizes one RunTenant run
+type PrepaidRunResult struct {
+	TenantID string `json:"tenant_id"`
+	Extended int    `json:"extended"`
+	Reminded int    `json:"reminded"`
+	Failed   int    `json:"failed"`
+}
+
+// Paid invoices are caught up this many days back, so switching a tenant to
+// prepaid does not extend service for old invoices
+const prepaidCatchUpDays = 7
+
+type prepaidService struct {
+	prepaidRepo  repository.PrepaidRepository
+	settingsRepo repository.SettingsRepository
+	tenantRepo   repository.TenantRepository
+	radiusSync   RadiusUserSyncer
+	mailer       CustomerMailer
+	templates    NotificationTemplateService
+}
+
+// NewPrepaidService creates the prepaid billing service. Expiry reminders are
+// rendered from the tenant's service_expiring template and not sent when
+// mailer is nil.
+func NewPrepaidService(
+	prepaidRepo repository.PrepaidRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+	radiusSync RadiusUserSyncer,
+	mailer CustomerMailer,
+	templates NotificationTemplateService,
+) PrepaidService {
+	return &prepaidService{
+		prepaidRepo:  prepaidRepo,
+		settingsRepo: settingsRepo,
+		tenantRepo:   tenantRepo,
+		radiusSync:   radiusSync,
+		mailer:       mailer,
+		templates:    templates,
+	}
+}
+
+// nextServiceUntil returns the service end after paying an invoice. An invoice
+// for a billing period runs the service to the end of that period, or adds the
+// period's months when the service already reaches into it; other invoices add
+// one month from the current end or from now when the service has lapsed.
+func nextServiceUntil(payment *entity.Payment, current *time.Time, now time.Time) time.Time {
+	if payment.PeriodStart != nil && payment.PeriodEnd != nil {
+		start := dateOf(*payment.PeriodStart)
+		end := dateOf(*payment.PeriodEnd).AddDate(0, 0, 1)
+		if current == nil || !current.After(start) {
+			return end
+		}
+		months := (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month())
+		if months < 1 {
+			months = 1
+		}
+		return current.AddDate(0, months, 0)
+	}
+
+	base := now
+	if current != nil && current.After(now) {
+		base = *current
+	}
+	return base.AddDate(0, 1, 0)
+}
+
+func (s *prepaidService) loadSettings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		return defaultTenantSettings(tenantID), nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	return settings, nil
+}
+
+func (s *prepaidService) HandlePaymentPaid(ctx context.Context, payment *entity.Payment) error {
+	if payment.Status != entity.PaymentStatusPaid {
+		return nil
+	}
+	settings, err := s.loadSettings(ctx, payment.TenantID)
+	if err != nil {
+		return err
+	}
+	if settings.BillingType != entity.BillingTypePrepaid {
+		return nil
+	}
+	_, err = s.extend(ctx, payment, time.Now())
+	return err
+}
+
+// extend runs the customer's service through the paid invoice's period and
+// pushes the new ExpireDate to FreeRADIUS. It reports whether the invoice
+// had not extended the service before.
+func (s *prepaidService) extend(ctx context.Context, payment *entity.Payment, now time.Time) (bool, error) {
+	until, err := s.prepaidRepo.ExtendService(ctx, payment, now, func(current *time.Time) time.Time {
+		return nextServiceUntil(payment, current, now)
+	})
+	if err != nil {
+		return false, errors.NewDatabaseError("extend service", err)
+	}
+	if until == nil {
+		return false, nil
+	}
+
+	users, err := s.prepaidRepo.FindRadiusUsers(ctx, payment.CustomerID)
+	if err != nil {
+		logger.Error("Prepaid: failed to load RADIUS users of customer %s: %v", payment.CustomerID, err)
+	}
+	for _, user := range users {
+		if err := s.radiusSync.SyncRadiusUser(user); err != nil {
+			logger.Error("Prepaid: failed to sync RADIUS user %s: %v", user.Username, err)
+		}
+	}
+
+	paymentID := payment.ID
+	s.logAction(ctx, &entity.BillingActionLog{
+		TenantID:   payment.TenantID,
+		CustomerID: payment.CustomerID,
+		PaymentID:  &paymentID,
+		Action:     entity.BillingActionServiceExtend,
+		Reason:     fmt.Sprintf("Service extended until %s", until.Format("02/01/2006")),
+	})
+	return true, nil
+}
+
+func (s *prepaidService) logAction(ctx context.Context, action *entity.BillingActionLog) {
+	if err := s.prepaidRepo.CreateAction(ctx, action); err != nil {
+		logger.Error("Failed to log billing action %s for customer %s: %v", action.Action, action.CustomerID, err)
+	}
+	logger.Info("Billing action %s for customer %s: %s", action.Action, action.CustomerID, action.Reason)
+}
+
+func (s *prepaidService) RunTenant(ctx context.Context, tenantID string, now time.Time) (*PrepaidRunResult, error) {
+	settings, err := s.loadSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	result := &PrepaidRunResult{TenantID: tenantID}
+	if settings.BillingType != entity.BillingTypePrepaid {
+		return result, nil
+	}
+
+	payments, err := s.prepaidRepo.FindUnextendedPayments(ctx, tenantID, now.AddDate(0, 0, -prepaidCatchUpDays))
+	if err != nil {
+		return nil, errors.NewDatabaseError("find paid invoices", err)
+	}
+	for _, payment := range payments {
+		extended, err := s.extend(ctx, payment, now)
+		if err != nil {
+			logger.Error("Prepaid: failed to extend service for invoice %s: %v", payment.ID, err)
+			result.Failed++
+			continue
+		}
+		if extended {
+			result.Extended++
+		}
+	}
+
+	if settings.SendPaymentReminder && s.mailer != nil {
+		customers, err := s.prepaidRepo.FindExpiringCustomers(ctx, tenantID, now, now.AddDate(0, 0, settings.ReminderDaysBefore))
+		if err != nil {
+			return nil, errors.NewDatabaseError("find expiring customers", err)
+		}
+		for _, customer := range customers {
+			if customer.Email == "" {
+				continue
+			}
+			if err := s.remind(ctx, settings, customer, now); err != nil {
+				logger.Error("Prepaid: failed to remind customer %s: %v", customer.ID, err)
+				result.Failed++
+				continue
+			}
+			result.Reminded++
+		}
+	}
+
+	logger.Info("Prepaid billing for tenant %s: extended=%d reminded=%d failed=%d",
+		tenantID, result.Extended, result.Reminded, result.Failed)
+	return result, nil
+}
+
+// remind emails the customer that their service is about to end
+func (s *prepaidService) remind(ctx context.Context, settings *entity.TenantSettings, customer *entity.Customer, now time.Time) error {
+	vars := customerTemplateVars(customer)
+	vars["expiry_date"] = *customer.ServiceUntil
+	if customer.ServicePlan != nil {
+		vars["plan_name"] = customer.ServicePlan.Name
+	}
+	msg, err := s.templates.Render(ctx, settings, entity.NotificationEventServiceExpiring, entity.NotificationChannelEmail, vars)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.SendHTML(customer.Email, msg.Subject, msg.Body); err != nil {
+		return err
+	}
+	if err := s.prepaidRepo.MarkExpiryReminded(ctx, customer.ID, now); err != nil {
+		return err
+	}
+
+	s.logAction(ctx, &entity.BillingActionLog{
+		TenantID:   customer.TenantID,
+		CustomerID: customer.ID,
+		Action:     entity.BillingActionExpiryReminder,
+		Reason:     fmt.Sprintf("Service ends %s", customer.ServiceUntil.Format("02/01/2006 15:04")),
+	})
+	return nil
+}
+
+func (s *prepaidService) RunAllTenants(ctx context.Context, now time.Time) error {
+	tenants, err := s.tenantRepo.FindAll(ctx)
+	if err != nil {
+		return errors.NewDatabaseError("load tenants", err)
+	}
+
+	for _, tenant := range tenants {
+		if !tenant.IsActive {
+			continue
+		}
+		if _, err := s.RunTenant(ctx, tenant.ID, now); err != nil {
+			logger.Error("Prepaid billing for tenant %s failed: %v", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartPrepaidJob runs RunAllTenants now and then on every interval
+func StartPrepaidJob(service PrepaidService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.RunAllTenants(context.Background(), time.Now()); err != nil {
+				logger.Error("Prepaid billing job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Prepaid billing job started (interval: %s)", interval)
+}
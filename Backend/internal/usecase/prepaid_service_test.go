@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPrepaidRepository struct {
+	mock.Mock
+}
+
+// ExtendService returns the ServiceUntil computed from the current one given
+// to Return, or nil when Return reports the invoice as already extended
+func (m *MockPrepaidRepository) ExtendService(ctx context.Context, payment *entity.Payment, at time.Time, until func(current *time.Time) time.Time) (*time.Time, error) {
+	args := m.Called(ctx, payment, at)
+	if !args.Bool(1) {
+		return nil, args.Error(2)
+	}
+	current, _ := args.Get(0).(*time.Time)
+	next := until(current)
+	return &next, args.Error(2)
+}
+
+func (m *MockPrepaidRepository) FindUnextendedPayments(ctx context.Context, tenantID string, paidSince time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, paidSince)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockPrepaidRepository) FindExpiringCustomers(ctx context.Context, tenantID string, from, to time.Time) ([]*entity.Customer, error) {
+	args := m.Called(ctx, tenantID, from, to)
+	return args.Get(0).([]*entity.Customer), args.Error(1)
+}
+
+func (m *MockPrepaidRepository) MarkExpiryReminded(ctx context.Context, customerID string, at time.Time) error {
+	args := m.Called(ctx, customerID, at)
+	return args.Error(0)
+}
+
+func (m *MockPrepaidRepository) FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error) {
+	args := m.Called(ctx, customerID)
+	return args.Get(0).([]*entity.RadiusUser), args.Error(1)
+}
+
+func (m *MockPrepaidRepository) CreateAction(ctx context.Context, action *entity.BillingActionLog) error {
+	args := m.Called(ctx, action)
+	return args.Error(0)
+}
+
+type MockRadiusUserSyncer struct {
+	mock.Mock
+}
+
+func (m *MockRadiusUserSyncer) SyncRadiusUser(user *entity.RadiusUser) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+type MockCustomerMailer struct {
+	mock.Mock
+}
+
+func (m *MockCustomerMailer) SendHTML(to, subject, htmlBody string) error {
+	args := m.Called(to, subject, htmlBody)
+	return args.Error(0)
+}
+
+func TestNextServiceUntil(t *testing.T) {
+	now := time.Date(2025, 3, 28, 10, 0, 0, 0, time.UTC)
+	start, end := billingDate(2025, 4, 1), billingDate(2025, 4, 30)
+	april := &entity.Payment{PeriodStart: &start, PeriodEnd: &end}
+	manual := &entity.Payment{}
+	at := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name    string
+		payment *entity.Payment
+		current *time.Time
+		want    time.Time
+	}{
+		{"First Period", april, nil, billingDate(2025, 5, 1)},
+		{"Paid On Time", april, at(billingDate(2025, 4, 1)), billingDate(2025, 5, 1)},
+		{"Lapsed Customer Keeps The Cycle", april, at(billingDate(2025, 2, 1)), billingDate(2025, 5, 1)},
+		{"Service Already Covers The Period", april, at(billingDate(2025, 5, 1)), billingDate(2025, 6, 1)},
+		{"Invoice Without Period From Now", manual, at(billingDate(2025, 3, 1)), now.AddDate(0, 1, 0)},
+		{"Invoice Without Period From Current End", manual, at(billingDate(2025, 4, 10)), billingDate(2025, 5, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextServiceUntil(tt.payment, tt.current, now))
+		})
+	}
+}
+
+func TestPrepaidService_HandlePaymentPaid(t *testing.T) {
+	ctx := context.Background()
+	start, end := billingDate(2025, 4, 1), billingDate(2025, 4, 30)
+	payment := &entity.Payment{
+		ID: "p1", TenantID: "tenant-1", CustomerID: "c1", Status: entity.PaymentStatusPaid,
+		PeriodStart: &start, PeriodEnd: &end,
+	}
+	user := &entity.RadiusUser{Username: "budi"}
+
+	t.Run("Extends Service And Syncs RADIUS", func(t *testing.T) {
+		mockRepo := new(MockPrepaidRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockMailer := new(MockCustomerMailer)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{
+			BillingType: entity.BillingTypePrepaid, SendPaymentReminder: true, ReminderDaysBefore: 3, CompanyName: "Net Warga",
+		}, nil)
+		mockRepo.On("CreateAction", mock.Anything, mock.Anything).Return(nil)
+		service := NewPrepaidService(mockRepo, mockSettingsRepo, nil, mockRadiusSync, mockMailer, builtinTemplateService())
+
+		mockRepo.On("ExtendService", ctx, payment, mock.Anything).Return(nil, true, nil)
+		mockRepo.On("FindRadiusUsers", ctx, "c1").Return([]*entity.RadiusUser{user}, nil)
+		mockRadiusSync.On("SyncRadiusUser", user).Return(nil)
+
+		require.NoError(t, service.HandlePaymentPaid(ctx, payment))
+		mockRadiusSync.AssertExpectations(t)
+
+		action := mockRepo.Calls[2].Arguments.Get(1).(*entity.BillingActionLog)
+		assert.Equal(t, entity.BillingActionServiceExtend, action.Action)
+		assert.Equal(t, "Service extended until 01/05/2025", action.Reason)
+	})
+
+	t.Run("Invoice Extends Once", func(t *testing.T) {
+		mockRepo := new(MockPrepaidRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockMailer := new(MockCustomerMailer)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{
+			BillingType: entity.BillingTypePrepaid, SendPaymentReminder: true, ReminderDaysBefore: 3, CompanyName: "Net Warga",
+		}, nil)
+		mockRepo.On("CreateAction", mock.Anything, mock.Anything).Return(nil)
+		service := NewPrepaidService(mockRepo, mockSettingsRepo, nil, mockRadiusSync, mockMailer, builtinTemplateService())
+
+		mockRepo.On("ExtendService", ctx, payment, mock.Anything).Return(nil, false, nil)
+
+		require.NoError(t, service.HandlePaymentPaid(ctx, payment))
+		mockRadiusSync.AssertNotCalled(t, "SyncRadiusUser", mock.Anything)
+		mockRepo.AssertNotCalled(t, "CreateAction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Postpaid Is Not Extended", func(t *testing.T) {
+		mockRepo := new(MockPrepaidRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockMailer := new(MockCustomerMailer)
+		mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{
+			BillingType: entity.BillingTypePostpaid, SendPaymentReminder: true, ReminderDaysBefore: 3, CompanyName: "Net Warga",
+		}, nil)
+		mockRepo.On("CreateAction", mock.Anything, mock.Anything).Return(nil)
+		service := NewPrepaidService(mockRepo, mockSettingsRepo, nil, mockRadiusSync, mockMailer, builtinTemplateService())
+
+		require.NoError(t, service.HandlePaymentPaid(ctx, payment))
+		mockRepo.AssertNotCalled(t, "ExtendService", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPrepaidService_RunTenant(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 28, 10, 0, 0, 0, time.UTC)
+	paid := &entity.Payment{ID: "p1", TenantID: "tenant-1", CustomerID: "c1", Status: entity.PaymentStatusPaid}
+	until := billingDate(2025, 3, 30)
+	budi := &entity.Customer{ID: "c2", TenantID: "tenant-1", Name: "Budi", CustomerCode: "CUST-002", Email: "budi@example.com", ServiceUntil: &until}
+	noEmail := &entity.Customer{ID: "c3", TenantID: "tenant-1", ServiceUntil: &until}
+
+	mockRepo := new(MockPrepaidRepository)
+	mockSettingsRepo := new(MockSettingsRepository)
+	mockRadiusSync := new(MockRadiusUserSyncer)
+	mockMailer := new(MockCustomerMailer)
+	mockSettingsRepo.On("GetTenantSettings", mock.Anything, "tenant-1").Return(&entity.TenantSettings{
+		BillingType: entity.BillingTypePrepaid, SendPaymentReminder: true, ReminderDaysBefore: 3, CompanyName: "Net Warga",
+	}, nil)
+	mockRepo.On("CreateAction", mock.Anything, mock.Anything).Return(nil)
+	service := NewPrepaidService(mockRepo, mockSettingsRepo, nil, mockRadiusSync, mockMailer, builtinTemplateService())
+
+	mockRepo.On("FindUnextendedPayments", ctx, "tenant-1", now.AddDate(0, 0, -prepaidCatchUpDays)).Return([]*entity.Payment{paid}, nil)
+	mockRepo.On("ExtendService", ctx, paid, now).Return(nil, true, nil)
+	mockRepo.On("FindRadiusUsers", ctx, "c1").Return([]*entity.RadiusUser{}, nil)
+	mockRepo.On("FindExpiringCustomers", ctx, "tenant-1", now, now.AddDate(0, 0, 3)).Return([]*entity.Customer{budi, noEmail}, nil)
+	mockMailer.On("SendHTML", "budi@example.com", "Layanan internet Anda berakhir pada 30/03/2025 00:00", mock.Anything).Return(nil)
+	mockRepo.On("MarkExpiryReminded", ctx, "c2", now).Return(nil)
+
+	result, err := service.RunTenant(ctx, "tenant-1", now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Extended)
+	assert.Equal(t, 1, result.Reminded)
+	assert.Equal(t, 0, result.Failed)
+	mockMailer.AssertNumberOfCalls(t, "SendHTML", 1)
+	assert.Contains(t, mockMailer.Calls[0].Arguments.String(2), "Net Warga")
+}
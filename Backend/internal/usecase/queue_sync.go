@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+)
+
+// Queues created by the reconciler carry this comment prefix followed by the
+// customer ID. Queues without it belong to the operator and are never touched.
+const managedQueueCommentPrefix = "rtrwnet:customer:"
+
+// Queue sync action types
+const (
+	QueueActionCreate = "create"
+	QueueActionUpdate = "update"
+	QueueActionRemove = "remove"
+	QueueActionSkip   = "skip"
+)
+
+// QueueSyncAction is one planned change to a router's simple queues
+type QueueSyncAction struct {
+	Action     string            `json:"action"`
+	QueueName  string            `json:"queue_name"`
+	CustomerID string            `json:"customer_id,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	Changes    map[string]string `json:"changes,omitempty"` // attribute -> "old -> new"
+	Reason     string            `json:"reason,omitempty"`
+	Error      string            `json:"error,omitempty"`
+
+	queue MikrotikQueue
+}
+
+// QueueSyncResult is returned by SyncMikrotikQueues
+type QueueSyncResult struct {
+	Report  *entity.QueueSyncReport `json:"report"`
+	Actions []QueueSyncAction       `json:"actions"`
+}
+
+// desiredQueues builds the simple queue set that should exist for the given
+// customers. DHCP and static customers get one queue per IP; PPPoE customers
+// are shaped through RADIUS (Mikrotik-Rate-Limit) and are left out. Customers
+// without an IP or plan are returned as skip actions.
+func desiredQueues(customers []*entity.Customer, settings map[string]*entity.ServicePlanAdvancedSettings) ([]MikrotikQueue, []QueueSyncAction) {
+	var queues []MikrotikQueue
+	var skipped []QueueSyncAction
+
+	for _, c := range customers {
+		if c.ServiceType == entity.ServiceTypePPPoE {
+			continue
+		}
+
+		ip := c.IPAddress
+		if c.ServiceType == entity.ServiceTypeStatic || ip == "" {
+			if c.StaticIP != "" {
+				ip = c.StaticIP
+			}
+		}
+		if ip == "" {
+			skipped = append(skipped, QueueSyncAction{Action: QueueActionSkip, QueueName: c.CustomerCode, CustomerID: c.ID, Reason: "customer has no IP address"})
+			continue
+		}
+		if c.ServicePlan == nil {
+			skipped = append(skipped, QueueSyncAction{Action: QueueActionSkip, QueueName: c.CustomerCode, CustomerID: c.ID, Reason: "customer has no service plan"})
+			continue
+		}
+
+		q := MikrotikQueue{
+			Name:        c.CustomerCode,
+			Target:      queueTarget(ip),
+			MaxUpload:   c.ServicePlan.SpeedUpload * 1000000,
+			MaxDownload: c.ServicePlan.SpeedDownload * 1000000,
+			Priority:    8,
+			Comment:     managedQueueCommentPrefix + c.ID,
+		}
+
+		if adv, ok := settings[c.ServicePlanID]; ok {
+			if adv.BurstEnabled && adv.BurstLimit > 0 {
+				q.BurstLimitUpload = adv.BurstLimit * 1000000
+				q.BurstLimitDownload = adv.BurstLimit * 1000000
+				q.BurstThresholdUpload = adv.BurstThreshold * 1000000
+				q.BurstThresholdDownload = adv.BurstThreshold * 1000000
+				q.BurstTime = adv.BurstTime
+			}
+			if adv.Priority >= 1 && adv.Priority <= 8 {
+				q.Priority = adv.Priority
+			}
+			q.Parent = adv.ParentQueue
+		}
+
+		queues = append(queues, q)
+	}
+
+	return queues, skipped
+}
+
+// planQueueSync diffs desired queues against what is on the router. Managed
+// queues are matched by customer ID in the comment so renames become updates;
+// managed queues without a matching customer are removed.
+func planQueueSync(desired, actual []MikrotikQueue) ([]QueueSyncAction, int) {
+	existing := make(map[string]MikrotikQueue)
+	for _, q := range actual {
+		if strings.HasPrefix(q.Comment, managedQueueCommentPrefix) {
+			existing[q.Comment] = q
+		}
+	}
+
+	var actions []QueueSyncAction
+	unchanged := 0
+	for _, want := range desired {
+		customerID := strings.TrimPrefix(want.Comment, managedQueueCommentPrefix)
+		have, ok := existing[want.Comment]
+		if !ok {
+			actions = append(actions, QueueSyncAction{
+				Action:     QueueActionCreate,
+				QueueName:  want.Name,
+				CustomerID: customerID,
+				Target:     want.Target,
+				queue:      want,
+			})
+			continue
+		}
+		delete(existing, want.Comment)
+
+		changes := diffQueue(have, want)
+		if len(changes) == 0 {
+			unchanged++
+			continue
+		}
+		want.ID = have.ID
+		actions = append(actions, QueueSyncAction{
+			Action:     QueueActionUpdate,
+			QueueName:  want.Name,
+			CustomerID: customerID,
+			Target:     want.Target,
+			Changes:    changes,
+			queue:      want,
+		})
+	}
+
+	// Remaining managed queues have no active customer any more
+	stale := make([]MikrotikQueue, 0, len(existing))
+	for _, q := range existing {
+		stale = append(stale, q)
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Name < stale[j].Name })
+	for _, q := range stale {
+		actions = append(actions, QueueSyncAction{
+			Action:     QueueActionRemove,
+			QueueName:  q.Name,
+			CustomerID: strings.TrimPrefix(q.Comment, managedQueueCommentPrefix),
+			Target:     q.Target,
+			Reason:     "customer is no longer active",
+			queue:      q,
+		})
+	}
+
+	return actions, unchanged
+}
+
+// diffQueue lists attributes that differ between the router and the desired queue
+func diffQueue(have, want MikrotikQueue) map[string]string {
+	changes := make(map[string]string)
+	compare := func(attr, from, to string) {
+		if from != to {
+			changes[attr] = from + " -> " + to
+		}
+	}
+
+	compare("name", have.Name, want.Name)
+	compare("target", have.Target, want.Target)
+	compare("max-limit", formatMaxLimit(have.MaxUpload, have.MaxDownload), formatMaxLimit(want.MaxUpload, want.MaxDownload))
+	compare("burst-limit", formatMaxLimit(have.BurstLimitUpload, have.BurstLimitDownload), formatMaxLimit(want.BurstLimitUpload, want.BurstLimitDownload))
+	compare("burst-threshold", formatMaxLimit(have.BurstThresholdUpload, have.BurstThresholdDownload), formatMaxLimit(want.BurstThresholdUpload, want.BurstThresholdDownload))
+	compare("burst-time", fmt.Sprintf("%ds", have.BurstTime), fmt.Sprintf("%ds", want.BurstTime))
+	compare("priority", fmt.Sprint(have.Priority), fmt.Sprint(want.Priority))
+	compare("parent", queueParent(have.Parent), queueParent(want.Parent))
+	compare("disabled", fmt.Sprint(have.Disabled), fmt.Sprint(want.Disabled))
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// queueTarget normalizes an IP to the form RouterOS prints ("10.0.0.2/32")
+func queueTarget(ip string) string {
+	ip = strings.TrimSpace(ip)
+	if strings.Contains(ip, "/") {
+		return ip
+	}
+	if strings.Contains(ip, ":") {
+		return ip + "/128"
+	}
+	return ip + "/32"
+}
+
+func queueParent(parent string) string {
+	if parent == "" {
+		return "none"
+	}
+	return parent
+}
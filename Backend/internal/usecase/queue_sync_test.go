@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDesiredQueues(t *testing.T) {
+	plan := &entity.ServicePlan{ID: "plan-1", SpeedUpload: 2, SpeedDownload: 10}
+	customers := []*entity.Customer{
+		{ID: "c1", CustomerCode: "CUST001", ServiceType: entity.ServiceTypeDHCP, IPAddress: "10.0.0.2", ServicePlanID: plan.ID, ServicePlan: plan},
+		{ID: "c2", CustomerCode: "CUST002", ServiceType: entity.ServiceTypeStatic, IPAddress: "10.0.0.99", StaticIP: "10.0.0.3", ServicePlanID: plan.ID, ServicePlan: plan},
+		{ID: "c3", CustomerCode: "CUST003", ServiceType: entity.ServiceTypePPPoE, IPAddress: "10.0.0.4", ServicePlanID: plan.ID, ServicePlan: plan},
+		{ID: "c4", CustomerCode: "CUST004", ServiceType: entity.ServiceTypeDHCP, ServicePlanID: plan.ID, ServicePlan: plan},
+	}
+	settings := map[string]*entity.ServicePlanAdvancedSettings{
+		"plan-1": {ServicePlanID: "plan-1", BurstEnabled: true, BurstLimit: 20, BurstThreshold: 8, BurstTime: 16, Priority: 3, ParentQueue: "total"},
+	}
+
+	queues, skipped := desiredQueues(customers, settings)
+
+	require.Len(t, queues, 2)
+	assert.Equal(t, MikrotikQueue{
+		Name:                   "CUST001",
+		Target:                 "10.0.0.2/32",
+		MaxUpload:              2000000,
+		MaxDownload:            10000000,
+		BurstLimitUpload:       20000000,
+		BurstLimitDownload:     20000000,
+		BurstThresholdUpload:   8000000,
+		BurstThresholdDownload: 8000000,
+		BurstTime:              16,
+		Priority:               3,
+		Parent:                 "total",
+		Comment:                "rtrwnet:customer:c1",
+	}, queues[0])
+	assert.Equal(t, "10.0.0.3/32", queues[1].Target, "static customers use StaticIP")
+
+	require.Len(t, skipped, 1)
+	assert.Equal(t, "c4", skipped[0].CustomerID)
+}
+
+func TestPlanQueueSync(t *testing.T) {
+	desired := []MikrotikQueue{
+		{Name: "CUST001", Target: "10.0.0.2/32", MaxUpload: 2000000, MaxDownload: 10000000, Priority: 8, Comment: "rtrwnet:customer:c1"},
+		{Name: "CUST002", Target: "10.0.0.3/32", MaxUpload: 2000000, MaxDownload: 10000000, Priority: 8, Comment: "rtrwnet:customer:c2"},
+	}
+	actual := []MikrotikQueue{
+		{ID: "*1", Name: "CUST001", Target: "10.0.0.2/32", MaxUpload: 1000000, MaxDownload: 5000000, Priority: 8, Comment: "rtrwnet:customer:c1"},
+		{ID: "*2", Name: "old-name", Target: "10.0.0.3/32", MaxUpload: 2000000, MaxDownload: 10000000, Priority: 8, Comment: "rtrwnet:customer:c2"},
+		{ID: "*3", Name: "manual", Target: "10.0.0.50/32", MaxUpload: 1000000, MaxDownload: 1000000},
+	}
+
+	t.Run("Updates Only Differences", func(t *testing.T) {
+		actions, unchanged := planQueueSync(desired, actual)
+
+		assert.Equal(t, 0, unchanged)
+		require.Len(t, actions, 2)
+		assert.Equal(t, QueueActionUpdate, actions[0].Action)
+		assert.Equal(t, map[string]string{"max-limit": "1000000/5000000 -> 2000000/10000000"}, actions[0].Changes)
+		assert.Equal(t, "*1", actions[0].queue.ID)
+		assert.Equal(t, map[string]string{"name": "old-name -> CUST002"}, actions[1].Changes)
+	})
+
+	t.Run("Converged", func(t *testing.T) {
+		converged := []MikrotikQueue{actual[2]}
+		for i, q := range desired {
+			q.ID = actual[i].ID
+			converged = append(converged, q)
+		}
+
+		actions, unchanged := planQueueSync(desired, converged)
+
+		assert.Empty(t, actions)
+		assert.Equal(t, 2, unchanged)
+	})
+
+	t.Run("Removes Stale Managed Queues Only", func(t *testing.T) {
+		actions, _ := planQueueSync(nil, actual)
+
+		require.Len(t, actions, 2)
+		for _, a := range actions {
+			assert.Equal(t, QueueActionRemove, a.Action)
+			assert.NotEqual(t, "manual", a.QueueName)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/radius"
+	"gorm.io/gorm"
+)
+
+// RadiusCoAService pushes changes to sessions that are already online using
+// RFC 5176 Disconnect-Request and CoA-Request. Updating radcheck/radreply only
+// affects the next login; these calls make suspends and speed changes take
+// effect immediately.
+type RadiusCoAService interface {
+	// DisconnectUser kicks every open session of the user off its NAS
+	DisconnectUser(ctx context.Context, tenantID, username string) error
+	// DisconnectSession kicks a single accounting session off its NAS
+	DisconnectSession(ctx context.Context, tenantID, username, acctSessionID string) error
+	// UpdateRateLimit changes Mikrotik-Rate-Limit on every open session
+	UpdateRateLimit(ctx context.Context, tenantID, username, rateLimit string) error
+	// RefreshRateLimit re-sends the Mikrotik-Rate-Limit currently in radreply
+	RefreshRateLimit(ctx context.Context, tenantID, username string) error
+}
+
+// resyncRateLimit rewrites the users' radcheck/radreply rows and sends the
+// Mikrotik-Rate-Limit now in radreply to their open sessions. Failures are
+// logged and do not stop the other users.
+func resyncRateLimit(ctx context.Context, syncer RadiusUserSyncer, coaService RadiusCoAService, tenantID string, users []*entity.RadiusUser) {
+	for _, user := range users {
+		if err := syncer.SyncRadiusUser(user); err != nil {
+			logger.Error("Failed to sync RADIUS user %s: %v", user.Username, err)
+			continue
+		}
+		if !user.IsActive {
+			continue
+		}
+		if err := coaService.RefreshRateLimit(ctx, tenantID, user.Username); err != nil {
+			logger.Warn("Failed to send CoA rate limit for %s: %v", user.Username, err)
+		}
+	}
+}
+
+type radiusCoAService struct {
+	db      *gorm.DB
+	client  *radius.Client
+	coaPort int
+}
+
+// radacctSession is an open accounting session with the tenant's NAS that
+// reported it
+type radacctSession struct {
+	AcctSessionID   string `gorm:"column:acctsessionid"`
+	Username        string `gorm:"column:username"`
+	FramedIPAddress string `gorm:"column:framedipaddress"`
+	NASName         string `gorm:"column:nasname"`
+	NASSecret       string `gorm:"column:secret"`
+}
+
+func NewRadiusCoAService(db *gorm.DB, client *radius.Client, coaPort int) RadiusCoAService {
+	if coaPort <= 0 {
+		coaPort = radius.DefaultCoAPort
+	}
+	return &radiusCoAService{
+		db:      db,
+		client:  client,
+		coaPort: coaPort,
+	}
+}
+
+func (s *radiusCoAService) DisconnectUser(ctx context.Context, tenantID, username string) error {
+	if username == "" {
+		return nil
+	}
+	sessions, err := s.openSessions(ctx, tenantID, "a.username = ?", username)
+	if err != nil {
+		return err
+	}
+	return s.forEachSession(sessions, func(addr, secret string, session radius.Session) error {
+		return s.client.Disconnect(ctx, addr, secret, session)
+	})
+}
+
+func (s *radiusCoAService) DisconnectSession(ctx context.Context, tenantID, username, acctSessionID string) error {
+	sessions, err := s.openSessions(ctx, tenantID, "a.username = ? AND a.acctsessionid = ?", username, acctSessionID)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		return errors.NewNotFoundError("session not found")
+	}
+	return s.forEachSession(sessions, func(addr, secret string, session radius.Session) error {
+		return s.client.Disconnect(ctx, addr, secret, session)
+	})
+}
+
+func (s *radiusCoAService) UpdateRateLimit(ctx context.Context, tenantID, username, rateLimit string) error {
+	if username == "" {
+		return nil
+	}
+	sessions, err := s.openSessions(ctx, tenantID, "a.username = ?", username)
+	if err != nil {
+		return err
+	}
+	return s.forEachSession(sessions, func(addr, secret string, session radius.Session) error {
+		return s.client.UpdateRateLimit(ctx, addr, secret, session, rateLimit)
+	})
+}
+
+func (s *radiusCoAService) RefreshRateLimit(ctx context.Context, tenantID, username string) error {
+	var rateLimit string
+	err := s.db.WithContext(ctx).
+		Table("radreply").
+		Select("value").
+		Where("tenant_id = ? AND username = ? AND attribute = ?", tenantID, username, "Mikrotik-Rate-Limit").
+		Limit(1).
+		Scan(&rateLimit).Error
+	if err != nil {
+		return fmt.Errorf("failed to read rate limit for %s: %w", username, err)
+	}
+	if rateLimit == "" {
+		return nil
+	}
+
+	return s.UpdateRateLimit(ctx, tenantID, username, rateLimit)
+}
+
+// openSessions loads the radacct rows matching the condition that have not
+// been stopped yet. FreeRADIUS does not fill radacct.tenant_id, so sessions
+// belong to the tenant through the active NAS that reported them; sessions
+// of other NASes are never returned.
+func (s *radiusCoAService) openSessions(ctx context.Context, tenantID, query string, args ...interface{}) ([]radacctSession, error) {
+	var sessions []radacctSession
+	err := s.db.WithContext(ctx).
+		Table("radacct AS a").
+		Select("a.acctsessionid, a.username, host(a.framedipaddress) AS framedipaddress, n.nasname, n.secret").
+		Joins("JOIN radius_nas n ON n.nasname = host(a.nasipaddress) AND n.tenant_id = ? AND n.is_active = ?", tenantID, true).
+		Where(query, args...).
+		Where("a.acctstoptime IS NULL").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// forEachSession calls fn for every session on the NAS that reported it.
+// Errors are collected so one unreachable NAS does not stop the others; the
+// first error is returned.
+func (s *radiusCoAService) forEachSession(sessions []radacctSession, fn func(addr, secret string, session radius.Session) error) error {
+	var firstErr error
+	for _, sess := range sessions {
+		addr := net.JoinHostPort(sess.NASName, strconv.Itoa(s.coaPort))
+		session := radius.Session{
+			Username:        sess.Username,
+			AcctSessionID:   sess.AcctSessionID,
+			FramedIPAddress: sess.FramedIPAddress,
+		}
+		if err := fn(addr, sess.NASSecret, session); err != nil {
+			logger.Error("CoA: request for %s on %s failed: %v", sess.Username, addr, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		logger.Info("CoA: updated session %s of %s on %s", sess.AcctSessionID, sess.Username, addr)
+	}
+
+	return firstErr
+}
@@ -257,6 +257,15 @@ func (s *settingsService) UpdateTenantSettings(ctx context.Context, tenantID str
 	if req.WarningDaysBeforeSuspension != nil {
 		settings.WarningDaysBeforeSuspension = *req.WarningDaysBeforeSuspension
 	}
+	if req.SendInvoiceNotification != nil {
+		settings.SendInvoiceNotification = *req.SendInvoiceNotification
+	}
+	if req.SendTicketNotification != nil {
+		settings.SendTicketNotification = *req.SendTicketNotification
+	}
+	if req.NotificationLanguage != "" {
+		settings.NotificationLanguage = req.NotificationLanguage
+	}
 
 	if settings.ID == uuid.Nil {
 		if err := s.settingsRepo.CreateTenantSettings(ctx, settings); err != nil {
@@ -296,6 +305,21 @@ func (s *settingsService) UpdateIntegrationSettings(ctx context.Context, tenantI
 	if req.TelegramBotToken != "" {
 		settings.TelegramBotToken = req.TelegramBotToken
 	}
+	if req.MidtransServerKey != "" {
+		settings.MidtransServerKey = req.MidtransServerKey
+	}
+	if req.MidtransClientKey != "" {
+		settings.MidtransClientKey = req.MidtransClientKey
+	}
+	if req.MidtransIsProduction != nil {
+		settings.MidtransIsProduction = *req.MidtransIsProduction
+	}
+	if req.MidtransEnabled != nil {
+		if *req.MidtransEnabled && settings.MidtransServerKey == "" {
+			return errors.NewValidationError("midtrans_server_key is required to enable online payment")
+		}
+		settings.MidtransEnabled = *req.MidtransEnabled
+	}
 
 	if settings.ID == uuid.Nil {
 		if err := s.settingsRepo.CreateTenantSettings(ctx, settings); err != nil {
@@ -385,6 +409,12 @@ func (s *settingsService) createDefaultUserSettings(tenantID, userID string) *en
 }
 
 func (s *settingsService) createDefaultTenantSettings(tenantID string) *entity.TenantSettings {
+	return defaultTenantSettings(tenantID)
+}
+
+// defaultTenantSettings returns the settings a tenant gets until they save
+// their own
+func defaultTenantSettings(tenantID string) *entity.TenantSettings {
 	tenantUUID, _ := uuid.Parse(tenantID)
 
 	return &entity.TenantSettings{
@@ -410,6 +440,9 @@ func (s *settingsService) createDefaultTenantSettings(tenantID string) *entity.T
 		SendPaymentConfirmation:     true,
 		SendSuspensionWarning:       true,
 		WarningDaysBeforeSuspension: 3,
+		SendInvoiceNotification:     false,
+		SendTicketNotification:      false,
+		NotificationLanguage:        entity.NotificationLanguageIndonesian,
 		WhatsappEnabled:             false,
 		TelegramEnabled:             false,
 	}
@@ -460,7 +493,14 @@ func (s *settingsService) toTenantSettingsResponse(settings *entity.TenantSettin
 		SendPaymentConfirmation:     settings.SendPaymentConfirmation,
 		SendSuspensionWarning:       settings.SendSuspensionWarning,
 		WarningDaysBeforeSuspension: settings.WarningDaysBeforeSuspension,
+		SendInvoiceNotification:     settings.SendInvoiceNotification,
+		SendTicketNotification:      settings.SendTicketNotification,
+		NotificationLanguage:        settings.NotificationLanguage,
 		WhatsappEnabled:             settings.WhatsappEnabled,
 		TelegramEnabled:             settings.TelegramEnabled,
+		MidtransEnabled:             settings.MidtransEnabled,
+		MidtransConfigured:          settings.MidtransServerKey != "",
+		MidtransClientKey:           settings.MidtransClientKey,
+		MidtransIsProduction:        settings.MidtransIsProduction,
 	}
 }
@@ -0,0 +1,516 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+const (
+	// maxSpeedBoostDays is the longest a boost may run; longer upgrades are plan changes
+	maxSpeedBoostDays = 30
+	// speedBoostLinkTTL is how long a customer's speed boost link can be used
+	speedBoostLinkTTL = 7 * 24 * time.Hour
+)
+
+// SpeedBoostService runs temporary plan upgrades. A request is approved or
+// rejected by an operator; an approved boost is charged to the customer and,
+// while active, FreeRADIUS shapes the customer at the boost plan's speed.
+// Boosts revert on their own once EndDate passes.
+type SpeedBoostService interface {
+	// RequestBoost files a boost for a customer on their behalf
+	RequestBoost(ctx context.Context, tenantID, customerID string, req *SpeedBoostRequest) (*entity.SpeedBoost, error)
+	// CreateCustomerLink signs a link an operator sends to a customer so they
+	// can request a boost themselves
+	CreateCustomerLink(ctx context.Context, tenantID, customerID string) (*SpeedBoostLink, error)
+	// RequestCustomerBoost files a boost requested by the customer, who is
+	// identified by the token of their link. The price is always worked out
+	// from the plans.
+	RequestCustomerBoost(ctx context.Context, tenantID, token string, req *SpeedBoostRequest) (*entity.SpeedBoost, error)
+	// ApproveBoost charges a pending boost and starts it, or schedules it
+	// when a later start date is given
+	ApproveBoost(ctx context.Context, tenantID, id string, req *SpeedBoostApproval) (*entity.SpeedBoost, error)
+	// RejectBoost rejects a pending boost
+	RejectBoost(ctx context.Context, tenantID, id, reason string, reviewedBy *string) (*entity.SpeedBoost, error)
+	GetBoost(ctx context.Context, tenantID, id string) (*entity.SpeedBoost, error)
+	ListBoosts(ctx context.Context, tenantID string, filter repository.SpeedBoostFilter) ([]*entity.SpeedBoost, error)
+	// ProcessDueBoosts starts approved boosts whose start date came and
+	// reverts active boosts that ended, for every tenant. It returns how many
+	// boosts changed.
+	ProcessDueBoosts(ctx context.Context, now time.Time) (int, error)
+}
+
+// SpeedBoostRequest describes a requested boost
+type SpeedBoostRequest struct {
+	BoostPlanID  string
+	DurationDays int
+	Price        *float64 // operator override, nil for the prorated plan difference
+	Notes        string
+	CreatedBy    *string
+}
+
+// SpeedBoostApproval describes how a boost is approved
+type SpeedBoostApproval struct {
+	StartDate  *time.Time // nil or past starts the boost now
+	Price      *float64   // overrides the requested price
+	ReviewedBy *string
+}
+
+// SpeedBoostLink is a signed link a customer requests boosts with
+type SpeedBoostLink struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type speedBoostService struct {
+	boostRepo    repository.SpeedBoostRepository
+	invoiceRepo  repository.InvoiceRepository
+	settingsRepo repository.SettingsRepository
+	radiusSync   RadiusUserSyncer
+	coaService   RadiusCoAService
+	linkSecret   string
+	linkPageURL  string
+}
+
+// NewSpeedBoostService creates the speed boost service. Customer links are
+// signed with linkSecret, and customers cannot request boosts without it;
+// linkPageURL is the customer's request page, the token is appended.
+func NewSpeedBoostService(
+	boostRepo repository.SpeedBoostRepository,
+	invoiceRepo repository.InvoiceRepository,
+	settingsRepo repository.SettingsRepository,
+	radiusSync RadiusUserSyncer,
+	coaService RadiusCoAService,
+	linkSecret string,
+	linkPageURL string,
+) SpeedBoostService {
+	return &speedBoostService{
+		boostRepo:    boostRepo,
+		invoiceRepo:  invoiceRepo,
+		settingsRepo: settingsRepo,
+		radiusSync:   radiusSync,
+		coaService:   coaService,
+		linkSecret:   linkSecret,
+		linkPageURL:  linkPageURL,
+	}
+}
+
+// speedBoostPrice charges the difference between the boost plan's price and
+// the customer's monthly fee for the days of the boost
+func speedBoostPrice(monthlyFee float64, boostPlan *entity.ServicePlan, days int) float64 {
+	diff := boostPlan.Price - monthlyFee
+	if diff <= 0 {
+		return 0
+	}
+	return entity.RoundMoney(diff * float64(days) / 30)
+}
+
+// signLink signs a link token for the tenant's customer, valid until expires
+func (s *speedBoostService) signLink(tenantID, customerID string, expires int64) string {
+	payload := customerID + "." + strconv.FormatInt(expires, 10)
+	mac := hmac.New(sha256.New, []byte(s.linkSecret))
+	mac.Write([]byte(tenantID + "." + payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyLink returns the customer of a link token signed for the tenant
+// that has not expired
+func (s *speedBoostService) verifyLink(tenantID, token string, now time.Time) (string, bool) {
+	parts := strings.Split(token, ".")
+	if s.linkSecret == "" || len(parts) != 3 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return "", false
+	}
+	if !hmac.Equal([]byte(token), []byte(s.signLink(tenantID, parts[0], expires))) {
+		return "", false
+	}
+	return parts[0], true
+}
+
+func (s *speedBoostService) RequestBoost(ctx context.Context, tenantID, customerID string, req *SpeedBoostRequest) (*entity.SpeedBoost, error) {
+	customer, err := s.boostRepo.FindCustomer(ctx, tenantID, customerID)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewCustomerNotFoundError(customerID)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load customer", err)
+	}
+	return s.request(ctx, customer, req, entity.SpeedBoostRequestedByOperator)
+}
+
+func (s *speedBoostService) CreateCustomerLink(ctx context.Context, tenantID, customerID string) (*SpeedBoostLink, error) {
+	if s.linkSecret == "" {
+		return nil, errors.NewInternalError("speed boost links are not configured")
+	}
+	customer, err := s.boostRepo.FindCustomer(ctx, tenantID, customerID)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewCustomerNotFoundError(customerID)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load customer", err)
+	}
+
+	expiresAt := time.Now().Add(speedBoostLinkTTL).Truncate(time.Second)
+	link := &SpeedBoostLink{
+		Token:     s.signLink(tenantID, customer.ID, expiresAt.Unix()),
+		ExpiresAt: expiresAt,
+	}
+	if s.linkPageURL != "" {
+		link.URL = s.linkPageURL + link.Token
+	}
+	return link, nil
+}
+
+func (s *speedBoostService) RequestCustomerBoost(ctx context.Context, tenantID, token string, req *SpeedBoostRequest) (*entity.SpeedBoost, error) {
+	customerID, ok := s.verifyLink(tenantID, token, time.Now())
+	if !ok {
+		return nil, errors.NewUnauthorizedError("invalid or expired speed boost link")
+	}
+	customer, err := s.boostRepo.FindCustomer(ctx, tenantID, customerID)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewNotFoundError("Customer not found")
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load customer", err)
+	}
+
+	customerReq := *req
+	customerReq.Price = nil
+	customerReq.CreatedBy = nil
+	return s.request(ctx, customer, &customerReq, entity.SpeedBoostRequestedByCustomer)
+}
+
+func (s *speedBoostService) request(ctx context.Context, customer *entity.Customer, req *SpeedBoostRequest, requestedBy string) (*entity.SpeedBoost, error) {
+	if req.DurationDays < 1 || req.DurationDays > maxSpeedBoostDays {
+		return nil, errors.NewValidationError(fmt.Sprintf("duration must be between 1 and %d days", maxSpeedBoostDays))
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return nil, errors.NewValidationError("price must not be negative")
+	}
+	if customer.Status != entity.CustomerStatusActive || customer.ServicePlan == nil {
+		return nil, errors.NewValidationError("speed boost is only available to active customers")
+	}
+
+	plan, err := s.boostRepo.FindPlan(ctx, customer.TenantID, req.BoostPlanID)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewServicePlanNotFoundError(req.BoostPlanID)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load service plan", err)
+	}
+	if !plan.IsActive {
+		return nil, errors.NewValidationError("service plan is not active")
+	}
+	if plan.SpeedDownload <= customer.ServicePlan.SpeedDownload {
+		return nil, errors.NewValidationError("boost plan must be faster than the customer's plan")
+	}
+
+	open, err := s.boostRepo.HasOpenBoost(ctx, customer.ID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("check open speed boosts", err)
+	}
+	if open {
+		return nil, errors.NewValidationError("customer already has a pending or running speed boost")
+	}
+
+	price := speedBoostPrice(customer.MonthlyFee, plan, req.DurationDays)
+	if req.Price != nil {
+		price = entity.RoundMoney(*req.Price)
+	}
+	boost := &entity.SpeedBoost{
+		TenantID:      customer.TenantID,
+		CustomerID:    customer.ID,
+		CurrentPlanID: customer.ServicePlanID,
+		BoostPlanID:   plan.ID,
+		DurationDays:  req.DurationDays,
+		Price:         price,
+		Status:        entity.SpeedBoostStatusPending,
+		RequestDate:   time.Now(),
+		Notes:         req.Notes,
+		RequestedBy:   requestedBy,
+		CreatedBy:     req.CreatedBy,
+	}
+	if err := s.boostRepo.Create(ctx, boost); err != nil {
+		return nil, errors.NewDatabaseError("create speed boost", err)
+	}
+	boost.CurrentPlan = customer.ServicePlan
+	boost.BoostPlan = plan
+
+	logger.Info("Speed boost %s requested by %s for customer %s: %s for %d days", boost.ID, requestedBy, customer.ID, plan.Name, boost.DurationDays)
+	return boost, nil
+}
+
+func (s *speedBoostService) findBoost(ctx context.Context, tenantID, id string) (*entity.SpeedBoost, error) {
+	boost, err := s.boostRepo.FindByID(ctx, tenantID, id)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewNotFoundError("Speed boost not found")
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("load speed boost", err)
+	}
+	return boost, nil
+}
+
+func (s *speedBoostService) ApproveBoost(ctx context.Context, tenantID, id string, req *SpeedBoostApproval) (*entity.SpeedBoost, error) {
+	if req.Price != nil && *req.Price < 0 {
+		return nil, errors.NewValidationError("price must not be negative")
+	}
+	boost, err := s.findBoost(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if boost.Status != entity.SpeedBoostStatusPending {
+		return nil, errors.NewValidationError("only pending speed boosts can be approved")
+	}
+
+	now := time.Now()
+	if req.Price != nil {
+		boost.Price = entity.RoundMoney(*req.Price)
+	}
+	if req.StartDate != nil && req.StartDate.After(now) {
+		boost.StartDate = req.StartDate
+	}
+	boost.Status = entity.SpeedBoostStatusApproved
+	boost.ReviewedBy = req.ReviewedBy
+	boost.ReviewedAt = &now
+	ok, err := s.boostRepo.Transition(ctx, boost, entity.SpeedBoostStatusPending)
+	if err != nil {
+		return nil, errors.NewDatabaseError("approve speed boost", err)
+	}
+	if !ok {
+		return nil, errors.NewValidationError("speed boost is no longer pending")
+	}
+
+	if err := s.charge(ctx, boost, now); err != nil {
+		// The boost stays approved; the charge is left to the operator
+		logger.Error("Speed boost %s: failed to bill %.2f: %v", boost.ID, boost.Price, err)
+		return nil, errors.NewInternalError("speed boost approved but the price could not be billed")
+	}
+
+	if boost.StartDate == nil {
+		if _, err := s.activate(ctx, boost, now); err != nil {
+			return nil, err
+		}
+	}
+	return boost, nil
+}
+
+// charge bills the boost's price. It goes on the customer's unpaid invoice
+// for the current cycle when there is one, otherwise on an invoice of its
+// own.
+func (s *speedBoostService) charge(ctx context.Context, boost *entity.SpeedBoost, now time.Time) error {
+	if boost.Price < 0.005 {
+		return nil
+	}
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, boost.TenantID)
+	if err == errors.ErrNotFound {
+		settings = defaultTenantSettings(boost.TenantID)
+	} else if err != nil {
+		return errors.NewDatabaseError("load tenant settings", err)
+	}
+
+	planName := boost.BoostPlanID
+	if boost.BoostPlan != nil {
+		planName = boost.BoostPlan.Name
+	}
+	items := []entity.InvoiceItem{{
+		Type:        entity.InvoiceItemAddon,
+		Description: fmt.Sprintf("Speed boost to %s, %d days", planName, boost.DurationDays),
+		Quantity:    1,
+		UnitPrice:   boost.Price,
+	}}
+
+	if boost.Customer != nil {
+		cycle := serviceCycle(settings, boost.Customer, now)
+		invoice, err := s.invoiceRepo.FindPeriodInvoice(ctx, boost.CustomerID, cycle.Start)
+		if err != nil {
+			return errors.NewDatabaseError("load cycle invoice", err)
+		}
+		if invoice != nil && len(invoice.Items) > 0 && invoice.Status != entity.PaymentStatusPaid {
+			_, added, err := addInvoiceItems(ctx, s.invoiceRepo, settings, invoice, items)
+			if err != nil {
+				return err
+			}
+			if added {
+				boost.PaymentID = &invoice.ID
+				return s.save(ctx, boost)
+			}
+		}
+	}
+
+	payment := &entity.Payment{
+		TenantID:   boost.TenantID,
+		CustomerID: boost.CustomerID,
+		DueDate:    dateOf(now).AddDate(0, 0, settings.InvoiceDueDays),
+		Status:     entity.PaymentStatusPending,
+		Notes:      items[0].Description,
+		// Paying for a boost does not extend prepaid service
+		ServiceExtendedAt: &now,
+		Items:             items,
+	}
+	applyInvoiceTotals(payment, settings)
+	if err := s.invoiceRepo.CreateInvoice(ctx, payment, settings.InvoicePrefix); err != nil {
+		return errors.NewDatabaseError("create speed boost invoice", err)
+	}
+	boost.PaymentID = &payment.ID
+	return s.save(ctx, boost)
+}
+
+// save stores the boost's fields without changing its status
+func (s *speedBoostService) save(ctx context.Context, boost *entity.SpeedBoost) error {
+	if _, err := s.boostRepo.Transition(ctx, boost, boost.Status); err != nil {
+		return errors.NewDatabaseError("save speed boost", err)
+	}
+	return nil
+}
+
+// activate starts an approved boost and pushes the boost plan's rate limit.
+// It reports false when the boost was no longer approved.
+func (s *speedBoostService) activate(ctx context.Context, boost *entity.SpeedBoost, now time.Time) (bool, error) {
+	start := now
+	if boost.StartDate != nil {
+		start = *boost.StartDate
+	}
+	end := start.AddDate(0, 0, boost.DurationDays)
+	boost.Status = entity.SpeedBoostStatusActive
+	boost.StartDate = &start
+	boost.EndDate = &end
+	ok, err := s.boostRepo.Transition(ctx, boost, entity.SpeedBoostStatusApproved)
+	if err != nil {
+		return false, errors.NewDatabaseError("activate speed boost", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	s.pushRateLimit(ctx, boost)
+	logger.Info("Speed boost %s of customer %s active until %s", boost.ID, boost.CustomerID, end.Format("02/01/2006 15:04"))
+	return true, nil
+}
+
+// expire ends an active boost and puts the customer back on their plan's
+// rate limit. It reports false when the boost was no longer active.
+func (s *speedBoostService) expire(ctx context.Context, boost *entity.SpeedBoost, now time.Time) (bool, error) {
+	boost.Status = entity.SpeedBoostStatusExpired
+	boost.ExpiredAt = &now
+	ok, err := s.boostRepo.Transition(ctx, boost, entity.SpeedBoostStatusActive)
+	if err != nil {
+		return false, errors.NewDatabaseError("expire speed boost", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	s.pushRateLimit(ctx, boost)
+	logger.Info("Speed boost %s of customer %s expired", boost.ID, boost.CustomerID)
+	return true, nil
+}
+
+// pushRateLimit re-syncs the customer's RADIUS users, which picks the boost
+// plan's rate limit while the boost is active, and sends it to open sessions
+func (s *speedBoostService) pushRateLimit(ctx context.Context, boost *entity.SpeedBoost) {
+	users, err := s.boostRepo.FindRadiusUsers(ctx, boost.CustomerID)
+	if err != nil {
+		logger.Error("Speed boost: failed to load RADIUS users of customer %s: %v", boost.CustomerID, err)
+		return
+	}
+	resyncRateLimit(ctx, s.radiusSync, s.coaService, boost.TenantID, users)
+}
+
+func (s *speedBoostService) RejectBoost(ctx context.Context, tenantID, id, reason string, reviewedBy *string) (*entity.SpeedBoost, error) {
+	boost, err := s.findBoost(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if boost.Status != entity.SpeedBoostStatusPending {
+		return nil, errors.NewValidationError("only pending speed boosts can be rejected")
+	}
+
+	now := time.Now()
+	boost.Status = entity.SpeedBoostStatusRejected
+	boost.RejectionReason = reason
+	boost.ReviewedBy = reviewedBy
+	boost.ReviewedAt = &now
+	ok, err := s.boostRepo.Transition(ctx, boost, entity.SpeedBoostStatusPending)
+	if err != nil {
+		return nil, errors.NewDatabaseError("reject speed boost", err)
+	}
+	if !ok {
+		return nil, errors.NewValidationError("speed boost is no longer pending")
+	}
+	return boost, nil
+}
+
+func (s *speedBoostService) GetBoost(ctx context.Context, tenantID, id string) (*entity.SpeedBoost, error) {
+	return s.findBoost(ctx, tenantID, id)
+}
+
+func (s *speedBoostService) ListBoosts(ctx context.Context, tenantID string, filter repository.SpeedBoostFilter) ([]*entity.SpeedBoost, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	boosts, err := s.boostRepo.ListBoosts(ctx, tenantID, filter)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list speed boosts", err)
+	}
+	return boosts, nil
+}
+
+func (s *speedBoostService) ProcessDueBoosts(ctx context.Context, now time.Time) (int, error) {
+	boosts, err := s.boostRepo.FindDueBoosts(ctx, now)
+	if err != nil {
+		return 0, errors.NewDatabaseError("find due speed boosts", err)
+	}
+
+	changed := 0
+	for _, boost := range boosts {
+		var ok bool
+		var err error
+		if boost.Status == entity.SpeedBoostStatusApproved {
+			ok, err = s.activate(ctx, boost, now)
+		} else {
+			ok, err = s.expire(ctx, boost, now)
+		}
+		if err != nil {
+			logger.Error("Speed boost %s failed: %v", boost.ID, err)
+			continue
+		}
+		if ok {
+			changed++
+		}
+	}
+	if len(boosts) > 0 {
+		logger.Info("Speed boosts: %d of %d started or reverted", changed, len(boosts))
+	}
+	return changed, nil
+}
+
+// StartSpeedBoostJob runs ProcessDueBoosts now and then on every interval
+func StartSpeedBoostJob(service SpeedBoostService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := service.ProcessDueBoosts(context.Background(), time.Now()); err != nil {
+				logger.Error("Speed boost job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Speed boost job started (interval: %s)", interval)
+}
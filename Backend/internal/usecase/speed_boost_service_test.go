@@ -0,0 +1,338 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSpeedBoostRepository struct {
+	mock.Mock
+}
+
+func (m *MockSpeedBoostRepository) FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error) {
+	args := m.Called(ctx, tenantID, customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Customer), args.Error(1)
+}
+
+func (m *MockSpeedBoostRepository) FindPlan(ctx context.Context, tenantID, planID string) (*entity.ServicePlan, error) {
+	args := m.Called(ctx, tenantID, planID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.ServicePlan), args.Error(1)
+}
+
+func (m *MockSpeedBoostRepository) Create(ctx context.Context, boost *entity.SpeedBoost) error {
+	args := m.Called(ctx, boost)
+	return args.Error(0)
+}
+
+func (m *MockSpeedBoostRepository) FindByID(ctx context.Context, tenantID, id string) (*entity.SpeedBoost, error) {
+	args := m.Called(ctx, tenantID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.SpeedBoost), args.Error(1)
+}
+
+func (m *MockSpeedBoostRepository) ListBoosts(ctx context.Context, tenantID string, filter repository.SpeedBoostFilter) ([]*entity.SpeedBoost, error) {
+	args := m.Called(ctx, tenantID, filter)
+	return args.Get(0).([]*entity.SpeedBoost), args.Error(1)
+}
+
+func (m *MockSpeedBoostRepository) HasOpenBoost(ctx context.Context, customerID string) (bool, error) {
+	args := m.Called(ctx, customerID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSpeedBoostRepository) Transition(ctx context.Context, boost *entity.SpeedBoost, from string) (bool, error) {
+	args := m.Called(ctx, boost, from)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSpeedBoostRepository) FindDueBoosts(ctx context.Context, now time.Time) ([]*entity.SpeedBoost, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]*entity.SpeedBoost), args.Error(1)
+}
+
+func (m *MockSpeedBoostRepository) FindRadiusUsers(ctx context.Context, customerID string) ([]*entity.RadiusUser, error) {
+	args := m.Called(ctx, customerID)
+	return args.Get(0).([]*entity.RadiusUser), args.Error(1)
+}
+
+func TestSpeedBoostPrice(t *testing.T) {
+	pro := &entity.ServicePlan{Price: 300000}
+	assert.Equal(t, 50000.0, speedBoostPrice(150000, pro, 10))
+	assert.Equal(t, 0.0, speedBoostPrice(350000, pro, 10))
+}
+
+func TestSpeedBoostService_RequestBoost(t *testing.T) {
+	ctx := context.Background()
+	basic := &entity.ServicePlan{ID: "plan-basic", Name: "Basic 10 Mbps", SpeedDownload: 10, Price: 150000, IsActive: true}
+	pro := &entity.ServicePlan{ID: "plan-pro", Name: "Pro 30 Mbps", SpeedDownload: 30, Price: 300000, IsActive: true}
+	customer := &entity.Customer{
+		ID: "c1", TenantID: "tenant-1", CustomerCode: "CUST-001", Phone: "0812-3456-7890",
+		Status: entity.CustomerStatusActive, ServicePlanID: basic.ID, ServicePlan: basic, MonthlyFee: 150000,
+	}
+
+	t.Run("Prices The Plan Difference For The Days", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", pro.ID).Return(pro, nil)
+		mockRepo.On("HasOpenBoost", ctx, "c1").Return(false, nil)
+		mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+
+		boost, err := service.RequestBoost(ctx, "tenant-1", "c1", &SpeedBoostRequest{BoostPlanID: pro.ID, DurationDays: 3})
+		require.NoError(t, err)
+		assert.Equal(t, entity.SpeedBoostStatusPending, boost.Status)
+		assert.Equal(t, entity.SpeedBoostRequestedByOperator, boost.RequestedBy)
+		assert.Equal(t, basic.ID, boost.CurrentPlanID)
+		assert.Equal(t, 15000.0, boost.Price)
+	})
+
+	t.Run("Boost Plan Must Be Faster", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", basic.ID).Return(basic, nil)
+
+		_, err := service.RequestBoost(ctx, "tenant-1", "c1", &SpeedBoostRequest{BoostPlanID: basic.ID, DurationDays: 3})
+		assert.ErrorContains(t, err, "faster")
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("One Open Boost Per Customer", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", pro.ID).Return(pro, nil)
+		mockRepo.On("HasOpenBoost", ctx, "c1").Return(true, nil)
+
+		_, err := service.RequestBoost(ctx, "tenant-1", "c1", &SpeedBoostRequest{BoostPlanID: pro.ID, DurationDays: 3})
+		assert.ErrorContains(t, err, "already has")
+	})
+
+	t.Run("Customer Request Needs A Valid Link", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/").(*speedBoostService)
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		link, err := service.CreateCustomerLink(ctx, "tenant-1", "c1")
+		require.NoError(t, err)
+		assert.Equal(t, "https://boost.example.com/"+link.Token, link.URL)
+
+		expired := service.signLink("tenant-1", "c1", time.Now().Add(-time.Minute).Unix())
+		for name, token := range map[string]string{
+			"Missing":        "",
+			"Tampered":       strings.Replace(link.Token, "c1.", "c2.", 1),
+			"Expired":        expired,
+			"Another Tenant": service.signLink("tenant-2", "c1", link.ExpiresAt.Unix()),
+		} {
+			_, err := service.RequestCustomerBoost(ctx, "tenant-1", token, &SpeedBoostRequest{BoostPlanID: pro.ID, DurationDays: 3})
+			assert.Equal(t, http.StatusUnauthorized, err.(*errors.AppError).Status, name)
+		}
+		mockRepo.AssertNotCalled(t, "FindPlan", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("No Links Without A Secret", func(t *testing.T) {
+		service := NewSpeedBoostService(new(MockSpeedBoostRepository), new(MockInvoiceRepository), new(MockSettingsRepository), new(MockRadiusUserSyncer), new(MockRadiusCoAService), "", "")
+
+		_, err := service.CreateCustomerLink(ctx, "tenant-1", "c1")
+		assert.Error(t, err)
+		_, err = service.RequestCustomerBoost(ctx, "tenant-1", "c1.9999999999.", &SpeedBoostRequest{BoostPlanID: pro.ID, DurationDays: 3})
+		assert.Equal(t, http.StatusUnauthorized, err.(*errors.AppError).Status)
+	})
+
+	t.Run("Customer Cannot Set The Price", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+		mockRepo.On("FindCustomer", ctx, "tenant-1", "c1").Return(customer, nil)
+		mockRepo.On("FindPlan", ctx, "tenant-1", pro.ID).Return(pro, nil)
+		mockRepo.On("HasOpenBoost", ctx, "c1").Return(false, nil)
+		mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+		link, err := service.CreateCustomerLink(ctx, "tenant-1", "c1")
+		require.NoError(t, err)
+
+		free := 0.0
+		boost, err := service.RequestCustomerBoost(ctx, "tenant-1", link.Token, &SpeedBoostRequest{BoostPlanID: pro.ID, DurationDays: 6, Price: &free})
+		require.NoError(t, err)
+		assert.Equal(t, entity.SpeedBoostRequestedByCustomer, boost.RequestedBy)
+		assert.Equal(t, 30000.0, boost.Price)
+	})
+}
+
+func TestSpeedBoostService_ApproveBoost(t *testing.T) {
+	ctx := context.Background()
+	settings := &entity.TenantSettings{BillingType: entity.BillingTypePostpaid, BillingDay: 1, InvoiceDueDays: 7, InvoicePrefix: "INV"}
+	pro := &entity.ServicePlan{ID: "plan-pro", Name: "Pro 30 Mbps"}
+	newBoost := func() *entity.SpeedBoost {
+		return &entity.SpeedBoost{
+			ID: "sb-1", TenantID: "tenant-1", CustomerID: "c1", BoostPlanID: pro.ID, BoostPlan: pro,
+			Customer: &entity.Customer{ID: "c1"}, DurationDays: 7, Price: 35000, Status: entity.SpeedBoostStatusPending,
+		}
+	}
+
+	t.Run("Charges A Separate Invoice And Starts Now", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+		boost := newBoost()
+		user := &entity.RadiusUser{Username: "budi", IsActive: true}
+		mockRepo.On("FindByID", ctx, "tenant-1", "sb-1").Return(boost, nil)
+		mockRepo.On("Transition", ctx, boost, mock.Anything).Return(true, nil)
+		mockSettingsRepo.On("GetTenantSettings", ctx, "tenant-1").Return(settings, nil)
+		mockInvoiceRepo.On("FindPeriodInvoice", ctx, "c1", mock.Anything).Return(nil, nil)
+		mockInvoiceRepo.On("CreateInvoice", ctx, mock.Anything, "INV").Return(nil)
+		mockRepo.On("FindRadiusUsers", ctx, "c1").Return([]*entity.RadiusUser{user}, nil)
+		mockRadiusSync.On("SyncRadiusUser", user).Return(nil)
+		mockCoA.On("RefreshRateLimit", ctx, "tenant-1", "budi").Return(nil)
+
+		reviewer := "user-1"
+		result, err := service.ApproveBoost(ctx, "tenant-1", "sb-1", &SpeedBoostApproval{ReviewedBy: &reviewer})
+		require.NoError(t, err)
+		assert.Equal(t, entity.SpeedBoostStatusActive, result.Status)
+		assert.Equal(t, &reviewer, result.ReviewedBy)
+		require.NotNil(t, result.EndDate)
+		assert.Equal(t, 7*24*time.Hour, result.EndDate.Sub(*result.StartDate))
+
+		invoice := mockInvoiceRepo.Calls[1].Arguments.Get(1).(*entity.Payment)
+		assert.Equal(t, 35000.0, invoice.Amount)
+		assert.Equal(t, "Speed boost to Pro 30 Mbps, 7 days", invoice.Items[0].Description)
+		assert.NotNil(t, invoice.ServiceExtendedAt)
+
+		// pending -> approved, payment saved, approved -> active
+		mockRepo.AssertNumberOfCalls(t, "Transition", 3)
+		assert.Equal(t, entity.SpeedBoostStatusPending, mockRepo.Calls[1].Arguments.Get(2))
+		assert.Equal(t, entity.SpeedBoostStatusApproved, mockRepo.Calls[3].Arguments.Get(2))
+		mockCoA.AssertExpectations(t)
+	})
+
+	t.Run("Charges The Open Cycle Invoice And Waits For The Start Date", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+		boost := newBoost()
+		invoice := &entity.Payment{
+			ID: "inv-1", TenantID: "tenant-1", Status: entity.PaymentStatusPending,
+			Items: []entity.InvoiceItem{{Type: entity.InvoiceItemMonthlyFee, Quantity: 1, UnitPrice: 150000}},
+		}
+		mockRepo.On("FindByID", ctx, "tenant-1", "sb-1").Return(boost, nil)
+		mockRepo.On("Transition", ctx, boost, mock.Anything).Return(true, nil)
+		mockSettingsRepo.On("GetTenantSettings", ctx, "tenant-1").Return(settings, nil)
+		mockInvoiceRepo.On("FindPeriodInvoice", ctx, "c1", mock.Anything).Return(invoice, nil)
+		mockInvoiceRepo.On("AddInvoiceItems", ctx, mock.Anything, mock.Anything).Return(true, nil)
+
+		start := time.Now().Add(48 * time.Hour)
+		result, err := service.ApproveBoost(ctx, "tenant-1", "sb-1", &SpeedBoostApproval{StartDate: &start})
+		require.NoError(t, err)
+		assert.Equal(t, entity.SpeedBoostStatusApproved, result.Status)
+		assert.Equal(t, "inv-1", *result.PaymentID)
+		assert.Nil(t, result.EndDate)
+
+		updated := mockInvoiceRepo.Calls[1].Arguments.Get(1).(*entity.Payment)
+		assert.Equal(t, 185000.0, updated.Amount)
+		mockRepo.AssertNotCalled(t, "FindRadiusUsers", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Only Pending Boosts", func(t *testing.T) {
+		mockRepo := new(MockSpeedBoostRepository)
+		mockInvoiceRepo := new(MockInvoiceRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockRadiusSync := new(MockRadiusUserSyncer)
+		mockCoA := new(MockRadiusCoAService)
+		service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+		boost := newBoost()
+		boost.Status = entity.SpeedBoostStatusActive
+		mockRepo.On("FindByID", ctx, "tenant-1", "sb-1").Return(boost, nil)
+
+		_, err := service.ApproveBoost(ctx, "tenant-1", "sb-1", &SpeedBoostApproval{})
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "Transition", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestSpeedBoostService_ProcessDueBoosts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 4, 16, 8, 0, 0, 0, time.UTC)
+	start := now.Add(-time.Hour)
+	ended := now.Add(-time.Minute)
+
+	mockRepo := new(MockSpeedBoostRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockSettingsRepo := new(MockSettingsRepository)
+	mockRadiusSync := new(MockRadiusUserSyncer)
+	mockCoA := new(MockRadiusCoAService)
+	service := NewSpeedBoostService(mockRepo, mockInvoiceRepo, mockSettingsRepo, mockRadiusSync, mockCoA, "link-secret", "https://boost.example.com/")
+
+	starting := &entity.SpeedBoost{ID: "sb-1", TenantID: "tenant-1", CustomerID: "c1", DurationDays: 2, StartDate: &start, Status: entity.SpeedBoostStatusApproved}
+	ending := &entity.SpeedBoost{ID: "sb-2", TenantID: "tenant-1", CustomerID: "c2", DurationDays: 1, EndDate: &ended, Status: entity.SpeedBoostStatusActive}
+	taken := &entity.SpeedBoost{ID: "sb-3", TenantID: "tenant-1", CustomerID: "c3", Status: entity.SpeedBoostStatusActive}
+	user := &entity.RadiusUser{Username: "siti", IsActive: true}
+
+	mockRepo.On("FindDueBoosts", ctx, now).Return([]*entity.SpeedBoost{starting, ending, taken}, nil)
+	mockRepo.On("Transition", ctx, starting, entity.SpeedBoostStatusApproved).Return(true, nil)
+	mockRepo.On("Transition", ctx, ending, entity.SpeedBoostStatusActive).Return(true, nil)
+	mockRepo.On("Transition", ctx, taken, entity.SpeedBoostStatusActive).Return(false, nil)
+	mockRepo.On("FindRadiusUsers", ctx, "c1").Return([]*entity.RadiusUser{}, nil)
+	mockRepo.On("FindRadiusUsers", ctx, "c2").Return([]*entity.RadiusUser{user}, nil)
+	mockRadiusSync.On("SyncRadiusUser", user).Return(nil)
+	mockCoA.On("RefreshRateLimit", ctx, "tenant-1", "siti").Return(nil)
+
+	changed, err := service.ProcessDueBoosts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+
+	assert.Equal(t, entity.SpeedBoostStatusActive, starting.Status)
+	assert.Equal(t, start.AddDate(0, 0, 2), *starting.EndDate)
+	assert.Equal(t, entity.SpeedBoostStatusExpired, ending.Status)
+	assert.Equal(t, now, *ending.ExpiredAt)
+	mockRepo.AssertNotCalled(t, "FindRadiusUsers", ctx, "c3")
+	mockCoA.AssertExpectations(t)
+}
@@ -0,0 +1,339 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// SubscriptionRenewalService renews auto-renewing tenant subscriptions. It
+// creates a renewal order ahead of the billing date and sends reminders; an
+// unpaid subscription becomes past_due, then suspended and finally expired.
+// Paying the renewal order reactivates it through ProcessPayment.
+//
+// Trials end the same way: a plan whose TrialConfig auto-converts bills the
+// trial like a renewal, any other trial becomes read_only when it ends.
+type SubscriptionRenewalService interface {
+	// ProcessRenewals takes every renewal and dunning step due at now. Steps
+	// are recorded per billing date, so running it again is safe.
+	ProcessRenewals(ctx context.Context, now time.Time) error
+}
+
+// RenewalPolicy is the dunning timeline, in days relative to the billing date
+type RenewalPolicy struct {
+	LeadDays        int   // renewal orders are created this many days before
+	ReminderDays    []int // reminders are sent this many days before
+	GracePeriodDays int   // past_due subscriptions stay usable this long after
+	SuspensionDays  int   // suspended subscriptions expire this long after the grace period
+}
+
+type subscriptionRenewalService struct {
+	subscriptionRepo    repository.TenantSubscriptionRepository
+	transactionRepo     repository.PaymentTransactionRepository
+	dunningRepo         repository.SubscriptionDunningRepository
+	notificationService NotificationService
+	templates           NotificationTemplateService
+	policy              RenewalPolicy
+}
+
+func NewSubscriptionRenewalService(
+	subscriptionRepo repository.TenantSubscriptionRepository,
+	transactionRepo repository.PaymentTransactionRepository,
+	dunningRepo repository.SubscriptionDunningRepository,
+	notificationService NotificationService,
+	templates NotificationTemplateService,
+	policy RenewalPolicy,
+) SubscriptionRenewalService {
+	return &subscriptionRenewalService{
+		subscriptionRepo:    subscriptionRepo,
+		transactionRepo:     transactionRepo,
+		dunningRepo:         dunningRepo,
+		notificationService: notificationService,
+		templates:           templates,
+		policy:              policy,
+	}
+}
+
+// billingDateOf is when the current period of a subscription must be paid
+func billingDateOf(subscription *entity.TenantSubscription) *time.Time {
+	if subscription.NextBillingDate != nil {
+		return subscription.NextBillingDate
+	}
+	return subscription.EndDate
+}
+
+// daysUntil rounds the time left until t up to whole days
+func daysUntil(t, now time.Time) int {
+	return int(math.Ceil(t.Sub(now).Hours() / 24))
+}
+
+func (s *subscriptionRenewalService) ProcessRenewals(ctx context.Context, now time.Time) error {
+	lookahead := s.policy.LeadDays
+	for _, days := range s.policy.ReminderDays {
+		if days > lookahead {
+			lookahead = days
+		}
+	}
+
+	subscriptions, err := s.subscriptionRepo.FindForDunning(ctx, now.AddDate(0, 0, lookahead))
+	if err != nil {
+		return errors.NewDatabaseError("find subscriptions for renewal", err)
+	}
+
+	for _, subscription := range subscriptions {
+		if err := s.processSubscription(ctx, subscription, now); err != nil {
+			logger.Error("Renewal of subscription %s failed: %v", subscription.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *subscriptionRenewalService) processSubscription(ctx context.Context, subscription *entity.TenantSubscription, now time.Time) error {
+	billingDate := billingDateOf(subscription)
+	if billingDate == nil {
+		return nil
+	}
+
+	switch subscription.Status {
+	case entity.SubscriptionStatusTrial:
+		return s.processTrial(ctx, subscription, *billingDate, now)
+
+	case entity.SubscriptionStatusActive:
+		// Free plans renew without an order
+		if subscription.AutoRenew && subscription.Plan != nil && subscription.Plan.Price == 0 {
+			if now.Before(*billingDate) {
+				return nil
+			}
+			startSubscriptionPeriod(subscription, *billingDate, true)
+			return s.subscriptionRepo.Update(ctx, subscription)
+		}
+
+		if subscription.AutoRenew && !now.Before(billingDate.AddDate(0, 0, -s.policy.LeadDays)) {
+			if err := s.ensureRenewalOrder(ctx, subscription, *billingDate, now); err != nil {
+				return err
+			}
+		}
+		if now.Before(*billingDate) {
+			return s.remind(ctx, subscription, *billingDate, now, "expiring")
+		}
+		if !subscription.AutoRenew {
+			return s.moveTo(ctx, subscription, *billingDate, entity.SubscriptionStatusExpired, entity.DunningStepExpired, 0)
+		}
+		graceUntil := billingDate.AddDate(0, 0, s.policy.GracePeriodDays)
+		subscription.GraceUntil = &graceUntil
+		return s.moveTo(ctx, subscription, *billingDate, entity.SubscriptionStatusPastDue, entity.DunningStepGrace, daysUntil(graceUntil, now))
+
+	case entity.SubscriptionStatusPastDue:
+		graceUntil := billingDate.AddDate(0, 0, s.policy.GracePeriodDays)
+		if subscription.GraceUntil != nil {
+			graceUntil = *subscription.GraceUntil
+		}
+		if now.Before(graceUntil) {
+			return nil
+		}
+		return s.moveTo(ctx, subscription, *billingDate, entity.SubscriptionStatusSuspended, entity.DunningStepSuspended, 0)
+
+	case entity.SubscriptionStatusSuspended:
+		if now.Before(billingDate.AddDate(0, 0, s.policy.GracePeriodDays+s.policy.SuspensionDays)) {
+			return nil
+		}
+		return s.moveTo(ctx, subscription, *billingDate, entity.SubscriptionStatusExpired, entity.DunningStepExpired, 0)
+	}
+	return nil
+}
+
+// processTrial warns the tenant before a trial ends. At the end an
+// auto-converting trial moves to its paid plan with a renewal order to pay
+// within the grace period; other trials become read_only.
+func (s *subscriptionRenewalService) processTrial(ctx context.Context, subscription *entity.TenantSubscription, trialEnd, now time.Time) error {
+	plan := subscription.Plan
+	if plan == nil {
+		return fmt.Errorf("subscription %s has no plan", subscription.ID)
+	}
+	// A dedicated trial plan has no paid plan to convert to
+	convert := plan.Trial().AutoConvert && !plan.IsTrial
+
+	if now.Before(trialEnd) {
+		if convert && plan.Price > 0 && !now.Before(trialEnd.AddDate(0, 0, -s.policy.LeadDays)) {
+			if err := s.ensureRenewalOrder(ctx, subscription, trialEnd, now); err != nil {
+				return err
+			}
+		}
+		return s.remind(ctx, subscription, trialEnd, now, "trial_expiring")
+	}
+
+	if !convert {
+		return s.moveTo(ctx, subscription, trialEnd, entity.SubscriptionStatusReadOnly, entity.DunningStepTrialEnded, 0)
+	}
+
+	daysLeft := 0
+	if plan.Price == 0 {
+		startSubscriptionPeriod(subscription, trialEnd, false)
+	} else {
+		if err := s.ensureRenewalOrder(ctx, subscription, trialEnd, now); err != nil {
+			return err
+		}
+		graceUntil := trialEnd.AddDate(0, 0, s.policy.GracePeriodDays)
+		subscription.Status = entity.SubscriptionStatusPastDue
+		subscription.GraceUntil = &graceUntil
+		daysLeft = daysUntil(graceUntil, now)
+	}
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return errors.NewDatabaseError("update subscription", err)
+	}
+	logger.Info("Trial subscription %s of tenant %s converted to %s (%s)", subscription.ID, subscription.TenantID, plan.Name, subscription.Status)
+
+	s.notifyStep(ctx, subscription, trialEnd, entity.DunningStepTrialConverted, "trial_converted", daysLeft)
+	return nil
+}
+
+// ensureRenewalOrder creates the pending transaction the tenant pays to renew,
+// unless one is already open for the subscription
+func (s *subscriptionRenewalService) ensureRenewalOrder(ctx context.Context, subscription *entity.TenantSubscription, billingDate, now time.Time) error {
+	transactions, err := s.transactionRepo.FindByTenantID(ctx, subscription.TenantID)
+	if err != nil {
+		return errors.NewDatabaseError("find transactions", err)
+	}
+	for _, tx := range transactions {
+		if tx.Status == entity.TransactionStatusPending && tx.SubscriptionID != nil && *tx.SubscriptionID == subscription.ID {
+			return nil
+		}
+	}
+	if subscription.Plan == nil {
+		return fmt.Errorf("subscription %s has no plan", subscription.ID)
+	}
+
+	// Unpaid orders stay payable until the subscription expires
+	expiredAt := billingDate.AddDate(0, 0, s.policy.GracePeriodDays+s.policy.SuspensionDays)
+	transaction := &entity.PaymentTransaction{
+		TenantID:        subscription.TenantID,
+		SubscriptionID:  &subscription.ID,
+		PlanID:          &subscription.PlanID,
+		OrderID:         renewalOrderID(subscription, billingDate),
+		Amount:          subscription.Plan.Price,
+		Status:          entity.TransactionStatusPending,
+		GatewayResponse: "{}",
+		ExpiredAt:       &expiredAt,
+	}
+	if err := s.transactionRepo.Create(ctx, transaction); err != nil {
+		return errors.NewDatabaseError("create renewal order", err)
+	}
+	logger.Info("Renewal order %s created: tenant=%s, amount=%.2f", transaction.OrderID, subscription.TenantID, transaction.Amount)
+
+	s.notifyStep(ctx, subscription, billingDate, entity.DunningStepRenewalOrder, "renewal", daysUntil(billingDate, now))
+	return nil
+}
+
+// renewalOrderID is unique per subscription and billing date
+func renewalOrderID(subscription *entity.TenantSubscription, billingDate time.Time) string {
+	id := subscription.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("REN-%s-%s", billingDate.Format("20060102"), id)
+}
+
+// remind sends the reminder of the closest reminder day that has been
+// reached, as a subscription notification with the given status
+func (s *subscriptionRenewalService) remind(ctx context.Context, subscription *entity.TenantSubscription, billingDate, now time.Time, status string) error {
+	reminder := -1
+	for _, days := range s.policy.ReminderDays {
+		if days < 0 || now.Before(billingDate.AddDate(0, 0, -days)) {
+			continue
+		}
+		if reminder < 0 || days < reminder {
+			reminder = days
+		}
+	}
+	if reminder < 0 {
+		return nil
+	}
+
+	step := fmt.Sprintf("%s_%d", entity.DunningStepReminder, reminder)
+	s.notifyStep(ctx, subscription, billingDate, step, status, daysUntil(billingDate, now))
+	return nil
+}
+
+// moveTo changes the status of an unpaid subscription and tells the tenant
+func (s *subscriptionRenewalService) moveTo(ctx context.Context, subscription *entity.TenantSubscription, billingDate time.Time, status, step string, daysLeft int) error {
+	previous := subscription.Status
+	subscription.Status = status
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return errors.NewDatabaseError("update subscription", err)
+	}
+	logger.Info("Subscription %s of tenant %s moved from %s to %s", subscription.ID, subscription.TenantID, previous, status)
+
+	s.notifyStep(ctx, subscription, billingDate, step, status, daysLeft)
+	return nil
+}
+
+// notifyStep sends the subscription notification of a step once per billing date
+func (s *subscriptionRenewalService) notifyStep(ctx context.Context, subscription *entity.TenantSubscription, billingDate time.Time, step, status string, daysLeft int) {
+	claimed, err := s.dunningRepo.ClaimStep(ctx, &entity.SubscriptionDunningStep{
+		SubscriptionID: subscription.ID,
+		TenantID:       subscription.TenantID,
+		BillingDate:    billingDate,
+		Step:           step,
+	})
+	if err != nil {
+		logger.Error("Failed to record dunning step %s for subscription %s: %v", step, subscription.ID, err)
+		return
+	}
+	if !claimed || s.notificationService == nil || s.templates == nil {
+		return
+	}
+
+	planName := ""
+	if subscription.Plan != nil {
+		planName = subscription.Plan.Name
+	}
+	notification, err := NewSubscriptionNotification(ctx, s.templates, subscription.TenantID, planName, status, daysLeft)
+	if err != nil {
+		logger.Error("Failed to render subscription notification for tenant %s: %v", subscription.TenantID, err)
+		return
+	}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		logger.Error("Failed to notify tenant %s: %v", subscription.TenantID, err)
+	}
+}
+
+// startSubscriptionPeriod activates the month a payment bought. A renewal
+// paid before the current period or trial ends extends it rather than
+// restarting it.
+func startSubscriptionPeriod(subscription *entity.TenantSubscription, paidAt time.Time, renewal bool) {
+	start := paidAt
+	current := subscription.Status == entity.SubscriptionStatusActive || subscription.Status == entity.SubscriptionStatusTrial
+	if renewal && current && subscription.EndDate != nil && subscription.EndDate.After(paidAt) {
+		start = *subscription.EndDate
+	} else {
+		subscription.StartDate = &paidAt
+	}
+
+	endDate := start.AddDate(0, 1, 0)
+	subscription.Status = entity.SubscriptionStatusActive
+	subscription.EndDate = &endDate
+	subscription.NextBillingDate = &endDate
+	subscription.GraceUntil = nil
+}
+
+// StartSubscriptionRenewalJob runs ProcessRenewals now and then on every interval
+func StartSubscriptionRenewalJob(service SubscriptionRenewalService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.ProcessRenewals(context.Background(), time.Now()); err != nil {
+				logger.Error("Subscription renewal job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Subscription renewal job started (interval: %s)", interval)
+}
@@ -0,0 +1,322 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSubscriptionDunningRepository struct {
+	mock.Mock
+}
+
+func (m *MockSubscriptionDunningRepository) ClaimStep(ctx context.Context, step *entity.SubscriptionDunningStep) (bool, error) {
+	args := m.Called(ctx, step)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockNotificationService only implements CreateNotification
+type MockNotificationService struct {
+	NotificationService
+	mock.Mock
+}
+
+func (m *MockNotificationService) CreateNotification(ctx context.Context, notification *entity.Notification) error {
+	args := m.Called(ctx, notification)
+	return args.Error(0)
+}
+
+// claimedStep matches the dunning step with the given name
+func claimedStep(step string) interface{} {
+	return mock.MatchedBy(func(s *entity.SubscriptionDunningStep) bool { return s.Step == step })
+}
+
+func TestSubscriptionRenewalService_ProcessRenewals(t *testing.T) {
+	ctx := context.Background()
+	// Tenants without settings are notified in Indonesian
+	settingsRepo := new(MockSettingsRepository)
+	settingsRepo.On("GetTenantSettings", mock.Anything, mock.Anything).Return(nil, errors.ErrNotFound)
+	templates := NewNotificationTemplateService(new(MockNotificationTemplateRepository), settingsRepo)
+	policy := RenewalPolicy{LeadDays: 7, ReminderDays: []int{3, 1}, GracePeriodDays: 3, SuspensionDays: 14}
+	billingDate := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	subscription := func(status string) *entity.TenantSubscription {
+		date := billingDate
+		return &entity.TenantSubscription{
+			ID: "0b5c7a9e-sub", TenantID: "tenant-1", PlanID: "plan-1", Status: status,
+			EndDate: &date, NextBillingDate: &date, AutoRenew: true,
+			Plan: &entity.SubscriptionPlan{Name: "Pro", Price: 300000},
+		}
+	}
+
+	t.Run("Creates Renewal Order Ahead Of Billing Date", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		now := billingDate.AddDate(0, 0, -6)
+		sub := subscription(entity.SubscriptionStatusActive)
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockTransactionRepo.On("FindByTenantID", ctx, "tenant-1").Return([]*entity.PaymentTransaction{}, nil)
+		mockTransactionRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep(entity.DunningStepRenewalOrder)).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, now))
+
+		order := mockTransactionRepo.Calls[1].Arguments.Get(1).(*entity.PaymentTransaction)
+		assert.Equal(t, "REN-20250310-0b5c7a9e", order.OrderID)
+		assert.Equal(t, 300000.0, order.Amount)
+		assert.Equal(t, "plan-1", *order.PlanID)
+		assert.Equal(t, entity.TransactionStatusPending, order.Status)
+		notification := mockNotifications.Calls[0].Arguments.Get(1).(*entity.Notification)
+		assert.Equal(t, entity.NotificationTypePayment, notification.Type)
+	})
+
+	t.Run("Sends Closest Reminder Once", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		now := billingDate.Add(-20 * time.Hour)
+		sub := subscription(entity.SubscriptionStatusActive)
+		pending := &entity.PaymentTransaction{SubscriptionID: &sub.ID, Status: entity.TransactionStatusPending}
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockTransactionRepo.On("FindByTenantID", ctx, "tenant-1").Return([]*entity.PaymentTransaction{pending}, nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep("reminder_1")).Return(false, nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, now))
+		mockTransactionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		mockNotifications.AssertNotCalled(t, "CreateNotification", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unpaid Subscription Enters Grace", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		now := billingDate.Add(time.Hour)
+		sub := subscription(entity.SubscriptionStatusActive)
+		pending := &entity.PaymentTransaction{SubscriptionID: &sub.ID, Status: entity.TransactionStatusPending}
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockTransactionRepo.On("FindByTenantID", ctx, "tenant-1").Return([]*entity.PaymentTransaction{pending}, nil)
+		mockSubscriptionRepo.On("Update", ctx, sub).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep(entity.DunningStepGrace)).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, now))
+		assert.Equal(t, entity.SubscriptionStatusPastDue, sub.Status)
+		assert.Equal(t, billingDate.AddDate(0, 0, 3), *sub.GraceUntil)
+		notification := mockNotifications.Calls[0].Arguments.Get(1).(*entity.Notification)
+		assert.Contains(t, notification.Message, "3 hari")
+	})
+
+	t.Run("Suspends After Grace And Expires After Suspension", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		pastDue := subscription(entity.SubscriptionStatusPastDue)
+		graceUntil := billingDate.AddDate(0, 0, 3)
+		pastDue.GraceUntil = &graceUntil
+		suspended := subscription(entity.SubscriptionStatusSuspended)
+		suspended.ID = "sub-2"
+		now := billingDate.AddDate(0, 0, 18)
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{pastDue, suspended}, nil)
+		mockSubscriptionRepo.On("Update", ctx, mock.Anything).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, mock.Anything).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, now))
+		assert.Equal(t, entity.SubscriptionStatusSuspended, pastDue.Status)
+		assert.Equal(t, entity.SubscriptionStatusExpired, suspended.Status)
+	})
+
+	t.Run("Subscription Without Auto Renew Expires", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		sub := subscription(entity.SubscriptionStatusActive)
+		sub.AutoRenew = false
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockSubscriptionRepo.On("Update", ctx, sub).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep(entity.DunningStepExpired)).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, billingDate.Add(time.Hour)))
+		assert.Equal(t, entity.SubscriptionStatusExpired, sub.Status)
+		mockTransactionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSubscriptionRenewalService_ProcessTrials(t *testing.T) {
+	ctx := context.Background()
+	// Tenants without settings are notified in Indonesian
+	settingsRepo := new(MockSettingsRepository)
+	settingsRepo.On("GetTenantSettings", mock.Anything, mock.Anything).Return(nil, errors.ErrNotFound)
+	templates := NewNotificationTemplateService(new(MockNotificationTemplateRepository), settingsRepo)
+	policy := RenewalPolicy{LeadDays: 7, ReminderDays: []int{3, 1}, GracePeriodDays: 3, SuspensionDays: 14}
+	trialEnd := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	trial := func(trialConfig string) *entity.TenantSubscription {
+		date := trialEnd
+		return &entity.TenantSubscription{
+			ID: "7d1e4f2a-sub", TenantID: "tenant-1", PlanID: "plan-1", Status: entity.SubscriptionStatusTrial,
+			EndDate: &date, NextBillingDate: &date, TrialEndsAt: &date, AutoRenew: true,
+			Plan: &entity.SubscriptionPlan{Name: "Pro", Price: 300000, TrialConfig: trialConfig},
+		}
+	}
+	autoConvert := `{"trial_days":14,"trial_enabled":true,"auto_convert":true}`
+
+	t.Run("Warns Before Trial Ends", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		sub := trial(`{"trial_days":14,"trial_enabled":true}`)
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep("reminder_3")).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, trialEnd.AddDate(0, 0, -2).Add(-time.Hour)))
+		notification := mockNotifications.Calls[0].Arguments.Get(1).(*entity.Notification)
+		assert.Equal(t, "Masa Trial Akan Berakhir", notification.Title)
+		assert.Contains(t, notification.Message, "3 hari")
+		mockTransactionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Auto Convert Creates Order Ahead Of Trial End", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		sub := trial(autoConvert)
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockTransactionRepo.On("FindByTenantID", ctx, "tenant-1").Return([]*entity.PaymentTransaction{}, nil)
+		mockTransactionRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep(entity.DunningStepRenewalOrder)).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, trialEnd.AddDate(0, 0, -5)))
+		order := mockTransactionRepo.Calls[1].Arguments.Get(1).(*entity.PaymentTransaction)
+		assert.Equal(t, "REN-20250310-7d1e4f2a", order.OrderID)
+		assert.Equal(t, 300000.0, order.Amount)
+		assert.Equal(t, entity.SubscriptionStatusTrial, sub.Status)
+	})
+
+	t.Run("Auto Convert Moves Ended Trial To Paid Plan", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		sub := trial(autoConvert)
+		pending := &entity.PaymentTransaction{SubscriptionID: &sub.ID, Status: entity.TransactionStatusPending}
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockTransactionRepo.On("FindByTenantID", ctx, "tenant-1").Return([]*entity.PaymentTransaction{pending}, nil)
+		mockSubscriptionRepo.On("Update", ctx, sub).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep(entity.DunningStepTrialConverted)).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, trialEnd.Add(time.Hour)))
+		assert.Equal(t, entity.SubscriptionStatusPastDue, sub.Status)
+		assert.Equal(t, trialEnd.AddDate(0, 0, 3), *sub.GraceUntil)
+		notification := mockNotifications.Calls[0].Arguments.Get(1).(*entity.Notification)
+		assert.Equal(t, entity.NotificationTypePayment, notification.Type)
+	})
+
+	t.Run("Trial Without Auto Convert Becomes Read Only", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		sub := trial(`{"trial_days":14,"trial_enabled":true,"auto_convert":false}`)
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockSubscriptionRepo.On("Update", ctx, sub).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep(entity.DunningStepTrialEnded)).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, trialEnd.Add(time.Hour)))
+		assert.Equal(t, entity.SubscriptionStatusReadOnly, sub.Status)
+		mockTransactionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Trial Plan Is Not Converted", func(t *testing.T) {
+		mockSubscriptionRepo := new(MockTenantSubscriptionRepository)
+		mockTransactionRepo := new(MockPaymentTransactionRepository)
+		mockDunningRepo := new(MockSubscriptionDunningRepository)
+		mockNotifications := new(MockNotificationService)
+		service := NewSubscriptionRenewalService(mockSubscriptionRepo, mockTransactionRepo, mockDunningRepo, mockNotifications, templates, policy)
+
+		sub := trial(autoConvert)
+		sub.Plan.IsTrial = true
+		mockSubscriptionRepo.On("FindForDunning", ctx, mock.Anything).Return([]*entity.TenantSubscription{sub}, nil)
+		mockSubscriptionRepo.On("Update", ctx, sub).Return(nil)
+		mockDunningRepo.On("ClaimStep", ctx, claimedStep(entity.DunningStepTrialEnded)).Return(true, nil)
+		mockNotifications.On("CreateNotification", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.ProcessRenewals(ctx, trialEnd.Add(time.Hour)))
+		assert.Equal(t, entity.SubscriptionStatusReadOnly, sub.Status)
+	})
+}
+
+func TestStartSubscriptionPeriod(t *testing.T) {
+	paidAt := time.Date(2025, 3, 5, 10, 0, 0, 0, time.Local)
+	endDate := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+
+	t.Run("Early Renewal Extends Period", func(t *testing.T) {
+		end := endDate
+		sub := &entity.TenantSubscription{Status: entity.SubscriptionStatusActive, EndDate: &end}
+		startSubscriptionPeriod(sub, paidAt, true)
+		assert.Equal(t, endDate.AddDate(0, 1, 0), *sub.EndDate)
+		assert.Equal(t, *sub.EndDate, *sub.NextBillingDate)
+	})
+
+	t.Run("Past Due Renewal Restarts Period", func(t *testing.T) {
+		end := endDate
+		grace := endDate.AddDate(0, 0, 3)
+		sub := &entity.TenantSubscription{Status: entity.SubscriptionStatusPastDue, EndDate: &end, GraceUntil: &grace}
+		startSubscriptionPeriod(sub, endDate.AddDate(0, 0, 2), true)
+		assert.Equal(t, entity.SubscriptionStatusActive, sub.Status)
+		assert.Equal(t, endDate.AddDate(0, 0, 2).AddDate(0, 1, 0), *sub.EndDate)
+		assert.Nil(t, sub.GraceUntil)
+	})
+
+	t.Run("Trial Paid Early Starts After Trial", func(t *testing.T) {
+		end := endDate
+		sub := &entity.TenantSubscription{Status: entity.SubscriptionStatusTrial, EndDate: &end}
+		startSubscriptionPeriod(sub, paidAt, true)
+		assert.Equal(t, entity.SubscriptionStatusActive, sub.Status)
+		assert.Equal(t, endDate.AddDate(0, 1, 0), *sub.EndDate)
+	})
+
+	t.Run("Upgrade Restarts Period", func(t *testing.T) {
+		end := endDate
+		sub := &entity.TenantSubscription{Status: entity.SubscriptionStatusActive, EndDate: &end}
+		startSubscriptionPeriod(sub, paidAt, false)
+		assert.Equal(t, paidAt, *sub.StartDate)
+		assert.Equal(t, paidAt.AddDate(0, 1, 0), *sub.EndDate)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
@@ -22,6 +23,11 @@ type SubscriptionService interface {
 	CreatePayment(ctx context.Context, req *CreatePaymentRequest) (*CreatePaymentResponse, error)
 	GetPaymentStatus(ctx context.Context, orderID string) (*PaymentStatusResponse, error)
 	ProcessPayment(ctx context.Context, orderID string, status string, paymentMethod string, gatewayTxID string) error
+	// ParseGatewayNotification verifies a webhook from the named gateway. It
+	// returns payment.ErrInvalidSignature when the webhook is not authentic.
+	ParseGatewayNotification(ctx context.Context, gateway string, header http.Header, body []byte) (*payment.Notification, error)
+	// ApplyGatewayNotification processes the payment of a verified notification
+	ApplyGatewayNotification(ctx context.Context, notification *payment.Notification) error
 }
 
 type SubscriptionPlanProfile struct {
@@ -84,11 +90,14 @@ type PaymentStatusResponse struct {
 }
 
 type subscriptionService struct {
-	planRepo         repository.SubscriptionPlanRepository
-	tenantRepo       repository.TenantRepository
-	userRepo         repository.UserRepository
-	subscriptionRepo repository.TenantSubscriptionRepository
-	transactionRepo  repository.PaymentTransactionRepository
+	planRepo            repository.SubscriptionPlanRepository
+	tenantRepo          repository.TenantRepository
+	userRepo            repository.UserRepository
+	subscriptionRepo    repository.TenantSubscriptionRepository
+	transactionRepo     repository.PaymentTransactionRepository
+	gateways            *payment.Gateways
+	notificationService NotificationService // optional
+	templates           NotificationTemplateService
 }
 
 func NewSubscriptionService(
@@ -97,6 +106,7 @@ func NewSubscriptionService(
 	userRepo repository.UserRepository,
 	subscriptionRepo repository.TenantSubscriptionRepository,
 	transactionRepo repository.PaymentTransactionRepository,
+	gateways *payment.Gateways,
 ) SubscriptionService {
 	return &subscriptionService{
 		planRepo:         planRepo,
@@ -104,6 +114,30 @@ func NewSubscriptionService(
 		userRepo:         userRepo,
 		subscriptionRepo: subscriptionRepo,
 		transactionRepo:  transactionRepo,
+		gateways:         gateways,
+	}
+}
+
+// NewSubscriptionServiceWithNotification creates subscription service with notification support
+func NewSubscriptionServiceWithNotification(
+	planRepo repository.SubscriptionPlanRepository,
+	tenantRepo repository.TenantRepository,
+	userRepo repository.UserRepository,
+	subscriptionRepo repository.TenantSubscriptionRepository,
+	transactionRepo repository.PaymentTransactionRepository,
+	gateways *payment.Gateways,
+	notificationService NotificationService,
+	templates NotificationTemplateService,
+) SubscriptionService {
+	return &subscriptionService{
+		planRepo:            planRepo,
+		tenantRepo:          tenantRepo,
+		userRepo:            userRepo,
+		subscriptionRepo:    subscriptionRepo,
+		transactionRepo:     transactionRepo,
+		gateways:            gateways,
+		notificationService: notificationService,
+		templates:           templates,
 	}
 }
 
@@ -162,6 +196,11 @@ func (s *subscriptionService) SignUp(ctx context.Context, req *SignUpRequest) (*
 		return nil, errors.ErrInvalidPlan
 	}
 
+	trial := plan.Trial()
+	if req.UseTrial && !trial.TrialEnabled {
+		return nil, errors.ErrTrialNotAvailable
+	}
+
 	// 3. Create tenant
 	tenant := &entity.Tenant{
 		Name:     req.ISPName,
@@ -195,9 +234,9 @@ func (s *subscriptionService) SignUp(ctx context.Context, req *SignUpRequest) (*
 
 	// 5. Handle Trial vs Paid
 	if req.UseTrial {
-		// FREE TRIAL - Activate immediately for 7 days
+		// FREE TRIAL - Activate immediately for the plan's trial days
 		now := time.Now()
-		trialEnd := now.AddDate(0, 0, 7) // +7 days
+		trialEnd := now.AddDate(0, 0, trial.TrialDays)
 
 		subscription := &entity.TenantSubscription{
 			TenantID:        tenant.ID,
@@ -206,6 +245,7 @@ func (s *subscriptionService) SignUp(ctx context.Context, req *SignUpRequest) (*
 			StartDate:       &now,
 			EndDate:         &trialEnd,
 			NextBillingDate: &trialEnd,
+			TrialEndsAt:     &trialEnd,
 			AutoRenew:       true,
 		}
 		if err := s.subscriptionRepo.Create(ctx, subscription); err != nil {
@@ -220,7 +260,7 @@ func (s *subscriptionService) SignUp(ctx context.Context, req *SignUpRequest) (*
 			UserID:    user.ID,
 			IsTrial:   true,
 			TrialEnds: trialEnd.Format("2006-01-02"),
-			Message:   "Your 7-day free trial has started! You can start using the platform immediately.",
+			Message:   fmt.Sprintf("Your %d-day free trial has started! You can start using the platform immediately.", trial.TrialDays),
 		}, nil
 	}
 
@@ -304,60 +344,34 @@ func (s *subscriptionService) CreatePayment(ctx context.Context, req *CreatePaym
 	}
 	user := users[0]
 
-	// 3. Initialize Midtrans client
-	midtransConfig := &payment.MidtransConfig{
-		ServerKey:    os.Getenv("MIDTRANS_SERVER_KEY"),
-		ClientKey:    os.Getenv("MIDTRANS_CLIENT_KEY"),
-		IsProduction: os.Getenv("MIDTRANS_IS_PRODUCTION") == "true",
-	}
-
-	if midtransConfig.ServerKey == "" {
+	// 3. Charge through the configured gateway
+	gateway := s.gateways.Default()
+	if gateway == nil {
 		return nil, errors.NewWithDetails("PAY_4002", "Payment gateway not configured", 500, nil)
 	}
 
-	midtransClient := payment.NewMidtransClient(midtransConfig)
-
-	customer := &payment.CustomerDetails{
-		FirstName: user.Name,
-		Email:     user.Email,
-	}
-
-	items := []payment.ItemDetail{
-		{
-			ID:       req.OrderID,
-			Name:     "Subscription Payment",
-			Price:    transaction.Amount,
-			Quantity: 1,
+	charge, chargeErr := gateway.CreatePayment(ctx, &payment.ChargeParams{
+		OrderID:     req.OrderID,
+		Amount:      transaction.Amount,
+		Method:      req.PaymentMethod,
+		Description: "Subscription Payment",
+		Customer: &payment.CustomerDetails{
+			FirstName: user.Name,
+			Email:     user.Email,
 		},
-	}
-
-	callbackURL := os.Getenv("MIDTRANS_FINISH_URL")
-
-	// 4. Create charge based on payment method
-	var chargeResp *payment.ChargeResponse
-	var chargeErr error
-
-	switch req.PaymentMethod {
-	case "bca_va":
-		chargeResp, chargeErr = midtransClient.ChargeBankTransfer(req.OrderID, transaction.Amount, "bca", customer, items)
-	case "bni_va":
-		chargeResp, chargeErr = midtransClient.ChargeBankTransfer(req.OrderID, transaction.Amount, "bni", customer, items)
-	case "bri_va":
-		chargeResp, chargeErr = midtransClient.ChargeBankTransfer(req.OrderID, transaction.Amount, "bri", customer, items)
-	case "permata_va":
-		chargeResp, chargeErr = midtransClient.ChargeBankTransfer(req.OrderID, transaction.Amount, "permata", customer, items)
-	case "mandiri_bill":
-		chargeResp, chargeErr = midtransClient.ChargeMandiriBill(req.OrderID, transaction.Amount, customer, items)
-	case "gopay":
-		chargeResp, chargeErr = midtransClient.ChargeGopay(req.OrderID, transaction.Amount, customer, items, callbackURL)
-	case "shopeepay":
-		chargeResp, chargeErr = midtransClient.ChargeShopeePay(req.OrderID, transaction.Amount, customer, items, callbackURL)
-	case "qris":
-		chargeResp, chargeErr = midtransClient.ChargeQRIS(req.OrderID, transaction.Amount, customer, items)
-	default:
+		Items: []payment.ItemDetail{
+			{
+				ID:       req.OrderID,
+				Name:     "Subscription Payment",
+				Price:    transaction.Amount,
+				Quantity: 1,
+			},
+		},
+		CallbackURL: os.Getenv("MIDTRANS_FINISH_URL"),
+	})
+	if chargeErr == payment.ErrUnsupportedMethod {
 		return nil, errors.NewWithDetails("PAY_4003", "Invalid payment method", 400, nil)
 	}
-
 	if chargeErr != nil {
 		logger.Error("Failed to create charge: %v", chargeErr)
 		return nil, errors.NewWithDetails("PAY_4004", "Failed to create payment", 500, map[string]interface{}{
@@ -365,52 +379,61 @@ func (s *subscriptionService) CreatePayment(ctx context.Context, req *CreatePaym
 		})
 	}
 
-	// 5. Update transaction with gateway response
-	responseJSON, _ := json.Marshal(chargeResp)
+	// 4. Update transaction with gateway response
+	responseJSON, _ := json.Marshal(charge)
 	transaction.GatewayResponse = string(responseJSON)
 	transaction.PaymentMethod = req.PaymentMethod
-	transaction.GatewayTransactionID = chargeResp.TransactionID
+	transaction.PaymentGateway = gateway.Name()
+	transaction.GatewayTransactionID = charge.TransactionID
 
 	if err := s.transactionRepo.Update(ctx, transaction); err != nil {
 		logger.Error("Failed to update transaction: %v", err)
 	}
 
-	// 6. Build payment info based on payment type
+	// 5. Build payment info based on payment type
 	paymentInfo := make(map[string]interface{})
 
 	switch req.PaymentMethod {
 	case "bca_va", "bni_va", "bri_va":
-		if len(chargeResp.VANumbers) > 0 {
-			paymentInfo["bank"] = chargeResp.VANumbers[0].Bank
-			paymentInfo["va_number"] = chargeResp.VANumbers[0].VANumber
+		if len(charge.VANumbers) > 0 {
+			paymentInfo["bank"] = charge.VANumbers[0].Bank
+			paymentInfo["va_number"] = charge.VANumbers[0].VANumber
 		}
 	case "permata_va":
 		paymentInfo["bank"] = "permata"
-		paymentInfo["va_number"] = chargeResp.PermataVANumber
+		paymentInfo["va_number"] = charge.PermataVANumber
+		if len(charge.VANumbers) > 0 {
+			paymentInfo["va_number"] = charge.VANumbers[0].VANumber
+		}
 	case "mandiri_bill":
-		paymentInfo["biller_code"] = chargeResp.BillerCode
-		paymentInfo["bill_key"] = chargeResp.BillKey
+		paymentInfo["biller_code"] = charge.BillerCode
+		paymentInfo["bill_key"] = charge.BillKey
+		if len(charge.VANumbers) > 0 {
+			paymentInfo["bank"] = charge.VANumbers[0].Bank
+			paymentInfo["va_number"] = charge.VANumbers[0].VANumber
+		}
 	case "gopay", "shopeepay":
-		for _, action := range chargeResp.Actions {
-			if action.Name == "generate-qr-code" {
-				paymentInfo["qr_url"] = action.URL
-			}
-			if action.Name == "deeplink-redirect" {
-				paymentInfo["deeplink"] = action.URL
-			}
+		if charge.QRCodeURL != "" {
+			paymentInfo["qr_url"] = charge.QRCodeURL
+		}
+		if charge.DeeplinkURL != "" {
+			paymentInfo["deeplink"] = charge.DeeplinkURL
 		}
 	case "qris":
-		paymentInfo["qr_string"] = chargeResp.QRString
+		paymentInfo["qr_string"] = charge.QRString
+	}
+	if charge.CheckoutURL != "" {
+		paymentInfo["checkout_url"] = charge.CheckoutURL
 	}
 
-	logger.Info("Payment created: order=%s, method=%s, tx_id=%s", req.OrderID, req.PaymentMethod, chargeResp.TransactionID)
+	logger.Info("Payment created: order=%s, method=%s, gateway=%s, tx_id=%s", req.OrderID, req.PaymentMethod, gateway.Name(), charge.TransactionID)
 
 	return &CreatePaymentResponse{
-		OrderID:           chargeResp.OrderID,
-		PaymentType:       chargeResp.PaymentType,
-		TransactionStatus: chargeResp.TransactionStatus,
+		OrderID:           charge.OrderID,
+		PaymentType:       charge.PaymentType,
+		TransactionStatus: charge.GatewayStatus,
 		GrossAmount:       transaction.Amount,
-		ExpiryTime:        chargeResp.ExpiryTime,
+		ExpiryTime:        charge.ExpiryTime,
 		PaymentInfo:       paymentInfo,
 	}, nil
 }
@@ -426,36 +449,26 @@ func (s *subscriptionService) GetPaymentStatus(ctx context.Context, orderID stri
 		return nil, errors.ErrNotFound
 	}
 
-	// If status is still pending, check with Midtrans for latest status
-	if transaction.Status == entity.PaymentStatusPending {
-		midtransConfig := &payment.MidtransConfig{
-			ServerKey:    os.Getenv("MIDTRANS_SERVER_KEY"),
-			ClientKey:    os.Getenv("MIDTRANS_CLIENT_KEY"),
-			IsProduction: os.Getenv("MIDTRANS_IS_PRODUCTION") == "true",
-		}
-		midtransClient := payment.NewMidtransClient(midtransConfig)
-
-		statusResp, err := midtransClient.GetTransactionStatus(orderID)
-		if err == nil && statusResp != nil {
-			// Map Midtrans status to our status
+	// If status is still pending, check with the gateway for latest status
+	gateway := s.gateways.Get(transaction.PaymentGateway)
+	if transaction.Status == entity.PaymentStatusPending && gateway != nil {
+		charge, err := gateway.GetStatus(ctx, orderID)
+		if err == nil && charge != nil {
+			// Map gateway status to our status
 			var newStatus string
-			switch statusResp.TransactionStatus {
-			case "capture":
-				if statusResp.FraudStatus == "accept" {
-					newStatus = entity.PaymentStatusPaid
-				}
-			case "settlement":
+			switch charge.Status {
+			case payment.StatusPaid:
 				newStatus = entity.PaymentStatusPaid
-			case "pending":
+			case payment.StatusPending:
 				newStatus = entity.PaymentStatusPending
-			case "deny", "cancel", "expire":
+			case payment.StatusFailed, payment.StatusExpired:
 				newStatus = "failed"
 			}
 
 			// If status changed, update database
 			if newStatus != "" && newStatus != transaction.Status {
 				transaction.Status = newStatus
-				transaction.GatewayTransactionID = statusResp.TransactionID
+				transaction.GatewayTransactionID = charge.TransactionID
 
 				if newStatus == entity.PaymentStatusPaid {
 					now := time.Now()
@@ -465,12 +478,12 @@ func (s *subscriptionService) GetPaymentStatus(ctx context.Context, orderID stri
 					if transaction.SubscriptionID != nil {
 						subscription, err := s.subscriptionRepo.FindByID(ctx, *transaction.SubscriptionID)
 						if err == nil && subscription != nil {
-							subscription.Status = entity.SubscriptionStatusActive
-							subscription.StartDate = &now
-							endDate := now.AddDate(0, 1, 0)
-							subscription.EndDate = &endDate
-							subscription.NextBillingDate = &endDate
-							subscription.PaymentMethod = statusResp.PaymentType
+							renewal := transaction.PlanID == nil || *transaction.PlanID == "" || *transaction.PlanID == subscription.PlanID
+							if !renewal {
+								subscription.PlanID = *transaction.PlanID
+							}
+							startSubscriptionPeriod(subscription, now, renewal)
+							subscription.PaymentMethod = charge.PaymentType
 							s.subscriptionRepo.Update(ctx, subscription)
 						}
 					}
@@ -529,18 +542,18 @@ func (s *subscriptionService) ProcessPayment(ctx context.Context, orderID string
 			}
 
 			if subscription != nil {
+				// A transaction for the current plan renews it
+				renewal := transaction.PlanID == nil || *transaction.PlanID == "" || *transaction.PlanID == subscription.PlanID
+				previousStatus := subscription.Status
+
 				// Update plan if transaction has a new plan ID (upgrade)
-				if transaction.PlanID != nil && *transaction.PlanID != "" {
+				if !renewal {
 					subscription.PlanID = *transaction.PlanID
 					logger.Info("Upgrading subscription plan: tenant=%s, new_plan=%s", transaction.TenantID, *transaction.PlanID)
 				}
 				
 				// Activate subscription
-				subscription.Status = entity.SubscriptionStatusActive
-				subscription.StartDate = &now
-				endDate := now.AddDate(0, 1, 0) // +1 month
-				subscription.EndDate = &endDate
-				subscription.NextBillingDate = &endDate
+				startSubscriptionPeriod(subscription, now, renewal)
 				subscription.PaymentMethod = paymentMethod
 
 				if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
@@ -549,6 +562,7 @@ func (s *subscriptionService) ProcessPayment(ctx context.Context, orderID string
 				}
 
 				logger.Info("Subscription activated/upgraded: tenant=%s, plan=%s", transaction.TenantID, subscription.PlanID)
+				s.notifyReactivated(ctx, subscription, previousStatus)
 			}
 		}
 
@@ -594,6 +608,64 @@ func (s *subscriptionService) ProcessPayment(ctx context.Context, orderID string
 	return nil
 }
 
+func (s *subscriptionService) ParseGatewayNotification(ctx context.Context, gatewayName string, header http.Header, body []byte) (*payment.Notification, error) {
+	gateway := s.gateways.Get(gatewayName)
+	if gateway == nil {
+		return nil, errors.NewWithDetails("PAY_4002", "Payment gateway not configured", 404, nil)
+	}
+
+	notification, err := gateway.ParseNotification(header, body)
+	if err == payment.ErrInvalidSignature {
+		logger.Warn("Rejected %s notification with invalid signature", gatewayName)
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.NewWithDetails("VAL_2001", "Invalid notification data", 400, nil)
+	}
+	return notification, nil
+}
+
+func (s *subscriptionService) ApplyGatewayNotification(ctx context.Context, notification *payment.Notification) error {
+	// Refunds are recorded by the admin refund workflow that requested them
+	if notification.Status == payment.StatusRefunded {
+		logger.Info("Ignoring refund notification for order %s", notification.OrderID)
+		return nil
+	}
+
+	// Subscriptions only track pending, paid and failed payments
+	status := notification.Status
+	if status == payment.StatusExpired {
+		status = "failed"
+	}
+	return s.ProcessPayment(ctx, notification.OrderID, status, notification.PaymentType, notification.TransactionID)
+}
+
+// notifyReactivated tells the tenant that a paid renewal restored a
+// subscription the dunning job had put on hold or made read-only
+func (s *subscriptionService) notifyReactivated(ctx context.Context, subscription *entity.TenantSubscription, previousStatus string) {
+	switch previousStatus {
+	case entity.SubscriptionStatusPastDue, entity.SubscriptionStatusSuspended, entity.SubscriptionStatusExpired, entity.SubscriptionStatusReadOnly:
+	default:
+		return
+	}
+	if s.notificationService == nil || s.templates == nil {
+		return
+	}
+
+	planName := ""
+	if plan, err := s.planRepo.FindByID(ctx, subscription.PlanID); err == nil && plan != nil {
+		planName = plan.Name
+	}
+	notification, err := NewSubscriptionNotification(ctx, s.templates, subscription.TenantID, planName, subscription.Status, 0)
+	if err != nil {
+		logger.Error("Failed to render subscription notification for tenant %s: %v", subscription.TenantID, err)
+		return
+	}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		logger.Error("Failed to notify tenant %s: %v", subscription.TenantID, err)
+	}
+}
+
 func generateOrderID() string {
 	return fmt.Sprintf("ORD-%d", time.Now().Unix())
 }
@@ -0,0 +1,890 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/pdf"
+	"github.com/rtrwnet/saas-backend/pkg/telegram"
+)
+
+// TelegramBotService runs each tenant's own Telegram bot
+// (TenantSettings.TelegramBotToken). Users of the tenant link their chat with
+// a code from the dashboard; the bot then pushes them new tickets, received
+// payments, failing routers and a daily overdue list, and answers commands
+// with the permissions of their role. Updates arrive by long polling, or on
+// the webhook when a public webhook URL is configured.
+type TelegramBotService interface {
+	// CreateLinkCode gives a user a code to send to the tenant's bot as
+	// "/start <code>" to link their chat
+	CreateLinkCode(ctx context.Context, tenantID, userID string) (*TelegramLink, error)
+	// ListChats returns the tenant's linked chats
+	ListChats(ctx context.Context, tenantID string) ([]*entity.TelegramChat, error)
+	UnlinkChat(ctx context.Context, tenantID, id string) error
+	// HandleUpdate answers a message sent to the tenant's bot
+	HandleUpdate(ctx context.Context, tenantID string, update *telegram.Update) error
+	// HandleWebhook checks the secret token of a webhook call and handles its update
+	HandleWebhook(ctx context.Context, tenantID, secret string, body []byte) error
+	// PollUpdates fetches the bot's pending updates, waiting up to timeout for
+	// one, and handles them
+	PollUpdates(ctx context.Context, tenantID string, timeout time.Duration) error
+	// PushEvents sends linked chats the tenant's tickets, payments and router
+	// failures since the last push, and the overdue list once a day
+	PushEvents(ctx context.Context, tenantID string, now time.Time) error
+	// RunAllTenants sets up the bot of every tenant with Telegram enabled and
+	// pushes their events. It returns the tenants whose bot is running.
+	RunAllTenants(ctx context.Context, now time.Time) ([]string, error)
+}
+
+// TelegramLink is a pending link of a user's chat
+type TelegramLink struct {
+	LinkCode    string    `json:"link_code"`
+	BotUsername string    `json:"bot_username"`
+	Command     string    `json:"command"` // what the user sends to the bot
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+const (
+	telegramLinkCodeTTL   = 15 * time.Minute
+	telegramPollTimeout   = 25 * time.Second
+	telegramOverdueLimit  = 20
+	telegramSearchLimit   = 10
+	telegramMessageLength = 4000 // Telegram allows 4096 characters
+)
+
+// telegramCommand is a bot command and the roles allowed to run it. Like
+// the matching API routes, it needs a plan feature and, when it changes
+// data, a tenant that is not read-only.
+type telegramCommand struct {
+	usage       string
+	description string
+	roles       []string
+	args        int // number of arguments, -1 for free text
+	feature     func(features *dto.PlanFeatures) bool
+	writes      bool
+	run         func(s *telegramBotService, ctx context.Context, chat *entity.TelegramChat, args []string) string
+}
+
+func customerManagement(features *dto.PlanFeatures) bool { return features.CustomerManagement }
+func billingManagement(features *dto.PlanFeatures) bool  { return features.BillingManagement }
+
+// TenantPlanLimits returns the plan limits of a tenant's subscription,
+// implemented by DashboardService
+type TenantPlanLimits interface {
+	GetPlanLimits(ctx context.Context, tenantID string) (*dto.PlanLimitsResponse, error)
+}
+
+var telegramCommands = map[string]*telegramCommand{
+	"cari": {
+		usage:       "/cari <nama>",
+		description: "cari pelanggan",
+		roles:       []string{entity.RoleAdmin, entity.RoleOperator, entity.RoleTechnician, entity.RoleViewer},
+		args:        -1,
+		feature:     customerManagement,
+		run:         (*telegramBotService).searchCommand,
+	},
+	"status": {
+		usage:       "/status <kode_pelanggan>",
+		description: "status layanan dan tagihan pelanggan",
+		roles:       []string{entity.RoleAdmin, entity.RoleOperator, entity.RoleTechnician, entity.RoleViewer},
+		args:        1,
+		feature:     customerManagement,
+		run:         (*telegramBotService).statusCommand,
+	},
+	"isolir": {
+		usage:       "/isolir <kode_pelanggan>",
+		description: "isolir layanan pelanggan",
+		roles:       []string{entity.RoleAdmin, entity.RoleOperator},
+		args:        1,
+		feature:     customerManagement,
+		writes:      true,
+		run:         (*telegramBotService).suspendCommand,
+	},
+	"bayar": {
+		usage:       "/bayar <kode_pelanggan> <jumlah>",
+		description: "catat pembayaran tunai ke tagihan terlama",
+		roles:       []string{entity.RoleAdmin, entity.RoleOperator},
+		args:        2,
+		feature:     billingManagement,
+		writes:      true,
+		run:         (*telegramBotService).payCommand,
+	},
+}
+
+// telegramCommandOrder lists the commands in /bantuan
+var telegramCommandOrder = []string{"cari", "status", "isolir", "bayar"}
+
+// Roles that receive each kind of pushed event
+var (
+	telegramTicketRoles  = []string{entity.RoleAdmin, entity.RoleOperator, entity.RoleTechnician}
+	telegramPaymentRoles = []string{entity.RoleAdmin, entity.RoleOperator}
+	telegramRouterRoles  = []string{entity.RoleAdmin, entity.RoleOperator, entity.RoleTechnician}
+	telegramOverdueRoles = []string{entity.RoleAdmin, entity.RoleOperator}
+)
+
+type telegramBotService struct {
+	telegramRepo   repository.TelegramRepository
+	settingsRepo   repository.SettingsRepository
+	tenantRepo     repository.TenantRepository
+	client         *telegram.Client
+	invoiceService InvoiceService
+	customers      CustomerStatusChanger
+	plans          TenantPlanLimits
+	templates      NotificationTemplateService
+	webhookBaseURL string
+	overdueHour    int
+}
+
+// NewTelegramBotService creates the Telegram bot service. With a
+// webhookBaseURL the bots get their updates on
+// <webhookBaseURL>/webhooks/telegram/<tenant_id>, otherwise by long polling.
+// The overdue list is pushed once a day from overdueHour.
+func NewTelegramBotService(
+	telegramRepo repository.TelegramRepository,
+	settingsRepo repository.SettingsRepository,
+	tenantRepo repository.TenantRepository,
+	client *telegram.Client,
+	invoiceService InvoiceService,
+	customers CustomerStatusChanger,
+	plans TenantPlanLimits,
+	templates NotificationTemplateService,
+	webhookBaseURL string,
+	overdueHour int,
+) TelegramBotService {
+	return &telegramBotService{
+		telegramRepo:   telegramRepo,
+		settingsRepo:   settingsRepo,
+		tenantRepo:     tenantRepo,
+		client:         client,
+		invoiceService: invoiceService,
+		customers:      customers,
+		plans:          plans,
+		templates:      templates,
+		webhookBaseURL: strings.TrimRight(webhookBaseURL, "/"),
+		overdueHour:    overdueHour,
+	}
+}
+
+// telegramEnabled reports whether the tenant runs a Telegram bot
+func telegramEnabled(settings *entity.TenantSettings) bool {
+	return settings.TelegramEnabled && settings.TelegramBotToken != ""
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// settings returns the tenant's settings when the bot is enabled
+func (s *telegramBotService) settings(ctx context.Context, tenantID string) (*entity.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err != nil && err != errors.ErrNotFound {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	if settings == nil || !telegramEnabled(settings) {
+		return nil, errors.NewValidationError("Telegram bot is not enabled, set it up in the integration settings")
+	}
+	return settings, nil
+}
+
+// loadBot returns the tenant's bot state. A new or changed token is checked
+// with getMe and starts over with a fresh offset and webhook secret.
+func (s *telegramBotService) loadBot(ctx context.Context, tenantID string, settings *entity.TenantSettings) (*entity.TelegramBot, error) {
+	bot, err := s.telegramRepo.FindBot(ctx, tenantID)
+	if err == errors.ErrNotFound {
+		bot = &entity.TelegramBot{TenantID: tenantID}
+	} else if err != nil {
+		return nil, errors.NewDatabaseError("load telegram bot", err)
+	}
+
+	sum := sha256.Sum256([]byte(settings.TelegramBotToken))
+	tokenHash := hex.EncodeToString(sum[:])
+	if bot.TokenHash == tokenHash {
+		return bot, nil
+	}
+
+	me, err := s.client.GetMe(ctx, settings.TelegramBotToken)
+	if err != nil {
+		return nil, errors.New("TELEGRAM_BOT_ERROR", "Telegram rejected the bot token: "+err.Error(), 502)
+	}
+	bot.TokenHash = tokenHash
+	bot.BotUsername = me.Username
+	bot.UpdateOffset = 0
+	bot.WebhookURL = ""
+	bot.WebhookSecret = randomHex(24)
+	bot.LastError = ""
+	if err := s.telegramRepo.SaveBot(ctx, bot); err != nil {
+		return nil, errors.NewDatabaseError("save telegram bot", err)
+	}
+	logger.Info("Telegram bot @%s set up for tenant %s", me.Username, tenantID)
+	return bot, nil
+}
+
+func (s *telegramBotService) CreateLinkCode(ctx context.Context, tenantID, userID string) (*TelegramLink, error) {
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	bot, err := s.loadBot(ctx, tenantID, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := s.telegramRepo.FindChatByUser(ctx, tenantID, userID)
+	if err == errors.ErrNotFound {
+		chat = &entity.TelegramChat{TenantID: tenantID, UserID: userID}
+	} else if err != nil {
+		return nil, errors.NewDatabaseError("find telegram chat", err)
+	}
+
+	code := strings.ToUpper(randomHex(4))
+	expiresAt := time.Now().Add(telegramLinkCodeTTL)
+	chat.LinkCode = &code
+	chat.LinkCodeExpiresAt = &expiresAt
+	chat.User = nil
+	if err := s.telegramRepo.SaveChat(ctx, chat); err != nil {
+		return nil, errors.NewDatabaseError("save telegram chat", err)
+	}
+
+	return &TelegramLink{
+		LinkCode:    code,
+		BotUsername: bot.BotUsername,
+		Command:     "/start " + code,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+func (s *telegramBotService) ListChats(ctx context.Context, tenantID string) ([]*entity.TelegramChat, error) {
+	chats, err := s.telegramRepo.FindLinkedChats(ctx, tenantID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list telegram chats", err)
+	}
+	return chats, nil
+}
+
+func (s *telegramBotService) UnlinkChat(ctx context.Context, tenantID, id string) error {
+	err := s.telegramRepo.DeleteChat(ctx, tenantID, id)
+	if err == errors.ErrNotFound {
+		return errors.NewNotFoundError("telegram chat not found")
+	}
+	if err != nil {
+		return errors.NewDatabaseError("delete telegram chat", err)
+	}
+	return nil
+}
+
+func (s *telegramBotService) HandleWebhook(ctx context.Context, tenantID, secret string, body []byte) error {
+	bot, err := s.telegramRepo.FindBot(ctx, tenantID)
+	if err != nil && err != errors.ErrNotFound {
+		return errors.NewDatabaseError("load telegram bot", err)
+	}
+	if bot == nil || bot.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(bot.WebhookSecret)) != 1 {
+		return errors.NewUnauthorizedError("invalid telegram secret token")
+	}
+
+	var update telegram.Update
+	if err := json.Unmarshal(body, &update); err != nil {
+		return errors.NewValidationError("invalid telegram update")
+	}
+	// Telegram redelivers updates it could not hand over
+	if update.UpdateID < bot.UpdateOffset {
+		return nil
+	}
+	// A failed reply is not retried, the command may already have run
+	if err := s.HandleUpdate(ctx, tenantID, &update); err != nil {
+		logger.Error("Telegram update %d of tenant %s failed: %v", update.UpdateID, tenantID, err)
+	}
+	if err := s.telegramRepo.SetUpdateOffset(ctx, tenantID, update.UpdateID+1); err != nil {
+		logger.Error("Failed to save telegram update offset of tenant %s: %v", tenantID, err)
+	}
+	return nil
+}
+
+func (s *telegramBotService) PollUpdates(ctx context.Context, tenantID string, timeout time.Duration) error {
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	bot, err := s.loadBot(ctx, tenantID, settings)
+	if err != nil {
+		return err
+	}
+
+	updates, err := s.client.GetUpdates(ctx, settings.TelegramBotToken, bot.UpdateOffset, timeout)
+	if err != nil {
+		return fmt.Errorf("get telegram updates: %w", err)
+	}
+	for i := range updates {
+		if err := s.HandleUpdate(ctx, tenantID, &updates[i]); err != nil {
+			logger.Error("Telegram update %d of tenant %s failed: %v", updates[i].UpdateID, tenantID, err)
+		}
+		if err := s.telegramRepo.SetUpdateOffset(ctx, tenantID, updates[i].UpdateID+1); err != nil {
+			return errors.NewDatabaseError("save telegram update offset", err)
+		}
+	}
+	return nil
+}
+
+func (s *telegramBotService) HandleUpdate(ctx context.Context, tenantID string, update *telegram.Update) error {
+	msg := update.Message
+	// Commands run with the linked user's role, so only private chats count
+	if msg == nil || msg.Text == "" || msg.Chat.Type != "private" {
+		return nil
+	}
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	reply := s.answer(ctx, tenantID, msg)
+	if _, err := s.client.SendMessage(ctx, settings.TelegramBotToken, msg.Chat.ID, reply); err != nil {
+		return fmt.Errorf("send telegram reply: %w", err)
+	}
+	return nil
+}
+
+// answer runs the command of a message and returns the reply
+func (s *telegramBotService) answer(ctx context.Context, tenantID string, msg *telegram.Message) string {
+	name, args := telegram.ParseCommand(msg.Text)
+	if name == "start" && len(args) > 0 {
+		return s.link(ctx, tenantID, msg, args[0])
+	}
+
+	chat, err := s.telegramRepo.FindChatByChatID(ctx, tenantID, msg.Chat.ID)
+	if err == errors.ErrNotFound {
+		return "Chat ini belum terhubung. Buat kode di dashboard (Pengaturan > Telegram), lalu kirim /start <kode> ke bot ini."
+	}
+	if err != nil {
+		logger.Error("Failed to find telegram chat %d of tenant %s: %v", msg.Chat.ID, tenantID, err)
+		return "Terjadi kesalahan, coba lagi nanti."
+	}
+	if chat.User == nil || !chat.User.IsActive {
+		return "Akun Anda tidak aktif."
+	}
+
+	switch name {
+	case "", "start", "bantuan", "help":
+		return telegramHelp(chat.User)
+	}
+	command, ok := telegramCommands[name]
+	if !ok {
+		return "Perintah tidak dikenal. Ketik /bantuan untuk daftar perintah."
+	}
+	if !hasRole(command.roles, chat.User.Role) {
+		return fmt.Sprintf("Peran %s tidak boleh menjalankan /%s.", chat.User.Role, name)
+	}
+	if (command.args < 0 && len(args) == 0) || (command.args >= 0 && len(args) != command.args) {
+		return "Gunakan: " + command.usage
+	}
+	if reply := s.checkPlan(ctx, tenantID, name, command); reply != "" {
+		return reply
+	}
+	return command.run(s, ctx, chat, args)
+}
+
+// checkPlan returns the reply when the tenant's plan does not allow the
+// command, or an empty string
+func (s *telegramBotService) checkPlan(ctx context.Context, tenantID, name string, command *telegramCommand) string {
+	limits, err := s.plans.GetPlanLimits(ctx, tenantID)
+	if err == errors.ErrSubscriptionRequired || err == errors.ErrInvalidPlan {
+		return "Langganan tidak aktif. Pilih paket di dashboard untuk memakai bot."
+	}
+	if err != nil {
+		logger.Error("Failed to get plan limits of tenant %s: %v", tenantID, err)
+		return "Terjadi kesalahan, coba lagi nanti."
+	}
+	if !command.feature(&limits.Features) {
+		return fmt.Sprintf("Paket %s tidak menyediakan /%s. Upgrade paket untuk memakai perintah ini.", limits.PlanName, name)
+	}
+	if command.writes && limits.ReadOnly {
+		return "Masa trial sudah berakhir dan akun hanya bisa dilihat. Pilih paket di dashboard untuk melanjutkan."
+	}
+	return ""
+}
+
+// link connects the chat to the user that created the link code
+func (s *telegramBotService) link(ctx context.Context, tenantID string, msg *telegram.Message, code string) string {
+	chat, err := s.telegramRepo.FindChatByLinkCode(ctx, tenantID, strings.ToUpper(code))
+	if err == nil && (chat.LinkCodeExpiresAt == nil || time.Now().After(*chat.LinkCodeExpiresAt)) {
+		err = errors.ErrNotFound
+	}
+	if err == errors.ErrNotFound {
+		return "Kode tidak valid atau sudah kedaluwarsa. Buat kode baru di dashboard."
+	}
+	if err != nil {
+		logger.Error("Failed to find telegram link code of tenant %s: %v", tenantID, err)
+		return "Terjadi kesalahan, coba lagi nanti."
+	}
+
+	// The Telegram account was linked to another user before
+	if previous, err := s.telegramRepo.FindChatByChatID(ctx, tenantID, msg.Chat.ID); err == nil && previous.ID != chat.ID {
+		if err := s.telegramRepo.DeleteChat(ctx, tenantID, previous.ID); err != nil {
+			logger.Error("Failed to unlink telegram chat %s: %v", previous.ID, err)
+			return "Terjadi kesalahan, coba lagi nanti."
+		}
+	}
+
+	now := time.Now()
+	chatID := msg.Chat.ID
+	chat.ChatID = &chatID
+	chat.Username = msg.Chat.Username
+	chat.LinkCode = nil
+	chat.LinkCodeExpiresAt = nil
+	chat.LinkedAt = &now
+	user := chat.User
+	chat.User = nil
+	if err := s.telegramRepo.SaveChat(ctx, chat); err != nil {
+		logger.Error("Failed to link telegram chat of tenant %s: %v", tenantID, err)
+		return "Terjadi kesalahan, coba lagi nanti."
+	}
+	if user == nil {
+		return "Chat berhasil terhubung."
+	}
+	return fmt.Sprintf("Chat berhasil terhubung dengan akun %s (%s).\n\n%s", user.Name, user.Role, telegramHelp(user))
+}
+
+// telegramHelp lists the commands the user's role may run
+func telegramHelp(user *entity.User) string {
+	lines := []string{"Perintah yang tersedia:"}
+	for _, name := range telegramCommandOrder {
+		command := telegramCommands[name]
+		if hasRole(command.roles, user.Role) {
+			lines = append(lines, fmt.Sprintf("%s - %s", command.usage, command.description))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *telegramBotService) searchCommand(ctx context.Context, chat *entity.TelegramChat, args []string) string {
+	query := strings.Join(args, " ")
+	if len(query) < 2 {
+		return "Kata kunci minimal 2 huruf."
+	}
+	customers, err := s.telegramRepo.SearchCustomers(ctx, chat.TenantID, query, telegramSearchLimit)
+	if err != nil {
+		logger.Error("Telegram customer search failed: %v", err)
+		return "Terjadi kesalahan, coba lagi nanti."
+	}
+	if len(customers) == 0 {
+		return fmt.Sprintf("Tidak ada pelanggan yang cocok dengan \"%s\".", query)
+	}
+
+	lines := []string{fmt.Sprintf("Pelanggan yang cocok dengan \"%s\":", query)}
+	for _, c := range customers {
+		lines = append(lines, fmt.Sprintf("%s - %s (%s) %s", c.CustomerCode, c.Name, c.Status, c.Phone))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findCustomer returns the customer of a code argument, or the reply to send
+func (s *telegramBotService) findCustomer(ctx context.Context, tenantID, code string) (*entity.Customer, string) {
+	customer, err := s.telegramRepo.FindCustomerByCode(ctx, tenantID, code)
+	if err == errors.ErrNotFound {
+		return nil, fmt.Sprintf("Pelanggan dengan kode %s tidak ditemukan.", code)
+	}
+	if err != nil {
+		logger.Error("Telegram customer lookup failed: %v", err)
+		return nil, "Terjadi kesalahan, coba lagi nanti."
+	}
+	return customer, ""
+}
+
+func (s *telegramBotService) statusCommand(ctx context.Context, chat *entity.TelegramChat, args []string) string {
+	customer, reply := s.findCustomer(ctx, chat.TenantID, args[0])
+	if customer == nil {
+		return reply
+	}
+	invoices, err := s.telegramRepo.FindUnpaidInvoices(ctx, chat.TenantID, customer.ID)
+	if err != nil {
+		logger.Error("Telegram invoice lookup failed: %v", err)
+		return "Terjadi kesalahan, coba lagi nanti."
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s - %s", customer.CustomerCode, customer.Name),
+		"Status: " + customer.Status,
+	}
+	if customer.ServicePlan != nil {
+		lines = append(lines, "Paket: "+customer.ServicePlan.Name)
+	}
+	if customer.Phone != "" {
+		lines = append(lines, "Telepon: "+customer.Phone)
+	}
+	if customer.ServiceUntil != nil {
+		lines = append(lines, "Aktif sampai: "+customer.ServiceUntil.Format("02/01/2006"))
+	}
+	if len(invoices) == 0 {
+		lines = append(lines, "Tagihan: lunas")
+	} else {
+		var total float64
+		for _, invoice := range invoices {
+			total += invoice.Balance()
+		}
+		lines = append(lines, fmt.Sprintf("Tagihan: %d belum dibayar, total %s, tertua jatuh tempo %s",
+			len(invoices), pdf.FormatRupiah(total), invoices[0].DueDate.Format("02/01/2006")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *telegramBotService) suspendCommand(ctx context.Context, chat *entity.TelegramChat, args []string) string {
+	customer, reply := s.findCustomer(ctx, chat.TenantID, args[0])
+	if customer == nil {
+		return reply
+	}
+	if customer.Status == entity.CustomerStatusSuspended {
+		return fmt.Sprintf("%s - %s sudah diisolir.", customer.CustomerCode, customer.Name)
+	}
+
+	reason := "Diisolir lewat Telegram oleh " + chat.User.Name
+	if err := s.customers.SuspendCustomer(ctx, chat.TenantID, customer.ID, reason); err != nil {
+		logger.Error("Telegram suspension of customer %s failed: %v", customer.ID, err)
+		return fmt.Sprintf("Gagal mengisolir %s.", customer.CustomerCode)
+	}
+	logger.Info("Customer %s suspended over Telegram by user %s", customer.ID, chat.UserID)
+	return fmt.Sprintf("%s - %s berhasil diisolir.", customer.CustomerCode, customer.Name)
+}
+
+// parseRupiah reads an amount like 150000, 150.000 or Rp150.000
+func parseRupiah(s string) (float64, bool) {
+	s = strings.TrimPrefix(strings.ToLower(s), "rp")
+	s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
+
+func (s *telegramBotService) payCommand(ctx context.Context, chat *entity.TelegramChat, args []string) string {
+	amount, ok := parseRupiah(args[1])
+	if !ok {
+		return "Jumlah tidak valid, contoh: /bayar CUST-001 150000"
+	}
+	customer, reply := s.findCustomer(ctx, chat.TenantID, args[0])
+	if customer == nil {
+		return reply
+	}
+	invoices, err := s.telegramRepo.FindUnpaidInvoices(ctx, chat.TenantID, customer.ID)
+	if err != nil {
+		logger.Error("Telegram invoice lookup failed: %v", err)
+		return "Terjadi kesalahan, coba lagi nanti."
+	}
+	if len(invoices) == 0 {
+		return fmt.Sprintf("%s - %s tidak punya tagihan yang belum dibayar.", customer.CustomerCode, customer.Name)
+	}
+
+	userID := chat.UserID
+	invoice, err := s.invoiceService.AllocatePayment(ctx, chat.TenantID, invoices[0].ID, &entity.PaymentAllocation{
+		Amount:        amount,
+		PaymentMethod: "cash",
+		Reference:     "telegram",
+		Notes:         "Dicatat lewat Telegram oleh " + chat.User.Name,
+		CreatedBy:     &userID,
+	})
+	if err != nil {
+		logger.Error("Telegram payment for customer %s failed: %v", customer.ID, err)
+		return "Gagal mencatat pembayaran: " + err.Error()
+	}
+
+	number := invoice.ID
+	if invoice.InvoiceNumber != nil {
+		number = *invoice.InvoiceNumber
+	}
+	if invoice.Status != entity.PaymentStatusPaid {
+		return fmt.Sprintf("Pembayaran %s dicatat untuk tagihan %s. Sisa tagihan %s.",
+			pdf.FormatRupiah(amount), number, pdf.FormatRupiah(invoice.Balance()))
+	}
+	return fmt.Sprintf("Pembayaran %s dicatat, tagihan %s lunas.", pdf.FormatRupiah(amount), number)
+}
+
+func (s *telegramBotService) PushEvents(ctx context.Context, tenantID string, now time.Time) error {
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	bot, err := s.loadBot(ctx, tenantID, settings)
+	if err != nil {
+		return err
+	}
+
+	// The first run starts the window instead of pushing the tenant's history
+	until := now
+	if bot.EventsPushedAt == nil {
+		if err := s.telegramRepo.SetEventsPushed(ctx, tenantID, until, bot.OverduePushedOn); err != nil {
+			return errors.NewDatabaseError("save telegram push window", err)
+		}
+		return nil
+	}
+	since := *bot.EventsPushedAt
+
+	chats, err := s.telegramRepo.FindLinkedChats(ctx, tenantID)
+	if err != nil {
+		return errors.NewDatabaseError("load telegram chats", err)
+	}
+	push := func(roles []string, text string) {
+		for _, chat := range chats {
+			if chat.User == nil || !hasRole(roles, chat.User.Role) {
+				continue
+			}
+			if _, err := s.client.SendMessage(ctx, settings.TelegramBotToken, *chat.ChatID, limitText(text)); err != nil {
+				logger.Error("Failed to push telegram message to chat %s of tenant %s: %v", chat.ID, tenantID, err)
+			}
+		}
+	}
+
+	tickets, err := s.telegramRepo.FindNewTickets(ctx, tenantID, since, until)
+	if err != nil {
+		return errors.NewDatabaseError("load new tickets", err)
+	}
+	for _, ticket := range tickets {
+		text, err := s.ticketMessage(ctx, settings, ticket)
+		if err != nil {
+			return err
+		}
+		push(telegramTicketRoles, text)
+	}
+
+	allocations, err := s.telegramRepo.FindAllocations(ctx, tenantID, since, until)
+	if err != nil {
+		return errors.NewDatabaseError("load payments", err)
+	}
+	if len(allocations) > 0 {
+		text, err := s.paymentsMessage(ctx, tenantID, allocations)
+		if err != nil {
+			return err
+		}
+		push(telegramPaymentRoles, text)
+	}
+
+	routers, err := s.telegramRepo.FindDownRouters(ctx, tenantID, since, until)
+	if err != nil {
+		return errors.NewDatabaseError("load routers", err)
+	}
+	if len(routers) > 0 {
+		push(telegramRouterRoles, routersMessage(routers))
+	}
+
+	overduePushedOn := bot.OverduePushedOn
+	today := dateOf(now)
+	if now.Hour() >= s.overdueHour && (overduePushedOn == nil || overduePushedOn.Format("2006-01-02") != today.Format("2006-01-02")) {
+		invoices, total, err := s.telegramRepo.FindOverdueInvoices(ctx, tenantID, today, telegramOverdueLimit)
+		if err != nil {
+			return errors.NewDatabaseError("load overdue invoices", err)
+		}
+		if total > 0 {
+			push(telegramOverdueRoles, overdueMessage(invoices, total, today))
+		}
+		overduePushedOn = &today
+	}
+
+	if err := s.telegramRepo.SetEventsPushed(ctx, tenantID, until, overduePushedOn); err != nil {
+		return errors.NewDatabaseError("save telegram push window", err)
+	}
+	return nil
+}
+
+// ticketMessage renders the tenant's ticket_created template for Telegram
+func (s *telegramBotService) ticketMessage(ctx context.Context, settings *entity.TenantSettings, ticket *entity.Ticket) (string, error) {
+	msg, err := s.templates.Render(ctx, settings, entity.NotificationEventTicketCreated, entity.NotificationChannelTelegram, ticketTemplateVars(ticket))
+	if err != nil {
+		return "", err
+	}
+	return msg.Body, nil
+}
+
+func (s *telegramBotService) paymentsMessage(ctx context.Context, tenantID string, allocations []*entity.PaymentAllocation) (string, error) {
+	ids := make([]string, 0, len(allocations))
+	for _, a := range allocations {
+		ids = append(ids, a.CustomerID)
+	}
+	customers, err := s.telegramRepo.FindCustomers(ctx, tenantID, ids)
+	if err != nil {
+		return "", errors.NewDatabaseError("load customers", err)
+	}
+	byID := make(map[string]*entity.Customer, len(customers))
+	for _, c := range customers {
+		byID[c.ID] = c
+	}
+
+	lines := []string{"Pembayaran diterima:"}
+	for _, a := range allocations {
+		name := a.CustomerID
+		if c := byID[a.CustomerID]; c != nil {
+			name = c.CustomerCode + " - " + c.Name
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (%s)", name, pdf.FormatRupiah(a.Amount), a.PaymentMethod))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func routersMessage(routers []*entity.Device) string {
+	lines := []string{"Router tidak terhubung:"}
+	for _, d := range routers {
+		lines = append(lines, fmt.Sprintf("%s (%s) %s", d.DeviceName, d.IPAddress, d.ConnectionStatus))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func overdueMessage(invoices []*entity.Payment, total int64, today time.Time) string {
+	lines := []string{fmt.Sprintf("Tagihan lewat jatuh tempo per %s: %d", today.Format("02/01/2006"), total)}
+	for _, invoice := range invoices {
+		name := invoice.CustomerID
+		if invoice.Customer != nil {
+			name = invoice.Customer.CustomerCode + " - " + invoice.Customer.Name
+		}
+		lines = append(lines, fmt.Sprintf("%s %s, jatuh tempo %s", name, pdf.FormatRupiah(invoice.Balance()), invoice.DueDate.Format("02/01/2006")))
+	}
+	if more := total - int64(len(invoices)); more > 0 {
+		lines = append(lines, fmt.Sprintf("dan %d lainnya", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// limitText cuts a message to what Telegram accepts
+func limitText(text string) string {
+	if len(text) <= telegramMessageLength {
+		return text
+	}
+	cut := strings.LastIndex(text[:telegramMessageLength], "\n")
+	if cut <= 0 {
+		cut = telegramMessageLength
+	}
+	return text[:cut] + "\n..."
+}
+
+// setupWebhook points the bot at the webhook, or removes it when polling
+func (s *telegramBotService) setupWebhook(ctx context.Context, settings *entity.TenantSettings, bot *entity.TelegramBot) error {
+	want := ""
+	if s.webhookBaseURL != "" {
+		want = s.webhookBaseURL + "/webhooks/telegram/" + bot.TenantID
+	}
+	if bot.WebhookURL == want {
+		return nil
+	}
+
+	var err error
+	if want == "" {
+		err = s.client.DeleteWebhook(ctx, settings.TelegramBotToken)
+	} else {
+		err = s.client.SetWebhook(ctx, settings.TelegramBotToken, want, bot.WebhookSecret)
+	}
+	if err != nil {
+		return fmt.Errorf("set telegram webhook: %w", err)
+	}
+	bot.WebhookURL = want
+	if err := s.telegramRepo.SaveBot(ctx, bot); err != nil {
+		return errors.NewDatabaseError("save telegram bot", err)
+	}
+	return nil
+}
+
+func (s *telegramBotService) RunAllTenants(ctx context.Context, now time.Time) ([]string, error) {
+	tenants, err := s.tenantRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError("load tenants", err)
+	}
+
+	var running []string
+	for _, tenant := range tenants {
+		if !tenant.IsActive {
+			continue
+		}
+		settings, err := s.settingsRepo.GetTenantSettings(ctx, tenant.ID)
+		if err != nil || settings == nil || !telegramEnabled(settings) {
+			continue
+		}
+		bot, err := s.loadBot(ctx, tenant.ID, settings)
+		if err == nil {
+			err = s.setupWebhook(ctx, settings, bot)
+		}
+		if err != nil {
+			logger.Error("Telegram bot of tenant %s is not running: %v", tenant.ID, err)
+			continue
+		}
+		running = append(running, tenant.ID)
+
+		if err := s.PushEvents(ctx, tenant.ID, now); err != nil {
+			logger.Error("Telegram events of tenant %s failed: %v", tenant.ID, err)
+		}
+	}
+	return running, nil
+}
+
+// StartTelegramBotJob runs RunAllTenants now and then on every interval. With
+// polling it also keeps one long polling loop running per tenant bot.
+func StartTelegramBotJob(service TelegramBotService, interval time.Duration, polling bool) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		pollers := make(map[string]context.CancelFunc)
+		for {
+			running, err := service.RunAllTenants(context.Background(), time.Now())
+			if err != nil {
+				logger.Error("Telegram bot job error: %v", err)
+			} else if polling {
+				syncTelegramPollers(service, pollers, running)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Telegram bot job started (interval: %s, polling: %t)", interval, polling)
+}
+
+// syncTelegramPollers starts a polling loop for every running tenant bot and
+// stops the loops of the others
+func syncTelegramPollers(service TelegramBotService, pollers map[string]context.CancelFunc, running []string) {
+	keep := make(map[string]bool, len(running))
+	for _, tenantID := range running {
+		keep[tenantID] = true
+		if _, ok := pollers[tenantID]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		pollers[tenantID] = cancel
+		go pollTelegram(ctx, service, tenantID)
+	}
+	for tenantID, cancel := range pollers {
+		if !keep[tenantID] {
+			cancel()
+			delete(pollers, tenantID)
+		}
+	}
+}
+
+func pollTelegram(ctx context.Context, service TelegramBotService, tenantID string) {
+	for ctx.Err() == nil {
+		if err := service.PollUpdates(ctx, tenantID, telegramPollTimeout); err != nil && ctx.Err() == nil {
+			logger.Error("Telegram polling of tenant %s failed: %v", tenantID, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(10 * time.Second):
+			}
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/telegram"
+	"github.com/rtrwnet/saas-backend/pkg/telegram/telegramtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTelegramRepository struct {
+	mock.Mock
+}
+
+func (m *MockTelegramRepository) FindBot(ctx context.Context, tenantID string) (*entity.TelegramBot, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TelegramBot), args.Error(1)
+}
+
+func (m *MockTelegramRepository) SaveBot(ctx context.Context, bot *entity.TelegramBot) error {
+	args := m.Called(ctx, bot)
+	return args.Error(0)
+}
+
+func (m *MockTelegramRepository) SetUpdateOffset(ctx context.Context, tenantID string, offset int64) error {
+	args := m.Called(ctx, tenantID, offset)
+	return args.Error(0)
+}
+
+func (m *MockTelegramRepository) SetEventsPushed(ctx context.Context, tenantID string, until time.Time, overduePushedOn *time.Time) error {
+	args := m.Called(ctx, tenantID, until, overduePushedOn)
+	return args.Error(0)
+}
+
+func (m *MockTelegramRepository) chat(args mock.Arguments) (*entity.TelegramChat, error) {
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TelegramChat), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindChatByUser(ctx context.Context, tenantID, userID string) (*entity.TelegramChat, error) {
+	return m.chat(m.Called(ctx, tenantID, userID))
+}
+
+func (m *MockTelegramRepository) FindChatByLinkCode(ctx context.Context, tenantID, code string) (*entity.TelegramChat, error) {
+	return m.chat(m.Called(ctx, tenantID, code))
+}
+
+func (m *MockTelegramRepository) FindChatByChatID(ctx context.Context, tenantID string, chatID int64) (*entity.TelegramChat, error) {
+	return m.chat(m.Called(ctx, tenantID, chatID))
+}
+
+func (m *MockTelegramRepository) FindLinkedChats(ctx context.Context, tenantID string) ([]*entity.TelegramChat, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]*entity.TelegramChat), args.Error(1)
+}
+
+func (m *MockTelegramRepository) SaveChat(ctx context.Context, chat *entity.TelegramChat) error {
+	args := m.Called(ctx, chat)
+	return args.Error(0)
+}
+
+func (m *MockTelegramRepository) DeleteChat(ctx context.Context, tenantID, id string) error {
+	args := m.Called(ctx, tenantID, id)
+	return args.Error(0)
+}
+
+func (m *MockTelegramRepository) SearchCustomers(ctx context.Context, tenantID, query string, limit int) ([]*entity.Customer, error) {
+	args := m.Called(ctx, tenantID, query, limit)
+	return args.Get(0).([]*entity.Customer), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindCustomerByCode(ctx context.Context, tenantID, code string) (*entity.Customer, error) {
+	args := m.Called(ctx, tenantID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Customer), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindCustomers(ctx context.Context, tenantID string, ids []string) ([]*entity.Customer, error) {
+	args := m.Called(ctx, tenantID, ids)
+	return args.Get(0).([]*entity.Customer), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindUnpaidInvoices(ctx context.Context, tenantID, customerID string) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, customerID)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindNewTickets(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Ticket, error) {
+	args := m.Called(ctx, tenantID, since, until)
+	return args.Get(0).([]*entity.Ticket), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindAllocations(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.PaymentAllocation, error) {
+	args := m.Called(ctx, tenantID, since, until)
+	return args.Get(0).([]*entity.PaymentAllocation), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindDownRouters(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Device, error) {
+	args := m.Called(ctx, tenantID, since, until)
+	return args.Get(0).([]*entity.Device), args.Error(1)
+}
+
+func (m *MockTelegramRepository) FindOverdueInvoices(ctx context.Context, tenantID string, before time.Time, limit int) ([]*entity.Payment, int64, error) {
+	args := m.Called(ctx, tenantID, before, limit)
+	return args.Get(0).([]*entity.Payment), args.Get(1).(int64), args.Error(2)
+}
+
+type MockTenantPlanLimits struct {
+	mock.Mock
+}
+
+func (m *MockTenantPlanLimits) GetPlanLimits(ctx context.Context, tenantID string) (*dto.PlanLimitsResponse, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.PlanLimitsResponse), args.Error(1)
+}
+
+const testBotToken = "123456:test-token"
+
+// telegramFixture runs the service against a fake Bot API with a bot that
+// was already set up for the token
+type telegramFixture struct {
+	srv         *telegramtest.Server
+	repo        *MockTelegramRepository
+	invoiceRepo *MockInvoiceRepository
+	reactivator *MockPaymentReactivator
+	customers   *MockCustomerStatusChanger
+	limits      *dto.PlanLimitsResponse // the tenant's plan, every feature and writable
+	bot         *entity.TelegramBot
+	service     TelegramBotService
+}
+
+func newTelegramFixture(t *testing.T, ctx context.Context) *telegramFixture {
+	srv := telegramtest.NewServer(testBotToken)
+	t.Cleanup(srv.Close)
+
+	sum := sha256.Sum256([]byte(testBotToken))
+	f := &telegramFixture{
+		srv:         srv,
+		repo:        new(MockTelegramRepository),
+		invoiceRepo: new(MockInvoiceRepository),
+		reactivator: new(MockPaymentReactivator),
+		customers:   new(MockCustomerStatusChanger),
+		limits: &dto.PlanLimitsResponse{PlanName: "Pro", Features: dto.PlanFeatures{
+			CustomerManagement: true, BillingManagement: true,
+		}},
+		bot: &entity.TelegramBot{TenantID: "tenant-1", TokenHash: hex.EncodeToString(sum[:]), BotUsername: "test_bot", WebhookSecret: "s3cret"},
+	}
+	settingsRepo := new(MockSettingsRepository)
+	settingsRepo.On("GetTenantSettings", ctx, "tenant-1").Return(&entity.TenantSettings{TelegramEnabled: true, TelegramBotToken: testBotToken}, nil)
+	f.repo.On("FindBot", ctx, "tenant-1").Return(f.bot, nil)
+	plans := new(MockTenantPlanLimits)
+	plans.On("GetPlanLimits", ctx, "tenant-1").Return(f.limits, nil)
+
+	invoiceService := NewInvoiceService(f.invoiceRepo, settingsRepo)
+	invoiceService.SetPaymentReactivator(f.reactivator)
+	f.service = NewTelegramBotService(f.repo, settingsRepo, nil, telegram.NewClient(&telegram.Config{BaseURL: srv.URL}),
+		invoiceService, f.customers, plans, builtinTemplateService(), "", 8)
+	return f
+}
+
+// linkedChat returns the chat of a user with the role, linked to Telegram chat id
+func linkedChat(id int64, role string) *entity.TelegramChat {
+	return &entity.TelegramChat{
+		ID:       fmt.Sprintf("chat-%d", id),
+		TenantID: "tenant-1",
+		UserID:   fmt.Sprintf("user-%d", id),
+		ChatID:   &id,
+		User:     &entity.User{ID: fmt.Sprintf("user-%d", id), Name: "Andi", Role: role, IsActive: true},
+	}
+}
+
+// send hands the fake server's update for a message straight to the service
+func (f *telegramFixture) send(t *testing.T, ctx context.Context, chatID int64, text string) string {
+	update := f.srv.SendText(chatID, "andi", text)
+	require.NoError(t, f.service.HandleUpdate(ctx, "tenant-1", &update))
+	messages := f.srv.Messages(chatID)
+	require.NotEmpty(t, messages)
+	return messages[len(messages)-1]
+}
+
+func TestTelegramBotService_Link(t *testing.T) {
+	ctx := context.Background()
+	f := newTelegramFixture(t, ctx)
+
+	code := "A1B2C3D4"
+	expires := time.Now().Add(time.Minute)
+	pending := &entity.TelegramChat{ID: "chat-1", TenantID: "tenant-1", UserID: "user-1", LinkCode: &code, LinkCodeExpiresAt: &expires,
+		User: &entity.User{ID: "user-1", Name: "Andi", Role: entity.RoleTechnician, IsActive: true}}
+	f.repo.On("FindChatByLinkCode", ctx, "tenant-1", "A1B2C3D4").Return(pending, nil)
+	f.repo.On("FindChatByLinkCode", ctx, "tenant-1", "EXPIRED0").Return(nil, errors.ErrNotFound)
+	f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(555)).Return(nil, errors.ErrNotFound)
+	f.repo.On("SaveChat", ctx, pending).Return(nil)
+
+	reply := f.send(t, ctx, 555, "/start a1b2c3d4")
+
+	assert.Contains(t, reply, "Chat berhasil terhubung dengan akun Andi (technician)")
+	assert.Contains(t, reply, "/status <kode_pelanggan>")
+	assert.NotContains(t, reply, "/isolir", "technicians cannot suspend customers")
+	assert.Equal(t, int64(555), *pending.ChatID)
+	assert.Nil(t, pending.LinkCode)
+	assert.NotNil(t, pending.LinkedAt)
+
+	assert.Contains(t, f.send(t, ctx, 556, "/start EXPIRED0"), "Kode tidak valid")
+}
+
+func TestTelegramBotService_Commands(t *testing.T) {
+	ctx := context.Background()
+	number := "INV-2025-03-0001"
+	customer := &entity.Customer{ID: "c1", TenantID: "tenant-1", CustomerCode: "CUST-1", Name: "Budi", Status: entity.CustomerStatusActive,
+		Phone: "081234567890", ServicePlan: &entity.ServicePlan{Name: "Home 20 Mbps"}}
+	invoice := &entity.Payment{ID: "p1", TenantID: "tenant-1", CustomerID: "c1", InvoiceNumber: &number, Amount: 150000,
+		Status: entity.PaymentStatusOverdue, DueDate: billingDate(2025, 3, 10)}
+
+	t.Run("Not linked", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(9)).Return(nil, errors.ErrNotFound)
+
+		assert.Contains(t, f.send(t, ctx, 9, "/status CUST-1"), "belum terhubung")
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleViewer), nil)
+		f.repo.On("FindCustomerByCode", ctx, "tenant-1", "CUST-1").Return(customer, nil)
+		f.repo.On("FindUnpaidInvoices", ctx, "tenant-1", "c1").Return([]*entity.Payment{invoice}, nil)
+
+		reply := f.send(t, ctx, 1, "/status@test_bot CUST-1")
+
+		assert.Contains(t, reply, "CUST-1 - Budi")
+		assert.Contains(t, reply, "Paket: Home 20 Mbps")
+		assert.Contains(t, reply, "1 belum dibayar, total Rp 150.000, tertua jatuh tempo 10/03/2025")
+		assert.Equal(t, "Gunakan: /status <kode_pelanggan>", f.send(t, ctx, 1, "/status"))
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleTechnician), nil)
+		f.repo.On("SearchCustomers", ctx, "tenant-1", "budi santoso", telegramSearchLimit).Return([]*entity.Customer{customer}, nil)
+
+		assert.Contains(t, f.send(t, ctx, 1, "/cari budi santoso"), "CUST-1 - Budi (active) 081234567890")
+	})
+
+	t.Run("Role not allowed", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleTechnician), nil)
+
+		assert.Equal(t, "Peran technician tidak boleh menjalankan /isolir.", f.send(t, ctx, 1, "/isolir CUST-1"))
+		f.customers.AssertNotCalled(t, "SuspendCustomer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Suspend", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleOperator), nil)
+		f.repo.On("FindCustomerByCode", ctx, "tenant-1", "CUST-1").Return(customer, nil)
+		f.customers.On("SuspendCustomer", ctx, "tenant-1", "c1", "Diisolir lewat Telegram oleh Andi").Return(nil)
+
+		assert.Equal(t, "CUST-1 - Budi berhasil diisolir.", f.send(t, ctx, 1, "/isolir CUST-1"))
+	})
+
+	t.Run("Read-only tenant can only look", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		f.limits.ReadOnly = true
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleAdmin), nil)
+		f.repo.On("FindCustomerByCode", ctx, "tenant-1", "CUST-1").Return(customer, nil)
+		f.repo.On("FindUnpaidInvoices", ctx, "tenant-1", "c1").Return([]*entity.Payment{}, nil)
+
+		assert.Contains(t, f.send(t, ctx, 1, "/isolir CUST-1"), "hanya bisa dilihat")
+		assert.Contains(t, f.send(t, ctx, 1, "/bayar CUST-1 150000"), "hanya bisa dilihat")
+		assert.Contains(t, f.send(t, ctx, 1, "/status CUST-1"), "Tagihan: lunas")
+		f.customers.AssertNotCalled(t, "SuspendCustomer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Feature not in plan", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		f.limits.Features.BillingManagement = false
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleAdmin), nil)
+
+		assert.Equal(t, "Paket Pro tidak menyediakan /bayar. Upgrade paket untuk memakai perintah ini.", f.send(t, ctx, 1, "/bayar CUST-1 150000"))
+		f.repo.AssertNotCalled(t, "FindCustomerByCode", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Pay", func(t *testing.T) {
+		f := newTelegramFixture(t, ctx)
+		paid := *invoice
+		paid.PaidAmount = 150000
+		paid.Status = entity.PaymentStatusPaid
+		f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleAdmin), nil)
+		f.repo.On("FindCustomerByCode", ctx, "tenant-1", "CUST-1").Return(customer, nil)
+		f.repo.On("FindUnpaidInvoices", ctx, "tenant-1", "c1").Return([]*entity.Payment{invoice}, nil)
+		f.invoiceRepo.On("FindInvoice", ctx, "p1").Return(invoice, nil)
+		f.invoiceRepo.On("AddAllocation", ctx, mock.MatchedBy(func(a *entity.PaymentAllocation) bool {
+			return a.Amount == 150000 && a.PaymentMethod == "cash" && *a.CreatedBy == "user-1"
+		})).Return(&paid, nil)
+		f.reactivator.On("HandlePaymentPaid", ctx, &paid).Return(nil)
+
+		reply := f.send(t, ctx, 1, "/bayar CUST-1 Rp150.000")
+
+		assert.Equal(t, "Pembayaran Rp 150.000 dicatat, tagihan INV-2025-03-0001 lunas.", reply)
+		f.reactivator.AssertExpectations(t)
+		assert.Contains(t, f.send(t, ctx, 1, "/bayar CUST-1 lima"), "Jumlah tidak valid")
+	})
+}
+
+func TestTelegramBotService_PollUpdates(t *testing.T) {
+	ctx := context.Background()
+	f := newTelegramFixture(t, ctx)
+	f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleViewer), nil)
+	f.repo.On("SetUpdateOffset", ctx, "tenant-1", mock.Anything).Return(nil)
+
+	f.srv.SendText(1, "andi", "/bantuan")
+	f.srv.SendText(1, "andi", "/hapus CUST-1")
+
+	require.NoError(t, f.service.PollUpdates(ctx, "tenant-1", 0))
+
+	messages := f.srv.Messages(1)
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages[0], "/cari <nama>")
+	assert.Contains(t, messages[1], "Perintah tidak dikenal")
+	f.repo.AssertCalled(t, "SetUpdateOffset", ctx, "tenant-1", int64(3))
+}
+
+func TestTelegramBotService_HandleWebhook(t *testing.T) {
+	ctx := context.Background()
+	f := newTelegramFixture(t, ctx)
+	f.bot.UpdateOffset = 5
+	f.repo.On("FindChatByChatID", ctx, "tenant-1", int64(1)).Return(linkedChat(1, entity.RoleViewer), nil)
+	f.repo.On("SetUpdateOffset", ctx, "tenant-1", int64(6)).Return(nil)
+	body := func(id int) []byte {
+		return []byte(fmt.Sprintf(`{"update_id":%d,"message":{"message_id":1,"chat":{"id":1,"type":"private"},"text":"/bantuan"}}`, id))
+	}
+
+	err := f.service.HandleWebhook(ctx, "tenant-1", "wrong", body(5))
+	require.Error(t, err)
+	assert.Equal(t, 401, err.(*errors.AppError).Status)
+
+	require.NoError(t, f.service.HandleWebhook(ctx, "tenant-1", "s3cret", body(5)))
+	// Redelivered
+	require.NoError(t, f.service.HandleWebhook(ctx, "tenant-1", "s3cret", body(4)))
+
+	assert.Len(t, f.srv.Messages(1), 1)
+}
+
+func TestTelegramBotService_PushEvents(t *testing.T) {
+	ctx := context.Background()
+	lastPush := time.Date(2025, 3, 20, 8, 59, 0, 0, time.Local)
+	now := time.Date(2025, 3, 20, 9, 0, 0, 0, time.Local)
+	today := dateOf(now)
+	budi := &entity.Customer{ID: "c1", CustomerCode: "CUST-1", Name: "Budi"}
+
+	f := newTelegramFixture(t, ctx)
+	f.bot.EventsPushedAt = &lastPush
+	admin, technician, viewer := linkedChat(1, entity.RoleAdmin), linkedChat(2, entity.RoleTechnician), linkedChat(3, entity.RoleViewer)
+	f.repo.On("FindLinkedChats", ctx, "tenant-1").Return([]*entity.TelegramChat{admin, technician, viewer}, nil)
+	f.repo.On("FindNewTickets", ctx, "tenant-1", lastPush, now).Return([]*entity.Ticket{
+		{TicketNumber: "TCK-0001", Title: "Internet mati", Priority: entity.TicketPriorityHigh, Customer: budi},
+	}, nil)
+	f.repo.On("FindAllocations", ctx, "tenant-1", lastPush, now).Return([]*entity.PaymentAllocation{
+		{CustomerID: "c1", Amount: 150000, PaymentMethod: "transfer"},
+	}, nil)
+	f.repo.On("FindCustomers", ctx, "tenant-1", []string{"c1"}).Return([]*entity.Customer{budi}, nil)
+	f.repo.On("FindDownRouters", ctx, "tenant-1", lastPush, now).Return([]*entity.Device{}, nil)
+	f.repo.On("FindOverdueInvoices", ctx, "tenant-1", today, telegramOverdueLimit).Return([]*entity.Payment{
+		{CustomerID: "c1", Amount: 150000, DueDate: billingDate(2025, 3, 10), Customer: budi},
+	}, int64(21), nil)
+	f.repo.On("SetEventsPushed", ctx, "tenant-1", now, &today).Return(nil)
+
+	require.NoError(t, f.service.PushEvents(ctx, "tenant-1", now))
+
+	assert.Equal(t, []string{
+		"Tiket baru TCK-0001 (high)\nInternet mati\nPelanggan: CUST-1 - Budi",
+		"Pembayaran diterima:\nCUST-1 - Budi Rp 150.000 (transfer)",
+		"Tagihan lewat jatuh tempo per 20/03/2025: 21\nCUST-1 - Budi Rp 150.000, jatuh tempo 10/03/2025\ndan 20 lainnya",
+	}, f.srv.Messages(1))
+	assert.Equal(t, []string{"Tiket baru TCK-0001 (high)\nInternet mati\nPelanggan: CUST-1 - Budi"}, f.srv.Messages(2))
+	assert.Empty(t, f.srv.Messages(3))
+	f.repo.AssertExpectations(t)
+
+	// The overdue list went out today already
+	f = newTelegramFixture(t, ctx)
+	f.bot.EventsPushedAt = &lastPush
+	f.bot.OverduePushedOn = &today
+	f.repo.On("FindLinkedChats", ctx, "tenant-1").Return([]*entity.TelegramChat{admin}, nil)
+	f.repo.On("FindNewTickets", ctx, "tenant-1", lastPush, now).Return([]*entity.Ticket{}, nil)
+	f.repo.On("FindAllocations", ctx, "tenant-1", lastPush, now).Return([]*entity.PaymentAllocation{}, nil)
+	f.repo.On("FindDownRouters", ctx, "tenant-1", lastPush, now).Return([]*entity.Device{
+		{DeviceName: "RB-Tower", IPAddress: "10.0.0.1", ConnectionStatus: entity.ConnectionStatusError},
+	}, nil)
+	f.repo.On("SetEventsPushed", ctx, "tenant-1", now, &today).Return(nil)
+
+	require.NoError(t, f.service.PushEvents(ctx, "tenant-1", now))
+
+	assert.Equal(t, []string{"Router tidak terhubung:\nRB-Tower (10.0.0.1) error"}, f.srv.Messages(1))
+	f.repo.AssertNotCalled(t, "FindOverdueInvoices", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTelegramBotService_SetsUpNewToken(t *testing.T) {
+	ctx := context.Background()
+	srv := telegramtest.NewServer(testBotToken)
+	defer srv.Close()
+
+	repo := new(MockTelegramRepository)
+	settingsRepo := new(MockSettingsRepository)
+	tenantRepo := new(MockTenantRepository)
+	settingsRepo.On("GetTenantSettings", ctx, "tenant-1").Return(&entity.TenantSettings{TelegramEnabled: true, TelegramBotToken: testBotToken}, nil)
+	tenantRepo.On("FindAll", ctx).Return([]*entity.Tenant{{ID: "tenant-1", IsActive: true}}, nil)
+	// The tenant replaced an older bot token
+	repo.On("FindBot", ctx, "tenant-1").Return(&entity.TelegramBot{TenantID: "tenant-1", TokenHash: "old", UpdateOffset: 40}, nil)
+	repo.On("SaveBot", ctx, mock.AnythingOfType("*entity.TelegramBot")).Return(nil)
+	repo.On("SetEventsPushed", ctx, "tenant-1", mock.Anything, (*time.Time)(nil)).Return(nil)
+
+	service := NewTelegramBotService(repo, settingsRepo, tenantRepo, telegram.NewClient(&telegram.Config{BaseURL: srv.URL}),
+		nil, nil, nil, nil, "https://api.example.com/", 8)
+	running, err := service.RunAllTenants(ctx, time.Now())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant-1"}, running)
+	bot := repo.Calls[1].Arguments.Get(1).(*entity.TelegramBot)
+	assert.Equal(t, "test_bot", bot.BotUsername)
+	assert.Equal(t, int64(0), bot.UpdateOffset)
+	url, secret := srv.Webhook()
+	assert.Equal(t, "https://api.example.com/webhooks/telegram/tenant-1", url)
+	assert.Equal(t, bot.WebhookSecret, secret)
+	assert.Equal(t, url, bot.WebhookURL)
+}
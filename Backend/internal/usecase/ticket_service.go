@@ -2,12 +2,14 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/rtrwnet/saas-backend/internal/domain/entity"
 	"github.com/rtrwnet/saas-backend/internal/domain/repository"
 	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
 )
 
 type TicketService interface {
@@ -22,14 +24,21 @@ type TicketService interface {
 }
 
 type ticketService struct {
-	ticketRepo   repository.TicketRepository
-	customerRepo repository.CustomerRepository
+	ticketRepo          repository.TicketRepository
+	customerRepo        repository.CustomerRepository
+	notificationService NotificationService
+	templates           NotificationTemplateService
 }
 
-func NewTicketService(ticketRepo repository.TicketRepository, customerRepo repository.CustomerRepository) TicketService {
+// NewTicketService creates the ticket service. With a notification service
+// and templates, the tenant's users are told of new tickets in the app from
+// the tenant's ticket_created template.
+func NewTicketService(ticketRepo repository.TicketRepository, customerRepo repository.CustomerRepository, notificationService NotificationService, templates NotificationTemplateService) TicketService {
 	return &ticketService{
-		ticketRepo:   ticketRepo,
-		customerRepo: customerRepo,
+		ticketRepo:          ticketRepo,
+		customerRepo:        customerRepo,
+		notificationService: notificationService,
+		templates:           templates,
 	}
 }
 
@@ -91,9 +100,38 @@ func (s *ticketService) CreateTicket(ctx context.Context, tenantID, customerID,
 	}
 	_ = s.ticketRepo.CreateActivity(ctx, activity)
 
+	if s.notificationService != nil && s.templates != nil {
+		ticket.Customer = customer
+		s.notifyCreated(ctx, ticket)
+	}
+
 	return ticket, nil
 }
 
+// notifyCreated creates the in-app notification of a new ticket for every
+// user of the tenant. Failures are logged, the ticket is created regardless.
+func (s *ticketService) notifyCreated(ctx context.Context, ticket *entity.Ticket) {
+	msg, err := s.templates.RenderTenant(ctx, ticket.TenantID, entity.NotificationEventTicketCreated, entity.NotificationChannelInApp, ticketTemplateVars(ticket))
+	if err != nil {
+		logger.Error("Failed to render notification of ticket %s: %v", ticket.ID, err)
+		return
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"ticket_id":     ticket.ID,
+		"ticket_number": ticket.TicketNumber,
+	})
+	notification := &entity.Notification{
+		TenantID: ticket.TenantID,
+		Type:     entity.NotificationTypeTicket,
+		Title:    msg.Subject,
+		Message:  msg.Body,
+		Data:     string(data),
+	}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		logger.Error("Failed to create notification of ticket %s: %v", ticket.ID, err)
+	}
+}
+
 func (s *ticketService) UpdateTicket(ctx context.Context, tenantID, ticketID, userID string, req *UpdateTicketRequest) (*entity.Ticket, error) {
 	ticket, err := s.ticketRepo.GetByID(ctx, ticketID)
 	if err != nil {
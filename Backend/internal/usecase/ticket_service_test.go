@@ -134,7 +134,7 @@ func (m *MockCustomerRepository) GenerateCustomerCode(ctx context.Context, tenan
 func TestTicketService_CreateTicket(t *testing.T) {
 	mockTicketRepo := new(MockTicketRepository)
 	mockCustomerRepo := new(MockCustomerRepository)
-	service := NewTicketService(mockTicketRepo, mockCustomerRepo)
+	service := NewTicketService(mockTicketRepo, mockCustomerRepo, nil, nil)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -187,7 +187,7 @@ func TestTicketService_CreateTicket(t *testing.T) {
 func TestTicketService_AssignTicket(t *testing.T) {
 	mockTicketRepo := new(MockTicketRepository)
 	mockCustomerRepo := new(MockCustomerRepository)
-	service := NewTicketService(mockTicketRepo, mockCustomerRepo)
+	service := NewTicketService(mockTicketRepo, mockCustomerRepo, nil, nil)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -227,7 +227,7 @@ func TestTicketService_AssignTicket(t *testing.T) {
 func TestTicketService_ResolveTicket(t *testing.T) {
 	mockTicketRepo := new(MockTicketRepository)
 	mockCustomerRepo := new(MockCustomerRepository)
-	service := NewTicketService(mockTicketRepo, mockCustomerRepo)
+	service := NewTicketService(mockTicketRepo, mockCustomerRepo, nil, nil)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -258,7 +258,7 @@ func TestTicketService_ResolveTicket(t *testing.T) {
 func TestTicketService_ListTickets(t *testing.T) {
 	mockTicketRepo := new(MockTicketRepository)
 	mockCustomerRepo := new(MockCustomerRepository)
-	service := NewTicketService(mockTicketRepo, mockCustomerRepo)
+	service := NewTicketService(mockTicketRepo, mockCustomerRepo, nil, nil)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
@@ -298,7 +298,7 @@ func TestTicketService_ListTickets(t *testing.T) {
 func TestTicketService_GetTicketByID(t *testing.T) {
 	mockTicketRepo := new(MockTicketRepository)
 	mockCustomerRepo := new(MockCustomerRepository)
-	service := NewTicketService(mockTicketRepo, mockCustomerRepo)
+	service := NewTicketService(mockTicketRepo, mockCustomerRepo, nil, nil)
 
 	ctx := context.Background()
 	tenantID := "tenant-123"
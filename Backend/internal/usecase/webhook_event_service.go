@@ -0,0 +1,338 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/payment"
+)
+
+// WebhookEventService stores every inbound payment webhook before processing
+// it, applies each (order_id, transaction_status) pair once and lets admins
+// replay events that failed.
+type WebhookEventService interface {
+	// Receive stores, verifies and processes a webhook from the named source
+	Receive(ctx context.Context, source, tenantID string, header http.Header, body []byte) error
+	ListEvents(ctx context.Context, filter repository.WebhookEventFilter) (*WebhookEventListResponse, error)
+	GetEvent(ctx context.Context, id string) (*entity.WebhookEvent, error)
+	// Replay processes a failed event again from its stored payment update
+	Replay(ctx context.Context, id string) (*entity.WebhookEvent, error)
+}
+
+// WebhookProcessor verifies and applies the webhooks of one source
+type WebhookProcessor interface {
+	// Signed reports whether the source signs its webhooks
+	Signed() bool
+	// Parse verifies a webhook and returns the payment update it carries. It
+	// returns payment.ErrInvalidSignature when the webhook is not authentic.
+	Parse(ctx context.Context, tenantID string, header http.Header, body []byte) (*payment.Notification, error)
+	// Apply processes a verified payment update
+	Apply(ctx context.Context, tenantID string, notification *payment.Notification) error
+}
+
+type WebhookEventListResponse struct {
+	Events  []*entity.WebhookEvent `json:"events"`
+	Total   int64                  `json:"total"`
+	Page    int                    `json:"page"`
+	PerPage int                    `json:"per_page"`
+}
+
+// Headers that carry credentials are not stored
+var redactedWebhookHeaders = map[string]bool{
+	"Authorization":    true,
+	"Cookie":           true,
+	"X-Callback-Token": true,
+}
+
+type webhookEventService struct {
+	eventRepo  repository.WebhookEventRepository
+	processors map[string]WebhookProcessor
+}
+
+func NewWebhookEventService(eventRepo repository.WebhookEventRepository, processors map[string]WebhookProcessor) WebhookEventService {
+	return &webhookEventService{
+		eventRepo:  eventRepo,
+		processors: processors,
+	}
+}
+
+func (s *webhookEventService) Receive(ctx context.Context, source, tenantID string, header http.Header, body []byte) error {
+	processor, ok := s.processors[source]
+	if !ok {
+		return errors.NewWithDetails("PAY_4002", "Payment gateway not configured", 404, nil)
+	}
+
+	event := &entity.WebhookEvent{
+		Source:  source,
+		Headers: webhookHeaders(header),
+		Body:    string(body),
+		Status:  entity.WebhookEventReceived,
+	}
+	if tenantID != "" {
+		event.TenantID = &tenantID
+	}
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		return errors.NewDatabaseError("store webhook event", err)
+	}
+
+	notification, err := processor.Parse(ctx, tenantID, header, body)
+	if err != nil {
+		event.Status = entity.WebhookEventRejected
+		event.Error = err.Error()
+		if err == payment.ErrInvalidSignature {
+			valid := false
+			event.SignatureValid = &valid
+			err = errors.NewWithDetails("PAY_4005", "Invalid signature", 401, nil)
+		}
+		s.save(ctx, event)
+		return err
+	}
+
+	if processor.Signed() {
+		valid := true
+		event.SignatureValid = &valid
+	}
+	event.OrderID = notification.OrderID
+	event.TransactionStatus = notification.GatewayStatus
+	event.PaymentStatus = notification.Status
+	event.PaymentType = notification.PaymentType
+	event.TransactionID = notification.TransactionID
+	event.Amount = notification.Amount
+
+	return s.process(ctx, event, processor)
+}
+
+// process applies an event unless another event already applied the same
+// order and transaction status
+func (s *webhookEventService) process(ctx context.Context, event *entity.WebhookEvent, processor WebhookProcessor) error {
+	key := webhookIdempotencyKey(event)
+	claimed, err := s.eventRepo.ClaimIdempotencyKey(ctx, event.ID, key)
+	if err != nil {
+		return errors.NewDatabaseError("claim webhook event", err)
+	}
+	if !claimed {
+		logger.Info("Skipping duplicate %s webhook: order=%s, status=%s", event.Source, event.OrderID, event.TransactionStatus)
+		event.Status = entity.WebhookEventDuplicate
+		event.Error = ""
+		s.save(ctx, event)
+		return nil
+	}
+	event.IdempotencyKey = &key
+	event.Attempts++
+
+	tenantID := ""
+	if event.TenantID != nil {
+		tenantID = *event.TenantID
+	}
+	if err := processor.Apply(ctx, tenantID, notificationOf(event)); err != nil {
+		logger.Error("Failed to process %s webhook %s: %v", event.Source, event.ID, err)
+		// Release the key so a retry from the gateway or a replay can apply it
+		event.IdempotencyKey = nil
+		event.Status = entity.WebhookEventFailed
+		event.Error = err.Error()
+		s.save(ctx, event)
+		return err
+	}
+
+	now := time.Now()
+	event.Status = entity.WebhookEventProcessed
+	event.Error = ""
+	event.ProcessedAt = &now
+	s.save(ctx, event)
+	return nil
+}
+
+// save records the outcome of an event; the outcome stands even when it
+// cannot be recorded
+func (s *webhookEventService) save(ctx context.Context, event *entity.WebhookEvent) {
+	if err := s.eventRepo.Update(ctx, event); err != nil {
+		logger.Error("Failed to update webhook event %s: %v", event.ID, err)
+	}
+}
+
+func (s *webhookEventService) ListEvents(ctx context.Context, filter repository.WebhookEventFilter) (*WebhookEventListResponse, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = 10
+	}
+
+	events, total, err := s.eventRepo.List(ctx, filter)
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to list webhook events")
+	}
+
+	return &WebhookEventListResponse{
+		Events:  events,
+		Total:   total,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+	}, nil
+}
+
+func (s *webhookEventService) GetEvent(ctx context.Context, id string) (*entity.WebhookEvent, error) {
+	event, err := s.eventRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewDatabaseError("find webhook event", err)
+	}
+	if event == nil {
+		return nil, errors.NewNotFoundError("Webhook event not found")
+	}
+	return event, nil
+}
+
+func (s *webhookEventService) Replay(ctx context.Context, id string) (*entity.WebhookEvent, error) {
+	event, err := s.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch event.Status {
+	case entity.WebhookEventRejected:
+		return nil, errors.New("WEBHOOK_REJECTED", "Rejected webhooks cannot be replayed", 400)
+	case entity.WebhookEventProcessed, entity.WebhookEventDuplicate:
+		return nil, errors.New("WEBHOOK_ALREADY_PROCESSED", "Webhook has already been processed", 409)
+	}
+
+	processor, ok := s.processors[event.Source]
+	if !ok {
+		return nil, errors.NewWithDetails("PAY_4002", "Payment gateway not configured", 404, nil)
+	}
+
+	// A failed attempt is recorded on the event and is not an error here
+	attempts := event.Attempts
+	if err := s.process(ctx, event, processor); err != nil && event.Attempts == attempts {
+		return nil, err
+	}
+	return event, nil
+}
+
+// webhookIdempotencyKey identifies the payment update an event carries
+func webhookIdempotencyKey(event *entity.WebhookEvent) string {
+	tenantID := ""
+	if event.TenantID != nil {
+		tenantID = *event.TenantID
+	}
+	return strings.Join([]string{event.Source, tenantID, event.OrderID, event.TransactionStatus}, ":")
+}
+
+// notificationOf rebuilds the verified payment update stored on an event
+func notificationOf(event *entity.WebhookEvent) *payment.Notification {
+	return &payment.Notification{
+		Gateway:       event.Source,
+		OrderID:       event.OrderID,
+		TransactionID: event.TransactionID,
+		Status:        event.PaymentStatus,
+		GatewayStatus: event.TransactionStatus,
+		PaymentType:   event.PaymentType,
+		Amount:        event.Amount,
+	}
+}
+
+// webhookHeaders encodes request headers as JSON without credentials
+func webhookHeaders(header http.Header) string {
+	stored := make(map[string]string, len(header))
+	for name, values := range header {
+		name = http.CanonicalHeaderKey(name)
+		if redactedWebhookHeaders[name] {
+			stored[name] = "[redacted]"
+			continue
+		}
+		stored[name] = strings.Join(values, ", ")
+	}
+	data, _ := json.Marshal(stored)
+	return string(data)
+}
+
+// ========================================
+// Processors
+// ========================================
+
+// gatewayWebhookProcessor handles subscription payment webhooks from a gateway
+type gatewayWebhookProcessor struct {
+	gateway             string
+	subscriptionService SubscriptionService
+}
+
+// NewGatewayWebhookProcessor processes subscription payment webhooks from the named gateway
+func NewGatewayWebhookProcessor(gateway string, subscriptionService SubscriptionService) WebhookProcessor {
+	return &gatewayWebhookProcessor{gateway: gateway, subscriptionService: subscriptionService}
+}
+
+func (p *gatewayWebhookProcessor) Signed() bool {
+	return true
+}
+
+func (p *gatewayWebhookProcessor) Parse(ctx context.Context, tenantID string, header http.Header, body []byte) (*payment.Notification, error) {
+	return p.subscriptionService.ParseGatewayNotification(ctx, p.gateway, header, body)
+}
+
+func (p *gatewayWebhookProcessor) Apply(ctx context.Context, tenantID string, notification *payment.Notification) error {
+	return p.subscriptionService.ApplyGatewayNotification(ctx, notification)
+}
+
+// paymentWebhookProcessor handles the generic /webhooks/payment callback,
+// which carries no verifiable signature
+type paymentWebhookProcessor struct {
+	subscriptionService SubscriptionService
+}
+
+// NewPaymentWebhookProcessor processes generic subscription payment callbacks
+func NewPaymentWebhookProcessor(subscriptionService SubscriptionService) WebhookProcessor {
+	return &paymentWebhookProcessor{subscriptionService: subscriptionService}
+}
+
+func (p *paymentWebhookProcessor) Signed() bool {
+	return false
+}
+
+func (p *paymentWebhookProcessor) Parse(ctx context.Context, tenantID string, header http.Header, body []byte) (*payment.Notification, error) {
+	var req dto.PaymentWebhookRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.OrderID == "" || req.Status == "" {
+		return nil, errors.NewValidationError("Invalid webhook request data")
+	}
+	return &payment.Notification{
+		Gateway:       entity.WebhookSourcePayment,
+		OrderID:       req.OrderID,
+		TransactionID: req.GatewayTransactionID,
+		Status:        req.Status,
+		GatewayStatus: req.Status,
+		PaymentType:   req.PaymentMethod,
+		Amount:        req.Amount,
+	}, nil
+}
+
+func (p *paymentWebhookProcessor) Apply(ctx context.Context, tenantID string, notification *payment.Notification) error {
+	return p.subscriptionService.ProcessPayment(ctx, notification.OrderID, notification.Status, notification.PaymentType, notification.TransactionID)
+}
+
+// invoiceWebhookProcessor handles notifications sent to a tenant's Midtrans webhook
+type invoiceWebhookProcessor struct {
+	invoicePaymentService InvoicePaymentService
+}
+
+// NewInvoiceWebhookProcessor processes invoice payment notifications from tenants' Midtrans accounts
+func NewInvoiceWebhookProcessor(invoicePaymentService InvoicePaymentService) WebhookProcessor {
+	return &invoiceWebhookProcessor{invoicePaymentService: invoicePaymentService}
+}
+
+func (p *invoiceWebhookProcessor) Signed() bool {
+	return true
+}
+
+func (p *invoiceWebhookProcessor) Parse(ctx context.Context, tenantID string, header http.Header, body []byte) (*payment.Notification, error) {
+	return p.invoicePaymentService.ParseNotification(ctx, tenantID, header, body)
+}
+
+func (p *invoiceWebhookProcessor) Apply(ctx context.Context, tenantID string, notification *payment.Notification) error {
+	return p.invoicePaymentService.ApplyNotification(ctx, tenantID, notification)
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/payment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookEventRepository) Create(ctx context.Context, event *entity.WebhookEvent) error {
+	args := m.Called(ctx, event)
+	if event.ID == "" {
+		event.ID = "evt-1"
+	}
+	return args.Error(0)
+}
+
+func (m *MockWebhookEventRepository) Update(ctx context.Context, event *entity.WebhookEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockWebhookEventRepository) FindByID(ctx context.Context, id string) (*entity.WebhookEvent, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.WebhookEvent), args.Error(1)
+}
+
+func (m *MockWebhookEventRepository) List(ctx context.Context, filter repository.WebhookEventFilter) ([]*entity.WebhookEvent, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*entity.WebhookEvent), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockWebhookEventRepository) ClaimIdempotencyKey(ctx context.Context, id, key string) (bool, error) {
+	args := m.Called(ctx, id, key)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockWebhookProcessor struct {
+	mock.Mock
+}
+
+func (m *MockWebhookProcessor) Signed() bool {
+	return true
+}
+
+func (m *MockWebhookProcessor) Parse(ctx context.Context, tenantID string, header http.Header, body []byte) (*payment.Notification, error) {
+	args := m.Called(ctx, tenantID, header, body)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Notification), args.Error(1)
+}
+
+func (m *MockWebhookProcessor) Apply(ctx context.Context, tenantID string, notification *payment.Notification) error {
+	args := m.Called(ctx, tenantID, notification)
+	return args.Error(0)
+}
+
+func TestWebhookEventService_Receive(t *testing.T) {
+	ctx := context.Background()
+	body := []byte(`{"order_id":"ORD-1","transaction_status":"settlement"}`)
+	notification := &payment.Notification{
+		Gateway: payment.GatewayMidtrans, OrderID: "ORD-1", TransactionID: "tx-1",
+		Status: payment.StatusPaid, GatewayStatus: "settlement", PaymentType: "qris", Amount: 150000,
+	}
+	key := "midtrans::ORD-1:settlement"
+
+	t.Run("Processes And Records Outcome", func(t *testing.T) {
+		mockEventRepo := new(MockWebhookEventRepository)
+		mockProcessor := new(MockWebhookProcessor)
+		service := NewWebhookEventService(mockEventRepo, map[string]WebhookProcessor{entity.WebhookSourceMidtrans: mockProcessor})
+
+		mockEventRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockProcessor.On("Parse", ctx, "", mock.Anything, body).Return(notification, nil)
+		mockEventRepo.On("ClaimIdempotencyKey", ctx, "evt-1", key).Return(true, nil)
+		mockProcessor.On("Apply", ctx, "", mock.Anything).Return(nil)
+		mockEventRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.Receive(ctx, entity.WebhookSourceMidtrans, "", http.Header{}, body))
+
+		event := mockEventRepo.Calls[0].Arguments.Get(1).(*entity.WebhookEvent)
+		assert.Equal(t, entity.WebhookEventProcessed, event.Status)
+		assert.True(t, *event.SignatureValid)
+		assert.Equal(t, "ORD-1", event.OrderID)
+		assert.Equal(t, string(body), event.Body)
+		assert.Equal(t, 1, event.Attempts)
+		assert.NotNil(t, event.ProcessedAt)
+
+		applied := mockProcessor.Calls[1].Arguments.Get(2).(*payment.Notification)
+		assert.Equal(t, payment.StatusPaid, applied.Status)
+		assert.Equal(t, "tx-1", applied.TransactionID)
+	})
+
+	t.Run("Duplicate Is Not Applied", func(t *testing.T) {
+		mockEventRepo := new(MockWebhookEventRepository)
+		mockProcessor := new(MockWebhookProcessor)
+		service := NewWebhookEventService(mockEventRepo, map[string]WebhookProcessor{entity.WebhookSourceMidtrans: mockProcessor})
+
+		mockEventRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockProcessor.On("Parse", ctx, "", mock.Anything, body).Return(notification, nil)
+		mockEventRepo.On("ClaimIdempotencyKey", ctx, "evt-1", key).Return(false, nil)
+		mockEventRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, service.Receive(ctx, entity.WebhookSourceMidtrans, "", http.Header{}, body))
+
+		event := mockEventRepo.Calls[0].Arguments.Get(1).(*entity.WebhookEvent)
+		assert.Equal(t, entity.WebhookEventDuplicate, event.Status)
+		mockProcessor.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Invalid Signature", func(t *testing.T) {
+		mockEventRepo := new(MockWebhookEventRepository)
+		mockProcessor := new(MockWebhookProcessor)
+		service := NewWebhookEventService(mockEventRepo, map[string]WebhookProcessor{entity.WebhookSourceMidtrans: mockProcessor})
+
+		mockEventRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockProcessor.On("Parse", ctx, "", mock.Anything, body).Return(nil, payment.ErrInvalidSignature)
+		mockEventRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		err := service.Receive(ctx, entity.WebhookSourceMidtrans, "", http.Header{}, body)
+		require.Error(t, err)
+		assert.Equal(t, 401, err.(*errors.AppError).Status)
+
+		event := mockEventRepo.Calls[0].Arguments.Get(1).(*entity.WebhookEvent)
+		assert.Equal(t, entity.WebhookEventRejected, event.Status)
+		assert.False(t, *event.SignatureValid)
+		mockEventRepo.AssertNotCalled(t, "ClaimIdempotencyKey", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure Releases Key", func(t *testing.T) {
+		mockEventRepo := new(MockWebhookEventRepository)
+		mockProcessor := new(MockWebhookProcessor)
+		service := NewWebhookEventService(mockEventRepo, map[string]WebhookProcessor{entity.WebhookSourceMidtrans: mockProcessor})
+
+		mockEventRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockProcessor.On("Parse", ctx, "", mock.Anything, body).Return(notification, nil)
+		mockEventRepo.On("ClaimIdempotencyKey", ctx, "evt-1", key).Return(true, nil)
+		mockProcessor.On("Apply", ctx, "", mock.Anything).Return(errors.ErrInternalServer)
+		mockEventRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		err := service.Receive(ctx, entity.WebhookSourceMidtrans, "", http.Header{}, body)
+		assert.Equal(t, errors.ErrInternalServer, err)
+
+		event := mockEventRepo.Calls[0].Arguments.Get(1).(*entity.WebhookEvent)
+		assert.Equal(t, entity.WebhookEventFailed, event.Status)
+		assert.Nil(t, event.IdempotencyKey)
+		assert.NotEmpty(t, event.Error)
+	})
+}
+
+func TestWebhookEventService_Replay(t *testing.T) {
+	ctx := context.Background()
+	failed := func() *entity.WebhookEvent {
+		return &entity.WebhookEvent{
+			ID: "evt-1", Source: entity.WebhookSourceMidtrans, OrderID: "ORD-1",
+			TransactionStatus: "settlement", PaymentStatus: payment.StatusPaid, TransactionID: "tx-1",
+			Status: entity.WebhookEventFailed, Error: "database unavailable", Attempts: 1,
+		}
+	}
+
+	t.Run("Processes Failed Event", func(t *testing.T) {
+		mockEventRepo := new(MockWebhookEventRepository)
+		mockProcessor := new(MockWebhookProcessor)
+		service := NewWebhookEventService(mockEventRepo, map[string]WebhookProcessor{entity.WebhookSourceMidtrans: mockProcessor})
+
+		mockEventRepo.On("FindByID", ctx, "evt-1").Return(failed(), nil)
+		mockEventRepo.On("ClaimIdempotencyKey", ctx, "evt-1", "midtrans::ORD-1:settlement").Return(true, nil)
+		mockProcessor.On("Apply", ctx, "", mock.Anything).Return(nil)
+		mockEventRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		event, err := service.Replay(ctx, "evt-1")
+		require.NoError(t, err)
+		assert.Equal(t, entity.WebhookEventProcessed, event.Status)
+		assert.Equal(t, 2, event.Attempts)
+		assert.Empty(t, event.Error)
+		mockProcessor.AssertNotCalled(t, "Parse", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed Replay Is Recorded", func(t *testing.T) {
+		mockEventRepo := new(MockWebhookEventRepository)
+		mockProcessor := new(MockWebhookProcessor)
+		service := NewWebhookEventService(mockEventRepo, map[string]WebhookProcessor{entity.WebhookSourceMidtrans: mockProcessor})
+
+		mockEventRepo.On("FindByID", ctx, "evt-1").Return(failed(), nil)
+		mockEventRepo.On("ClaimIdempotencyKey", ctx, "evt-1", mock.Anything).Return(true, nil)
+		mockProcessor.On("Apply", ctx, "", mock.Anything).Return(errors.ErrNotFound)
+		mockEventRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+		event, err := service.Replay(ctx, "evt-1")
+		require.NoError(t, err)
+		assert.Equal(t, entity.WebhookEventFailed, event.Status)
+		assert.Equal(t, 2, event.Attempts)
+	})
+
+	t.Run("Rejected And Processed Events Are Not Replayed", func(t *testing.T) {
+		mockEventRepo := new(MockWebhookEventRepository)
+		mockProcessor := new(MockWebhookProcessor)
+		service := NewWebhookEventService(mockEventRepo, map[string]WebhookProcessor{entity.WebhookSourceMidtrans: mockProcessor})
+
+		rejected := failed()
+		rejected.Status = entity.WebhookEventRejected
+		processed := failed()
+		processed.ID = "evt-2"
+		processed.Status = entity.WebhookEventProcessed
+		mockEventRepo.On("FindByID", ctx, "evt-1").Return(rejected, nil)
+		mockEventRepo.On("FindByID", ctx, "evt-2").Return(processed, nil)
+
+		_, err := service.Replay(ctx, "evt-1")
+		assert.Equal(t, 400, err.(*errors.AppError).Status)
+		_, err = service.Replay(ctx, "evt-2")
+		assert.Equal(t, 409, err.(*errors.AppError).Status)
+		mockProcessor.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestWebhookHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Callback-Token", "secret")
+
+	var stored map[string]string
+	require.NoError(t, json.Unmarshal([]byte(webhookHeaders(header)), &stored))
+	assert.Equal(t, "application/json", stored["Content-Type"])
+	assert.Equal(t, "[redacted]", stored["X-Callback-Token"])
+}
@@ -0,0 +1,781 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/webhook"
+)
+
+// WebhookService sends the events of a tenant to the webhooks the tenant
+// registered. The webhook job scans each active webhook's subscribed events
+// since its last run into deliveries, then sends the due deliveries signed
+// with the webhook's secret. Failed deliveries are retried with exponential
+// backoff. Tenants need a plan with WebhookSupport; webhooks over the plan's
+// MaxWebhooks are not served.
+type WebhookService interface {
+	// ListEvents returns the events webhooks can subscribe to
+	ListEvents() []*WebhookEventInfo
+	ListWebhooks(ctx context.Context, tenantID string) ([]*Webhook, error)
+	GetWebhook(ctx context.Context, tenantID, id string) (*Webhook, error)
+	// CreateWebhook registers a webhook. The returned webhook holds the
+	// secret, which is not shown again.
+	CreateWebhook(ctx context.Context, tenantID, userID string, req *dto.CreateWebhookRequest) (*Webhook, error)
+	UpdateWebhook(ctx context.Context, tenantID, id string, req *dto.UpdateWebhookRequest) (*Webhook, error)
+	DeleteWebhook(ctx context.Context, tenantID, id string) error
+	// RotateSecret gives the webhook a new secret and returns it
+	RotateSecret(ctx context.Context, tenantID, id string) (*Webhook, error)
+	// Ping sends a ping event to the webhook now and returns the delivery
+	Ping(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error)
+	// ListDeliveries returns a webhook's latest deliveries, optionally only
+	// those with the status
+	ListDeliveries(ctx context.Context, tenantID, webhookID, status string, limit int) ([]*entity.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error)
+	// Redeliver sends the payload of a delivery again as a new delivery,
+	// trying it now and retrying it like any other
+	Redeliver(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error)
+	// RunAll turns new events into deliveries and sends the due deliveries
+	RunAll(ctx context.Context, now time.Time) error
+}
+
+// Webhook is a tenant webhook as shown to the tenant. Secret is only set when
+// the webhook is created or its secret rotated.
+type Webhook struct {
+	*entity.TenantWebhook
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+// WebhookEventInfo describes an event webhooks can subscribe to
+type WebhookEventInfo struct {
+	Event       string `json:"event"`
+	Description string `json:"description"`
+}
+
+// webhookEvents is the catalog of events webhooks can subscribe to
+var webhookEvents = []*WebhookEventInfo{
+	{Event: entity.WebhookEventCustomerCreated, Description: "A customer was added"},
+	{Event: entity.WebhookEventCustomerSuspended, Description: "A customer was suspended, by hand or for an unpaid invoice"},
+	{Event: entity.WebhookEventPaymentPaid, Description: "An invoice was paid in full"},
+	{Event: entity.WebhookEventVoucherActivated, Description: "A hotspot voucher was used for the first time"},
+	{Event: entity.WebhookEventDeviceDown, Description: "A connection check of a router failed"},
+}
+
+const (
+	// Deliveries are tried this many times before they fail
+	webhookMaxAttempts = 8
+	// The first retry waits this long, doubling with every attempt
+	webhookRetryDelay    = time.Minute
+	webhookMaxRetryDelay = 6 * time.Hour
+	// A claimed delivery is retried after this long if its run died
+	webhookAttemptLease = 5 * time.Minute
+	// Due deliveries sent per run
+	webhookBatchSize = 200
+	// Events are scanned up to this long before now so rows committed late
+	// by slow transactions are not skipped
+	webhookEventDelay = 30 * time.Second
+	webhookListLimit  = 50
+)
+
+type webhookService struct {
+	webhookRepo      repository.WebhookRepository
+	subscriptionRepo repository.TenantSubscriptionRepository
+	planRepo         repository.SubscriptionPlanRepository
+	client           *webhook.Client
+}
+
+func NewWebhookService(
+	webhookRepo repository.WebhookRepository,
+	subscriptionRepo repository.TenantSubscriptionRepository,
+	planRepo repository.SubscriptionPlanRepository,
+	client *webhook.Client,
+) WebhookService {
+	return &webhookService{
+		webhookRepo:      webhookRepo,
+		subscriptionRepo: subscriptionRepo,
+		planRepo:         planRepo,
+		client:           client,
+	}
+}
+
+func (s *webhookService) ListEvents() []*WebhookEventInfo {
+	return webhookEvents
+}
+
+func toWebhook(w *entity.TenantWebhook) *Webhook {
+	return &Webhook{TenantWebhook: w, Events: w.EventList()}
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context, tenantID string) ([]*Webhook, error) {
+	webhooks, err := s.webhookRepo.FindByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list webhooks", err)
+	}
+	result := make([]*Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, toWebhook(w))
+	}
+	return result, nil
+}
+
+func (s *webhookService) findWebhook(ctx context.Context, tenantID, id string) (*entity.TenantWebhook, error) {
+	w, err := s.webhookRepo.FindByID(ctx, tenantID, id)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewNotFoundError("Webhook not found")
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("find webhook", err)
+	}
+	return w, nil
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, tenantID, id string) (*Webhook, error) {
+	w, err := s.findWebhook(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhook(w), nil
+}
+
+// validateWebhook checks the URL and that the events are known, returning
+// them without duplicates
+func (s *webhookService) validateWebhook(url string, events []string) ([]string, error) {
+	if err := s.client.ValidateURL(url); err != nil {
+		return nil, errors.NewValidationError("Invalid webhook URL: " + err.Error())
+	}
+	seen := make(map[string]bool, len(events))
+	var valid []string
+	for _, event := range events {
+		if !isWebhookEvent(event) {
+			return nil, errors.NewValidationError("Unknown webhook event: " + event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			valid = append(valid, event)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, errors.NewValidationError("Subscribe the webhook to at least one event")
+	}
+	return valid, nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range webhookEvents {
+		if e.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, tenantID, userID string, req *dto.CreateWebhookRequest) (*Webhook, error) {
+	events, err := s.validateWebhook(req.URL, req.Events)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate webhook secret")
+	}
+
+	w := &entity.TenantWebhook{
+		TenantID:    tenantID,
+		URL:         req.URL,
+		Description: req.Description,
+		Secret:      secret,
+		IsActive:    true,
+		// Only events from now on are sent, not the tenant's history
+		EventsScannedAt: time.Now(),
+	}
+	w.SetEvents(events)
+	if userID != "" {
+		w.CreatedBy = &userID
+	}
+	if err := s.webhookRepo.Create(ctx, w); err != nil {
+		return nil, errors.NewDatabaseError("create webhook", err)
+	}
+
+	logger.Info("Webhook %s created for tenant %s", w.ID, tenantID)
+	result := toWebhook(w)
+	result.Secret = secret
+	return result, nil
+}
+
+func (s *webhookService) UpdateWebhook(ctx context.Context, tenantID, id string, req *dto.UpdateWebhookRequest) (*Webhook, error) {
+	w, err := s.findWebhook(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	url, events := w.URL, w.EventList()
+	if req.URL != nil {
+		url = *req.URL
+	}
+	if req.Events != nil {
+		events = req.Events
+	}
+	if events, err = s.validateWebhook(url, events); err != nil {
+		return nil, err
+	}
+	w.URL = url
+	w.SetEvents(events)
+	if req.Description != nil {
+		w.Description = *req.Description
+	}
+	reactivated := req.IsActive != nil && *req.IsActive && !w.IsActive
+	if req.IsActive != nil {
+		w.IsActive = *req.IsActive
+	}
+
+	if err := s.webhookRepo.Update(ctx, w); err != nil {
+		return nil, errors.NewDatabaseError("update webhook", err)
+	}
+	// Events that happened while the webhook was off are not sent
+	if reactivated {
+		if err := s.webhookRepo.SetEventsScanned(ctx, w.ID, time.Now()); err != nil {
+			return nil, errors.NewDatabaseError("update webhook", err)
+		}
+	}
+	return toWebhook(w), nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, tenantID, id string) error {
+	err := s.webhookRepo.Delete(ctx, tenantID, id)
+	if err == errors.ErrNotFound {
+		return errors.NewNotFoundError("Webhook not found")
+	}
+	if err != nil {
+		return errors.NewDatabaseError("delete webhook", err)
+	}
+	logger.Info("Webhook %s of tenant %s deleted", id, tenantID)
+	return nil
+}
+
+func (s *webhookService) RotateSecret(ctx context.Context, tenantID, id string) (*Webhook, error) {
+	w, err := s.findWebhook(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate webhook secret")
+	}
+	w.Secret = secret
+	if err := s.webhookRepo.Update(ctx, w); err != nil {
+		return nil, errors.NewDatabaseError("update webhook", err)
+	}
+	result := toWebhook(w)
+	result.Secret = secret
+	return result, nil
+}
+
+func (s *webhookService) Ping(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error) {
+	w, err := s.findWebhook(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	event := &webhookEvent{
+		id:         uuid.New().String(),
+		name:       entity.WebhookEventPing,
+		occurredAt: now,
+		data:       map[string]interface{}{"webhook_id": w.ID},
+	}
+	delivery, err := newWebhookDelivery(w, event, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, errors.NewDatabaseError("create webhook delivery", err)
+	}
+	if err := s.attempt(ctx, w, delivery, now); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, tenantID, webhookID, status string, limit int) ([]*entity.WebhookDelivery, error) {
+	if _, err := s.findWebhook(ctx, tenantID, webhookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = webhookListLimit
+	}
+	deliveries, err := s.webhookRepo.FindDeliveries(ctx, tenantID, webhookID, status, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+func (s *webhookService) findDelivery(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.FindDelivery(ctx, tenantID, id)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewNotFoundError("Webhook delivery not found")
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("find webhook delivery", err)
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) GetDelivery(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error) {
+	return s.findDelivery(ctx, tenantID, id)
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error) {
+	original, err := s.findDelivery(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.findWebhook(ctx, tenantID, original.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status == entity.WebhookDeliveryPending {
+		return nil, errors.NewConflictError("Webhook delivery is still being retried")
+	}
+
+	now := time.Now()
+	redeliveryOf := original.ID
+	delivery := &entity.WebhookDelivery{
+		TenantID:      tenantID,
+		WebhookID:     w.ID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        entity.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &redeliveryOf,
+	}
+	if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, errors.NewDatabaseError("create webhook delivery", err)
+	}
+	if err := s.attempt(ctx, w, delivery, now); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// webhookEvent is an event as sent to webhooks
+type webhookEvent struct {
+	id         string
+	name       string
+	occurredAt time.Time
+	data       interface{}
+}
+
+// webhookEventID identifies an event the same way on every scan, so an event
+// found twice becomes one delivery
+func webhookEventID(tenantID, event, subjectID string, occurredAt time.Time) string {
+	key := tenantID + "/" + event + "/" + subjectID + "/" + occurredAt.UTC().Format(time.RFC3339Nano)
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+}
+
+// newWebhookDelivery returns a pending delivery of the event, due at now
+func newWebhookDelivery(w *entity.TenantWebhook, event *webhookEvent, now time.Time) (*entity.WebhookDelivery, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         event.id,
+		"event":      event.name,
+		"created_at": event.occurredAt,
+		"tenant_id":  w.TenantID,
+		"data":       event.data,
+	})
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to encode webhook event")
+	}
+	return &entity.WebhookDelivery{
+		TenantID:      w.TenantID,
+		WebhookID:     w.ID,
+		EventID:       event.id,
+		Event:         event.name,
+		Payload:       string(payload),
+		Status:        entity.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}, nil
+}
+
+// attempt sends a claimed delivery once and records the outcome, scheduling
+// a retry when it failed and has attempts left. Errors are only returned for
+// failures to store the outcome.
+func (s *webhookService) attempt(ctx context.Context, w *entity.TenantWebhook, delivery *entity.WebhookDelivery, now time.Time) error {
+	claimed, err := s.webhookRepo.ClaimDelivery(ctx, delivery, now.Add(webhookAttemptLease))
+	if err != nil {
+		return errors.NewDatabaseError("claim webhook delivery", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	resp, sendErr := s.client.Send(ctx, &webhook.Request{
+		URL:        w.URL,
+		Secret:     w.Secret,
+		Event:      delivery.Event,
+		DeliveryID: delivery.ID,
+		Body:       []byte(delivery.Payload),
+		Time:       now,
+	})
+	delivery.ResponseStatus, delivery.ResponseBody, delivery.DurationMs = nil, "", nil
+	if resp != nil {
+		status, ms := resp.StatusCode, int(resp.Duration/time.Millisecond)
+		delivery.ResponseStatus, delivery.ResponseBody, delivery.DurationMs = &status, resp.Body, &ms
+	}
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = entity.WebhookDeliveryDelivered
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.Error = sendErr.Error()
+		delivery.NextAttemptAt = nil
+	default:
+		delivery.Error = sendErr.Error()
+		next := now.Add(webhookBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if sendErr != nil {
+		logger.Warn("Webhook delivery %s to %s failed (attempt %d): %v", delivery.ID, w.URL, delivery.Attempts, sendErr)
+	}
+
+	if err := s.webhookRepo.FinishDelivery(ctx, delivery); err != nil {
+		return errors.NewDatabaseError("save webhook delivery", err)
+	}
+	return nil
+}
+
+// webhookBackoff returns how long to wait after a failed attempt: 1 minute
+// after the first, doubling up to webhookMaxRetryDelay
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryDelay
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxRetryDelay {
+		delay = webhookMaxRetryDelay
+	}
+	return delay
+}
+
+// servedWebhooks returns the active webhooks the plan of their tenant
+// allows: none without WebhookSupport, otherwise the oldest MaxWebhooks
+func (s *webhookService) servedWebhooks(ctx context.Context, webhooks []*entity.TenantWebhook) map[string]*entity.TenantWebhook {
+	served := make(map[string]*entity.TenantWebhook)
+	plans := make(map[string]int) // webhooks left per tenant, -1 for no limit
+	for _, w := range webhooks {
+		left, ok := plans[w.TenantID]
+		if !ok {
+			left = s.webhookAllowance(ctx, w.TenantID)
+		}
+		if left != 0 {
+			served[w.ID] = w
+			if left > 0 {
+				left--
+			}
+		}
+		plans[w.TenantID] = left
+	}
+	return served
+}
+
+// webhookAllowance returns how many webhooks the tenant's plan allows, -1 for
+// no limit and 0 without WebhookSupport or an active subscription
+func (s *webhookService) webhookAllowance(ctx context.Context, tenantID string) int {
+	subscription, err := s.subscriptionRepo.FindActiveByTenantID(ctx, tenantID)
+	if err != nil || subscription == nil {
+		return 0
+	}
+	plan, err := s.planRepo.FindByID(ctx, subscription.PlanID)
+	if err != nil || plan == nil {
+		return 0
+	}
+	var features entity.PlanFeatures
+	if plan.PlanFeatures != "" {
+		json.Unmarshal([]byte(plan.PlanFeatures), &features)
+	}
+	if !features.WebhookSupport {
+		return 0
+	}
+	var limits entity.PlanLimits
+	if plan.Limits != "" {
+		json.Unmarshal([]byte(plan.Limits), &limits)
+	}
+	if limits.MaxWebhooks < 0 {
+		return -1
+	}
+	return limits.MaxWebhooks
+}
+
+func (s *webhookService) RunAll(ctx context.Context, now time.Time) error {
+	webhooks, err := s.webhookRepo.FindActive(ctx)
+	if err != nil {
+		return errors.NewDatabaseError("load webhooks", err)
+	}
+	served := s.servedWebhooks(ctx, webhooks)
+
+	until := now.Add(-webhookEventDelay)
+	for _, w := range webhooks {
+		if !until.After(w.EventsScannedAt) {
+			continue
+		}
+		// Webhooks the plan does not allow skip their events
+		if served[w.ID] != nil {
+			if err := s.scanEvents(ctx, w, until); err != nil {
+				logger.Error("Webhook events of %s (tenant %s) failed: %v", w.ID, w.TenantID, err)
+				continue
+			}
+		}
+		if err := s.webhookRepo.SetEventsScanned(ctx, w.ID, until); err != nil {
+			logger.Error("Failed to save webhook scan of %s: %v", w.ID, err)
+		}
+	}
+
+	due, err := s.webhookRepo.FindDueDeliveries(ctx, now, webhookBatchSize)
+	if err != nil {
+		return errors.NewDatabaseError("load due webhook deliveries", err)
+	}
+	sent := 0
+	for _, delivery := range due {
+		w := served[delivery.WebhookID]
+		if w == nil {
+			s.dropDelivery(ctx, delivery, "Webhooks are not available on the tenant's plan")
+			continue
+		}
+		if err := s.attempt(ctx, w, delivery, time.Now()); err != nil {
+			logger.Error("Webhook delivery %s failed: %v", delivery.ID, err)
+			continue
+		}
+		sent++
+	}
+	if len(due) > 0 {
+		logger.Info("Webhook run: %d deliveries attempted of %d due", sent, len(due))
+	}
+	return nil
+}
+
+// dropDelivery fails a pending delivery without sending it
+func (s *webhookService) dropDelivery(ctx context.Context, delivery *entity.WebhookDelivery, reason string) {
+	delivery.Status = entity.WebhookDeliveryFailed
+	delivery.Error = reason
+	delivery.NextAttemptAt = nil
+	if err := s.webhookRepo.FinishDelivery(ctx, delivery); err != nil {
+		logger.Error("Failed to drop webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// scanEvents stores a delivery for each subscribed event of the webhook in
+// [EventsScannedAt, until)
+func (s *webhookService) scanEvents(ctx context.Context, w *entity.TenantWebhook, until time.Time) error {
+	since := w.EventsScannedAt
+	var deliveries []*entity.WebhookDelivery
+	for _, name := range w.EventList() {
+		events, err := s.findEvents(ctx, w.TenantID, name, since, until)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			delivery, err := newWebhookDelivery(w, event, until)
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	if err := s.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		return errors.NewDatabaseError("create webhook deliveries", err)
+	}
+	return nil
+}
+
+// findEvents returns the tenant's events of one kind in [since, until)
+func (s *webhookService) findEvents(ctx context.Context, tenantID, name string, since, until time.Time) ([]*webhookEvent, error) {
+	var events []*webhookEvent
+	add := func(subjectID string, occurredAt time.Time, data interface{}) {
+		events = append(events, &webhookEvent{
+			id:         webhookEventID(tenantID, name, subjectID, occurredAt),
+			name:       name,
+			occurredAt: occurredAt,
+			data:       data,
+		})
+	}
+
+	switch name {
+	case entity.WebhookEventCustomerCreated:
+		customers, err := s.webhookRepo.FindNewCustomers(ctx, tenantID, since, until)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find new customers", err)
+		}
+		for _, c := range customers {
+			add(c.ID, c.CreatedAt, map[string]interface{}{"customer": newWebhookCustomer(c)})
+		}
+	case entity.WebhookEventCustomerSuspended:
+		customers, err := s.webhookRepo.FindSuspendedCustomers(ctx, tenantID, since, until)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find suspended customers", err)
+		}
+		for _, c := range customers {
+			add(c.ID, *c.SuspendedAt, map[string]interface{}{"customer": newWebhookCustomer(c)})
+		}
+	case entity.WebhookEventPaymentPaid:
+		invoices, err := s.webhookRepo.FindPaidInvoices(ctx, tenantID, since, until)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find paid invoices", err)
+		}
+		for _, p := range invoices {
+			data := map[string]interface{}{"invoice": newWebhookInvoice(p)}
+			if p.Customer != nil {
+				data["customer"] = newWebhookCustomer(p.Customer)
+			}
+			add(p.ID, *p.PaymentDate, data)
+		}
+	case entity.WebhookEventVoucherActivated:
+		vouchers, err := s.webhookRepo.FindActivatedVouchers(ctx, tenantID, since, until)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find activated vouchers", err)
+		}
+		for _, v := range vouchers {
+			add(v.ID.String(), *v.ActivatedAt, map[string]interface{}{"voucher": newWebhookVoucher(v)})
+		}
+	case entity.WebhookEventDeviceDown:
+		devices, err := s.webhookRepo.FindDownRouters(ctx, tenantID, since, until)
+		if err != nil {
+			return nil, errors.NewDatabaseError("find down routers", err)
+		}
+		for _, d := range devices {
+			add(d.ID, *d.LastConnectedAt, map[string]interface{}{"device": newWebhookDevice(d)})
+		}
+	}
+	return events, nil
+}
+
+// Event payloads. They carry what a receiver needs to act on the event, not
+// secrets like PPPoE or voucher passwords.
+
+type webhookCustomer struct {
+	ID            string     `json:"id"`
+	CustomerCode  string     `json:"customer_code"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	Address       string     `json:"address"`
+	ServicePlanID string     `json:"service_plan_id"`
+	Status        string     `json:"status"`
+	SuspendedAt   *time.Time `json:"suspended_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func newWebhookCustomer(c *entity.Customer) *webhookCustomer {
+	return &webhookCustomer{
+		ID:            c.ID,
+		CustomerCode:  c.CustomerCode,
+		Name:          c.Name,
+		Email:         c.Email,
+		Phone:         c.Phone,
+		Address:       c.Address,
+		ServicePlanID: c.ServicePlanID,
+		Status:        c.Status,
+		SuspendedAt:   c.SuspendedAt,
+		CreatedAt:     c.CreatedAt,
+	}
+}
+
+type webhookInvoice struct {
+	ID            string     `json:"id"`
+	InvoiceNumber string     `json:"invoice_number"`
+	CustomerID    string     `json:"customer_id"`
+	Amount        float64    `json:"amount"`
+	PaidAmount    float64    `json:"paid_amount"`
+	DueDate       time.Time  `json:"due_date"`
+	PaymentDate   *time.Time `json:"payment_date"`
+	PaymentMethod string     `json:"payment_method"`
+}
+
+func newWebhookInvoice(p *entity.Payment) *webhookInvoice {
+	number := p.ID
+	if p.InvoiceNumber != nil {
+		number = *p.InvoiceNumber
+	}
+	return &webhookInvoice{
+		ID:            p.ID,
+		InvoiceNumber: number,
+		CustomerID:    p.CustomerID,
+		Amount:        p.Amount + p.LateFee,
+		PaidAmount:    p.PaidAmount,
+		DueDate:       p.DueDate,
+		PaymentDate:   p.PaymentDate,
+		PaymentMethod: p.PaymentMethod,
+	}
+}
+
+type webhookVoucher struct {
+	ID          string     `json:"id"`
+	VoucherCode string     `json:"voucher_code"`
+	PackageID   string     `json:"package_id"`
+	PackageName string     `json:"package_name,omitempty"`
+	Price       int        `json:"price"`
+	DeviceMAC   string     `json:"device_mac,omitempty"`
+	ActivatedAt *time.Time `json:"activated_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+func newWebhookVoucher(v *entity.HotspotVoucher) *webhookVoucher {
+	voucher := &webhookVoucher{
+		ID:          v.ID.String(),
+		VoucherCode: v.VoucherCode,
+		PackageID:   v.PackageID.String(),
+		DeviceMAC:   v.DeviceMAC,
+		ActivatedAt: v.ActivatedAt,
+		ExpiresAt:   v.ExpiresAt,
+	}
+	if v.Package != nil {
+		voucher.PackageName = v.Package.Name
+		voucher.Price = v.Package.Price
+	}
+	return voucher
+}
+
+type webhookDevice struct {
+	ID               string     `json:"id"`
+	DeviceName       string     `json:"device_name"`
+	DeviceType       string     `json:"device_type"`
+	IPAddress        string     `json:"ip_address"`
+	ConnectionStatus string     `json:"connection_status"`
+	CheckedAt        *time.Time `json:"checked_at"`
+}
+
+func newWebhookDevice(d *entity.Device) *webhookDevice {
+	return &webhookDevice{
+		ID:               d.ID,
+		DeviceName:       d.DeviceName,
+		DeviceType:       d.DeviceType,
+		IPAddress:        d.IPAddress,
+		ConnectionStatus: d.ConnectionStatus,
+		CheckedAt:        d.LastConnectedAt,
+	}
+}
+
+// StartWebhookJob runs the webhook job now and then on every interval
+func StartWebhookJob(service WebhookService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := service.RunAll(context.Background(), time.Now()); err != nil {
+				logger.Error("Webhook job error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logger.Info("Webhook job started (interval: %s)", interval)
+}
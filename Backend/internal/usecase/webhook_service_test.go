@@ -0,0 +1,430 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/delivery/http/dto"
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookRepository struct {
+	repository.WebhookRepository
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) Create(ctx context.Context, webhook *entity.TenantWebhook) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FindByID(ctx context.Context, tenantID, id string) (*entity.TenantWebhook, error) {
+	args := m.Called(ctx, tenantID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TenantWebhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) FindActive(ctx context.Context) ([]*entity.TenantWebhook, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.TenantWebhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) SetEventsScanned(ctx context.Context, id string, until time.Time) error {
+	args := m.Called(ctx, id, until)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	args := m.Called(ctx, deliveries)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FindDelivery(ctx context.Context, tenantID, id string) (*entity.WebhookDelivery, error) {
+	args := m.Called(ctx, tenantID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	args := m.Called(ctx, now, limit)
+	if fn, ok := args.Get(0).(func(context.Context, time.Time, int) []*entity.WebhookDelivery); ok {
+		return fn(ctx, now, limit), args.Error(1)
+	}
+	return args.Get(0).([]*entity.WebhookDelivery), args.Error(1)
+}
+
+// ClaimDelivery counts the attempt like the database does
+func (m *MockWebhookRepository) ClaimDelivery(ctx context.Context, delivery *entity.WebhookDelivery, leaseUntil time.Time) (bool, error) {
+	args := m.Called(ctx, delivery, leaseUntil)
+	if args.Bool(0) {
+		delivery.Attempts++
+		delivery.NextAttemptAt = &leaseUntil
+	}
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) FinishDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FindPaidInvoices(ctx context.Context, tenantID string, since, until time.Time) ([]*entity.Payment, error) {
+	args := m.Called(ctx, tenantID, since, until)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+type MockSubscriptionPlanRepository struct {
+	repository.SubscriptionPlanRepository
+	mock.Mock
+}
+
+func (m *MockSubscriptionPlanRepository) FindByID(ctx context.Context, id string) (*entity.SubscriptionPlan, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.SubscriptionPlan), args.Error(1)
+}
+
+// webhookReceiver is a tenant endpoint answering with status and recording
+// what it received
+type webhookReceiver struct {
+	server   *httptest.Server
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	r := &webhookReceiver{status: http.StatusOK}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+type webhookFixture struct {
+	repo             *MockWebhookRepository
+	subscriptionRepo *MockTenantSubscriptionRepository
+	planRepo         *MockSubscriptionPlanRepository
+	receiver         *webhookReceiver
+	service          WebhookService
+}
+
+func newWebhookFixture(t *testing.T) *webhookFixture {
+	f := &webhookFixture{
+		repo:             new(MockWebhookRepository),
+		subscriptionRepo: new(MockTenantSubscriptionRepository),
+		planRepo:         new(MockSubscriptionPlanRepository),
+		receiver:         newWebhookReceiver(t),
+	}
+	f.service = NewWebhookService(f.repo, f.subscriptionRepo, f.planRepo,
+		webhook.NewClient(&webhook.Config{Timeout: 5 * time.Second, AllowPrivate: true}))
+	return f
+}
+
+// withPlan gives tenant-1 a plan with the webhook feature and limit
+func (f *webhookFixture) withPlan(webhookSupport bool, maxWebhooks int) {
+	features, _ := json.Marshal(entity.PlanFeatures{WebhookSupport: webhookSupport})
+	limits, _ := json.Marshal(entity.PlanLimits{MaxWebhooks: maxWebhooks})
+	f.subscriptionRepo.On("FindActiveByTenantID", mock.Anything, "tenant-1").
+		Return(&entity.TenantSubscription{TenantID: "tenant-1", PlanID: "plan-1"}, nil)
+	f.planRepo.On("FindByID", mock.Anything, "plan-1").
+		Return(&entity.SubscriptionPlan{ID: "plan-1", PlanFeatures: string(features), Limits: string(limits)}, nil)
+}
+
+func (f *webhookFixture) tenantWebhook(id string, events ...string) *entity.TenantWebhook {
+	w := &entity.TenantWebhook{
+		ID:              id,
+		TenantID:        "tenant-1",
+		URL:             f.receiver.server.URL + "/hooks/" + id,
+		Secret:          "whsec_" + id,
+		IsActive:        true,
+		EventsScannedAt: time.Date(2025, 3, 20, 9, 0, 0, 0, time.UTC),
+	}
+	w.SetEvents(events)
+	return w
+}
+
+func TestWebhookService_CreateWebhook(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.repo.On("Create", mock.Anything, mock.AnythingOfType("*entity.TenantWebhook")).Return(nil)
+
+	created, err := f.service.CreateWebhook(context.Background(), "tenant-1", "user-1", &dto.CreateWebhookRequest{
+		URL:    "https://hooks.example.com/rtrwnet",
+		Events: []string{entity.WebhookEventPaymentPaid, entity.WebhookEventDeviceDown, entity.WebhookEventPaymentPaid},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.WebhookEventPaymentPaid, entity.WebhookEventDeviceDown}, created.Events)
+	assert.Equal(t, created.TenantWebhook.Secret, created.Secret)
+	assert.Regexp(t, `^whsec_[0-9a-f]{48}$`, created.Secret)
+	assert.True(t, created.IsActive)
+	assert.WithinDuration(t, time.Now(), created.EventsScannedAt, time.Minute, "history is not sent")
+	require.NotNil(t, created.CreatedBy)
+	assert.Equal(t, "user-1", *created.CreatedBy)
+
+	// The secret is not part of the webhook as listed
+	data, err := json.Marshal(toWebhook(created.TenantWebhook))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), created.Secret)
+}
+
+func TestWebhookService_CreateWebhook_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		events []string
+	}{
+		{"unknown event", "https://hooks.example.com", []string{"customer.deleted"}},
+		{"ping is not subscribable", "https://hooks.example.com", []string{entity.WebhookEventPing}},
+		{"no events", "https://hooks.example.com", []string{}},
+		{"not http", "ftp://hooks.example.com", []string{entity.WebhookEventPaymentPaid}},
+		{"private address", "http://10.0.0.5/hook", []string{entity.WebhookEventPaymentPaid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockWebhookRepository)
+			service := NewWebhookService(repo, nil, nil, webhook.NewClient(&webhook.Config{}))
+
+			_, err := service.CreateWebhook(context.Background(), "tenant-1", "", &dto.CreateWebhookRequest{URL: tt.url, Events: tt.events})
+
+			appErr, ok := err.(*errors.AppError)
+			require.True(t, ok, "got %v", err)
+			assert.Equal(t, 400, appErr.Status)
+			repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestWebhookService_RunAll_DeliversSignedEvents(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.withPlan(true, 2)
+	w := f.tenantWebhook("wh-1", entity.WebhookEventPaymentPaid)
+	now := time.Date(2025, 3, 20, 10, 0, 0, 0, time.UTC)
+	until := now.Add(-webhookEventDelay)
+	paidAt := time.Date(2025, 3, 20, 9, 30, 0, 0, time.UTC)
+	number := "INV-202503-0001"
+	invoice := &entity.Payment{
+		ID: "pay-1", TenantID: "tenant-1", CustomerID: "cust-1", InvoiceNumber: &number,
+		Amount: 150000, PaidAmount: 150000, Status: entity.PaymentStatusPaid, PaymentDate: &paidAt,
+		Customer: &entity.Customer{ID: "cust-1", CustomerCode: "CUST-001", Name: "Budi", PPPoEPassword: "rahasia"},
+	}
+
+	var queued []*entity.WebhookDelivery
+	f.repo.On("FindActive", mock.Anything).Return([]*entity.TenantWebhook{w}, nil)
+	f.repo.On("FindPaidInvoices", mock.Anything, "tenant-1", w.EventsScannedAt, until).Return([]*entity.Payment{invoice}, nil)
+	f.repo.On("CreateDeliveries", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		queued = args.Get(1).([]*entity.WebhookDelivery)
+		for i, d := range queued {
+			d.ID = "del-" + strconv.Itoa(i+1)
+		}
+	}).Return(nil)
+	f.repo.On("SetEventsScanned", mock.Anything, "wh-1", until).Return(nil)
+	f.repo.On("FindDueDeliveries", mock.Anything, now, webhookBatchSize).Return(func(context.Context, time.Time, int) []*entity.WebhookDelivery {
+		return queued
+	}, nil)
+	f.repo.On("ClaimDelivery", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	f.repo.On("FinishDelivery", mock.Anything, mock.Anything).Return(nil)
+
+	require.NoError(t, f.service.RunAll(context.Background(), now))
+
+	require.Len(t, queued, 1)
+	delivery := queued[0]
+	assert.Equal(t, entity.WebhookDeliveryDelivered, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.NotNil(t, delivery.DeliveredAt)
+	assert.Nil(t, delivery.NextAttemptAt)
+	require.NotNil(t, delivery.ResponseStatus)
+	assert.Equal(t, 200, *delivery.ResponseStatus)
+
+	// The same event gets the same id on every scan
+	assert.Equal(t, webhookEventID("tenant-1", entity.WebhookEventPaymentPaid, "pay-1", paidAt), delivery.EventID)
+
+	require.Len(t, f.receiver.requests, 1)
+	req, body := f.receiver.requests[0], f.receiver.bodies[0]
+	assert.Equal(t, "/hooks/wh-1", req.URL.Path)
+	assert.Equal(t, entity.WebhookEventPaymentPaid, req.Header.Get(webhook.HeaderEvent))
+	assert.Equal(t, "del-1", req.Header.Get(webhook.HeaderDelivery))
+	timestamp, err := strconv.ParseInt(req.Header.Get(webhook.HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, webhook.Verify("whsec_wh-1", req.Header.Get(webhook.HeaderSignature), timestamp, body, time.Now(), time.Minute))
+
+	var payload struct {
+		ID       string `json:"id"`
+		Event    string `json:"event"`
+		TenantID string `json:"tenant_id"`
+		Data     struct {
+			Invoice  map[string]interface{} `json:"invoice"`
+			Customer map[string]interface{} `json:"customer"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, delivery.EventID, payload.ID)
+	assert.Equal(t, entity.WebhookEventPaymentPaid, payload.Event)
+	assert.Equal(t, "tenant-1", payload.TenantID)
+	assert.Equal(t, number, payload.Data.Invoice["invoice_number"])
+	assert.Equal(t, "CUST-001", payload.Data.Customer["customer_code"])
+	assert.NotContains(t, string(body), "rahasia")
+}
+
+func TestWebhookService_RunAll_RetriesWithBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int // before the run
+		wantStatus string
+		wantNext   time.Duration
+	}{
+		{"first failure", 0, entity.WebhookDeliveryPending, time.Minute},
+		{"third failure", 2, entity.WebhookDeliveryPending, 4 * time.Minute},
+		{"last attempt", webhookMaxAttempts - 1, entity.WebhookDeliveryFailed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWebhookFixture(t)
+			f.withPlan(true, -1)
+			f.receiver.status = http.StatusInternalServerError
+			w := f.tenantWebhook("wh-1", entity.WebhookEventPaymentPaid)
+			now := time.Now()
+			w.EventsScannedAt = now // nothing new to scan
+			due := now.Add(-time.Minute)
+			delivery := &entity.WebhookDelivery{
+				ID: "del-1", TenantID: "tenant-1", WebhookID: "wh-1", Event: entity.WebhookEventPaymentPaid,
+				Payload: `{}`, Status: entity.WebhookDeliveryPending, Attempts: tt.attempts, NextAttemptAt: &due,
+			}
+			f.repo.On("FindActive", mock.Anything).Return([]*entity.TenantWebhook{w}, nil)
+			f.repo.On("FindDueDeliveries", mock.Anything, now, webhookBatchSize).Return([]*entity.WebhookDelivery{delivery}, nil)
+			f.repo.On("ClaimDelivery", mock.Anything, delivery, mock.Anything).Return(true, nil)
+			f.repo.On("FinishDelivery", mock.Anything, delivery).Return(nil)
+
+			require.NoError(t, f.service.RunAll(context.Background(), now))
+
+			assert.Equal(t, tt.attempts+1, delivery.Attempts)
+			assert.Equal(t, tt.wantStatus, delivery.Status)
+			assert.Contains(t, delivery.Error, "500")
+			require.NotNil(t, delivery.ResponseStatus)
+			assert.Equal(t, 500, *delivery.ResponseStatus)
+			if tt.wantNext == 0 {
+				assert.Nil(t, delivery.NextAttemptAt)
+			} else {
+				require.NotNil(t, delivery.NextAttemptAt)
+				assert.WithinDuration(t, time.Now().Add(tt.wantNext), *delivery.NextAttemptAt, 5*time.Second)
+			}
+			f.repo.AssertNotCalled(t, "SetEventsScanned", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, webhookBackoff(1))
+	assert.Equal(t, 2*time.Minute, webhookBackoff(2))
+	assert.Equal(t, 64*time.Minute, webhookBackoff(7))
+	assert.Equal(t, webhookMaxRetryDelay, webhookBackoff(20))
+}
+
+func TestWebhookService_RunAll_PlanWithoutWebhooks(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.withPlan(false, 2)
+	w := f.tenantWebhook("wh-1", entity.WebhookEventPaymentPaid)
+	now := time.Date(2025, 3, 20, 10, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Minute)
+	delivery := &entity.WebhookDelivery{
+		ID: "del-1", TenantID: "tenant-1", WebhookID: "wh-1", Status: entity.WebhookDeliveryPending, NextAttemptAt: &due,
+	}
+	f.repo.On("FindActive", mock.Anything).Return([]*entity.TenantWebhook{w}, nil)
+	f.repo.On("SetEventsScanned", mock.Anything, "wh-1", now.Add(-webhookEventDelay)).Return(nil)
+	f.repo.On("FindDueDeliveries", mock.Anything, now, webhookBatchSize).Return([]*entity.WebhookDelivery{delivery}, nil)
+	f.repo.On("FinishDelivery", mock.Anything, delivery).Return(nil)
+
+	require.NoError(t, f.service.RunAll(context.Background(), now))
+
+	// Events are skipped and pending deliveries dropped, nothing is sent
+	f.repo.AssertNotCalled(t, "FindPaidInvoices", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	f.repo.AssertNotCalled(t, "ClaimDelivery", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, entity.WebhookDeliveryFailed, delivery.Status)
+	assert.Nil(t, delivery.NextAttemptAt)
+	assert.Empty(t, f.receiver.requests)
+}
+
+func TestWebhookService_RunAll_OverPlanLimit(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.withPlan(true, 1)
+	first := f.tenantWebhook("wh-1", entity.WebhookEventPaymentPaid)
+	second := f.tenantWebhook("wh-2", entity.WebhookEventPaymentPaid)
+	now := time.Date(2025, 3, 20, 10, 0, 0, 0, time.UTC)
+	until := now.Add(-webhookEventDelay)
+	f.repo.On("FindActive", mock.Anything).Return([]*entity.TenantWebhook{first, second}, nil)
+	f.repo.On("FindPaidInvoices", mock.Anything, "tenant-1", first.EventsScannedAt, until).Return([]*entity.Payment{}, nil)
+	f.repo.On("CreateDeliveries", mock.Anything, mock.Anything).Return(nil)
+	f.repo.On("SetEventsScanned", mock.Anything, mock.Anything, until).Return(nil)
+	f.repo.On("FindDueDeliveries", mock.Anything, now, webhookBatchSize).Return([]*entity.WebhookDelivery{}, nil)
+
+	require.NoError(t, f.service.RunAll(context.Background(), now))
+
+	// Only the oldest webhook is served; the other still moves past the events
+	f.repo.AssertNumberOfCalls(t, "FindPaidInvoices", 1)
+	f.repo.AssertNumberOfCalls(t, "CreateDeliveries", 1)
+	f.repo.AssertCalled(t, "SetEventsScanned", mock.Anything, "wh-2", until)
+	f.subscriptionRepo.AssertNumberOfCalls(t, "FindActiveByTenantID", 1)
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	f := newWebhookFixture(t)
+	w := f.tenantWebhook("wh-1", entity.WebhookEventCustomerCreated)
+	original := &entity.WebhookDelivery{
+		ID: "del-1", TenantID: "tenant-1", WebhookID: "wh-1", EventID: "evt-1", Event: entity.WebhookEventCustomerCreated,
+		Payload: `{"id":"evt-1","event":"customer.created"}`, Status: entity.WebhookDeliveryFailed, Attempts: webhookMaxAttempts,
+	}
+	f.repo.On("FindDelivery", mock.Anything, "tenant-1", "del-1").Return(original, nil)
+	f.repo.On("FindByID", mock.Anything, "tenant-1", "wh-1").Return(w, nil)
+	f.repo.On("CreateDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.WebhookDelivery).ID = "del-2"
+	}).Return(nil)
+	f.repo.On("ClaimDelivery", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	f.repo.On("FinishDelivery", mock.Anything, mock.Anything).Return(nil)
+
+	redelivery, err := f.service.Redeliver(context.Background(), "tenant-1", "del-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "del-2", redelivery.ID)
+	require.NotNil(t, redelivery.RedeliveryOf)
+	assert.Equal(t, "del-1", *redelivery.RedeliveryOf)
+	assert.Equal(t, "evt-1", redelivery.EventID)
+	assert.Equal(t, entity.WebhookDeliveryDelivered, redelivery.Status)
+	assert.Equal(t, 1, redelivery.Attempts)
+	require.Len(t, f.receiver.bodies, 1)
+	assert.Equal(t, original.Payload, string(f.receiver.bodies[0]))
+	assert.Equal(t, "del-2", f.receiver.requests[0].Header.Get(webhook.HeaderDelivery))
+
+	// A delivery still being retried is not redelivered
+	original.Status = entity.WebhookDeliveryPending
+	_, err = f.service.Redeliver(context.Background(), "tenant-1", "del-1")
+	appErr, ok := err.(*errors.AppError)
+	require.True(t, ok)
+	assert.Equal(t, 409, appErr.Status)
+}
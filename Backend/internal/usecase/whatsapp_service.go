@@ -0,0 +1,279 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/internal/domain/repository"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+	"github.com/rtrwnet/saas-backend/pkg/whatsapp"
+)
+
+// WhatsAppService sends WhatsApp messages to customers through the tenant's
+// own gateway account (TenantSettings.WhatsappAPIKey). Every message is kept
+// in a send log that follows the gateway's delivery status, and each tenant
+// may only send a limited number of messages per minute so the gateway does
+// not ban their number.
+type WhatsAppService interface {
+	// SendToCustomer sends an operator's message to a customer's phone
+	SendToCustomer(ctx context.Context, tenantID, customerID, text string, sentBy *string) (*entity.WhatsAppMessage, error)
+	// SendNotification sends a dispatcher notification, waiting for the
+	// tenant's rate limit instead of failing
+	SendNotification(ctx context.Context, settings *entity.TenantSettings, customer *entity.Customer, phone, text string) (*entity.WhatsAppMessage, error)
+	// ListMessages returns the messages sent to a customer, newest first
+	ListMessages(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.WhatsAppMessage, error)
+	// StatusWebhookToken returns the token the tenant's status callback URL
+	// carries, empty while no webhook secret is configured
+	StatusWebhookToken(tenantID string) string
+	// HandleStatus checks the token of a delivery status callback of the
+	// gateway and applies it
+	HandleStatus(ctx context.Context, tenantID, token string, body []byte) error
+}
+
+const (
+	whatsAppRateWindow = time.Minute
+	// Longest message an operator can send
+	maxWhatsAppMessageLength = 4096
+)
+
+type whatsAppService struct {
+	whatsAppRepo   repository.WhatsAppRepository
+	settingsRepo   repository.SettingsRepository
+	provider       whatsapp.Provider
+	ratePerMinute  int
+	rateRetryAfter time.Duration
+	webhookSecret  string
+}
+
+// NewWhatsAppService creates the WhatsApp service. Each tenant may send
+// ratePerMinute messages per minute; zero disables the limit. The gateway
+// does not sign its status callbacks, so each tenant's callback URL carries
+// a token derived from webhookSecret instead; without it callbacks are refused.
+func NewWhatsAppService(
+	whatsAppRepo repository.WhatsAppRepository,
+	settingsRepo repository.SettingsRepository,
+	provider whatsapp.Provider,
+	ratePerMinute int,
+	webhookSecret string,
+) WhatsAppService {
+	return &whatsAppService{
+		whatsAppRepo:   whatsAppRepo,
+		settingsRepo:   settingsRepo,
+		provider:       provider,
+		ratePerMinute:  ratePerMinute,
+		rateRetryAfter: 5 * time.Second,
+		webhookSecret:  webhookSecret,
+	}
+}
+
+// whatsAppEnabled reports whether the tenant can send WhatsApp messages
+func whatsAppEnabled(settings *entity.TenantSettings) bool {
+	return settings.WhatsappEnabled && settings.WhatsappAPIKey != ""
+}
+
+func (s *whatsAppService) SendToCustomer(ctx context.Context, tenantID, customerID, text string, sentBy *string) (*entity.WhatsAppMessage, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.NewValidationError("message is required")
+	}
+	if len(text) > maxWhatsAppMessageLength {
+		return nil, errors.NewValidationError("message is too long")
+	}
+
+	settings, err := s.settingsRepo.GetTenantSettings(ctx, tenantID)
+	if err != nil && err != errors.ErrNotFound {
+		return nil, errors.NewDatabaseError("load tenant settings", err)
+	}
+	if settings == nil || !whatsAppEnabled(settings) {
+		return nil, errors.NewValidationError("WhatsApp is not enabled, set it up in the integration settings")
+	}
+
+	customer, err := s.whatsAppRepo.FindCustomer(ctx, tenantID, customerID)
+	if err == errors.ErrNotFound {
+		return nil, errors.NewCustomerNotFoundError(customerID)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("find customer", err)
+	}
+	phone := whatsapp.NormalizePhone(customer.Phone)
+	if phone == "" {
+		return nil, errors.NewValidationError("customer has no valid phone number")
+	}
+
+	if err := s.checkRate(ctx, tenantID, false); err != nil {
+		return nil, err
+	}
+	return s.send(ctx, settings, &entity.WhatsAppMessage{
+		TenantID:   tenantID,
+		CustomerID: &customer.ID,
+		Phone:      phone,
+		Message:    text,
+		Source:     entity.WhatsAppSourceOperator,
+		SentBy:     sentBy,
+	})
+}
+
+func (s *whatsAppService) SendNotification(ctx context.Context, settings *entity.TenantSettings, customer *entity.Customer, phone, text string) (*entity.WhatsAppMessage, error) {
+	if !whatsAppEnabled(settings) {
+		return nil, errors.NewValidationError("WhatsApp is not enabled")
+	}
+	if err := s.checkRate(ctx, customer.TenantID, true); err != nil {
+		return nil, err
+	}
+	return s.send(ctx, settings, &entity.WhatsAppMessage{
+		TenantID:   customer.TenantID,
+		CustomerID: &customer.ID,
+		Phone:      phone,
+		Message:    text,
+		Source:     entity.WhatsAppSourceNotification,
+	})
+}
+
+// checkRate returns ErrRateLimitExceeded when the tenant used up its messages
+// of the last minute, or with wait blocks until one is free
+func (s *whatsAppService) checkRate(ctx context.Context, tenantID string, wait bool) error {
+	if s.ratePerMinute <= 0 {
+		return nil
+	}
+	for {
+		count, err := s.whatsAppRepo.CountSince(ctx, tenantID, time.Now().Add(-whatsAppRateWindow))
+		if err != nil {
+			return errors.NewDatabaseError("count whatsapp messages", err)
+		}
+		if count < int64(s.ratePerMinute) {
+			return nil
+		}
+		if !wait {
+			return errors.ErrRateLimitExceeded
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.rateRetryAfter):
+		}
+	}
+}
+
+// send logs the message and hands it to the gateway. A gateway failure is
+// recorded on the message and returned.
+func (s *whatsAppService) send(ctx context.Context, settings *entity.TenantSettings, message *entity.WhatsAppMessage) (*entity.WhatsAppMessage, error) {
+	message.Provider = s.provider.Name()
+	message.Status = whatsapp.StatusQueued
+	if err := s.whatsAppRepo.Create(ctx, message); err != nil {
+		return nil, errors.NewDatabaseError("create whatsapp message", err)
+	}
+
+	result, sendErr := s.provider.Send(ctx, settings.WhatsappAPIKey, &whatsapp.Message{To: message.Phone, Text: message.Message})
+	now := time.Now()
+	message.StatusAt = &now
+	if sendErr != nil {
+		message.Status = whatsapp.StatusFailed
+		message.Error = sendErr.Error()
+	} else {
+		message.Status = result.Status
+		message.ProviderMessageID = result.MessageID
+		message.SentAt = &now
+	}
+	if err := s.whatsAppRepo.Update(ctx, message); err != nil {
+		logger.Error("Failed to record whatsapp message %s: %v", message.ID, err)
+	}
+
+	if sendErr != nil {
+		return message, errors.New("WHATSAPP_SEND_FAILED", "Failed to send WhatsApp message: "+sendErr.Error(), 502)
+	}
+	return message, nil
+}
+
+func (s *whatsAppService) ListMessages(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.WhatsAppMessage, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	messages, err := s.whatsAppRepo.FindByCustomer(ctx, tenantID, customerID, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list whatsapp messages", err)
+	}
+	return messages, nil
+}
+
+// whatsAppStatusRank orders delivery statuses so late callbacks do not move a
+// message back. A failure can follow sent but not delivered.
+var whatsAppStatusRank = map[string]int{
+	whatsapp.StatusQueued:    0,
+	whatsapp.StatusSent:      1,
+	whatsapp.StatusFailed:    2,
+	whatsapp.StatusDelivered: 3,
+	whatsapp.StatusRead:      4,
+}
+
+func (s *whatsAppService) StatusWebhookToken(tenantID string) string {
+	if s.webhookSecret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+	mac.Write([]byte(tenantID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *whatsAppService) HandleStatus(ctx context.Context, tenantID, token string, body []byte) error {
+	want := s.StatusWebhookToken(tenantID)
+	if want == "" || !hmac.Equal([]byte(token), []byte(want)) {
+		return errors.NewUnauthorizedError("invalid whatsapp webhook token")
+	}
+
+	update, err := s.provider.ParseStatus(body)
+	if err != nil {
+		return errors.NewValidationError(err.Error())
+	}
+
+	message, err := s.whatsAppRepo.FindByProviderMessageID(ctx, tenantID, s.provider.Name(), update.MessageID)
+	if err == errors.ErrNotFound {
+		logger.Info("WhatsApp status for unknown message %s of tenant %s", update.MessageID, tenantID)
+		return nil
+	}
+	if err != nil {
+		return errors.NewDatabaseError("find whatsapp message", err)
+	}
+
+	if whatsAppStatusRank[update.Status] <= whatsAppStatusRank[message.Status] {
+		return nil
+	}
+	now := time.Now()
+	message.Status = update.Status
+	message.StatusAt = &now
+	if err := s.whatsAppRepo.Update(ctx, message); err != nil {
+		return errors.NewDatabaseError("update whatsapp message", err)
+	}
+	return nil
+}
+
+type whatsAppChannel struct {
+	service WhatsAppService
+}
+
+// NewWhatsAppChannel returns the WhatsApp channel for customer notifications,
+// used by tenants that enabled WhatsApp
+func NewWhatsAppChannel(service WhatsAppService) CustomerChannel {
+	return &whatsAppChannel{service: service}
+}
+
+func (c *whatsAppChannel) Name() string {
+	return entity.NotificationChannelWhatsApp
+}
+
+func (c *whatsAppChannel) Recipient(settings *entity.TenantSettings, customer *entity.Customer) string {
+	if !whatsAppEnabled(settings) {
+		return ""
+	}
+	return whatsapp.NormalizePhone(customer.Phone)
+}
+
+func (c *whatsAppChannel) Send(ctx context.Context, settings *entity.TenantSettings, customer *entity.Customer, recipient string, msg *CustomerMessage) error {
+	_, err := c.service.SendNotification(ctx, settings, customer, recipient, msg.Text)
+	return err
+}
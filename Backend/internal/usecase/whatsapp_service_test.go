@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/internal/domain/entity"
+	"github.com/rtrwnet/saas-backend/pkg/errors"
+	"github.com/rtrwnet/saas-backend/pkg/whatsapp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWhatsAppRepository struct {
+	mock.Mock
+}
+
+func (m *MockWhatsAppRepository) FindCustomer(ctx context.Context, tenantID, customerID string) (*entity.Customer, error) {
+	args := m.Called(ctx, tenantID, customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Customer), args.Error(1)
+}
+
+func (m *MockWhatsAppRepository) CountSince(ctx context.Context, tenantID string, since time.Time) (int64, error) {
+	args := m.Called(ctx, tenantID, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWhatsAppRepository) Create(ctx context.Context, message *entity.WhatsAppMessage) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockWhatsAppRepository) Update(ctx context.Context, message *entity.WhatsAppMessage) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockWhatsAppRepository) FindByProviderMessageID(ctx context.Context, tenantID, provider, providerMessageID string) (*entity.WhatsAppMessage, error) {
+	args := m.Called(ctx, tenantID, provider, providerMessageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.WhatsAppMessage), args.Error(1)
+}
+
+func (m *MockWhatsAppRepository) FindByCustomer(ctx context.Context, tenantID, customerID string, limit int) ([]*entity.WhatsAppMessage, error) {
+	args := m.Called(ctx, tenantID, customerID, limit)
+	return args.Get(0).([]*entity.WhatsAppMessage), args.Error(1)
+}
+
+// failingProvider rejects every message like a gateway with a bad token
+type failingProvider struct {
+	whatsapp.StubProvider
+}
+
+func (p *failingProvider) Send(ctx context.Context, token string, msg *whatsapp.Message) (*whatsapp.SendResult, error) {
+	return nil, fmt.Errorf("fonnte error: 200 invalid token")
+}
+
+func TestWhatsAppService_SendToCustomer(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-1"
+	settings := &entity.TenantSettings{WhatsappEnabled: true, WhatsappAPIKey: "device-token"}
+	customer := &entity.Customer{ID: "c1", TenantID: tenantID, Name: "Budi", Phone: "0812-3456-7890"}
+
+	t.Run("Sent", func(t *testing.T) {
+		repo := new(MockWhatsAppRepository)
+		settingsRepo := new(MockSettingsRepository)
+		provider := whatsapp.NewStubProvider()
+		service := NewWhatsAppService(repo, settingsRepo, provider, 20, "")
+
+		userID := "user-1"
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindCustomer", ctx, tenantID, "c1").Return(customer, nil)
+		repo.On("CountSince", ctx, tenantID, mock.Anything).Return(int64(19), nil)
+		repo.On("Create", ctx, mock.AnythingOfType("*entity.WhatsAppMessage")).Return(nil)
+		repo.On("Update", ctx, mock.AnythingOfType("*entity.WhatsAppMessage")).Return(nil)
+
+		message, err := service.SendToCustomer(ctx, tenantID, "c1", "  Halo Budi, gangguan sudah diperbaiki.  ", &userID)
+
+		require.NoError(t, err)
+		assert.Equal(t, "6281234567890", message.Phone)
+		assert.Equal(t, "Halo Budi, gangguan sudah diperbaiki.", message.Message)
+		assert.Equal(t, entity.WhatsAppSourceOperator, message.Source)
+		assert.Equal(t, whatsapp.ProviderStub, message.Provider)
+		assert.Equal(t, "stub-1", message.ProviderMessageID)
+		assert.Equal(t, whatsapp.StatusSent, message.Status)
+		assert.Equal(t, &userID, message.SentBy)
+		assert.NotNil(t, message.SentAt)
+		assert.Equal(t, []whatsapp.Message{{To: "6281234567890", Text: "Halo Budi, gangguan sudah diperbaiki."}}, provider.Sent())
+	})
+
+	t.Run("Rate limited", func(t *testing.T) {
+		repo := new(MockWhatsAppRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewWhatsAppService(repo, settingsRepo, whatsapp.NewStubProvider(), 20, "")
+
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindCustomer", ctx, tenantID, "c1").Return(customer, nil)
+		repo.On("CountSince", ctx, tenantID, mock.Anything).Return(int64(20), nil)
+
+		_, err := service.SendToCustomer(ctx, tenantID, "c1", "Halo", nil)
+
+		assert.Equal(t, errors.ErrRateLimitExceeded, err)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not enabled", func(t *testing.T) {
+		settingsRepo := new(MockSettingsRepository)
+		service := NewWhatsAppService(new(MockWhatsAppRepository), settingsRepo, whatsapp.NewStubProvider(), 20, "")
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(&entity.TenantSettings{WhatsappEnabled: true}, nil)
+
+		_, err := service.SendToCustomer(ctx, tenantID, "c1", "Halo", nil)
+
+		require.Error(t, err)
+		assert.Equal(t, 400, err.(*errors.AppError).Status)
+	})
+
+	t.Run("Gateway failure", func(t *testing.T) {
+		repo := new(MockWhatsAppRepository)
+		settingsRepo := new(MockSettingsRepository)
+		service := NewWhatsAppService(repo, settingsRepo, &failingProvider{}, 0, "")
+
+		settingsRepo.On("GetTenantSettings", ctx, tenantID).Return(settings, nil)
+		repo.On("FindCustomer", ctx, tenantID, "c1").Return(customer, nil)
+		repo.On("Create", ctx, mock.AnythingOfType("*entity.WhatsAppMessage")).Return(nil)
+		repo.On("Update", ctx, mock.AnythingOfType("*entity.WhatsAppMessage")).Return(nil)
+
+		message, err := service.SendToCustomer(ctx, tenantID, "c1", "Halo", nil)
+
+		require.Error(t, err)
+		assert.Equal(t, whatsapp.StatusFailed, message.Status)
+		assert.Equal(t, "fonnte error: 200 invalid token", message.Error)
+		assert.Nil(t, message.SentAt)
+		repo.AssertNotCalled(t, "CountSince", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestWhatsAppService_SendNotification_WaitsForRateLimit(t *testing.T) {
+	ctx := context.Background()
+	settings := &entity.TenantSettings{WhatsappEnabled: true, WhatsappAPIKey: "device-token"}
+	customer := &entity.Customer{ID: "c1", TenantID: "tenant-1", Phone: "081234567890"}
+
+	repo := new(MockWhatsAppRepository)
+	service := NewWhatsAppService(repo, nil, whatsapp.NewStubProvider(), 20, "").(*whatsAppService)
+	service.rateRetryAfter = time.Millisecond
+
+	repo.On("CountSince", ctx, "tenant-1", mock.Anything).Return(int64(20), nil).Twice()
+	repo.On("CountSince", ctx, "tenant-1", mock.Anything).Return(int64(12), nil).Once()
+	repo.On("Create", ctx, mock.AnythingOfType("*entity.WhatsAppMessage")).Return(nil)
+	repo.On("Update", ctx, mock.AnythingOfType("*entity.WhatsAppMessage")).Return(nil)
+
+	err := NewWhatsAppChannel(service).Send(ctx, settings, customer, "6281234567890", &CustomerMessage{Text: "Tagihan Anda jatuh tempo"})
+
+	require.NoError(t, err)
+	repo.AssertNumberOfCalls(t, "CountSince", 3)
+	message := repo.Calls[3].Arguments.Get(1).(*entity.WhatsAppMessage)
+	assert.Equal(t, entity.WhatsAppSourceNotification, message.Source)
+	assert.Equal(t, "c1", *message.CustomerID)
+}
+
+func TestWhatsAppService_HandleStatus(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		current string
+		update  string
+		want    string
+	}{
+		{"Delivered", whatsapp.StatusSent, whatsapp.StatusDelivered, whatsapp.StatusDelivered},
+		{"Failed while queued", whatsapp.StatusQueued, whatsapp.StatusFailed, whatsapp.StatusFailed},
+		{"Late sent after read", whatsapp.StatusRead, whatsapp.StatusSent, whatsapp.StatusRead},
+		{"Failed after delivered", whatsapp.StatusDelivered, whatsapp.StatusFailed, whatsapp.StatusDelivered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockWhatsAppRepository)
+			service := NewWhatsAppService(repo, nil, whatsapp.NewStubProvider(), 20, "hook-secret")
+			message := &entity.WhatsAppMessage{ID: "m1", TenantID: "tenant-1", Status: tt.current}
+
+			repo.On("FindByProviderMessageID", ctx, "tenant-1", whatsapp.ProviderStub, "stub-1").Return(message, nil)
+			repo.On("Update", ctx, message).Return(nil)
+
+			err := service.HandleStatus(ctx, "tenant-1", service.StatusWebhookToken("tenant-1"), []byte(fmt.Sprintf(`{"id":"stub-1","status":%q}`, tt.update)))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, message.Status)
+		})
+	}
+
+	// Statuses of messages sent elsewhere are ignored
+	repo := new(MockWhatsAppRepository)
+	service := NewWhatsAppService(repo, nil, whatsapp.NewStubProvider(), 20, "hook-secret")
+	repo.On("FindByProviderMessageID", ctx, "tenant-1", whatsapp.ProviderStub, "other").Return(nil, errors.ErrNotFound)
+	assert.NoError(t, service.HandleStatus(ctx, "tenant-1", service.StatusWebhookToken("tenant-1"), []byte(`{"id":"other","status":"read"}`)))
+}
+
+func TestWhatsAppService_HandleStatusToken(t *testing.T) {
+	ctx := context.Background()
+	body := []byte(`{"id":"stub-1","status":"read"}`)
+	service := NewWhatsAppService(new(MockWhatsAppRepository), nil, whatsapp.NewStubProvider(), 20, "hook-secret")
+
+	token := service.StatusWebhookToken("tenant-1")
+	assert.NotEmpty(t, token)
+	assert.NotEqual(t, token, service.StatusWebhookToken("tenant-2"))
+
+	tests := []struct {
+		name     string
+		tenantID string
+		token    string
+	}{
+		{"Missing Token", "tenant-1", ""},
+		{"Wrong Token", "tenant-1", "deadbeef"},
+		{"Token Of Another Tenant", "tenant-2", token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.HandleStatus(ctx, tt.tenantID, tt.token, body)
+
+			require.Error(t, err)
+			assert.Equal(t, http.StatusUnauthorized, err.(*errors.AppError).Status)
+		})
+	}
+
+	t.Run("No Webhook Secret", func(t *testing.T) {
+		service := NewWhatsAppService(new(MockWhatsAppRepository), nil, whatsapp.NewStubProvider(), 20, "")
+
+		assert.Empty(t, service.StatusWebhookToken("tenant-1"))
+		err := service.HandleStatus(ctx, "tenant-1", "", body)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, err.(*errors.AppError).Status)
+	})
+}
@@ -0,0 +1,25 @@
+-- Queue sync reports: one row per SyncMikrotikQueues run
+CREATE TABLE IF NOT EXISTS queue_sync_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    device_id UUID NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL, -- planned, success, partial, failed
+    created_count INTEGER NOT NULL DEFAULT 0,
+    updated_count INTEGER NOT NULL DEFAULT 0,
+    removed_count INTEGER NOT NULL DEFAULT 0,
+    unchanged_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    actions JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_queue_sync_reports_device ON queue_sync_reports(device_id, created_at DESC);
+CREATE INDEX idx_queue_sync_reports_tenant ON queue_sync_reports(tenant_id);
+
+COMMENT ON TABLE queue_sync_reports IS 'Result of each Mikrotik simple queue reconciliation run';
+COMMENT ON COLUMN queue_sync_reports.actions IS 'Planned/applied queue changes as JSON array';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS invoice_runs;
+
+DROP INDEX IF EXISTS idx_payments_customer_period;
+ALTER TABLE payments DROP COLUMN IF EXISTS period_end;
+ALTER TABLE payments DROP COLUMN IF EXISTS period_start;
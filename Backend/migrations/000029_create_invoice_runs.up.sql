@@ -0,0 +1,36 @@
+-- Billing period of generated invoices; one invoice per customer per period
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS period_start DATE;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS period_end DATE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_customer_period
+    ON payments(customer_id, period_start) WHERE period_start IS NOT NULL;
+
+COMMENT ON COLUMN payments.period_start IS 'First day of the billing cycle covered by a generated invoice';
+COMMENT ON COLUMN payments.period_end IS 'Last day of the billing cycle covered by a generated invoice';
+
+-- Invoice runs: one row per recurring invoice generator run
+CREATE TABLE IF NOT EXISTS invoice_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    run_date DATE NOT NULL,
+    triggered_by VARCHAR(20) NOT NULL, -- scheduled, manual
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL, -- planned, success, partial, failed
+    billing_type VARCHAR(20) NOT NULL,
+    customer_count INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    not_due_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    total_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    invoices JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_invoice_runs_tenant ON invoice_runs(tenant_id, created_at DESC);
+
+COMMENT ON TABLE invoice_runs IS 'Summary of each recurring invoice generator run';
+COMMENT ON COLUMN invoice_runs.invoices IS 'Created, skipped and failed invoices as JSON array';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS billing_action_logs;
+
+ALTER TABLE customers DROP COLUMN IF EXISTS auto_suspended_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS auto_suspend_exempt_until;
+ALTER TABLE customers DROP COLUMN IF EXISTS auto_suspend_exempt;
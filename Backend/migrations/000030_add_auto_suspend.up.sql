@@ -0,0 +1,24 @@
+-- Per-customer auto-suspend exemption and marker for automatic suspensions
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS auto_suspend_exempt BOOLEAN DEFAULT FALSE;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS auto_suspend_exempt_until TIMESTAMP;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS auto_suspended_at TIMESTAMP;
+
+COMMENT ON COLUMN customers.auto_suspend_exempt IS 'Never suspend this customer automatically';
+COMMENT ON COLUMN customers.auto_suspend_exempt_until IS 'Do not suspend automatically before this time';
+COMMENT ON COLUMN customers.auto_suspended_at IS 'Set when the billing job suspended the customer; cleared on manual suspend or activation';
+
+-- Billing action logs: every automatic action taken on a customer
+CREATE TABLE IF NOT EXISTS billing_action_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    action VARCHAR(30) NOT NULL, -- auto_suspend, auto_reactivate
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_billing_action_logs_customer ON billing_action_logs(customer_id, created_at DESC);
+CREATE INDEX idx_billing_action_logs_tenant ON billing_action_logs(tenant_id, created_at DESC);
+
+COMMENT ON TABLE billing_action_logs IS 'Automatic billing actions (suspend, reactivate) taken on customers';
@@ -0,0 +1,5 @@
+ALTER TABLE payments DROP COLUMN IF EXISTS late_fee_waive_reason;
+ALTER TABLE payments DROP COLUMN IF EXISTS late_fee_waived_by;
+ALTER TABLE payments DROP COLUMN IF EXISTS late_fee_waived_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS late_fee_applied_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS late_fee;
@@ -0,0 +1,10 @@
+-- Late fee charged on an overdue invoice, kept apart from the invoice amount
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS late_fee DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS late_fee_applied_at TIMESTAMP;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS late_fee_waived_at TIMESTAMP;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS late_fee_waived_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS late_fee_waive_reason TEXT;
+
+COMMENT ON COLUMN payments.late_fee IS 'Late fee on top of amount; owed unless late_fee_waived_at is set';
+COMMENT ON COLUMN payments.late_fee_applied_at IS 'Set once when the billing job charged the late fee';
+COMMENT ON COLUMN payments.late_fee_waived_at IS 'Set when an operator waived the late fee';
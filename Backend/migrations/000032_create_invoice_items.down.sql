@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS payment_allocations;
+DROP TABLE IF EXISTS invoice_items;
+DROP TABLE IF EXISTS invoice_sequences;
+
+DROP INDEX IF EXISTS idx_payments_invoice_number;
+
+ALTER TABLE payments DROP COLUMN IF EXISTS paid_amount;
+ALTER TABLE payments DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE payments DROP COLUMN IF EXISTS tax_percentage;
+ALTER TABLE payments DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE payments DROP COLUMN IF EXISTS subtotal;
+ALTER TABLE payments DROP COLUMN IF EXISTS invoice_number;
@@ -0,0 +1,99 @@
+-- Customer invoices (payments) get a number, line items, tax and allocations
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS invoice_number VARCHAR(50);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS subtotal DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS tax_percentage DECIMAL(5,2) NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS paid_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_invoice_number ON payments(tenant_id, invoice_number) WHERE invoice_number IS NOT NULL;
+
+COMMENT ON COLUMN payments.amount IS 'Invoice total: subtotal - discount_amount + tax_amount, without late fee';
+COMMENT ON COLUMN payments.paid_amount IS 'Sum of payment_allocations for the invoice';
+
+-- Last invoice number issued per tenant
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    last_number BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Invoice line items
+CREATE TABLE IF NOT EXISTS invoice_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL, -- monthly_fee, installation, addon, late_fee, discount, other
+    description TEXT NOT NULL,
+    quantity DECIMAL(10,2) NOT NULL DEFAULT 1,
+    unit_price DECIMAL(12,2) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_invoice_items_payment ON invoice_items(payment_id);
+
+-- Money received and allocated against an invoice
+CREATE TABLE IF NOT EXISTS payment_allocations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL,
+    payment_method VARCHAR(50),
+    paid_at TIMESTAMP NOT NULL,
+    reference VARCHAR(100),
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payment_allocations_payment ON payment_allocations(payment_id);
+CREATE INDEX idx_payment_allocations_tenant ON payment_allocations(tenant_id, paid_at DESC);
+
+-- Migrate existing payments: one line item for the old amount, the late fee
+-- as its own line, and an allocation for invoices that were already paid
+UPDATE payments SET subtotal = amount WHERE invoice_number IS NULL;
+
+WITH numbered AS (
+    SELECT p.id,
+           COALESCE(NULLIF(ts.invoice_prefix, ''), 'INV') AS prefix,
+           ROW_NUMBER() OVER (PARTITION BY p.tenant_id ORDER BY p.created_at, p.id) AS n
+    FROM payments p
+    LEFT JOIN tenant_settings ts ON ts.tenant_id = p.tenant_id
+    WHERE p.invoice_number IS NULL
+)
+UPDATE payments p
+SET invoice_number = numbered.prefix || '-' || CASE WHEN numbered.n < 1000000 THEN LPAD(numbered.n::text, 6, '0') ELSE numbered.n::text END
+FROM numbered
+WHERE p.id = numbered.id;
+
+INSERT INTO invoice_sequences (tenant_id, last_number)
+SELECT tenant_id, COUNT(*) FROM payments GROUP BY tenant_id
+ON CONFLICT (tenant_id) DO UPDATE SET last_number = EXCLUDED.last_number;
+
+INSERT INTO invoice_items (tenant_id, payment_id, type, description, quantity, unit_price, amount, created_at)
+SELECT p.tenant_id, p.id,
+       CASE WHEN p.period_start IS NOT NULL THEN 'monthly_fee' ELSE 'other' END,
+       COALESCE(NULLIF(p.notes, ''), 'Invoice'),
+       1, p.amount, p.amount, p.created_at
+FROM payments p
+WHERE NOT EXISTS (SELECT 1 FROM invoice_items i WHERE i.payment_id = p.id);
+
+INSERT INTO invoice_items (tenant_id, payment_id, type, description, quantity, unit_price, amount, created_at)
+SELECT p.tenant_id, p.id, 'late_fee', 'Late fee', 1, p.late_fee, p.late_fee, p.late_fee_applied_at
+FROM payments p
+WHERE p.late_fee_applied_at IS NOT NULL;
+
+UPDATE payments
+SET paid_amount = amount + CASE WHEN late_fee_waived_at IS NULL THEN late_fee ELSE 0 END
+WHERE status = 'paid';
+
+INSERT INTO payment_allocations (tenant_id, customer_id, payment_id, amount, payment_method, paid_at, notes, created_at)
+SELECT p.tenant_id, p.customer_id, p.id, p.paid_amount, p.payment_method,
+       COALESCE(p.payment_date, p.updated_at), 'Migrated from payment record', p.updated_at
+FROM payments p
+WHERE p.status = 'paid';
+
+COMMENT ON TABLE invoice_items IS 'Line items of customer invoices (payments)';
+COMMENT ON TABLE payment_allocations IS 'Customer payments allocated against invoices';
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS invoice_payment_orders;
+
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS midtrans_is_production;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS midtrans_client_key;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS midtrans_server_key;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS midtrans_enabled;
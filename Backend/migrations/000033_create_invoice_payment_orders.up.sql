@@ -0,0 +1,26 @@
+-- Tenants can let their customers pay invoices online through their own Midtrans account
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS midtrans_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS midtrans_server_key TEXT;
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS midtrans_client_key TEXT;
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS midtrans_is_production BOOLEAN DEFAULT FALSE;
+
+-- One Midtrans charge for a customer invoice; a new one is created when the
+-- customer picks another payment method or the previous charge expired
+CREATE TABLE IF NOT EXISTS invoice_payment_orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    order_id VARCHAR(50) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    payment_method VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, paid, failed
+    gateway_transaction_id VARCHAR(255),
+    gateway_response JSONB DEFAULT '{}',
+    expired_at TIMESTAMP,
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_payment_orders_order ON invoice_payment_orders(tenant_id, order_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_payment_orders_payment ON invoice_payment_orders(payment_id, created_at DESC);
@@ -0,0 +1,28 @@
+-- Every inbound payment webhook with its verification and processing outcome.
+-- idempotency_key is held by the event that applied (or is applying) an
+-- (order_id, transaction_status) pair, so repeated notifications are skipped
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source VARCHAR(30) NOT NULL, -- midtrans, xendit, payment, tenant_midtrans
+    tenant_id VARCHAR(64), -- as given in the webhook URL, kept even when unknown
+    headers JSONB DEFAULT '{}',
+    body TEXT,
+    signature_valid BOOLEAN,
+    order_id VARCHAR(100),
+    transaction_status VARCHAR(50),
+    payment_status VARCHAR(20),
+    payment_type VARCHAR(50),
+    transaction_id VARCHAR(255),
+    amount DECIMAL(12,2) DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'received', -- received, processed, failed, duplicate, rejected
+    error TEXT,
+    idempotency_key VARCHAR(255),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_events_idempotency_key ON webhook_events(idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_created ON webhook_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_order ON webhook_events(order_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_status ON webhook_events(source, status);
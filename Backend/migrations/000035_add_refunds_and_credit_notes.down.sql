@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS credit_notes;
+ALTER TABLE invoice_sequences DROP COLUMN IF EXISTS last_credit_note_number;
+ALTER TABLE payments DROP COLUMN IF EXISTS credited_amount;
+
+DROP TABLE IF EXISTS payment_transaction_refunds;
+ALTER TABLE payment_transactions DROP COLUMN IF EXISTS refunded_amount;
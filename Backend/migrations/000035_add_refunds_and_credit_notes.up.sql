@@ -0,0 +1,41 @@
+-- Refunds of paid subscription payments, issued by platform admins
+ALTER TABLE payment_transactions ADD COLUMN IF NOT EXISTS refunded_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS payment_transaction_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL REFERENCES payment_transactions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL,
+    reason TEXT,
+    method VARCHAR(20) NOT NULL, -- gateway, manual
+    gateway_refund_id VARCHAR(255),
+    status VARCHAR(20) NOT NULL, -- pending, succeeded
+    refunded_by UUID,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_payment_transaction_refunds_transaction ON payment_transaction_refunds(transaction_id);
+
+-- Credit notes reduce what a customer owes on an invoice; on paid invoices
+-- the credited amount is refunded
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS credited_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE invoice_sequences ADD COLUMN IF NOT EXISTS last_credit_note_number BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    credit_note_number VARCHAR(50) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    refund_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    refund_method VARCHAR(20), -- cash, transfer, midtrans
+    refund_reference VARCHAR(255),
+    reason TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_notes_number ON credit_notes(tenant_id, credit_note_number);
+CREATE INDEX IF NOT EXISTS idx_credit_notes_payment ON credit_notes(payment_id);
+
+COMMENT ON COLUMN payments.paid_amount IS 'Sum of payment_allocations for the invoice, less refunds from credit_notes';
+COMMENT ON COLUMN payments.credited_amount IS 'Sum of credit_notes for the invoice';
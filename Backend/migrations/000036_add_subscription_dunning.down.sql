@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tenant_subscriptions_next_billing_date;
+DROP TABLE IF EXISTS subscription_dunning_steps;
+ALTER TABLE tenant_subscriptions DROP COLUMN IF EXISTS grace_until;
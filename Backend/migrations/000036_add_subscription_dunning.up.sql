@@ -0,0 +1,15 @@
+-- Unpaid auto-renewing subscriptions become past_due and stay usable until grace_until
+ALTER TABLE tenant_subscriptions ADD COLUMN IF NOT EXISTS grace_until TIMESTAMP;
+
+-- Renewal orders, reminders and dunning status changes taken per billing date
+CREATE TABLE IF NOT EXISTS subscription_dunning_steps (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES tenant_subscriptions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    billing_date TIMESTAMP NOT NULL,
+    step VARCHAR(30) NOT NULL, -- renewal_order, reminder_<days>, grace, suspended, expired
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_dunning_steps_step ON subscription_dunning_steps(subscription_id, billing_date, step);
+CREATE INDEX IF NOT EXISTS idx_subscription_dunning_steps_tenant ON subscription_dunning_steps(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_tenant_subscriptions_next_billing_date ON tenant_subscriptions(next_billing_date);
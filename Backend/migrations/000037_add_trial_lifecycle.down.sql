@@ -0,0 +1,3 @@
+UPDATE tenant_subscriptions SET status = 'expired' WHERE status = 'read_only';
+DROP INDEX IF EXISTS idx_tenant_subscriptions_trial_ends_at;
+ALTER TABLE tenant_subscriptions DROP COLUMN IF EXISTS trial_ends_at;
@@ -0,0 +1,4 @@
+-- When a subscription's trial ends; kept after conversion for trial metrics
+ALTER TABLE tenant_subscriptions ADD COLUMN IF NOT EXISTS trial_ends_at TIMESTAMP;
+UPDATE tenant_subscriptions SET trial_ends_at = end_date WHERE status = 'trial' AND trial_ends_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tenant_subscriptions_trial_ends_at ON tenant_subscriptions(trial_ends_at);
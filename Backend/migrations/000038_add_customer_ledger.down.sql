@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS customer_ledger_entries;
+ALTER TABLE customers DROP COLUMN IF EXISTS credit_balance;
@@ -0,0 +1,18 @@
+-- Prepaid credit of a customer; every change is recorded in customer_ledger_entries
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS credit_balance DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS customer_ledger_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    balance_after DECIMAL(12,2) NOT NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_customer_ledger_entries_customer ON customer_ledger_entries(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_customer_ledger_entries_tenant ON customer_ledger_entries(tenant_id);
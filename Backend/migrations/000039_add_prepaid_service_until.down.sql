@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_customers_service_until;
+ALTER TABLE payments DROP COLUMN IF EXISTS service_extended_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS expiry_reminded_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS service_until;
@@ -0,0 +1,9 @@
+-- Prepaid billing: paid invoices extend the customer's service
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS service_until TIMESTAMP;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS expiry_reminded_at TIMESTAMP;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS service_extended_at TIMESTAMP;
+
+-- Invoices paid before this change must not extend service again
+UPDATE payments SET service_extended_at = COALESCE(payment_date, updated_at) WHERE status = 'paid' AND service_extended_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_customers_service_until ON customers(tenant_id, service_until);
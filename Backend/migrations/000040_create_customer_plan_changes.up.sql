@@ -0,0 +1,24 @@
+-- Plan history of customers; next-cycle changes stay scheduled until their effective date.
+-- Plan IDs carry no foreign key so the history outlives deleted plans.
+CREATE TABLE IF NOT EXISTS customer_plan_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    from_plan_id UUID NOT NULL,
+    to_plan_id UUID NOT NULL,
+    from_monthly_fee DECIMAL(12,2) NOT NULL,
+    to_monthly_fee DECIMAL(12,2) NOT NULL,
+    effective VARCHAR(20) NOT NULL,
+    effective_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    proration_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    proration_payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    applied_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_customer_plan_changes_customer ON customer_plan_changes(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_customer_plan_changes_due ON customer_plan_changes(tenant_id, status, effective_date);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS speed_boosts;
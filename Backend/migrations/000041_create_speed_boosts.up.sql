@@ -0,0 +1,28 @@
+-- Temporary plan upgrades. An active boost overrides the customer's
+-- Mikrotik-Rate-Limit in radreply until end_date. Plan IDs carry no foreign
+-- key so the history outlives deleted plans.
+CREATE TABLE IF NOT EXISTS speed_boosts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    current_plan_id UUID NOT NULL,
+    boost_plan_id UUID NOT NULL,
+    duration_days INTEGER NOT NULL,
+    price DECIMAL(12,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    request_date TIMESTAMP NOT NULL,
+    start_date TIMESTAMP,
+    end_date TIMESTAMP,
+    notes TEXT,
+    rejection_reason TEXT,
+    requested_by VARCHAR(20) NOT NULL DEFAULT 'operator',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    expired_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_speed_boosts_tenant ON speed_boosts(tenant_id, status, request_date);
+CREATE INDEX IF NOT EXISTS idx_speed_boosts_customer ON speed_boosts(customer_id, status);
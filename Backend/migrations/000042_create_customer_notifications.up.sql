@@ -0,0 +1,23 @@
+-- Delivery log of billing reminders and notices sent to customers. dedup_key
+-- keeps the daily dispatcher from sending the same message twice.
+CREATE TABLE IF NOT EXISTS customer_notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    event VARCHAR(30) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255),
+    body TEXT,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    dedup_key VARCHAR(150) NOT NULL,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_notifications_dedup ON customer_notifications(dedup_key);
+CREATE INDEX IF NOT EXISTS idx_customer_notifications_customer ON customer_notifications(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_customer_notifications_tenant ON customer_notifications(tenant_id);
@@ -0,0 +1,22 @@
+-- WhatsApp messages sent to customers, with the delivery status reported by
+-- the gateway. The tenant's rate limit counts rows by created_at.
+CREATE TABLE IF NOT EXISTS whatsapp_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID REFERENCES customers(id) ON DELETE SET NULL,
+    phone VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    provider_message_id VARCHAR(100),
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    sent_by UUID,
+    sent_at TIMESTAMP,
+    status_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_tenant ON whatsapp_messages(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_customer ON whatsapp_messages(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_provider_id ON whatsapp_messages(tenant_id, provider_message_id);
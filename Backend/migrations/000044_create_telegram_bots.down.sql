@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS telegram_chats;
+DROP TABLE IF EXISTS telegram_bots;
@@ -0,0 +1,32 @@
+-- State of each tenant's Telegram bot and the operator chats linked to it.
+-- A chat row is created with a link code and gets its chat_id once the user
+-- sends "/start <code>" to the bot.
+CREATE TABLE IF NOT EXISTS telegram_bots (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64),
+    bot_username VARCHAR(100),
+    update_offset BIGINT NOT NULL DEFAULT 0,
+    webhook_url VARCHAR(500),
+    webhook_secret VARCHAR(64),
+    events_pushed_at TIMESTAMP,
+    overdue_pushed_on DATE,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS telegram_chats (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chat_id BIGINT,
+    username VARCHAR(100),
+    link_code VARCHAR(20),
+    link_code_expires_at TIMESTAMP,
+    linked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_chat_user ON telegram_chats(tenant_id, user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_chats_chat ON telegram_chats(tenant_id, chat_id) WHERE chat_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_chats_link_code ON telegram_chats(link_code) WHERE link_code IS NOT NULL;
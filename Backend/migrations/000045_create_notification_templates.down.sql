@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS notification_templates;
+
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS send_ticket_notification;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS send_invoice_notification;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS notification_language;
@@ -0,0 +1,21 @@
+-- Language of customer and staff notifications, and switches for the
+-- notifications added with templates
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS notification_language VARCHAR(5) DEFAULT 'id';
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS send_invoice_notification BOOLEAN DEFAULT FALSE;
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS send_ticket_notification BOOLEAN DEFAULT FALSE;
+
+-- A tenant's own text for the message of an event on one channel in one
+-- language. Events without one are sent with the built-in text.
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event VARCHAR(30) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    language VARCHAR(5) NOT NULL,
+    subject VARCHAR(255),
+    body TEXT NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_templates_key ON notification_templates(tenant_id, event, channel, language);
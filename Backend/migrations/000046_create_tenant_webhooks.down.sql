@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS tenant_webhooks;
+
+ALTER TABLE customers DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_credit_notes_pending;
+ALTER TABLE credit_notes DROP COLUMN IF EXISTS status;
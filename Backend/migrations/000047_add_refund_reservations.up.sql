@@ -0,0 +1,6 @@
+-- Gateway refunds are reserved before the gateway is asked and completed or
+-- failed afterwards, so the refund never runs inside a database transaction
+ALTER TABLE credit_notes ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'issued'; -- pending, issued, failed
+CREATE INDEX IF NOT EXISTS idx_credit_notes_pending ON credit_notes(payment_id) WHERE status = 'pending';
+
+COMMENT ON COLUMN payment_transaction_refunds.status IS 'processing while the gateway is asked, then pending, succeeded or failed';
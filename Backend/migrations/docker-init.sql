@@ -85,6 +85,7 @@ CREATE TABLE IF NOT EXISTS tenant_subscriptions (
     payment_method VARCHAR(50) DEFAULT '',
     auto_renew BOOLEAN DEFAULT TRUE,
     trial_ends_at TIMESTAMP,
+    grace_until TIMESTAMP,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
@@ -101,6 +102,7 @@ CREATE TABLE IF NOT EXISTS payment_transactions (
     plan_id UUID REFERENCES subscription_plans(id),
     order_id VARCHAR(255) NOT NULL UNIQUE,
     amount DECIMAL(12,2) NOT NULL,
+    refunded_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
     status VARCHAR(50) NOT NULL DEFAULT 'pending',
     payment_method VARCHAR(100),
     payment_gateway VARCHAR(50),
@@ -182,7 +184,14 @@ CREATE TABLE IF NOT EXISTS customers (
     installation_date TIMESTAMP,
     due_date INTEGER DEFAULT 15,
     monthly_fee DECIMAL(12,2) NOT NULL DEFAULT 0,
+    credit_balance DECIMAL(12,2) NOT NULL DEFAULT 0,
     notes TEXT,
+    service_until TIMESTAMP,
+    expiry_reminded_at TIMESTAMP,
+    auto_suspend_exempt BOOLEAN DEFAULT FALSE,
+    auto_suspend_exempt_until TIMESTAMP,
+    auto_suspended_at TIMESTAMP,
+    suspended_at TIMESTAMP,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP,
@@ -202,17 +211,34 @@ CREATE TABLE IF NOT EXISTS payments (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
     customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    invoice_number VARCHAR(50),
+    subtotal DECIMAL(12,2) NOT NULL DEFAULT 0,
+    discount_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    tax_percentage DECIMAL(5,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
     amount DECIMAL(12,2) NOT NULL,
+    paid_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    credited_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
     payment_date TIMESTAMP,
     due_date TIMESTAMP NOT NULL,
     status VARCHAR(50) NOT NULL DEFAULT 'pending',
     payment_method VARCHAR(50),
     notes TEXT,
+    period_start DATE,
+    period_end DATE,
+    service_extended_at TIMESTAMP,
+    late_fee DECIMAL(12,2) NOT NULL DEFAULT 0,
+    late_fee_applied_at TIMESTAMP,
+    late_fee_waived_at TIMESTAMP,
+    late_fee_waived_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    late_fee_waive_reason TEXT,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 CREATE INDEX IF NOT EXISTS idx_payments_tenant_id ON payments(tenant_id);
 CREATE INDEX IF NOT EXISTS idx_payments_customer_id ON payments(customer_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_customer_period ON payments(customer_id, period_start) WHERE period_start IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_invoice_number ON payments(tenant_id, invoice_number) WHERE invoice_number IS NOT NULL;
 
 -- ============================================
 -- 10. TICKETS
@@ -678,6 +704,10 @@ CREATE TABLE IF NOT EXISTS tenant_settings (
     whatsapp_api_key TEXT,
     telegram_enabled BOOLEAN DEFAULT FALSE,
     telegram_bot_token TEXT,
+    midtrans_enabled BOOLEAN DEFAULT FALSE,
+    midtrans_server_key TEXT,
+    midtrans_client_key TEXT,
+    midtrans_is_production BOOLEAN DEFAULT FALSE,
     billing_type VARCHAR(20) DEFAULT 'postpaid',
     billing_date_type VARCHAR(20) DEFAULT 'fixed',
     billing_day INTEGER DEFAULT 1,
@@ -689,6 +719,9 @@ CREATE TABLE IF NOT EXISTS tenant_settings (
     warning_days_before_suspension INTEGER DEFAULT 3,
     auto_reactivate_on_payment BOOLEAN DEFAULT TRUE,
     send_payment_confirmation BOOLEAN DEFAULT TRUE,
+    notification_language VARCHAR(5) DEFAULT 'id',
+    send_invoice_notification BOOLEAN DEFAULT FALSE,
+    send_ticket_notification BOOLEAN DEFAULT FALSE,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
@@ -763,3 +796,441 @@ CREATE TABLE IF NOT EXISTS vpn_connections (
 );
 CREATE INDEX IF NOT EXISTS idx_vpn_connections_tenant ON vpn_connections(tenant_id);
 CREATE INDEX IF NOT EXISTS idx_vpn_connections_device ON vpn_connections(device_id);
+
+
+-- ============================================
+-- QUEUE SYNC REPORTS
+-- ============================================
+CREATE TABLE IF NOT EXISTS queue_sync_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    device_id UUID NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    updated_count INTEGER NOT NULL DEFAULT 0,
+    removed_count INTEGER NOT NULL DEFAULT 0,
+    unchanged_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    actions JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_queue_sync_reports_device ON queue_sync_reports(device_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_queue_sync_reports_tenant ON queue_sync_reports(tenant_id);
+
+
+-- ============================================
+-- INVOICE RUNS
+-- ============================================
+CREATE TABLE IF NOT EXISTS invoice_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    run_date DATE NOT NULL,
+    triggered_by VARCHAR(20) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL,
+    billing_type VARCHAR(20) NOT NULL,
+    customer_count INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    not_due_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    total_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    invoices JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_invoice_runs_tenant ON invoice_runs(tenant_id, created_at DESC);
+
+
+-- ============================================
+-- BILLING ACTION LOGS
+-- ============================================
+CREATE TABLE IF NOT EXISTS billing_action_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    action VARCHAR(30) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_billing_action_logs_customer ON billing_action_logs(customer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_billing_action_logs_tenant ON billing_action_logs(tenant_id, created_at DESC);
+
+
+-- ============================================
+-- INVOICE ITEMS & PAYMENT ALLOCATIONS
+-- ============================================
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    last_number BIGINT NOT NULL DEFAULT 0,
+    last_credit_note_number BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS invoice_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    description TEXT NOT NULL,
+    quantity DECIMAL(10,2) NOT NULL DEFAULT 1,
+    unit_price DECIMAL(12,2) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_invoice_items_payment ON invoice_items(payment_id);
+
+CREATE TABLE IF NOT EXISTS payment_allocations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL,
+    payment_method VARCHAR(50),
+    paid_at TIMESTAMP NOT NULL,
+    reference VARCHAR(100),
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_payment_allocations_payment ON payment_allocations(payment_id);
+CREATE INDEX IF NOT EXISTS idx_payment_allocations_tenant ON payment_allocations(tenant_id, paid_at DESC);
+
+
+-- ============================================
+-- ONLINE INVOICE PAYMENTS (tenant Midtrans)
+-- ============================================
+CREATE TABLE IF NOT EXISTS invoice_payment_orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    order_id VARCHAR(50) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    payment_method VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    gateway_transaction_id VARCHAR(255),
+    gateway_response JSONB DEFAULT '{}',
+    expired_at TIMESTAMP,
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_payment_orders_order ON invoice_payment_orders(tenant_id, order_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_payment_orders_payment ON invoice_payment_orders(payment_id, created_at DESC);
+
+
+-- ============================================
+-- WEBHOOK EVENTS
+-- ============================================
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source VARCHAR(30) NOT NULL,
+    tenant_id VARCHAR(64),
+    headers JSONB DEFAULT '{}',
+    body TEXT,
+    signature_valid BOOLEAN,
+    order_id VARCHAR(100),
+    transaction_status VARCHAR(50),
+    payment_status VARCHAR(20),
+    payment_type VARCHAR(50),
+    transaction_id VARCHAR(255),
+    amount DECIMAL(12,2) DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'received',
+    error TEXT,
+    idempotency_key VARCHAR(255),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_events_idempotency_key ON webhook_events(idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_created ON webhook_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_order ON webhook_events(order_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_status ON webhook_events(source, status);
+
+
+-- ============================================
+-- REFUNDS AND CREDIT NOTES
+-- ============================================
+CREATE TABLE IF NOT EXISTS payment_transaction_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL REFERENCES payment_transactions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL,
+    reason TEXT,
+    method VARCHAR(20) NOT NULL,
+    gateway_refund_id VARCHAR(255),
+    status VARCHAR(20) NOT NULL, -- processing, pending, succeeded, failed
+    refunded_by UUID,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_payment_transaction_refunds_transaction ON payment_transaction_refunds(transaction_id);
+
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    credit_note_number VARCHAR(50) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    refund_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    refund_method VARCHAR(20),
+    refund_reference VARCHAR(255),
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'issued', -- pending, issued, failed
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_notes_number ON credit_notes(tenant_id, credit_note_number);
+CREATE INDEX IF NOT EXISTS idx_credit_notes_payment ON credit_notes(payment_id);
+CREATE INDEX IF NOT EXISTS idx_credit_notes_pending ON credit_notes(payment_id) WHERE status = 'pending';
+
+-- ============================================
+-- SUBSCRIPTION DUNNING
+-- ============================================
+CREATE TABLE IF NOT EXISTS subscription_dunning_steps (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES tenant_subscriptions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    billing_date TIMESTAMP NOT NULL,
+    step VARCHAR(30) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_dunning_steps_step ON subscription_dunning_steps(subscription_id, billing_date, step);
+CREATE INDEX IF NOT EXISTS idx_subscription_dunning_steps_tenant ON subscription_dunning_steps(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_tenant_subscriptions_next_billing_date ON tenant_subscriptions(next_billing_date);
+
+-- ============================================
+-- TRIAL LIFECYCLE
+-- ============================================
+CREATE INDEX IF NOT EXISTS idx_tenant_subscriptions_trial_ends_at ON tenant_subscriptions(trial_ends_at);
+
+-- ============================================
+-- CUSTOMER LEDGER
+-- ============================================
+CREATE TABLE IF NOT EXISTS customer_ledger_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    balance_after DECIMAL(12,2) NOT NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_customer_ledger_entries_customer ON customer_ledger_entries(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_customer_ledger_entries_tenant ON customer_ledger_entries(tenant_id);
+
+-- ============================================
+-- PREPAID SERVICE
+-- ============================================
+CREATE INDEX IF NOT EXISTS idx_customers_service_until ON customers(tenant_id, service_until);
+
+-- ============================================
+-- CUSTOMER PLAN CHANGES
+-- ============================================
+CREATE TABLE IF NOT EXISTS customer_plan_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    from_plan_id UUID NOT NULL,
+    to_plan_id UUID NOT NULL,
+    from_monthly_fee DECIMAL(12,2) NOT NULL,
+    to_monthly_fee DECIMAL(12,2) NOT NULL,
+    effective VARCHAR(20) NOT NULL,
+    effective_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    proration_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    proration_payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    applied_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_customer_plan_changes_customer ON customer_plan_changes(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_customer_plan_changes_due ON customer_plan_changes(tenant_id, status, effective_date);
+
+-- ============================================
+-- SPEED BOOSTS
+-- ============================================
+CREATE TABLE IF NOT EXISTS speed_boosts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    current_plan_id UUID NOT NULL,
+    boost_plan_id UUID NOT NULL,
+    duration_days INTEGER NOT NULL,
+    price DECIMAL(12,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    request_date TIMESTAMP NOT NULL,
+    start_date TIMESTAMP,
+    end_date TIMESTAMP,
+    notes TEXT,
+    rejection_reason TEXT,
+    requested_by VARCHAR(20) NOT NULL DEFAULT 'operator',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    expired_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_speed_boosts_tenant ON speed_boosts(tenant_id, status, request_date);
+CREATE INDEX IF NOT EXISTS idx_speed_boosts_customer ON speed_boosts(customer_id, status);
+
+-- ============================================
+-- CUSTOMER NOTIFICATIONS
+-- ============================================
+CREATE TABLE IF NOT EXISTS customer_notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    event VARCHAR(30) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255),
+    body TEXT,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    dedup_key VARCHAR(150) NOT NULL,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_notifications_dedup ON customer_notifications(dedup_key);
+CREATE INDEX IF NOT EXISTS idx_customer_notifications_customer ON customer_notifications(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_customer_notifications_tenant ON customer_notifications(tenant_id);
+
+-- ============================================
+-- WHATSAPP MESSAGES
+-- ============================================
+CREATE TABLE IF NOT EXISTS whatsapp_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    customer_id UUID REFERENCES customers(id) ON DELETE SET NULL,
+    phone VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    provider_message_id VARCHAR(100),
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    sent_by UUID,
+    sent_at TIMESTAMP,
+    status_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_tenant ON whatsapp_messages(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_customer ON whatsapp_messages(customer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_provider_id ON whatsapp_messages(tenant_id, provider_message_id);
+
+-- ============================================
+-- TELEGRAM BOTS
+-- ============================================
+CREATE TABLE IF NOT EXISTS telegram_bots (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64),
+    bot_username VARCHAR(100),
+    update_offset BIGINT NOT NULL DEFAULT 0,
+    webhook_url VARCHAR(500),
+    webhook_secret VARCHAR(64),
+    events_pushed_at TIMESTAMP,
+    overdue_pushed_on DATE,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS telegram_chats (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chat_id BIGINT,
+    username VARCHAR(100),
+    link_code VARCHAR(20),
+    link_code_expires_at TIMESTAMP,
+    linked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_chat_user ON telegram_chats(tenant_id, user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_chats_chat ON telegram_chats(tenant_id, chat_id) WHERE chat_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_chats_link_code ON telegram_chats(link_code) WHERE link_code IS NOT NULL;
+
+-- ============================================
+-- NOTIFICATION TEMPLATES
+-- ============================================
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event VARCHAR(30) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    language VARCHAR(5) NOT NULL,
+    subject VARCHAR(255),
+    body TEXT NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_templates_key ON notification_templates(tenant_id, event, channel, language);
+
+-- ============================================
+-- TENANT WEBHOOKS
+-- ============================================
+CREATE TABLE IF NOT EXISTS tenant_webhooks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    description VARCHAR(255),
+    events JSONB NOT NULL DEFAULT '[]',
+    secret VARCHAR(100) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    events_scanned_at TIMESTAMP NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_tenant_webhooks_tenant ON tenant_webhooks(tenant_id);
+
+-- A redelivery is a new row pointing at the delivery it repeats, so only
+-- first deliveries are unique per event
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    webhook_id UUID NOT NULL REFERENCES tenant_webhooks(id) ON DELETE CASCADE,
+    event_id VARCHAR(64) NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    duration_ms INTEGER,
+    delivered_at TIMESTAMP,
+    redelivery_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(webhook_id, event_id) WHERE redelivery_of IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
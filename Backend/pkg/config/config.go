@@ -20,9 +20,17 @@ type Config struct {
 	Email      EmailConfig
 	RabbitMQ   RabbitMQConfig
 	Backup     BackupConfig
+	Payment    PaymentConfig
 	Midtrans   MidtransConfig
+	Xendit     XenditConfig
 	R2Storage  R2StorageConfig
 	VPN        VPNConfig
+	Mikrotik   MikrotikConfig
+	Radius     RadiusConfig
+	Billing    BillingConfig
+	WhatsApp   WhatsAppConfig
+	Telegram   TelegramConfig
+	Webhook    WebhookConfig
 }
 
 type ServerConfig struct {
@@ -86,12 +94,22 @@ type BackupConfig struct {
 	RetentionDays int
 }
 
+// PaymentConfig selects the gateway used for new platform payments
+type PaymentConfig struct {
+	Gateway string // midtrans or xendit
+}
+
 type MidtransConfig struct {
 	ServerKey    string
 	ClientKey    string
 	IsProduction bool
 }
 
+type XenditConfig struct {
+	SecretKey     string
+	CallbackToken string
+}
+
 type R2StorageConfig struct {
 	AccountID       string
 	AccessKeyID     string
@@ -106,6 +124,69 @@ type VPNConfig struct {
 	RadiusInternalIP string
 }
 
+type MikrotikConfig struct {
+	MaxConnsPerRouter int
+	IdleTimeout       time.Duration
+	DialRetries       int
+	FailureThreshold  int
+	CircuitCooldown   time.Duration
+}
+
+type RadiusConfig struct {
+	CoAPort    int
+	CoATimeout time.Duration
+	CoARetries int
+}
+
+// WhatsAppConfig selects the gateway tenants send WhatsApp messages through.
+// Each tenant uses their own device token, TenantSettings.WhatsappAPIKey.
+type WhatsAppConfig struct {
+	Provider      string // fonnte or stub
+	APIURL        string // overrides the gateway URL
+	RatePerMinute int    // messages per tenant per minute, 0 for no limit
+	WebhookSecret string // signs the tenants' status callback tokens
+}
+
+// TelegramConfig runs the tenants' Telegram bots. Each tenant uses their own
+// bot token, TenantSettings.TelegramBotToken.
+type TelegramConfig struct {
+	APIURL       string        // overrides the Bot API URL
+	WebhookURL   string        // public API URL for bot webhooks, empty to long-poll instead
+	PushInterval time.Duration // how often new events are pushed to linked chats
+	OverdueHour  int           // hour of the day the overdue list is pushed
+}
+
+// WebhookConfig runs the deliveries to tenant webhooks. Each webhook signs
+// with its own secret, TenantWebhook.Secret.
+type WebhookConfig struct {
+	Interval     time.Duration // how often new events are queued and due deliveries sent
+	Timeout      time.Duration // how long a webhook has to answer
+	AllowPrivate bool          // allow webhooks on private and loopback addresses
+}
+
+type BillingConfig struct {
+	InvoiceInterval      time.Duration
+	AutoSuspendInterval  time.Duration
+	LateFeeInterval      time.Duration
+	PrepaidInterval      time.Duration // prepaid service extension catch-up and expiry reminders
+	PlanChangeInterval   time.Duration // scheduled customer plan changes
+	SpeedBoostInterval   time.Duration // speed boost start and revert
+	SpeedBoostLinkSecret string        // signs the links customers request speed boosts with
+	SpeedBoostPageURL    string        // customer speed boost request page, the link token is appended
+	NotificationInterval time.Duration // customer payment reminders, notices and confirmations
+	PaymentPageURL       string        // customer invoice payment page, the invoice ID is appended
+	// Tenant subscription renewal and dunning
+	RenewalInterval     time.Duration
+	RenewalLeadDays     int   // renewal orders are created this many days before NextBillingDate
+	RenewalReminderDays []int // reminders are sent this many days before NextBillingDate
+	GracePeriodDays     int   // unpaid subscriptions stay usable this long after NextBillingDate
+	SuspensionDays      int   // then stay suspended this long before they expire
+	// Issuer printed on subscription invoices
+	PlatformName    string
+	PlatformAddress string
+	PlatformEmail   string
+}
+
 func Load() (*Config, error) {
 	// Load .env file if exists
 	_ = godotenv.Load()
@@ -162,11 +243,18 @@ func Load() (*Config, error) {
 			Path:          getEnv("BACKUP_PATH", "./backups"),
 			RetentionDays: getEnvAsInt("BACKUP_RETENTION_DAYS", 30),
 		},
+		Payment: PaymentConfig{
+			Gateway: getEnv("PAYMENT_GATEWAY", "midtrans"),
+		},
 		Midtrans: MidtransConfig{
 			ServerKey:    getEnv("MIDTRANS_SERVER_KEY", ""),
 			ClientKey:    getEnv("MIDTRANS_CLIENT_KEY", ""),
 			IsProduction: getEnv("MIDTRANS_IS_PRODUCTION", "false") == "true",
 		},
+		Xendit: XenditConfig{
+			SecretKey:     getEnv("XENDIT_SECRET_KEY", ""),
+			CallbackToken: getEnv("XENDIT_CALLBACK_TOKEN", ""),
+		},
 		R2Storage: R2StorageConfig{
 			AccountID:       getEnv("R2_ACCOUNT_ID", ""),
 			AccessKeyID:     getEnv("R2_ACCESS_KEY_ID", ""),
@@ -179,6 +267,55 @@ func Load() (*Config, error) {
 			ServerPort:       getEnvAsInt("VPN_SERVER_PORT", 1194),
 			RadiusInternalIP: getEnv("RADIUS_INTERNAL_IP", "10.8.0.1"),
 		},
+		Mikrotik: MikrotikConfig{
+			MaxConnsPerRouter: getEnvAsInt("MIKROTIK_MAX_CONNS_PER_ROUTER", 2),
+			IdleTimeout:       parseDuration(getEnv("MIKROTIK_IDLE_TIMEOUT", "2m")),
+			DialRetries:       getEnvAsInt("MIKROTIK_DIAL_RETRIES", 2),
+			FailureThreshold:  getEnvAsInt("MIKROTIK_FAILURE_THRESHOLD", 3),
+			CircuitCooldown:   parseDuration(getEnv("MIKROTIK_CIRCUIT_COOLDOWN", "30s")),
+		},
+		Radius: RadiusConfig{
+			CoAPort:    getEnvAsInt("RADIUS_COA_PORT", 3799),
+			CoATimeout: parseDuration(getEnv("RADIUS_COA_TIMEOUT", "3s")),
+			CoARetries: getEnvAsInt("RADIUS_COA_RETRIES", 1),
+		},
+		WhatsApp: WhatsAppConfig{
+			Provider:      getEnv("WHATSAPP_PROVIDER", "fonnte"),
+			APIURL:        getEnv("WHATSAPP_API_URL", ""),
+			RatePerMinute: getEnvAsInt("WHATSAPP_RATE_PER_MINUTE", 20),
+			WebhookSecret: getEnv("WHATSAPP_WEBHOOK_SECRET", ""),
+		},
+		Telegram: TelegramConfig{
+			APIURL:       getEnv("TELEGRAM_API_URL", ""),
+			WebhookURL:   getEnv("TELEGRAM_WEBHOOK_URL", ""),
+			PushInterval: parseDuration(getEnv("TELEGRAM_PUSH_INTERVAL", "1m")),
+			OverdueHour:  getEnvAsInt("TELEGRAM_OVERDUE_HOUR", 8),
+		},
+		Webhook: WebhookConfig{
+			Interval:     parseDuration(getEnv("WEBHOOK_INTERVAL", "1m")),
+			Timeout:      parseDuration(getEnv("WEBHOOK_TIMEOUT", "10s")),
+			AllowPrivate: getEnv("WEBHOOK_ALLOW_PRIVATE", "false") == "true",
+		},
+		Billing: BillingConfig{
+			InvoiceInterval:      parseDuration(getEnv("BILLING_INVOICE_INTERVAL", "1h")),
+			AutoSuspendInterval:  parseDuration(getEnv("BILLING_AUTO_SUSPEND_INTERVAL", "24h")),
+			LateFeeInterval:      parseDuration(getEnv("BILLING_LATE_FEE_INTERVAL", "24h")),
+			PrepaidInterval:      parseDuration(getEnv("BILLING_PREPAID_INTERVAL", "1h")),
+			PlanChangeInterval:   parseDuration(getEnv("BILLING_PLAN_CHANGE_INTERVAL", "1h")),
+			SpeedBoostInterval:   parseDuration(getEnv("BILLING_SPEED_BOOST_INTERVAL", "5m")),
+			SpeedBoostLinkSecret: getEnv("BILLING_SPEED_BOOST_LINK_SECRET", ""),
+			SpeedBoostPageURL:    getEnv("BILLING_SPEED_BOOST_PAGE_URL", ""),
+			NotificationInterval: parseDuration(getEnv("BILLING_NOTIFICATION_INTERVAL", "24h")),
+			PaymentPageURL:       getEnv("BILLING_PAYMENT_PAGE_URL", ""),
+			RenewalInterval:      parseDuration(getEnv("BILLING_RENEWAL_INTERVAL", "1h")),
+			RenewalLeadDays:      getEnvAsInt("BILLING_RENEWAL_LEAD_DAYS", 7),
+			RenewalReminderDays:  parseIntSlice(getEnv("BILLING_RENEWAL_REMINDER_DAYS", "3,1")),
+			GracePeriodDays:      getEnvAsInt("BILLING_GRACE_PERIOD_DAYS", 3),
+			SuspensionDays:       getEnvAsInt("BILLING_SUSPENSION_DAYS", 14),
+			PlatformName:         getEnv("BILLING_PLATFORM_NAME", "RTRWNet"),
+			PlatformAddress:      getEnv("BILLING_PLATFORM_ADDRESS", ""),
+			PlatformEmail:        getEnv("BILLING_PLATFORM_EMAIL", "billing@rtrwnet.com"),
+		},
 	}
 
 	return cfg, nil
@@ -234,6 +371,16 @@ func parseStringSlice(s string) []string {
 	return parts
 }
 
+func parseIntSlice(s string) []int {
+	values := []int{}
+	for _, part := range parseStringSlice(s) {
+		if value, err := strconv.Atoi(part); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func splitString(s, sep string) []string {
 	result := []string{}
 	current := ""
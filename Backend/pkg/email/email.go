@@ -28,65 +28,6 @@ func NewService(config *Config) *Service {
 	return &Service{config: config}
 }
 
-// SendOTP sends OTP verification email
-func (s *Service) SendOTP(to, otp, purpose string) error {
-	subject := "Kode Verifikasi Email"
-	purposeText := "registrasi"
-	
-	switch purpose {
-	case "registration":
-		purposeText = "registrasi akun"
-	case "reset_password":
-		purposeText = "reset password"
-		subject = "Kode Reset Password"
-	case "email_change":
-		purposeText = "perubahan email"
-		subject = "Kode Verifikasi Perubahan Email"
-	}
-
-	body := fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background: #4F46E5; color: white; padding: 20px; text-align: center; border-radius: 8px 8px 0 0; }
-        .content { background: #f9fafb; padding: 30px; border-radius: 0 0 8px 8px; }
-        .otp-code { font-size: 32px; font-weight: bold; color: #4F46E5; text-align: center; 
-                    padding: 20px; background: white; border-radius: 8px; margin: 20px 0;
-                    letter-spacing: 8px; }
-        .footer { text-align: center; color: #666; font-size: 12px; margin-top: 20px; }
-        .warning { color: #dc2626; font-size: 14px; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>RT/RW Net SaaS</h1>
-        </div>
-        <div class="content">
-            <h2>Kode Verifikasi</h2>
-            <p>Halo,</p>
-            <p>Anda menerima email ini karena ada permintaan %s pada platform RT/RW Net SaaS.</p>
-            <p>Gunakan kode OTP berikut untuk melanjutkan:</p>
-            <div class="otp-code">%s</div>
-            <p class="warning">⚠️ Kode ini akan kadaluarsa dalam 10 menit.</p>
-            <p>Jika Anda tidak melakukan permintaan ini, abaikan email ini.</p>
-        </div>
-        <div class="footer">
-            <p>Email ini dikirim secara otomatis. Mohon tidak membalas email ini.</p>
-            <p>&copy; 2024 RT/RW Net SaaS. All rights reserved.</p>
-        </div>
-    </div>
-</body>
-</html>
-`, purposeText, otp)
-
-	return s.SendHTML(to, subject, body)
-}
-
 // SendHTML sends an HTML email
 func (s *Service) SendHTML(to, subject, htmlBody string) error {
 	from := s.config.FromEmail
@@ -30,6 +30,8 @@ const (
 	ErrCodeSubscriptionCancelled = "SUB_4003"
 	ErrCodeInvalidPlan           = "SUB_4004"
 	ErrCodeTrialAlreadyUsed      = "SUB_4005"
+	ErrCodeTrialNotAvailable     = "SUB_4006"
+	ErrCodeReadOnlyMode          = "SUB_4007"
 	
 	// Payment errors (5xxx)
 	ErrCodePaymentFailed         = "PAY_5001"
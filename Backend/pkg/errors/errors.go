@@ -85,6 +85,8 @@ var (
 	ErrSubscriptionRequired  = &AppError{Code: "SUB_4003", Message: "Active subscription required", Status: http.StatusForbidden}
 	ErrInvalidPlan           = &AppError{Code: "SUB_4004", Message: "Invalid subscription plan", Status: http.StatusBadRequest}
 	ErrTrialExpired          = &AppError{Code: "SUB_4005", Message: "Trial period has expired. Please upgrade to continue.", Status: http.StatusForbidden}
+	ErrTrialNotAvailable     = &AppError{Code: "SUB_4006", Message: "This plan does not offer a free trial", Status: http.StatusBadRequest}
+	ErrReadOnlyMode          = &AppError{Code: "SUB_4007", Message: "Trial period has ended and the account is read-only. Please choose a plan to continue.", Status: http.StatusForbidden}
 	ErrCustomerLimitReached  = &AppError{Code: "PLAN_4001", Message: "Customer limit reached for your plan", Status: http.StatusForbidden}
 	ErrUserLimitReached      = &AppError{Code: "PLAN_4002", Message: "User limit reached for your plan", Status: http.StatusForbidden}
 	ErrFeatureNotAvailable   = &AppError{Code: "PLAN_4003", Message: "Feature not available in your plan", Status: http.StatusForbidden}
@@ -0,0 +1,169 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Gateway names, as stored in PaymentTransaction.PaymentGateway
+const (
+	GatewayMidtrans = "midtrans"
+	GatewayXendit   = "xendit"
+	GatewayManual   = "manual"
+)
+
+// Normalized payment statuses returned by every gateway
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusFailed   = "failed"
+	StatusExpired  = "expired"
+	StatusRefunded = "refunded"
+)
+
+var (
+	// ErrInvalidSignature is returned when a webhook cannot be verified
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrUnsupportedMethod is returned when a gateway does not offer a payment method
+	ErrUnsupportedMethod = errors.New("payment method not supported by gateway")
+	// ErrTransactionNotFound is returned when the gateway has no transaction for an order
+	ErrTransactionNotFound = errors.New("transaction not found at gateway")
+)
+
+// PaymentGateway is implemented by every online payment provider
+type PaymentGateway interface {
+	// Name returns the gateway name, e.g. "midtrans"
+	Name() string
+	// CreatePayment charges an order with one of the AvailablePaymentMethods
+	CreatePayment(ctx context.Context, params *ChargeParams) (*Charge, error)
+	// GetStatus returns the current state of an order at the gateway
+	GetStatus(ctx context.Context, orderID string) (*Charge, error)
+	// Cancel cancels a pending order
+	Cancel(ctx context.Context, orderID string) (*Charge, error)
+	// Expire expires a pending order
+	Expire(ctx context.Context, orderID string) (*Charge, error)
+	// Refund returns all or part of a settled order to the payer
+	Refund(ctx context.Context, params *RefundParams) (*Refund, error)
+	// ParseNotification verifies a webhook request and returns its payment update.
+	// It returns ErrInvalidSignature when the request was not sent by the gateway.
+	ParseNotification(header http.Header, body []byte) (*Notification, error)
+}
+
+// ChargeParams describes a payment to create
+type ChargeParams struct {
+	OrderID     string
+	Amount      float64
+	Method      string // bca_va, bni_va, bri_va, permata_va, mandiri_bill, gopay, shopeepay, qris
+	Description string
+	Customer    *CustomerDetails
+	Items       []ItemDetail
+	CallbackURL string // where e-wallets send the customer after paying
+}
+
+// Charge is a gateway transaction with its payment instructions
+type Charge struct {
+	Gateway         string     `json:"gateway"`
+	OrderID         string     `json:"order_id"`
+	TransactionID   string     `json:"transaction_id"`
+	Status          string     `json:"status"`             // normalized status
+	GatewayStatus   string     `json:"transaction_status"` // status as reported by the gateway
+	PaymentType     string     `json:"payment_type"`
+	Amount          float64    `json:"amount"`
+	TransactionTime string     `json:"transaction_time,omitempty"`
+	ExpiryTime      string     `json:"expiry_time,omitempty"` // ExpiryLayout, in WIB
+	VANumbers       []VANumber `json:"va_numbers,omitempty"`
+	PermataVANumber string     `json:"permata_va_number,omitempty"`
+	BillerCode      string     `json:"biller_code,omitempty"`
+	BillKey         string     `json:"bill_key,omitempty"`
+	QRString        string     `json:"qr_string,omitempty"`
+	QRCodeURL       string     `json:"qr_code_url,omitempty"`
+	DeeplinkURL     string     `json:"deeplink_url,omitempty"`
+	CheckoutURL     string     `json:"checkout_url,omitempty"`
+}
+
+// ExpiryLayout is the layout of Charge.ExpiryTime. Gateways report the time
+// in WIB (Asia/Jakarta), as Midtrans does.
+const ExpiryLayout = "2006-01-02 15:04:05"
+
+// wib is Asia/Jakarta, falling back to a fixed UTC+7 where the system has
+// no zone database; Indonesia has no daylight saving time
+var wib = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Jakarta"); err == nil {
+		return loc
+	}
+	return time.FixedZone("WIB", 7*60*60)
+}()
+
+// ExpiresAt returns ExpiryTime as an instant
+func (c *Charge) ExpiresAt() (time.Time, error) {
+	return time.ParseInLocation(ExpiryLayout, c.ExpiryTime, wib)
+}
+
+// RefundParams describes a refund of a settled order
+type RefundParams struct {
+	OrderID   string
+	RefundKey string // unique per refund, so a retried request refunds once
+	Amount    float64
+	Reason    string
+}
+
+// Refund is a refund accepted by a gateway
+type Refund struct {
+	Gateway  string  `json:"gateway"`
+	OrderID  string  `json:"order_id"`
+	RefundID string  `json:"refund_id"`
+	Amount   float64 `json:"amount"`
+	Status   string  `json:"status"` // StatusRefunded, or StatusPending while the gateway processes it
+}
+
+// Notification is a verified payment update sent by a gateway webhook
+type Notification struct {
+	Gateway       string
+	OrderID       string
+	TransactionID string
+	Status        string // normalized status
+	GatewayStatus string
+	PaymentType   string
+	Amount        float64
+}
+
+// Gateways holds the configured gateways and the one used for new payments
+type Gateways struct {
+	gateways       map[string]PaymentGateway
+	defaultGateway string
+}
+
+// NewGateways creates a registry that charges new payments through defaultGateway
+func NewGateways(defaultGateway string, gateways ...PaymentGateway) *Gateways {
+	g := &Gateways{
+		gateways:       make(map[string]PaymentGateway),
+		defaultGateway: defaultGateway,
+	}
+	for _, gateway := range gateways {
+		g.Register(gateway)
+	}
+	return g
+}
+
+// Register adds a gateway, replacing any gateway with the same name
+func (g *Gateways) Register(gateway PaymentGateway) {
+	g.gateways[gateway.Name()] = gateway
+}
+
+// Get returns the named gateway, or nil when it is not configured
+func (g *Gateways) Get(name string) PaymentGateway {
+	if g == nil {
+		return nil
+	}
+	return g.gateways[name]
+}
+
+// Default returns the gateway for new payments, or nil when it is not configured
+func (g *Gateways) Default() PaymentGateway {
+	if g == nil {
+		return nil
+	}
+	return g.gateways[g.defaultGateway]
+}
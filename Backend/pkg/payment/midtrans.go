@@ -2,12 +2,14 @@ package payment
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -17,6 +19,7 @@ type MidtransConfig struct {
 	ClientKey    string
 	IsProduction bool
 	MerchantID   string
+	BaseURL      string // overrides the Core API URL, e.g. for a local stand-in
 }
 
 // MidtransClient is the Midtrans API client
@@ -37,6 +40,9 @@ func NewMidtransClient(config *MidtransConfig) *MidtransClient {
 
 // GetCoreAPIURL returns the Core API URL
 func (c *MidtransClient) GetCoreAPIURL() string {
+	if c.config.BaseURL != "" {
+		return c.config.BaseURL
+	}
 	if c.config.IsProduction {
 		return "https://api.midtrans.com/v2"
 	}
@@ -159,6 +165,17 @@ type ChargeResponse struct {
 
 	// Expiry
 	ExpiryTime string `json:"expiry_time,omitempty"`
+
+	// Refund specific
+	RefundChargebackID int64  `json:"refund_chargeback_id,omitempty"`
+	RefundAmount       string `json:"refund_amount,omitempty"`
+}
+
+// RefundRequest represents a Core API refund request
+type RefundRequest struct {
+	RefundKey string  `json:"refund_key"`
+	Amount    float64 `json:"amount"`
+	Reason    string  `json:"reason,omitempty"`
 }
 
 // VANumber represents virtual account number
@@ -180,41 +197,17 @@ type Action struct {
 
 // Charge creates a new payment transaction
 func (c *MidtransClient) Charge(req *ChargeRequest) (*ChargeResponse, error) {
-	jsonData, err := json.Marshal(req)
+	chargeResp, err := c.do("POST", c.GetCoreAPIURL()+"/charge", req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := c.GetCoreAPIURL() + "/charge"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	c.setHeaders(httpReq)
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var chargeResp ChargeResponse
-	if err := json.Unmarshal(body, &chargeResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, err
 	}
 
 	// Check for errors
 	if chargeResp.StatusCode != "200" && chargeResp.StatusCode != "201" {
-		return &chargeResp, fmt.Errorf("midtrans error: %s - %s", chargeResp.StatusCode, chargeResp.StatusMessage)
+		return chargeResp, fmt.Errorf("midtrans error: %s - %s", chargeResp.StatusCode, chargeResp.StatusMessage)
 	}
 
-	return &chargeResp, nil
+	return chargeResp, nil
 }
 
 // CreateCharge is an alias for Charge
@@ -224,66 +217,36 @@ func (c *MidtransClient) CreateCharge(req ChargeRequest) (*ChargeResponse, error
 
 // GetTransactionStatus gets the status of a transaction
 func (c *MidtransClient) GetTransactionStatus(orderID string) (*ChargeResponse, error) {
-	url := fmt.Sprintf("%s/%s/status", c.GetCoreAPIURL(), orderID)
-	httpReq, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	c.setHeaders(httpReq)
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var statusResp ChargeResponse
-	if err := json.Unmarshal(body, &statusResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	return &statusResp, nil
+	return c.do("GET", fmt.Sprintf("%s/%s/status", c.GetCoreAPIURL(), orderID), nil)
 }
 
 // CancelTransaction cancels a pending transaction
 func (c *MidtransClient) CancelTransaction(orderID string) (*ChargeResponse, error) {
-	url := fmt.Sprintf("%s/%s/cancel", c.GetCoreAPIURL(), orderID)
-	httpReq, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	c.setHeaders(httpReq)
+	return c.do("POST", fmt.Sprintf("%s/%s/cancel", c.GetCoreAPIURL(), orderID), nil)
+}
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+// ExpireTransaction expires a pending transaction
+func (c *MidtransClient) ExpireTransaction(orderID string) (*ChargeResponse, error) {
+	return c.do("POST", fmt.Sprintf("%s/%s/expire", c.GetCoreAPIURL(), orderID), nil)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+// RefundTransaction refunds a settled GoPay, ShopeePay, QRIS or card transaction
+func (c *MidtransClient) RefundTransaction(orderID string, req *RefundRequest) (*ChargeResponse, error) {
+	return c.do("POST", fmt.Sprintf("%s/%s/refund/online/direct", c.GetCoreAPIURL(), orderID), req)
+}
 
-	var cancelResp ChargeResponse
-	if err := json.Unmarshal(body, &cancelResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// do sends a Core API request and decodes the response
+func (c *MidtransClient) do(method, url string, payload interface{}) (*ChargeResponse, error) {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewBuffer(jsonData)
 	}
 
-	return &cancelResp, nil
-}
-
-// ExpireTransaction expires a pending transaction
-func (c *MidtransClient) ExpireTransaction(orderID string) (*ChargeResponse, error) {
-	url := fmt.Sprintf("%s/%s/expire", c.GetCoreAPIURL(), orderID)
-	httpReq, err := http.NewRequest("POST", url, nil)
+	httpReq, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -296,17 +259,17 @@ func (c *MidtransClient) ExpireTransaction(orderID string) (*ChargeResponse, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var expireResp ChargeResponse
-	if err := json.Unmarshal(body, &expireResp); err != nil {
+	var chargeResp ChargeResponse
+	if err := json.Unmarshal(respBody, &chargeResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &expireResp, nil
+	return &chargeResp, nil
 }
 
 func (c *MidtransClient) setHeaders(req *http.Request) {
@@ -488,6 +451,178 @@ func (c *MidtransClient) GetClientKey() string {
 	return c.config.ClientKey
 }
 
+// ========================================
+// PaymentGateway Implementation
+// ========================================
+
+// Name returns the gateway name
+func (c *MidtransClient) Name() string {
+	return GatewayMidtrans
+}
+
+// CreatePayment charges an order with the Core API helper for its method
+func (c *MidtransClient) CreatePayment(ctx context.Context, params *ChargeParams) (*Charge, error) {
+	var resp *ChargeResponse
+	var err error
+
+	switch params.Method {
+	case "bca_va":
+		resp, err = c.ChargeBankTransfer(params.OrderID, params.Amount, BankBCA, params.Customer, params.Items)
+	case "bni_va":
+		resp, err = c.ChargeBankTransfer(params.OrderID, params.Amount, BankBNI, params.Customer, params.Items)
+	case "bri_va":
+		resp, err = c.ChargeBankTransfer(params.OrderID, params.Amount, BankBRI, params.Customer, params.Items)
+	case "permata_va":
+		resp, err = c.ChargeBankTransfer(params.OrderID, params.Amount, BankPermata, params.Customer, params.Items)
+	case "mandiri_bill":
+		resp, err = c.ChargeMandiriBill(params.OrderID, params.Amount, params.Customer, params.Items)
+	case "gopay":
+		resp, err = c.ChargeGopay(params.OrderID, params.Amount, params.Customer, params.Items, params.CallbackURL)
+	case "shopeepay":
+		resp, err = c.ChargeShopeePay(params.OrderID, params.Amount, params.Customer, params.Items, params.CallbackURL)
+	case "qris":
+		resp, err = c.ChargeQRIS(params.OrderID, params.Amount, params.Customer, params.Items)
+	default:
+		return nil, ErrUnsupportedMethod
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.toCharge(), nil
+}
+
+// GetStatus returns the status of an order
+func (c *MidtransClient) GetStatus(ctx context.Context, orderID string) (*Charge, error) {
+	return c.statusCall(c.GetTransactionStatus(orderID))
+}
+
+// Cancel cancels a pending order
+func (c *MidtransClient) Cancel(ctx context.Context, orderID string) (*Charge, error) {
+	return c.statusCall(c.CancelTransaction(orderID))
+}
+
+// Expire expires a pending order
+func (c *MidtransClient) Expire(ctx context.Context, orderID string) (*Charge, error) {
+	return c.statusCall(c.ExpireTransaction(orderID))
+}
+
+// Refund refunds a settled order. Bank transfers cannot be refunded through Midtrans.
+func (c *MidtransClient) Refund(ctx context.Context, params *RefundParams) (*Refund, error) {
+	resp, err := c.RefundTransaction(params.OrderID, &RefundRequest{
+		RefundKey: params.RefundKey,
+		Amount:    params.Amount,
+		Reason:    params.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case "200":
+	case "404":
+		return nil, ErrTransactionNotFound
+	default:
+		return nil, fmt.Errorf("midtrans error: %s - %s", resp.StatusCode, resp.StatusMessage)
+	}
+
+	amount, _ := strconv.ParseFloat(resp.RefundAmount, 64)
+	return &Refund{
+		Gateway:  GatewayMidtrans,
+		OrderID:  params.OrderID,
+		RefundID: strconv.FormatInt(resp.RefundChargebackID, 10),
+		Amount:   amount,
+		Status:   StatusRefunded,
+	}, nil
+}
+
+func (c *MidtransClient) statusCall(resp *ChargeResponse, err error) (*Charge, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case "200", "201", "407", "412":
+		// 407 and 412 report expired and already settled transactions
+		return resp.toCharge(), nil
+	case "404":
+		return nil, ErrTransactionNotFound
+	default:
+		return nil, fmt.Errorf("midtrans error: %s - %s", resp.StatusCode, resp.StatusMessage)
+	}
+}
+
+// ParseNotification verifies the notification signature with the server key
+func (c *MidtransClient) ParseNotification(header http.Header, body []byte) (*Notification, error) {
+	var payload NotificationPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification: %w", err)
+	}
+	if !c.VerifySignature(payload.OrderID, payload.StatusCode, payload.GrossAmount, payload.SignatureKey) {
+		return nil, ErrInvalidSignature
+	}
+
+	amount, _ := strconv.ParseFloat(payload.GrossAmount, 64)
+	return &Notification{
+		Gateway:       GatewayMidtrans,
+		OrderID:       payload.OrderID,
+		TransactionID: payload.TransactionID,
+		Status:        midtransStatus(payload.TransactionStatus, payload.FraudStatus),
+		GatewayStatus: payload.TransactionStatus,
+		PaymentType:   payload.PaymentType,
+		Amount:        amount,
+	}, nil
+}
+
+// toCharge converts a Core API response to the gateway-neutral Charge
+func (r *ChargeResponse) toCharge() *Charge {
+	amount, _ := strconv.ParseFloat(r.GrossAmount, 64)
+	charge := &Charge{
+		Gateway:         GatewayMidtrans,
+		OrderID:         r.OrderID,
+		TransactionID:   r.TransactionID,
+		Status:          midtransStatus(r.TransactionStatus, r.FraudStatus),
+		GatewayStatus:   r.TransactionStatus,
+		PaymentType:     r.PaymentType,
+		Amount:          amount,
+		TransactionTime: r.TransactionTime,
+		ExpiryTime:      r.ExpiryTime,
+		VANumbers:       r.VANumbers,
+		PermataVANumber: r.PermataVANumber,
+		BillerCode:      r.BillerCode,
+		BillKey:         r.BillKey,
+		QRString:        r.QRString,
+	}
+	for _, action := range r.Actions {
+		switch action.Name {
+		case "generate-qr-code":
+			charge.QRCodeURL = action.URL
+		case "deeplink-redirect":
+			charge.DeeplinkURL = action.URL
+		}
+	}
+	return charge
+}
+
+// midtransStatus maps a Midtrans transaction status to a normalized status
+func midtransStatus(transactionStatus, fraudStatus string) string {
+	switch transactionStatus {
+	case "capture":
+		if fraudStatus == "accept" {
+			return StatusPaid
+		}
+		return StatusPending
+	case "settlement":
+		return StatusPaid
+	case "deny", "cancel", "failure":
+		return StatusFailed
+	case "expire":
+		return StatusExpired
+	case "refund", "partial_refund":
+		return StatusRefunded
+	default:
+		return StatusPending
+	}
+}
+
 // ========================================
 // Payment Method Constants
 // ========================================
@@ -0,0 +1,167 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ PaymentGateway = (*MidtransClient)(nil)
+
+// midtransStandIn serves canned Core API responses keyed by method and path
+func midtransStandIn(t *testing.T, responses map[string]string, requests *[]map[string]interface{}) *MidtransClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "SB-server", user)
+
+		if requests != nil && r.Body != nil {
+			var body map[string]interface{}
+			if json.NewDecoder(r.Body).Decode(&body) == nil {
+				*requests = append(*requests, body)
+			}
+		}
+
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			resp = `{"status_code":"404","status_message":"Transaction doesn't exist."}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewMidtransClient(&MidtransConfig{ServerKey: "SB-server", BaseURL: server.URL})
+}
+
+func TestMidtransClient_CreatePayment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Bank Transfer", func(t *testing.T) {
+		var requests []map[string]interface{}
+		client := midtransStandIn(t, map[string]string{
+			"POST /charge": `{"status_code":"201","transaction_id":"tx-1","order_id":"ORD-1","gross_amount":"150000.00",
+				"payment_type":"bank_transfer","transaction_status":"pending","expiry_time":"2025-03-02 10:00:00",
+				"va_numbers":[{"bank":"bca","va_number":"12345678901"}]}`,
+		}, &requests)
+
+		charge, err := client.CreatePayment(ctx, &ChargeParams{OrderID: "ORD-1", Amount: 150000, Method: "bca_va"})
+		require.NoError(t, err)
+		assert.Equal(t, GatewayMidtrans, charge.Gateway)
+		assert.Equal(t, StatusPending, charge.Status)
+		assert.Equal(t, 150000.0, charge.Amount)
+		assert.Equal(t, []VANumber{{Bank: "bca", VANumber: "12345678901"}}, charge.VANumbers)
+
+		require.Len(t, requests, 1)
+		assert.Equal(t, "bank_transfer", requests[0]["payment_type"])
+		assert.Equal(t, "bca", requests[0]["bank_transfer"].(map[string]interface{})["bank"])
+	})
+
+	t.Run("GoPay Actions", func(t *testing.T) {
+		client := midtransStandIn(t, map[string]string{
+			"POST /charge": `{"status_code":"201","order_id":"ORD-2","payment_type":"gopay","transaction_status":"pending",
+				"actions":[{"name":"generate-qr-code","url":"https://qr"},{"name":"deeplink-redirect","url":"gojek://pay"}]}`,
+		}, nil)
+
+		charge, err := client.CreatePayment(ctx, &ChargeParams{OrderID: "ORD-2", Amount: 1000, Method: "gopay"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://qr", charge.QRCodeURL)
+		assert.Equal(t, "gojek://pay", charge.DeeplinkURL)
+	})
+
+	t.Run("Gateway Error", func(t *testing.T) {
+		client := midtransStandIn(t, map[string]string{
+			"POST /charge": `{"status_code":"406","status_message":"Duplicate order ID"}`,
+		}, nil)
+
+		_, err := client.CreatePayment(ctx, &ChargeParams{OrderID: "ORD-1", Amount: 1000, Method: "qris"})
+		assert.ErrorContains(t, err, "Duplicate order ID")
+	})
+
+	t.Run("Unsupported Method", func(t *testing.T) {
+		client := midtransStandIn(t, nil, nil)
+
+		_, err := client.CreatePayment(ctx, &ChargeParams{OrderID: "ORD-1", Amount: 1000, Method: "ovo"})
+		assert.ErrorIs(t, err, ErrUnsupportedMethod)
+	})
+}
+
+func TestMidtransClient_Status(t *testing.T) {
+	ctx := context.Background()
+	client := midtransStandIn(t, map[string]string{
+		"GET /ORD-1/status":  `{"status_code":"200","order_id":"ORD-1","transaction_status":"settlement","gross_amount":"150000.00"}`,
+		"POST /ORD-2/cancel": `{"status_code":"200","order_id":"ORD-2","transaction_status":"cancel"}`,
+		"POST /ORD-3/expire": `{"status_code":"407","order_id":"ORD-3","transaction_status":"expire"}`,
+	}, nil)
+
+	charge, err := client.GetStatus(ctx, "ORD-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaid, charge.Status)
+
+	charge, err = client.Cancel(ctx, "ORD-2")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, charge.Status)
+
+	charge, err = client.Expire(ctx, "ORD-3")
+	require.NoError(t, err)
+	assert.Equal(t, StatusExpired, charge.Status)
+
+	_, err = client.GetStatus(ctx, "ORD-404")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestMidtransClient_Refund(t *testing.T) {
+	ctx := context.Background()
+	var requests []map[string]interface{}
+	client := midtransStandIn(t, map[string]string{
+		"POST /ORD-1/refund/online/direct": `{"status_code":"200","order_id":"ORD-1","transaction_status":"partial_refund",
+			"refund_chargeback_id":9,"refund_amount":"50000.00","refund_key":"rf-1"}`,
+		"POST /ORD-2/refund/online/direct": `{"status_code":"412","status_message":"Merchant cannot modify the status of the transaction"}`,
+	}, &requests)
+
+	refund, err := client.Refund(ctx, &RefundParams{OrderID: "ORD-1", RefundKey: "rf-1", Amount: 50000, Reason: "Double payment"})
+	require.NoError(t, err)
+	assert.Equal(t, "9", refund.RefundID)
+	assert.Equal(t, 50000.0, refund.Amount)
+	assert.Equal(t, StatusRefunded, refund.Status)
+	assert.Equal(t, "rf-1", requests[0]["refund_key"])
+
+	_, err = client.Refund(ctx, &RefundParams{OrderID: "ORD-2", RefundKey: "rf-2", Amount: 1000})
+	assert.ErrorContains(t, err, "412")
+}
+
+func TestMidtransClient_ParseNotification(t *testing.T) {
+	client := NewMidtransClient(&MidtransConfig{ServerKey: "SB-server"})
+	signature := fmt.Sprintf("%x", sha512.Sum512([]byte("ORD-1"+"200"+"150000.00"+"SB-server")))
+
+	body := fmt.Sprintf(`{"order_id":"ORD-1","status_code":"200","gross_amount":"150000.00","signature_key":"%s",
+		"transaction_id":"tx-1","transaction_status":"capture","fraud_status":"accept","payment_type":"credit_card"}`, signature)
+	notification, err := client.ParseNotification(http.Header{}, []byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, "ORD-1", notification.OrderID)
+	assert.Equal(t, StatusPaid, notification.Status)
+	assert.Equal(t, 150000.0, notification.Amount)
+
+	forged := `{"order_id":"ORD-1","status_code":"200","gross_amount":"150000.00","signature_key":"abc","transaction_status":"settlement"}`
+	_, err = client.ParseNotification(http.Header{}, []byte(forged))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestGateways(t *testing.T) {
+	midtrans := NewMidtransClient(&MidtransConfig{})
+	xendit := NewXenditClient(&XenditConfig{})
+
+	gateways := NewGateways(GatewayXendit, midtrans, xendit)
+	assert.Equal(t, xendit, gateways.Default())
+	assert.Equal(t, midtrans, gateways.Get(GatewayMidtrans))
+	assert.Nil(t, gateways.Get(GatewayManual))
+
+	var none *Gateways
+	assert.Nil(t, none.Default())
+}
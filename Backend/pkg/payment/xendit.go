@@ -0,0 +1,430 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// XenditConfig holds Xendit configuration
+type XenditConfig struct {
+	SecretKey     string
+	CallbackToken string // verification token from the Xendit dashboard
+	BaseURL       string // overrides the API URL, e.g. for a local stand-in
+}
+
+// XenditClient is the Xendit API client. Payments are created as Xendit
+// invoices restricted to the chosen channel, so one webhook covers every
+// payment method.
+type XenditClient struct {
+	config     *XenditConfig
+	httpClient *http.Client
+}
+
+// NewXenditClient creates a new Xendit client
+func NewXenditClient(config *XenditConfig) *XenditClient {
+	return &XenditClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// GetAPIURL returns the API URL
+func (c *XenditClient) GetAPIURL() string {
+	if c.config.BaseURL != "" {
+		return c.config.BaseURL
+	}
+	return "https://api.xendit.co"
+}
+
+// ========================================
+// Invoice API Request/Response Types
+// ========================================
+
+// XenditInvoiceRequest represents an invoice creation request
+type XenditInvoiceRequest struct {
+	ExternalID         string              `json:"external_id"`
+	Amount             float64             `json:"amount"`
+	Description        string              `json:"description,omitempty"`
+	PayerEmail         string              `json:"payer_email,omitempty"`
+	InvoiceDuration    int                 `json:"invoice_duration,omitempty"` // seconds
+	Currency           string              `json:"currency"`
+	PaymentMethods     []string            `json:"payment_methods,omitempty"`
+	Customer           *XenditCustomer     `json:"customer,omitempty"`
+	Items              []XenditInvoiceItem `json:"items,omitempty"`
+	SuccessRedirectURL string              `json:"success_redirect_url,omitempty"`
+}
+
+// XenditCustomer contains customer information
+type XenditCustomer struct {
+	GivenNames   string `json:"given_names,omitempty"`
+	Surname      string `json:"surname,omitempty"`
+	Email        string `json:"email,omitempty"`
+	MobileNumber string `json:"mobile_number,omitempty"`
+}
+
+// XenditInvoiceItem contains item information
+type XenditInvoiceItem struct {
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+// XenditInvoice represents an invoice returned by the API and sent to the
+// invoice callback
+type XenditInvoice struct {
+	ID             string       `json:"id"`
+	ExternalID     string       `json:"external_id"`
+	Status         string       `json:"status"` // PENDING, PAID, SETTLED, EXPIRED
+	Amount         float64      `json:"amount"`
+	PaidAmount     float64      `json:"paid_amount,omitempty"`
+	InvoiceURL     string       `json:"invoice_url,omitempty"`
+	ExpiryDate     string       `json:"expiry_date,omitempty"`
+	PaymentMethod  string       `json:"payment_method,omitempty"`
+	PaymentChannel string       `json:"payment_channel,omitempty"`
+	PaymentID      string       `json:"payment_id,omitempty"`
+	AvailableBanks []XenditBank `json:"available_banks,omitempty"`
+	ErrorCode      string       `json:"error_code,omitempty"`
+	Message        string       `json:"message,omitempty"`
+}
+
+// XenditRefundRequest represents a refund of an invoice payment
+type XenditRefundRequest struct {
+	InvoiceID   string  `json:"invoice_id"`
+	ReferenceID string  `json:"reference_id"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Reason      string  `json:"reason"` // FRAUDULENT, DUPLICATE, REQUESTED_BY_CUSTOMER, CANCELLATION, OTHERS
+}
+
+// XenditRefund represents a refund returned by the API
+type XenditRefund struct {
+	ID          string  `json:"id"`
+	InvoiceID   string  `json:"invoice_id"`
+	ReferenceID string  `json:"reference_id"`
+	Amount      float64 `json:"amount"`
+	Status      string  `json:"status"` // PENDING, SUCCEEDED, FAILED
+}
+
+// XenditBank is a virtual account offered on an invoice
+type XenditBank struct {
+	BankCode          string `json:"bank_code"`
+	BankAccountNumber string `json:"bank_account_number"`
+}
+
+// xenditChannels maps payment method IDs to Xendit invoice channels.
+// Xendit does not offer GoPay.
+var xenditChannels = map[string]string{
+	"bca_va":       "BCA",
+	"bni_va":       "BNI",
+	"bri_va":       "BRI",
+	"permata_va":   "PERMATA",
+	"mandiri_bill": "MANDIRI",
+	"shopeepay":    "SHOPEEPAY",
+	"qris":         "QRIS",
+}
+
+// ========================================
+// Invoice API Methods
+// ========================================
+
+// CreateInvoice creates a new invoice
+func (c *XenditClient) CreateInvoice(req *XenditInvoiceRequest) (*XenditInvoice, error) {
+	var invoice XenditInvoice
+	if err := c.do("POST", "/v2/invoices", req, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// FindInvoice returns the latest invoice created for an external ID
+func (c *XenditClient) FindInvoice(externalID string) (*XenditInvoice, error) {
+	var invoices []XenditInvoice
+	if err := c.do("GET", "/v2/invoices?external_id="+url.QueryEscape(externalID), nil, &invoices); err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	return &invoices[len(invoices)-1], nil
+}
+
+// ExpireInvoice expires a pending invoice
+func (c *XenditClient) ExpireInvoice(invoiceID string) (*XenditInvoice, error) {
+	var invoice XenditInvoice
+	if err := c.do("POST", "/invoices/"+url.PathEscape(invoiceID)+"/expire!", nil, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// CreateRefund refunds an e-wallet, QRIS or card payment of an invoice
+func (c *XenditClient) CreateRefund(req *XenditRefundRequest) (*XenditRefund, error) {
+	var refund XenditRefund
+	if err := c.do("POST", "/refunds", req, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// do sends an API request and decodes the response into out
+func (c *XenditClient) do(method, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	httpReq, err := http.NewRequest(method, c.GetAPIURL()+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte(c.config.SecretKey + ":"))
+	httpReq.Header.Set("Authorization", "Basic "+auth)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrTransactionNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr XenditInvoice
+		json.Unmarshal(respBody, &apiErr)
+		return fmt.Errorf("xendit error: %d %s - %s", resp.StatusCode, apiErr.ErrorCode, apiErr.Message)
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// ========================================
+// PaymentGateway Implementation
+// ========================================
+
+// Name returns the gateway name
+func (c *XenditClient) Name() string {
+	return GatewayXendit
+}
+
+// CreatePayment creates an invoice that only accepts the chosen channel
+func (c *XenditClient) CreatePayment(ctx context.Context, params *ChargeParams) (*Charge, error) {
+	channel, ok := xenditChannels[params.Method]
+	if !ok {
+		return nil, ErrUnsupportedMethod
+	}
+
+	// Same windows as the Midtrans charges: a day for bank transfers,
+	// fifteen minutes for e-wallets and QRIS
+	duration := 24 * time.Hour
+	if params.Method == "shopeepay" || params.Method == "qris" {
+		duration = 15 * time.Minute
+	}
+
+	req := &XenditInvoiceRequest{
+		ExternalID:         params.OrderID,
+		Amount:             params.Amount,
+		Description:        params.Description,
+		InvoiceDuration:    int(duration.Seconds()),
+		Currency:           "IDR",
+		PaymentMethods:     []string{channel},
+		SuccessRedirectURL: params.CallbackURL,
+	}
+	if req.Description == "" {
+		req.Description = "Payment " + params.OrderID
+	}
+	if params.Customer != nil {
+		req.PayerEmail = params.Customer.Email
+		req.Customer = &XenditCustomer{
+			GivenNames:   params.Customer.FirstName,
+			Surname:      params.Customer.LastName,
+			Email:        params.Customer.Email,
+			MobileNumber: params.Customer.Phone,
+		}
+	}
+	for _, item := range params.Items {
+		req.Items = append(req.Items, XenditInvoiceItem{Name: item.Name, Quantity: item.Quantity, Price: item.Price})
+	}
+
+	invoice, err := c.CreateInvoice(req)
+	if err != nil {
+		return nil, err
+	}
+
+	charge := invoice.toCharge()
+	charge.PaymentType = methodType(params.Method)
+	return charge, nil
+}
+
+// GetStatus returns the status of the latest invoice for an order
+func (c *XenditClient) GetStatus(ctx context.Context, orderID string) (*Charge, error) {
+	invoice, err := c.FindInvoice(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return invoice.toCharge(), nil
+}
+
+// Cancel expires the invoice, Xendit invoices cannot be cancelled
+func (c *XenditClient) Cancel(ctx context.Context, orderID string) (*Charge, error) {
+	return c.Expire(ctx, orderID)
+}
+
+// Expire expires the latest invoice for an order
+func (c *XenditClient) Expire(ctx context.Context, orderID string) (*Charge, error) {
+	invoice, err := c.FindInvoice(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Status != "PENDING" {
+		return invoice.toCharge(), nil
+	}
+
+	invoice, err = c.ExpireInvoice(invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	return invoice.toCharge(), nil
+}
+
+// Refund refunds the paid invoice of an order. Virtual account payments
+// cannot be refunded through Xendit.
+func (c *XenditClient) Refund(ctx context.Context, params *RefundParams) (*Refund, error) {
+	invoice, err := c.FindInvoice(params.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	refund, err := c.CreateRefund(&XenditRefundRequest{
+		InvoiceID:   invoice.ID,
+		ReferenceID: params.RefundKey,
+		Amount:      params.Amount,
+		Currency:    "IDR",
+		Reason:      "REQUESTED_BY_CUSTOMER",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := StatusPending
+	switch refund.Status {
+	case "SUCCEEDED":
+		status = StatusRefunded
+	case "FAILED":
+		return nil, fmt.Errorf("xendit refund %s failed", refund.ID)
+	}
+	return &Refund{
+		Gateway:  GatewayXendit,
+		OrderID:  params.OrderID,
+		RefundID: refund.ID,
+		Amount:   refund.Amount,
+		Status:   status,
+	}, nil
+}
+
+// ParseNotification verifies the x-callback-token header of an invoice callback
+func (c *XenditClient) ParseNotification(header http.Header, body []byte) (*Notification, error) {
+	token := header.Get("x-callback-token")
+	if c.config.CallbackToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(c.config.CallbackToken)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	var invoice XenditInvoice
+	if err := json.Unmarshal(body, &invoice); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification: %w", err)
+	}
+
+	amount := invoice.PaidAmount
+	if amount == 0 {
+		amount = invoice.Amount
+	}
+	transactionID := invoice.PaymentID
+	if transactionID == "" {
+		transactionID = invoice.ID
+	}
+	return &Notification{
+		Gateway:       GatewayXendit,
+		OrderID:       invoice.ExternalID,
+		TransactionID: transactionID,
+		Status:        xenditStatus(invoice.Status),
+		GatewayStatus: invoice.Status,
+		PaymentType:   strings.ToLower(invoice.PaymentMethod),
+		Amount:        amount,
+	}, nil
+}
+
+// toCharge converts an invoice to the gateway-neutral Charge
+func (inv *XenditInvoice) toCharge() *Charge {
+	charge := &Charge{
+		Gateway:       GatewayXendit,
+		OrderID:       inv.ExternalID,
+		TransactionID: inv.ID,
+		Status:        xenditStatus(inv.Status),
+		GatewayStatus: inv.Status,
+		PaymentType:   strings.ToLower(inv.PaymentMethod),
+		Amount:        inv.Amount,
+		CheckoutURL:   inv.InvoiceURL,
+	}
+	if expiry, err := time.Parse(time.RFC3339, inv.ExpiryDate); err == nil {
+		// Same layout and zone (WIB) as Midtrans expiry times
+		charge.ExpiryTime = expiry.In(wib).Format(ExpiryLayout)
+	}
+	for _, bank := range inv.AvailableBanks {
+		if bank.BankAccountNumber != "" {
+			charge.VANumbers = append(charge.VANumbers, VANumber{
+				Bank:     strings.ToLower(bank.BankCode),
+				VANumber: bank.BankAccountNumber,
+			})
+		}
+	}
+	return charge
+}
+
+// xenditStatus maps a Xendit invoice status to a normalized status
+func xenditStatus(status string) string {
+	switch status {
+	case "PAID", "SETTLED":
+		return StatusPaid
+	case "EXPIRED":
+		return StatusExpired
+	default:
+		return StatusPending
+	}
+}
+
+// methodType returns the payment type of a payment method ID
+func methodType(method string) string {
+	for _, m := range AvailablePaymentMethods() {
+		if m["id"] == method {
+			paymentType, _ := m["type"].(string)
+			return paymentType
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ PaymentGateway = (*XenditClient)(nil)
+
+// xenditStandIn serves canned Invoice API responses keyed by method and request URI
+func xenditStandIn(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *XenditClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "xnd_development_key", user)
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewXenditClient(&XenditConfig{SecretKey: "xnd_development_key", CallbackToken: "cb-token", BaseURL: server.URL})
+}
+
+func TestXenditClient_CreatePayment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Virtual Account", func(t *testing.T) {
+		var request XenditInvoiceRequest
+		client := xenditStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST /v2/invoices", r.Method+" "+r.URL.Path)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			fmt.Fprint(w, `{"id":"inv-1","external_id":"ORD-1","status":"PENDING","amount":150000,
+				"invoice_url":"https://checkout.xendit.co/web/inv-1","expiry_date":"2025-03-02T03:00:00.000Z",
+				"available_banks":[{"bank_code":"BCA","bank_account_number":"38165123456"}]}`)
+		})
+
+		charge, err := client.CreatePayment(ctx, &ChargeParams{
+			OrderID:  "ORD-1",
+			Amount:   150000,
+			Method:   "bca_va",
+			Customer: &CustomerDetails{FirstName: "Budi", Email: "budi@example.com"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"BCA"}, request.PaymentMethods)
+		assert.Equal(t, 86400, request.InvoiceDuration)
+		assert.Equal(t, "budi@example.com", request.PayerEmail)
+
+		assert.Equal(t, GatewayXendit, charge.Gateway)
+		assert.Equal(t, "inv-1", charge.TransactionID)
+		assert.Equal(t, StatusPending, charge.Status)
+		assert.Equal(t, "bank_transfer", charge.PaymentType)
+		assert.Equal(t, "2025-03-02 10:00:00", charge.ExpiryTime)
+		assert.Equal(t, []VANumber{{Bank: "bca", VANumber: "38165123456"}}, charge.VANumbers)
+		assert.Equal(t, "https://checkout.xendit.co/web/inv-1", charge.CheckoutURL)
+	})
+
+	t.Run("API Error", func(t *testing.T) {
+		client := xenditStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error_code":"DUPLICATE_ERROR","message":"external_id already used"}`)
+		})
+
+		_, err := client.CreatePayment(ctx, &ChargeParams{OrderID: "ORD-1", Amount: 1000, Method: "qris"})
+		assert.ErrorContains(t, err, "DUPLICATE_ERROR")
+	})
+
+	t.Run("GoPay Is Not Offered", func(t *testing.T) {
+		client := xenditStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("no request expected")
+		})
+
+		_, err := client.CreatePayment(ctx, &ChargeParams{OrderID: "ORD-1", Amount: 1000, Method: "gopay"})
+		assert.ErrorIs(t, err, ErrUnsupportedMethod)
+	})
+}
+
+func TestXenditClient_Status(t *testing.T) {
+	ctx := context.Background()
+	var expired bool
+	client := xenditStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v2/invoices":
+			switch r.URL.Query().Get("external_id") {
+			case "ORD-1":
+				fmt.Fprint(w, `[{"id":"inv-0","external_id":"ORD-1","status":"EXPIRED"},{"id":"inv-1","external_id":"ORD-1","status":"SETTLED","amount":150000}]`)
+			case "ORD-2":
+				fmt.Fprint(w, `[{"id":"inv-2","external_id":"ORD-2","status":"PENDING"}]`)
+			default:
+				fmt.Fprint(w, `[]`)
+			}
+		case "POST /invoices/inv-2/expire!":
+			expired = true
+			fmt.Fprint(w, `{"id":"inv-2","external_id":"ORD-2","status":"EXPIRED"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	charge, err := client.GetStatus(ctx, "ORD-1")
+	require.NoError(t, err)
+	assert.Equal(t, "inv-1", charge.TransactionID)
+	assert.Equal(t, StatusPaid, charge.Status)
+
+	charge, err = client.Cancel(ctx, "ORD-2")
+	require.NoError(t, err)
+	assert.True(t, expired)
+	assert.Equal(t, StatusExpired, charge.Status)
+
+	_, err = client.GetStatus(ctx, "ORD-404")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestXenditClient_Refund(t *testing.T) {
+	var request XenditRefundRequest
+	client := xenditStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v2/invoices":
+			fmt.Fprint(w, `[{"id":"inv-1","external_id":"ORD-1","status":"SETTLED","amount":150000}]`)
+		case "POST /refunds":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			fmt.Fprint(w, `{"id":"rfd-1","invoice_id":"inv-1","reference_id":"rf-1","amount":50000,"status":"PENDING"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	refund, err := client.Refund(context.Background(), &RefundParams{OrderID: "ORD-1", RefundKey: "rf-1", Amount: 50000})
+	require.NoError(t, err)
+	assert.Equal(t, "inv-1", request.InvoiceID)
+	assert.Equal(t, "rf-1", request.ReferenceID)
+	assert.Equal(t, "rfd-1", refund.RefundID)
+	assert.Equal(t, StatusPending, refund.Status)
+}
+
+func TestXenditClient_ParseNotification(t *testing.T) {
+	client := NewXenditClient(&XenditConfig{CallbackToken: "cb-token"})
+	body := []byte(`{"id":"inv-1","external_id":"ORD-1","status":"PAID","amount":150000,"paid_amount":150000,
+		"payment_method":"BANK_TRANSFER","payment_channel":"BCA","payment_id":"pay-1"}`)
+
+	header := http.Header{}
+	header.Set("X-Callback-Token", "cb-token")
+	notification, err := client.ParseNotification(header, body)
+	require.NoError(t, err)
+	assert.Equal(t, GatewayXendit, notification.Gateway)
+	assert.Equal(t, "ORD-1", notification.OrderID)
+	assert.Equal(t, "pay-1", notification.TransactionID)
+	assert.Equal(t, StatusPaid, notification.Status)
+	assert.Equal(t, "bank_transfer", notification.PaymentType)
+
+	header.Set("X-Callback-Token", "wrong")
+	_, err = client.ParseNotification(header, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = NewXenditClient(&XenditConfig{}).ParseNotification(http.Header{}, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
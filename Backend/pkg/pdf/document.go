@@ -0,0 +1,254 @@
+// Package pdf writes simple single-column PDF documents such as invoices and
+// receipts. It uses the standard Helvetica fonts, so nothing is embedded
+// except JPEG images.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font is one of the standard fonts every PDF reader provides
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+func (f Font) resource() string {
+	if f == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+type pdfImage struct {
+	data          []byte
+	width, height int
+}
+
+// Document is a PDF being built page by page. Coordinates are in points with
+// the origin at the top-left corner of the page.
+type Document struct {
+	pages  []*bytes.Buffer
+	images []pdfImage
+}
+
+// New returns a document with one empty A4 page
+func New() *Document {
+	d := &Document{}
+	d.AddPage()
+	return d
+}
+
+// AddPage starts a new page; later drawing goes to it
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// PageCount returns the number of pages
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+func (d *Document) content() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Text draws s with its baseline at y
+func (d *Document) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(d.content(), "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font.resource(), size, x, PageHeight-y, escapeText(s))
+}
+
+// TextRight draws s so that it ends at x
+func (d *Document) TextRight(x, y float64, font Font, size float64, s string) {
+	d.Text(x-TextWidth(font, size, s), y, font, size, s)
+}
+
+// TextGray draws s in a shade of gray, 0 being black and 1 white
+func (d *Document) TextGray(x, y float64, font Font, size, gray float64, s string) {
+	fmt.Fprintf(d.content(), "%.2f g\n", gray)
+	d.Text(x, y, font, size, s)
+	d.content().WriteString("0 g\n")
+}
+
+// Line draws a black line
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.content(), "%.2f w %.2f %.2f m %.2f %.2f l S\n",
+		width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// FillRect fills a rectangle whose top-left corner is at x, y
+func (d *Document) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(d.content(), "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n",
+		gray, x, PageHeight-y-h, w, h)
+}
+
+// Image draws img scaled into the w x h box whose top-left corner is at x, y.
+// Transparent areas become white.
+func (d *Document) Image(img image.Image, x, y, w, h float64) error {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 90}); err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	d.images = append(d.images, pdfImage{data: buf.Bytes(), width: bounds.Dx(), height: bounds.Dy()})
+	fmt.Fprintf(d.content(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n",
+		w, h, x, PageHeight-y-h, len(d.images))
+	return nil
+}
+
+// Bytes serializes the document
+func (d *Document) Bytes() []byte {
+	var objects [][]byte
+	add := func(body string) int {
+		objects = append(objects, []byte(body))
+		return len(objects)
+	}
+	addStream := func(dict string, data []byte) int {
+		if dict != "" {
+			dict += " "
+		}
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "<< %s/Length %d >>\nstream\n", dict, len(data))
+		b.Write(data)
+		b.WriteString("\nendstream")
+		objects = append(objects, b.Bytes())
+		return len(objects)
+	}
+
+	// Object numbers of the catalog and page tree are fixed
+	add("<< /Type /Catalog /Pages 2 0 R >>")
+	add("") // page tree, filled in once the pages are known
+	f1 := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	f2 := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	var xobjects strings.Builder
+	for i, img := range d.images {
+		n := addStream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode",
+			img.width, img.height), img.data)
+		fmt.Fprintf(&xobjects, " /Im%d %d 0 R", i+1, n)
+	}
+	resources := fmt.Sprintf("<< /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject <<%s >> >>", f1, f2, xobjects.String())
+
+	kids := make([]string, 0, len(d.pages))
+	for _, page := range d.pages {
+		contents := addStream("", page.Bytes())
+		n := add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>",
+			PageWidth, PageHeight, resources, contents))
+		kids = append(kids, fmt.Sprintf("%d 0 R", n))
+	}
+	objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(obj)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// winAnsi maps the characters outside Latin-1 that WinAnsiEncoding supports
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// encodeText converts s to WinAnsiEncoding, replacing unsupported characters
+func encodeText(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			out = append(out, byte(r))
+		case winAnsi[r] != 0:
+			out = append(out, winAnsi[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, c := range encodeText(s) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// TextWidth returns the width of s in points
+func TextWidth(font Font, size float64, s string) float64 {
+	widths := &helveticaWidths
+	if font == HelveticaBold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, c := range encodeText(s) {
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// WrapText splits s into lines no wider than width
+func WrapText(font Font, size float64, s string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && TextWidth(font, size, candidate) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
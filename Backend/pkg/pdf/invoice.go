@@ -0,0 +1,226 @@
+package pdf
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Party is the issuer or recipient printed on an invoice
+type Party struct {
+	Name  string
+	Lines []string
+}
+
+// InvoiceLine is one row of the item table
+type InvoiceLine struct {
+	Description string
+	Quantity    float64
+	UnitPrice   float64
+	Amount      float64
+}
+
+// InvoiceTotal is one row below the item table, e.g. subtotal or tax
+type InvoiceTotal struct {
+	Label  string
+	Amount float64
+	Bold   bool
+}
+
+// Detail is a label/value pair printed next to the recipient, e.g. the due date
+type Detail struct {
+	Label string
+	Value string
+}
+
+// Invoice describes an invoice or receipt document
+type Invoice struct {
+	Title   string // e.g. INVOICE or RECEIPT
+	Number  string
+	Issuer  Party
+	Logo    image.Image // optional
+	BillTo  Party
+	Details []Detail
+	Lines   []InvoiceLine
+	Totals  []InvoiceTotal
+	Stamp   string // large status mark, e.g. PAID
+	Notes   string
+	Footer  string
+}
+
+const (
+	margin       = 50.0
+	contentRight = PageWidth - margin
+	bottomLimit  = PageHeight - 90
+
+	colQuantity  = 340.0
+	colUnitPrice = 440.0
+)
+
+// FormatRupiah formats an amount the Indonesian way, e.g. "Rp 1.500.000"
+func FormatRupiah(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	cents := int64(math.Round(amount * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	if frac := cents % 100; frac != 0 {
+		fmt.Fprintf(&b, ",%02d", frac)
+	}
+	return sign + "Rp " + b.String()
+}
+
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// RenderInvoice lays out inv on A4 pages and returns the PDF bytes
+func RenderInvoice(inv *Invoice) ([]byte, error) {
+	d := New()
+	y := margin
+
+	// Issuer, with the logo on its left
+	textX := margin
+	if inv.Logo != nil {
+		w, h := fitBox(inv.Logo, 120, 60)
+		if err := d.Image(inv.Logo, margin, y, w, h); err != nil {
+			return nil, err
+		}
+		textX = margin + w + 12
+	}
+	d.Text(textX, y+14, HelveticaBold, 14, inv.Issuer.Name)
+	issuerY := y + 30
+	for _, line := range inv.Issuer.Lines {
+		for _, wrapped := range WrapText(Helvetica, 9, line, 250) {
+			d.TextGray(textX, issuerY, Helvetica, 9, 0.3, wrapped)
+			issuerY += 12
+		}
+	}
+
+	d.TextRight(contentRight, y+22, HelveticaBold, 22, inv.Title)
+	d.TextRight(contentRight, y+40, Helvetica, 10, inv.Number)
+
+	y = math.Max(issuerY, y+70) + 16
+	d.Line(margin, y, contentRight, y, 0.5)
+	y += 24
+
+	// Recipient on the left, details on the right
+	blockY := y
+	d.TextGray(margin, y, HelveticaBold, 9, 0.4, "BILL TO")
+	y += 16
+	d.Text(margin, y, HelveticaBold, 11, inv.BillTo.Name)
+	y += 14
+	for _, line := range inv.BillTo.Lines {
+		for _, wrapped := range WrapText(Helvetica, 9, line, 260) {
+			d.Text(margin, y, Helvetica, 9, wrapped)
+			y += 12
+		}
+	}
+
+	detailY := blockY
+	for _, detail := range inv.Details {
+		d.TextGray(colQuantity, detailY, Helvetica, 9, 0.4, detail.Label)
+		d.TextRight(contentRight, detailY, HelveticaBold, 9, detail.Value)
+		detailY += 14
+	}
+	y = math.Max(y, detailY) + 20
+
+	// Item table
+	y = tableHeader(d, y)
+	for _, line := range inv.Lines {
+		description := WrapText(Helvetica, 9, line.Description, colQuantity-margin-20)
+		rowHeight := float64(len(description))*12 + 8
+		if y+rowHeight > bottomLimit {
+			d.AddPage()
+			y = tableHeader(d, margin)
+		}
+
+		for i, text := range description {
+			d.Text(margin+6, y+12+float64(i)*12, Helvetica, 9, text)
+		}
+		d.TextRight(colQuantity+40, y+12, Helvetica, 9, formatQuantity(line.Quantity))
+		d.TextRight(colUnitPrice+60, y+12, Helvetica, 9, FormatRupiah(line.UnitPrice))
+		d.TextRight(contentRight-6, y+12, Helvetica, 9, FormatRupiah(line.Amount))
+		y += rowHeight
+		d.Line(margin, y, contentRight, y, 0.25)
+	}
+	y += 8
+
+	// Totals
+	if y+float64(len(inv.Totals))*16+40 > bottomLimit {
+		d.AddPage()
+		y = margin
+	}
+	for _, total := range inv.Totals {
+		font, size := Helvetica, 9.0
+		if total.Bold {
+			font, size = HelveticaBold, 11
+			d.Line(colQuantity, y+2, contentRight, y+2, 0.5)
+			y += 4
+		}
+		y += 14
+		d.Text(colQuantity, y, font, size, total.Label)
+		d.TextRight(contentRight-6, y, font, size, FormatRupiah(total.Amount))
+	}
+
+	if inv.Stamp != "" {
+		d.TextGray(margin, y, HelveticaBold, 28, 0.75, inv.Stamp)
+	}
+	y += 30
+
+	if inv.Notes != "" {
+		notes := WrapText(Helvetica, 9, inv.Notes, contentRight-margin)
+		if y+float64(len(notes))*12+16 > bottomLimit {
+			d.AddPage()
+			y = margin
+		}
+		d.TextGray(margin, y, HelveticaBold, 9, 0.4, "NOTES")
+		y += 14
+		for _, line := range notes {
+			d.Text(margin, y, Helvetica, 9, line)
+			y += 12
+		}
+	}
+
+	if inv.Footer != "" {
+		footerY := PageHeight - 50
+		for _, line := range WrapText(Helvetica, 8, inv.Footer, contentRight-margin) {
+			x := (PageWidth - TextWidth(Helvetica, 8, line)) / 2
+			d.TextGray(x, footerY, Helvetica, 8, 0.4, line)
+			footerY += 10
+		}
+	}
+
+	return d.Bytes(), nil
+}
+
+func tableHeader(d *Document, y float64) float64 {
+	d.FillRect(margin, y, contentRight-margin, 20, 0.92)
+	d.Text(margin+6, y+13, HelveticaBold, 9, "Description")
+	d.TextRight(colQuantity+40, y+13, HelveticaBold, 9, "Qty")
+	d.TextRight(colUnitPrice+60, y+13, HelveticaBold, 9, "Unit Price")
+	d.TextRight(contentRight-6, y+13, HelveticaBold, 9, "Amount")
+	return y + 20
+}
+
+// fitBox scales img down to fit in maxW x maxH, keeping its aspect ratio
+func fitBox(img image.Image, maxW, maxH float64) (float64, float64) {
+	w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	if w <= 0 || h <= 0 {
+		return maxW, maxH
+	}
+	scale := math.Min(maxW/w, maxH/h)
+	return w * scale, h * scale
+}
@@ -0,0 +1,80 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRupiah(t *testing.T) {
+	assert.Equal(t, "Rp 0", FormatRupiah(0))
+	assert.Equal(t, "Rp 150.000", FormatRupiah(150000))
+	assert.Equal(t, "Rp 1.234.567,50", FormatRupiah(1234567.5))
+	assert.Equal(t, "-Rp 50.000", FormatRupiah(-50000))
+}
+
+func TestTextWidth(t *testing.T) {
+	assert.InDelta(t, 5.56, TextWidth(Helvetica, 10, "0"), 0.001)
+	assert.Greater(t, TextWidth(HelveticaBold, 10, "Invoice"), TextWidth(Helvetica, 10, "Invoice"))
+}
+
+func TestWrapText(t *testing.T) {
+	lines := WrapText(Helvetica, 10, "Jl. Merdeka No. 10 RT 01 RW 02 Kelurahan Sukamaju", 100)
+	assert.Greater(t, len(lines), 1)
+	for _, line := range lines {
+		assert.LessOrEqual(t, TextWidth(Helvetica, 10, line), 100.0)
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	assert.Equal(t, `Paket \(10 Mbps\) \\ Rp`, escapeText(`Paket (10 Mbps) \ Rp`))
+	assert.Equal(t, "caf\xe9 ?", escapeText("café 日"))
+}
+
+func TestRenderInvoice(t *testing.T) {
+	logo := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	logo.Set(1, 1, color.RGBA{R: 255, A: 255})
+
+	inv := &Invoice{
+		Title:   "INVOICE",
+		Number:  "INV-000001",
+		Issuer:  Party{Name: "Net Desa", Lines: []string{"Jl. Merdeka 10"}},
+		Logo:    logo,
+		BillTo:  Party{Name: "Budi", Lines: []string{"CUST-001"}},
+		Details: []Detail{{Label: "Due Date", Value: "10/03/2025"}},
+		Totals:  []InvoiceTotal{{Label: "Total", Amount: 150000, Bold: true}},
+		Stamp:   "PAID",
+		Footer:  "Thank you",
+	}
+	for i := 0; i < 60; i++ {
+		inv.Lines = append(inv.Lines, InvoiceLine{Description: "Paket 10 Mbps", Quantity: 1, UnitPrice: 150000, Amount: 150000})
+	}
+
+	data, err := RenderInvoice(inv)
+	require.NoError(t, err)
+
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+	assert.Contains(t, string(data), "(INV-000001) Tj")
+	assert.Contains(t, string(data), "/Filter /DCTDecode")
+	assert.Contains(t, string(data), "/Count 3", "60 rows span three pages")
+
+	// startxref must point at the xref table and every entry at its object
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	require.NotNil(t, match)
+	xref, _ := strconv.Atoi(string(match[1]))
+	require.True(t, bytes.HasPrefix(data[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(data[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(data[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+}
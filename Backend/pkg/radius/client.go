@@ -0,0 +1,163 @@
+package radius
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultCoAPort is the standard Dynamic Authorization port (RFC 5176),
+// also the default of MikroTik "/radius incoming"
+const DefaultCoAPort = 3799
+
+// NAKError is returned when the NAS answers with Disconnect-NAK or CoA-NAK
+type NAKError struct {
+	Code       Code
+	ErrorCause uint32
+}
+
+func (e *NAKError) Error() string {
+	if cause, ok := errorCauses[e.ErrorCause]; ok {
+		return fmt.Sprintf("radius: %s: %s (%d)", e.Code, cause, e.ErrorCause)
+	}
+	if e.ErrorCause != 0 {
+		return fmt.Sprintf("radius: %s: error cause %d", e.Code, e.ErrorCause)
+	}
+	return fmt.Sprintf("radius: %s", e.Code)
+}
+
+// Client sends Dynamic Authorization requests over UDP
+type Client struct {
+	Timeout time.Duration // per attempt
+	Retries int           // extra attempts after the first
+}
+
+// NewClient creates a client with the given per-attempt timeout and retries
+func NewClient(timeout time.Duration, retries int) *Client {
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	return &Client{Timeout: timeout, Retries: retries}
+}
+
+// Session identifies a user session on a NAS. Any non-empty field is sent;
+// MikroTik matches on User-Name plus Acct-Session-Id or Framed-IP-Address.
+type Session struct {
+	Username        string
+	AcctSessionID   string
+	FramedIPAddress string
+	NASIPAddress    string
+}
+
+// Disconnect sends a Disconnect-Request and waits for Disconnect-ACK
+func (c *Client) Disconnect(ctx context.Context, addr, secret string, session Session) error {
+	p := NewPacket(CodeDisconnectRequest)
+	addSession(p, session)
+	_, err := c.Exchange(ctx, addr, secret, p)
+	return err
+}
+
+// UpdateRateLimit sends a CoA-Request changing Mikrotik-Rate-Limit for the
+// session and waits for CoA-ACK
+func (c *Client) UpdateRateLimit(ctx context.Context, addr, secret string, session Session, rateLimit string) error {
+	p := NewPacket(CodeCoARequest)
+	addSession(p, session)
+	p.AddVendorString(VendorMikrotik, MikrotikRateLimit, rateLimit)
+	_, err := c.Exchange(ctx, addr, secret, p)
+	return err
+}
+
+func addSession(p *Packet, session Session) {
+	if session.Username != "" {
+		p.AddString(AttrUserName, session.Username)
+	}
+	if session.AcctSessionID != "" {
+		p.AddString(AttrAcctSessionID, session.AcctSessionID)
+	}
+	if session.FramedIPAddress != "" {
+		p.AddIP(AttrFramedIPAddress, session.FramedIPAddress)
+	}
+	if session.NASIPAddress != "" {
+		p.AddIP(AttrNASIPAddress, session.NASIPAddress)
+	}
+}
+
+// Exchange signs and sends a request to addr (host or host:port, default
+// port 3799), retransmitting on timeout. An ACK returns the reply; a NAK
+// returns a *NAKError.
+func (c *Client) Exchange(ctx context.Context, addr, secret string, request *Packet) (*Packet, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, fmt.Sprint(DefaultCoAPort))
+	}
+
+	var id [1]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("radius: failed to generate identifier: %w", err)
+	}
+	request.Identifier = id[0]
+
+	raw, err := request.Sign(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("radius: failed to dial %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	// Unblock reads as soon as the caller gives up
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	buf := make([]byte, maxPacketLength)
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if _, err := conn.Write(raw); err != nil {
+			return nil, fmt.Errorf("radius: failed to send %s to %s: %w", request.Code, addr, err)
+		}
+		conn.SetReadDeadline(time.Now().Add(c.Timeout))
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, fmt.Errorf("radius: %w", ctxErr)
+				}
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break // retransmit
+				}
+				return nil, fmt.Errorf("radius: failed to read reply from %s: %w", addr, err)
+			}
+
+			reply, err := Decode(buf[:n])
+			if err != nil || reply.Identifier != request.Identifier {
+				continue // not ours; keep waiting
+			}
+			if !VerifyResponse(buf[:n], request, secret) {
+				return nil, ErrInvalidAuthenticator
+			}
+
+			switch reply.Code {
+			case request.Code + 1: // ACK
+				return reply, nil
+			case request.Code + 2: // NAK
+				return nil, &NAKError{Code: reply.Code, ErrorCause: reply.ErrorCause()}
+			default:
+				return nil, fmt.Errorf("radius: unexpected reply %s to %s", reply.Code, request.Code)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("radius: no reply from %s after %d attempts", addr, c.Retries+1)
+}
@@ -0,0 +1,91 @@
+package radius_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/pkg/radius"
+	"github.com/rtrwnet/saas-backend/pkg/radius/radiustest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	p := radius.NewPacket(radius.CodeCoARequest)
+	p.Identifier = 7
+	p.AddString(radius.AttrUserName, "budi")
+	p.AddIP(radius.AttrFramedIPAddress, "10.10.0.5")
+	p.AddVendorString(radius.VendorMikrotik, radius.MikrotikRateLimit, "5000k/10000k")
+
+	raw, err := p.Sign("s3cret")
+	require.NoError(t, err)
+	assert.True(t, radius.VerifyRequest(raw, "s3cret"))
+	assert.False(t, radius.VerifyRequest(raw, "wrong"))
+
+	got, err := radius.Decode(raw)
+	require.NoError(t, err)
+	assert.Equal(t, radius.CodeCoARequest, got.Code)
+	assert.Equal(t, byte(7), got.Identifier)
+	assert.Equal(t, "budi", got.Text(radius.AttrUserName))
+	assert.Equal(t, "10.10.0.5", got.IP(radius.AttrFramedIPAddress))
+	assert.Equal(t, "5000k/10000k", got.VendorString(radius.VendorMikrotik, radius.MikrotikRateLimit))
+}
+
+func TestClient_Disconnect(t *testing.T) {
+	nas := radiustest.NewServer("s3cret")
+	defer nas.Close()
+
+	client := radius.NewClient(200*time.Millisecond, 1)
+	ctx := context.Background()
+	session := radius.Session{Username: "budi", AcctSessionID: "81a00004", FramedIPAddress: "10.10.0.5"}
+
+	t.Run("ACK", func(t *testing.T) {
+		require.NoError(t, client.Disconnect(ctx, nas.Addr, "s3cret", session))
+
+		requests := nas.Requests()
+		require.Len(t, requests, 1)
+		assert.Equal(t, radius.CodeDisconnectRequest, requests[0].Code)
+		assert.Equal(t, "budi", requests[0].Text(radius.AttrUserName))
+		assert.Equal(t, "81a00004", requests[0].Text(radius.AttrAcctSessionID))
+	})
+
+	t.Run("NAK", func(t *testing.T) {
+		nas.Handle(func(*radius.Packet) uint32 { return 503 })
+		defer nas.Handle(nil)
+
+		err := client.Disconnect(ctx, nas.Addr, "s3cret", session)
+		var nak *radius.NAKError
+		require.ErrorAs(t, err, &nak)
+		assert.Equal(t, radius.CodeDisconnectNAK, nak.Code)
+		assert.Equal(t, uint32(503), nak.ErrorCause)
+		assert.Contains(t, err.Error(), "Session Context Not Found")
+	})
+
+	t.Run("Retransmits", func(t *testing.T) {
+		nas.DropNext(1)
+		assert.NoError(t, client.Disconnect(ctx, nas.Addr, "s3cret", session))
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		err := client.Disconnect(ctx, nas.Addr, "wrong", session)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no reply")
+	})
+}
+
+func TestClient_UpdateRateLimit(t *testing.T) {
+	nas := radiustest.NewServer("s3cret")
+	defer nas.Close()
+
+	client := radius.NewClient(200*time.Millisecond, 0)
+	session := radius.Session{Username: "budi", FramedIPAddress: "10.10.0.5"}
+
+	err := client.UpdateRateLimit(context.Background(), nas.Addr, "s3cret", session, "10000k/20000k")
+	require.NoError(t, err)
+
+	requests := nas.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, radius.CodeCoARequest, requests[0].Code)
+	assert.Equal(t, "10000k/20000k", requests[0].VendorString(radius.VendorMikrotik, radius.MikrotikRateLimit))
+}
@@ -0,0 +1,301 @@
+// Package radius implements the subset of RADIUS (RFC 2865) needed to send
+// Dynamic Authorization requests (RFC 5176) to a NAS: Disconnect-Request and
+// CoA-Request, plus the MikroTik vendor attributes used for rate limiting.
+package radius
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Code is a RADIUS packet code
+type Code byte
+
+// Dynamic Authorization codes (RFC 5176 section 2.3)
+const (
+	CodeDisconnectRequest Code = 40
+	CodeDisconnectACK     Code = 41
+	CodeDisconnectNAK     Code = 42
+	CodeCoARequest        Code = 43
+	CodeCoAACK            Code = 44
+	CodeCoANAK            Code = 45
+)
+
+func (c Code) String() string {
+	switch c {
+	case CodeDisconnectRequest:
+		return "Disconnect-Request"
+	case CodeDisconnectACK:
+		return "Disconnect-ACK"
+	case CodeDisconnectNAK:
+		return "Disconnect-NAK"
+	case CodeCoARequest:
+		return "CoA-Request"
+	case CodeCoAACK:
+		return "CoA-ACK"
+	case CodeCoANAK:
+		return "CoA-NAK"
+	}
+	return fmt.Sprintf("Code(%d)", byte(c))
+}
+
+// Standard attribute types
+const (
+	AttrUserName        byte = 1
+	AttrNASIPAddress    byte = 4
+	AttrFramedIPAddress byte = 8
+	AttrVendorSpecific  byte = 26
+	AttrAcctSessionID   byte = 44
+	AttrErrorCause      byte = 101
+)
+
+// MikroTik vendor attributes
+const (
+	VendorMikrotik    uint32 = 14988
+	MikrotikRateLimit byte   = 8
+)
+
+const (
+	headerLength            = 20
+	maxPacketLength         = 4096
+	maxAttributeValueLength = 253
+)
+
+// Error-Cause values a NAS may return in a NAK (RFC 5176 section 3.5)
+var errorCauses = map[uint32]string{
+	201: "Residual Session Context Removed",
+	401: "Unsupported Attribute",
+	402: "Missing Attribute",
+	403: "NAS Identification Mismatch",
+	404: "Invalid Request",
+	405: "Unsupported Service",
+	406: "Unsupported Extension",
+	407: "Invalid Attribute Value",
+	501: "Administratively Prohibited",
+	502: "Request Not Routable (Proxy)",
+	503: "Session Context Not Found",
+	504: "Session Context Not Removable",
+	505: "Other Proxy Processing Error",
+	506: "Resources Unavailable",
+	507: "Request Initiated",
+}
+
+// ErrInvalidAuthenticator is returned when a response fails verification,
+// which almost always means the shared secret is wrong
+var ErrInvalidAuthenticator = errors.New("radius: invalid response authenticator")
+
+// Attribute is a raw type/value pair
+type Attribute struct {
+	Type  byte
+	Value []byte
+}
+
+// Packet is a RADIUS packet
+type Packet struct {
+	Code          Code
+	Identifier    byte
+	Authenticator [16]byte
+	Attributes    []Attribute
+}
+
+// NewPacket creates an empty packet with the given code
+func NewPacket(code Code) *Packet {
+	return &Packet{Code: code}
+}
+
+// AddString appends a text attribute
+func (p *Packet) AddString(typ byte, value string) {
+	p.Attributes = append(p.Attributes, Attribute{Type: typ, Value: []byte(value)})
+}
+
+// AddIP appends an IPv4 address attribute. Invalid addresses are ignored.
+func (p *Packet) AddIP(typ byte, ip string) {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return
+	}
+	p.Attributes = append(p.Attributes, Attribute{Type: typ, Value: []byte(parsed)})
+}
+
+// AddVendorString appends a Vendor-Specific attribute holding a text value
+func (p *Packet) AddVendorString(vendor uint32, typ byte, value string) {
+	buf := make([]byte, 6, 6+len(value))
+	binary.BigEndian.PutUint32(buf, vendor)
+	buf[4] = typ
+	buf[5] = byte(2 + len(value))
+	buf = append(buf, value...)
+	p.Attributes = append(p.Attributes, Attribute{Type: AttrVendorSpecific, Value: buf})
+}
+
+// Text returns the first attribute of the given type as text
+func (p *Packet) Text(typ byte) string {
+	for _, a := range p.Attributes {
+		if a.Type == typ {
+			return string(a.Value)
+		}
+	}
+	return ""
+}
+
+// IP returns the first attribute of the given type as an IPv4 address
+func (p *Packet) IP(typ byte) string {
+	for _, a := range p.Attributes {
+		if a.Type == typ && len(a.Value) == 4 {
+			return net.IP(a.Value).String()
+		}
+	}
+	return ""
+}
+
+// VendorString returns the first vendor attribute of the given vendor and type
+func (p *Packet) VendorString(vendor uint32, typ byte) string {
+	for _, a := range p.Attributes {
+		if a.Type != AttrVendorSpecific || len(a.Value) < 6 {
+			continue
+		}
+		if binary.BigEndian.Uint32(a.Value) != vendor {
+			continue
+		}
+		// A Vendor-Specific attribute may carry several sub-attributes
+		rest := a.Value[4:]
+		for len(rest) >= 2 {
+			l := int(rest[1])
+			if l < 2 || l > len(rest) {
+				break
+			}
+			if rest[0] == typ {
+				return string(rest[2:l])
+			}
+			rest = rest[l:]
+		}
+	}
+	return ""
+}
+
+// ErrorCause returns the Error-Cause attribute, or 0 if absent
+func (p *Packet) ErrorCause() uint32 {
+	for _, a := range p.Attributes {
+		if a.Type == AttrErrorCause && len(a.Value) == 4 {
+			return binary.BigEndian.Uint32(a.Value)
+		}
+	}
+	return 0
+}
+
+// Encode serializes the packet. The Authenticator field is written as is;
+// use Sign for requests and SignResponse for replies.
+func (p *Packet) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{byte(p.Code), p.Identifier, 0, 0})
+	buf.Write(p.Authenticator[:])
+	for _, a := range p.Attributes {
+		if len(a.Value) > maxAttributeValueLength {
+			return nil, fmt.Errorf("radius: attribute %d too long (%d bytes)", a.Type, len(a.Value))
+		}
+		buf.WriteByte(a.Type)
+		buf.WriteByte(byte(2 + len(a.Value)))
+		buf.Write(a.Value)
+	}
+	if buf.Len() > maxPacketLength {
+		return nil, fmt.Errorf("radius: packet too long (%d bytes)", buf.Len())
+	}
+
+	raw := buf.Bytes()
+	binary.BigEndian.PutUint16(raw[2:4], uint16(len(raw)))
+	return raw, nil
+}
+
+// Sign computes the Request Authenticator for a CoA or Disconnect request
+// (RFC 5176 section 2.3, same as Accounting-Request in RFC 2866) and returns
+// the encoded packet
+func (p *Packet) Sign(secret string) ([]byte, error) {
+	p.Authenticator = [16]byte{}
+	raw, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+	sum := authenticator(raw, nil, secret)
+	copy(p.Authenticator[:], sum)
+	copy(raw[4:20], sum)
+	return raw, nil
+}
+
+// SignResponse computes the Response Authenticator for a reply to request
+// and returns the encoded packet
+func (p *Packet) SignResponse(request *Packet, secret string) ([]byte, error) {
+	raw, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+	sum := authenticator(raw, request.Authenticator[:], secret)
+	copy(p.Authenticator[:], sum)
+	copy(raw[4:20], sum)
+	return raw, nil
+}
+
+// VerifyRequest checks the Request Authenticator of a received CoA or
+// Disconnect request
+func VerifyRequest(raw []byte, secret string) bool {
+	if len(raw) < headerLength {
+		return false
+	}
+	sum := authenticator(raw, make([]byte, 16), secret)
+	return bytes.Equal(sum, raw[4:20])
+}
+
+// VerifyResponse checks the Response Authenticator of a reply
+func VerifyResponse(raw []byte, request *Packet, secret string) bool {
+	if len(raw) < headerLength {
+		return false
+	}
+	sum := authenticator(raw, request.Authenticator[:], secret)
+	return bytes.Equal(sum, raw[4:20])
+}
+
+// authenticator returns MD5(Code+ID+Length+auth+Attributes+Secret), where
+// auth is the given 16 bytes or zeros when nil
+func authenticator(raw, auth []byte, secret string) []byte {
+	if auth == nil {
+		auth = make([]byte, 16)
+	}
+	h := md5.New()
+	h.Write(raw[:4])
+	h.Write(auth)
+	h.Write(raw[headerLength:])
+	h.Write([]byte(secret))
+	return h.Sum(nil)
+}
+
+// Decode parses a raw packet
+func Decode(raw []byte) (*Packet, error) {
+	if len(raw) < headerLength {
+		return nil, fmt.Errorf("radius: packet too short (%d bytes)", len(raw))
+	}
+	length := int(binary.BigEndian.Uint16(raw[2:4]))
+	if length < headerLength || length > len(raw) {
+		return nil, fmt.Errorf("radius: invalid packet length %d", length)
+	}
+
+	p := &Packet{Code: Code(raw[0]), Identifier: raw[1]}
+	copy(p.Authenticator[:], raw[4:20])
+
+	rest := raw[headerLength:length]
+	for len(rest) > 0 {
+		if len(rest) < 2 {
+			return nil, errors.New("radius: truncated attribute")
+		}
+		l := int(rest[1])
+		if l < 2 || l > len(rest) {
+			return nil, fmt.Errorf("radius: invalid attribute length %d", l)
+		}
+		value := make([]byte, l-2)
+		copy(value, rest[2:l])
+		p.Attributes = append(p.Attributes, Attribute{Type: rest[0], Value: value})
+		rest = rest[l:]
+	}
+	return p, nil
+}
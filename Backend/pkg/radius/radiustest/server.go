@@ -0,0 +1,136 @@
+// Package radiustest provides an in-process UDP responder that acts like a
+// NAS accepting RFC 5176 Disconnect and CoA requests, for use in tests.
+package radiustest
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/rtrwnet/saas-backend/pkg/radius"
+)
+
+// Handler decides how the NAS answers a verified request. Return 0 to ACK,
+// or an Error-Cause value (e.g. 503 Session Context Not Found) to NAK.
+type Handler func(request *radius.Packet) uint32
+
+// Server is a fake NAS listening on a local UDP port
+type Server struct {
+	Addr   string
+	Secret string
+
+	conn *net.UDPConn
+
+	mu       sync.Mutex
+	drop     int
+	handler  Handler
+	requests []*radius.Packet
+	done     chan struct{}
+}
+
+// NewServer starts a responder on 127.0.0.1 that ACKs every request signed
+// with secret and ignores requests with a bad authenticator
+func NewServer(secret string) *Server {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		panic("radiustest: failed to listen: " + err.Error())
+	}
+
+	s := &Server{
+		Addr:   conn.LocalAddr().String(),
+		Secret: secret,
+		conn:   conn,
+		done:   make(chan struct{}),
+	}
+	go s.serve()
+	return s
+}
+
+// Host returns the listener IP
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+
+// Port returns the listener port
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.conn.Close()
+	<-s.done
+}
+
+// Handle replaces the default ACK-everything behaviour
+func (s *Server) Handle(h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = h
+}
+
+// DropNext makes the server swallow the next n requests without replying
+func (s *Server) DropNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop = n
+}
+
+// Requests returns the verified requests received so far
+func (s *Server) Requests() []*radius.Packet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*radius.Packet(nil), s.requests...)
+}
+
+func (s *Server) serve() {
+	defer close(s.done)
+
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		raw := append([]byte(nil), buf[:n]...)
+
+		request, err := radius.Decode(raw)
+		if err != nil || !radius.VerifyRequest(raw, s.Secret) {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.drop > 0 {
+			s.drop--
+			s.mu.Unlock()
+			continue
+		}
+		s.requests = append(s.requests, request)
+		handler := s.handler
+		s.mu.Unlock()
+
+		var cause uint32
+		if handler != nil {
+			cause = handler(request)
+		}
+
+		reply := radius.NewPacket(request.Code + 1)
+		if cause != 0 {
+			reply.Code = request.Code + 2
+			reply.Attributes = append(reply.Attributes, radius.Attribute{
+				Type:  radius.AttrErrorCause,
+				Value: []byte{byte(cause >> 24), byte(cause >> 16), byte(cause >> 8), byte(cause)},
+			})
+		}
+		reply.Identifier = request.Identifier
+
+		out, err := reply.SignResponse(request, s.Secret)
+		if err != nil {
+			continue
+		}
+		s.conn.WriteToUDP(out, addr)
+	}
+}
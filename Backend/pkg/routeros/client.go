@@ -0,0 +1,212 @@
+package routeros
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Default RouterOS API ports
+const (
+	DefaultPort    = "8728" // api
+	DefaultTLSPort = "8729" // api-ssl
+)
+
+// DefaultTimeout is used when Config.Timeout is not set
+const DefaultTimeout = 10 * time.Second
+
+// ErrClosed is returned when a command is issued on a closed client
+var ErrClosed = errors.New("routeros: connection closed")
+
+// Config holds RouterOS API connection settings
+type Config struct {
+	Address   string // host:port
+	Username  string
+	Password  string
+	UseTLS    bool
+	TLSConfig *tls.Config
+	Timeout   time.Duration // dial and per-command timeout
+}
+
+// Client is a RouterOS API client. A Client runs one command at a time and
+// is safe for concurrent use.
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Dial connects to a router and logs in
+func Dial(ctx context.Context, cfg *Config) (*Client, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if cfg.UseTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: cfg.TLSConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", cfg.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", cfg.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("routeros: failed to dial %s: %w", cfg.Address, err)
+	}
+
+	c := &Client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		timeout: timeout,
+	}
+
+	if err := c.login(ctx, cfg.Username, cfg.Password); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// login authenticates using the post-6.43 plain login, falling back to the
+// legacy MD5 challenge-response when the router returns a challenge
+func (c *Client) login(ctx context.Context, username, password string) error {
+	reply, err := c.Run(ctx, "/login", "=name="+username, "=password="+password)
+	if err != nil {
+		return fmt.Errorf("routeros: login failed: %w", err)
+	}
+
+	challenge, ok := reply.Done.Attrs["ret"]
+	if !ok {
+		return nil
+	}
+
+	raw, err := hex.DecodeString(challenge)
+	if err != nil {
+		return fmt.Errorf("routeros: invalid login challenge: %w", err)
+	}
+
+	h := md5.New()
+	h.Write([]byte{0})
+	h.Write([]byte(password))
+	h.Write(raw)
+	response := "00" + hex.EncodeToString(h.Sum(nil))
+
+	if _, err := c.Run(ctx, "/login", "=name="+username, "=response="+response); err != nil {
+		return fmt.Errorf("routeros: login failed: %w", err)
+	}
+	return nil
+}
+
+// Run sends a command and waits for its complete reply. Arguments are raw
+// API words such as "=name=foo" or "?disabled=false".
+func (c *Client) Run(ctx context.Context, command string, args ...string) (*Reply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+
+	// Unblock pending I/O as soon as the caller gives up
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	words := append([]string{command}, args...)
+	if err := WriteSentence(c.conn, words...); err != nil {
+		c.closeLocked()
+		return nil, c.wrapIOError(ctx, err)
+	}
+
+	reply := &Reply{}
+	var trap *DeviceError
+	for {
+		raw, err := ReadSentence(c.reader)
+		if err != nil {
+			c.closeLocked()
+			return nil, c.wrapIOError(ctx, err)
+		}
+
+		sentence := parseSentence(raw)
+		switch sentence.Word {
+		case ReplyRe:
+			reply.Re = append(reply.Re, sentence)
+		case ReplyTrap:
+			// A trap is followed by !done; keep reading to drain the reply
+			if trap == nil {
+				trap = &DeviceError{
+					Category: sentence.Attrs["category"],
+					Message:  sentence.Attrs["message"],
+				}
+			}
+		case ReplyFatal:
+			c.closeLocked()
+			return nil, &FatalError{Message: sentence.Attrs["message"]}
+		case ReplyDone, ReplyEmpty:
+			reply.Done = sentence
+			if sentence.Word == ReplyEmpty {
+				// !empty (RouterOS 7.18+) is always followed by !done
+				continue
+			}
+			if trap != nil {
+				return nil, trap
+			}
+			return reply, nil
+		}
+	}
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+// Closed reports whether the connection has been closed, either explicitly
+// or because of an I/O or fatal error
+func (c *Client) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *Client) closeLocked() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.conn.Close()
+}
+
+func (c *Client) wrapIOError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("routeros: %w", ctxErr)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("routeros: command timed out: %w", err)
+	}
+	return fmt.Errorf("routeros: connection error: %w", err)
+}
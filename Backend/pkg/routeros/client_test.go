@@ -0,0 +1,145 @@
+package routeros_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/pkg/routeros"
+	"github.com/rtrwnet/saas-backend/pkg/routeros/routerostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSentenceRoundTrip(t *testing.T) {
+	words := []string{
+		"/queue/simple/add",
+		"=name=" + strings.Repeat("a", 0x7F),
+		"=comment=" + strings.Repeat("b", 0x3FFF),
+		"=x=" + strings.Repeat("c", 0x4000),
+		"=y=" + strings.Repeat("d", 0x200000),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, routeros.WriteSentence(&buf, words...))
+
+	got, err := routeros.ReadSentence(bufio.NewReader(&buf))
+	require.NoError(t, err)
+	assert.Equal(t, words, got)
+}
+
+func TestDial_Login(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		client, err := routeros.Dial(ctx, &routeros.Config{Address: srv.Addr, Username: "admin", Password: "secret"})
+		require.NoError(t, err)
+		defer client.Close()
+
+		reply, err := client.Run(ctx, "/system/identity/print")
+		require.NoError(t, err)
+		require.Len(t, reply.Re, 1)
+		assert.Equal(t, "MikroTik", reply.Re[0].Attrs["name"])
+	})
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		client, err := routeros.Dial(ctx, &routeros.Config{Address: srv.Addr, Username: "admin", Password: "wrong"})
+		assert.Nil(t, client)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user name or password")
+	})
+
+	t.Run("Legacy Challenge", func(t *testing.T) {
+		legacy := routerostest.NewServer("admin", "secret")
+		legacy.LegacyLogin = true
+		defer legacy.Close()
+
+		client, err := routeros.Dial(ctx, &routeros.Config{Address: legacy.Addr, Username: "admin", Password: "secret"})
+		require.NoError(t, err)
+		client.Close()
+	})
+}
+
+func TestDial_TLS(t *testing.T) {
+	srv := routerostest.NewTLSServer("admin", "secret")
+	defer srv.Close()
+
+	ctx := context.Background()
+	client, err := routeros.Dial(ctx, &routeros.Config{
+		Address:   srv.Addr,
+		Username:  "admin",
+		Password:  "secret",
+		UseTLS:    true,
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Run(ctx, "/system/identity/print")
+	assert.NoError(t, err)
+}
+
+func TestRun_Errors(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+
+	ctx := context.Background()
+	dial := func() *routeros.Client {
+		client, err := routeros.Dial(ctx, &routeros.Config{
+			Address:  srv.Addr,
+			Username: "admin",
+			Password: "secret",
+			Timeout:  200 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("Trap", func(t *testing.T) {
+		client := dial()
+		defer client.Close()
+
+		_, err := client.Run(ctx, "/queue/simple/set", "=.id=*99", "=max-limit=1M/1M")
+		var devErr *routeros.DeviceError
+		require.ErrorAs(t, err, &devErr)
+		assert.Equal(t, "no such item", devErr.Message)
+
+		// Connection stays usable after a trap
+		_, err = client.Run(ctx, "/system/identity/print")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Fatal", func(t *testing.T) {
+		client := dial()
+		defer client.Close()
+
+		_, err := client.Run(ctx, "/quit")
+		var fatalErr *routeros.FatalError
+		require.ErrorAs(t, err, &fatalErr)
+		assert.True(t, client.Closed())
+
+		_, err = client.Run(ctx, "/system/identity/print")
+		assert.ErrorIs(t, err, routeros.ErrClosed)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		srv.Handle("/slow/print", func(map[string]string) ([]map[string]string, error) {
+			time.Sleep(time.Second)
+			return nil, nil
+		})
+		client := dial()
+		defer client.Close()
+
+		_, err := client.Run(ctx, "/slow/print")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out")
+		assert.True(t, client.Closed())
+	})
+}
@@ -0,0 +1,338 @@
+package routeros
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a router has failed too many times in a
+// row and the pool refuses to dial it until the cooldown has passed
+var ErrCircuitOpen = errors.New("routeros: router temporarily unavailable (circuit open)")
+
+// ErrPoolClosed is returned when acquiring from a closed pool
+var ErrPoolClosed = errors.New("routeros: pool closed")
+
+// PoolConfig holds connection pool settings
+type PoolConfig struct {
+	MaxConnsPerRouter int           // concurrent sessions per router
+	IdleTimeout       time.Duration // idle sessions older than this are closed
+	DialRetries       int           // extra dial attempts after the first
+	BackoffBase       time.Duration // first retry delay, doubled per attempt
+	BackoffMax        time.Duration
+	FailureThreshold  int           // consecutive failures that open the circuit
+	Cooldown          time.Duration // how long the circuit stays open
+}
+
+// DefaultPoolConfig returns sensible defaults for RT/RW Net routers
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxConnsPerRouter: 2,
+		IdleTimeout:       2 * time.Minute,
+		DialRetries:       2,
+		BackoffBase:       500 * time.Millisecond,
+		BackoffMax:        5 * time.Second,
+		FailureThreshold:  3,
+		Cooldown:          30 * time.Second,
+	}
+}
+
+// Pool keeps authenticated sessions per router key (typically Device.ID).
+// Each router has its own connection limit, idle list and circuit breaker,
+// so a slow or dead router never blocks the others.
+type Pool struct {
+	cfg  PoolConfig
+	dial func(ctx context.Context, cfg *Config) (*Client, error)
+
+	mu      sync.Mutex
+	routers map[string]*routerPool
+	closed  bool
+	stop    chan struct{}
+}
+
+type routerPool struct {
+	key       string
+	cfg       Config
+	slots     chan struct{}
+	idle      []*idleConn
+	failures  int
+	openUntil time.Time
+}
+
+type idleConn struct {
+	client *Client
+	since  time.Time
+}
+
+// Conn is a session borrowed from the pool. Call Release when done.
+type Conn struct {
+	pool   *Pool
+	router *routerPool
+	client *Client
+	cfg    Config
+	done   bool
+}
+
+// NewPool creates a pool and starts its idle eviction loop
+func NewPool(cfg PoolConfig) *Pool {
+	def := DefaultPoolConfig()
+	if cfg.MaxConnsPerRouter <= 0 {
+		cfg.MaxConnsPerRouter = def.MaxConnsPerRouter
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = def.IdleTimeout
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = def.BackoffBase
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = def.BackoffMax
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = def.FailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = def.Cooldown
+	}
+
+	p := &Pool{
+		cfg:     cfg,
+		dial:    Dial,
+		routers: make(map[string]*routerPool),
+		stop:    make(chan struct{}),
+	}
+	go p.evictLoop()
+	return p
+}
+
+// Acquire borrows a session for the router identified by key. If the
+// connection settings changed since the last call, existing idle sessions
+// for that key are discarded.
+func (p *Pool) Acquire(ctx context.Context, key string, cfg *Config) (*Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	r := p.routerLocked(key, cfg)
+	if time.Now().Before(r.openUntil) {
+		p.mu.Unlock()
+		return nil, ErrCircuitOpen
+	}
+	slots := r.slots
+	p.mu.Unlock()
+
+	// Wait for a free slot on this router
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("routeros: waiting for connection: %w", ctx.Err())
+	}
+
+	// Reuse an idle session if one is still alive
+	p.mu.Lock()
+	for len(r.idle) > 0 {
+		ic := r.idle[len(r.idle)-1]
+		r.idle = r.idle[:len(r.idle)-1]
+		if !ic.client.Closed() {
+			p.mu.Unlock()
+			return &Conn{pool: p, router: r, client: ic.client, cfg: r.cfg}, nil
+		}
+	}
+	dialCfg := r.cfg
+	p.mu.Unlock()
+
+	client, err := p.dialWithBackoff(ctx, &dialCfg)
+	if err != nil {
+		<-slots
+		p.recordFailure(r)
+		return nil, err
+	}
+
+	p.recordSuccess(r)
+	return &Conn{pool: p, router: r, client: client, cfg: dialCfg}, nil
+}
+
+// Evict closes idle sessions for a router and resets its circuit breaker.
+// Use it after a device is deleted or when an operator retries manually.
+func (p *Pool) Evict(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.routers[key]
+	if !ok {
+		return
+	}
+	for _, ic := range r.idle {
+		ic.client.Close()
+	}
+	r.idle = nil
+	r.failures = 0
+	r.openUntil = time.Time{}
+}
+
+// Close closes every idle session and stops the eviction loop. Sessions that
+// are still borrowed are closed when released.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.stop)
+	for _, r := range p.routers {
+		for _, ic := range r.idle {
+			ic.client.Close()
+		}
+		r.idle = nil
+	}
+}
+
+// routerLocked returns the per-router state, resetting it when the
+// connection settings differ from the cached ones
+func (p *Pool) routerLocked(key string, cfg *Config) *routerPool {
+	r, ok := p.routers[key]
+	if ok && sameConfig(&r.cfg, cfg) {
+		return r
+	}
+	if ok {
+		for _, ic := range r.idle {
+			ic.client.Close()
+		}
+		r.idle = nil
+		r.cfg = *cfg
+		r.failures = 0
+		r.openUntil = time.Time{}
+		return r
+	}
+
+	r = &routerPool{
+		key:   key,
+		cfg:   *cfg,
+		slots: make(chan struct{}, p.cfg.MaxConnsPerRouter),
+	}
+	p.routers[key] = r
+	return r
+}
+
+func (p *Pool) dialWithBackoff(ctx context.Context, cfg *Config) (*Client, error) {
+	delay := p.cfg.BackoffBase
+	var lastErr error
+	for attempt := 0; attempt <= p.cfg.DialRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, fmt.Errorf("routeros: %w (last error: %v)", ctx.Err(), lastErr)
+			}
+			delay *= 2
+			if delay > p.cfg.BackoffMax {
+				delay = p.cfg.BackoffMax
+			}
+		}
+
+		client, err := p.dial(ctx, cfg)
+		if err == nil {
+			return client, nil
+		}
+		lastErr = err
+
+		// Wrong credentials will not fix themselves; don't hammer the router
+		var devErr *DeviceError
+		if errors.As(err, &devErr) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+func (p *Pool) recordFailure(r *routerPool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r.failures++
+	if r.failures >= p.cfg.FailureThreshold {
+		r.openUntil = time.Now().Add(p.cfg.Cooldown)
+	}
+}
+
+func (p *Pool) recordSuccess(r *routerPool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r.failures = 0
+	r.openUntil = time.Time{}
+}
+
+func (p *Pool) evictLoop() {
+	ticker := time.NewTicker(p.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.evictIdle()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *Pool) evictIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cutoff := time.Now().Add(-p.cfg.IdleTimeout)
+	for _, r := range p.routers {
+		kept := r.idle[:0]
+		for _, ic := range r.idle {
+			if ic.since.Before(cutoff) || ic.client.Closed() {
+				ic.client.Close()
+				continue
+			}
+			kept = append(kept, ic)
+		}
+		r.idle = kept
+	}
+}
+
+// Run executes a command on the borrowed session. Connection-level failures
+// count against the router's circuit breaker; !trap replies do not.
+func (c *Conn) Run(ctx context.Context, command string, args ...string) (*Reply, error) {
+	reply, err := c.client.Run(ctx, command, args...)
+	if err != nil && c.client.Closed() {
+		c.pool.recordFailure(c.router)
+	}
+	return reply, err
+}
+
+// Release returns the session to the pool. Broken sessions, sessions opened
+// with outdated settings and sessions released after Close are discarded.
+func (c *Conn) Release() {
+	if c.done {
+		return
+	}
+	c.done = true
+
+	p := c.pool
+	p.mu.Lock()
+	if !p.closed && !c.client.Closed() && sameConfig(&c.router.cfg, &c.cfg) {
+		c.router.idle = append(c.router.idle, &idleConn{client: c.client, since: time.Now()})
+	} else {
+		c.client.Close()
+	}
+	p.mu.Unlock()
+
+	<-c.router.slots
+}
+
+func sameConfig(a, b *Config) bool {
+	return a.Address == b.Address &&
+		a.Username == b.Username &&
+		a.Password == b.Password &&
+		a.UseTLS == b.UseTLS
+}
@@ -0,0 +1,174 @@
+package routeros_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/pkg/routeros"
+	"github.com/rtrwnet/saas-backend/pkg/routeros/routerostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPoolConfig() routeros.PoolConfig {
+	return routeros.PoolConfig{
+		MaxConnsPerRouter: 2,
+		IdleTimeout:       time.Minute,
+		DialRetries:       0,
+		BackoffBase:       10 * time.Millisecond,
+		BackoffMax:        20 * time.Millisecond,
+		FailureThreshold:  2,
+		Cooldown:          time.Minute,
+	}
+}
+
+func TestPool_ReusesSessions(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+
+	pool := routeros.NewPool(testPoolConfig())
+	defer pool.Close()
+
+	ctx := context.Background()
+	cfg := &routeros.Config{Address: srv.Addr, Username: "admin", Password: "secret"}
+
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Acquire(ctx, "router-1", cfg)
+		require.NoError(t, err)
+		_, err = conn.Run(ctx, "/system/identity/print")
+		require.NoError(t, err)
+		conn.Release()
+	}
+
+	assert.Equal(t, 1, srv.ConnectionCount())
+}
+
+func TestPool_LimitsConcurrentSessions(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+
+	var mu sync.Mutex
+	active, peak := 0, 0
+	srv.Handle("/slow/print", func(map[string]string) ([]map[string]string, error) {
+		mu.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+
+		time.Sleep(30 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil, nil
+	})
+
+	pool := routeros.NewPool(testPoolConfig())
+	defer pool.Close()
+
+	ctx := context.Background()
+	cfg := &routeros.Config{Address: srv.Addr, Username: "admin", Password: "secret"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := pool.Acquire(ctx, "router-1", cfg)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Release()
+			_, err = conn.Run(ctx, "/slow/print")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, peak)
+	assert.LessOrEqual(t, srv.ConnectionCount(), 2)
+}
+
+func TestPool_CircuitBreaker(t *testing.T) {
+	pool := routeros.NewPool(testPoolConfig())
+	defer pool.Close()
+
+	ctx := context.Background()
+	cfg := &routeros.Config{Address: "127.0.0.1:1", Username: "admin", Password: "secret", Timeout: 200 * time.Millisecond}
+
+	for i := 0; i < 2; i++ {
+		_, err := pool.Acquire(ctx, "dead-router", cfg)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, routeros.ErrCircuitOpen)
+	}
+
+	_, err := pool.Acquire(ctx, "dead-router", cfg)
+	assert.ErrorIs(t, err, routeros.ErrCircuitOpen)
+
+	// Other routers are unaffected
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+	conn, err := pool.Acquire(ctx, "live-router", &routeros.Config{Address: srv.Addr, Username: "admin", Password: "secret"})
+	require.NoError(t, err)
+	conn.Release()
+
+	// Evict resets the breaker
+	pool.Evict("dead-router")
+	_, err = pool.Acquire(ctx, "dead-router", cfg)
+	assert.NotErrorIs(t, err, routeros.ErrCircuitOpen)
+}
+
+func TestPool_ConfigChangeDropsIdleSessions(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+
+	pool := routeros.NewPool(testPoolConfig())
+	defer pool.Close()
+
+	ctx := context.Background()
+	cfg := &routeros.Config{Address: srv.Addr, Username: "admin", Password: "secret"}
+
+	conn, err := pool.Acquire(ctx, "router-1", cfg)
+	require.NoError(t, err)
+	conn.Release()
+
+	_, err = pool.Acquire(ctx, "router-1", &routeros.Config{Address: srv.Addr, Username: "admin", Password: "wrong"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid user name or password")
+}
+
+func TestPool_BrokenSessionIsNotReused(t *testing.T) {
+	srv := routerostest.NewServer("admin", "secret")
+	defer srv.Close()
+
+	pool := routeros.NewPool(testPoolConfig())
+	defer pool.Close()
+
+	ctx := context.Background()
+	cfg := &routeros.Config{Address: srv.Addr, Username: "admin", Password: "secret"}
+
+	conn, err := pool.Acquire(ctx, "router-1", cfg)
+	require.NoError(t, err)
+	_, err = conn.Run(ctx, "/quit")
+	require.Error(t, err)
+	conn.Release()
+
+	conn, err = pool.Acquire(ctx, "router-1", cfg)
+	require.NoError(t, err)
+	defer conn.Release()
+
+	_, err = conn.Run(ctx, "/system/identity/print")
+	assert.NoError(t, err)
+}
+
+func TestPool_Closed(t *testing.T) {
+	pool := routeros.NewPool(testPoolConfig())
+	pool.Close()
+
+	_, err := pool.Acquire(context.Background(), "router-1", &routeros.Config{Address: "127.0.0.1:1"})
+	assert.ErrorIs(t, err, routeros.ErrPoolClosed)
+}
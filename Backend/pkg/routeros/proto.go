@@ -0,0 +1,188 @@
+package routeros
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ========================================
+// Word / Sentence Encoding
+// ========================================
+//
+// The RouterOS API is a stream of sentences. Each sentence is a list of
+// words terminated by a zero-length word, and each word is prefixed by its
+// length using a variable-width encoding (1 to 5 bytes).
+
+// encodeLength encodes a word length using the RouterOS variable-width scheme
+func encodeLength(l int) []byte {
+	switch {
+	case l < 0x80:
+		return []byte{byte(l)}
+	case l < 0x4000:
+		l |= 0x8000
+		return []byte{byte(l >> 8), byte(l)}
+	case l < 0x200000:
+		l |= 0xC00000
+		return []byte{byte(l >> 16), byte(l >> 8), byte(l)}
+	case l < 0x10000000:
+		l |= 0xE0000000
+		return []byte{byte(l >> 24), byte(l >> 16), byte(l >> 8), byte(l)}
+	default:
+		return []byte{0xF0, byte(l >> 24), byte(l >> 16), byte(l >> 8), byte(l)}
+	}
+}
+
+// readLength decodes a word length from the stream
+func readLength(r *bufio.Reader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var extra int
+	var l int
+	switch {
+	case b&0x80 == 0x00:
+		return int(b), nil
+	case b&0xC0 == 0x80:
+		extra, l = 1, int(b&0x3F)
+	case b&0xE0 == 0xC0:
+		extra, l = 2, int(b&0x1F)
+	case b&0xF0 == 0xE0:
+		extra, l = 3, int(b&0x0F)
+	case b == 0xF0:
+		extra, l = 4, 0
+	default:
+		return 0, fmt.Errorf("routeros: invalid length prefix 0x%02x", b)
+	}
+
+	for i := 0; i < extra; i++ {
+		next, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		l = l<<8 | int(next)
+	}
+	return l, nil
+}
+
+// WriteSentence writes a sentence (words followed by the empty terminator word)
+func WriteSentence(w io.Writer, words ...string) error {
+	var buf []byte
+	for _, word := range words {
+		buf = append(buf, encodeLength(len(word))...)
+		buf = append(buf, word...)
+	}
+	buf = append(buf, 0)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadSentence reads a single sentence and returns its words
+func ReadSentence(r *bufio.Reader) ([]string, error) {
+	var words []string
+	for {
+		l, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		if l == 0 {
+			return words, nil
+		}
+
+		word := make([]byte, l)
+		if _, err := io.ReadFull(r, word); err != nil {
+			return nil, err
+		}
+		words = append(words, string(word))
+	}
+}
+
+// ========================================
+// Reply Types
+// ========================================
+
+// Reply word constants
+const (
+	ReplyRe    = "!re"
+	ReplyDone  = "!done"
+	ReplyTrap  = "!trap"
+	ReplyFatal = "!fatal"
+	ReplyEmpty = "!empty"
+)
+
+// Sentence is a parsed reply sentence
+type Sentence struct {
+	Word  string            // !re, !done, !trap, !fatal, !empty
+	Tag   string            // .tag value, if any
+	Attrs map[string]string // =key=value attribute words
+}
+
+// parseSentence converts raw words into a Sentence
+func parseSentence(words []string) *Sentence {
+	s := &Sentence{Attrs: make(map[string]string)}
+	if len(words) == 0 {
+		return s
+	}
+
+	s.Word = words[0]
+	for _, word := range words[1:] {
+		switch {
+		case strings.HasPrefix(word, ".tag="):
+			s.Tag = strings.TrimPrefix(word, ".tag=")
+		case strings.HasPrefix(word, "="):
+			key, value := splitAttr(word[1:])
+			s.Attrs[key] = value
+		default:
+			// RouterOS sends the !fatal reason as a bare word
+			if s.Word == ReplyFatal {
+				s.Attrs["message"] = word
+			}
+		}
+	}
+	return s
+}
+
+// splitAttr splits "key=value" (value may itself contain '=')
+func splitAttr(word string) (string, string) {
+	if i := strings.Index(word, "="); i >= 0 {
+		return word[:i], word[i+1:]
+	}
+	return word, ""
+}
+
+// Reply is the complete response to one command
+type Reply struct {
+	Re   []*Sentence
+	Done *Sentence
+}
+
+// ========================================
+// Errors
+// ========================================
+
+// DeviceError is returned when the router answers a command with !trap
+type DeviceError struct {
+	Category string
+	Message  string
+}
+
+func (e *DeviceError) Error() string {
+	if e.Category != "" {
+		return fmt.Sprintf("routeros: %s (category %s)", e.Message, e.Category)
+	}
+	return "routeros: " + e.Message
+}
+
+// FatalError is returned when the router sends !fatal. The connection is
+// closed by the router after a fatal reply and cannot be reused.
+type FatalError struct {
+	Message string
+}
+
+func (e *FatalError) Error() string {
+	return "routeros: fatal: " + e.Message
+}
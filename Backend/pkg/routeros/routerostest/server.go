@@ -0,0 +1,449 @@
+// Package routerostest provides an in-process fake RouterOS API server for
+// testing code that talks to MikroTik routers without real hardware.
+package routerostest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/pkg/routeros"
+)
+
+// Handler serves a custom command. Returned rows are sent as !re sentences.
+// Returning a *routeros.DeviceError sends !trap, a *routeros.FatalError sends
+// !fatal and closes the connection.
+type Handler func(attrs map[string]string) ([]map[string]string, error)
+
+// Server is a fake RouterOS API server. It implements login (both the plain
+// and legacy challenge flows), /system/identity/print and an in-memory
+// /queue/simple table.
+type Server struct {
+	Addr     string
+	Username string
+	Password string
+	Identity string
+
+	// LegacyLogin makes /login answer with an MD5 challenge (pre-6.43)
+	LegacyLogin bool
+
+	listener net.Listener
+
+	mu       sync.Mutex
+	queues   []map[string]string
+	nextID   int
+	handlers map[string]Handler
+	commands []string
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a plain API server on a random local port
+func NewServer(username, password string) *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("routerostest: failed to listen: %v", err))
+	}
+	return start(l, username, password)
+}
+
+// NewTLSServer starts an api-ssl server with a self-signed certificate.
+// Clients must skip verification or trust the certificate themselves.
+func NewTLSServer(username, password string) *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("routerostest: failed to listen: %v", err))
+	}
+	cert, err := selfSignedCert()
+	if err != nil {
+		panic(fmt.Sprintf("routerostest: failed to create certificate: %v", err))
+	}
+	return start(tls.NewListener(l, &tls.Config{Certificates: []tls.Certificate{cert}}), username, password)
+}
+
+func start(l net.Listener, username, password string) *Server {
+	s := &Server{
+		Addr:     l.Addr().String(),
+		Username: username,
+		Password: password,
+		Identity: "MikroTik",
+		listener: l,
+		nextID:   1,
+		handlers: make(map[string]Handler),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Host returns the listener host
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+
+// Port returns the listener port
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	return port
+}
+
+// Close stops the server and drops all open connections
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Handle registers a handler for a command path, e.g. "/interface/print"
+func (s *Server) Handle(command string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = h
+}
+
+// AddQueue seeds a simple queue and returns its .id
+func (s *Server) AddQueue(attrs map[string]string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addQueueLocked(attrs)
+}
+
+// Queues returns a copy of the simple queue table
+func (s *Server) Queues() []map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]map[string]string, 0, len(s.queues))
+	for _, q := range s.queues {
+		out = append(out, copyAttrs(q))
+	}
+	return out
+}
+
+// Commands returns every command received after login, in order
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// ConnectionCount returns the number of currently open connections
+func (s *Server) ConnectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	loggedIn := false
+	challenge := ""
+
+	for {
+		words, err := routeros.ReadSentence(reader)
+		if err != nil {
+			return
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		command := words[0]
+		attrs, query := parseWords(words[1:])
+
+		if command == "/login" {
+			ok, ret := s.login(attrs, &challenge)
+			if ret != "" {
+				routeros.WriteSentence(conn, routeros.ReplyDone, "=ret="+ret)
+				continue
+			}
+			if !ok {
+				routeros.WriteSentence(conn, routeros.ReplyTrap, "=message=invalid user name or password (6)")
+				routeros.WriteSentence(conn, routeros.ReplyDone)
+				return
+			}
+			loggedIn = true
+			routeros.WriteSentence(conn, routeros.ReplyDone)
+			continue
+		}
+
+		if !loggedIn {
+			routeros.WriteSentence(conn, routeros.ReplyFatal, "not logged in")
+			return
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		s.mu.Unlock()
+
+		if command == "/quit" {
+			routeros.WriteSentence(conn, routeros.ReplyFatal, "session terminated on request")
+			return
+		}
+
+		rows, ret, err := s.dispatch(command, attrs, query)
+		if err != nil {
+			switch e := err.(type) {
+			case *routeros.FatalError:
+				routeros.WriteSentence(conn, routeros.ReplyFatal, e.Message)
+				return
+			case *routeros.DeviceError:
+				trap := []string{routeros.ReplyTrap, "=message=" + e.Message}
+				if e.Category != "" {
+					trap = append(trap, "=category="+e.Category)
+				}
+				routeros.WriteSentence(conn, trap...)
+			default:
+				routeros.WriteSentence(conn, routeros.ReplyTrap, "=message="+err.Error())
+			}
+			routeros.WriteSentence(conn, routeros.ReplyDone)
+			continue
+		}
+
+		for _, row := range rows {
+			routeros.WriteSentence(conn, append([]string{routeros.ReplyRe}, encodeAttrs(row)...)...)
+		}
+		if ret != "" {
+			routeros.WriteSentence(conn, routeros.ReplyDone, "=ret="+ret)
+		} else {
+			routeros.WriteSentence(conn, routeros.ReplyDone)
+		}
+	}
+}
+
+// login handles both login flows. A non-empty ret is a challenge to send back.
+func (s *Server) login(attrs map[string]string, challenge *string) (bool, string) {
+	if s.LegacyLogin {
+		if response, ok := attrs["response"]; ok {
+			raw, _ := hex.DecodeString(*challenge)
+			h := md5.New()
+			h.Write([]byte{0})
+			h.Write([]byte(s.Password))
+			h.Write(raw)
+			expected := "00" + hex.EncodeToString(h.Sum(nil))
+			return attrs["name"] == s.Username && response == expected, ""
+		}
+		buf := make([]byte, 16)
+		rand.Read(buf)
+		*challenge = hex.EncodeToString(buf)
+		return false, *challenge
+	}
+	return attrs["name"] == s.Username && attrs["password"] == s.Password, ""
+}
+
+func (s *Server) dispatch(command string, attrs, query map[string]string) ([]map[string]string, string, error) {
+	s.mu.Lock()
+	h, ok := s.handlers[command]
+	s.mu.Unlock()
+	if ok {
+		rows, err := h(attrs)
+		return rows, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch command {
+	case "/system/identity/print":
+		return []map[string]string{{"name": s.Identity}}, "", nil
+
+	case "/queue/simple/print":
+		var rows []map[string]string
+		for _, q := range s.queues {
+			if matches(q, query) {
+				rows = append(rows, project(q, attrs[".proplist"]))
+			}
+		}
+		return rows, "", nil
+
+	case "/queue/simple/add":
+		name := attrs["name"]
+		if name == "" {
+			return nil, "", &routeros.DeviceError{Message: "failure: name is required"}
+		}
+		for _, q := range s.queues {
+			if q["name"] == name {
+				return nil, "", &routeros.DeviceError{Message: "failure: already have such name"}
+			}
+		}
+		return nil, s.addQueueLocked(attrs), nil
+
+	case "/queue/simple/set":
+		q := s.findQueueLocked(attrs[".id"])
+		if q == nil {
+			return nil, "", &routeros.DeviceError{Message: "no such item"}
+		}
+		for k, v := range attrs {
+			if k != ".id" {
+				q[k] = v
+			}
+		}
+		return nil, "", nil
+
+	case "/queue/simple/remove":
+		for _, id := range strings.Split(attrs[".id"], ",") {
+			idx := -1
+			for i, q := range s.queues {
+				if q[".id"] == id {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				return nil, "", &routeros.DeviceError{Message: "no such item"}
+			}
+			s.queues = append(s.queues[:idx], s.queues[idx+1:]...)
+		}
+		return nil, "", nil
+	}
+
+	return nil, "", &routeros.DeviceError{Message: "no such command prefix", Category: "0"}
+}
+
+func (s *Server) addQueueLocked(attrs map[string]string) string {
+	q := copyAttrs(attrs)
+	q[".id"] = fmt.Sprintf("*%X", s.nextID)
+	s.nextID++
+	if _, ok := q["disabled"]; !ok {
+		q["disabled"] = "false"
+	}
+	s.queues = append(s.queues, q)
+	return q[".id"]
+}
+
+func (s *Server) findQueueLocked(id string) map[string]string {
+	for _, q := range s.queues {
+		if q[".id"] == id {
+			return q
+		}
+	}
+	return nil
+}
+
+// parseWords splits "=key=value" attribute words and "?key=value" query words
+func parseWords(words []string) (map[string]string, map[string]string) {
+	attrs := make(map[string]string)
+	query := make(map[string]string)
+	for _, w := range words {
+		switch {
+		case strings.HasPrefix(w, "="):
+			kv := strings.SplitN(w[1:], "=", 2)
+			if len(kv) == 2 {
+				attrs[kv[0]] = kv[1]
+			}
+		case strings.HasPrefix(w, "?"):
+			kv := strings.SplitN(w[1:], "=", 2)
+			if len(kv) == 2 {
+				query[kv[0]] = kv[1]
+			}
+		}
+	}
+	return attrs, query
+}
+
+func matches(row, query map[string]string) bool {
+	for k, v := range query {
+		if row[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func project(row map[string]string, proplist string) map[string]string {
+	if proplist == "" {
+		return copyAttrs(row)
+	}
+	out := make(map[string]string)
+	for _, k := range strings.Split(proplist, ",") {
+		if v, ok := row[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func encodeAttrs(row map[string]string) []string {
+	keys := make([]string, 0, len(row))
+	for k := range row {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	words := make([]string, 0, len(keys))
+	for _, k := range keys {
+		words = append(words, "="+k+"="+row[k])
+	}
+	return words
+}
+
+func copyAttrs(attrs map[string]string) map[string]string {
+	out := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		out[k] = v
+	}
+	return out
+}
+
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "routerostest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
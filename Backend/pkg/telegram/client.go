@@ -0,0 +1,219 @@
+// Package telegram is a small client for the Telegram Bot API. Every tenant
+// runs their own bot, so each call takes the bot token instead of the client
+// holding one.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNoToken is returned when the tenant has not saved a bot token
+var ErrNoToken = errors.New("telegram bot token is not set")
+
+// Config holds Bot API configuration
+type Config struct {
+	BaseURL string // overrides the API URL, e.g. for a local Bot API server or a fake in tests
+}
+
+// Client calls the Bot API
+type Client struct {
+	config     *Config
+	httpClient *http.Client
+}
+
+// User is a Telegram user or bot
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// Chat is the chat a message was sent in
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"` // private, group, supergroup, channel
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Message is a chat message
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Update is an incoming update. Only messages are used by the bot.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+// APIError is an error answer of the Bot API
+type APIError struct {
+	Code        int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram error: %d %s", e.Code, e.Description)
+}
+
+// apiResponse wraps every Bot API answer
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	ErrorCode   int             `json:"error_code,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+// NewClient creates a new Bot API client
+func NewClient(config *Config) *Client {
+	return &Client{
+		config: config,
+		httpClient: &http.Client{
+			// Long enough for long polling getUpdates calls
+			Timeout: 90 * time.Second,
+		},
+	}
+}
+
+// GetAPIURL returns the API URL
+func (c *Client) GetAPIURL() string {
+	if c.config.BaseURL != "" {
+		return strings.TrimRight(c.config.BaseURL, "/")
+	}
+	return "https://api.telegram.org"
+}
+
+// GetMe returns the bot of the token, which also checks that the token works
+func (c *Client) GetMe(ctx context.Context, token string) (*User, error) {
+	var bot User
+	if err := c.call(ctx, token, "getMe", nil, &bot); err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+// SendMessage sends a plain text message to a chat
+func (c *Client) SendMessage(ctx context.Context, token string, chatID int64, text string) (*Message, error) {
+	var message Message
+	params := map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}
+	if err := c.call(ctx, token, "sendMessage", params, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// GetUpdates returns the updates after offset, waiting up to timeout for one
+// to arrive (long polling)
+func (c *Client) GetUpdates(ctx context.Context, token string, offset int64, timeout time.Duration) ([]Update, error) {
+	var updates []Update
+	params := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout / time.Second),
+		"allowed_updates": []string{"message"},
+	}
+	if err := c.call(ctx, token, "getUpdates", params, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// SetWebhook makes Telegram post updates to webhookURL, sending secret in the
+// X-Telegram-Bot-Api-Secret-Token header
+func (c *Client) SetWebhook(ctx context.Context, token, webhookURL, secret string) error {
+	params := map[string]interface{}{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": []string{"message"},
+	}
+	return c.call(ctx, token, "setWebhook", params, nil)
+}
+
+// DeleteWebhook switches the bot back to getUpdates
+func (c *Client) DeleteWebhook(ctx context.Context, token string) error {
+	return c.call(ctx, token, "deleteWebhook", nil, nil)
+}
+
+// call posts params as JSON to a Bot API method and decodes its result
+func (c *Client) call(ctx context.Context, token, method string, params interface{}, result interface{}) error {
+	if token == "" {
+		return ErrNoToken
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.GetAPIURL()+"/bot"+token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		// The URL contains the token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var answer apiResponse
+	if err := json.Unmarshal(respBody, &answer); err != nil {
+		return fmt.Errorf("telegram error: %d %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if !answer.OK {
+		return &APIError{Code: answer.ErrorCode, Description: answer.Description}
+	}
+	if result != nil {
+		if err := json.Unmarshal(answer.Result, result); err != nil {
+			return fmt.Errorf("failed to unmarshal %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// ParseCommand splits a command message into its name and arguments, e.g.
+// "/bayar@netdesa_bot CUST-1 150000" into "bayar" and [CUST-1 150000]. It
+// returns an empty name for text that is not a command.
+func ParseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+	name := strings.TrimPrefix(fields[0], "/")
+	if at := strings.Index(name, "@"); at >= 0 {
+		name = name[:at]
+	}
+	return strings.ToLower(name), fields[1:]
+}
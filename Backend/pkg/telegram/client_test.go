@@ -0,0 +1,84 @@
+package telegram_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/pkg/telegram"
+	"github.com/rtrwnet/saas-backend/pkg/telegram/telegramtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetMe(t *testing.T) {
+	ctx := context.Background()
+	srv := telegramtest.NewServer("123:abc")
+	defer srv.Close()
+	client := telegram.NewClient(&telegram.Config{BaseURL: srv.URL})
+
+	bot, err := client.GetMe(ctx, "123:abc")
+	require.NoError(t, err)
+	assert.Equal(t, "test_bot", bot.Username)
+
+	_, err = client.GetMe(ctx, "123:wrong")
+	var apiErr *telegram.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 401, apiErr.Code)
+
+	_, err = client.GetMe(ctx, "")
+	assert.ErrorIs(t, err, telegram.ErrNoToken)
+}
+
+func TestClient_UpdatesAndMessages(t *testing.T) {
+	ctx := context.Background()
+	srv := telegramtest.NewServer("123:abc")
+	defer srv.Close()
+	client := telegram.NewClient(&telegram.Config{BaseURL: srv.URL})
+
+	srv.SendText(555, "budi", "/start")
+	srv.SendText(555, "budi", "/status CUST-1")
+
+	updates, err := client.GetUpdates(ctx, "123:abc", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	assert.Equal(t, int64(555), updates[1].Message.Chat.ID)
+	assert.Equal(t, "/status CUST-1", updates[1].Message.Text)
+
+	// The offset confirms earlier updates
+	updates, err = client.GetUpdates(ctx, "123:abc", updates[1].UpdateID+1, 0)
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+
+	// Long polling returns as soon as a message arrives
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		srv.SendText(555, "budi", "/cari siti")
+	}()
+	updates, err = client.GetUpdates(ctx, "123:abc", 3, 5*time.Second)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Equal(t, "/cari siti", updates[0].Message.Text)
+
+	_, err = client.SendMessage(ctx, "123:abc", 555, "Halo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Halo"}, srv.Messages(555))
+
+	require.NoError(t, client.SetWebhook(ctx, "123:abc", "https://api.example.com/webhooks/telegram/t1", "s3cret"))
+	url, secret := srv.Webhook()
+	assert.Equal(t, "https://api.example.com/webhooks/telegram/t1", url)
+	assert.Equal(t, "s3cret", secret)
+}
+
+func TestParseCommand(t *testing.T) {
+	name, args := telegram.ParseCommand("/bayar@netdesa_bot CUST-1  150000")
+	assert.Equal(t, "bayar", name)
+	assert.Equal(t, []string{"CUST-1", "150000"}, args)
+
+	name, args = telegram.ParseCommand("/Status")
+	assert.Equal(t, "status", name)
+	assert.Empty(t, args)
+
+	name, _ = telegram.ParseCommand("halo")
+	assert.Equal(t, "", name)
+}
@@ -0,0 +1,200 @@
+// Package telegramtest provides an in-process fake Telegram Bot API server
+// for tests. It queues updates as if users wrote to the bot and records the
+// messages the bot sends back.
+package telegramtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtrwnet/saas-backend/pkg/telegram"
+)
+
+// SentMessage is a message the bot sent through sendMessage
+type SentMessage struct {
+	ChatID int64
+	Text   string
+}
+
+// Server is a fake Bot API for a single bot token
+type Server struct {
+	URL   string
+	Token string
+	Bot   telegram.User
+
+	http *httptest.Server
+
+	mu         sync.Mutex
+	nextUpdate int64
+	nextMsg    int64
+	updates    []telegram.Update
+	sent       []SentMessage
+	webhookURL string
+	secret     string
+	arrived    chan struct{}
+}
+
+// NewServer starts a fake Bot API that accepts calls with token
+func NewServer(token string) *Server {
+	s := &Server{
+		Token:      token,
+		Bot:        telegram.User{ID: 100000, IsBot: true, FirstName: "Test Bot", Username: "test_bot"},
+		nextUpdate: 1,
+		nextMsg:    1,
+		arrived:    make(chan struct{}),
+	}
+	s.http = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.http.URL
+	return s
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.http.Close()
+}
+
+// SendText queues a private message from a user to the bot and returns the
+// update getUpdates will deliver. The chat ID equals the user ID, as it does
+// for private chats.
+func (s *Server) SendText(userID int64, username, text string) telegram.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update := telegram.Update{
+		UpdateID: s.nextUpdate,
+		Message: &telegram.Message{
+			MessageID: s.nextMsg,
+			From:      &telegram.User{ID: userID, FirstName: username, Username: username},
+			Chat:      telegram.Chat{ID: userID, Type: "private", Username: username},
+			Date:      time.Now().Unix(),
+			Text:      text,
+		},
+	}
+	s.nextUpdate++
+	s.nextMsg++
+	s.updates = append(s.updates, update)
+
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+	return update
+}
+
+// Sent returns the messages the bot sent so far
+func (s *Server) Sent() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage(nil), s.sent...)
+}
+
+// Messages returns the texts the bot sent to a chat
+func (s *Server) Messages(chatID int64) []string {
+	var texts []string
+	for _, m := range s.Sent() {
+		if m.ChatID == chatID {
+			texts = append(texts, m.Text)
+		}
+	}
+	return texts
+}
+
+// Webhook returns the webhook URL and secret token set by the bot
+func (s *Server) Webhook() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.webhookURL, s.secret
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != s.Token {
+		reply(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	var params struct {
+		ChatID      int64  `json:"chat_id"`
+		Text        string `json:"text"`
+		Offset      int64  `json:"offset"`
+		Timeout     int    `json:"timeout"`
+		URL         string `json:"url"`
+		SecretToken string `json:"secret_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		reply(w, http.StatusBadRequest, nil, "Bad Request: invalid JSON")
+		return
+	}
+
+	switch method {
+	case "getMe":
+		reply(w, http.StatusOK, s.Bot, "")
+	case "sendMessage":
+		if params.Text == "" {
+			reply(w, http.StatusBadRequest, nil, "Bad Request: message text is empty")
+			return
+		}
+		s.mu.Lock()
+		s.sent = append(s.sent, SentMessage{ChatID: params.ChatID, Text: params.Text})
+		message := telegram.Message{MessageID: s.nextMsg, From: &s.Bot, Chat: telegram.Chat{ID: params.ChatID, Type: "private"}, Date: time.Now().Unix(), Text: params.Text}
+		s.nextMsg++
+		s.mu.Unlock()
+		reply(w, http.StatusOK, message, "")
+	case "getUpdates":
+		reply(w, http.StatusOK, s.pending(r, params.Offset, time.Duration(params.Timeout)*time.Second), "")
+	case "setWebhook":
+		s.mu.Lock()
+		s.webhookURL, s.secret = params.URL, params.SecretToken
+		s.mu.Unlock()
+		reply(w, http.StatusOK, true, "")
+	case "deleteWebhook":
+		s.mu.Lock()
+		s.webhookURL, s.secret = "", ""
+		s.mu.Unlock()
+		reply(w, http.StatusOK, true, "")
+	default:
+		reply(w, http.StatusNotFound, nil, "Not Found: method not found")
+	}
+}
+
+// pending confirms the updates before offset and returns the rest, waiting up
+// to timeout for one to arrive like the real long polling does
+func (s *Server) pending(r *http.Request, offset int64, timeout time.Duration) []telegram.Update {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		kept := s.updates[:0]
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				kept = append(kept, u)
+			}
+		}
+		s.updates = kept
+		updates := append([]telegram.Update{}, kept...)
+		arrived := s.arrived
+		s.mu.Unlock()
+
+		if len(updates) > 0 || timeout <= 0 {
+			return updates
+		}
+		select {
+		case <-arrived:
+		case <-deadline:
+			return updates
+		case <-r.Context().Done():
+			return updates
+		}
+	}
+}
+
+func reply(w http.ResponseWriter, status int, result interface{}, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if status != http.StatusOK {
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": status, "description": description})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
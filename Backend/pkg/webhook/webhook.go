@@ -0,0 +1,263 @@
+// Package webhook signs and sends the events of a tenant to the endpoints
+// the tenant registered.
+//
+// Every request is a JSON POST with these headers:
+//
+//	X-Webhook-Event:     event name, e.g. payment.paid
+//	X-Webhook-Delivery:  delivery ID, repeated on retries
+//	X-Webhook-Timestamp: unix seconds when the request was signed
+//	X-Webhook-Signature: v1=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// Receivers should check the signature with their webhook secret and reject
+// timestamps that are too old to stop replays.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Request headers
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	signatureVersion = "v1"
+	secretPrefix     = "whsec_"
+	// Only the start of a response is kept for the delivery log
+	maxResponseBody = 2048
+)
+
+// ErrPrivateAddress is returned for endpoints on loopback, private or
+// link-local addresses unless Config.AllowPrivate is set
+var ErrPrivateAddress = errors.New("webhook endpoint resolves to a private address")
+
+// Config holds webhook client configuration
+type Config struct {
+	Timeout      time.Duration // per request, 10s when zero
+	AllowPrivate bool          // allow endpoints on private networks, for development
+	UserAgent    string
+}
+
+// Client sends signed webhook requests
+type Client struct {
+	config     *Config
+	httpClient *http.Client
+}
+
+// Request is one signed POST of an event
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+	Time       time.Time // signing time
+}
+
+// Response is what the endpoint answered
+type Response struct {
+	StatusCode int
+	Body       string // cut to the first 2 KB
+	Duration   time.Duration
+}
+
+// StatusError is returned when the endpoint answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook endpoint answered %d", e.StatusCode)
+}
+
+// NewClient creates a new webhook client
+func NewClient(config *Config) *Client {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		config:     config,
+		httpClient: NewRestrictedHTTPClient(timeout, config.AllowPrivate),
+	}
+}
+
+// NewRestrictedHTTPClient returns the HTTP client webhooks are sent with, for
+// any request to a URL a tenant entered. Unless allowPrivate is set it refuses
+// loopback, private and link-local addresses, and it never follows redirects.
+func NewRestrictedHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// Checked on the resolved address, so DNS names pointing inside
+		// the network are refused too
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// A redirect is an answer like any other and is not followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// reservedNets are the special-purpose ranges (RFC 6890) that are not
+// reachable on the public internet besides those net.IP already knows
+var reservedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",       // this network
+		"100.64.0.0/10",   // carrier-grade NAT
+		"192.0.0.0/24",    // IETF protocol assignments
+		"192.0.2.0/24",    // documentation
+		"198.18.0.0/15",   // benchmarking
+		"198.51.100.0/24", // documentation
+		"203.0.113.0/24",  // documentation
+		"240.0.0.0/4",     // reserved and broadcast
+		"64:ff9b:1::/48",  // local-use NAT64
+		"100::/64",        // discard only
+		"2001:db8::/32",   // documentation
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send signs and posts the request. The response is returned whenever the
+// endpoint answered, also together with a StatusError for non-2xx answers.
+func (c *Client) Send(ctx context.Context, req *Request) (*Response, error) {
+	timestamp := req.Time.Unix()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.config.UserAgent != "" {
+		httpReq.Header.Set("User-Agent", c.config.UserAgent)
+	}
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, req.DeliveryID)
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Body))
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	result := &Response{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Duration:   time.Since(start),
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return result, nil
+}
+
+// Sign returns the signature header value of a body signed at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header against the body, and that timestamp is
+// within tolerance of now
+func Verify(secret, signature string, timestamp int64, body []byte, now time.Time, tolerance time.Duration) bool {
+	signedAt := time.Unix(timestamp, 0)
+	if now.Sub(signedAt) > tolerance || signedAt.Sub(now) > tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// GenerateSecret returns a new random webhook secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// ValidateURL checks that raw is an absolute http or https URL, and not on
+// a private address unless Config.AllowPrivate is set. Names are resolved
+// and checked again when sending, as DNS may change in between.
+func (c *Client) ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("URL must start with http:// or https://")
+	}
+	if u.Hostname() == "" {
+		return errors.New("URL must have a host")
+	}
+	if u.User != nil {
+		return errors.New("URL must not contain credentials")
+	}
+	if c.config.AllowPrivate {
+		return nil
+	}
+	if ip := net.ParseIP(u.Hostname()); (ip != nil && isPrivateIP(ip)) || strings.EqualFold(u.Hostname(), "localhost") {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FonnteConfig holds Fonnte configuration
+type FonnteConfig struct {
+	BaseURL string // overrides the API URL, e.g. for a Wablas-compatible gateway or a local stand-in
+}
+
+// FonnteClient sends messages through the Fonnte HTTP API. Each tenant
+// connects their own device and saves its token as WhatsappAPIKey.
+type FonnteClient struct {
+	config     *FonnteConfig
+	httpClient *http.Client
+}
+
+// NewFonnteClient creates a new Fonnte client
+func NewFonnteClient(config *FonnteConfig) *FonnteClient {
+	return &FonnteClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// GetAPIURL returns the API URL
+func (c *FonnteClient) GetAPIURL() string {
+	if c.config.BaseURL != "" {
+		return strings.TrimRight(c.config.BaseURL, "/")
+	}
+	return "https://api.fonnte.com"
+}
+
+// fonnteSendResponse is the response of POST /send. Fonnte answers 200 for
+// rejected messages too, with status false and a reason.
+type fonnteSendResponse struct {
+	Status  bool              `json:"status"`
+	Reason  string            `json:"reason,omitempty"`
+	Detail  string            `json:"detail,omitempty"`
+	ID      []json.RawMessage `json:"id,omitempty"` // numbers or strings
+	Process string            `json:"process,omitempty"`
+}
+
+// fonnteStatus is the body of the message status webhook
+type fonnteStatus struct {
+	ID      string `json:"id"`
+	StateID string `json:"stateid"`
+	Status  string `json:"status"` // sent, pending, failed, invalid, expired
+	State   string `json:"state"`  // delivered, read
+}
+
+// Name returns the provider name
+func (c *FonnteClient) Name() string {
+	return ProviderFonnte
+}
+
+// Send queues a text message on the tenant's device
+func (c *FonnteClient) Send(ctx context.Context, token string, msg *Message) (*SendResult, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	if msg.To == "" {
+		return nil, ErrInvalidPhone
+	}
+
+	form := url.Values{}
+	form.Set("target", msg.To)
+	form.Set("message", msg.Text)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.GetAPIURL()+"/send", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", token)
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result fonnteSendResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("fonnte error: %d %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if resp.StatusCode >= 300 || !result.Status {
+		return nil, fmt.Errorf("fonnte error: %d %s", resp.StatusCode, result.Reason)
+	}
+
+	sent := &SendResult{Provider: ProviderFonnte, Status: StatusQueued}
+	if len(result.ID) > 0 {
+		sent.MessageID = strings.Trim(string(result.ID[0]), `"`)
+	}
+	return sent, nil
+}
+
+// ParseStatus parses the message status webhook
+func (c *FonnteClient) ParseStatus(body []byte) (*StatusUpdate, error) {
+	var status fonnteStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status: %w", err)
+	}
+	if status.ID == "" {
+		return nil, fmt.Errorf("status has no message id")
+	}
+	return &StatusUpdate{MessageID: status.ID, Status: fonnteDeliveryStatus(status)}, nil
+}
+
+// fonnteDeliveryStatus maps a Fonnte status and state to a normalized status
+func fonnteDeliveryStatus(status fonnteStatus) string {
+	switch strings.ToLower(status.State) {
+	case "read":
+		return StatusRead
+	case "delivered":
+		return StatusDelivered
+	}
+	switch strings.ToLower(status.Status) {
+	case "sent":
+		return StatusSent
+	case "failed", "invalid", "expired":
+		return StatusFailed
+	default:
+		return StatusQueued
+	}
+}
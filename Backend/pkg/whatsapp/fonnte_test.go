@@ -0,0 +1,103 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ Provider = (*FonnteClient)(nil)
+	_ Provider = (*StubProvider)(nil)
+)
+
+// fonnteStandIn serves canned Fonnte API responses
+func fonnteStandIn(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *FonnteClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewFonnteClient(&FonnteConfig{BaseURL: server.URL})
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := map[string]string{
+		"081234567890":      "6281234567890",
+		"0812-3456-7890":    "6281234567890",
+		"+62 812 3456 7890": "6281234567890",
+		"6281234567890":     "6281234567890",
+		"81234567890":       "6281234567890",
+		"12345":             "",
+		"":                  "",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, NormalizePhone(in), in)
+	}
+}
+
+func TestFonnteClient_Send(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Queued", func(t *testing.T) {
+		client := fonnteStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST /send", r.Method+" "+r.URL.Path)
+			assert.Equal(t, "device-token", r.Header.Get("Authorization"))
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "6281234567890", r.PostForm.Get("target"))
+			assert.Equal(t, "Halo Budi", r.PostForm.Get("message"))
+			fmt.Fprint(w, `{"status":true,"detail":"success! message in queue","id":[80367170],"process":"pending","target":["6281234567890"]}`)
+		})
+
+		result, err := client.Send(ctx, "device-token", &Message{To: "6281234567890", Text: "Halo Budi"})
+		require.NoError(t, err)
+		assert.Equal(t, ProviderFonnte, result.Provider)
+		assert.Equal(t, "80367170", result.MessageID)
+		assert.Equal(t, StatusQueued, result.Status)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		client := fonnteStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status":false,"reason":"invalid token"}`)
+		})
+
+		_, err := client.Send(ctx, "bad-token", &Message{To: "6281234567890", Text: "Halo"})
+		assert.EqualError(t, err, "fonnte error: 200 invalid token")
+	})
+
+	t.Run("No token", func(t *testing.T) {
+		client := NewFonnteClient(&FonnteConfig{})
+		_, err := client.Send(ctx, "", &Message{To: "6281234567890", Text: "Halo"})
+		assert.ErrorIs(t, err, ErrNoToken)
+	})
+}
+
+func TestFonnteClient_ParseStatus(t *testing.T) {
+	client := NewFonnteClient(&FonnteConfig{})
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"device":"6281200000000","id":"80367170","stateid":"s1","status":"sent","state":""}`, StatusSent},
+		{`{"id":"80367170","status":"sent","state":"delivered"}`, StatusDelivered},
+		{`{"id":"80367170","status":"sent","state":"read"}`, StatusRead},
+		{`{"id":"80367170","status":"invalid"}`, StatusFailed},
+		{`{"id":"80367170","status":"pending"}`, StatusQueued},
+	}
+	for _, tt := range tests {
+		update, err := client.ParseStatus([]byte(tt.body))
+		require.NoError(t, err)
+		assert.Equal(t, "80367170", update.MessageID)
+		assert.Equal(t, tt.want, update.Status, tt.body)
+	}
+
+	_, err := client.ParseStatus([]byte(`{"status":"sent"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/rtrwnet/saas-backend/pkg/logger"
+)
+
+// StubProvider logs messages instead of sending them, for local development
+// and tests. Every message is reported as sent.
+type StubProvider struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewStubProvider creates a stub provider
+func NewStubProvider() *StubProvider {
+	return &StubProvider{}
+}
+
+// Name returns the provider name
+func (p *StubProvider) Name() string {
+	return ProviderStub
+}
+
+// Send logs the message
+func (p *StubProvider) Send(ctx context.Context, token string, msg *Message) (*SendResult, error) {
+	p.mu.Lock()
+	p.sent = append(p.sent, *msg)
+	id := fmt.Sprintf("stub-%d", len(p.sent))
+	p.mu.Unlock()
+
+	logger.Info("WhatsApp stub: message %s to %s: %s", id, msg.To, msg.Text)
+	return &SendResult{Provider: ProviderStub, MessageID: id, Status: StatusSent}, nil
+}
+
+// ParseStatus parses {"id": "...", "status": "delivered"}
+func (p *StubProvider) ParseStatus(body []byte) (*StatusUpdate, error) {
+	var update struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &update); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status: %w", err)
+	}
+	return &StatusUpdate{MessageID: update.ID, Status: update.Status}, nil
+}
+
+// Sent returns the messages sent so far
+func (p *StubProvider) Sent() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.sent...)
+}
@@ -0,0 +1,92 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Provider names, as stored in WhatsAppMessage.Provider
+const (
+	ProviderFonnte = "fonnte"
+	ProviderStub   = "stub"
+)
+
+// Normalized delivery statuses returned by every provider
+const (
+	StatusQueued    = "queued" // accepted by the gateway, not yet on the phone
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+	StatusFailed    = "failed"
+)
+
+var (
+	// ErrInvalidPhone is returned when a number cannot be sent to
+	ErrInvalidPhone = errors.New("invalid whatsapp number")
+	// ErrNoToken is returned when a send has no gateway token
+	ErrNoToken = errors.New("whatsapp gateway token is not set")
+)
+
+// Provider is implemented by every WhatsApp gateway. Tenants bring their own
+// gateway account, so each call takes the tenant's token.
+type Provider interface {
+	// Name returns the provider name, e.g. "fonnte"
+	Name() string
+	// Send sends a text message to a normalized number
+	Send(ctx context.Context, token string, msg *Message) (*SendResult, error)
+	// ParseStatus parses a delivery status callback of the gateway
+	ParseStatus(body []byte) (*StatusUpdate, error)
+}
+
+// Message is a text message to one number
+type Message struct {
+	To   string // international format without "+", e.g. 6281234567890
+	Text string
+}
+
+// SendResult is a message accepted by a gateway
+type SendResult struct {
+	Provider  string `json:"provider"`
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"` // normalized status
+}
+
+// StatusUpdate is a delivery status reported by a gateway callback
+type StatusUpdate struct {
+	MessageID string
+	Status    string // normalized status
+}
+
+// NormalizePhone returns an Indonesian number in international format
+// without "+" or separators: 0812-3456-7890 and +62 812 3456 7890 both
+// become 6281234567890. It returns "" when the number is too short.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+
+	switch {
+	case strings.HasPrefix(digits, "0"):
+		digits = "62" + digits[1:]
+	case strings.HasPrefix(digits, "8"):
+		digits = "62" + digits
+	}
+	if len(digits) < 10 {
+		return ""
+	}
+	return digits
+}
+
+// NewProvider returns the named provider: ProviderStub, or the Fonnte client
+// for anything else. apiURL overrides the gateway URL.
+func NewProvider(name, apiURL string) Provider {
+	if name == ProviderStub {
+		return NewStubProvider()
+	}
+	return NewFonnteClient(&FonnteConfig{BaseURL: apiURL})
+}